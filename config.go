@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render/html"
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+	"github.com/wildreason/reader/tui"
+)
+
+// projectConfigName is the per-project config file, looked up from the
+// current directory upward
+const projectConfigName = ".aster.toml"
+
+// config holds the settings read from ~/.config/aster/config.toml and the
+// project's .aster.toml, which overrides it. Flags override both.
+type config struct {
+	Output       string               // "browser" (default), "terminal" or "serve"
+	Border       term.BorderStyle     // Block borders in the terminal
+	Theme        string               // Terminal color theme, "auto" to suit the background
+	Themes       map[string]userTheme // Themes defined in [themes.NAME] tables
+	CodeTheme    string               // Terminal code colors
+	Recent       int                  // Files kept in the recent list
+	Keys         map[string][]string  // Reader actions remapped to keys
+	JSONLFilters []string             // Transcript content types shown without asking
+	Associations map[string]string    // Extensions opened as a type, e.g. ".mdx" = "md"
+	ServePort    int                  // Port used when output is "serve"
+	ServeColumns []string             // Frontmatter fields shown in directory listings
+	HTMLTheme    html.PageTheme       // Color scheme pages open in
+	CSS          []string             // Stylesheets added to pages: files, or URLs to link
+
+	dir string // Directory of the file being parsed, for relative paths
+}
+
+// userTheme is a theme defined in the config: a built-in or user theme with
+// some colors changed
+type userTheme struct {
+	Base   string            // Theme it starts from, "dark" by default
+	Colors map[string]string // Role names, or code.FIELD, to colors
+}
+
+// borderStyles lists the border style names accepted in the config
+var borderStyles = []term.BorderStyle{
+	term.BorderNone, term.BorderLeft, term.BorderMinimal,
+	term.BorderBox, term.BorderDouble, term.BorderRounded,
+}
+
+// configPaths returns the user and project config files, in the order they
+// apply
+func configPaths() []string {
+	var paths []string
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config")
+		}
+	}
+	if dir != "" {
+		paths = append(paths, filepath.Join(dir, "aster", "config.toml"))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		for dir := cwd; ; dir = filepath.Dir(dir) {
+			path := filepath.Join(dir, projectConfigName)
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+				break
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// loadConfig reads the config files that exist, later files overriding
+// earlier ones
+func loadConfig(paths []string) (*config, error) {
+	cfg := &config{Border: term.BorderNone, Recent: 5}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg.dir = filepath.Dir(path)
+		if err := cfg.parse(string(content)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Output == "serve" && cfg.ServePort == 0 {
+		return nil, fmt.Errorf("output = \"serve\" needs a serve.port")
+	}
+	return cfg, nil
+}
+
+// parse applies the settings in a TOML config file
+func (cfg *config) parse(content string) error {
+	m, err := parse.ParseTOML(content)
+	if err != nil {
+		return err
+	}
+	for _, key := range m.Keys {
+		v := m.Values[key]
+		switch key {
+		case "output":
+			s, err := configString(key, v)
+			if err != nil {
+				return err
+			}
+			if s != "browser" && s != "terminal" && s != "serve" {
+				return fmt.Errorf("output must be browser, terminal or serve, not %q", s)
+			}
+			cfg.Output = s
+		case "border":
+			s, err := configString(key, v)
+			if err != nil {
+				return err
+			}
+			if !validBorderStyle(term.BorderStyle(s)) {
+				return fmt.Errorf("unknown border style %q (none, left, minimal, box, double, rounded)", s)
+			}
+			cfg.Border = term.BorderStyle(s)
+		case "theme":
+			s, err := configString(key, v)
+			if err != nil {
+				return err
+			}
+			cfg.Theme = s
+		case "themes":
+			if err := cfg.parseThemes(v); err != nil {
+				return err
+			}
+		case "code_theme":
+			s, err := configString(key, v)
+			if err != nil {
+				return err
+			}
+			if _, ok := term.CodeColorsByName(s); !ok {
+				return fmt.Errorf("unknown code theme %q (dark, light, plain)", s)
+			}
+			cfg.CodeTheme = s
+		case "recent":
+			n, ok := v.(int64)
+			if !ok || n < 1 {
+				return fmt.Errorf("recent must be a positive number")
+			}
+			cfg.Recent = int(n)
+		case "keys":
+			if err := cfg.parseKeys(v); err != nil {
+				return err
+			}
+		case "jsonl":
+			table, ok := v.(*parse.Map)
+			if !ok {
+				return fmt.Errorf("jsonl must be a table")
+			}
+			for _, k := range table.Keys {
+				if k != "filters" {
+					return fmt.Errorf("unknown setting jsonl.%s", k)
+				}
+				filters, err := configStrings("jsonl.filters", table.Values[k])
+				if err != nil {
+					return err
+				}
+				cfg.JSONLFilters = filters
+			}
+		case "associations":
+			table, ok := v.(*parse.Map)
+			if !ok {
+				return fmt.Errorf("associations must be a table")
+			}
+			if cfg.Associations == nil {
+				cfg.Associations = make(map[string]string)
+			}
+			for _, ext := range table.Keys {
+				typeName, err := configString("associations."+ext, table.Values[ext])
+				if err != nil {
+					return err
+				}
+				cfg.Associations[ext] = typeName
+			}
+		case "serve":
+			if err := cfg.parseServe(v); err != nil {
+				return err
+			}
+		case "html":
+			if err := cfg.parseHTML(v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return nil
+}
+
+// parseKeys reads the [keys] table: each action is bound to a key or a
+// list of keys
+func (cfg *config) parseKeys(v any) error {
+	table, ok := v.(*parse.Map)
+	if !ok {
+		return fmt.Errorf("keys must be a table")
+	}
+	if cfg.Keys == nil {
+		cfg.Keys = make(map[string][]string)
+	}
+	for _, action := range table.Keys {
+		keys, err := configStrings("keys."+action, table.Values[action])
+		if err != nil {
+			return err
+		}
+		cfg.Keys[action] = keys
+	}
+	return nil
+}
+
+// parseThemes reads the [themes.NAME] tables: a base theme, role colors,
+// and code colors in a [themes.NAME.code] table
+func (cfg *config) parseThemes(v any) error {
+	table, ok := v.(*parse.Map)
+	if !ok {
+		return fmt.Errorf("themes must be a table")
+	}
+	if cfg.Themes == nil {
+		cfg.Themes = make(map[string]userTheme)
+	}
+	for _, name := range table.Keys {
+		prefix := "themes." + name
+		settings, ok := table.Values[name].(*parse.Map)
+		if !ok {
+			return fmt.Errorf("%s must be a table", prefix)
+		}
+		t := userTheme{Base: "dark", Colors: make(map[string]string)}
+		for _, key := range settings.Keys {
+			if key == "code" {
+				code, ok := settings.Values[key].(*parse.Map)
+				if !ok {
+					return fmt.Errorf("%s.code must be a table", prefix)
+				}
+				for _, field := range code.Keys {
+					color, err := configString(prefix+".code."+field, code.Values[field])
+					if err != nil {
+						return err
+					}
+					t.Colors["code."+field] = color
+				}
+				continue
+			}
+			s, err := configString(prefix+"."+key, settings.Values[key])
+			if err != nil {
+				return err
+			}
+			if key == "base" {
+				t.Base = s
+				continue
+			}
+			t.Colors[key] = s
+		}
+		// Check the colors now, so mistakes are reported with the file
+		if _, err := theme.Dark().With(t.Colors); err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		cfg.Themes[name] = t
+	}
+	return nil
+}
+
+// resolveTheme returns the theme with the given name, a user theme or a
+// built-in one; darkBackground picks the high-contrast variant
+func (cfg *config) resolveTheme(name string, darkBackground bool) (theme.Theme, error) {
+	seen := make(map[string]bool)
+	var resolve func(name string) (theme.Theme, error)
+	resolve = func(name string) (theme.Theme, error) {
+		t, ok := cfg.Themes[name]
+		if !ok {
+			if builtin, ok := theme.ByName(name, darkBackground); ok {
+				return builtin, nil
+			}
+			return theme.Theme{}, fmt.Errorf("unknown theme %q (%s, or a [themes.NAME] table)", name, strings.Join(theme.Names, ", "))
+		}
+		if seen[name] {
+			return theme.Theme{}, fmt.Errorf("theme %q is its own base", name)
+		}
+		seen[name] = true
+		base, err := resolve(t.Base)
+		if err != nil {
+			return theme.Theme{}, err
+		}
+		return base.With(t.Colors)
+	}
+	return resolve(name)
+}
+
+// parseServe reads the [serve] table
+func (cfg *config) parseServe(v any) error {
+	table, ok := v.(*parse.Map)
+	if !ok {
+		return fmt.Errorf("serve must be a table")
+	}
+	for _, key := range table.Keys {
+		switch key {
+		case "port":
+			n, ok := table.Values[key].(int64)
+			if !ok || n < 1 || n > 65535 {
+				return fmt.Errorf("serve.port must be a port number")
+			}
+			cfg.ServePort = int(n)
+		case "columns":
+			columns, err := configStrings("serve.columns", table.Values[key])
+			if err != nil {
+				return err
+			}
+			cfg.ServeColumns = columns
+		default:
+			return fmt.Errorf("unknown setting serve.%s", key)
+		}
+	}
+	return nil
+}
+
+// parseHTML reads the [html] table. Stylesheet paths are relative to the
+// config file.
+func (cfg *config) parseHTML(v any) error {
+	table, ok := v.(*parse.Map)
+	if !ok {
+		return fmt.Errorf("html must be a table")
+	}
+	for _, key := range table.Keys {
+		switch key {
+		case "theme":
+			s, err := configString("html.theme", table.Values[key])
+			if err != nil {
+				return err
+			}
+			t, ok := html.ParsePageTheme(s)
+			if !ok {
+				return fmt.Errorf("html.theme must be auto, light or dark, not %q", s)
+			}
+			cfg.HTMLTheme = t
+		case "css":
+			sheets, err := configStrings("html.css", table.Values[key])
+			if err != nil {
+				return err
+			}
+			for i, sheet := range sheets {
+				if !isURL(sheet) && !filepath.IsAbs(sheet) && cfg.dir != "" {
+					sheets[i] = filepath.Join(cfg.dir, sheet)
+				}
+			}
+			cfg.CSS = sheets
+		default:
+			return fmt.Errorf("unknown setting html.%s", key)
+		}
+	}
+	return nil
+}
+
+// configString returns a string setting
+func configString(key string, v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+	return s, nil
+}
+
+// configStrings returns a setting that is a string or a list of strings
+func configStrings(key string, v any) ([]string, error) {
+	if s, ok := v.(string); ok {
+		return []string{s}, nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string or a list of strings", key)
+	}
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string or a list of strings", key)
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+// validBorderStyle reports whether style is a known border style
+func validBorderStyle(style term.BorderStyle) bool {
+	for _, s := range borderStyles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the defaults the config changes, before flags are read.
+// Output mode and directory listing columns are applied in main.
+func (cfg *config) apply() error {
+	if err := tui.SetKeys(cfg.Keys); err != nil {
+		return err
+	}
+	for ext, typeName := range cfg.Associations {
+		if err := parse.AssociateExtension(ext, typeName); err != nil {
+			return fmt.Errorf("associations: %w", err)
+		}
+	}
+	if cfg.CodeTheme != "" {
+		setCodeTheme(cfg.CodeTheme)
+	}
+	if cfg.HTMLTheme != "" {
+		html.SetPageTheme(cfg.HTMLTheme)
+	}
+	borderStyle = cfg.Border
+	maxRecent = cfg.Recent
+	if len(cfg.JSONLFilters) > 0 {
+		jsonlFilters = make(map[string]bool)
+		for _, name := range cfg.JSONLFilters {
+			jsonlFilters[name] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wildreason/reader/render/html"
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+)
+
+func TestLoadConfig_ProjectOverridesUser(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "config.toml")
+	project := filepath.Join(dir, ".aster.toml")
+	os.WriteFile(user, []byte(`
+output = "terminal"
+border = "rounded"
+recent = 10
+
+[keys]
+quit = ["x", "ctrl-q"]
+next_buffer = "L"
+
+[jsonl]
+filters = ["user", "assistant"]
+
+[associations]
+".mdx" = "md"
+
+[serve]
+port = 8080
+columns = ["status"]
+`), 0644)
+	os.WriteFile(project, []byte(`
+border = "box"
+
+[associations]
+".jsonl.log" = "jsonl"
+`), 0644)
+
+	cfg, err := loadConfig([]string{user, filepath.Join(dir, "missing.toml"), project})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Output != "terminal" || cfg.Border != term.BorderBox || cfg.Recent != 10 {
+		t.Errorf("got output %q, border %q, recent %d", cfg.Output, cfg.Border, cfg.Recent)
+	}
+	if want := []string{"x", "ctrl-q"}; !reflect.DeepEqual(cfg.Keys["quit"], want) {
+		t.Errorf("quit keys = %v, want %v", cfg.Keys["quit"], want)
+	}
+	if want := []string{"L"}; !reflect.DeepEqual(cfg.Keys["next_buffer"], want) {
+		t.Errorf("next_buffer keys = %v, want %v", cfg.Keys["next_buffer"], want)
+	}
+	if want := []string{"user", "assistant"}; !reflect.DeepEqual(cfg.JSONLFilters, want) {
+		t.Errorf("jsonl filters = %v, want %v", cfg.JSONLFilters, want)
+	}
+	if want := map[string]string{".mdx": "md", ".jsonl.log": "jsonl"}; !reflect.DeepEqual(cfg.Associations, want) {
+		t.Errorf("associations = %v, want %v", cfg.Associations, want)
+	}
+	if cfg.ServePort != 8080 || !reflect.DeepEqual(cfg.ServeColumns, []string{"status"}) {
+		t.Errorf("serve = %d %v", cfg.ServePort, cfg.ServeColumns)
+	}
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, err := loadConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Output != "" || cfg.Border != term.BorderNone || cfg.Recent != 5 {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestConfigParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		`output = "paper"`:         "output must be",
+		`border = "wavy"`:          "unknown border style",
+		`recent = 0`:               "recent must be",
+		`colour = "red"`:           "unknown setting",
+		"[serve]\nport = 70000":    "serve.port",
+		"[keys]\nquit = 3":         "keys.quit",
+		"[jsonl]\nfilter = []":     "jsonl.filter",
+		"output = \"terminal\"\nx": "line 2",
+	}
+	for content, want := range tests {
+		cfg := &config{}
+		err := cfg.parse(content)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parse(%q) = %v, want error containing %q", content, err, want)
+		}
+	}
+}
+
+func TestConfigThemes(t *testing.T) {
+	cfg := &config{}
+	err := cfg.parse(`
+theme = "paper"
+
+[themes.paper]
+base = "light"
+heading = "#005f87"
+
+[themes.paper.code]
+keyword = "#870087"
+
+[themes.ink]
+base = "paper"
+muted = "#444444"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Theme != "paper" {
+		t.Errorf("theme = %q", cfg.Theme)
+	}
+	th, err := cfg.resolveTheme("ink", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.Colors[theme.Heading] != "#005f87" || th.Colors[theme.Muted] != "#444444" || th.Code.Keyword != "#870087" {
+		t.Errorf("ink theme = %v, keyword %q", th.Colors, th.Code.Keyword)
+	}
+	if th.Colors[theme.Link] != theme.Light().Colors[theme.Link] {
+		t.Errorf("ink theme did not inherit light colors")
+	}
+
+	if _, err := cfg.resolveTheme("sepia", true); err == nil {
+		t.Errorf("expected an error for an unknown theme")
+	}
+	cfg.Themes["loop"] = userTheme{Base: "loop"}
+	if _, err := cfg.resolveTheme("loop", true); err == nil {
+		t.Errorf("expected an error for a theme based on itself")
+	}
+	if err := (&config{}).parse("[themes.bad]\nheadline = \"red\""); err == nil || !strings.Contains(err.Error(), "themes.bad") {
+		t.Errorf("unknown role error = %v", err)
+	}
+}
+
+func TestLoadConfig_HTML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".aster.toml")
+	os.WriteFile(path, []byte(`
+[html]
+theme = "dark"
+css = ["brand.css", "https://example.com/fonts.css"]
+`), 0644)
+	cfg, err := loadConfig([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTMLTheme != html.ThemeDark {
+		t.Errorf("html theme = %q", cfg.HTMLTheme)
+	}
+	if want := []string{filepath.Join(dir, "brand.css"), "https://example.com/fonts.css"}; !reflect.DeepEqual(cfg.CSS, want) {
+		t.Errorf("css = %v, want %v", cfg.CSS, want)
+	}
+	if err := (&config{}).parse("[html]\ntheme = \"sepia\""); err == nil {
+		t.Errorf("expected an error for an unknown html theme")
+	}
+}
+
+func TestLoadConfig_ServeNeedsPort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte(`output = "serve"`), 0644)
+	if _, err := loadConfig([]string{path}); err == nil {
+		t.Errorf("expected an error for output = \"serve\" without a port")
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	github.com/gdamore/tcell/v2 v2.10.0
 	github.com/rivo/tview v0.42.0
+	golang.org/x/image v0.25.0
 	golang.org/x/term v0.36.0
 )
 
@@ -2,7 +2,8 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -10,7 +11,15 @@ import (
 	"path/filepath"
 	"strings"
 
-	"golang.org/x/term"
+	xterm "golang.org/x/term"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render"
+	"github.com/wildreason/reader/render/html"
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/serve"
+	"github.com/wildreason/reader/theme"
+	"github.com/wildreason/reader/tui"
 )
 
 // Version information (injected at build time)
@@ -35,6 +44,108 @@ var shareFlag bool
 // terminalFlag forces terminal TUI rendering (-t with no type argument)
 var terminalFlag bool
 
+// strictMode exits non-zero when parsing reports errors (--strict flag)
+var strictMode bool
+
+// indexColumns are extra frontmatter fields shown when listing a directory (--columns a,b.c flag)
+var indexColumns []string
+
+// borderStyle separates blocks in the terminal (border in the config)
+var borderStyle = term.BorderNone
+
+// jsonlFilters are the transcript content types shown without asking
+// (jsonl.filters in the config), or nil to ask
+var jsonlFilters map[string]bool
+
+// cssFiles are stylesheets added to HTML pages (--css flag or html.css in
+// the config): files to inline, or URLs to link
+var cssFiles []string
+
+// themeName is the terminal color theme (--theme flag, ASTER_THEME or theme
+// in the config); "auto" picks dark or light to suit the background
+var themeName = "auto"
+
+// codeTheme overrides the theme's code block colors (--code-theme flag,
+// ASTER_CODE_THEME or code_theme in the config)
+var codeTheme string
+
+// setCodeTheme selects the terminal code block colors (--code-theme flag or
+// ASTER_CODE_THEME)
+func setCodeTheme(name string) {
+	if _, ok := term.CodeColorsByName(name); !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown code theme %q (dark, light, plain)\n", name)
+		os.Exit(1)
+	}
+	codeTheme = name
+}
+
+// applyTheme sets the terminal color theme. It runs before files are parsed,
+// since parsers color their output. Themes that suit the background ask the
+// terminal for its color, assuming dark if it doesn't say.
+func applyTheme(cfg *config) {
+	dark := true
+	if themeName == "auto" || themeName == "high-contrast" {
+		if xterm.IsTerminal(int(os.Stdout.Fd())) {
+			if d, ok := term.DetectDarkBackground(); ok {
+				dark = d
+			}
+		}
+	}
+	name := themeName
+	if name == "auto" {
+		name = "light"
+		if dark {
+			name = "dark"
+		}
+	}
+	t, err := cfg.resolveTheme(name, dark)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	theme.Set(t)
+	if colors, ok := term.CodeColorsByName(codeTheme); ok {
+		term.SetCodeColors(colors)
+	}
+}
+
+// addStylesheets adds cssFiles to HTML pages
+func addStylesheets() {
+	for _, sheet := range cssFiles {
+		if isURL(sheet) {
+			html.LinkStylesheet(sheet)
+			continue
+		}
+		css, err := os.ReadFile(sheet)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		html.AddStylesheet(string(css))
+	}
+}
+
+// isURL reports whether s is an http or https URL rather than a file
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// checkStrict exits with status 1 if --strict is set and any diagnostics are errors
+func checkStrict(sourceName string, diags []parse.Diagnostic) {
+	if !strictMode || !parse.HasErrors(diags) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", sourceName, parse.SummarizeDiagnostics(diags))
+	os.Exit(1)
+}
+
+// printDiagnostics lists diagnostics on stderr, for output modes without a status area
+func printDiagnostics(sourceName string, diags []parse.Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d.Format(sourceName))
+	}
+}
+
 // outputHTML prints HTML to stdout, or writes to a temp file and opens in browser if --share is set.
 func outputHTML(html string) {
 	if !shareFlag {
@@ -58,27 +169,9 @@ func outputHTML(html string) {
 // forceType overrides content type detection (-t TYPE flag)
 var forceType string
 
-// fileType defines a supported file type with its extensions
-type fileType struct {
-	name       string
-	extensions []string
-}
-
-var fileTypes = map[string]fileType{
-	"md":    {name: "markdown", extensions: []string{".md", ".markdown"}},
-	"img":   {name: "image", extensions: imageExtensions},
-	"vid":   {name: "video", extensions: videoExtensions},
-	"txt":   {name: "text", extensions: []string{".txt", ".log"}},
-	"json":  {name: "json", extensions: []string{".json"}},
-	"yaml":  {name: "yaml", extensions: []string{".yaml", ".yml"}},
-	"diff":  {name: "diff", extensions: []string{".diff", ".patch"}},
-	"jsonl": {name: "jsonl", extensions: []string{".jsonl"}},
-	"csv":   {name: "csv", extensions: []string{".csv", ".tsv"}},
-}
-
 func detectTerminalWidth() int {
-	if term.IsTerminal(int(os.Stdout.Fd())) {
-		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+	if xterm.IsTerminal(int(os.Stdout.Fd())) {
+		if w, _, err := xterm.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
 			return w
 		}
 	}
@@ -91,8 +184,8 @@ func detectTerminalWidth() int {
 }
 
 func detectTerminalHeight() int {
-	if term.IsTerminal(int(os.Stdout.Fd())) {
-		if _, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil && h > 0 {
+	if xterm.IsTerminal(int(os.Stdout.Fd())) {
+		if _, h, err := xterm.GetSize(int(os.Stdout.Fd())); err == nil && h > 0 {
 			return h
 		}
 	}
@@ -118,81 +211,24 @@ func parsePositiveInt(s string) (int, error) {
 	return n, nil
 }
 
-// detectFileType returns the type key for a file path based on extension
-func detectFileType(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	for key, ft := range fileTypes {
-		for _, e := range ft.extensions {
-			if ext == e {
-				return key
-			}
-		}
-	}
-	return ""
-}
-
-// detectParser selects the appropriate parser based on file extension
-func detectParser(filePath string) Parser {
-	parsers := []Parser{
-		&TodoParser{},
-		&DiffParser{},
-		&CsvParser{},
-		&ContractParser{},
-		&MarkdownParser{},
-		&JSONLParser{},
-		&TxtParser{},
-	}
-
-	for _, parser := range parsers {
-		if parser.Detect(filePath) {
-			return parser
-		}
+// isTypeArg reports whether a first argument could name a type: it isn't a
+// flag or an existing file. Looking up other names would load plugins.
+func isTypeArg(arg string) bool {
+	if strings.HasPrefix(arg, "-") {
+		return false
 	}
-
-	return &MarkdownParser{}
+	_, err := os.Stat(arg)
+	return err != nil
 }
 
-// detectParserFromContent tries to detect parser type from content (for stdin)
-func detectParserFromContent(content string) Parser {
-	if DetectBlockContentType(content) == BlockContentDiff {
-		return &DiffParser{}
-	}
-
-	// Count JSON lines to distinguish JSONL from single JSON
-	lines := strings.Split(content, "\n")
-	jsonLineCount := 0
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		var testJSON map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &testJSON); err == nil {
-			jsonLineCount++
-		} else {
-			break
+// resolveSpec returns the forced type's spec, or detects one from the file
+func resolveSpec(filePath string, forceType string) *parse.ParserSpec {
+	if forceType != "" {
+		if spec := parse.LookupParser(forceType); spec != nil {
+			return spec
 		}
 	}
-	if jsonLineCount >= 2 {
-		return &JSONLParser{}
-	}
-
-	// JSON object/array -> plain text (preserves structure)
-	if isJSON(content) {
-		return &TxtParser{}
-	}
-
-	// YAML -> plain text (preserves structure)
-	if isYAML(content) {
-		return &TxtParser{}
-	}
-
-	// CSV -> table
-	if isCSV(content) {
-		return &CsvParser{}
-	}
-
-	return &MarkdownParser{}
+	return parse.DetectFileSpec(filePath)
 }
 
 func hasStdinData() bool {
@@ -203,29 +239,9 @@ func hasStdinData() bool {
 	return (stat.Mode() & os.ModeCharDevice) == 0
 }
 
-func readStdin() (string, error) {
-	reader := bufio.NewReader(os.Stdin)
-	var builder strings.Builder
-	for {
-		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			if line != "" {
-				builder.WriteString(line)
-			}
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-		builder.WriteString(line)
-	}
-	return builder.String(), nil
-}
-
-func showContentSelector(content string) map[string]bool {
-	types := ScanContentTypes(content)
-	if len(types) == 0 {
-		return map[string]bool{"user": true, "assistant": true}
+func showContentSelector(types []parse.ContentType) map[string]bool {
+	if len(types) == 0 || jsonlFilters != nil {
+		return contentFilters(types)
 	}
 
 	fmt.Println("Scanning transcript...")
@@ -282,12 +298,23 @@ func showContentSelector(content string) map[string]bool {
 		fmt.Print("> ")
 	}
 
+	fmt.Println()
+	return contentFilters(types)
+}
+
+// contentFilters returns the configured content types, the enabled content
+// types of a transcript, or user and assistant messages if none were found
+func contentFilters(types []parse.ContentType) map[string]bool {
+	if jsonlFilters != nil {
+		return jsonlFilters
+	}
+	if len(types) == 0 {
+		return map[string]bool{"user": true, "assistant": true}
+	}
 	filters := make(map[string]bool)
 	for _, ct := range types {
 		filters[ct.Name] = ct.Enabled
 	}
-
-	fmt.Println()
 	return filters
 }
 
@@ -332,58 +359,50 @@ func viewFile(filePath string) {
 	info, err := os.Stat(filePath)
 	if err == nil && info.IsDir() {
 		if servePort > 0 {
-			serveDirectory(filePath, servePort)
+			serve.Directory(filePath, servePort, indexColumns...)
 		} else {
 			listDirectory(filePath)
 		}
 		return
 	}
 
-	ft := detectFileType(filePath)
-
-	if ft == "img" {
-		AddRecent(filePath)
-		if exportHTML || servePort > 0 {
-			parser := &ImageParser{}
-			blocks, err := parser.ParseFile(filePath, exportHTML)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			if exportHTML {
-				title := filepath.Base(filePath)
-				outputHTML(RenderStaticHTMLPage(title, blocks, false))
-				return
-			}
-			serveHTML(filePath, blocks, servePort)
-			return
-		}
-		viewImage(filePath)
+	spec := resolveSpec(filePath, forceType)
+	if spec.Binary {
+		viewBinaryFile(spec, filePath)
 		return
 	}
 
-	if ft == "vid" {
-		AddRecent(filePath)
-		if exportHTML || servePort > 0 {
-			parser := &VideoParser{}
-			blocks, err := parser.ParseFile(filePath, exportHTML)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			if exportHTML {
-				title := filepath.Base(filePath)
-				outputHTML(RenderStaticHTMLPage(title, blocks, false))
-				return
-			}
-			serveHTML(filePath, blocks, servePort)
-			return
-		}
-		viewVideo(filePath)
+	viewTextFile(filePath, forceType, false)
+}
+
+// binaryViewers render binary types directly to the terminal
+var binaryViewers = map[string]func(string){
+	"img": viewImage,
+	"vid": viewVideo,
+}
+
+// viewBinaryFile shows an image or video in the terminal, or as HTML in export/serve mode
+func viewBinaryFile(spec *parse.ParserSpec, filePath string) {
+	AddRecent(filePath)
+	if !exportHTML && servePort == 0 {
+		binaryViewers[spec.Name](filePath)
 		return
 	}
-
-	viewTextFile(filePath, forceType, false)
+	parser, ok := spec.New().(parse.FileParser)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: %s parser cannot read files\n", spec.Name)
+		os.Exit(1)
+	}
+	blocks, err := parser.ParseFile(filePath, exportHTML)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if exportHTML {
+		outputHTML(html.RenderStaticHTMLPage(filepath.Base(filePath), blocks, false))
+		return
+	}
+	serve.File(filePath, blocks, servePort, showLineNumbers)
 }
 
 // listDirectory prints a formatted table of markdown files in a directory
@@ -398,18 +417,24 @@ func listDirectory(dirPath string) {
 		title   string
 		created string
 		tags    string
+		fields  []string
 		path    string
 	}
 
 	var entries []entry
 	maxTitle := 5 // "TITLE"
+	maxTags := 4  // "TAGS"
+	widths := make([]int, len(indexColumns))
+	for i, col := range indexColumns {
+		widths[i] = len(col)
+	}
 
 	for _, f := range files {
 		content, err := os.ReadFile(f)
 		if err != nil {
 			continue
 		}
-		fm, _ := ParseFrontmatter(string(content))
+		fm, _ := parse.ParseFrontmatter(string(content))
 		title := filepath.Base(f)
 		if fm.Title != "" {
 			title = fm.Title
@@ -422,7 +447,15 @@ func listDirectory(dirPath string) {
 		if len(title) > maxTitle {
 			maxTitle = len(title)
 		}
-		entries = append(entries, entry{title: title, created: created, tags: tags, path: f})
+		if len(tags) > maxTags {
+			maxTags = len(tags)
+		}
+		fields := make([]string, len(indexColumns))
+		for i, col := range indexColumns {
+			fields[i] = fm.Field(col)
+			widths[i] = max(widths[i], len(fields[i]))
+		}
+		entries = append(entries, entry{title: title, created: created, tags: tags, fields: fields, path: f})
 	}
 
 	// Sort by created desc
@@ -435,18 +468,53 @@ func listDirectory(dirPath string) {
 	}
 
 	// Print header
-	fmt.Printf("%-*s  %-12s  %s\n", maxTitle, "TITLE", "CREATED", "TAGS")
-	fmt.Printf("%-*s  %-12s  %s\n", maxTitle, strings.Repeat("-", maxTitle), "------------", "----")
+	header := []string{"TITLE", "CREATED", "TAGS"}
+	rule := []string{strings.Repeat("-", maxTitle), "------------", "----"}
+	colWidths := []int{maxTitle, 12, maxTags}
+	for i, col := range indexColumns {
+		header = append(header, strings.ToUpper(col))
+		rule = append(rule, strings.Repeat("-", len(col)))
+		colWidths = append(colWidths, widths[i])
+	}
+	printRow := func(cells []string) {
+		var line strings.Builder
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				line.WriteString(cell)
+			} else {
+				fmt.Fprintf(&line, "%-*s  ", colWidths[i], cell)
+			}
+		}
+		fmt.Println(line.String())
+	}
+	printRow(header)
+	printRow(rule)
 
 	for _, e := range entries {
-		fmt.Printf("%-*s  %-12s  %s\n", maxTitle, e.title, e.created, e.tags)
+		printRow(append([]string{e.title, e.created, e.tags}, e.fields...))
 	}
 }
 
+// maxFileSize caps files read fully into memory. Streamed terminal views
+// read larger files too, keeping only their last lines.
 const maxFileSize = 100 * 1024 * 1024 // 100MB
 
+// stdinSampleSize is how much stdin is read ahead to detect its type
+const stdinSampleSize = 64 * 1024
+
+// jsonlScanSize is how much of a streamed transcript is scanned for the
+// content types to offer
+const jsonlScanSize = 4 * 1024 * 1024
+
 // viewTextFile reads a file and renders it in the TUI
 func viewTextFile(filePath string, forceType string, follow bool) {
+	if !follow && !exportHTML && servePort == 0 {
+		spec := resolveSpec(filePath, forceType)
+		if parser, ok := spec.New().(parse.StreamParser); ok {
+			viewTextStream(filePath, parser)
+			return
+		}
+	}
 	if stat, err := os.Stat(filePath); err == nil && stat.Size() > maxFileSize {
 		fmt.Fprintf(os.Stderr, "Error: file too large (%d bytes, max %d)\n", stat.Size(), maxFileSize)
 		os.Exit(1)
@@ -461,178 +529,283 @@ func viewTextFile(filePath string, forceType string, follow bool) {
 
 	AddRecent(filePath)
 
-	var parser Parser
-	var isJSONL bool
-
-	if forceType != "" {
-		switch forceType {
-		case "md":
-			parser = &MarkdownParser{}
-		case "jsonl":
-			parser = &JSONLParser{}
-			isJSONL = true
-		case "diff":
-			parser = &DiffParser{}
-		case "json":
-			parser = &TodoParser{}
-		case "txt":
-			parser = &TxtParser{}
-		case "yaml":
-			parser = &TxtParser{}
-		case "csv":
-			parser = &CsvParser{}
-		}
-	} else {
-		parser = detectParser(filePath)
-		_, isJSONL = parser.(*JSONLParser)
-	}
+	spec := resolveSpec(filePath, forceType)
+	parser := spec.New()
+	isJSONL := spec.Name == "jsonl"
 
 	if follow && servePort == 0 {
-		runFollowMode(filePath, fileContent, isJSONL, termWidth, "auto", BorderNone)
+		var filters map[string]bool
+		if isJSONL {
+			filters = showContentSelector(parse.ScanContentTypes(fileContent))
+		}
+		tui.Follow(filePath, fileContent, filters, termWidth, "auto", borderStyle)
 		return
 	}
 
-	_, isContract := parser.(*ContractParser)
-
 	// Static HTML export
 	if exportHTML {
-		isCSVType := forceType == "csv" || detectFileType(filePath) == "csv"
-		var blocks []Block
-		if isJSONL || isCSVType || isContract {
-			blocks = parser.Parse(fileContent)
-		} else {
-			contentType := BlockContentPlain
-			if forceType == "json" || detectFileType(filePath) == "json" {
-				contentType = BlockContentJSON
-			} else if forceType == "yaml" || detectFileType(filePath) == "yaml" {
-				contentType = BlockContentYAML
-			}
-			blocks = []Block{{
-				Name:        filepath.Base(filePath),
-				Content:     fileContent,
-				Pages:       []string{fileContent},
-				TotalPages:  1,
-				ContentType: contentType,
-			}}
-		}
-		title := filepath.Base(filePath)
-		fm, body := ParseFrontmatter(fileContent)
-		if fm.Title != "" {
-			title = fm.Title
-		}
-		if !isJSONL && !isCSVType && !isContract {
-			blocks[0].Content = body
-			blocks[0].Pages = []string{body}
+		doc, err := render.Render(context.Background(), bytes.NewReader(content), render.Options{
+			Type:        spec.Name,
+			Name:        filePath,
+			Format:      render.FormatStaticHTML,
+			LineNumbers: showLineNumbers,
+			Bundle:      true,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-		outputHTML(RenderStaticHTMLPage(title, blocks, showLineNumbers))
+		outputHTML(doc.Output)
+		printDiagnostics(filePath, doc.Diagnostics)
+		checkStrict(filePath, doc.Diagnostics)
 		return
 	}
 
 	// For --port mode, render as single block so HTML formatter handles headings natively
 	if servePort > 0 {
-		isCSVType := forceType == "csv" || detectFileType(filePath) == "csv"
-		var blocks []Block
+		var blocks []parse.Block
+		var diags []parse.Diagnostic
 		if isJSONL {
-			jsonlParser := &JSONLParser{}
-			filters := showContentSelector(fileContent)
+			jsonlParser := &parse.JSONLParser{}
+			filters := showContentSelector(parse.ScanContentTypes(fileContent))
 			jsonlParser.Filters = filters
-			blocks = jsonlParser.Parse(fileContent)
-		} else if isCSVType || isContract {
-			blocks = parser.Parse(fileContent)
+			blocks, diags = parse.ParseWithDiagnostics(jsonlParser, fileContent)
+		} else if spec.Structured {
+			blocks, diags = parse.ParseWithDiagnostics(parser, fileContent)
 		} else {
 			// Single block: let HTML render h1/h2/h3 directly instead of splitting by headers
-			contentType := BlockContentPlain
-			if forceType == "json" || detectFileType(filePath) == "json" {
-				contentType = BlockContentJSON
-			} else if forceType == "yaml" || detectFileType(filePath) == "yaml" {
-				contentType = BlockContentYAML
-			}
-			blocks = []Block{{
+			blocks = []parse.Block{{
 				Name:        filepath.Base(filePath),
 				Content:     fileContent,
 				Pages:       []string{fileContent},
 				TotalPages:  1,
-				ContentType: contentType,
+				ContentType: spec.RawType,
 			}}
+			diags = parse.Diagnose(parser, fileContent)
 		}
-		serveHTML(filePath, blocks, servePort)
+		printDiagnostics(filePath, diags)
+		checkStrict(filePath, diags)
+		serve.File(filePath, blocks, servePort, showLineNumbers, diags...)
 		return
 	}
 
-	var blocks []Block
+	var filters map[string]bool
 	if isJSONL {
-		jsonlParser := &JSONLParser{}
-		filters := showContentSelector(fileContent)
+		filters = showContentSelector(parse.ScanContentTypes(fileContent))
+	}
+	blocks, diags := parseDocument(spec, fileContent, filters)
+
+	doc := &tui.Document{Name: filePath, Blocks: blocks, Diags: diags}
+	resumeDocument(doc, content)
+	tui.Run([]*tui.Document{doc}, openDocument, termWidth, "auto", borderStyle, showLineNumbers)
+	checkStrict(filePath, diags)
+}
+
+// parseDocument parses content for the reader: markdown flows continuously
+// in pages sized to the terminal, other types split into their blocks.
+// filters picks the content types shown from transcripts.
+func parseDocument(spec *parse.ParserSpec, content string, filters map[string]bool) ([]parse.Block, []parse.Diagnostic) {
+	parser := spec.New()
+	if jsonlParser, ok := parser.(*parse.JSONLParser); ok {
 		jsonlParser.Filters = filters
-		blocks = jsonlParser.Parse(fileContent)
-	} else if mdParser, ok := parser.(*MarkdownParser); ok {
-		termHeight := detectTerminalHeight()
-		blocks = mdParser.ParseContinuous(fileContent, termHeight)
-	} else {
-		blocks = parser.Parse(fileContent)
+	}
+	if mdParser, ok := parser.(*parse.MarkdownParser); ok {
+		return mdParser.ParseContinuous(content, detectTerminalHeight()), nil
+	}
+	return parse.ParseWithDiagnostics(parser, content)
+}
+
+// loadDocument reads and parses a text file for a reader buffer, detecting
+// its type unless forceType is set. Transcripts show their default content
+// types rather than asking.
+func loadDocument(filePath string, forceType string) (*tui.Document, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not find %s", filePath)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", filePath)
+	}
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("file too large (%d bytes, max %d)", info.Size(), maxFileSize)
+	}
+	spec := resolveSpec(filePath, forceType)
+	if spec.Binary {
+		return nil, fmt.Errorf("%s: %s can't be shown in the reader", filePath, strings.ToLower(spec.Label))
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	AddRecent(filePath)
+
+	var filters map[string]bool
+	if spec.Name == "jsonl" {
+		filters = contentFilters(parse.ScanContentTypes(string(content)))
+	}
+	blocks, diags := parseDocument(spec, string(content), filters)
+	doc := &tui.Document{Name: filePath, Blocks: blocks, Diags: diags}
+	resumeDocument(doc, content)
+	return doc, nil
+}
+
+// openDocument loads a file opened from within the reader, from a link or
+// the : prompt
+func openDocument(path string) (*tui.Document, error) {
+	return loadDocument(expandPath(path), "")
+}
+
+// viewFiles opens several files in the reader, one buffer each, or in the
+// browser, one page each.
+func viewFiles(paths []string) {
+	if err := multiFileError(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if shareFlag {
+		for _, path := range paths {
+			viewFile(expandPath(path))
+		}
+		return
+	}
+	var docs []*tui.Document
+	for _, path := range paths {
+		doc, err := loadDocument(expandPath(path), forceType)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		docs = append(docs, doc)
+	}
+	tui.Run(docs, openDocument, detectTerminalWidth(), "auto", borderStyle, showLineNumbers)
+	for _, doc := range docs {
+		checkStrict(doc.Name, doc.Diags)
+	}
+}
+
+// multiFileError reports when the output mode takes a single file: --port
+// serves one page and --html writes one document to stdout. Opening in the
+// browser or the reader takes several.
+func multiFileError() error {
+	switch {
+	case servePort > 0:
+		return fmt.Errorf("--port serves a single file; serve a directory to browse several, or use -t to read them")
+	case exportHTML && !shareFlag:
+		return fmt.Errorf("--html exports a single file; export each file on its own, or use -t to read them")
+	}
+	return nil
+}
+
+// viewTextStream renders a file in the TUI while it is parsed, without
+// reading it into memory
+func viewTextStream(filePath string, parser parse.StreamParser) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not find %s\n", filePath)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	AddRecent(filePath)
+
+	if jsonlParser, ok := parser.(*parse.JSONLParser); ok && jsonlFilters != nil {
+		jsonlParser.Filters = jsonlFilters
+	} else if ok {
+		// Offer the content types found near the start, so the selector
+		// doesn't wait for the whole file
+		types, err := parse.ScanContentTypesReader(io.LimitReader(f, jsonlScanSize))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		jsonlParser.Filters = showContentSelector(types)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The file is known by the hash of its start, as reading all of it
+	// would wait for the whole file
+	doc := &tui.Document{Name: filePath}
+	if info, err := f.Stat(); err == nil && info.Size() > maxFileSize {
+		doc.Unbounded = true
+	}
+	head := make([]byte, streamHashSize)
+	n, _ := f.ReadAt(head, 0)
+	resumeDocument(doc, head[:n])
+
+	diags := tui.RunStream(doc, detectTerminalWidth(), "auto", borderStyle, func(ctx context.Context, emit func(parse.Block)) ([]parse.Diagnostic, error) {
+		err := parser.ParseStream(ctx, f, emit)
+		return parse.DiagnosticsOf(parser), err
+	})
+	checkStrict(filePath, diags)
+}
+
+// viewStdin renders piped input. Streamable types are shown in the terminal
+// as they arrive; everything else is read fully first.
+func viewStdin(forceType string) {
+	var in io.Reader = os.Stdin
+
+	if !exportHTML && servePort == 0 {
+		// Detect the type from the start of the input
+		spec := parse.LookupParser(forceType)
+		if spec == nil || spec.Binary {
+			var sample []byte
+			sample, in = sampleInput(os.Stdin)
+			spec = parse.DetectContentSpec(string(sample))
+		}
+		if parser, ok := spec.New().(parse.StreamParser); ok {
+			diags := tui.RunStream(&tui.Document{Name: "stdin", Unbounded: true}, detectTerminalWidth(), "auto", borderStyle, func(ctx context.Context, emit func(parse.Block)) ([]parse.Diagnostic, error) {
+				err := parser.ParseStream(ctx, in, emit)
+				return parse.DiagnosticsOf(parser), err
+			})
+			checkStrict("stdin", diags)
+			return
+		}
 	}
 
-	runReaderMode(blocks, filePath, termWidth, "auto", BorderNone)
+	content, err := io.ReadAll(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	viewStdinContent(string(content), forceType)
 }
 
 // viewStdinContent renders stdin content
 func viewStdinContent(content string, forceType string) {
 	termWidth := detectTerminalWidth()
 
-	var parser Parser
-	var isJSONL bool
-
-	if forceType != "" {
-		switch forceType {
-		case "md":
-			parser = &MarkdownParser{}
-		case "jsonl":
-			parser = &JSONLParser{}
-			isJSONL = true
-		case "diff":
-			parser = &DiffParser{}
-		case "json":
-			parser = &TodoParser{}
-		case "txt":
-			parser = &TxtParser{}
-		case "yaml":
-			parser = &TxtParser{}
-		case "csv":
-			parser = &CsvParser{}
-		default:
-			parser = &MarkdownParser{}
-		}
-	} else {
-		parser = detectParserFromContent(content)
-		_, isJSONL = parser.(*JSONLParser)
+	spec := parse.LookupParser(forceType)
+	if spec == nil || spec.Binary {
+		spec = parse.DetectContentSpec(content)
 	}
 
-	var blocks []Block
-	if isJSONL {
-		jsonlParser := &JSONLParser{}
-		filters := showContentSelector(content)
-		jsonlParser.Filters = filters
-		blocks = jsonlParser.Parse(content)
-	} else if mdParser, ok := parser.(*MarkdownParser); ok {
-		termHeight := detectTerminalHeight()
-		blocks = mdParser.ParseContinuous(content, termHeight)
-	} else {
-		blocks = parser.Parse(content)
+	var filters map[string]bool
+	if spec.Name == "jsonl" {
+		filters = showContentSelector(parse.ScanContentTypes(content))
 	}
+	blocks, diags := parseDocument(spec, content, filters)
 
 	// Static HTML export
 	if exportHTML {
-		outputHTML(RenderStaticHTMLPage("stdin", blocks, showLineNumbers))
+		outputHTML(html.RenderStaticHTMLPage("stdin", blocks, showLineNumbers, diags...))
+		printDiagnostics("stdin", diags)
+		checkStrict("stdin", diags)
 		return
 	}
 
 	// Web mode: serve as HTML
 	if servePort > 0 {
-		serveHTML("stdin", blocks, servePort)
+		printDiagnostics("stdin", diags)
+		checkStrict("stdin", diags)
+		serve.File("stdin", blocks, servePort, showLineNumbers, diags...)
 		return
 	}
 
-	runReaderMode(blocks, "stdin", termWidth, "auto", BorderNone)
+	tui.Run([]*tui.Document{{Name: "stdin", Blocks: blocks, Diags: diags}}, openDocument, termWidth, "auto", borderStyle, showLineNumbers)
+	checkStrict("stdin", diags)
 }
 
 func printUsage() {
@@ -643,31 +816,56 @@ func printUsage() {
 	fmt.Fprintln(w, "Usage:")
 	fmt.Fprintln(w, "  aster <file>          Open file in browser (default)")
 	fmt.Fprintln(w, "  aster <file> -t       View file in terminal")
+	fmt.Fprintln(w, "  aster <file>... -t    View several files as buffers")
 	fmt.Fprintln(w, "  aster pick            Pick from recent files")
 	fmt.Fprintln(w, "  aster latest          Open newest file in current directory")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Flags:")
 	fmt.Fprintln(w, "  -t                    Render in terminal instead of browser")
-	fmt.Fprintln(w, "  -t TYPE               Force content type (md, json, jsonl, diff, txt, yaml, csv)")
+	fmt.Fprintf(w, "  -t TYPE               Force content type (%s)\n", strings.Join(parse.TypeNames(), ", "))
 	fmt.Fprintln(w, "  -n                    Show source file line numbers")
 	fmt.Fprintln(w, "  --port N              Serve rendered HTML on localhost:N")
 	fmt.Fprintln(w, "  --html                Export self-contained HTML to stdout")
+	fmt.Fprintln(w, "  --strict              Exit with status 1 if parsing reports errors")
+	fmt.Fprintln(w, "  --columns a,b.c       Show frontmatter fields when listing a directory")
+	fmt.Fprintln(w, "  --css FILE            Add a stylesheet (file or URL) to HTML pages; repeatable")
+	fmt.Fprintln(w, "  --html-theme NAME     HTML color scheme: auto (follow the system), light, dark")
+	fmt.Fprintln(w, "  --theme NAME          Terminal colors: auto, dark, light, high-contrast ($ASTER_THEME)")
+	fmt.Fprintln(w, "  --code-theme NAME     Terminal code colors: dark, light, plain ($ASTER_CODE_THEME)")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Supported formats:")
-	fmt.Fprintln(w, "  Markdown        .md .markdown")
-	fmt.Fprintln(w, "  Plain text      .txt .log")
-	fmt.Fprintln(w, "  Unified diffs   .diff .patch")
-	fmt.Fprintln(w, "  JSON            .json")
-	fmt.Fprintln(w, "  Transcripts     .jsonl")
-	fmt.Fprintln(w, "  CSV/TSV         .csv .tsv")
-	fmt.Fprintln(w, "  Images          .png .jpg .gif .webp .bmp .svg")
-	fmt.Fprintln(w, "  Video           .mp4 .webm .mov .mkv")
+	for _, spec := range parse.RegisteredParsers() {
+		formats := spec.Detail
+		if formats == "" {
+			formats = strings.Join(spec.Extensions, " ")
+		}
+		fmt.Fprintf(w, "  %-15s %s\n", spec.Label, formats)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  Plugins: executables named aster-parser-<type> in ~/.aster/plugins or on PATH.")
+	if names := parse.PluginNames(); len(names) > 0 {
+		fmt.Fprintf(w, "  Installed: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  Config: ~/.config/aster/config.toml, then the nearest .aster.toml (output mode,")
+	fmt.Fprintln(w, "  border, key bindings, JSONL filters, extension associations, serve defaults).")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Navigation:")
 	fmt.Fprintln(w, "  j / k             Scroll down / up")
 	fmt.Fprintln(w, "  d / u             Half-page down / up")
 	fmt.Fprintln(w, "  g / G             Top / bottom")
 	fmt.Fprintln(w, "  PgDn / PgUp       Full page down / up")
+	fmt.Fprintln(w, "  h / l  0 / $      Scroll left / right, to line start / end")
+	fmt.Fprintln(w, "  w                 Toggle wrapping of wide tables and code")
+	fmt.Fprintln(w, "  / or ?            Search forward / backward (regex, smart case)")
+	fmt.Fprintln(w, "  n / N             Next / previous match")
+	fmt.Fprintln(w, "  m{a-z} / '{a-z}   Set / jump to a bookmark (kept per file)")
+	fmt.Fprintln(w, "  o                 Outline of headings (Enter jumps)")
+	fmt.Fprintln(w, "  :                 Go to heading (fuzzy) or run a command")
+	fmt.Fprintln(w, "  gt / gT           Next / previous buffer")
+	fmt.Fprintln(w, "  :b N  :bn  :bp    Switch to buffer N / next / previous")
+	fmt.Fprintln(w, "  :e FILE           Open FILE in a new buffer")
+	fmt.Fprintln(w, "  Tab / Enter       Select / open links on screen")
 	fmt.Fprintln(w, "  q                 Quit")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Piping:")
@@ -684,22 +882,71 @@ func printUsage() {
 	fmt.Fprintln(w, "  aster latest                  Open the newest file in cwd")
 	fmt.Fprintln(w, "  aster file.md --port 3000     Serve rendered HTML on localhost")
 	fmt.Fprintln(w)
-	fmt.Fprintln(w, "  Images require chafa (brew install chafa).")
+	fmt.Fprintln(w, "  Images use kitty, iTerm2 or sixel graphics when the terminal supports")
+	fmt.Fprintln(w, "  them, otherwise colored Unicode blocks (ASTER_IMAGE_PROTOCOL=kitty|iterm|")
+	fmt.Fprintln(w, "  sixel|blocks|braille overrides).")
 	fmt.Fprintln(w)
 }
 
 func main() {
+	// External parsers are registered when a type or file needs them
+	parse.EnablePlugins()
+
+	cfg, err := loadConfig(configPaths())
+	if err == nil {
+		err = cfg.apply()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.Theme != "" {
+		themeName = cfg.Theme
+	}
+	if name := os.Getenv("ASTER_THEME"); name != "" {
+		themeName = name
+	}
+	if name := os.Getenv("ASTER_CODE_THEME"); name != "" {
+		setCodeTheme(name)
+	}
+
 	// Parse flags early (before other arg processing)
 	var cleanArgs []string
 	args := os.Args[1:]
-	validTypes := map[string]bool{"md": true, "json": true, "jsonl": true, "diff": true, "txt": true, "yaml": true, "csv": true}
 	for i := 0; i < len(args); i++ {
 		if args[i] == "-n" {
 			showLineNumbers = true
 		} else if args[i] == "--html" {
 			exportHTML = true
+		} else if args[i] == "--strict" {
+			strictMode = true
 		} else if args[i] == "--share" {
 			shareFlag = true
+		} else if args[i] == "--columns" && i+1 < len(args) {
+			for _, col := range strings.Split(args[i+1], ",") {
+				if col = strings.TrimSpace(col); col != "" {
+					indexColumns = append(indexColumns, col)
+				}
+			}
+			i++ // skip the column list
+		} else if args[i] == "--code-theme" && i+1 < len(args) {
+			setCodeTheme(args[i+1])
+			i++ // skip the theme name
+		} else if args[i] == "--css" && i+1 < len(args) {
+			cssFiles = append(cssFiles, args[i+1])
+			i++ // skip the stylesheet
+		} else if args[i] == "--html-theme" && i+1 < len(args) {
+			t, ok := html.ParsePageTheme(args[i+1])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: --html-theme must be auto, light or dark\n")
+				os.Exit(1)
+			}
+			html.SetPageTheme(t)
+			i++ // skip the theme name
+		} else if args[i] == "--theme" && i+1 < len(args) {
+			themeName = args[i+1]
+			i++ // skip the theme name
 		} else if args[i] == "--port" && i+1 < len(args) {
 			if p, err := parsePositiveInt(args[i+1]); err == nil {
 				servePort = p
@@ -709,8 +956,8 @@ func main() {
 			}
 			i++ // skip the port number
 		} else if args[i] == "-t" {
-			if i+1 < len(args) && validTypes[args[i+1]] {
-				forceType = args[i+1]
+			if spec := forceTypeArg(args, i+1); spec != nil {
+				forceType = spec.Name
 				i++ // skip the type value
 			} else {
 				terminalFlag = true
@@ -725,10 +972,30 @@ func main() {
 		exportHTML = true
 	}
 
-	// Default to browser if no output mode specified and not terminal mode
+	if indexColumns == nil {
+		indexColumns = cfg.ServeColumns
+	}
+	if cssFiles == nil {
+		cssFiles = cfg.CSS
+	}
+
+	// Default to the configured output mode, or the browser, if no output
+	// mode specified and not terminal mode
 	if !terminalFlag && !exportHTML && !shareFlag && servePort == 0 {
-		shareFlag = true
-		exportHTML = true
+		switch cfg.Output {
+		case "terminal":
+		case "serve":
+			servePort = cfg.ServePort
+		default:
+			shareFlag = true
+			exportHTML = true
+		}
+	}
+
+	if !exportHTML && servePort == 0 {
+		applyTheme(cfg)
+	} else {
+		addStylesheets()
 	}
 
 	// Check for subcommand or shortcut as first arg
@@ -766,10 +1033,12 @@ func main() {
 		}
 
 		// Subcommand mode: aster <type> [file|-|+]
-		if ft, ok := fileTypes[first]; ok {
-			TrackUsage(first)
-			runSubcommand(first, ft, os.Args[2:])
-			return
+		if isTypeArg(first) {
+			if spec := parse.LookupParser(first); spec != nil && spec.Name == first {
+				TrackUsage(first)
+				runSubcommand(spec, os.Args[2:])
+				return
+			}
 		}
 
 		// Hidden flag: -f <file>
@@ -779,6 +1048,13 @@ func main() {
 			return
 		}
 
+		// Several files open as buffers in one reader
+		if len(os.Args) > 2 {
+			TrackUsage("view")
+			viewFiles(os.Args[1:])
+			return
+		}
+
 		// Default: treat as file path
 		TrackUsage("view")
 		filePath := expandPath(first)
@@ -790,12 +1066,7 @@ func main() {
 	// No args: try stdin
 	TrackUsage("stdin")
 	if hasStdinData() {
-		content, err := readStdin()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		viewStdinContent(content, forceType)
+		viewStdin(forceType)
 		return
 	}
 
@@ -803,22 +1074,32 @@ func main() {
 	os.Exit(1)
 }
 
+// forceTypeArg returns the text type named by args[i], if any. An existing
+// file is never a type, so looking it up doesn't load plugins.
+func forceTypeArg(args []string, i int) *parse.ParserSpec {
+	if i >= len(args) || !isTypeArg(args[i]) {
+		return nil
+	}
+	spec := parse.LookupParser(args[i])
+	if spec == nil || spec.Binary {
+		return nil
+	}
+	return spec
+}
+
 // runSubcommand handles: aster <type> [file|-|+]
-func runSubcommand(typeName string, ft fileType, args []string) {
+func runSubcommand(spec *parse.ParserSpec, args []string) {
+	typeName := spec.Name
 	if len(args) == 0 {
 		if hasStdinData() {
-			content, err := readStdin()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			viewStdinContent(content, typeName)
+			viewStdin(typeName)
 			return
 		}
+		label := strings.ToLower(spec.Label)
 		fmt.Fprintf(os.Stderr, "Usage: aster %s [file | - | +]\n\n", typeName)
-		fmt.Fprintf(os.Stderr, "  aster %s <file>   View %s file\n", typeName, ft.name)
-		fmt.Fprintf(os.Stderr, "  aster %s -        Pick from recent %s files\n", typeName, ft.name)
-		fmt.Fprintf(os.Stderr, "  aster %s +        Open newest %s in cwd\n", typeName, ft.name)
+		fmt.Fprintf(os.Stderr, "  aster %s <file>   View %s\n", typeName, label)
+		fmt.Fprintf(os.Stderr, "  aster %s -        Pick from recent %s\n", typeName, label)
+		fmt.Fprintf(os.Stderr, "  aster %s +        Open newest in cwd\n", typeName)
 		os.Exit(1)
 	}
 
@@ -830,46 +1111,14 @@ func runSubcommand(typeName string, ft fileType, args []string) {
 		target = args[1]
 	}
 
-	filePath := resolveShortcut(target, ft.extensions)
+	filePath := resolveShortcut(target, spec.Extensions)
 	if filePath == "" {
 		fmt.Fprintf(os.Stderr, "Usage: aster %s [file | - | +]\n", typeName)
 		os.Exit(0)
 	}
 
-	if typeName == "img" {
-		AddRecent(filePath)
-		if exportHTML || servePort > 0 {
-			parser := &ImageParser{}
-			blocks, err := parser.ParseFile(filePath, exportHTML)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			if exportHTML {
-				outputHTML(RenderStaticHTMLPage(filepath.Base(filePath), blocks, false))
-			} else {
-				serveHTML(filePath, blocks, servePort)
-			}
-		} else {
-			viewImage(filePath)
-		}
-	} else if typeName == "vid" {
-		AddRecent(filePath)
-		if exportHTML || servePort > 0 {
-			parser := &VideoParser{}
-			blocks, err := parser.ParseFile(filePath, exportHTML)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			if exportHTML {
-				outputHTML(RenderStaticHTMLPage(filepath.Base(filePath), blocks, false))
-			} else {
-				serveHTML(filePath, blocks, servePort)
-			}
-		} else {
-			viewVideo(filePath)
-		}
+	if spec.Binary {
+		viewBinaryFile(spec, filePath)
 	} else {
 		viewTextFile(filePath, typeName, follow)
 	}
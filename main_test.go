@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiFileError(t *testing.T) {
+	defer func(port int, html, share bool) {
+		servePort, exportHTML, shareFlag = port, html, share
+	}(servePort, exportHTML, shareFlag)
+
+	tests := []struct {
+		port        int
+		html, share bool
+		want        string
+	}{
+		{0, false, false, ""},      // reader
+		{0, true, true, ""},        // browser, one page each
+		{0, true, false, "--html"}, // one document on stdout
+		{8080, false, false, "--port"},
+		{8080, true, true, "--port"},
+	}
+	for _, tt := range tests {
+		servePort, exportHTML, shareFlag = tt.port, tt.html, tt.share
+		err := multiFileError()
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("port %d html %v share %v: unexpected error %v", tt.port, tt.html, tt.share, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("port %d html %v share %v: error %v, want one naming %s", tt.port, tt.html, tt.share, err, tt.want)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package parse
 
 import (
 	"regexp"
@@ -56,6 +56,17 @@ func (ct BlockContentType) String() string {
 	}
 }
 
+// ParseBlockContentType returns the content type with the given String() name
+func ParseBlockContentType(name string) (BlockContentType, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for ct := BlockContentPlain; ct <= BlockContentShell; ct++ {
+		if ct.String() == name {
+			return ct, true
+		}
+	}
+	return BlockContentPlain, false
+}
+
 // DetectBlockContentType analyzes content and returns its type
 func DetectBlockContentType(content string) BlockContentType {
 	// Check for diff/patch format
@@ -186,3 +197,36 @@ func isYAML(content string) bool {
 	// Significant portion should match YAML patterns
 	return len(lines) > 2 && yamlPatterns > len(lines)/2
 }
+
+// IsTableLine checks if a line is part of a markdown table
+func IsTableLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	// Must have pipes and at least 2 cells
+	return strings.Contains(trimmed, "|") && strings.Count(trimmed, "|") >= 2
+}
+
+// IsTableSeparator checks if a line is a table separator (|---|---|)
+func IsTableSeparator(line string) bool {
+	for _, ch := range line {
+		if ch != '-' && ch != ':' && ch != '|' && ch != ' ' {
+			return false
+		}
+	}
+	return strings.Contains(line, "-")
+}
+
+// ParseTableCells extracts cells from a table row
+func ParseTableCells(line string) []string {
+	trimmed := strings.TrimSpace(line)
+	trimmed = strings.Trim(trimmed, "|")
+
+	parts := strings.Split(trimmed, "|")
+	var cells []string
+	for _, p := range parts {
+		cells = append(cells, strings.TrimSpace(p))
+	}
+	return cells
+}
@@ -1,4 +1,4 @@
-package main
+package parse
 
 // ImageData holds payload for BlockContentImage blocks
 type ImageData struct {
@@ -28,7 +28,7 @@ type TranscriptData struct {
 // ContractData holds payload for BlockContentContract blocks
 type ContractData struct {
 	Preamble  string
-	Clauses   []contractClause
+	Clauses   []ContractClause
 	Parties   string
 	Effective string
 }
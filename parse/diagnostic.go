@@ -0,0 +1,177 @@
+package parse
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity ranks a Diagnostic
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the lowercase severity name
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// Diagnostic is a problem found while parsing. Line and Column are 1-based;
+// zero means the position is unknown.
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+// Position returns "line:col", "line", or "" when the position is unknown
+func (d Diagnostic) Position() string {
+	switch {
+	case d.Line > 0 && d.Column > 0:
+		return fmt.Sprintf("%d:%d", d.Line, d.Column)
+	case d.Line > 0:
+		return fmt.Sprintf("%d", d.Line)
+	default:
+		return ""
+	}
+}
+
+// String formats the diagnostic as "line:col: severity: message"
+func (d Diagnostic) String() string {
+	if pos := d.Position(); pos != "" {
+		return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+// Format prefixes the diagnostic with its source, as in "notes.csv:3:14: error: message"
+func (d Diagnostic) Format(source string) string {
+	switch {
+	case source == "":
+		return d.String()
+	case d.Position() == "":
+		return source + ": " + d.String()
+	default:
+		return source + ":" + d.String()
+	}
+}
+
+// DiagnosticParser is implemented by parsers that report problems found by
+// their last Parse or ParseStream call
+type DiagnosticParser interface {
+	Parser
+	Diagnostics() []Diagnostic
+}
+
+// DiagnosticsOf returns the diagnostics from p's last parse, or nil if p
+// doesn't report any
+func DiagnosticsOf(p Parser) []Diagnostic {
+	if dp, ok := p.(DiagnosticParser); ok {
+		return dp.Diagnostics()
+	}
+	return nil
+}
+
+// ParseWithDiagnostics parses content and returns the blocks with any
+// diagnostics the parser reported
+func ParseWithDiagnostics(p Parser, content string) ([]Block, []Diagnostic) {
+	blocks := p.Parse(content)
+	return blocks, DiagnosticsOf(p)
+}
+
+// Diagnose parses content only for its diagnostics. Parsers that don't
+// report diagnostics are not run.
+func Diagnose(p Parser, content string) []Diagnostic {
+	if _, ok := p.(DiagnosticParser); !ok {
+		return nil
+	}
+	_, diags := ParseWithDiagnostics(p, content)
+	return diags
+}
+
+// HasErrors reports whether any diagnostic is an error
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// SummarizeDiagnostics returns counts like "2 errors, 1 warning"
+func SummarizeDiagnostics(diags []Diagnostic) string {
+	counts := map[Severity]int{}
+	for _, d := range diags {
+		counts[d.Severity]++
+	}
+	var parts []string
+	for _, s := range []Severity{SeverityError, SeverityWarning, SeverityInfo} {
+		n := counts[s]
+		if n == 0 {
+			continue
+		}
+		name := s.String()
+		if n != 1 && s != SeverityInfo {
+			name += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", n, name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// jsonDiagnostic converts a JSON decoding error to a diagnostic. line is the
+// 1-based line the JSON text starts on.
+func jsonDiagnostic(content string, line int, err error) Diagnostic {
+	d := Diagnostic{Line: line, Severity: SeverityError, Message: "invalid JSON: " + err.Error()}
+
+	var offset int64 = -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	if offset >= 0 {
+		if offset > int64(len(content)) {
+			offset = int64(len(content))
+		}
+		before := content[:offset]
+		d.Line += strings.Count(before, "\n")
+		d.Column = int(offset) - (strings.LastIndex(before, "\n") + 1)
+		if d.Column == 0 {
+			d.Column = 1
+		}
+	}
+	return d
+}
+
+// csvDiagnostic converts a CSV reader error to a diagnostic
+func csvDiagnostic(err error) Diagnostic {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Diagnostic{
+			Line:     parseErr.Line,
+			Column:   parseErr.Column,
+			Severity: SeverityError,
+			Message:  "invalid CSV: " + parseErr.Err.Error(),
+		}
+	}
+	return Diagnostic{Severity: SeverityError, Message: "invalid CSV: " + err.Error()}
+}
@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONDiagnosticPosition(t *testing.T) {
+	p := &TodoParser{}
+	blocks := p.Parse("{\"a\": 1,\n  \"b\": ]\n}")
+	if len(blocks) == 0 {
+		t.Fatal("invalid JSON should still be shown as text")
+	}
+	diags := p.Diagnostics()
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1", len(diags))
+	}
+	d := diags[0]
+	if d.Line != 2 || d.Column != 8 || d.Severity != SeverityError {
+		t.Errorf("got %s, want 2:8 error", d)
+	}
+}
+
+func TestTodoParserShowsOtherJSON(t *testing.T) {
+	p := &TodoParser{}
+	blocks := p.Parse(`{"name": "aster"}`)
+	if len(blocks) != 1 || !strings.Contains(blocks[0].Content, "aster") {
+		t.Errorf("expected JSON shown as text, got %+v", blocks)
+	}
+	if diags := p.Diagnostics(); len(diags) != 0 {
+		t.Errorf("valid JSON should not report diagnostics, got %v", diags)
+	}
+}
+
+func TestCsvParserDiagnostic(t *testing.T) {
+	p := &CsvParser{}
+	blocks, diags := ParseWithDiagnostics(p, "a,b\n1,2,3\n")
+	if len(blocks) != 1 || blocks[0].ContentType != BlockContentPlain {
+		t.Errorf("expected plain text fallback, got %+v", blocks)
+	}
+	if len(diags) != 1 || diags[0].Line != 2 || !HasErrors(diags) {
+		t.Errorf("expected an error on line 2, got %v", diags)
+	}
+}
+
+func TestJSONLParserDiagnostics(t *testing.T) {
+	content := "{\"type\":\"user\",\"message\":{\"content\":\"hi\"}}\n{bad\n"
+	_, diags := ParseWithDiagnostics(&JSONLParser{}, content)
+	if len(diags) != 1 || diags[0].Line != 2 || diags[0].Column != 2 {
+		t.Errorf("expected an error at 2:2, got %v", diags)
+	}
+}
+
+func TestDiagnosticFormat(t *testing.T) {
+	d := Diagnostic{Line: 3, Column: 14, Severity: SeverityWarning, Message: "odd"}
+	if got := d.Format("data.csv"); got != "data.csv:3:14: warning: odd" {
+		t.Errorf("Format = %q", got)
+	}
+	d = Diagnostic{Severity: SeverityError, Message: "plugin failed"}
+	if got := d.Format("x.mf"); got != "x.mf: error: plugin failed" {
+		t.Errorf("Format = %q", got)
+	}
+	diags := []Diagnostic{{Severity: SeverityError}, {Severity: SeverityError}, {Severity: SeverityWarning}}
+	if got := SummarizeDiagnostics(diags); got != "2 errors, 1 warning" {
+		t.Errorf("SummarizeDiagnostics = %q", got)
+	}
+}
@@ -1,9 +1,11 @@
-package main
+package parse
 
 import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/wildreason/reader/theme"
 )
 
 // ShellOutput represents parsed shell/tool output data
@@ -24,15 +26,8 @@ type ShellFormatter struct {
 	TruncationLimit int // Chars before truncation, default 5000
 }
 
-// Color constants for shell formatting (tview tags)
-const (
-	shellHeaderColor    = "[#179299:-:b]" // Bold teal for tool:command header
-	shellStdoutColor    = "[-]"           // Default for stdout
-	shellStderrColor    = "[#E05252]"     // Coral for stderr
-	shellFilePathColor  = "[#1e66f5]"     // Blue for file paths
-	shellTruncatedColor = "[#808080]"     // Gray for truncation notice
-	shellResetColor     = "[-]"
-)
+// shellResetColor ends a colored part of shell output (tview tag)
+const shellResetColor = "[-]"
 
 // ansiRegex matches ANSI escape sequences
 var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
@@ -68,6 +63,11 @@ func (f *ShellFormatter) formatHeader(output *ShellOutput) string {
 		return ""
 	}
 
+	// Colors from the theme (tview tags): bold tool name, file paths, counts
+	shellHeaderColor := theme.Tag(theme.Tool) + "[::b]"
+	shellFilePathColor := theme.Tag(theme.Link)
+	shellTruncatedColor := theme.Tag(theme.Muted)
+
 	var header string
 	switch output.ToolName {
 	case "Bash":
@@ -132,7 +132,7 @@ func ParseToolResult(toolUseResult map[string]interface{}) *ShellOutput {
 	}
 
 	// Skip Edit tool results (handled as diff separately)
-	if _, hasStructuredPatch := toolUseResult["structuredPatch"]; hasStructuredPatch {
+	if _, HasStructuredPatch := toolUseResult["structuredPatch"]; HasStructuredPatch {
 		return nil
 	}
 
@@ -0,0 +1,300 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Frontmatter holds the parsed YAML (---) or TOML (+++) frontmatter of a
+// document
+type Frontmatter struct {
+	Title   string
+	Created string
+	Tags    []string
+	Type    string // e.g. "contract" for Oberon-style interactive rendering
+	Format  string // "yaml" or "toml"; empty without frontmatter
+	Fields  *Map   // every field, in document order, with typed values
+	Raw     map[string]string
+	Err     error // why the frontmatter didn't parse; Fields then holds its plain "key: value" lines
+}
+
+// Map is a frontmatter table. Values are string, int64, float64, bool, nil,
+// []any or *Map; dates and times are kept as the strings written.
+type Map struct {
+	Keys   []string
+	Values map[string]any
+}
+
+// NewMap returns an empty Map
+func NewMap() *Map {
+	return &Map{Values: make(map[string]any)}
+}
+
+// Get returns the value stored under key
+func (m *Map) Get(key string) (any, bool) {
+	if m == nil {
+		return nil, false
+	}
+	v, ok := m.Values[key]
+	return v, ok
+}
+
+// Set stores a value under key, appending new keys to Keys
+func (m *Map) Set(key string, v any) {
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = v
+}
+
+// Len returns the number of keys
+func (m *Map) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.Keys)
+}
+
+// FrontmatterError reports where frontmatter failed to parse. Line is
+// 1-based within the document.
+type FrontmatterError struct {
+	Line    int
+	Message string
+}
+
+func (e *FrontmatterError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ParseFrontmatter extracts YAML frontmatter delimited by --- or TOML
+// frontmatter delimited by +++ from content.
+// Returns the parsed frontmatter and the remaining body.
+// If no frontmatter is found, returns empty Frontmatter and original content.
+func ParseFrontmatter(content string) (Frontmatter, string) {
+	format, block, body, ok := splitFrontmatter(content)
+	if !ok {
+		return Frontmatter{}, content
+	}
+
+	fm := Frontmatter{
+		Format: format,
+		Raw:    make(map[string]string),
+	}
+
+	var err error
+	sep := byte(':')
+	if format == "toml" {
+		fm.Fields, err = ParseTOML(block)
+		sep = '='
+	} else {
+		fm.Fields, err = parseYAML(block)
+	}
+	if err != nil {
+		// Keep what a line-by-line read finds, so a slip elsewhere in the
+		// frontmatter doesn't lose the title
+		var fe *FrontmatterError
+		if errors.As(err, &fe) {
+			fe.Line++ // the opening delimiter
+		}
+		fm.Err = err
+		fm.Fields = parseFlatFields(block, sep)
+	}
+
+	for _, key := range fm.Fields.Keys {
+		fm.Raw[key] = FormatValue(fm.Fields.Values[key])
+	}
+	fm.Title = fm.Raw["title"]
+	fm.Created = fm.Raw["created"]
+	fm.Type = fm.Raw["type"]
+	switch tags := fm.Fields.Values["tags"].(type) {
+	case nil:
+	case []any:
+		for _, tag := range tags {
+			if s := FormatValue(tag); s != "" {
+				fm.Tags = append(fm.Tags, s)
+			}
+		}
+	case string:
+		fm.Tags = parseBracketList(tags)
+	default:
+		fm.Tags = []string{FormatValue(tags)}
+	}
+
+	return fm, body
+}
+
+// Lookup returns the field at a dotted path such as "review.status" or
+// "authors.0"
+func (fm Frontmatter) Lookup(path string) (any, bool) {
+	var v any = fm.Fields
+	for _, part := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case *Map:
+			var ok bool
+			if v, ok = node.Get(part); !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Field returns the field at a dotted path as text, or "" if it is not set
+func (fm Frontmatter) Field(path string) string {
+	v, _ := fm.Lookup(path)
+	return FormatValue(v)
+}
+
+// FormatValue formats a frontmatter value as text: lists are joined with
+// commas and tables written as "key: value" pairs. Lists and tables inside
+// them are bracketed.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatNested(item)
+		}
+		return strings.Join(parts, ", ")
+	case *Map:
+		parts := make([]string, len(v.Keys))
+		for i, key := range v.Keys {
+			parts[i] = key + ": " + formatNested(v.Values[key])
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// formatNested formats a value inside a list or table
+func formatNested(v any) string {
+	switch v.(type) {
+	case []any:
+		return "[" + FormatValue(v) + "]"
+	case *Map:
+		return "{" + FormatValue(v) + "}"
+	}
+	return FormatValue(v)
+}
+
+// splitFrontmatter finds the frontmatter block at the start of content. The
+// closing delimiter must be on a line of its own; YAML may also close with
+// "...".
+func splitFrontmatter(content string) (format, block, body string, ok bool) {
+	var delim string
+	switch {
+	case strings.HasPrefix(content, "---"):
+		format, delim = "yaml", "---"
+	case strings.HasPrefix(content, "+++"):
+		format, delim = "toml", "+++"
+	default:
+		return "", "", "", false
+	}
+
+	// Skip the newline after the opening delimiter
+	rest := content[3:]
+	if strings.HasPrefix(rest, "\n") {
+		rest = rest[1:]
+	} else if strings.HasPrefix(rest, "\r\n") {
+		rest = rest[2:]
+	} else {
+		return "", "", "", false
+	}
+
+	for start := 0; start < len(rest); {
+		end := strings.IndexByte(rest[start:], '\n')
+		if end == -1 {
+			end = len(rest)
+		} else {
+			end += start
+		}
+		line := strings.TrimRight(rest[start:end], " \t\r")
+		if line == delim || (format == "yaml" && line == "...") {
+			body = ""
+			if end < len(rest) {
+				body = rest[end+1:]
+			}
+			// Strip leading newlines from body
+			return format, rest[:start], strings.TrimLeft(body, "\r\n"), true
+		}
+		start = end + 1
+	}
+	return "", "", "", false
+}
+
+// parseFlatFields reads "key: value" (or "key = value") lines as strings,
+// ignoring anything nested
+func parseFlatFields(block string, sep byte) *Map {
+	fields := NewMap()
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		sepIdx := strings.IndexByte(line, sep)
+		if sepIdx == -1 {
+			continue
+		}
+		key := strings.TrimSpace(line[:sepIdx])
+		value := strings.TrimSpace(line[sepIdx+1:])
+		if key == "" || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		fields.Set(key, value)
+	}
+	return fields
+}
+
+// parseBracketList parses "[a, b, c]" into []string{"a", "b", "c"}
+func parseBracketList(s string) []string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	s = s[1 : len(s)-1]
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	var result []string
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,248 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseFrontmatter_Basic(t *testing.T) {
+	content := "---\ntitle: Test Doc\ncreated: 2026-02-23\ntags: [demo, test]\n---\n\n# Hello\n\nWorld"
+	fm, body := ParseFrontmatter(content)
+
+	if fm.Title != "Test Doc" {
+		t.Errorf("expected title 'Test Doc', got %q", fm.Title)
+	}
+	if fm.Created != "2026-02-23" {
+		t.Errorf("expected created '2026-02-23', got %q", fm.Created)
+	}
+	if len(fm.Tags) != 2 || fm.Tags[0] != "demo" || fm.Tags[1] != "test" {
+		t.Errorf("expected tags [demo, test], got %v", fm.Tags)
+	}
+	if !strings.HasPrefix(body, "# Hello") {
+		t.Errorf("expected body to start with '# Hello', got %q", body[:min(len(body), 20)])
+	}
+}
+
+func TestParseFrontmatter_NoFrontmatter(t *testing.T) {
+	content := "# Just a heading\n\nSome text."
+	fm, body := ParseFrontmatter(content)
+
+	if fm.Title != "" {
+		t.Errorf("expected empty title, got %q", fm.Title)
+	}
+	if body != content {
+		t.Errorf("expected body to equal original content")
+	}
+}
+
+func TestParseFrontmatter_EmptyTags(t *testing.T) {
+	content := "---\ntitle: No Tags\ntags: []\n---\n\nBody"
+	fm, body := ParseFrontmatter(content)
+
+	if fm.Title != "No Tags" {
+		t.Errorf("expected title 'No Tags', got %q", fm.Title)
+	}
+	if len(fm.Tags) != 0 {
+		t.Errorf("expected empty tags, got %v", fm.Tags)
+	}
+	if body != "Body" {
+		t.Errorf("expected body 'Body', got %q", body)
+	}
+}
+
+func TestParseFrontmatter_RawFields(t *testing.T) {
+	content := "---\ntitle: Doc\nauthor: Alice\nstatus: draft\n---\n\nContent"
+	fm, _ := ParseFrontmatter(content)
+
+	if fm.Raw["author"] != "Alice" {
+		t.Errorf("expected raw author 'Alice', got %q", fm.Raw["author"])
+	}
+	if fm.Raw["status"] != "draft" {
+		t.Errorf("expected raw status 'draft', got %q", fm.Raw["status"])
+	}
+}
+
+func TestParseFrontmatter_UnclosedDelimiter(t *testing.T) {
+	content := "---\ntitle: Broken\nNo closing delimiter"
+	fm, body := ParseFrontmatter(content)
+
+	if fm.Title != "" {
+		t.Errorf("expected empty title for unclosed frontmatter, got %q", fm.Title)
+	}
+	if body != content {
+		t.Errorf("expected body to equal original content")
+	}
+}
+
+func TestParseBracketList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"[a, b, c]", []string{"a", "b", "c"}},
+		{"[]", nil},
+		{"[single]", []string{"single"}},
+		{"plain", []string{"plain"}},
+		{"", nil},
+	}
+
+	for _, tc := range tests {
+		result := parseBracketList(tc.input)
+		if len(result) != len(tc.expected) {
+			t.Errorf("parseBracketList(%q): expected %v, got %v", tc.input, tc.expected, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != tc.expected[i] {
+				t.Errorf("parseBracketList(%q)[%d]: expected %q, got %q", tc.input, i, tc.expected[i], result[i])
+			}
+		}
+	}
+}
+
+func TestParseFrontmatter_YAMLStructure(t *testing.T) {
+	content := `---
+title: "Design: v2"   # quoted, with a colon
+created: 2026-03-01
+draft: false
+version: 3
+score: 4.5
+authors:
+  - name: Ann Lee
+    email: ann@example.com
+  - Bob
+review:
+  status: approved
+  reviewers: [carol, "dan, jr"]
+  notes: |
+    Line one
+    Line two
+summary: >-
+  Folded
+  text
+
+  next
+url: https://example.com/a#b
+empty:
+tags:
+- demo
+- test
+---
+Body`
+	fm, body := ParseFrontmatter(content)
+	if fm.Err != nil {
+		t.Fatalf("unexpected error: %v", fm.Err)
+	}
+	if body != "Body" {
+		t.Errorf("body = %q", body)
+	}
+	if fm.Format != "yaml" || fm.Title != "Design: v2" || fm.Created != "2026-03-01" {
+		t.Errorf("format %q, title %q, created %q", fm.Format, fm.Title, fm.Created)
+	}
+	if len(fm.Tags) != 2 || fm.Tags[0] != "demo" || fm.Tags[1] != "test" {
+		t.Errorf("tags = %v", fm.Tags)
+	}
+
+	checks := map[string]any{
+		"draft":              false,
+		"version":            int64(3),
+		"score":              4.5,
+		"authors.0.name":     "Ann Lee",
+		"authors.0.email":    "ann@example.com",
+		"authors.1":          "Bob",
+		"review.status":      "approved",
+		"review.reviewers.1": "dan, jr",
+		"review.notes":       "Line one\nLine two\n",
+		"summary":            "Folded text\nnext",
+		"url":                "https://example.com/a#b",
+		"empty":              nil,
+	}
+	for path, want := range checks {
+		got, ok := fm.Lookup(path)
+		if !ok || got != want {
+			t.Errorf("Lookup(%q) = %#v, %v; want %#v", path, got, ok, want)
+		}
+	}
+	if got := fm.Fields.Keys; strings.Join(got, ",") != "title,created,draft,version,score,authors,review,summary,url,empty,tags" {
+		t.Errorf("keys out of order: %v", got)
+	}
+	if got := fm.Field("review.reviewers"); got != "carol, dan, jr" {
+		t.Errorf("Field(review.reviewers) = %q", got)
+	}
+	if got := fm.Raw["authors"]; got != "{name: Ann Lee, email: ann@example.com}, Bob" {
+		t.Errorf("Raw[authors] = %q", got)
+	}
+}
+
+func TestParseFrontmatter_TOML(t *testing.T) {
+	content := `+++
+title = "Release notes"
+created = 2026-03-01
+tags = ["release", 'v2']
+weight = 1_000
+
+[review]
+status = "approved"
+"sign off".by = "Ann"
+
+[[authors]]
+name = "Ann"
+
+[[authors]]
+name = "Bob"
+roles = [
+  "editor", # trailing comment
+  "writer",
+]
++++
+
+Body`
+	fm, body := ParseFrontmatter(content)
+	if fm.Err != nil {
+		t.Fatalf("unexpected error: %v", fm.Err)
+	}
+	if body != "Body" {
+		t.Errorf("body = %q", body)
+	}
+	if fm.Format != "toml" || fm.Title != "Release notes" || fm.Created != "2026-03-01" {
+		t.Errorf("format %q, title %q, created %q", fm.Format, fm.Title, fm.Created)
+	}
+	if len(fm.Tags) != 2 || fm.Tags[1] != "v2" {
+		t.Errorf("tags = %v", fm.Tags)
+	}
+	checks := map[string]any{
+		"weight":             int64(1000),
+		"review.status":      "approved",
+		"review.sign off.by": "Ann",
+		"authors.1.name":     "Bob",
+		"authors.1.roles.0":  "editor",
+	}
+	for path, want := range checks {
+		got, ok := fm.Lookup(path)
+		if !ok || got != want {
+			t.Errorf("Lookup(%q) = %#v, %v; want %#v", path, got, ok, want)
+		}
+	}
+}
+
+func TestParseFrontmatter_InvalidKeepsFlatFields(t *testing.T) {
+	tests := []struct {
+		content string
+		line    int
+	}{
+		{"---\ntitle: Broken\nauthors: [ann, bob\n---\nBody", 3},
+		{"---\ntitle: Broken\nreview:\n  status: ok\n    extra: 1\n---\nBody", 5},
+		{"+++\ntitle = \"Broken\"\ncount = 12abc\n+++\nBody", 3},
+	}
+	for _, tc := range tests {
+		fm, body := ParseFrontmatter(tc.content)
+		var fe *FrontmatterError
+		if !errors.As(fm.Err, &fe) || fe.Line != tc.line {
+			t.Errorf("%q: error %v, want one at line %d", tc.content, fm.Err, tc.line)
+		}
+		if fm.Title != "Broken" || body != "Body" {
+			t.Errorf("%q: title %q, body %q", tc.content, fm.Title, body)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Package markdown parses CommonMark, with the GitHub Flavored Markdown
+// extensions, into a syntax tree shared by the terminal and HTML renderers.
+package markdown
+
+// Kind identifies the type of a Node
+type Kind int
+
+const (
+	// Block nodes
+	Document Kind = iota
+	BlockQuote
+	List
+	Item
+	CodeBlock
+	HTMLBlock
+	Paragraph
+	Heading
+	ThematicBreak
+	Table
+	TableRow
+	TableCell
+	FootnoteList
+	FootnoteDefinition
+	Callout
+	MathBlock
+
+	// Inline nodes
+	Text
+	SoftBreak
+	HardBreak
+	CodeSpan
+	HTMLInline
+	Emph
+	Strong
+	Link
+	Image
+	Strikethrough
+	TaskCheckbox
+	FootnoteReference
+	Math
+	WikiLink
+)
+
+var kindNames = [...]string{
+	Document:           "document",
+	BlockQuote:         "block_quote",
+	List:               "list",
+	Item:               "item",
+	CodeBlock:          "code_block",
+	HTMLBlock:          "html_block",
+	Paragraph:          "paragraph",
+	Heading:            "heading",
+	ThematicBreak:      "thematic_break",
+	Table:              "table",
+	TableRow:           "table_row",
+	TableCell:          "table_cell",
+	FootnoteList:       "footnote_list",
+	FootnoteDefinition: "footnote_definition",
+	Callout:            "callout",
+	MathBlock:          "math_block",
+	Text:               "text",
+	SoftBreak:          "softbreak",
+	HardBreak:          "linebreak",
+	CodeSpan:           "code",
+	HTMLInline:         "html_inline",
+	Emph:               "emph",
+	Strong:             "strong",
+	Link:               "link",
+	Image:              "image",
+	Strikethrough:      "strikethrough",
+	TaskCheckbox:       "task_checkbox",
+	FootnoteReference:  "footnote_reference",
+	Math:               "math",
+	WikiLink:           "wikilink",
+}
+
+// String returns the CommonMark name of the kind
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// IsBlock reports whether nodes of this kind are blocks
+func (k Kind) IsBlock() bool {
+	return k < Text
+}
+
+// Align is the alignment of a table column
+type Align int
+
+const (
+	AlignNone Align = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+// String returns the HTML align attribute value, or "" for AlignNone
+func (a Align) String() string {
+	switch a {
+	case AlignLeft:
+		return "left"
+	case AlignCenter:
+		return "center"
+	case AlignRight:
+		return "right"
+	default:
+		return ""
+	}
+}
+
+// Node is a block or inline element of a parsed document
+type Node struct {
+	Kind Kind
+
+	Parent      *Node
+	FirstChild  *Node
+	LastChild   *Node
+	PrevSibling *Node
+	NextSibling *Node
+
+	// Line is the 1-based source line a block starts on; 0 for inlines
+	Line int
+	// EndLine is the 1-based source line a block ends on
+	EndLine int
+
+	// Literal is the content of Text, CodeSpan, CodeBlock, HTMLBlock and
+	// HTMLInline nodes, and the TeX source of Math and MathBlock nodes
+	Literal string
+	// Display is set on Math written inline with $$ delimiters
+	Display bool
+
+	// Level is the heading level, 1-6
+	Level int
+
+	// Info is the info string of a fenced code block
+	Info string
+	// Fenced is set for fenced code blocks
+	Fenced bool
+
+	// List fields: Start is the first number of an ordered list and
+	// Delimiter is '.' or ')'; BulletChar is '-', '+' or '*' otherwise
+	Ordered    bool
+	Start      int
+	Delimiter  byte
+	BulletChar byte
+	Tight      bool
+
+	// Destination and Title of a Link or Image. Title is also the heading
+	// of a Callout; an empty Title means the callout has none. The
+	// Destination of a WikiLink is the note it names, with any #heading.
+	Destination string
+	Title       string
+
+	// Align is the column alignment of a TableCell
+	Align Align
+	// Header is set on the header TableRow and its cells
+	Header bool
+
+	// Checked is set on the TaskCheckbox of a done task list item
+	Checked bool
+
+	// Label is the label of a FootnoteDefinition or FootnoteReference and
+	// Index the footnote's number, in order of first reference. Refs is the
+	// number of references to a definition; on a reference it is the
+	// reference's own 1-based position among them. Renderers may also
+	// number links with Index.
+	Label string
+	Index int
+	Refs  int
+
+	// CalloutType is the style of a Callout: note, tip, important, warning,
+	// caution and the MkDocs types (abstract, info, success, question,
+	// failure, danger, bug, example, quote). Foldable marks a collapsible
+	// ??? admonition, which starts open if Expanded is set.
+	CalloutType string
+	Foldable    bool
+	Expanded    bool
+
+	// block parser state
+	open        bool
+	content     []byte
+	fenceChar   byte
+	fenceLength int
+	fenceOffset int
+	htmlType    int
+	list        *listData
+	aligns      []Align
+}
+
+// listData describes a list marker
+type listData struct {
+	ordered      bool
+	bulletChar   byte
+	start        int
+	delimiter    byte
+	padding      int
+	markerOffset int
+}
+
+// newNode returns a node of the given kind
+func newNode(kind Kind, line int) *Node {
+	return &Node{Kind: kind, Line: line, open: kind.IsBlock()}
+}
+
+// text returns a Text node
+func text(s string) *Node {
+	return &Node{Kind: Text, Literal: s}
+}
+
+// AppendChild adds child as the last child of n
+func (n *Node) AppendChild(child *Node) {
+	child.unlink()
+	child.Parent = n
+	if n.LastChild != nil {
+		n.LastChild.NextSibling = child
+		child.PrevSibling = n.LastChild
+		n.LastChild = child
+	} else {
+		n.FirstChild = child
+		n.LastChild = child
+	}
+}
+
+// InsertAfter inserts sibling directly after n
+func (n *Node) InsertAfter(sibling *Node) {
+	sibling.unlink()
+	sibling.NextSibling = n.NextSibling
+	if sibling.NextSibling != nil {
+		sibling.NextSibling.PrevSibling = sibling
+	}
+	sibling.PrevSibling = n
+	n.NextSibling = sibling
+	sibling.Parent = n.Parent
+	if sibling.NextSibling == nil && sibling.Parent != nil {
+		sibling.Parent.LastChild = sibling
+	}
+}
+
+// unlink removes n from its parent
+func (n *Node) unlink() {
+	if n.PrevSibling != nil {
+		n.PrevSibling.NextSibling = n.NextSibling
+	} else if n.Parent != nil {
+		n.Parent.FirstChild = n.NextSibling
+	}
+	if n.NextSibling != nil {
+		n.NextSibling.PrevSibling = n.PrevSibling
+	} else if n.Parent != nil {
+		n.Parent.LastChild = n.PrevSibling
+	}
+	n.Parent = nil
+	n.NextSibling = nil
+	n.PrevSibling = nil
+}
+
+// Walk calls fn for n and each of its descendants in document order. If fn
+// returns false the node's children are skipped.
+func Walk(n *Node, fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		Walk(c, fn)
+	}
+}
+
+// PlainText returns the text content of n with all markup removed. Breaks
+// become spaces.
+func PlainText(n *Node) string {
+	var buf []byte
+	Walk(n, func(c *Node) bool {
+		switch c.Kind {
+		case Text, CodeSpan, Math:
+			buf = append(buf, c.Literal...)
+		case FootnoteReference:
+			return false
+		case SoftBreak, HardBreak:
+			buf = append(buf, ' ')
+		case CodeBlock, HTMLBlock, MathBlock:
+			buf = append(buf, c.Literal...)
+		}
+		return true
+	})
+	return string(buf)
+}
@@ -0,0 +1,132 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reWWWAutolink   = regexp.MustCompile(`^www\.[a-zA-Z0-9_-]+(?:\.[a-zA-Z0-9_-]+)*[^\s<]*`)
+	reURLAutolink   = regexp.MustCompile(`^(?:https?|ftp)://[a-zA-Z0-9_-]+(?:\.[a-zA-Z0-9_-]+)*[^\s<]*`)
+	reEmailAutoLink = regexp.MustCompile(`^[a-zA-Z0-9._+-]+@[a-zA-Z0-9_-]+(?:\.[a-zA-Z0-9_-]+)+`)
+	reTrailingRef   = regexp.MustCompile(`&[a-zA-Z0-9]+;$`)
+)
+
+// linkify turns bare URLs, www. addresses and email addresses in the text
+// of n into links, following the GFM extended autolink rules
+func linkify(n *Node) {
+	// Delimiters that didn't become emphasis are left as separate text
+	// nodes; join them so URLs containing _ or * are found whole
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		for c.Kind == Text && c.NextSibling != nil && c.NextSibling.Kind == Text {
+			c.Literal += c.NextSibling.Literal
+			c.NextSibling.unlink()
+		}
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Kind {
+		case Text:
+			linkifyText(c)
+		case Emph, Strong, Strikethrough:
+			linkify(c)
+		}
+		c = next
+	}
+}
+
+// linkifyText splits a text node around the autolinks it contains
+func linkifyText(t *Node) {
+	s := t.Literal
+	for i := 0; i < len(s); i++ {
+		if i > 0 && !isAutolinkBoundary(s[i-1]) {
+			continue
+		}
+		length, dest := matchAutolink(s[i:])
+		if length == 0 {
+			continue
+		}
+		link := &Node{Kind: Link, Destination: normalizeURI(dest)}
+		link.AppendChild(text(s[i : i+length]))
+		t.Literal = s[:i]
+		t.InsertAfter(link)
+		if rest := s[i+length:]; rest != "" {
+			after := text(rest)
+			link.InsertAfter(after)
+			linkifyText(after)
+		}
+		if t.Literal == "" {
+			t.unlink()
+		}
+		return
+	}
+}
+
+// isAutolinkBoundary reports whether an autolink may start after c
+func isAutolinkBoundary(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '*', '_', '~', '(':
+		return true
+	}
+	return false
+}
+
+// matchAutolink returns the length and destination of an autolink at the
+// start of s, or 0 if there is none
+func matchAutolink(s string) (int, string) {
+	switch s[0] {
+	case 'w':
+		if m := reWWWAutolink.FindString(s); m != "" {
+			if m = trimAutolink(m); validDomain(m) {
+				return len(m), "http://" + m
+			}
+		}
+	case 'h', 'f':
+		if m := reURLAutolink.FindString(s); m != "" {
+			m = trimAutolink(m)
+			if _, host, _ := strings.Cut(m, "://"); validDomain(host) {
+				return len(m), m
+			}
+		}
+	}
+	if m := reEmailAutoLink.FindString(s); m != "" {
+		m = strings.TrimSuffix(m, ".")
+		if last := m[len(m)-1]; last != '-' && last != '_' && strings.Contains(m[strings.IndexByte(m, '@'):], ".") {
+			return len(m), "mailto:" + m
+		}
+	}
+	return 0, ""
+}
+
+// trimAutolink drops trailing punctuation, unbalanced closing parentheses
+// and entity references from an autolink
+func trimAutolink(s string) string {
+	for s != "" {
+		switch last := s[len(s)-1]; {
+		case strings.IndexByte("?!.,:*_~'\"", last) >= 0:
+			s = s[:len(s)-1]
+		case last == ')' && strings.Count(s, ")") > strings.Count(s, "("):
+			s = s[:len(s)-1]
+		case last == ';' && reTrailingRef.MatchString(s):
+			s = s[:strings.LastIndexByte(s, '&')]
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+// validDomain reports whether the domain at the start of s has no
+// underscores in its last two labels
+func validDomain(s string) bool {
+	if i := strings.IndexAny(s, "/?#"); i >= 0 {
+		s = s[:i]
+	}
+	labels := strings.Split(s, ".")
+	for i := len(labels) - 2; i < len(labels); i++ {
+		if i >= 0 && strings.Contains(labels[i], "_") {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,1065 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Options selects syntax extensions beyond CommonMark
+type Options struct {
+	// Tables enables GitHub pipe tables
+	Tables bool
+	// Strikethrough enables ~text~ and ~~text~~
+	Strikethrough bool
+	// TaskLists turns a leading [ ] or [x] in a list item into a checkbox
+	TaskLists bool
+	// Autolinks links bare www., http(s)://, ftp:// and email addresses
+	Autolinks bool
+	// Footnotes enables [^label] references and [^label]: definitions
+	Footnotes bool
+	// Alerts turns a block quote starting with [!NOTE], [!TIP], [!IMPORTANT],
+	// [!WARNING] or [!CAUTION] into a callout
+	Alerts bool
+	// Admonitions enables MkDocs !!! type "title" callouts and their
+	// foldable ??? and ???+ variants
+	Admonitions bool
+	// Math enables $tex$ and $$tex$$ spans and $$ display math blocks
+	Math bool
+	// WikiLinks enables [[note]] and [[note|label]] links between notes
+	WikiLinks bool
+}
+
+// GFM enables the GitHub Flavored Markdown extensions
+var GFM = Options{Tables: true, Strikethrough: true, TaskLists: true, Autolinks: true, Footnotes: true, Alerts: true, Math: true}
+
+// Extended enables GFM, MkDocs admonitions and wiki links
+var Extended = Options{Tables: true, Strikethrough: true, TaskLists: true, Autolinks: true, Footnotes: true, Alerts: true, Admonitions: true, Math: true, WikiLinks: true}
+
+// codeIndent is the indentation that starts an indented code block
+const codeIndent = 4
+
+var (
+	reThematicBreak      = regexp.MustCompile(`^(?:\*[ \t]*){3,}$|^(?:_[ \t]*){3,}$|^(?:-[ \t]*){3,}$`)
+	reMaybeSpecial       = regexp.MustCompile(`^[#` + "`" + `~*+_=<>0-9\-|:\[!?$]`)
+	reNonSpace           = regexp.MustCompile(`[^ \t\f\v\r\n]`)
+	reBulletListMarker   = regexp.MustCompile(`^[*+-]`)
+	reOrderedListMarker  = regexp.MustCompile(`^(\d{1,9})([.)])`)
+	reATXHeadingMarker   = regexp.MustCompile(`^#{1,6}(?:[ \t]+|$)`)
+	reSetextHeadingLine  = regexp.MustCompile(`^(?:=+|-+)[ \t]*$`)
+	reATXClosingOnly     = regexp.MustCompile(`^[ \t]*#+[ \t]*$`)
+	reATXClosingSequence = regexp.MustCompile(`[ \t]+#+[ \t]*$`)
+	reTableDelimiterCell = regexp.MustCompile(`^:?-+:?$`)
+	reTrailingBlankLines = regexp.MustCompile(`(\n *)+$`)
+	reFootnoteDefinition = regexp.MustCompile(`^\[\^([^\]\s]+)\]:`)
+	reAlertMarker        = regexp.MustCompile(`^\[!([A-Za-z]+)\][ \t]*(?:\n|$)`)
+	reAdmonition         = regexp.MustCompile(`^(!!!|\?\?\?\+?)[ \t]+([A-Za-z][\w-]*)(?:[ \t]+[A-Za-z][\w-]*)*(?:[ \t]+("[^"]*"))?[ \t]*$`)
+
+	reHTMLBlockOpen = []*regexp.Regexp{
+		nil,
+		regexp.MustCompile(`(?i)^<(?:script|pre|textarea|style)(?:\s|>|$)`),
+		regexp.MustCompile(`^<!--`),
+		regexp.MustCompile(`^<[?]`),
+		regexp.MustCompile(`^<![A-Za-z]`),
+		regexp.MustCompile(`^<!\[CDATA\[`),
+		regexp.MustCompile(`(?i)^<[/]?(?:address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[123456]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:\s|[/]?[>]|$)`),
+		regexp.MustCompile(`(?i)^(?:` + openTag + `|` + closeTag + `)\s*$`),
+	}
+	reHTMLBlockClose = []*regexp.Regexp{
+		nil,
+		regexp.MustCompile(`(?i)</(?:script|pre|textarea|style)>`),
+		regexp.MustCompile(`-->`),
+		regexp.MustCompile(`\?>`),
+		regexp.MustCompile(`>`),
+		regexp.MustCompile(`\]\]>`),
+	}
+)
+
+// calloutTypes maps alert and admonition types to the style they share
+var calloutTypes = map[string]string{
+	"note":      "note",
+	"seealso":   "note",
+	"abstract":  "abstract",
+	"summary":   "abstract",
+	"tldr":      "abstract",
+	"info":      "info",
+	"todo":      "info",
+	"tip":       "tip",
+	"hint":      "tip",
+	"important": "important",
+	"success":   "success",
+	"check":     "success",
+	"done":      "success",
+	"question":  "question",
+	"help":      "question",
+	"faq":       "question",
+	"warning":   "warning",
+	"attention": "warning",
+	"caution":   "caution",
+	"failure":   "failure",
+	"fail":      "failure",
+	"missing":   "failure",
+	"danger":    "danger",
+	"error":     "danger",
+	"bug":       "bug",
+	"example":   "example",
+	"quote":     "quote",
+	"cite":      "quote",
+}
+
+// gitHubAlerts are the types a [!TYPE] block quote marker may name
+var gitHubAlerts = map[string]bool{"note": true, "tip": true, "important": true, "warning": true, "caution": true}
+
+// blockParser builds the block structure of a document line by line,
+// following the CommonMark reference parsing strategy
+type blockParser struct {
+	opts      Options
+	doc       *Node
+	tip       *Node
+	oldtip    *Node
+	refmap    map[string]linkRef
+	footnotes map[string]*Node
+
+	currentLine          string
+	lineNumber           int
+	offset               int
+	column               int
+	nextNonspace         int
+	nextNonspaceColumn   int
+	indent               int
+	indented             bool
+	blank                bool
+	partiallyConsumedTab bool
+	allClosed            bool
+	lastMatchedContainer *Node
+}
+
+// Parse parses a GitHub Flavored Markdown document, with MkDocs admonitions
+func Parse(src string) *Node {
+	return ParseWithOptions(src, Extended)
+}
+
+// ParseWithOptions parses a document with the given extensions enabled
+func ParseWithOptions(src string, opts Options) *Node {
+	p := &blockParser{opts: opts, refmap: map[string]linkRef{}, footnotes: map[string]*Node{}}
+	p.doc = newNode(Document, 1)
+	p.tip = p.doc
+	p.lastMatchedContainer = p.doc
+
+	lines := splitLines(src)
+	for _, line := range lines {
+		p.incorporateLine(line)
+	}
+	for p.tip != nil {
+		p.finalize(p.tip, len(lines))
+	}
+
+	ip := &inlineParser{opts: opts, refmap: p.refmap, footnotes: p.footnotes}
+	Walk(p.doc, func(n *Node) bool {
+		switch n.Kind {
+		case Paragraph, Heading, TableCell:
+			ip.parse(n)
+		}
+		return true
+	})
+	if opts.Footnotes {
+		collectFootnotes(p.doc, ip.footnoteCount)
+	}
+	return p.doc
+}
+
+// ParseInline parses text as the content of a single paragraph and returns
+// the paragraph node. GFM inline extensions are enabled.
+func ParseInline(src string) *Node {
+	para := newNode(Paragraph, 1)
+	para.content = []byte(src)
+	(&inlineParser{opts: GFM, refmap: map[string]linkRef{}}).parse(para)
+	return para
+}
+
+// collectFootnotes moves referenced footnote definitions, in reference order,
+// into a FootnoteList at the end of the document and drops the rest
+func collectFootnotes(doc *Node, count int) {
+	var defs []*Node
+	Walk(doc, func(n *Node) bool {
+		if n.Kind == FootnoteDefinition {
+			defs = append(defs, n)
+		}
+		return n.Kind.IsBlock()
+	})
+	if len(defs) == 0 {
+		return
+	}
+
+	ordered := make([]*Node, count)
+	for _, def := range defs {
+		def.unlink()
+		if def.Index > 0 {
+			ordered[def.Index-1] = def
+		}
+	}
+	if count == 0 {
+		return
+	}
+	list := newNode(FootnoteList, ordered[0].Line)
+	list.open = false
+	for _, def := range ordered {
+		list.AppendChild(def)
+		if def.Line < list.Line {
+			list.Line = def.Line
+		}
+		if def.EndLine > list.EndLine {
+			list.EndLine = def.EndLine
+		}
+	}
+	doc.AppendChild(list)
+}
+
+// splitLines splits src on any line ending, dropping the empty line after a
+// final newline
+func splitLines(src string) []string {
+	if src == "" {
+		return nil
+	}
+	var lines []string
+	start := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\n':
+			lines = append(lines, src[start:i])
+			start = i + 1
+		case '\r':
+			lines = append(lines, src[start:i])
+			if i+1 < len(src) && src[i+1] == '\n' {
+				i++
+			}
+			start = i + 1
+		}
+	}
+	if start < len(src) {
+		lines = append(lines, src[start:])
+	}
+	return lines
+}
+
+// peek returns the byte at pos in s, or 0 past the end
+func peek(s string, pos int) byte {
+	if pos < len(s) {
+		return s[pos]
+	}
+	return 0
+}
+
+func isSpaceOrTab(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// canContain reports whether a block of kind parent can hold a child block
+func canContain(parent, child Kind) bool {
+	switch parent {
+	case Document, BlockQuote, Item, FootnoteDefinition, Callout:
+		return child != Item
+	case List:
+		return child == Item
+	default:
+		return false
+	}
+}
+
+// acceptsLines reports whether a block takes raw text lines
+func acceptsLines(k Kind) bool {
+	switch k {
+	case Paragraph, CodeBlock, HTMLBlock, Table, MathBlock:
+		return true
+	}
+	return false
+}
+
+func (p *blockParser) findNextNonspace() {
+	i := p.offset
+	cols := p.column
+	line := p.currentLine
+	for i < len(line) {
+		if line[i] == ' ' {
+			i++
+			cols++
+		} else if line[i] == '\t' {
+			i++
+			cols += 4 - (cols % 4)
+		} else {
+			break
+		}
+	}
+	p.blank = i >= len(line)
+	p.nextNonspace = i
+	p.nextNonspaceColumn = cols
+	p.indent = cols - p.column
+	p.indented = p.indent >= codeIndent
+}
+
+func (p *blockParser) advanceNextNonspace() {
+	p.offset = p.nextNonspace
+	p.column = p.nextNonspaceColumn
+	p.partiallyConsumedTab = false
+}
+
+// advanceOffset moves count bytes, or count columns when columns is set,
+// splitting tabs into spaces as needed
+func (p *blockParser) advanceOffset(count int, columns bool) {
+	line := p.currentLine
+	for count > 0 && p.offset < len(line) {
+		if line[p.offset] == '\t' {
+			charsToTab := 4 - (p.column % 4)
+			if columns {
+				p.partiallyConsumedTab = charsToTab > count
+				charsToAdvance := charsToTab
+				if charsToAdvance > count {
+					charsToAdvance = count
+				}
+				p.column += charsToAdvance
+				if !p.partiallyConsumedTab {
+					p.offset++
+				}
+				count -= charsToAdvance
+			} else {
+				p.partiallyConsumedTab = false
+				p.column += charsToTab
+				p.offset++
+				count--
+			}
+		} else {
+			p.partiallyConsumedTab = false
+			p.offset++
+			p.column++
+			count--
+		}
+	}
+}
+
+// addLine appends the rest of the current line to the tip's content
+func (p *blockParser) addLine() {
+	if p.partiallyConsumedTab {
+		p.offset++
+		charsToTab := 4 - (p.column % 4)
+		p.tip.content = append(p.tip.content, strings.Repeat(" ", charsToTab)...)
+	}
+	p.tip.content = append(p.tip.content, p.currentLine[p.offset:]...)
+	p.tip.content = append(p.tip.content, '\n')
+}
+
+// addChild adds a block to the tip, closing blocks that can't contain it
+func (p *blockParser) addChild(kind Kind) *Node {
+	for !canContain(p.tip.Kind, kind) {
+		p.finalize(p.tip, p.lineNumber-1)
+	}
+	n := newNode(kind, p.lineNumber)
+	p.tip.AppendChild(n)
+	p.tip = n
+	return n
+}
+
+func (p *blockParser) closeUnmatchedBlocks() {
+	if p.allClosed {
+		return
+	}
+	for p.oldtip != p.lastMatchedContainer {
+		parent := p.oldtip.Parent
+		p.finalize(p.oldtip, p.lineNumber-1)
+		p.oldtip = parent
+	}
+	p.allClosed = true
+}
+
+func (p *blockParser) incorporateLine(line string) {
+	container := p.doc
+	p.oldtip = p.tip
+	p.offset = 0
+	p.column = 0
+	p.blank = false
+	p.partiallyConsumedTab = false
+	p.lineNumber++
+	if strings.IndexByte(line, 0) >= 0 {
+		line = strings.ReplaceAll(line, "\x00", "\uFFFD")
+	}
+	p.currentLine = line
+
+	// Match the line against each open container
+	allMatched := true
+	for container.LastChild != nil && container.LastChild.open {
+		container = container.LastChild
+		p.findNextNonspace()
+		switch p.continueBlock(container) {
+		case 1:
+			allMatched = false
+		case 2:
+			return
+		}
+		if !allMatched {
+			container = container.Parent
+			break
+		}
+	}
+
+	p.allClosed = container == p.oldtip
+	p.lastMatchedContainer = container
+
+	matchedLeaf := container.Kind != Paragraph && container.Kind != Table && acceptsLines(container.Kind)
+	for !matchedLeaf {
+		p.findNextNonspace()
+		if !p.indented && !reMaybeSpecial.MatchString(line[p.nextNonspace:]) {
+			p.advanceNextNonspace()
+			break
+		}
+		res := p.startBlock(container)
+		if res == 0 {
+			p.advanceNextNonspace()
+			break
+		}
+		container = p.tip
+		if res == 2 {
+			matchedLeaf = true
+		}
+	}
+
+	if !p.allClosed && !p.blank && p.tip.Kind == Paragraph {
+		// Lazy paragraph continuation
+		p.addLine()
+		return
+	}
+
+	p.closeUnmatchedBlocks()
+	if container.Kind == Table && p.offset >= len(line) {
+		// The delimiter row was consumed by startTable
+		return
+	}
+	if acceptsLines(container.Kind) {
+		p.addLine()
+		if container.Kind == HTMLBlock && container.htmlType >= 1 && container.htmlType <= 5 &&
+			reHTMLBlockClose[container.htmlType].MatchString(line[p.offset:]) {
+			p.finalize(container, p.lineNumber)
+		}
+	} else if p.offset < len(line) && !p.blank {
+		p.addChild(Paragraph)
+		p.advanceNextNonspace()
+		p.addLine()
+	}
+}
+
+// continueBlock checks whether the current line continues an open block.
+// It returns 0 if it does, 1 if it doesn't, and 2 if the line was consumed
+// entirely (a closing code fence).
+func (p *blockParser) continueBlock(n *Node) int {
+	line := p.currentLine
+	switch n.Kind {
+	case BlockQuote:
+		if !p.indented && peek(line, p.nextNonspace) == '>' {
+			p.advanceNextNonspace()
+			p.advanceOffset(1, false)
+			if isSpaceOrTab(peek(line, p.offset)) {
+				p.advanceOffset(1, true)
+			}
+			return 0
+		}
+		return 1
+	case Item:
+		if p.blank {
+			if n.FirstChild == nil {
+				// A list item can begin with at most one blank line
+				return 1
+			}
+			p.advanceNextNonspace()
+		} else if p.indent >= n.list.markerOffset+n.list.padding {
+			p.advanceOffset(n.list.markerOffset+n.list.padding, true)
+		} else {
+			return 1
+		}
+		return 0
+	case FootnoteDefinition, Callout:
+		// Continuation lines are indented four columns
+		if p.blank {
+			p.advanceNextNonspace()
+		} else if p.indent >= codeIndent {
+			p.advanceOffset(codeIndent, true)
+		} else {
+			return 1
+		}
+		return 0
+	case Heading, ThematicBreak:
+		return 1
+	case MathBlock:
+		for i := n.fenceOffset; i > 0 && isSpaceOrTab(peek(line, p.offset)); i-- {
+			p.advanceOffset(1, true)
+		}
+		if tex, ok := strings.CutSuffix(strings.TrimRight(line[p.offset:], " \t"), "$$"); ok {
+			n.content = append(n.content, tex...)
+			p.finalize(n, p.lineNumber)
+			return 2
+		}
+		return 0
+	case CodeBlock:
+		if n.Fenced {
+			if p.indent <= 3 && peek(line, p.nextNonspace) == n.fenceChar {
+				if length := closingFenceLength(line[p.nextNonspace:], n.fenceChar); length >= n.fenceLength {
+					p.finalize(n, p.lineNumber)
+					return 2
+				}
+			}
+			for i := n.fenceOffset; i > 0 && isSpaceOrTab(peek(line, p.offset)); i-- {
+				p.advanceOffset(1, true)
+			}
+			return 0
+		}
+		if p.indent >= codeIndent {
+			p.advanceOffset(codeIndent, true)
+		} else if p.blank {
+			p.advanceNextNonspace()
+		} else {
+			return 1
+		}
+		return 0
+	case HTMLBlock:
+		if p.blank && (n.htmlType == 6 || n.htmlType == 7) {
+			return 1
+		}
+		return 0
+	case Paragraph, Table:
+		if p.blank {
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
+
+// closingFenceLength returns the length of a closing code fence at the start
+// of s, or 0 if s isn't one
+func closingFenceLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	if n < 3 || strings.TrimRight(s[n:], " \t") != "" {
+		return 0
+	}
+	return n
+}
+
+// openingFence returns the fence character and length at the start of s, or
+// 0 if s doesn't open a code fence
+func openingFence(s string) (byte, int) {
+	c := peek(s, 0)
+	if c != '`' && c != '~' {
+		return 0, 0
+	}
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	if n < 3 || (c == '`' && strings.IndexByte(s[n:], '`') >= 0) {
+		return 0, 0
+	}
+	return c, n
+}
+
+// startBlock tries each block start in turn. It returns 0 if none matched, 1
+// for a container start and 2 for a leaf start.
+func (p *blockParser) startBlock(container *Node) int {
+	line := p.currentLine
+	rest := line[p.nextNonspace:]
+
+	if !p.indented {
+		// Block quote
+		if peek(line, p.nextNonspace) == '>' {
+			p.advanceNextNonspace()
+			p.advanceOffset(1, false)
+			if isSpaceOrTab(peek(line, p.offset)) {
+				p.advanceOffset(1, true)
+			}
+			p.closeUnmatchedBlocks()
+			p.addChild(BlockQuote)
+			return 1
+		}
+
+		// ATX heading
+		if m := reATXHeadingMarker.FindString(rest); m != "" {
+			p.advanceNextNonspace()
+			p.advanceOffset(len(m), false)
+			p.closeUnmatchedBlocks()
+			h := p.addChild(Heading)
+			h.Level = len(strings.TrimRight(m, " \t"))
+			content := line[p.offset:]
+			if reATXClosingOnly.MatchString(content) {
+				content = ""
+			} else {
+				content = reATXClosingSequence.ReplaceAllString(content, "")
+			}
+			h.content = []byte(content)
+			p.advanceOffset(len(line)-p.offset, false)
+			return 2
+		}
+
+		// Fenced code block
+		if c, length := openingFence(rest); length > 0 {
+			p.closeUnmatchedBlocks()
+			code := p.addChild(CodeBlock)
+			code.Fenced = true
+			code.fenceChar = c
+			code.fenceLength = length
+			code.fenceOffset = p.indent
+			p.advanceNextNonspace()
+			p.advanceOffset(length, false)
+			return 2
+		}
+
+		// Display math, either on one line or up to a line ending in $$
+		if p.opts.Math && strings.HasPrefix(rest, "$$") {
+			body := strings.TrimRight(rest[2:], " \t")
+			tex, closed := strings.CutSuffix(body, "$$")
+			if closed || !strings.Contains(body, "$$") {
+				p.closeUnmatchedBlocks()
+				m := p.addChild(MathBlock)
+				m.fenceOffset = p.indent
+				p.advanceNextNonspace()
+				if closed {
+					m.content = []byte(tex)
+					p.advanceOffset(len(line)-p.offset, false)
+					p.finalize(m, p.lineNumber)
+				} else {
+					p.advanceOffset(2, false)
+				}
+				return 2
+			}
+		}
+
+		// HTML block
+		if peek(line, p.nextNonspace) == '<' {
+			for t := 1; t <= 7; t++ {
+				if !reHTMLBlockOpen[t].MatchString(rest) {
+					continue
+				}
+				if t == 7 && (container.Kind == Paragraph || (!p.allClosed && !p.blank && p.tip.Kind == Paragraph)) {
+					continue
+				}
+				p.closeUnmatchedBlocks()
+				b := p.addChild(HTMLBlock)
+				b.htmlType = t
+				return 2
+			}
+		}
+
+		// Footnote definition
+		if p.opts.Footnotes && container.Kind != Paragraph {
+			if m := reFootnoteDefinition.FindStringSubmatch(rest); m != nil {
+				p.advanceNextNonspace()
+				p.advanceOffset(len(m[0]), false)
+				p.closeUnmatchedBlocks()
+				def := p.addChild(FootnoteDefinition)
+				def.Label = m[1]
+				if key := normalizeReference("[" + m[1] + "]"); p.footnotes[key] == nil {
+					p.footnotes[key] = def
+				}
+				return 1
+			}
+		}
+
+		// Admonition
+		if p.opts.Admonitions {
+			if m := reAdmonition.FindStringSubmatch(rest); m != nil {
+				p.closeUnmatchedBlocks()
+				c := p.addChild(Callout)
+				c.Foldable = m[1] != "!!!"
+				c.Expanded = m[1] == "???+"
+				c.CalloutType = calloutType(m[2])
+				c.Title = calloutTitle(m[2])
+				if m[3] != "" && (m[3] != `""` || !c.Foldable) {
+					// An empty title hides the title bar, except on a
+					// foldable admonition, whose title is the toggle
+					c.Title = m[3][1 : len(m[3])-1]
+				}
+				p.advanceOffset(len(line)-p.offset, false)
+				return 1
+			}
+		}
+
+		// Table delimiter row under a paragraph's last line
+		if p.opts.Tables && container.Kind == Paragraph && p.startTable(container) {
+			return 2
+		}
+
+		// Setext heading
+		if container.Kind == Paragraph && reSetextHeadingLine.MatchString(rest) {
+			p.closeUnmatchedBlocks()
+			content := container.content
+			for len(content) > 0 && content[0] == '[' {
+				n := parseReference(string(content), p.refmap)
+				if n == 0 {
+					break
+				}
+				content = content[n:]
+			}
+			if len(content) > 0 {
+				h := newNode(Heading, container.Line)
+				h.Level = 1
+				if rest[0] == '-' {
+					h.Level = 2
+				}
+				h.content = content
+				container.InsertAfter(h)
+				container.unlink()
+				p.tip = h
+				p.advanceOffset(len(line)-p.offset, false)
+				return 2
+			}
+			container.content = content
+		}
+
+		// Thematic break
+		if reThematicBreak.MatchString(rest) {
+			p.closeUnmatchedBlocks()
+			p.addChild(ThematicBreak)
+			p.advanceOffset(len(line)-p.offset, false)
+			return 2
+		}
+	}
+
+	// List item
+	if !p.indented || container.Kind == List {
+		if data := p.parseListMarker(container); data != nil {
+			p.closeUnmatchedBlocks()
+			if p.tip.Kind != List || !listsMatch(p.tip.list, data) {
+				list := p.addChild(List)
+				list.list = data
+			}
+			item := p.addChild(Item)
+			item.list = data
+			return 1
+		}
+	}
+
+	// Indented code block
+	if p.indented && p.tip.Kind != Paragraph && p.tip.Kind != Table && !p.blank {
+		p.advanceOffset(codeIndent, true)
+		p.closeUnmatchedBlocks()
+		p.addChild(CodeBlock)
+		return 2
+	}
+
+	return 0
+}
+
+// parseListMarker parses a list marker at the current position, advancing
+// past it and the following spaces
+func (p *blockParser) parseListMarker(container *Node) *listData {
+	if p.indent >= 4 {
+		return nil
+	}
+	line := p.currentLine
+	rest := line[p.nextNonspace:]
+	data := &listData{markerOffset: p.indent}
+
+	var markerLen int
+	if m := reBulletListMarker.FindString(rest); m != "" {
+		data.bulletChar = m[0]
+		markerLen = 1
+	} else if m := reOrderedListMarker.FindStringSubmatch(rest); m != nil && (container.Kind != Paragraph || m[1] == "1") {
+		data.ordered = true
+		data.start, _ = strconv.Atoi(m[1])
+		data.delimiter = m[2][0]
+		markerLen = len(m[0])
+	} else {
+		return nil
+	}
+
+	// The marker must be followed by a space, a tab or the end of the line
+	next := peek(line, p.nextNonspace+markerLen)
+	if next != 0 && next != ' ' && next != '\t' {
+		return nil
+	}
+	// An item that interrupts a paragraph can't start with a blank line
+	if container.Kind == Paragraph && !reNonSpace.MatchString(line[p.nextNonspace+markerLen:]) {
+		return nil
+	}
+
+	p.advanceNextNonspace()
+	p.advanceOffset(markerLen, true)
+	spacesStartCol := p.column
+	spacesStartOffset := p.offset
+	for {
+		p.advanceOffset(1, true)
+		if p.column-spacesStartCol >= 5 || !isSpaceOrTab(peek(line, p.offset)) {
+			break
+		}
+	}
+	blankItem := p.offset >= len(line)
+	spacesAfterMarker := p.column - spacesStartCol
+	if spacesAfterMarker >= 5 || spacesAfterMarker < 1 || blankItem {
+		data.padding = markerLen + 1
+		p.column = spacesStartCol
+		p.offset = spacesStartOffset
+		if isSpaceOrTab(peek(line, p.offset)) {
+			p.advanceOffset(1, true)
+		}
+	} else {
+		data.padding = markerLen + spacesAfterMarker
+	}
+	return data
+}
+
+func listsMatch(a, b *listData) bool {
+	return a.ordered == b.ordered && a.delimiter == b.delimiter && a.bulletChar == b.bulletChar
+}
+
+// startTable turns the last line of paragraph into a table header when the
+// current line is a matching delimiter row
+func (p *blockParser) startTable(para *Node) bool {
+	delim := p.currentLine[p.nextNonspace:]
+	content := strings.TrimSuffix(string(para.content), "\n")
+	headerStart := strings.LastIndexByte(content, '\n') + 1
+	header := content[headerStart:]
+	if !strings.Contains(delim, "|") && !strings.Contains(header, "|") {
+		return false
+	}
+	aligns := parseDelimiterRow(delim)
+	if aligns == nil || len(splitTableRow(header)) != len(aligns) {
+		return false
+	}
+
+	p.closeUnmatchedBlocks()
+	headerLine := para.Line + strings.Count(content[:headerStart], "\n")
+	if headerStart == 0 {
+		// The whole paragraph is the header row
+		para.Kind = Table
+		para.content = nil
+	} else {
+		para.content = []byte(content[:headerStart])
+		p.finalize(para, headerLine-1)
+		p.tip = para.Parent
+		table := newNode(Table, headerLine)
+		p.tip.AppendChild(table)
+		p.tip = table
+		para = table
+	}
+	para.aligns = aligns
+	para.content = append([]byte(header), '\n')
+	p.advanceOffset(len(p.currentLine)-p.offset, false)
+	return true
+}
+
+// parseDelimiterRow returns the column alignments of a table delimiter row,
+// or nil if s isn't one
+func parseDelimiterRow(s string) []Align {
+	cells := splitTableRow(s)
+	if len(cells) == 0 {
+		return nil
+	}
+	aligns := make([]Align, len(cells))
+	for i, cell := range cells {
+		if !reTableDelimiterCell.MatchString(cell) {
+			return nil
+		}
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns[i] = AlignCenter
+		case left:
+			aligns[i] = AlignLeft
+		case right:
+			aligns[i] = AlignRight
+		}
+	}
+	return aligns
+}
+
+// splitTableRow splits a table row into trimmed cells. Leading and trailing
+// pipes are optional and an escaped pipe stays in its cell as "|".
+func splitTableRow(row string) []string {
+	row = strings.Trim(row, " \t")
+	if strings.HasPrefix(row, "|") {
+		row = row[1:]
+	}
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.Trim(cell.String(), " \t"))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	cells = append(cells, strings.Trim(cell.String(), " \t"))
+	if len(cells) == 1 && cells[0] == "" {
+		return nil
+	}
+	return cells
+}
+
+// finalize closes a block, ending it at line
+func (p *blockParser) finalize(n *Node, line int) {
+	above := n.Parent
+	n.open = false
+	n.EndLine = line
+
+	switch n.Kind {
+	case Paragraph:
+		for len(n.content) > 0 && n.content[0] == '[' {
+			k := parseReference(string(n.content), p.refmap)
+			if k == 0 {
+				break
+			}
+			n.content = n.content[k:]
+		}
+		if !reNonSpace.Match(n.content) {
+			n.unlink()
+		}
+	case CodeBlock:
+		content := string(n.content)
+		if n.Fenced {
+			firstLine, rest, _ := strings.Cut(content, "\n")
+			n.Info = unescapeString(strings.Trim(firstLine, " \t"))
+			n.Literal = rest
+		} else {
+			lines := strings.Split(content, "\n")
+			for len(lines) > 0 && strings.Trim(lines[len(lines)-1], " \t") == "" {
+				lines = lines[:len(lines)-1]
+			}
+			n.Literal = strings.Join(lines, "\n") + "\n"
+			n.EndLine = n.Line + len(lines) - 1
+		}
+		n.content = nil
+	case HTMLBlock:
+		n.Literal = reTrailingBlankLines.ReplaceAllString(string(n.content), "")
+		n.content = nil
+	case MathBlock:
+		n.Literal = strings.Trim(string(n.content), " \t\n")
+		n.content = nil
+	case Table:
+		p.finalizeTable(n)
+	case BlockQuote:
+		if p.opts.Alerts {
+			p.finalizeAlert(n)
+		}
+	case Item, FootnoteDefinition, Callout:
+		if n.LastChild != nil {
+			n.EndLine = n.LastChild.EndLine
+		} else {
+			n.EndLine = n.Line
+		}
+	case List:
+		p.finalizeList(n)
+	}
+
+	p.tip = above
+}
+
+// finalizeAlert turns a block quote whose first line is a [!TYPE] marker
+// into a callout, dropping the marker
+func (p *blockParser) finalizeAlert(n *Node) {
+	para := n.FirstChild
+	if para == nil || para.Kind != Paragraph {
+		return
+	}
+	m := reAlertMarker.FindSubmatch(para.content)
+	if m == nil || !gitHubAlerts[strings.ToLower(string(m[1]))] {
+		return
+	}
+	rest := para.content[len(m[0]):]
+	if !reNonSpace.Match(rest) && para.NextSibling == nil {
+		// A marker with nothing under it stays a plain quote
+		return
+	}
+	n.Kind = Callout
+	n.CalloutType = calloutType(string(m[1]))
+	n.Title = calloutTitle(string(m[1]))
+	if reNonSpace.Match(rest) {
+		para.content = rest
+		para.Line++
+	} else {
+		para.unlink()
+	}
+}
+
+// calloutType returns the style of an alert or admonition type
+func calloutType(name string) string {
+	if t, ok := calloutTypes[strings.ToLower(name)]; ok {
+		return t
+	}
+	return "note"
+}
+
+// calloutTitle returns the default title of an alert or admonition type
+func calloutTitle(name string) string {
+	name = strings.ToLower(name)
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// finalizeList sets the list's fields and decides whether it is tight
+func (p *blockParser) finalizeList(n *Node) {
+	n.Ordered = n.list.ordered
+	n.Start = n.list.start
+	n.Delimiter = n.list.delimiter
+	n.BulletChar = n.list.bulletChar
+	n.Tight = true
+	if n.LastChild != nil {
+		n.EndLine = n.LastChild.EndLine
+	}
+	for item := n.FirstChild; item != nil; item = item.NextSibling {
+		if item.NextSibling != nil && endsWithBlankLine(item) {
+			n.Tight = false
+			return
+		}
+		for sub := item.FirstChild; sub != nil; sub = sub.NextSibling {
+			if (item.NextSibling != nil || sub.NextSibling != nil) && endsWithBlankLine(sub) {
+				n.Tight = false
+				return
+			}
+		}
+	}
+}
+
+// endsWithBlankLine reports whether a blank line separates n from the block
+// after it
+func endsWithBlankLine(n *Node) bool {
+	return n.NextSibling != nil && n.EndLine != n.NextSibling.Line-1
+}
+
+// finalizeTable splits the collected rows into cells
+func (p *blockParser) finalizeTable(n *Node) {
+	rows := strings.Split(strings.TrimSuffix(string(n.content), "\n"), "\n")
+	n.content = nil
+	for i, row := range rows {
+		r := newNode(TableRow, n.Line+i)
+		if i == 0 {
+			r.Header = true
+		} else {
+			// The delimiter row sits between the header and the first body row
+			r.Line++
+		}
+		r.open = false
+		r.EndLine = r.Line
+		cells := splitTableRow(row)
+		for c, align := range n.aligns {
+			cell := newNode(TableCell, r.Line)
+			cell.open = false
+			cell.EndLine = r.Line
+			cell.Align = align
+			cell.Header = r.Header
+			if c < len(cells) {
+				cell.content = []byte(cells[c])
+			}
+			r.AppendChild(cell)
+		}
+		n.AppendChild(r)
+	}
+	if last := n.LastChild; last != nil {
+		n.EndLine = last.Line
+	}
+}
@@ -0,0 +1,209 @@
+package markdown
+
+import "testing"
+
+func TestGFMExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "strikethrough",
+			markdown: "~~Hi~~ Hello, ~there~ world!\n",
+			want:     "<p><del>Hi</del> Hello, <del>there</del> world!</p>\n",
+		},
+		{
+			name:     "strikethrough run lengths must match",
+			markdown: "This ~~has a\n\nnew paragraph~~.\n\nA ~~~x~~~ and ~a~~\n",
+			want:     "<p>This ~~has a</p>\n<p>new paragraph~~.</p>\n<p>A ~~~x~~~ and ~a~~</p>\n",
+		},
+		{
+			name:     "task list",
+			markdown: "- [ ] foo\n- [x] bar\n- [X] baz\n- [y] qux\n",
+			want:     "<ul>\n<li><input type=\"checkbox\" disabled=\"\" /> foo</li>\n<li><input type=\"checkbox\" checked=\"\" disabled=\"\" /> bar</li>\n<li><input type=\"checkbox\" checked=\"\" disabled=\"\" /> baz</li>\n<li>[y] qux</li>\n</ul>\n",
+		},
+		{
+			name:     "nested task list",
+			markdown: "- [x] foo\n  - [ ] bar\n",
+			want:     "<ul>\n<li><input type=\"checkbox\" checked=\"\" disabled=\"\" /> foo\n<ul>\n<li><input type=\"checkbox\" disabled=\"\" /> bar</li>\n</ul>\n</li>\n</ul>\n",
+		},
+		{
+			name:     "www autolink",
+			markdown: "Visit www.commonmark.org/help for more information.\n",
+			want:     "<p>Visit <a href=\"http://www.commonmark.org/help\">www.commonmark.org/help</a> for more information.</p>\n",
+		},
+		{
+			name:     "autolink trailing punctuation",
+			markdown: "Visit www.commonmark.org.\n\n(www.google.com/search?q=Markup+(business))\n",
+			want:     "<p>Visit <a href=\"http://www.commonmark.org\">www.commonmark.org</a>.</p>\n<p>(<a href=\"http://www.google.com/search?q=Markup+(business)\">www.google.com/search?q=Markup+(business)</a>)</p>\n",
+		},
+		{
+			name:     "url autolink",
+			markdown: "https://example.com/a_b, and *http://x.org*\n",
+			want:     "<p><a href=\"https://example.com/a_b\">https://example.com/a_b</a>, and <em><a href=\"http://x.org\">http://x.org</a></em></p>\n",
+		},
+		{
+			name:     "email autolink",
+			markdown: "foo@bar.baz. and a.b-c_d@a.b- and `x@y.z`\n",
+			want:     "<p><a href=\"mailto:foo@bar.baz\">foo@bar.baz</a>. and a.b-c_d@a.b- and <code>x@y.z</code></p>\n",
+		},
+		{
+			name:     "no autolink inside links",
+			markdown: "[www.a.com](http://b.com)\n",
+			want:     "<p><a href=\"http://b.com\">www.a.com</a></p>\n",
+		},
+		{
+			name:     "footnotes",
+			markdown: "Here[^b] and there[^a] and again[^b].\n\n[^a]: First.\n[^b]: Second\n    continued.\n[^unused]: Dropped.\n",
+			want: "<p>Here<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1\">1</a></sup> and there<sup class=\"footnote-ref\"><a href=\"#fn-2\" id=\"fnref-2\">2</a></sup> and again<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1-2\">1</a></sup>.</p>\n" +
+				"<section class=\"footnotes\">\n<ol>\n" +
+				"<li id=\"fn-1\">\n<p>Second\ncontinued. <a href=\"#fnref-1\" class=\"footnote-backref\">&#x21A9;</a> <a href=\"#fnref-1-2\" class=\"footnote-backref\">&#x21A9;</a></p>\n</li>\n" +
+				"<li id=\"fn-2\">\n<p>First. <a href=\"#fnref-2\" class=\"footnote-backref\">&#x21A9;</a></p>\n</li>\n" +
+				"</ol>\n</section>\n",
+		},
+		{
+			name:     "undefined footnote",
+			markdown: "Missing[^x].\n",
+			want:     "<p>Missing[^x].</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&HTMLRenderer{}).Render(Parse(tt.markdown))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFootnoteListLines(t *testing.T) {
+	doc := Parse("a[^1]\n\n[^1]: note\n\nb\n")
+	list := doc.LastChild
+	if list.Kind != FootnoteList || list.Line != 3 || list.EndLine != 3 {
+		t.Fatalf("last block = %s lines %d-%d, want footnote_list lines 3-3", list.Kind, list.Line, list.EndLine)
+	}
+}
+
+func TestCallouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "github alert",
+			markdown: "> [!WARNING]\n> Careful *now*.\n",
+			want:     "<div class=\"callout callout-warning\">\n<p class=\"callout-title\">Warning</p>\n<p>Careful <em>now</em>.</p>\n</div>\n",
+		},
+		{
+			name:     "unknown or empty alert stays a quote",
+			markdown: "> [!FOO]\n> x\n\n> [!NOTE]\n",
+			want:     "<blockquote>\n<p>[!FOO]\nx</p>\n</blockquote>\n<blockquote>\n<p>[!NOTE]</p>\n</blockquote>\n",
+		},
+		{
+			name:     "admonition with title",
+			markdown: "!!! hint \"Pro tip\"\n    One.\n\n    Two.\nafter\n\nout\n",
+			want:     "<div class=\"callout callout-tip\">\n<p class=\"callout-title\">Pro tip</p>\n<p>One.</p>\n<p>Two.\nafter</p>\n</div>\n<p>out</p>\n",
+		},
+		{
+			name:     "admonition without title",
+			markdown: "!!! note \"\"\n    Body.\n",
+			want:     "<div class=\"callout callout-note\">\n<p>Body.</p>\n</div>\n",
+		},
+		{
+			name:     "foldable admonitions",
+			markdown: "??? danger\n    Hidden.\n\n???+ example \"\"\n    Shown.\n",
+			want:     "<details class=\"callout callout-danger\">\n<summary class=\"callout-title\">Danger</summary>\n<p>Hidden.</p>\n</details>\n<details class=\"callout callout-example\" open=\"\">\n<summary class=\"callout-title\">Example</summary>\n<p>Shown.</p>\n</details>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&HTMLRenderer{}).Render(Parse(tt.markdown))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertLines(t *testing.T) {
+	callout := Parse("text\n\n> [!TIP]\n> body\n").LastChild
+	if callout.Kind != Callout || callout.Line != 3 || callout.FirstChild.Line != 4 {
+		t.Fatalf("got %s at line %d, body at %d; want callout at 3, body at 4", callout.Kind, callout.Line, callout.FirstChild.Line)
+	}
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "inline",
+			markdown: "Euler: $e^{i\\pi} + 1 = 0$ and $$\\sum_i x_i$$.\n",
+			want:     "<p>Euler: <span class=\"math math-inline\">e^{i\\pi} + 1 = 0</span> and <span class=\"math math-display\">\\sum_i x_i</span>.</p>\n",
+		},
+		{
+			name:     "emphasis characters stay in math",
+			markdown: "$a_1 * b_2 * c$ and *d*\n",
+			want:     "<p><span class=\"math math-inline\">a_1 * b_2 * c</span> and <em>d</em></p>\n",
+		},
+		{
+			name:     "dollar amounts stay text",
+			markdown: "It costs $5 or $10 today.\n\n\\$x$, $ x $ and $y $2\n",
+			want:     "<p>It costs $5 or $10 today.</p>\n<p>$x$, $ x $ and $y $2</p>\n",
+		},
+		{
+			name:     "display block",
+			markdown: "Before\n$$\n\\frac{a}{b}\n  = c\n$$\nafter\n\n$$ x^2 $$\n",
+			want:     "<p>Before</p>\n<div class=\"math math-display\">\\frac{a}{b}\n  = c</div>\n<p>after</p>\n<div class=\"math math-display\">x^2</div>\n",
+		},
+		{
+			name:     "display block closed on its last line",
+			markdown: "> $$ a +\n> b $$\n",
+			want:     "<blockquote>\n<div class=\"math math-display\">a +\nb</div>\n</blockquote>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&HTMLRenderer{}).Render(Parse(tt.markdown))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWikiLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "note and label",
+			markdown: "See [[Project Plan]] and [[notes/todo#Next|what's next]].\n",
+			want:     "<p>See <a class=\"wikilink\" href=\"Project%20Plan\">Project Plan</a> and <a class=\"wikilink\" href=\"notes/todo#Next\">what's next</a>.</p>\n",
+		},
+		{
+			name:     "not wiki links",
+			markdown: "[[]] and [[a\nb]] and `[[code]]`\n",
+			want:     "<p>[[]] and [[a\nb]] and <code>[[code]]</code></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&HTMLRenderer{}).Render(Parse(tt.markdown))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HTMLRenderer renders a syntax tree as HTML in the style of the CommonMark
+// reference implementation
+type HTMLRenderer struct {
+	// Unsafe passes raw HTML through; otherwise it is escaped and shown as text
+	Unsafe bool
+	// SanitizeURL, if set, rewrites link and image destinations
+	SanitizeURL func(string) string
+	// Override, if set, is called for each node before the default
+	// rendering. Returning true means the node, including its children, was
+	// written by Override.
+	Override func(w *HTMLWriter, n *Node) bool
+}
+
+// HTMLWriter accumulates rendered HTML
+type HTMLWriter struct {
+	r           *HTMLRenderer
+	buf         []byte
+	disableTags int
+}
+
+// RenderHTML renders a document with the default renderer
+func RenderHTML(doc *Node) string {
+	return (&HTMLRenderer{}).Render(doc)
+}
+
+// Render renders n and its descendants
+func (r *HTMLRenderer) Render(n *Node) string {
+	w := &HTMLWriter{r: r}
+	w.Node(n)
+	return string(w.buf)
+}
+
+// Raw writes s unescaped
+func (w *HTMLWriter) Raw(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+// Text writes s with HTML special characters escaped
+func (w *HTMLWriter) Text(s string) {
+	w.buf = append(w.buf, EscapeHTML(s)...)
+}
+
+// CR writes a newline unless output is empty or already ends with one
+func (w *HTMLWriter) CR() {
+	if len(w.buf) > 0 && w.buf[len(w.buf)-1] != '\n' {
+		w.buf = append(w.buf, '\n')
+	}
+}
+
+// Tag writes an HTML tag unless tags are disabled inside image alt text
+func (w *HTMLWriter) Tag(s string) {
+	if w.disableTags == 0 {
+		w.buf = append(w.buf, s...)
+	}
+}
+
+// URL returns a link or image destination after sanitizing
+func (w *HTMLWriter) URL(dest string) string {
+	if w.r.SanitizeURL != nil {
+		return w.r.SanitizeURL(dest)
+	}
+	return dest
+}
+
+// Children renders the children of n
+func (w *HTMLWriter) Children(n *Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.Node(c)
+	}
+}
+
+// Node renders n, giving the renderer's Override the first chance
+func (w *HTMLWriter) Node(n *Node) {
+	if w.r.Override != nil && w.r.Override(w, n) {
+		return
+	}
+	w.Default(n)
+}
+
+// Default renders n with the standard CommonMark output
+func (w *HTMLWriter) Default(n *Node) {
+	switch n.Kind {
+	case Document:
+		w.Children(n)
+	case Paragraph:
+		if InTightList(n) {
+			w.Children(n)
+			return
+		}
+		w.CR()
+		w.Tag("<p>")
+		w.Children(n)
+		w.Raw(FootnoteBackrefs(n))
+		w.Tag("</p>")
+		w.CR()
+	case Heading:
+		level := strconv.Itoa(n.Level)
+		w.CR()
+		w.Tag("<h" + level + ">")
+		w.Children(n)
+		w.Tag("</h" + level + ">")
+		w.CR()
+	case BlockQuote:
+		w.CR()
+		w.Tag("<blockquote>")
+		w.CR()
+		w.Children(n)
+		w.CR()
+		w.Tag("</blockquote>")
+		w.CR()
+	case List:
+		name := "ul"
+		attrs := ""
+		if n.Ordered {
+			name = "ol"
+			if n.Start != 1 {
+				attrs = ` start="` + strconv.Itoa(n.Start) + `"`
+			}
+		}
+		w.CR()
+		w.Tag("<" + name + attrs + ">")
+		w.CR()
+		w.Children(n)
+		w.CR()
+		w.Tag("</" + name + ">")
+		w.CR()
+	case Item:
+		w.Tag("<li>")
+		w.Children(n)
+		w.Tag("</li>")
+		w.CR()
+	case CodeBlock:
+		w.CR()
+		w.Tag("<pre><code")
+		if lang := InfoLanguage(n.Info); lang != "" {
+			w.Tag(` class="language-` + EscapeHTML(lang) + `"`)
+		}
+		w.Tag(">")
+		w.Text(n.Literal)
+		w.Tag("</code></pre>")
+		w.CR()
+	case HTMLBlock:
+		w.CR()
+		if w.r.Unsafe {
+			w.Raw(n.Literal)
+		} else {
+			w.Tag("<p>")
+			w.Text(n.Literal)
+			w.Tag("</p>")
+		}
+		w.CR()
+	case ThematicBreak:
+		w.CR()
+		w.Tag("<hr />")
+		w.CR()
+	case Table:
+		w.CR()
+		w.Tag("<table>")
+		w.CR()
+		for row := n.FirstChild; row != nil; row = row.NextSibling {
+			if row.Header {
+				w.Tag("<thead>")
+				w.CR()
+				w.Node(row)
+				w.Tag("</thead>")
+				w.CR()
+				if row.NextSibling != nil {
+					w.Tag("<tbody>")
+					w.CR()
+				}
+			} else {
+				w.Node(row)
+			}
+		}
+		if n.FirstChild != nil && n.FirstChild != n.LastChild {
+			w.Tag("</tbody>")
+			w.CR()
+		}
+		w.Tag("</table>")
+		w.CR()
+	case TableRow:
+		w.Tag("<tr>")
+		w.CR()
+		w.Children(n)
+		w.Tag("</tr>")
+		w.CR()
+	case TableCell:
+		name := "td"
+		if n.Header {
+			name = "th"
+		}
+		attrs := ""
+		if align := n.Align.String(); align != "" {
+			attrs = ` align="` + align + `"`
+		}
+		w.Tag("<" + name + attrs + ">")
+		w.Children(n)
+		w.Tag("</" + name + ">")
+		w.CR()
+	case FootnoteList:
+		w.CR()
+		w.Tag(`<section class="footnotes">`)
+		w.CR()
+		w.Tag("<ol>")
+		w.CR()
+		w.Children(n)
+		w.Tag("</ol>")
+		w.CR()
+		w.Tag("</section>")
+		w.CR()
+	case FootnoteDefinition:
+		w.Tag(`<li id="fn-` + strconv.Itoa(n.Index) + `">`)
+		w.CR()
+		w.Children(n)
+		if n.LastChild == nil || n.LastChild.Kind != Paragraph {
+			w.Raw(FootnoteBackrefs(n))
+			w.CR()
+		}
+		w.Tag("</li>")
+		w.CR()
+	case Callout:
+		class := `class="callout callout-` + EscapeHTML(n.CalloutType) + `"`
+		w.CR()
+		if n.Foldable {
+			if n.Expanded {
+				class += ` open=""`
+			}
+			w.Tag("<details " + class + ">")
+			w.CR()
+			w.Tag(`<summary class="callout-title">`)
+			w.Text(n.Title)
+			w.Tag("</summary>")
+		} else {
+			w.Tag("<div " + class + ">")
+			if n.Title != "" {
+				w.CR()
+				w.Tag(`<p class="callout-title">`)
+				w.Text(n.Title)
+				w.Tag("</p>")
+			}
+		}
+		w.CR()
+		w.Children(n)
+		w.CR()
+		if n.Foldable {
+			w.Tag("</details>")
+		} else {
+			w.Tag("</div>")
+		}
+		w.CR()
+	case MathBlock:
+		w.CR()
+		w.Tag(`<div class="math math-display">`)
+		w.Text(n.Literal)
+		w.Tag("</div>")
+		w.CR()
+	case Text:
+		w.Text(n.Literal)
+	case SoftBreak:
+		w.Raw("\n")
+	case HardBreak:
+		w.Tag("<br />")
+		w.Raw("\n")
+	case CodeSpan:
+		w.Tag("<code>")
+		w.Text(n.Literal)
+		w.Tag("</code>")
+	case Math:
+		if n.Display {
+			w.Tag(`<span class="math math-display">`)
+		} else {
+			w.Tag(`<span class="math math-inline">`)
+		}
+		w.Text(n.Literal)
+		w.Tag("</span>")
+	case HTMLInline:
+		if w.r.Unsafe {
+			w.Raw(n.Literal)
+		} else {
+			w.Text(n.Literal)
+		}
+	case Emph:
+		w.Tag("<em>")
+		w.Children(n)
+		w.Tag("</em>")
+	case Strong:
+		w.Tag("<strong>")
+		w.Children(n)
+		w.Tag("</strong>")
+	case Strikethrough:
+		w.Tag("<del>")
+		w.Children(n)
+		w.Tag("</del>")
+	case TaskCheckbox:
+		if n.Checked {
+			w.Tag(`<input type="checkbox" checked="" disabled="" />`)
+		} else {
+			w.Tag(`<input type="checkbox" disabled="" />`)
+		}
+	case FootnoteReference:
+		id := FootnoteRefID(n)
+		w.Tag(`<sup class="footnote-ref"><a href="#fn-` + strconv.Itoa(n.Index) + `" id="` + id + `">`)
+		w.Raw(strconv.Itoa(n.Index))
+		w.Tag("</a></sup>")
+	case WikiLink:
+		w.Tag(`<a class="wikilink" href="` + EscapeHTML(w.URL(normalizeURI(n.Destination))) + `">`)
+		w.Children(n)
+		w.Tag("</a>")
+	case Link:
+		w.Tag(`<a href="` + EscapeHTML(w.URL(n.Destination)) + `"`)
+		if n.Title != "" {
+			w.Tag(` title="` + EscapeHTML(n.Title) + `"`)
+		}
+		w.Tag(">")
+		w.Children(n)
+		w.Tag("</a>")
+	case Image:
+		w.ImageAlt(n, func(alt string) {
+			w.Raw(`<img src="` + EscapeHTML(w.URL(n.Destination)) + `" alt="` + alt + `"`)
+			if n.Title != "" {
+				w.Raw(` title="` + EscapeHTML(n.Title) + `"`)
+			}
+			w.Raw(" />")
+		})
+	}
+}
+
+// ImageAlt renders the children of an image as escaped alt text, without
+// tags, and passes it to write. Nested images contribute only their text.
+func (w *HTMLWriter) ImageAlt(n *Node, write func(alt string)) {
+	if w.disableTags > 0 {
+		w.Children(n)
+		return
+	}
+	start := len(w.buf)
+	w.disableTags++
+	w.Children(n)
+	w.disableTags--
+	alt := string(w.buf[start:])
+	w.buf = w.buf[:start]
+	write(alt)
+}
+
+// FootnoteRefID returns the HTML id of a footnote reference: fnref-N for the
+// first reference to footnote N and fnref-N-K for the K-th
+func FootnoteRefID(n *Node) string {
+	id := "fnref-" + strconv.Itoa(n.Index)
+	if n.Refs > 1 {
+		id += "-" + strconv.Itoa(n.Refs)
+	}
+	return id
+}
+
+// FootnoteBackrefs returns the links from a footnote back to its references.
+// They belong at the end of the footnote's last paragraph, so it returns ""
+// for any other paragraph. For a footnote definition it returns the links
+// wrapped in a paragraph of their own.
+func FootnoteBackrefs(n *Node) string {
+	def := n
+	if n.Kind == Paragraph {
+		def = n.Parent
+		if def == nil || def.Kind != FootnoteDefinition || def.LastChild != n {
+			return ""
+		}
+	}
+	var sb strings.Builder
+	for k := 1; k <= def.Refs; k++ {
+		ref := &Node{Index: def.Index, Refs: k}
+		sb.WriteString(` <a href="#` + FootnoteRefID(ref) + `" class="footnote-backref">&#x21A9;</a>`)
+	}
+	if n.Kind == FootnoteDefinition {
+		return "<p>" + strings.TrimPrefix(sb.String(), " ") + "</p>"
+	}
+	return sb.String()
+}
+
+// InTightList reports whether a paragraph belongs to a tight list item,
+// where it is rendered without <p> tags
+func InTightList(n *Node) bool {
+	if n.Parent == nil || n.Parent.Parent == nil {
+		return false
+	}
+	list := n.Parent.Parent
+	return n.Parent.Kind == Item && list.Kind == List && list.Tight
+}
+
+// InfoLanguage returns the first word of a code block info string
+func InfoLanguage(info string) string {
+	if fields := strings.Fields(info); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+var htmlEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;")
+
+// EscapeHTML escapes &, <, > and " for HTML text and attribute values
+func EscapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
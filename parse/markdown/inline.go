@@ -0,0 +1,941 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Raw HTML patterns from the CommonMark spec
+const (
+	tagName           = `[A-Za-z][A-Za-z0-9-]*`
+	attributeName     = `[a-zA-Z_:][a-zA-Z0-9:._-]*`
+	unquotedValue     = "[^\"'=<>`\\x00-\\x20]+"
+	singleQuotedValue = `'[^']*'`
+	doubleQuotedValue = `"[^"]*"`
+	attributeValue    = `(?:` + unquotedValue + `|` + singleQuotedValue + `|` + doubleQuotedValue + `)`
+	attributeValSpec  = `(?:\s*=\s*` + attributeValue + `)`
+	attribute         = `(?:\s+` + attributeName + attributeValSpec + `?)`
+	openTag           = `<` + tagName + attribute + `*\s*/?>`
+	closeTag          = `</` + tagName + `\s*[>]`
+	htmlComment       = `<!-->|<!--->|<!--[\s\S]*?-->`
+	processingInstr   = `[<][?][\s\S]*?[?][>]`
+	declaration       = `<![A-Za-z]+[^>]*>`
+	cdata             = `<!\[CDATA\[[\s\S]*?\]\]>`
+	htmlTag           = `(?:` + openTag + `|` + closeTag + `|` + htmlComment + `|` + processingInstr + `|` + declaration + `|` + cdata + `)`
+
+	escapable = "[!\"#$%&'()*+,./:;<=>?@\\[\\\\\\]^_`{|}~-]"
+	entity    = `&(?:#[xX][a-fA-F0-9]{1,6}|#[0-9]{1,7}|[a-zA-Z][a-zA-Z0-9]{1,31});`
+)
+
+var (
+	reHTMLTag               = regexp.MustCompile(`^` + htmlTag)
+	reEntityHere            = regexp.MustCompile(`^` + entity)
+	reEntityOrEscapedChar   = regexp.MustCompile(`\\` + escapable + `|` + entity)
+	reEscapable             = regexp.MustCompile(`^` + escapable)
+	reLinkTitle             = regexp.MustCompile(`^(?:"(?:\\` + escapable + `|\\[^\\]|[^\\"\x00])*"|'(?:\\` + escapable + `|\\[^\\]|[^\\'\x00])*'|\((?:\\` + escapable + `|\\[^\\]|[^\\()\x00])*\))`)
+	reLinkDestinationBraces = regexp.MustCompile(`^(?:<(?:[^<>\n\\\x00]|\\.)*>)`)
+	reEmailAutolink         = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	reAutolink              = regexp.MustCompile(`^<[A-Za-z][A-Za-z0-9.+-]{1,31}:[^<>\x00-\x20]*>`)
+	reSpnl                  = regexp.MustCompile(`^[ \t]*(?:\n[ \t]*)?`)
+	reSpaceAtEndOfLine      = regexp.MustCompile(`^[ \t]*(?:\n|$)`)
+	reMain                  = regexp.MustCompile("^[^\n`\\[\\]\\\\!<&*_~$]+")
+	reWhitespace            = regexp.MustCompile(`[ \t\r\n]+`)
+	reTaskMarker            = regexp.MustCompile(`^\[([ xX])\][ \t]`)
+	reFootnoteReference     = regexp.MustCompile(`^\[\^([^\]\s]+)\]`)
+	reWikiLink              = regexp.MustCompile(`^\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+)
+
+// linkRef is a link reference definition
+type linkRef struct {
+	destination string
+	title       string
+}
+
+// delimiter is an entry in the emphasis delimiter stack
+type delimiter struct {
+	cc         byte
+	numDelims  int
+	origDelims int
+	node       *Node
+	previous   *delimiter
+	next       *delimiter
+	canOpen    bool
+	canClose   bool
+}
+
+// bracket is an entry in the link opener stack
+type bracket struct {
+	node              *Node
+	previous          *bracket
+	previousDelimiter *delimiter
+	index             int
+	image             bool
+	active            bool
+	bracketAfter      bool
+}
+
+// inlineParser parses the inline content of a block, following the
+// CommonMark reference delimiter algorithm
+type inlineParser struct {
+	opts       Options
+	subject    string
+	pos        int
+	delimiters *delimiter
+	brackets   *bracket
+	refmap     map[string]linkRef
+
+	// footnotes maps normalized labels to definitions; footnoteCount is the
+	// number of definitions referenced so far
+	footnotes     map[string]*Node
+	footnoteCount int
+}
+
+// parse replaces a block's raw content with inline children
+func (p *inlineParser) parse(block *Node) {
+	p.subject = strings.Trim(string(block.content), " \t\r\n")
+	p.pos = 0
+	p.delimiters = nil
+	p.brackets = nil
+	block.content = nil
+	if p.opts.TaskLists && isTaskCandidate(block) {
+		if m := reTaskMarker.FindStringSubmatch(p.subject); m != nil {
+			block.AppendChild(&Node{Kind: TaskCheckbox, Checked: m[1] != " "})
+			p.pos = 3
+		}
+	}
+	for p.parseInline(block) {
+	}
+	p.processEmphasis(nil)
+	if p.opts.Autolinks {
+		linkify(block)
+	}
+}
+
+// isTaskCandidate reports whether a paragraph opens a list item, where a
+// task list checkbox may appear
+func isTaskCandidate(block *Node) bool {
+	return block.Kind == Paragraph && block.Parent != nil && block.Parent.Kind == Item && block.Parent.FirstChild == block
+}
+
+// peek returns the byte at the current position, or 0 at the end
+func (p *inlineParser) peek() byte {
+	return peek(p.subject, p.pos)
+}
+
+// match advances past the first match of re at or after the current
+// position and returns it, or "" if there is none
+func (p *inlineParser) match(re *regexp.Regexp) string {
+	loc := re.FindStringIndex(p.subject[p.pos:])
+	if loc == nil {
+		return ""
+	}
+	m := p.subject[p.pos+loc[0] : p.pos+loc[1]]
+	p.pos += loc[1]
+	return m
+}
+
+func (p *inlineParser) parseInline(block *Node) bool {
+	if p.pos >= len(p.subject) {
+		return false
+	}
+	var res bool
+	switch c := p.peek(); c {
+	case '\n':
+		res = p.parseNewline(block)
+	case '\\':
+		res = p.parseBackslash(block)
+	case '`':
+		res = p.parseBackticks(block)
+	case '*', '_':
+		res = p.handleDelim(c, block)
+	case '~':
+		if p.opts.Strikethrough {
+			res = p.handleDelim(c, block)
+		}
+	case '[':
+		res = p.parseOpenBracket(block)
+	case '!':
+		res = p.parseBang(block)
+	case ']':
+		res = p.parseCloseBracket(block)
+	case '<':
+		res = p.parseAutolink(block) || p.parseHTMLTag(block)
+	case '&':
+		res = p.parseEntity(block)
+	case '$':
+		if p.opts.Math {
+			res = p.parseMath(block)
+		}
+	default:
+		res = p.parseString(block)
+	}
+	if !res {
+		_, size := utf8.DecodeRuneInString(p.subject[p.pos:])
+		block.AppendChild(text(p.subject[p.pos : p.pos+size]))
+		p.pos += size
+	}
+	return true
+}
+
+func (p *inlineParser) parseNewline(block *Node) bool {
+	p.pos++
+	last := block.LastChild
+	if last != nil && last.Kind == Text && strings.HasSuffix(last.Literal, " ") {
+		hard := strings.HasSuffix(last.Literal, "  ")
+		last.Literal = strings.TrimRight(last.Literal, " ")
+		if hard {
+			block.AppendChild(&Node{Kind: HardBreak})
+		} else {
+			block.AppendChild(&Node{Kind: SoftBreak})
+		}
+	} else {
+		block.AppendChild(&Node{Kind: SoftBreak})
+	}
+	// Skip leading spaces on the next line
+	for p.pos < len(p.subject) && p.subject[p.pos] == ' ' {
+		p.pos++
+	}
+	return true
+}
+
+func (p *inlineParser) parseBackslash(block *Node) bool {
+	p.pos++
+	switch {
+	case p.peek() == '\n':
+		p.pos++
+		block.AppendChild(&Node{Kind: HardBreak})
+	case p.pos < len(p.subject) && reEscapable.MatchString(p.subject[p.pos:p.pos+1]):
+		block.AppendChild(text(p.subject[p.pos : p.pos+1]))
+		p.pos++
+	default:
+		block.AppendChild(text(`\`))
+	}
+	return true
+}
+
+func (p *inlineParser) parseBackticks(block *Node) bool {
+	start := p.pos
+	for p.peek() == '`' {
+		p.pos++
+	}
+	ticks := p.subject[start:p.pos]
+	afterOpen := p.pos
+
+	for p.pos < len(p.subject) {
+		i := strings.IndexByte(p.subject[p.pos:], '`')
+		if i < 0 {
+			break
+		}
+		closeStart := p.pos + i
+		p.pos = closeStart
+		for p.peek() == '`' {
+			p.pos++
+		}
+		if p.pos-closeStart == len(ticks) {
+			contents := strings.ReplaceAll(p.subject[afterOpen:closeStart], "\n", " ")
+			if len(contents) > 0 && contents[0] == ' ' && contents[len(contents)-1] == ' ' && strings.Trim(contents, " ") != "" {
+				contents = contents[1 : len(contents)-1]
+			}
+			block.AppendChild(&Node{Kind: CodeSpan, Literal: contents})
+			return true
+		}
+	}
+	// No closing run: the opening backticks are literal
+	p.pos = afterOpen
+	block.AppendChild(text(ticks))
+	return true
+}
+
+// parseMath parses a $tex$ or $$tex$$ span. A single-dollar span can't start
+// or end with whitespace or be followed by a digit, so amounts like $5 and
+// $10 stay text.
+func (p *inlineParser) parseMath(block *Node) bool {
+	delim := "$"
+	if strings.HasPrefix(p.subject[p.pos:], "$$") {
+		delim = "$$"
+	}
+	start := p.pos + len(delim)
+	if delim == "$" && (start >= len(p.subject) || isUnicodeWhitespace(runeAt(p.subject, start))) {
+		return false
+	}
+	for i := start; ; {
+		j := strings.Index(p.subject[i:], delim)
+		if j < 0 {
+			break
+		}
+		end := i + j
+		i = end + 1
+		if end == start || p.subject[end-1] == '\\' {
+			continue
+		}
+		if next := peek(p.subject, end+1); delim == "$" && (isUnicodeWhitespace(runeBefore(p.subject, end)) || next >= '0' && next <= '9') {
+			continue
+		}
+		block.AppendChild(&Node{Kind: Math, Literal: p.subject[start:end], Display: delim == "$$"})
+		p.pos = end + len(delim)
+		return true
+	}
+	// No closing delimiter: the opening dollars are literal
+	p.pos = start
+	block.AppendChild(text(delim))
+	return true
+}
+
+func (p *inlineParser) parseAutolink(block *Node) bool {
+	if m := p.match(reEmailAutolink); m != "" {
+		dest := m[1 : len(m)-1]
+		link := &Node{Kind: Link, Destination: normalizeURI("mailto:" + dest)}
+		link.AppendChild(text(dest))
+		block.AppendChild(link)
+		return true
+	}
+	if m := p.match(reAutolink); m != "" {
+		dest := m[1 : len(m)-1]
+		link := &Node{Kind: Link, Destination: normalizeURI(dest)}
+		link.AppendChild(text(dest))
+		block.AppendChild(link)
+		return true
+	}
+	return false
+}
+
+func (p *inlineParser) parseHTMLTag(block *Node) bool {
+	m := p.match(reHTMLTag)
+	if m == "" {
+		return false
+	}
+	block.AppendChild(&Node{Kind: HTMLInline, Literal: m})
+	return true
+}
+
+func (p *inlineParser) parseEntity(block *Node) bool {
+	m := p.match(reEntityHere)
+	if m == "" {
+		return false
+	}
+	block.AppendChild(text(decodeEntity(m)))
+	return true
+}
+
+func (p *inlineParser) parseString(block *Node) bool {
+	m := p.match(reMain)
+	if m == "" {
+		return false
+	}
+	block.AppendChild(text(m))
+	return true
+}
+
+// runeBefore returns the rune ending at pos, or '\n' at the start
+func runeBefore(s string, pos int) rune {
+	if pos == 0 {
+		return '\n'
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:pos])
+	return r
+}
+
+// runeAt returns the rune starting at pos, or '\n' at the end
+func runeAt(s string, pos int) rune {
+	if pos >= len(s) {
+		return '\n'
+	}
+	r, _ := utf8.DecodeRuneInString(s[pos:])
+	return r
+}
+
+// isUnicodeWhitespace reports whether r is whitespace for emphasis flanking
+func isUnicodeWhitespace(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\f' || r == '\r' || unicode.Is(unicode.Zs, r)
+}
+
+// isUnicodePunctuation reports whether r is punctuation for emphasis flanking
+func isUnicodePunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// scanDelims measures the delimiter run at the current position and
+// whether it can open or close emphasis
+func (p *inlineParser) scanDelims(cc byte) (numDelims int, canOpen, canClose bool) {
+	start := p.pos
+	for peek(p.subject, start+numDelims) == cc {
+		numDelims++
+	}
+	before := runeBefore(p.subject, start)
+	after := runeAt(p.subject, start+numDelims)
+
+	afterIsWhitespace := isUnicodeWhitespace(after)
+	afterIsPunctuation := isUnicodePunctuation(after)
+	beforeIsWhitespace := isUnicodeWhitespace(before)
+	beforeIsPunctuation := isUnicodePunctuation(before)
+
+	leftFlanking := !afterIsWhitespace && (!afterIsPunctuation || beforeIsWhitespace || beforeIsPunctuation)
+	rightFlanking := !beforeIsWhitespace && (!beforeIsPunctuation || afterIsWhitespace || afterIsPunctuation)
+	if cc == '_' {
+		canOpen = leftFlanking && (!rightFlanking || beforeIsPunctuation)
+		canClose = rightFlanking && (!leftFlanking || afterIsPunctuation)
+	} else {
+		canOpen = leftFlanking
+		canClose = rightFlanking
+	}
+	return numDelims, canOpen, canClose
+}
+
+func (p *inlineParser) handleDelim(cc byte, block *Node) bool {
+	numDelims, canOpen, canClose := p.scanDelims(cc)
+	start := p.pos
+	p.pos += numDelims
+	node := text(p.subject[start:p.pos])
+	block.AppendChild(node)
+
+	// Strikethrough takes runs of one or two tildes
+	if cc == '~' && numDelims > 2 {
+		return true
+	}
+	if canOpen || canClose {
+		p.delimiters = &delimiter{
+			cc:         cc,
+			numDelims:  numDelims,
+			origDelims: numDelims,
+			node:       node,
+			previous:   p.delimiters,
+			canOpen:    canOpen,
+			canClose:   canClose,
+		}
+		if p.delimiters.previous != nil {
+			p.delimiters.previous.next = p.delimiters
+		}
+	}
+	return true
+}
+
+func (p *inlineParser) removeDelimiter(d *delimiter) {
+	if d.previous != nil {
+		d.previous.next = d.next
+	}
+	if d.next == nil {
+		// top of stack
+		p.delimiters = d.previous
+	} else {
+		d.next.previous = d.previous
+	}
+}
+
+func removeDelimitersBetween(bottom, top *delimiter) {
+	if bottom.next != top {
+		bottom.next = top
+		top.previous = bottom
+	}
+}
+
+// processEmphasis matches delimiter runs above stackBottom into Emph and
+// Strong nodes
+func (p *inlineParser) processEmphasis(stackBottom *delimiter) {
+	var openersBottom [14]*delimiter
+	for i := range openersBottom {
+		openersBottom[i] = stackBottom
+	}
+
+	// Find the first closer above stackBottom
+	closer := p.delimiters
+	for closer != nil && closer.previous != stackBottom {
+		closer = closer.previous
+	}
+
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		var bottomIndex int
+		switch closer.cc {
+		case '~':
+			bottomIndex = closer.origDelims - 1
+		case '_':
+			bottomIndex = 2
+		case '*':
+			bottomIndex = 8
+		}
+		if closer.cc != '~' {
+			if closer.canOpen {
+				bottomIndex += 3
+			}
+			bottomIndex += closer.origDelims % 3
+		}
+
+		// Look back for the first matching opener
+		opener := closer.previous
+		openerFound := false
+		for opener != nil && opener != stackBottom && opener != openersBottom[bottomIndex] {
+			oddMatch := (closer.canOpen || opener.canClose) && closer.origDelims%3 != 0 &&
+				(opener.origDelims+closer.origDelims)%3 == 0
+			if closer.cc == '~' {
+				// Tilde runs only match runs of the same length
+				oddMatch = opener.origDelims != closer.origDelims
+			}
+			if opener.cc == closer.cc && opener.canOpen && !oddMatch {
+				openerFound = true
+				break
+			}
+			opener = opener.previous
+		}
+
+		oldCloser := closer
+		if !openerFound {
+			closer = closer.next
+			openersBottom[bottomIndex] = oldCloser.previous
+			if !oldCloser.canOpen {
+				p.removeDelimiter(oldCloser)
+			}
+			continue
+		}
+
+		useDelims := 1
+		if closer.numDelims >= 2 && opener.numDelims >= 2 {
+			useDelims = 2
+		}
+		if closer.cc == '~' {
+			useDelims = closer.numDelims
+		}
+		openerInl := opener.node
+		closerInl := closer.node
+		opener.numDelims -= useDelims
+		closer.numDelims -= useDelims
+		openerInl.Literal = openerInl.Literal[:len(openerInl.Literal)-useDelims]
+		closerInl.Literal = closerInl.Literal[:len(closerInl.Literal)-useDelims]
+
+		emph := &Node{Kind: Emph}
+		switch {
+		case closer.cc == '~':
+			emph.Kind = Strikethrough
+		case useDelims == 2:
+			emph.Kind = Strong
+		}
+		for tmp := openerInl.NextSibling; tmp != nil && tmp != closerInl; {
+			next := tmp.NextSibling
+			emph.AppendChild(tmp)
+			tmp = next
+		}
+		openerInl.InsertAfter(emph)
+
+		removeDelimitersBetween(opener, closer)
+
+		if opener.numDelims == 0 {
+			openerInl.unlink()
+			p.removeDelimiter(opener)
+		}
+		if closer.numDelims == 0 {
+			closerInl.unlink()
+			next := closer.next
+			p.removeDelimiter(closer)
+			closer = next
+		}
+	}
+
+	for p.delimiters != nil && p.delimiters != stackBottom {
+		p.removeDelimiter(p.delimiters)
+	}
+}
+
+func (p *inlineParser) addBracket(node *Node, index int, image bool) {
+	if p.brackets != nil {
+		p.brackets.bracketAfter = true
+	}
+	p.brackets = &bracket{
+		node:              node,
+		previous:          p.brackets,
+		previousDelimiter: p.delimiters,
+		index:             index,
+		image:             image,
+		active:            true,
+	}
+}
+
+func (p *inlineParser) removeBracket() {
+	p.brackets = p.brackets.previous
+}
+
+func (p *inlineParser) parseOpenBracket(block *Node) bool {
+	if p.opts.WikiLinks && p.parseWikiLink(block) {
+		return true
+	}
+	if p.opts.Footnotes && p.parseFootnoteReference(block) {
+		return true
+	}
+	start := p.pos
+	p.pos++
+	node := text("[")
+	block.AppendChild(node)
+	p.addBracket(node, start, false)
+	return true
+}
+
+// parseWikiLink parses [[note]] and [[note|label]]. The label is plain
+// text; without one the note name is shown.
+func (p *inlineParser) parseWikiLink(block *Node) bool {
+	m := reWikiLink.FindStringSubmatch(p.subject[p.pos:])
+	if m == nil {
+		return false
+	}
+	target := strings.TrimSpace(m[1])
+	label := strings.TrimSpace(m[2])
+	if target == "" {
+		return false
+	}
+	if label == "" {
+		label = target
+	}
+	p.pos += len(m[0])
+	link := &Node{Kind: WikiLink, Destination: target}
+	link.AppendChild(text(label))
+	block.AppendChild(link)
+	return true
+}
+
+// parseFootnoteReference parses [^label] when label has a definition,
+// numbering footnotes in order of first reference
+func (p *inlineParser) parseFootnoteReference(block *Node) bool {
+	m := reFootnoteReference.FindStringSubmatch(p.subject[p.pos:])
+	if m == nil {
+		return false
+	}
+	def := p.footnotes[normalizeReference("["+m[1]+"]")]
+	if def == nil {
+		return false
+	}
+	if def.Index == 0 {
+		p.footnoteCount++
+		def.Index = p.footnoteCount
+	}
+	def.Refs++
+	p.pos += len(m[0])
+	block.AppendChild(&Node{Kind: FootnoteReference, Label: def.Label, Index: def.Index, Refs: def.Refs})
+	return true
+}
+
+func (p *inlineParser) parseBang(block *Node) bool {
+	start := p.pos
+	p.pos++
+	if p.peek() == '[' {
+		p.pos++
+		node := text("![")
+		block.AppendChild(node)
+		p.addBracket(node, start+1, true)
+	} else {
+		block.AppendChild(text("!"))
+	}
+	return true
+}
+
+func (p *inlineParser) parseCloseBracket(block *Node) bool {
+	p.pos++
+	start := p.pos
+
+	opener := p.brackets
+	if opener == nil {
+		block.AppendChild(text("]"))
+		return true
+	}
+	if !opener.active {
+		block.AppendChild(text("]"))
+		p.removeBracket()
+		return true
+	}
+
+	var dest, title string
+	matched := false
+	savePos := p.pos
+
+	// Inline link: [text](destination "title")
+	if p.peek() == '(' {
+		p.pos++
+		p.spnl()
+		if d, ok := p.parseLinkDestination(); ok {
+			dest = d
+			beforeTitle := p.pos
+			p.spnl()
+			// A title must be separated from the destination by whitespace
+			if p.pos > beforeTitle {
+				if t, ok := p.parseLinkTitle(); ok {
+					title = t
+				}
+			}
+			p.spnl()
+			if p.peek() == ')' {
+				p.pos++
+				matched = true
+			}
+		}
+		if !matched {
+			p.pos = savePos
+		}
+	}
+
+	// Reference link: [text][label], [text][] or [text]
+	if !matched {
+		beforeLabel := p.pos
+		n := p.parseLinkLabel()
+		var refLabel string
+		if n > 2 {
+			refLabel = p.subject[beforeLabel : beforeLabel+n]
+		} else if !opener.bracketAfter {
+			// An empty or missing second label uses the link text as label
+			refLabel = p.subject[opener.index:start]
+		}
+		if n == 0 {
+			// Shortcut reference: rewind before the skipped label
+			p.pos = savePos
+		}
+		if refLabel != "" {
+			if ref, ok := p.refmap[normalizeReference(refLabel)]; ok {
+				dest = ref.destination
+				title = ref.title
+				matched = true
+			}
+		}
+	}
+
+	if !matched {
+		p.removeBracket()
+		p.pos = start
+		block.AppendChild(text("]"))
+		return true
+	}
+
+	node := &Node{Kind: Link, Destination: dest, Title: title}
+	if opener.image {
+		node.Kind = Image
+	}
+	for tmp := opener.node.NextSibling; tmp != nil; {
+		next := tmp.NextSibling
+		node.AppendChild(tmp)
+		tmp = next
+	}
+	block.AppendChild(node)
+	p.processEmphasis(opener.previousDelimiter)
+	p.removeBracket()
+	opener.node.unlink()
+
+	// Links can't contain other links, so earlier link openers are disabled
+	if !opener.image {
+		for b := p.brackets; b != nil; b = b.previous {
+			if !b.image {
+				b.active = false
+			}
+		}
+	}
+	return true
+}
+
+// spnl skips spaces and tabs, including up to one newline
+func (p *inlineParser) spnl() {
+	p.match(reSpnl)
+}
+
+// parseLinkTitle parses a quoted or parenthesized link title
+func (p *inlineParser) parseLinkTitle() (string, bool) {
+	m := reLinkTitle.FindString(p.subject[p.pos:])
+	if m == "" {
+		return "", false
+	}
+	p.pos += len(m)
+	return unescapeString(m[1 : len(m)-1]), true
+}
+
+// parseLinkDestination parses a link destination in angle brackets or a
+// bare destination with balanced parentheses
+func (p *inlineParser) parseLinkDestination() (string, bool) {
+	if m := reLinkDestinationBraces.FindString(p.subject[p.pos:]); m != "" {
+		p.pos += len(m)
+		return normalizeURI(unescapeString(m[1 : len(m)-1])), true
+	}
+	if p.peek() == '<' {
+		return "", false
+	}
+	start := p.pos
+	openParens := 0
+	for p.pos < len(p.subject) {
+		c := p.subject[p.pos]
+		if c == '\\' && p.pos+1 < len(p.subject) && reEscapable.MatchString(p.subject[p.pos+1:p.pos+2]) {
+			p.pos += 2
+		} else if c == '(' {
+			p.pos++
+			openParens++
+		} else if c == ')' {
+			if openParens < 1 {
+				break
+			}
+			p.pos++
+			openParens--
+		} else if c <= ' ' || c == 0x7f {
+			break
+		} else {
+			p.pos++
+		}
+	}
+	if p.pos == start && p.peek() != ')' {
+		return "", false
+	}
+	if openParens != 0 {
+		return "", false
+	}
+	return normalizeURI(unescapeString(p.subject[start:p.pos])), true
+}
+
+// parseLinkLabel returns the length of a link label at the current
+// position, advancing past it, or 0 if there isn't one
+func (p *inlineParser) parseLinkLabel() int {
+	n := linkLabelLength(p.subject[p.pos:])
+	p.pos += n
+	return n
+}
+
+// linkLabelLength returns the length of the link label at the start of s,
+// including brackets, or 0 if s doesn't start with one
+func linkLabelLength(s string) int {
+	if peek(s, 0) != '[' {
+		return 0
+	}
+	for i := 1; i < len(s) && i <= 1000; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			return 0
+		case ']':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// parseReference parses link reference definitions at the start of s into
+// refmap and returns the number of bytes consumed, or 0
+func parseReference(s string, refmap map[string]linkRef) int {
+	p := &inlineParser{subject: s}
+
+	n := p.parseLinkLabel()
+	if n == 0 {
+		return 0
+	}
+	rawLabel := s[:n]
+
+	if p.peek() != ':' {
+		return 0
+	}
+	p.pos++
+
+	p.spnl()
+	dest, ok := p.parseLinkDestination()
+	if !ok {
+		return 0
+	}
+
+	beforeTitle := p.pos
+	p.spnl()
+	title := ""
+	hasTitle := false
+	if p.pos != beforeTitle {
+		title, hasTitle = p.parseLinkTitle()
+	}
+	if !hasTitle {
+		p.pos = beforeTitle
+	}
+
+	// The definition must end at the end of a line
+	if m := reSpaceAtEndOfLine.FindString(p.subject[p.pos:]); m != "" || p.pos == len(p.subject) {
+		p.pos += len(m)
+	} else {
+		if !hasTitle {
+			return 0
+		}
+		// The title isn't at the end of the line; try without it
+		title = ""
+		p.pos = beforeTitle
+		m := reSpaceAtEndOfLine.FindString(p.subject[p.pos:])
+		if m == "" && p.pos != len(p.subject) {
+			return 0
+		}
+		p.pos += len(m)
+	}
+
+	label := normalizeReference(rawLabel)
+	if label == "" {
+		return 0
+	}
+	if _, exists := refmap[label]; !exists {
+		refmap[label] = linkRef{destination: dest, title: title}
+	}
+	return p.pos
+}
+
+// normalizeReference case-folds a link label and collapses its whitespace
+func normalizeReference(label string) string {
+	label = strings.Trim(label[1:len(label)-1], " \t\r\n")
+	label = reWhitespace.ReplaceAllString(label, " ")
+	// Unicode case folding maps ß to "ss", which simple case mapping misses
+	return strings.ToUpper(strings.ReplaceAll(strings.ToLower(label), "ß", "ss"))
+}
+
+// unescapeString resolves backslash escapes and entities
+func unescapeString(s string) string {
+	if !strings.ContainsAny(s, `\&`) {
+		return s
+	}
+	return reEntityOrEscapedChar.ReplaceAllStringFunc(s, func(m string) string {
+		if m[0] == '\\' {
+			return m[1:]
+		}
+		return decodeEntity(m)
+	})
+}
+
+// decodeEntity decodes an HTML entity, leaving unknown names unchanged
+func decodeEntity(s string) string {
+	r := html.UnescapeString(s)
+	if s[1] == '#' {
+		if r == s {
+			return "\uFFFD"
+		}
+		return r
+	}
+	// html.UnescapeString also decodes a known prefix of an unknown name,
+	// as in "&notit;", which CommonMark leaves as text
+	if strings.HasSuffix(r, ";") && s != "&semi;" {
+		return s
+	}
+	return r
+}
+
+// normalizeURI percent-encodes characters not allowed in a URL, keeping
+// existing escapes
+func normalizeURI(uri string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(uri); i++ {
+		c := uri[i]
+		switch {
+		case c == '%' && i+2 < len(uri) && isHex(uri[i+1]) && isHex(uri[i+2]):
+			b.WriteByte(c)
+		case c < 0x80 && (isAlnum(c) || strings.IndexByte(";/?:@&=+$,-_.!~*'()#", c) >= 0):
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,34 @@
+package markdown
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// specExample is one example from the CommonMark spec
+type specExample struct {
+	Markdown string `json:"markdown"`
+	HTML     string `json:"html"`
+	Example  int    `json:"example"`
+	Section  string `json:"section"`
+}
+
+func TestCommonMarkSpec(t *testing.T) {
+	data, err := os.ReadFile("testdata/spec.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var examples []specExample
+	if err := json.Unmarshal(data, &examples); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &HTMLRenderer{Unsafe: true}
+	for _, ex := range examples {
+		got := r.Render(ParseWithOptions(ex.Markdown, Options{}))
+		if got != ex.HTML {
+			t.Errorf("example %d (%s)\nmarkdown: %q\ngot:  %q\nwant: %q", ex.Example, ex.Section, ex.Markdown, got, ex.HTML)
+		}
+	}
+}
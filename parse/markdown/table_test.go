@@ -0,0 +1,81 @@
+package markdown
+
+import "testing"
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "basic",
+			markdown: "| foo | bar |\n| --- | --- |\n| baz | bim |\n",
+			want:     "<table>\n<thead>\n<tr>\n<th>foo</th>\n<th>bar</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>baz</td>\n<td>bim</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "alignment",
+			markdown: "| abc | defghi |\n:-: | -----------:\nbar | baz\n",
+			want:     "<table>\n<thead>\n<tr>\n<th align=\"center\">abc</th>\n<th align=\"right\">defghi</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"center\">bar</td>\n<td align=\"right\">baz</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "escaped pipes",
+			markdown: "| f\\|oo  |\n| ------ |\n| b `\\|` az |\n| b **\\|** im |\n",
+			want:     "<table>\n<thead>\n<tr>\n<th>f|oo</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>b <code>|</code> az</td>\n</tr>\n<tr>\n<td>b <strong>|</strong> im</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "broken by block quote",
+			markdown: "| abc | def |\n| --- | --- |\n| bar | baz |\n> bar\n",
+			want:     "<table>\n<thead>\n<tr>\n<th>abc</th>\n<th>def</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>bar</td>\n<td>baz</td>\n</tr>\n</tbody>\n</table>\n<blockquote>\n<p>bar</p>\n</blockquote>\n",
+		},
+		{
+			name:     "broken by blank line",
+			markdown: "| abc | def |\n| --- | --- |\n| bar | baz |\nbar\n\nbar\n",
+			want:     "<table>\n<thead>\n<tr>\n<th>abc</th>\n<th>def</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>bar</td>\n<td>baz</td>\n</tr>\n<tr>\n<td>bar</td>\n<td></td>\n</tr>\n</tbody>\n</table>\n<p>bar</p>\n",
+		},
+		{
+			name:     "header mismatch",
+			markdown: "| abc | def |\n| --- |\n| bar |\n",
+			want:     "<p>| abc | def |\n| --- |\n| bar |</p>\n",
+		},
+		{
+			name:     "ragged rows",
+			markdown: "| abc | def |\n| --- | --- |\n| bar |\n| bar | baz | boo |\n",
+			want:     "<table>\n<thead>\n<tr>\n<th>abc</th>\n<th>def</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>bar</td>\n<td></td>\n</tr>\n<tr>\n<td>bar</td>\n<td>baz</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "no body",
+			markdown: "| abc | def |\n| --- | --- |\n",
+			want:     "<table>\n<thead>\n<tr>\n<th>abc</th>\n<th>def</th>\n</tr>\n</thead>\n</table>\n",
+		},
+		{
+			name:     "after paragraph text",
+			markdown: "intro\n| a |\n| - |\n| b |\n",
+			want:     "<p>intro</p>\n<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>b</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&HTMLRenderer{}).Render(Parse(tt.markdown))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableLines(t *testing.T) {
+	doc := Parse("text\n\n| a | b |\n|---|---|\n| 1 | 2 |\n| 3 | 4 |\n")
+	table := doc.LastChild
+	if table.Kind != Table || table.Line != 3 || table.EndLine != 6 {
+		t.Fatalf("table = %s lines %d-%d, want table lines 3-6", table.Kind, table.Line, table.EndLine)
+	}
+	var lines []int
+	for row := table.FirstChild; row != nil; row = row.NextSibling {
+		lines = append(lines, row.Line)
+	}
+	if len(lines) != 3 || lines[0] != 3 || lines[1] != 5 || lines[2] != 6 {
+		t.Errorf("row lines = %v, want [3 5 6]", lines)
+	}
+}
@@ -0,0 +1,342 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/wildreason/reader/parse/markdown"
+)
+
+// Parser interface for extensibility - allows different file formats
+// Parsers are selected through the registry (see RegisterParser)
+type Parser interface {
+	Parse(content string) []Block
+}
+
+// FileParser extends Parser for binary/file-based content (images, video)
+type FileParser interface {
+	Parser
+	ParseFile(filePath string, static bool) ([]Block, error)
+}
+
+// Block represents a markdown block with header and content
+type Block struct {
+	Name        string
+	Content     string             // Full content (untruncated)
+	LineNum     int
+	Pages       []string           // Content split into pages
+	TotalPages  int
+	ContentType BlockContentType   // Default content type (for simple blocks)
+	PageTypes   []BlockContentType // Per-page content type (for mixed content blocks)
+	PageMeta      []string           // Per-page metadata (e.g., filename for diff pages)
+	PageStartLine []int             // 1-indexed file line number for first content line of each page
+	Data          interface{}        // Typed payload: *ImageData, *VideoData, *CsvData, etc.
+}
+
+// LinesPerPage is the fixed number of lines per page in e-reader mode
+// TODO: Move to constants.go
+const LinesPerPage = 50
+
+// MarkdownParser implements Parser for markdown files
+type MarkdownParser struct{}
+
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "md",
+		Label:      "Markdown",
+		Extensions: []string{".md", ".markdown"},
+		Aliases:    []string{"markdown"},
+		Order:      10,
+		New:        func() Parser { return &MarkdownParser{} },
+		Sniff:      sniffMarkdown,
+		RawType:    BlockContentPlain,
+	})
+}
+
+// sniffMarkdown scores content by counting markdown-only constructs
+// (headings, fences, links, emphasis). Plain prose scores low.
+func sniffMarkdown(content string) float64 {
+	signals := 0
+	lines := strings.Split(content, "\n")
+	if len(lines) > 200 {
+		lines = lines[:200]
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case markdownHeadingRe.MatchString(trimmed):
+			signals += 2
+		case strings.HasPrefix(trimmed, "```"):
+			signals += 2
+		case strings.HasPrefix(trimmed, "> "):
+			signals++
+		}
+		if markdownLinkRe.MatchString(line) {
+			signals++
+		}
+		if strings.Contains(line, "**") || strings.Contains(line, "`") {
+			signals++
+		}
+	}
+	score := 0.2 + float64(signals)*0.05
+	if score > 0.8 {
+		score = 0.8
+	}
+	return score
+}
+
+var (
+	markdownHeadingRe = regexp.MustCompile(`^#{1,6} \S`)
+	markdownLinkRe    = regexp.MustCompile(`\[[^\]]+\]\([^)]+\)`)
+)
+
+// Parse reads a markdown file and extracts blocks, one per top-level (#) or
+// second-level (##) heading. Heading-like lines inside code fences, lists
+// and block quotes don't start blocks.
+func (p *MarkdownParser) Parse(content string) []Block {
+	lines := strings.Split(content, "\n")
+	var headings []*markdown.Node
+	for n := markdown.Parse(content).FirstChild; n != nil; n = n.NextSibling {
+		// Only ATX headings (one line) start blocks
+		if n.Kind == markdown.Heading && n.Level <= 2 && n.EndLine == n.Line {
+			headings = append(headings, n)
+		}
+	}
+
+	var blocks []Block
+	for i, h := range headings {
+		end := len(lines)
+		if i+1 < len(headings) {
+			end = headings[i+1].Line - 1
+		}
+		header := strings.TrimSpace(markdown.PlainText(h))
+		blocks = append(blocks, createBlock(header, lines[h.Line:end], h.Line-1))
+	}
+	return blocks
+}
+
+// ParseContinuous treats markdown as continuous flow without header-based block cuts
+// Pages are sized to fit the terminal: min(termHeight, maxLines)
+// Tracks header breadcrumbs for each page (e.g., "Title > Section")
+// Frontmatter is kept out of the markdown and carried as the block's Data.
+func (p *MarkdownParser) ParseContinuous(content string, termHeight int) []Block {
+	maxLines := 50
+	linesPerPage := termHeight - 4 // Reserve space for header/status
+	if linesPerPage < 10 {
+		linesPerPage = 10
+	}
+	if linesPerPage > maxLines {
+		linesPerPage = maxLines
+	}
+
+	fm, body := ParseFrontmatter(content)
+	var data any
+	if fm.Format != "" {
+		data = &fm
+	}
+	// Page start lines count from the top of the file
+	skipped := strings.Count(content[:len(content)-len(body)], "\n")
+
+	lines := strings.Split(body, "\n")
+
+	// Remove trailing empty lines
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return []Block{{
+			Name:        "Document",
+			Content:     "",
+			Pages:       []string{""},
+			TotalPages:  1,
+			ContentType: BlockContentPlain,
+			Data:        data,
+		}}
+	}
+
+	doc := markdown.Parse(strings.Join(lines, "\n"))
+	pages, starts := splitMarkdownPages(doc, lines, linesPerPage)
+
+	// Build breadcrumb for each page from the h1 and h2 active at its first line
+	pageMeta := make([]string, len(pages))
+	pageStartLine := make([]int, len(pages))
+	var h1, h2 string
+	heading := doc.FirstChild
+	for i, start := range starts {
+		for ; heading != nil && heading.Line <= start+1; heading = heading.NextSibling {
+			if heading.Kind != markdown.Heading {
+				continue
+			}
+			switch heading.Level {
+			case 1:
+				h1 = strings.TrimSpace(markdown.PlainText(heading))
+				h2 = "" // Reset h2 when new h1
+			case 2:
+				h2 = strings.TrimSpace(markdown.PlainText(heading))
+			}
+		}
+		switch {
+		case h1 != "" && h2 != "":
+			pageMeta[i] = h1 + " > " + h2
+		case h1 != "":
+			pageMeta[i] = h1
+		case h2 != "":
+			pageMeta[i] = h2
+		default:
+			pageMeta[i] = "Document"
+		}
+		pageStartLine[i] = start + 1 + skipped
+	}
+
+	return []Block{{
+		Name:          pageMeta[0], // First page breadcrumb as block name
+		Content:       content,
+		LineNum:       0,
+		Pages:         pages,
+		TotalPages:    len(pages),
+		ContentType:   BlockContentPlain,
+		PageMeta:      pageMeta, // Breadcrumb for each page
+		PageStartLine: pageStartLine,
+		Data:          data,
+	}}
+}
+
+// createBlock constructs a Block from accumulated lines
+func createBlock(header string, contentLines []string, lineNum int) Block {
+	// Remove trailing empty lines
+	for len(contentLines) > 0 && contentLines[len(contentLines)-1] == "" {
+		contentLines = contentLines[:len(contentLines)-1]
+	}
+
+	// Join content lines
+	fullContent := strings.Join(contentLines, "\n")
+
+	// Check if content is a diff - if so, paginate by hunks
+	var pages []string
+	var pageStartLine []int
+	contentType := DetectBlockContentType(fullContent)
+	if contentType == BlockContentDiff {
+		pages = splitDiffIntoHunkPages(fullContent)
+	} else {
+		// Compute PageStartLine for line number display (skip diff blocks)
+		var starts []int
+		pages, starts = splitMarkdownPages(markdown.Parse(fullContent), contentLines, LinesPerPage)
+		pageStartLine = make([]int, len(pages))
+		for i, start := range starts {
+			pageStartLine[i] = lineNum + 2 + start
+		}
+	}
+
+	return Block{
+		Name:          header,
+		Content:       fullContent,
+		LineNum:       lineNum,
+		Pages:         pages,
+		TotalPages:    len(pages),
+		ContentType:   contentType,
+		PageStartLine: pageStartLine,
+	}
+}
+
+// splitDiffIntoHunkPages splits diff content into pages where each page is one hunk
+func splitDiffIntoHunkPages(content string) []string {
+	hunks := ParseHunks(content)
+	if len(hunks) == 0 {
+		return []string{content}
+	}
+
+	// Each hunk becomes a "page" - but we store the full content
+	// and use the page index to select which hunk to render
+	pages := make([]string, len(hunks))
+	for i := range hunks {
+		// Store a placeholder - the actual hunk rendering happens in FormatDiffBlock
+		pages[i] = content
+	}
+	return pages
+}
+
+// splitMarkdownPages splits the lines of a parsed markdown document into
+// pages of about linesPerPage lines. Pages break only before a top-level
+// block or list item, so a code fence, table or paragraph is never split; a
+// page grows past linesPerPage when no break point falls inside it. It
+// returns the pages and the 0-based index of each page's first line.
+func splitMarkdownPages(doc *markdown.Node, lines []string, linesPerPage int) ([]string, []int) {
+	if len(lines) == 0 {
+		return []string{""}, []int{0}
+	}
+
+	// Break points are the 0-based first lines of top-level blocks
+	var breaks []int
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Kind == markdown.FootnoteList {
+			// Collected from wherever the definitions appear
+			continue
+		}
+		if n.Kind == markdown.List {
+			for item := n.FirstChild; item != nil; item = item.NextSibling {
+				breaks = append(breaks, item.Line-1)
+			}
+			continue
+		}
+		breaks = append(breaks, n.Line-1)
+	}
+
+	starts := []int{0}
+	next := 0 // index into breaks
+	for {
+		start := starts[len(starts)-1]
+		if len(lines)-start <= linesPerPage {
+			break
+		}
+		// Take the last break point that fits on the page
+		cut := -1
+		for ; next < len(breaks) && breaks[next] <= start+linesPerPage; next++ {
+			if breaks[next] > start {
+				cut = breaks[next]
+			}
+		}
+		if cut < 0 {
+			// No break point on this page: extend it to the next one
+			if next == len(breaks) {
+				break
+			}
+			cut = breaks[next]
+		}
+		starts = append(starts, cut)
+	}
+
+	var pages []string
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		pages = append(pages, strings.Join(lines[start:end], "\n"))
+	}
+	return pages, starts
+}
+
+// splitIntoPages splits content lines into pages of fixed size
+func splitIntoPages(lines []string, linesPerPage int) []string {
+	if len(lines) == 0 {
+		return []string{""}
+	}
+
+	var pages []string
+	for i := 0; i < len(lines); i += linesPerPage {
+		end := i + linesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		page := strings.Join(lines[i:end], "\n")
+		pages = append(pages, page)
+	}
+
+	if len(pages) == 0 {
+		pages = []string{""}
+	}
+
+	return pages
+}
+
@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ContractParser implements Parser for contract-type markdown files
+// Detected via frontmatter type: "contract"
+type ContractParser struct{}
+
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "contract",
+		Label:      "Contracts",
+		Extensions: []string{".md", ".markdown"},
+		Order:      15,
+		Detail:     ".md with type: contract",
+		New:        func() Parser { return &ContractParser{} },
+		Sniff: func(content string) float64 {
+			fm, _ := ParseFrontmatter(content)
+			if fm.Type == "contract" {
+				return 1
+			}
+			return 0
+		},
+		Structured: true,
+		RawType:    BlockContentContract,
+	})
+}
+
+// Parse reads contract content and returns a single block with ContractData
+func (p *ContractParser) Parse(content string) []Block {
+	fm, body := ParseFrontmatter(content)
+
+	preamble, clauses := ParseContractClauses(body)
+
+	title := "Contract"
+	if fm.Title != "" {
+		title = fm.Title
+	}
+
+	parties := fm.Raw["parties"]
+	effective := fm.Raw["effective"]
+
+	block := Block{
+		Name:        title,
+		Content:     body,
+		Pages:       []string{body},
+		TotalPages:  1,
+		ContentType: BlockContentContract,
+		Data: &ContractData{
+			Preamble:  preamble,
+			Clauses:   clauses,
+			Parties:   parties,
+			Effective: effective,
+		},
+	}
+
+	// Also build a TOC-friendly representation: store clause titles
+	var tocParts []string
+	for _, c := range clauses {
+		tocParts = append(tocParts, c.ID+". "+c.Title)
+	}
+	if len(tocParts) > 0 {
+		block.PageMeta = []string{strings.Join(tocParts, "\n")}
+	}
+
+	return []Block{block}
+}
+
+// ContractClause represents a numbered section of a contract
+type ContractClause struct {
+	ID    string // "1", "2", "3.1", etc.
+	Level int    // 1 = ##, 2 = ###
+	Title string // "Definitions", "Scope of Services"
+	Body  string // raw markdown body text
+}
+
+// ParseContractClauses splits markdown content into numbered clauses
+func ParseContractClauses(content string) (string, []ContractClause) {
+	var clauses []ContractClause
+	var current *ContractClause
+	var bodyLines []string
+	var preambleLines []string
+	inPreamble := true
+
+	clauseHeadingRe := regexp.MustCompile(`^(#{2,3})\s+(\d+[\d.]*)\.\s+(.*)`)
+
+	lines := strings.Split(content, "\n")
+
+	for _, line := range lines {
+		m := clauseHeadingRe.FindStringSubmatch(line)
+		if m != nil {
+			// Save previous clause
+			if current != nil {
+				current.Body = strings.TrimSpace(strings.Join(bodyLines, "\n"))
+				clauses = append(clauses, *current)
+				bodyLines = nil
+			}
+			inPreamble = false
+
+			level := 1
+			if m[1] == "###" {
+				level = 2
+			}
+
+			current = &ContractClause{
+				ID:    m[2],
+				Level: level,
+				Title: m[3],
+			}
+		} else if inPreamble {
+			preambleLines = append(preambleLines, line)
+		} else if current != nil {
+			bodyLines = append(bodyLines, line)
+		}
+	}
+
+	// Last clause
+	if current != nil {
+		current.Body = strings.TrimSpace(strings.Join(bodyLines, "\n"))
+		clauses = append(clauses, *current)
+	}
+
+	return strings.TrimSpace(strings.Join(preambleLines, "\n")), clauses
+}
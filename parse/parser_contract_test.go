@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseContractClauses_Structure(t *testing.T) {
+	content := `# Agreement
+
+Preamble text here.
+
+## 1. Definitions
+
+Definition body.
+
+## 2. Scope
+
+Scope body.
+
+### 2.1. Sub-scope
+
+Sub-scope body.`
+
+	preamble, clauses := ParseContractClauses(content)
+
+	if !strings.Contains(preamble, "Preamble text") {
+		t.Errorf("expected preamble to contain 'Preamble text', got: %s", preamble)
+	}
+	if len(clauses) != 3 {
+		t.Fatalf("expected 3 clauses, got %d", len(clauses))
+	}
+	if clauses[0].ID != "1" || clauses[0].Title != "Definitions" {
+		t.Errorf("clause 0: expected '1. Definitions', got '%s. %s'", clauses[0].ID, clauses[0].Title)
+	}
+	if clauses[2].ID != "2.1" || clauses[2].Level != 2 {
+		t.Errorf("clause 2: expected '2.1' level 2, got '%s' level %d", clauses[2].ID, clauses[2].Level)
+	}
+}
@@ -1,23 +1,47 @@
-package main
+package parse
 
 import (
+	"bufio"
+	"context"
 	"encoding/csv"
+	"fmt"
+	"io"
 	"strings"
 )
 
 // CsvParser implements Parser for CSV and TSV files
 type CsvParser struct {
 	Delimiter rune // ',' for CSV, '\t' for TSV
+	diags     []Diagnostic
 }
 
-// Detect checks if file is CSV or TSV
-func (p *CsvParser) Detect(filePath string) bool {
-	lower := strings.ToLower(filePath)
-	return strings.HasSuffix(lower, ".csv") || strings.HasSuffix(lower, ".tsv")
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "csv",
+		Label:      "CSV/TSV",
+		Extensions: []string{".csv", ".tsv"},
+		Aliases:    []string{"tsv"},
+		Order:      70,
+		New:        func() Parser { return &CsvParser{} },
+		Sniff: func(content string) float64 {
+			if isCSV(content) {
+				return 0.6
+			}
+			return 0
+		},
+		Structured: true,
+		RawType:    BlockContentCSV,
+	})
+}
+
+// Diagnostics returns the problems found by the last Parse or ParseStream
+func (p *CsvParser) Diagnostics() []Diagnostic {
+	return p.diags
 }
 
 // Parse reads CSV/TSV content and returns a single block with table content type
 func (p *CsvParser) Parse(content string) []Block {
+	p.diags = nil
 	delimiter := p.Delimiter
 	if delimiter == 0 {
 		delimiter = detectCSVDelimiter(content)
@@ -30,6 +54,11 @@ func (p *CsvParser) Parse(content string) []Block {
 
 	records, err := reader.ReadAll()
 	if err != nil || len(records) < 1 {
+		if err != nil {
+			d := csvDiagnostic(err)
+			d.Message += "; shown as plain text"
+			p.diags = append(p.diags, d)
+		}
 		// Fallback: treat as plain text
 		return []Block{{
 			Name:        "CSV",
@@ -53,6 +82,91 @@ func (p *CsvParser) Parse(content string) []Block {
 	}}
 }
 
+// csvStreamRows is the number of data rows per streamed CSV block
+const csvStreamRows = 500
+
+// ParseStream reads CSV/TSV records incrementally, emitting the header with
+// each chunk of csvStreamRows rows. Rows with a different field count than
+// the header are kept with a warning; malformed records are reported and skipped.
+func (p *CsvParser) ParseStream(ctx context.Context, r io.Reader, emit func(Block)) error {
+	p.diags = nil
+	br := bufio.NewReaderSize(r, sniffSampleSize)
+	delimiter := p.Delimiter
+	if delimiter == 0 {
+		sample, _ := br.Peek(sniffSampleSize)
+		delimiter = detectCSVDelimiter(string(sample))
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = delimiter
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	var header []string
+	var rows [][]string
+	firstRow := 1
+	flush := func() {
+		if header == nil {
+			return
+		}
+		records := append([][]string{header}, rows...)
+		mdTable := csvToMarkdownTable(records)
+		name := "CSV"
+		if firstRow > 1 {
+			name = fmt.Sprintf("CSV (rows %d-%d)", firstRow, firstRow+len(rows)-1)
+		}
+		emit(Block{
+			Name:        name,
+			Content:     mdTable,
+			Pages:       []string{mdTable},
+			TotalPages:  1,
+			ContentType: BlockContentCSV,
+			Data:        &CsvData{Records: records},
+		})
+		firstRow += len(rows)
+		rows = nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				p.diags = append(p.diags, csvDiagnostic(err))
+				continue
+			}
+			flush()
+			return err
+		}
+		if header == nil {
+			header = record
+			continue
+		}
+		if len(record) != len(header) {
+			line, _ := reader.FieldPos(0)
+			p.diags = append(p.diags, Diagnostic{
+				Line:     line,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("record has %d fields, header has %d", len(record), len(header)),
+			})
+		}
+		rows = append(rows, record)
+		if len(rows) >= csvStreamRows {
+			flush()
+		}
+	}
+	if len(rows) > 0 || firstRow == 1 {
+		flush()
+	}
+	return nil
+}
+
 // detectCSVDelimiter guesses whether content is comma or tab delimited
 func detectCSVDelimiter(content string) rune {
 	lines := strings.SplitN(content, "\n", 5)
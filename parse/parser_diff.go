@@ -0,0 +1,285 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DiffParser implements Parser for diff/patch files
+type DiffParser struct{}
+
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "diff",
+		Label:      "Unified diffs",
+		Extensions: []string{".diff", ".patch"},
+		Aliases:    []string{"patch"},
+		Order:      30,
+		New:        func() Parser { return &DiffParser{} },
+		Sniff: func(content string) float64 {
+			if isDiff(content) {
+				return 1
+			}
+			return 0
+		},
+		RawType: BlockContentDiff,
+	})
+}
+
+// Parse reads a diff file and creates blocks from hunks
+func (p *DiffParser) Parse(content string) []Block {
+	// First check if this is valid diff content
+	if DetectBlockContentType(content) != BlockContentDiff {
+		// Not a valid diff, return as single plain block
+		return []Block{
+			{
+				Name:        "diff",
+				Content:     content,
+				LineNum:     0,
+	
+				Pages:       []string{content},
+				TotalPages:  1,
+				ContentType: BlockContentPlain,
+			},
+		}
+	}
+
+	// Parse into hunks
+	hunks := ParseHunks(content)
+	if len(hunks) == 0 {
+		// Valid diff but no hunks parsed - show as single diff block
+		return []Block{
+			{
+				Name:        "diff",
+				Content:     content,
+				LineNum:     0,
+	
+				Pages:       []string{content},
+				TotalPages:  1,
+				ContentType: BlockContentDiff,
+				PageTypes:   []BlockContentType{BlockContentDiff},
+				},
+		}
+	}
+
+	// Get filename from diff
+	filename := GetFileFromDiff(content)
+	if filename == "" {
+		filename = "diff"
+	}
+
+	// Create a single block with hunks as pages
+	// Each page stores the full content; FormatDiffBlock handles hunk selection
+	pages := make([]string, len(hunks))
+	pageTypes := make([]BlockContentType, len(hunks))
+	for i := range hunks {
+		pages[i] = content
+		pageTypes[i] = BlockContentDiff
+	}
+
+	return []Block{
+		{
+			Name:        filename,
+			Content:     content,
+			LineNum:     0,
+
+			Pages:       pages,
+			TotalPages:  len(hunks),
+			ContentType: BlockContentDiff,
+			PageTypes:   pageTypes,
+		},
+	}
+}
+
+// ParseStream reads a diff one file at a time, emitting a block per file
+// section. Large sections are split at hunk boundaries, repeating the file
+// header so each chunk keeps its filename.
+func (p *DiffParser) ParseStream(ctx context.Context, r io.Reader, emit func(Block)) error {
+	var section []string
+	var fileHeader []string // Lines before the first hunk of the current file
+	inHeader := false
+	oldLeft, newLeft := 0, 0 // Lines remaining in the current hunk
+
+	flush := func() {
+		if len(section) == 0 {
+			return
+		}
+		for _, block := range p.Parse(strings.Join(section, "\n")) {
+			emit(block)
+		}
+		section = nil
+	}
+
+	err := scanLines(ctx, r, func(line string) {
+		inHunk := oldLeft > 0 || newLeft > 0
+		switch {
+		case inHunk:
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+			case strings.HasPrefix(line, "\\"):
+				// "\ No newline at end of file"
+			default:
+				oldLeft--
+				newLeft--
+			}
+		case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "--- ") && !inHeader:
+			// A new file section
+			flush()
+			fileHeader = nil
+			inHeader = true
+		case strings.HasPrefix(line, "@@"):
+			if len(section) >= streamChunkLines {
+				flush()
+				section = append(section, fileHeader...)
+			}
+			inHeader = false
+			oldLeft, newLeft = hunkLineCounts(line)
+		case len(section) >= 2*streamChunkLines:
+			// Not diff-shaped; keep chunks bounded anyway
+			flush()
+		}
+		if inHeader && len(fileHeader) < 10 {
+			fileHeader = append(fileHeader, line)
+		}
+		section = append(section, line)
+	})
+	flush()
+	return err
+}
+
+// hunkLineCounts returns the old and new line counts from a hunk header
+func hunkLineCounts(header string) (int, int) {
+	matches := hunkCountsRe.FindStringSubmatch(header)
+	if matches == nil {
+		return 0, 0
+	}
+	oldCount, newCount := 1, 1
+	if matches[1] != "" {
+		oldCount, _ = strconv.Atoi(matches[1])
+	}
+	if matches[2] != "" {
+		newCount, _ = strconv.Atoi(matches[2])
+	}
+	return oldCount, newCount
+}
+
+var hunkCountsRe = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// GetFileFromDiff extracts the filename from diff headers
+func GetFileFromDiff(diffContent string) string {
+	lines := strings.Split(diffContent, "\n")
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "+++ ") {
+			path := strings.TrimPrefix(line, "+++ ")
+			path = strings.TrimPrefix(path, "b/")
+			if idx := strings.Index(path, "\t"); idx != -1 {
+				path = path[:idx]
+			}
+			return path
+		}
+	}
+
+	return "file"
+}
+
+// DiffHunk represents a single hunk from a unified diff
+type DiffHunk struct {
+	Header   string   // The @@ line (we hide this in display)
+	Lines    []DiffLine
+	StartOld int      // Starting line in old file
+	StartNew int      // Starting line in new file
+}
+
+// DiffLine represents a single line in a hunk
+type DiffLine struct {
+	Type    DiffLineType
+	Content string
+}
+
+// DiffLineType indicates whether a line was added, removed, or context
+type DiffLineType int
+
+const (
+	DiffContext DiffLineType = iota
+	DiffAdded
+	DiffRemoved
+)
+
+// ParseHunks extracts hunks from unified diff content
+func ParseHunks(content string) []DiffHunk {
+	lines := strings.Split(content, "\n")
+	var hunks []DiffHunk
+	var currentHunk *DiffHunk
+
+	for _, line := range lines {
+		// Skip file headers
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+
+		// New hunk starts with @@
+		if strings.HasPrefix(line, "@@") {
+			if currentHunk != nil {
+				hunks = append(hunks, *currentHunk)
+			}
+			currentHunk = &DiffHunk{
+				Header: line,
+				Lines:  []DiffLine{},
+			}
+			// Parse line numbers from @@ -start,count +start,count @@
+			parseHunkHeader(line, currentHunk)
+			continue
+		}
+
+		// Add lines to current hunk
+		if currentHunk != nil {
+			var lineType DiffLineType
+			var lineContent string
+
+			if strings.HasPrefix(line, "+") {
+				lineType = DiffAdded
+				lineContent = line[1:] // Strip the + prefix
+			} else if strings.HasPrefix(line, "-") {
+				lineType = DiffRemoved
+				lineContent = line[1:] // Strip the - prefix
+			} else if strings.HasPrefix(line, " ") {
+				lineType = DiffContext
+				lineContent = line[1:] // Strip the space prefix
+			} else {
+				// Empty line or other content
+				lineType = DiffContext
+				lineContent = line
+			}
+
+			currentHunk.Lines = append(currentHunk.Lines, DiffLine{
+				Type:    lineType,
+				Content: lineContent,
+			})
+		}
+	}
+
+	// Don't forget the last hunk
+	if currentHunk != nil {
+		hunks = append(hunks, *currentHunk)
+	}
+
+	return hunks
+}
+
+// parseHunkHeader extracts line numbers from @@ -old,count +new,count @@
+func parseHunkHeader(header string, hunk *DiffHunk) {
+	re := regexp.MustCompile(`@@ -(\d+),?\d* \+(\d+),?\d* @@`)
+	matches := re.FindStringSubmatch(header)
+	if len(matches) >= 3 {
+		fmt.Sscanf(matches[1], "%d", &hunk.StartOld)
+		fmt.Sscanf(matches[2], "%d", &hunk.StartNew)
+	}
+}
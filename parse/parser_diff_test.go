@@ -1,8 +1,6 @@
-package main
+package parse
 
 import (
-	"fmt"
-	"strings"
 	"testing"
 )
 
@@ -72,24 +70,6 @@ func TestParseHunks(t *testing.T) {
 	}
 }
 
-func TestDetectFunctions(t *testing.T) {
-	formatter := NewDiffFormatter(80)
-
-	// Test Go function detection
-	hunk := DiffHunk{
-		Lines: []DiffLine{
-			{Type: DiffAdded, Content: "func calculateWithTax(subtotal int) float64 {"},
-			{Type: DiffAdded, Content: "    return float64(subtotal) * (1 + TaxRate)"},
-			{Type: DiffAdded, Content: "}"},
-		},
-	}
-
-	result := formatter.detectFunctions(hunk)
-	if !strings.Contains(result, "calculateWithTax()") {
-		t.Errorf("Expected to detect calculateWithTax(), got: %s", result)
-	}
-}
-
 func TestDiffContentDetection(t *testing.T) {
 	// Must include file headers for strict diff detection
 	diffContent := `--- a/file.go
@@ -112,61 +92,24 @@ func TestDiffContentDetection(t *testing.T) {
 	}
 }
 
-func TestFormatLine(t *testing.T) {
-	formatter := NewDiffFormatter(80)
-
-	// Test added line has background color (dark green: #2d5a2d)
-	addedLine := DiffLine{Type: DiffAdded, Content: "added line"}
-	result := formatter.formatLine(addedLine, 40)
-
-	// Should contain ANSI background code for dark green
-	if !strings.Contains(result, "\033[48;2;45;90;45m") {
-		t.Error("Added line should have dark green background")
-	}
-
-	// Test removed line has background color (dark magenta: #5a2d5a)
-	removedLine := DiffLine{Type: DiffRemoved, Content: "removed line"}
-	result = formatter.formatLine(removedLine, 40)
-
-	// Should contain ANSI background code for dark magenta
-	if !strings.Contains(result, "\033[48;2;90;45;90m") {
-		t.Error("Removed line should have dark magenta background")
-	}
-}
-
-// Run this to see visual output
-func ExampleDiffFormatter() {
-	diffContent := `--- a/file.go
-+++ b/file.go
-@@ -3,6 +3,8 @@
-
- import "fmt"
-
-+const TaxRate = 0.08
-+
- func calculateTotal(items []int) int {
-`
-
-	formatter := NewDiffFormatter(80)
-	output := formatter.Format(diffContent, "file.go")
-	fmt.Println(output)
-}
-
 func TestDiffParserDetect(t *testing.T) {
-	parser := &DiffParser{}
+	spec := LookupParser("diff")
+	if spec == nil {
+		t.Fatal("diff parser should be registered")
+	}
 
 	// Should detect .diff files
-	if !parser.Detect("changes.diff") {
+	if !spec.HasExtension("changes.diff") {
 		t.Error("Should detect .diff files")
 	}
 
 	// Should detect .patch files
-	if !parser.Detect("fix.patch") {
+	if !spec.HasExtension("fix.patch") {
 		t.Error("Should detect .patch files")
 	}
 
 	// Should not detect other files
-	if parser.Detect("file.go") {
+	if spec.HasExtension("file.go") {
 		t.Error("Should not detect .go files")
 	}
 }
@@ -0,0 +1,118 @@
+package parse
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImageExtensions lists file extensions handled by the image parser
+var ImageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".webp", ".ico", ".svg"}
+
+// ImageParser implements FileParser for image files
+type ImageParser struct{}
+
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "img",
+		Label:      "Images",
+		Extensions: ImageExtensions,
+		Aliases:    []string{"image"},
+		Order:      80,
+		New:        func() Parser { return &ImageParser{} },
+		Sniff:      sniffImage,
+		Binary:     true,
+		Structured: true,
+		RawType:    BlockContentImage,
+	})
+}
+
+// sniffImage recognizes common image formats by their magic bytes
+func sniffImage(content string) float64 {
+	switch {
+	case strings.HasPrefix(content, "\x89PNG\r\n\x1a\n"),
+		strings.HasPrefix(content, "\xff\xd8\xff"),
+		strings.HasPrefix(content, "GIF87a"), strings.HasPrefix(content, "GIF89a"),
+		len(content) >= 12 && content[:4] == "RIFF" && content[8:12] == "WEBP",
+		strings.HasPrefix(content, "\x00\x00\x01\x00"):
+		return 1
+	case strings.HasPrefix(content, "BM") && len(content) >= 14:
+		return 0.6
+	case strings.HasPrefix(strings.TrimSpace(content), "<svg"):
+		return 0.9
+	}
+	return 0
+}
+
+// Parse is not used for images (binary content); returns nil
+func (p *ImageParser) Parse(content string) []Block {
+	return nil
+}
+
+// ParseFile reads an image file and returns a single Block with ImageData
+// static=true inlines as base64 data URI; static=false stores file path for server mode
+func (p *ImageParser) ParseFile(filePath string, static bool) ([]Block, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve path: %w", err)
+	}
+
+	title := filepath.Base(filePath)
+	ext := filepath.Ext(filePath)
+	mime := ImageMIME(ext)
+
+	var src string
+	inline := false
+
+	if static {
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read file: %w", err)
+		}
+		b64 := base64.StdEncoding.EncodeToString(data)
+		src = fmt.Sprintf("data:%s;base64,%s", mime, b64)
+		inline = true
+	} else {
+		src = absPath
+	}
+
+	block := Block{
+		Name:        title,
+		Content:     src,
+		Pages:       []string{src},
+		TotalPages:  1,
+		ContentType: BlockContentImage,
+		Data: &ImageData{
+			Src:    src,
+			MIME:   mime,
+			Alt:    title,
+			Inline: inline,
+		},
+	}
+
+	return []Block{block}, nil
+}
+
+// ImageMIME returns the MIME type for an image extension
+func ImageMIME(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".svg":
+		return "image/svg+xml"
+	case ".bmp":
+		return "image/bmp"
+	case ".ico":
+		return "image/x-icon"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -1,10 +1,14 @@
-package main
+package parse
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
+
+	"github.com/wildreason/reader/theme"
 )
 
 // ContentType represents a type of content in JSONL
@@ -17,73 +21,122 @@ type ContentType struct {
 // JSONLParser implements Parser for JSONL transcript files
 type JSONLParser struct {
 	Filters map[string]bool // Which content types to include
+	diags   []Diagnostic
 }
 
-// Detect checks if file is JSONL
-func (p *JSONLParser) Detect(filePath string) bool {
-	return strings.HasSuffix(strings.ToLower(filePath), ".jsonl")
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "jsonl",
+		Label:      "Transcripts",
+		Extensions: []string{".jsonl"},
+		Order:      60,
+		New:        func() Parser { return &JSONLParser{} },
+		Sniff:      sniffJSONL,
+		Structured: true,
+		RawType:    BlockContentTranscript,
+	})
 }
 
-// ScanContentTypes scans JSONL content and returns available types with counts
-func ScanContentTypes(content string) []ContentType {
-	counts := make(map[string]int)
-	lines := strings.Split(content, "\n")
-
-	for _, line := range lines {
+// sniffJSONL counts leading lines that are standalone JSON objects
+func sniffJSONL(content string) float64 {
+	jsonLineCount := 0
+	for _, line := range strings.SplitN(content, "\n", 20) {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
-
-		var msg map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			continue
+		var testJSON map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &testJSON); err != nil {
+			break
 		}
+		jsonLineCount++
+	}
+	switch {
+	case jsonLineCount >= 2:
+		return 0.9
+	case jsonLineCount == 1:
+		return 0.2
+	}
+	return 0
+}
 
-		msgType, ok := msg["type"].(string)
-		if !ok {
-			continue
-		}
+// ScanContentTypes scans JSONL content and returns available types with counts
+func ScanContentTypes(content string) []ContentType {
+	counts := make(contentTypeCounts)
+	for _, line := range strings.Split(content, "\n") {
+		counts.add(line)
+	}
+	return counts.types()
+}
+
+// ScanContentTypesReader is ScanContentTypes over a reader, for inputs too
+// large to hold in memory
+func ScanContentTypesReader(r io.Reader) ([]ContentType, error) {
+	counts := make(contentTypeCounts)
+	err := scanLines(context.Background(), r, counts.add)
+	return counts.types(), err
+}
+
+// contentTypeCounts counts JSONL messages by filter category
+type contentTypeCounts map[string]int
+
+// add categorizes one JSONL line
+func (counts contentTypeCounts) add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		return
+	}
 
-		// Categorize message types
-		switch msgType {
-		case "user":
-			// Check if it's actual user text or tool result
-			if message, ok := msg["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"]; ok {
-					if _, isString := content.(string); isString {
-						counts["user"]++
-					} else if arr, isArr := content.([]interface{}); isArr {
-						// Check first item type
-						for _, item := range arr {
-							if itemMap, ok := item.(map[string]interface{}); ok {
-								if itemType, _ := itemMap["type"].(string); itemType == "tool_result" {
-									counts["tool_result"]++
-									// Also check for diff content
-									if hasStructuredPatch(msg) {
-										counts["diff"]++
-									}
-									break
-								} else {
-									counts["user"]++
-									break
+	msgType, ok := msg["type"].(string)
+	if !ok {
+		return
+	}
+
+	// Categorize message types
+	switch msgType {
+	case "user":
+		// Check if it's actual user text or tool result
+		if message, ok := msg["message"].(map[string]interface{}); ok {
+			if content, ok := message["content"]; ok {
+				if _, isString := content.(string); isString {
+					counts["user"]++
+				} else if arr, isArr := content.([]interface{}); isArr {
+					// Check first item type
+					for _, item := range arr {
+						if itemMap, ok := item.(map[string]interface{}); ok {
+							if itemType, _ := itemMap["type"].(string); itemType == "tool_result" {
+								counts["tool_result"]++
+								// Also check for diff content
+								if HasStructuredPatch(msg) {
+									counts["diff"]++
 								}
+								break
+							} else {
+								counts["user"]++
+								break
 							}
 						}
 					}
 				}
 			}
-		case "assistant":
-			counts["assistant"]++
-		case "system":
-			counts["system"]++
-		default:
-			// Group other types (file-history-snapshot, summary, etc.)
-			counts["other"]++
 		}
+	case "assistant":
+		counts["assistant"]++
+	case "system":
+		counts["system"]++
+	default:
+		// Group other types (file-history-snapshot, summary, etc.)
+		counts["other"]++
 	}
+}
 
-	// Build result with sensible ordering and defaults
+// types builds the result with sensible ordering and defaults
+func (counts contentTypeCounts) types() []ContentType {
 	var types []ContentType
 	order := []string{"user", "assistant", "diff", "tool_result", "system", "other"}
 	defaults := map[string]bool{"user": true, "assistant": true, "diff": true}
@@ -101,8 +154,8 @@ func ScanContentTypes(content string) []ContentType {
 	return types
 }
 
-// hasStructuredPatch checks if a message has a non-empty structuredPatch
-func hasStructuredPatch(msg map[string]interface{}) bool {
+// HasStructuredPatch checks if a message has a non-empty structuredPatch
+func HasStructuredPatch(msg map[string]interface{}) bool {
 	toolUseResult, ok := msg["toolUseResult"].(map[string]interface{})
 	if !ok {
 		return false
@@ -114,8 +167,8 @@ func hasStructuredPatch(msg map[string]interface{}) bool {
 	return len(patch) > 0
 }
 
-// extractStructuredPatch extracts and converts structuredPatch to unified diff format
-func extractStructuredPatch(msg map[string]interface{}) string {
+// ExtractStructuredPatch extracts and converts structuredPatch to unified diff format
+func ExtractStructuredPatch(msg map[string]interface{}) string {
 	toolUseResult, ok := msg["toolUseResult"].(map[string]interface{})
 	if !ok {
 		return ""
@@ -180,13 +233,13 @@ func colorizeDiffLines(diff string) string {
 	for _, line := range strings.Split(diff, "\n") {
 		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
 			// Added line - green background
-			sb.WriteString(fmt.Sprintf("[white:#2d5a2d]%s[-:-]\n", line))
+			sb.WriteString(theme.TagBg(theme.Added, theme.AddedBg) + line + "[-:-]\n")
 		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
 			// Removed line - magenta background
-			sb.WriteString(fmt.Sprintf("[white:#5a2d5a]%s[-:-]\n", line))
+			sb.WriteString(theme.TagBg(theme.Removed, theme.RemovedBg) + line + "[-:-]\n")
 		} else if strings.HasPrefix(line, "@@") {
 			// Hunk header - dim
-			sb.WriteString(fmt.Sprintf("[#808080]%s[-]\n", line))
+			sb.WriteString(theme.Tag(theme.Muted) + line + "[-]\n")
 		} else if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
 			// Skip file headers (we have our own header)
 			continue
@@ -198,117 +251,158 @@ func colorizeDiffLines(diff string) string {
 	return strings.TrimSuffix(sb.String(), "\n")
 }
 
+// Diagnostics returns the invalid lines found by the last Parse or ParseStream
+func (p *JSONLParser) Diagnostics() []Diagnostic {
+	return p.diags
+}
+
 // Parse reads a JSONL file and extracts conversation blocks
 func (p *JSONLParser) Parse(content string) []Block {
-	lines := strings.Split(content, "\n")
 	var blocks []Block
-	var currentTurn *ConversationTurn
-	turnNumber := 0
+	b := p.newTurnBuilder(func(block Block) { blocks = append(blocks, block) })
+	for lineNum, line := range strings.Split(content, "\n") {
+		b.addLine(lineNum, line)
+	}
+	b.finish()
+	return blocks
+}
+
+// ParseStream reads JSONL line by line, emitting each conversation turn as
+// soon as the next one starts
+func (p *JSONLParser) ParseStream(ctx context.Context, r io.Reader, emit func(Block)) error {
+	b := p.newTurnBuilder(emit)
+	lineNum := 0
+	err := scanLines(ctx, r, func(line string) {
+		b.addLine(lineNum, line)
+		lineNum++
+	})
+	b.finish()
+	return err
+}
+
+// jsonlTurnBuilder groups JSONL messages into conversation turns one line at a time
+type jsonlTurnBuilder struct {
+	p           *JSONLParser
+	emit        func(Block)
+	currentTurn *ConversationTurn
+	turnNumber  int
+
+	showUser, showAssistant, showDiff, showToolResult bool
+}
+
+// newTurnBuilder returns a builder using the parser's filters
+func (p *JSONLParser) newTurnBuilder(emit func(Block)) *jsonlTurnBuilder {
+	p.diags = nil
 
 	// Default filters if not set
 	if p.Filters == nil {
 		p.Filters = map[string]bool{"user": true, "assistant": true, "diff": true}
 	}
 
-	showUser := p.Filters["user"]
-	showAssistant := p.Filters["assistant"]
-	showDiff := p.Filters["diff"]
-	showToolResult := p.Filters["tool_result"]
+	return &jsonlTurnBuilder{
+		p:              p,
+		emit:           emit,
+		showUser:       p.Filters["user"],
+		showAssistant:  p.Filters["assistant"],
+		showDiff:       p.Filters["diff"],
+		showToolResult: p.Filters["tool_result"],
+	}
+}
 
-	for lineNum, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+// addLine feeds one JSONL line to the builder
+func (b *jsonlTurnBuilder) addLine(lineNum int, line string) {
+	p := b.p
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
 
-		// Parse JSON object
-		var msg map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			// Skip invalid JSON lines
-			continue
-		}
+	// Parse JSON object
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		// Skip invalid JSON lines
+		p.diags = append(p.diags, jsonDiagnostic(line, lineNum+1, err))
+		return
+	}
 
-		msgType, ok := msg["type"].(string)
-		if !ok {
-			continue
-		}
+	msgType, ok := msg["type"].(string)
+	if !ok {
+		return
+	}
 
-		// Process based on filters
-		if msgType == "user" {
-			// Determine if this is user text or tool result
-			isToolResult := p.isToolResultMessage(msg)
-
-			// TOOL RESULTS: Add diffs and/or tool output to current turn
-			if isToolResult {
-				if currentTurn != nil {
-					// Check for diff content (diffs come from tool results)
-					if showDiff && hasStructuredPatch(msg) {
-						diffContent := extractStructuredPatch(msg)
-						if diffContent != "" {
-							toolUseResult, _ := msg["toolUseResult"].(map[string]interface{})
-							filePath, _ := toolUseResult["filePath"].(string)
-							currentTurn.Parts = append(currentTurn.Parts, TurnPart{
-								Type:    "diff",
-								Content: diffContent,
-								Meta:    filePath,
-							})
-						}
+	// Process based on filters
+	if msgType == "user" {
+		// Determine if this is user text or tool result
+		isToolResult := p.isToolResultMessage(msg)
+
+		// TOOL RESULTS: Add diffs and/or tool output to current turn
+		if isToolResult {
+			if b.currentTurn != nil {
+				// Check for diff content (diffs come from tool results)
+				if b.showDiff && HasStructuredPatch(msg) {
+					diffContent := ExtractStructuredPatch(msg)
+					if diffContent != "" {
+						toolUseResult, _ := msg["toolUseResult"].(map[string]interface{})
+						filePath, _ := toolUseResult["filePath"].(string)
+						b.currentTurn.Parts = append(b.currentTurn.Parts, TurnPart{
+							Type:    "diff",
+							Content: diffContent,
+							Meta:    filePath,
+						})
 					}
+				}
 
-					// Show tool result output if filter enabled
-					if showToolResult {
-						toolContent := p.ExtractToolResultContent(msg)
-						if toolContent != "" {
-							currentTurn.Parts = append(currentTurn.Parts, TurnPart{
-								Type:    "tool_result",
-								Content: toolContent,
-							})
-						}
+				// Show tool result output if filter enabled
+				if b.showToolResult {
+					toolContent := p.ExtractToolResultContent(msg)
+					if toolContent != "" {
+						b.currentTurn.Parts = append(b.currentTurn.Parts, TurnPart{
+							Type:    "tool_result",
+							Content: toolContent,
+						})
 					}
 				}
-				// Tool results don't create new turns
-				continue
 			}
+			// Tool results don't create new turns
+			return
+		}
 
-			// REAL USER MESSAGE: Start a new turn
-			if !showUser {
-				continue
-			}
+		// REAL USER MESSAGE: Start a new turn
+		if !b.showUser {
+			return
+		}
 
-			// Save previous turn if exists
-			if currentTurn != nil {
-				block := p.CreateTurnBlock(currentTurn, turnNumber)
-				blocks = append(blocks, block)
-			}
+		// Save previous turn if exists
+		if b.currentTurn != nil {
+			b.emit(p.CreateTurnBlock(b.currentTurn, b.turnNumber))
+		}
 
-			// Start new turn with user message as first part
-			turnNumber++
-			userContent := p.ExtractUserContent(msg)
-			if userContent != "" {
-				currentTurn = &ConversationTurn{
-					Parts:   []TurnPart{{Type: "user", Content: userContent}},
-					LineNum: lineNum,
-				}
-			}
-		} else if msgType == "assistant" && showAssistant && currentTurn != nil {
-			// Add assistant response as a part of the current turn
-			assistantContent := p.ExtractAssistantContent(msg)
-			if assistantContent != "" {
-				currentTurn.Parts = append(currentTurn.Parts, TurnPart{
-					Type:    "assistant",
-					Content: assistantContent,
-				})
+		// Start new turn with user message as first part
+		b.turnNumber++
+		userContent := p.ExtractUserContent(msg)
+		if userContent != "" {
+			b.currentTurn = &ConversationTurn{
+				Parts:   []TurnPart{{Type: "user", Content: userContent}},
+				LineNum: lineNum,
 			}
 		}
+	} else if msgType == "assistant" && b.showAssistant && b.currentTurn != nil {
+		// Add assistant response as a part of the current turn
+		assistantContent := p.ExtractAssistantContent(msg)
+		if assistantContent != "" {
+			b.currentTurn.Parts = append(b.currentTurn.Parts, TurnPart{
+				Type:    "assistant",
+				Content: assistantContent,
+			})
+		}
 	}
+}
 
-	// Don't forget the last turn
-	if currentTurn != nil {
-		block := p.CreateTurnBlock(currentTurn, turnNumber)
-		blocks = append(blocks, block)
+// finish emits the last turn
+func (b *jsonlTurnBuilder) finish() {
+	if b.currentTurn != nil {
+		b.emit(b.p.CreateTurnBlock(b.currentTurn, b.turnNumber))
 	}
-
-	return blocks
 }
 
 // GetMessageType returns the message type from a JSONL line ("user", "assistant", or "")
@@ -451,12 +545,12 @@ func FormatQuestionContentIndexed(data *QuestionData, index int, total int) stri
 
 	// Q index/total prefix for multi-question
 	if total > 1 {
-		content.WriteString(fmt.Sprintf("[yellow]Q%d/%d[-] ", index, total))
+		content.WriteString(fmt.Sprintf("%sQ%d/%d[-] ", theme.Tag(theme.Highlight), index, total))
 	}
 
 	// Header
 	if data.Header != "" {
-		content.WriteString(fmt.Sprintf("[#808080]%s[-]\n", data.Header))
+		content.WriteString(fmt.Sprintf("%s%s[-]\n", theme.Tag(theme.Muted), data.Header))
 	}
 
 	// Question text
@@ -466,19 +560,19 @@ func FormatQuestionContentIndexed(data *QuestionData, index int, total int) stri
 
 	// Options
 	for i, opt := range data.Options {
-		content.WriteString(fmt.Sprintf("  [cyan]%d.[-] %s", i+1, opt.Label))
+		content.WriteString(fmt.Sprintf("  %s%d.[-] %s", theme.Tag(theme.Accent), i+1, opt.Label))
 		if opt.Description != "" {
-			content.WriteString(fmt.Sprintf(" - [#808080]%s[-]", opt.Description))
+			content.WriteString(fmt.Sprintf(" - %s%s[-]", theme.Tag(theme.Muted), opt.Description))
 		}
 		content.WriteString("\n")
 	}
 
 	// "Other" option hint
-	content.WriteString(fmt.Sprintf("  [cyan]%d.[-] Other (custom text)\n", len(data.Options)+1))
+	content.WriteString(fmt.Sprintf("  %s%d.[-] Other (custom text)\n", theme.Tag(theme.Accent), len(data.Options)+1))
 
 	// Multi-select hint
 	if data.MultiSelect {
-		content.WriteString("\n[#808080](multi-select: e.g. 1,3)[-]\n")
+		content.WriteString("\n" + theme.Tag(theme.Muted) + "(multi-select: e.g. 1,3)[-]\n")
 	}
 
 	return content.String()
@@ -526,7 +620,7 @@ func (p *JSONLParser) ParseSingleLine(line string, turnNumber int) *Block {
 
 		// TOOL RESULTS: Only show diffs, skip everything else
 		if isToolResult {
-			if p.Filters["diff"] && hasStructuredPatch(msg) {
+			if p.Filters["diff"] && HasStructuredPatch(msg) {
 				return p.createDiffBlock(msg, turnNumber, 0)
 			}
 			// Skip all other tool results - they don't create blocks
@@ -542,7 +636,7 @@ func (p *JSONLParser) ParseSingleLine(line string, turnNumber int) *Block {
 			return nil
 		}
 		blockName = fmt.Sprintf("block-%d", turnNumber)
-		contentLines = append(contentLines, fmt.Sprintf("[cyan]U:[-] %s", userContent))
+		contentLines = append(contentLines, fmt.Sprintf("%sU:[-] %s", theme.Tag(theme.Accent), userContent))
 
 	case "assistant":
 		if !p.Filters["assistant"] {
@@ -745,13 +839,41 @@ func (p *JSONLParser) ExtractAssistantContent(msg map[string]interface{}) string
 // Additional inline pattern for function references
 var codePatternBracket = regexp.MustCompile(`\[([^\]]+\(\))\]`) // [funcName()]
 
-// formatAssistantContent applies markdown formatting plus function highlighting
-func formatAssistantContent(text string, termWidth int) string {
-	// First apply standard markdown formatting from formatter.go
-	text = annotatedLinesToString(formatMarkdown(text, termWidth))
-	// Then highlight [funcName()] patterns - yellow for function references
-	text = codePatternBracket.ReplaceAllString(text, "[yellow]$1[-]")
-	return text
+// formatAssistantContent highlights [funcName()] patterns - yellow for function references.
+// Markdown itself is formatted when the block is rendered.
+func formatAssistantContent(text string) string {
+	return codePatternBracket.ReplaceAllString(text, theme.Tag(theme.Highlight)+"$1[-]")
+}
+
+// TurnPartText returns a turn part formatted with tview color tags, and
+// false for unknown part types. Assistant text is still markdown.
+func TurnPartText(part TurnPart) (string, bool) {
+	switch part.Type {
+	case "user":
+		// User message: white text on gray background (chat bubble style)
+		return theme.TagBg(theme.UserBubble, theme.UserBubbleBg) + part.Content + "[-:-:-]", true
+
+	case "diff":
+		// Extract filename for header
+		filename := part.Meta
+		if idx := strings.LastIndex(part.Meta, "/"); idx >= 0 {
+			filename = part.Meta[idx+1:]
+		}
+		// Add diff with separator header and colorized lines
+		diffHeader := fmt.Sprintf("%s--- %s ---[-]", theme.Tag(theme.Muted), filename)
+		return diffHeader + "\n" + colorizeDiffLines(part.Content), true
+
+	case "assistant":
+		return formatAssistantContent(part.Content), true
+
+	case "tool_result":
+		// Tool result output: already formatted by ShellFormatter
+		return part.Content, true
+
+	case "question":
+		return fmt.Sprintf("%s[?][-] %s", theme.Tag(theme.Highlight), part.Content), true
+	}
+	return "", false
 }
 
 // CreateTurnBlock creates a Block from a ConversationTurn
@@ -761,35 +883,9 @@ func (p *JSONLParser) CreateTurnBlock(turn *ConversationTurn, turnNumber int) Bl
 	name := fmt.Sprintf("block-%d", turnNumber)
 
 	var contentParts []string
-
 	for _, part := range turn.Parts {
-		switch part.Type {
-		case "user":
-			// User message: white text on gray background (chat bubble style)
-			contentParts = append(contentParts, fmt.Sprintf("[white:#303030]%s[-:-:-]", part.Content))
-
-		case "diff":
-			// Extract filename for header
-			filename := part.Meta
-			if idx := strings.LastIndex(part.Meta, "/"); idx >= 0 {
-				filename = part.Meta[idx+1:]
-			}
-			// Add diff with separator header and colorized lines
-			diffHeader := fmt.Sprintf("[#808080]--- %s ---[-]", filename)
-			colorizedDiff := colorizeDiffLines(part.Content)
-			contentParts = append(contentParts, diffHeader+"\n"+colorizedDiff)
-
-		case "assistant":
-			// Add assistant content immediately to preserve chronological order
-			formatted := formatAssistantContent(part.Content, 0)
-			contentParts = append(contentParts, formatted)
-
-		case "tool_result":
-			// Tool result output: already formatted by ShellFormatter
-			contentParts = append(contentParts, part.Content)
-
-		case "question":
-			contentParts = append(contentParts, fmt.Sprintf("[yellow][?][-] %s", part.Content))
+		if text, ok := TurnPartText(part); ok {
+			contentParts = append(contentParts, text)
 		}
 	}
 
@@ -816,21 +912,21 @@ func buildSummaryPage(userContent string, editedFiles []string, assistantContent
 	var sb strings.Builder
 
 	// User query (truncated to ~3 lines / 200 chars)
-	sb.WriteString("[cyan]U:[-] ")
+	sb.WriteString(theme.Tag(theme.Accent) + "U:[-] ")
 	userTrunc := truncateText(userContent, 200, 3)
 	sb.WriteString(userTrunc)
 	sb.WriteString("\n")
 
 	// Edits section (if any)
 	if len(editedFiles) > 0 {
-		sb.WriteString("\n[#808080]---[-]\n")
-		sb.WriteString(fmt.Sprintf("[yellow]%d edit(s):[-] ", len(editedFiles)))
+		sb.WriteString("\n" + theme.Tag(theme.Muted) + "---[-]\n")
+		sb.WriteString(fmt.Sprintf("%s%d edit(s):[-] ", theme.Tag(theme.Highlight), len(editedFiles)))
 		sb.WriteString(strings.Join(editedFiles, ", "))
-		sb.WriteString("\n[#808080]---[-]\n")
+		sb.WriteString("\n" + theme.Tag(theme.Muted) + "---[-]\n")
 	}
 
 	// Assistant response (truncated)
-	sb.WriteString("\n[green]A:[-] ")
+	sb.WriteString("\n" + theme.Tag(theme.Success) + "A:[-] ")
 	assistantTrunc := truncateText(assistantContent, 500, 10)
 	sb.WriteString(assistantTrunc)
 
@@ -858,7 +954,7 @@ func truncateText(text string, maxChars int, maxLines int) string {
 // createDiffBlock creates a Block from a message with structuredPatch
 func (p *JSONLParser) createDiffBlock(msg map[string]interface{}, diffNumber int, lineNum int) *Block {
 	// Extract the diff content
-	diffContent := extractStructuredPatch(msg)
+	diffContent := ExtractStructuredPatch(msg)
 	if diffContent == "" {
 		return nil
 	}
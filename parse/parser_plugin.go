@@ -0,0 +1,396 @@
+package parse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pluginPrefix is the executable name prefix for external parser plugins
+const pluginPrefix = "aster-parser-"
+
+// Timeouts for the `--describe` handshake when plugins load and for a single parse call
+var (
+	pluginDescribeTimeout = 2 * time.Second
+	pluginParseTimeout    = 10 * time.Second
+)
+
+// pluginOrder places plugins after built-in parsers in help text
+const pluginOrder = 100
+
+// pluginDescription is the JSON a plugin prints for `--describe`
+type pluginDescription struct {
+	Label      string   `json:"label"`
+	Extensions []string `json:"extensions"`
+	Aliases    []string `json:"aliases"`
+	Patterns   []string `json:"patterns"`   // Regexes matched against content for sniffing
+	Confidence float64  `json:"confidence"` // Sniff score when a pattern matches (default 0.8)
+}
+
+// pluginBlock mirrors Block for decoding plugin output.
+// ContentType and PageTypes accept names ("diff", "csv") or numeric values.
+type pluginBlock struct {
+	Name          string
+	Content       string
+	LineNum       int
+	Pages         []string
+	ContentType   json.RawMessage
+	PageTypes     []json.RawMessage
+	PageMeta      []string
+	PageStartLine []int
+	Data          json.RawMessage
+}
+
+// PluginParser implements Parser by running an external executable.
+// Content is written to stdin; a JSON array of blocks is read from stdout.
+type PluginParser struct {
+	Name  string
+	Path  string
+	diags []Diagnostic
+}
+
+// Diagnostics returns the plugin failure from the last Parse, if any
+func (p *PluginParser) Diagnostics() []Diagnostic {
+	return p.diags
+}
+
+// Parse runs the plugin and returns its blocks.
+// Failures are reported as a diagnostic and rendered as a single error block.
+func (p *PluginParser) Parse(content string) []Block {
+	p.diags = nil
+	blocks, err := p.run(content)
+	if err != nil {
+		p.diags = append(p.diags, Diagnostic{Severity: SeverityError, Message: err.Error()})
+		msg := fmt.Sprintf("Error: %v", err)
+		return []Block{{
+			Name:        p.Name,
+			Content:     msg,
+			Pages:       []string{msg},
+			TotalPages:  1,
+			ContentType: BlockContentPlain,
+		}}
+	}
+	return blocks
+}
+
+// run executes the plugin and decodes its output
+func (p *PluginParser) run(content string) ([]Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginParseTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // don't hang on grandchildren holding stdout open
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("plugin %s timed out after %s", p.Name, pluginParseTimeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s failed: %v: %s", p.Name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %v", p.Name, err)
+	}
+
+	blocks, err := decodePluginBlocks(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s returned bad output: %v", p.Name, err)
+	}
+	return blocks, nil
+}
+
+// decodePluginBlocks converts plugin JSON output into blocks, decoding Data by content type
+func decodePluginBlocks(output []byte) ([]Block, error) {
+	var raw []pluginBlock
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of blocks: %v", err)
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("no blocks")
+	}
+
+	blocks := make([]Block, 0, len(raw))
+	for i, rb := range raw {
+		contentType, err := decodeContentType(rb.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %v", i, err)
+		}
+		block := Block{
+			Name:          rb.Name,
+			Content:       rb.Content,
+			LineNum:       rb.LineNum,
+			Pages:         rb.Pages,
+			ContentType:   contentType,
+			PageMeta:      rb.PageMeta,
+			PageStartLine: rb.PageStartLine,
+		}
+		for j, pt := range rb.PageTypes {
+			pageType, err := decodeContentType(pt)
+			if err != nil {
+				return nil, fmt.Errorf("block %d page %d: %v", i, j, err)
+			}
+			block.PageTypes = append(block.PageTypes, pageType)
+		}
+		if len(block.Pages) == 0 {
+			block.Pages = []string{block.Content}
+		}
+		if block.Content == "" {
+			block.Content = strings.Join(block.Pages, "\n")
+		}
+		block.TotalPages = len(block.Pages)
+
+		data, err := decodePluginData(contentType, rb.Data)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: invalid %s data: %v", i, contentType, err)
+		}
+		block.Data = data
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// decodeContentType accepts a content type name or its numeric value
+func decodeContentType(raw json.RawMessage) (BlockContentType, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return BlockContentPlain, nil
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		if ct, ok := ParseBlockContentType(name); ok {
+			return ct, nil
+		}
+		return BlockContentPlain, fmt.Errorf("unknown content type %q", name)
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil || n < 0 || n > int(BlockContentShell) {
+		return BlockContentPlain, fmt.Errorf("invalid content type %s", raw)
+	}
+	return BlockContentType(n), nil
+}
+
+// decodePluginData decodes the typed payload for content types that carry one
+func decodePluginData(ct BlockContentType, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		if ct == BlockContentImage || ct == BlockContentVideo {
+			return nil, errors.New("missing Data")
+		}
+		return nil, nil
+	}
+	var data interface{}
+	switch ct {
+	case BlockContentImage:
+		data = &ImageData{}
+	case BlockContentVideo:
+		data = &VideoData{}
+	case BlockContentCSV:
+		data = &CsvData{}
+	case BlockContentTranscript:
+		data = &TranscriptData{}
+	case BlockContentContract:
+		data = &ContractData{}
+	default:
+		return nil, nil
+	}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// pluginDirs returns directories searched for plugins, in priority order
+func pluginDirs() []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".aster", "plugins"))
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	return dirs
+}
+
+// findPlugins returns plugin executables keyed by type name.
+// The first match in search order wins.
+func findPlugins(dirs []string) map[string]string {
+	found := make(map[string]string)
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, pluginPrefix+"*"))
+		for _, path := range matches {
+			name := strings.ToLower(strings.TrimPrefix(filepath.Base(path), pluginPrefix))
+			if name == "" || found[name] != "" {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			found[name] = path
+		}
+	}
+	return found
+}
+
+// describePlugin runs `<plugin> --describe` and parses the result
+func describePlugin(path string) (*pluginDescription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginDescribeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--describe").Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("--describe timed out after %s", pluginDescribeTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("--describe failed: %v", err)
+	}
+	var desc pluginDescription
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return nil, fmt.Errorf("--describe returned invalid JSON: %v", err)
+	}
+	return &desc, nil
+}
+
+// pluginSpec builds a registry spec for a plugin executable
+func pluginSpec(name, path string, desc *pluginDescription) (*ParserSpec, error) {
+	spec := &ParserSpec{
+		Name:       name,
+		Label:      name,
+		Order:      pluginOrder,
+		New:        func() Parser { return &PluginParser{Name: name, Path: path} },
+		Structured: true,
+		RawType:    BlockContentPlain,
+	}
+	if desc == nil {
+		return spec, nil
+	}
+	if desc.Label != "" {
+		spec.Label = desc.Label
+	}
+	for _, ext := range desc.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		spec.Extensions = append(spec.Extensions, ext)
+	}
+	for _, alias := range desc.Aliases {
+		spec.Aliases = append(spec.Aliases, strings.ToLower(alias))
+	}
+
+	var patterns []*regexp.Regexp
+	for _, p := range desc.Patterns {
+		re, err := regexp.Compile("(?m)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if len(patterns) > 0 {
+		confidence := desc.Confidence
+		if confidence <= 0 || confidence > 1 {
+			confidence = 0.8
+		}
+		spec.Sniff = func(content string) float64 {
+			for _, re := range patterns {
+				if re.MatchString(content) {
+					return confidence
+				}
+			}
+			return 0
+		}
+	}
+	return spec, nil
+}
+
+// plugins tracks whether plugins are loaded. Each one is asked to describe
+// itself, which takes a moment, so aster loads them only when needed.
+var plugins struct {
+	sync.Mutex
+	enabled bool // Load them when first needed
+	loaded  bool
+}
+
+// EnablePlugins has plugins loaded when they are first needed: to look up
+// a type that isn't built in, to detect a file whose extension no built-in
+// parser claims, or to detect stdin. Until then no plugin is run.
+func EnablePlugins() {
+	plugins.Lock()
+	defer plugins.Unlock()
+	plugins.enabled = true
+}
+
+// LoadPlugins discovers plugins and adds them to the parser registry, if
+// they aren't loaded already. Plugins cannot replace built-in types.
+func LoadPlugins() {
+	plugins.Lock()
+	defer plugins.Unlock()
+	if !plugins.loaded {
+		plugins.loaded = true
+		loadPlugins()
+	}
+}
+
+// needPlugins loads plugins if they are enabled and not loaded yet,
+// reporting whether it did
+func needPlugins() bool {
+	plugins.Lock()
+	defer plugins.Unlock()
+	if !plugins.enabled || plugins.loaded {
+		return false
+	}
+	plugins.loaded = true
+	loadPlugins()
+	return true
+}
+
+// loadPlugins adds the plugins found to the parser registry
+func loadPlugins() {
+	found := findPlugins(pluginDirs())
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := found[name]
+		if lookupRegistered(name) != nil {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s ignored: type %q is built in\n", path, name)
+			continue
+		}
+		desc, err := describePlugin(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s: %v\n", path, err)
+		}
+		spec, err := pluginSpec(name, path, desc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s ignored: %v\n", path, err)
+			continue
+		}
+		RegisterParser(spec)
+	}
+}
+
+// PluginNames returns the types of the plugins found, loaded or not,
+// without running them
+func PluginNames() []string {
+	var names []string
+	for name := range findPlugins(pluginDirs()) {
+		if spec := lookupRegistered(name); spec == nil || spec.Order == pluginOrder {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePlugin creates an executable shell script plugin in dir
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, pluginPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindPlugins_FirstDirWins(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	want := writePlugin(t, first, "manifest", "exit 0\n")
+	writePlugin(t, second, "manifest", "exit 0\n")
+	os.WriteFile(filepath.Join(second, pluginPrefix+"noexec"), []byte("x"), 0644)
+
+	found := findPlugins([]string{first, second})
+	if found["manifest"] != want {
+		t.Errorf("expected %s, got %s", want, found["manifest"])
+	}
+	if _, ok := found["noexec"]; ok {
+		t.Errorf("non-executable files should be ignored")
+	}
+}
+
+func TestPluginSpec_DescribeAndSniff(t *testing.T) {
+	dir := t.TempDir()
+	path := writePlugin(t, dir, "manifest", `echo '{"label":"Build manifests","extensions":["manifest"],"aliases":["mf"],"patterns":["^build:"]}'`+"\n")
+
+	desc, err := describePlugin(path)
+	if err != nil {
+		t.Fatalf("describePlugin: %v", err)
+	}
+	spec, err := pluginSpec("manifest", path, desc)
+	if err != nil {
+		t.Fatalf("pluginSpec: %v", err)
+	}
+	if !spec.HasExtension("app.manifest") {
+		t.Errorf("expected .manifest extension, got %v", spec.Extensions)
+	}
+	if spec.Sniff("name: x\nbuild: release\n") == 0 {
+		t.Errorf("expected pattern to match content")
+	}
+	if spec.Sniff("# Heading") != 0 {
+		t.Errorf("expected no match for unrelated content")
+	}
+}
+
+func TestPluginParser_DecodesBlocks(t *testing.T) {
+	dir := t.TempDir()
+	path := writePlugin(t, dir, "evals", `cat >/dev/null
+echo '[{"Name":"results","ContentType":"csv","Content":"a,b","Data":{"Records":[["a","b"],["1","2"]]}},{"Name":"notes","Pages":["p1","p2"],"PageTypes":["plain","diff"],"PageMeta":["","x.go"]}]'
+`)
+	p := &PluginParser{Name: "evals", Path: path}
+	blocks, err := p.run("input")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	data, ok := blocks[0].Data.(*CsvData)
+	if blocks[0].ContentType != BlockContentCSV || !ok || len(data.Records) != 2 {
+		t.Errorf("expected csv block with 2 records, got %v %#v", blocks[0].ContentType, blocks[0].Data)
+	}
+	if blocks[1].TotalPages != 2 || blocks[1].PageTypes[1] != BlockContentDiff || blocks[1].PageMeta[1] != "x.go" {
+		t.Errorf("unexpected page fields: %+v", blocks[1])
+	}
+}
+
+func TestPluginParser_BadOutput(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"not json":     "echo 'hello'\n",
+		"unknown type": `echo '[{"Name":"x","ContentType":"hologram"}]'` + "\n",
+		"exit status":  "echo boom >&2; exit 3\n",
+	}
+	for name, script := range tests {
+		path := writePlugin(t, dir, "bad", "cat >/dev/null\n"+script)
+		p := &PluginParser{Name: "bad", Path: path}
+		if _, err := p.run("input"); err == nil || !strings.Contains(err.Error(), "plugin bad") {
+			t.Errorf("%s: expected plugin error, got %v", name, err)
+		}
+	}
+}
+
+func TestPluginParser_Timeout(t *testing.T) {
+	saved := pluginParseTimeout
+	pluginParseTimeout = 100 * time.Millisecond
+	defer func() { pluginParseTimeout = saved }()
+
+	path := writePlugin(t, t.TempDir(), "slow", "exec sleep 5\n")
+	p := &PluginParser{Name: "slow", Path: path}
+	_, err := p.run("input")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestPlugins_LoadedWhenNeeded(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "described")
+	writePlugin(t, dir, "manifest", `: > `+marker+`
+echo '{"extensions":["manifest"]}'
+`)
+	t.Setenv("PATH", dir)
+	t.Setenv("HOME", t.TempDir())
+	saved := registered()
+	defer func() {
+		parserRegistry = saved
+		plugins.enabled, plugins.loaded = false, false
+	}()
+	EnablePlugins()
+
+	described := func() bool {
+		_, err := os.Stat(marker)
+		return err == nil
+	}
+	if spec := DetectSpec("notes.md", "# Notes"); spec == nil || spec.Name != "md" || described() {
+		t.Errorf("a file a built-in parser claims should not run plugins")
+	}
+	if LookupParser("markdown") == nil || described() {
+		t.Errorf("looking up a built-in type should not run plugins")
+	}
+	if names := PluginNames(); len(names) != 1 || names[0] != "manifest" || described() {
+		t.Errorf("PluginNames = %v, and should not run plugins", names)
+	}
+	if spec := DetectSpec("app.manifest", "name: x"); spec == nil || spec.Name != "manifest" || !described() {
+		t.Errorf("expected the plugin to be loaded for .manifest files, got %v", spec)
+	}
+}
@@ -0,0 +1,18 @@
+package parse
+
+import "testing"
+
+func TestMarkdownParse_HeadingsInsideBlocks(t *testing.T) {
+	content := "# Runbook\n\n1. Stop:\n\n   ```bash\n   # drain first\n   ## not a heading\n   ```\n\n> # quoted\n\n## Next\n\nbody"
+
+	blocks := (&MarkdownParser{}).Parse(content)
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	if blocks[0].Name != "Runbook" || blocks[1].Name != "Next" {
+		t.Errorf("unexpected block names %q, %q", blocks[0].Name, blocks[1].Name)
+	}
+	if blocks[1].LineNum != 11 || blocks[1].Content != "\nbody" {
+		t.Errorf("unexpected second block: line %d, content %q", blocks[1].LineNum, blocks[1].Content)
+	}
+}
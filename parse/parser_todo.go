@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wildreason/reader/theme"
+)
+
+// TodoItem represents a single todo from the JSON file
+type TodoItem struct {
+	Content    string `json:"content"`
+	Status     string `json:"status"`
+	ActiveForm string `json:"activeForm"`
+}
+
+// TodoParser implements Parser for JSON todo files.
+// JSON that isn't a todo list is shown as plain text.
+type TodoParser struct {
+	diags []Diagnostic
+}
+
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "json",
+		Label:      "JSON",
+		Extensions: []string{".json"},
+		Order:      40,
+		New:        func() Parser { return &TodoParser{} },
+		Sniff:      sniffTodo,
+		RawType:    BlockContentJSON,
+	})
+}
+
+// sniffTodo claims JSON arrays of todo items; other JSON is left to the txt parser
+func sniffTodo(content string) float64 {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "[") {
+		return 0
+	}
+	var todos []TodoItem
+	if err := json.Unmarshal([]byte(trimmed), &todos); err != nil || len(todos) == 0 {
+		return 0
+	}
+	for _, t := range todos {
+		if t.Content == "" || t.Status == "" {
+			return 0
+		}
+	}
+	return 0.95
+}
+
+// Diagnostics returns the problems found by the last Parse
+func (p *TodoParser) Diagnostics() []Diagnostic {
+	return p.diags
+}
+
+// Parse reads a JSON todo file and creates a single block
+func (p *TodoParser) Parse(content string) []Block {
+	p.diags = nil
+	if !json.Valid([]byte(content)) {
+		var v interface{}
+		err := json.Unmarshal([]byte(content), &v)
+		p.diags = append(p.diags, jsonDiagnostic(content, 1, err))
+		return (&TxtParser{}).Parse(content)
+	}
+
+	var todos []TodoItem
+	if err := json.Unmarshal([]byte(content), &todos); err != nil || len(todos) == 0 {
+		// Valid JSON, but not a todo list
+		return (&TxtParser{}).Parse(content)
+	}
+
+	// Count completed
+	completed := 0
+	for _, t := range todos {
+		if t.Status == "completed" {
+			completed++
+		}
+	}
+
+	// Build content
+	var sb strings.Builder
+
+	// Header with progress
+	sb.WriteString(fmt.Sprintf("%stodos[-] (%d/%d completed)\n\n", theme.Tag(theme.Highlight), completed, len(todos)))
+
+	// Render each todo
+	// Use unicode symbols instead of brackets to avoid tview escaping issues
+	for _, t := range todos {
+		if t.Status == "completed" {
+			sb.WriteString(fmt.Sprintf("%s✓[-] %s\n", theme.Tag(theme.Success), t.Content))
+		} else if t.Status == "in_progress" {
+			sb.WriteString(fmt.Sprintf("%s→[-] %s\n", theme.Tag(theme.Accent), t.Content))
+		} else {
+			sb.WriteString(fmt.Sprintf("%s○[-] %s\n", theme.Tag(theme.Muted), t.Content))
+		}
+	}
+
+	blockContent := sb.String()
+
+	return []Block{
+		{
+			Name:        fmt.Sprintf("%stodos[-] %s%d/%d[-]", theme.Tag(theme.Highlight), theme.Tag(theme.Muted), completed, len(todos)),
+			Content:     blockContent,
+			LineNum:     0,
+			Pages:       []string{blockContent},
+			TotalPages:  1,
+			ContentType: BlockContentPlain,
+		},
+	}
+}
@@ -0,0 +1,212 @@
+package parse
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// extractCommandFromStyledLine extracts the command text from a styled line
+// Handles formats like: [white:#303030] command [-:-:-]
+func extractCommandFromStyledLine(line string) string {
+	// Remove tview color tags: [anything] and [-:-:-]
+	tviewTagRegex := regexp.MustCompile(`\[[^\]]*\]`)
+	cleaned := tviewTagRegex.ReplaceAllString(line, "")
+	return strings.TrimSpace(cleaned)
+}
+
+// TxtParser implements Parser for plain text / shell output files
+type TxtParser struct{}
+
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "txt",
+		Label:      "Plain text",
+		Extensions: []string{".txt", ".log"},
+		Aliases:    []string{"text", "log"},
+		Order:      20,
+		New:        func() Parser { return &TxtParser{} },
+		Sniff:      sniffTxt,
+		RawType:    BlockContentPlain,
+	})
+	RegisterParser(&ParserSpec{
+		Name:       "yaml",
+		Label:      "YAML",
+		Extensions: []string{".yaml", ".yml"},
+		Aliases:    []string{"yml"},
+		Order:      50,
+		New:        func() Parser { return &TxtParser{} },
+		Sniff:      sniffYAML,
+		RawType:    BlockContentYAML,
+	})
+}
+
+// sniffTxt recognizes captured shell sessions; generic JSON is also shown as
+// plain text so its structure is preserved
+func sniffTxt(content string) float64 {
+	lines := strings.SplitN(content, "\n", 50)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "shell" || strings.HasPrefix(line, "$ ") {
+			return 0.6
+		}
+	}
+	if isJSON(content) {
+		return 0.5
+	}
+	return 0
+}
+
+// sniffYAML scores key/value documents; bare bullet lists are left to markdown
+func sniffYAML(content string) float64 {
+	if !isYAML(content) {
+		return 0
+	}
+	keyValues := 0
+	listItems := 0
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") {
+			listItems++
+		} else if strings.Contains(trimmed, ": ") || strings.HasSuffix(trimmed, ":") {
+			keyValues++
+		}
+	}
+	if keyValues < listItems {
+		return 0.2
+	}
+	return 0.55
+}
+
+// Parse reads a txt file and extracts blocks
+// Each "shell" line starts a new block, followed by command on next line
+func (p *TxtParser) Parse(content string) []Block {
+	var blocks []Block
+	b := txtBlockBuilder{emit: func(block Block) { blocks = append(blocks, block) }}
+	for _, line := range strings.Split(content, "\n") {
+		b.addLine(line)
+	}
+	b.finish()
+
+	// If no blocks found, create one with all content
+	if len(blocks) == 0 && strings.TrimSpace(content) != "" {
+		allLines := strings.Split(content, "\n")
+		blocks = append(blocks, Block{
+			Name:        "Output",
+			Content:     content,
+			LineNum:     1,
+			Pages:       splitIntoPages(allLines, LinesPerPage),
+			TotalPages:  len(splitIntoPages(allLines, LinesPerPage)),
+			ContentType: BlockContentShell,
+		})
+	}
+
+	return blocks
+}
+
+// ParseStream reads txt content line by line, emitting each shell block when
+// the next one starts. Long blocks are emitted in chunks of streamChunkLines.
+func (p *TxtParser) ParseStream(ctx context.Context, r io.Reader, emit func(Block)) error {
+	b := txtBlockBuilder{emit: emit, maxLines: streamChunkLines}
+	err := scanLines(ctx, r, b.addLine)
+	b.finish()
+	if b.emitted == 0 && b.pendingShell {
+		// A lone "shell" line with nothing after it
+		emit(Block{
+			Name:        "Output",
+			Content:     "shell",
+			LineNum:     1,
+			Pages:       []string{"shell"},
+			TotalPages:  1,
+			ContentType: BlockContentShell,
+		})
+	}
+	return err
+}
+
+// txtBlockBuilder groups txt lines into shell blocks one line at a time
+type txtBlockBuilder struct {
+	emit         func(Block)
+	maxLines     int // Emit the current block early once it holds this many lines (0 = no limit)
+	current      *Block
+	lines        []string
+	blockNum     int
+	emitted      int
+	pendingShell bool // A "shell" line was seen; the next line is its command
+}
+
+// addLine feeds one line of input to the builder
+func (b *txtBlockBuilder) addLine(line string) {
+	if b.pendingShell {
+		// Command line following "shell" (strip tview color tags)
+		b.pendingShell = false
+		command := extractCommandFromStyledLine(line)
+		if command == "" {
+			command = "shell"
+		}
+		// Truncate long command names
+		if len(command) > 40 {
+			command = command[:40] + "..."
+		}
+		b.start(command, line)
+		return
+	}
+
+	switch {
+	case strings.TrimSpace(line) == "shell":
+		// New format: "shell" line followed by command
+		b.flush()
+		b.current = nil
+		b.pendingShell = true
+	case strings.HasPrefix(line, "$ "):
+		// Old format: "$ command (timestamp)" - backward compatibility
+		name := line[2:] // Remove "$ "
+		if idx := strings.Index(name, " ("); idx > 0 {
+			name = name[:idx]
+		}
+		if len(name) > 40 {
+			name = name[:40] + "..."
+		}
+		b.start(name, line)
+	case b.current != nil:
+		if b.maxLines > 0 && len(b.lines) >= b.maxLines {
+			b.flush()
+		}
+		b.lines = append(b.lines, line)
+	case strings.TrimSpace(line) != "":
+		// Content before first shell block - create default block
+		b.start("Output", line)
+	}
+}
+
+// start saves the current block and begins a new one with its first line
+func (b *txtBlockBuilder) start(name string, first string) {
+	b.flush()
+	b.blockNum++
+	b.current = &Block{
+		Name:        name,
+		LineNum:     b.blockNum,
+		ContentType: BlockContentShell,
+	}
+	b.lines = []string{first}
+}
+
+// flush emits the lines collected for the current block, keeping the block
+// open so a chunked block continues under the same name
+func (b *txtBlockBuilder) flush() {
+	if b.current == nil || len(b.lines) == 0 {
+		return
+	}
+	block := *b.current
+	block.Content = strings.Join(b.lines, "\n")
+	block.Pages = splitIntoPages(b.lines, LinesPerPage)
+	block.TotalPages = len(block.Pages)
+	b.emit(block)
+	b.emitted++
+	b.lines = nil
+}
+
+// finish emits the last block
+func (b *txtBlockBuilder) finish() {
+	b.flush()
+}
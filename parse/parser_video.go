@@ -1,4 +1,4 @@
-package main
+package parse
 
 import (
 	"encoding/base64"
@@ -8,18 +8,36 @@ import (
 	"strings"
 )
 
+// VideoExtensions lists file extensions handled by the video parser
+var VideoExtensions = []string{".mp4", ".webm", ".mov", ".mkv"}
+
 // VideoParser implements FileParser for video files
 type VideoParser struct{}
 
-// Detect checks if file is a video
-func (p *VideoParser) Detect(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	for _, e := range videoExtensions {
-		if ext == e {
-			return true
-		}
+func init() {
+	RegisterParser(&ParserSpec{
+		Name:       "vid",
+		Label:      "Video",
+		Extensions: VideoExtensions,
+		Aliases:    []string{"video"},
+		Order:      90,
+		New:        func() Parser { return &VideoParser{} },
+		Sniff:      sniffVideo,
+		Binary:     true,
+		Structured: true,
+		RawType:    BlockContentVideo,
+	})
+}
+
+// sniffVideo recognizes MP4/MOV (ftyp box) and Matroska/WebM (EBML header)
+func sniffVideo(content string) float64 {
+	if len(content) >= 12 && content[4:8] == "ftyp" {
+		return 1
 	}
-	return false
+	if strings.HasPrefix(content, "\x1a\x45\xdf\xa3") {
+		return 1
+	}
+	return 0
 }
 
 // Parse is not used for video (binary content); returns nil
@@ -37,7 +55,7 @@ func (p *VideoParser) ParseFile(filePath string, static bool) ([]Block, error) {
 
 	title := filepath.Base(filePath)
 	ext := filepath.Ext(filePath)
-	mime := videoMIME(ext)
+	mime := VideoMIME(ext)
 
 	var src string
 	inline := false
@@ -82,3 +100,19 @@ func (p *VideoParser) ParseFile(filePath string, static bool) ([]Block, error) {
 
 	return []Block{block}, nil
 }
+
+// VideoMIME returns the MIME type for a video extension
+func VideoMIME(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".mp4":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".mov":
+		return "video/quicktime"
+	case ".mkv":
+		return "video/x-matroska"
+	default:
+		return "application/octet-stream"
+	}
+}
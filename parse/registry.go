@@ -0,0 +1,277 @@
+package parse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ParserSpec describes a parser registered with the parser registry.
+// Detection, -t TYPE, `aster <type>` subcommands and help text are all driven from specs.
+type ParserSpec struct {
+	Name       string   // Type key used by -t and subcommands (e.g. "md")
+	Label      string   // Human-readable name for help text (e.g. "Markdown")
+	Extensions []string // File extensions including the dot (e.g. ".md")
+	Aliases    []string // Alternative type keys (e.g. "markdown")
+	Order      int      // Position in help text; also breaks detection ties
+	Detail     string   // Help text shown instead of the extension list (optional)
+
+	// New returns a fresh parser instance
+	New func() Parser
+
+	// Sniff returns a confidence score in [0, 1] that content belongs to this type.
+	// nil means the parser is only selected by extension or by name.
+	Sniff func(content string) float64
+
+	// Binary marks file-based content (images, video) handled by FileParser.ParseFile
+	Binary bool
+
+	// Structured marks parsers whose blocks are used as-is for HTML output.
+	// Unstructured types are rendered as a single block of RawType.
+	Structured bool
+	RawType    BlockContentType
+}
+
+// sniffSampleSize bounds how much of a file is read for content sniffing
+const sniffSampleSize = 64 * 1024
+
+// minSniffConfidence is the lowest score accepted when detecting by content alone
+const minSniffConfidence = 0.3
+
+// parserRegistry holds all registered parser specs. Plugins may be added
+// while it is read, so it is read through registered.
+var (
+	parserRegistry []*ParserSpec
+	registryMu     sync.RWMutex
+)
+
+// registered returns the registered specs in help-text order
+func registered() []*ParserSpec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Clone(parserRegistry)
+}
+
+// RegisterParser adds a parser spec to the registry.
+// A spec with the same name replaces the existing one.
+func RegisterParser(spec *ParserSpec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for i, existing := range parserRegistry {
+		if existing.Name == spec.Name {
+			parserRegistry[i] = spec
+			return
+		}
+	}
+	parserRegistry = append(parserRegistry, spec)
+	sort.SliceStable(parserRegistry, func(i, j int) bool {
+		return parserRegistry[i].Order < parserRegistry[j].Order
+	})
+}
+
+// RegisteredParsers returns all specs in help-text order. Plugins are
+// among them once loaded.
+func RegisteredParsers() []*ParserSpec {
+	return registered()
+}
+
+// LookupParser finds a spec by type name or alias (case-insensitive),
+// loading plugins for a name that isn't built in
+func LookupParser(name string) *ParserSpec {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	if spec := lookupRegistered(name); spec != nil || !needPlugins() {
+		return spec
+	}
+	return lookupRegistered(name)
+}
+
+// lookupRegistered finds a registered spec by type name or alias
+func lookupRegistered(name string) *ParserSpec {
+	specs := registered()
+	for _, spec := range specs {
+		if spec.Name == name {
+			return spec
+		}
+	}
+	for _, spec := range specs {
+		for _, alias := range spec.Aliases {
+			if alias == name {
+				return spec
+			}
+		}
+	}
+	return nil
+}
+
+// HasExtension reports whether the spec claims the extension of filePath.
+// Extensions may have several dots, like ".jsonl.log".
+func (s *ParserSpec) HasExtension(filePath string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return s.hasExtension(filePath)
+}
+
+// hasExtension is HasExtension with registryMu held, as AssociateExtension
+// changes Extensions
+func (s *ParserSpec) hasExtension(filePath string) bool {
+	name := strings.ToLower(filepath.Base(filePath))
+	for _, e := range s.Extensions {
+		if strings.HasSuffix(name, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// associations maps extensions to the types files ending in them open as,
+// overriding detection. Like the specs' Extensions, it is guarded by
+// registryMu.
+var associations = map[string]*ParserSpec{}
+
+// AssociateExtension makes files ending in ext (e.g. ".mdx" or
+// ".jsonl.log") open as the named type
+func AssociateExtension(ext, typeName string) error {
+	spec := LookupParser(typeName)
+	if spec == nil {
+		return fmt.Errorf("unknown type %q", typeName)
+	}
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if len(ext) < 2 {
+		return fmt.Errorf("empty extension for type %q", typeName)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	associations[ext] = spec
+	if !spec.hasExtension(ext) {
+		// Replace the list rather than append to it: it may be shared,
+		// like ImageExtensions, and readers may hold it
+		spec.Extensions = append(slices.Clone(spec.Extensions), ext)
+	}
+	return nil
+}
+
+// associatedSpec returns the type associated with the longest extension
+// filePath ends in, if any
+func associatedSpec(filePath string) *ParserSpec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	name := strings.ToLower(filepath.Base(filePath))
+	var best *ParserSpec
+	bestLen := 0
+	for ext, spec := range associations {
+		if len(ext) > bestLen && strings.HasSuffix(name, ext) {
+			best, bestLen = spec, len(ext)
+		}
+	}
+	return best
+}
+
+// score rates how well the spec matches a file path and content sample.
+// An extension match is worth 1; the sniffer adds its confidence on top.
+func (s *ParserSpec) score(filePath string, content string) float64 {
+	score := 0.0
+	if filePath != "" && s.HasExtension(filePath) {
+		score += 1
+	}
+	if s.Sniff != nil && content != "" {
+		score += s.Sniff(content)
+	}
+	return score
+}
+
+// DetectSpec picks the best spec for a file path and/or content sample.
+// Returns nil when nothing matches by extension and no sniffer is confident enough.
+// Plugins are loaded unless a built-in parser claims the file's extension.
+func DetectSpec(filePath string, content string) *ParserSpec {
+	if spec := associatedSpec(filePath); spec != nil {
+		return spec
+	}
+	best, bestScore := bestSpec(filePath, content, false)
+	if bestScore < 1 && needPlugins() {
+		best, bestScore = bestSpec(filePath, content, false)
+	}
+	if bestScore < minSniffConfidence {
+		return nil
+	}
+	return best
+}
+
+// bestSpec returns the registered spec scoring highest for a file path
+// and/or content sample, and its score, leaving out binary types when
+// textOnly is set
+func bestSpec(filePath string, content string, textOnly bool) (*ParserSpec, float64) {
+	var best *ParserSpec
+	bestScore := 0.0
+	for _, spec := range registered() {
+		if textOnly && spec.Binary {
+			continue
+		}
+		s := spec.score(filePath, content)
+		if s > bestScore {
+			best = spec
+			bestScore = s
+		}
+	}
+	return best, bestScore
+}
+
+// readSniffSample reads the head of a file for content sniffing
+func readSniffSample(filePath string) string {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	buf := make([]byte, sniffSampleSize)
+	n, _ := f.Read(buf)
+	return string(buf[:n])
+}
+
+// DetectFileSpec returns the spec for a file, sniffing its content when needed
+func DetectFileSpec(filePath string) *ParserSpec {
+	if spec := DetectSpec(filePath, readSniffSample(filePath)); spec != nil {
+		return spec
+	}
+	return LookupParser("md")
+}
+
+// DetectParser selects the appropriate parser for a file by extension and content
+func DetectParser(filePath string) Parser {
+	return DetectFileSpec(filePath).New()
+}
+
+// DetectContentSpec returns the spec for content with no file name (stdin)
+func DetectContentSpec(content string) *ParserSpec {
+	needPlugins()
+	best, bestScore := bestSpec("", content, true)
+	if best == nil || bestScore < minSniffConfidence {
+		return LookupParser("md")
+	}
+	return best
+}
+
+// DetectParserFromContent tries to detect parser type from content (for stdin)
+func DetectParserFromContent(content string) Parser {
+	return DetectContentSpec(content).New()
+}
+
+// TypeNames returns the names of all text (non-binary) types for help text
+func TypeNames() []string {
+	var names []string
+	for _, spec := range registered() {
+		if !spec.Binary {
+			names = append(names, spec.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,156 @@
+package parse
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLookupParser_NamesAndAliases(t *testing.T) {
+	tests := map[string]string{
+		"md":       "md",
+		"markdown": "md",
+		"YAML":     "yaml",
+		"yml":      "yaml",
+		"patch":    "diff",
+		"image":    "img",
+	}
+	for input, want := range tests {
+		spec := LookupParser(input)
+		if spec == nil || spec.Name != want {
+			t.Errorf("LookupParser(%q) = %v, want %q", input, spec, want)
+		}
+	}
+	if LookupParser("nope") != nil {
+		t.Errorf("LookupParser should return nil for unknown types")
+	}
+}
+
+func TestDetectSpec_SniffsWithoutExtension(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"diff", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n+new\n", "diff"},
+		{"jsonl", "{\"type\":\"user\"}\n{\"type\":\"assistant\"}\n", "jsonl"},
+		{"csv", "name,age,city\nalice,30,paris\nbob,25,rome\n", "csv"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "img"},
+		{"markdown", "# Title\n\nSome **bold** text and a [link](x).\n", "md"},
+	}
+	for _, tc := range tests {
+		spec := DetectSpec("noext", tc.content)
+		if spec == nil || spec.Name != tc.want {
+			t.Errorf("%s: DetectSpec = %v, want %q", tc.name, spec, tc.want)
+		}
+	}
+}
+
+func TestDetectSpec_ExtensionBeatsWeakSniff(t *testing.T) {
+	spec := DetectSpec("notes.txt", "# Heading\n\nbody")
+	if spec == nil || spec.Name != "txt" {
+		t.Errorf("expected .txt extension to win, got %v", spec)
+	}
+}
+
+func TestDetectSpec_ContractFrontmatter(t *testing.T) {
+	spec := DetectSpec("deal.md", "---\ntype: contract\n---\n## 1. Terms\n")
+	if spec == nil || spec.Name != "contract" {
+		t.Errorf("expected contract spec, got %v", spec)
+	}
+}
+
+func TestDetectContentSpec_FallsBackToMarkdown(t *testing.T) {
+	if spec := DetectContentSpec("just some words"); spec.Name != "md" {
+		t.Errorf("expected md fallback, got %q", spec.Name)
+	}
+}
+
+func TestAssociateExtension(t *testing.T) {
+	md, jsonl := LookupParser("md"), LookupParser("jsonl")
+	mdExts, jsonlExts := md.Extensions, jsonl.Extensions
+	defer func() {
+		associations = map[string]*ParserSpec{}
+		md.Extensions, jsonl.Extensions = mdExts, jsonlExts
+	}()
+	if err := AssociateExtension(".mdx", "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AssociateExtension("jsonl.log", "jsonl"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AssociateExtension(".x", "nope"); err == nil {
+		t.Errorf("expected an error for an unknown type")
+	}
+
+	tests := map[string]string{
+		"docs/page.mdx":      "md",
+		"run.JSONL.LOG":      "jsonl",
+		"server.log":         "txt",
+		"notes/changes.diff": "diff",
+	}
+	for path, want := range tests {
+		if spec := DetectSpec(path, "plain words"); spec == nil || spec.Name != want {
+			t.Errorf("DetectSpec(%q) = %v, want %q", path, spec, want)
+		}
+	}
+	if !md.HasExtension("page.mdx") {
+		t.Errorf("md spec should claim .mdx after association")
+	}
+}
+
+func TestAssociateExtension_LeavesSharedListsAlone(t *testing.T) {
+	img, vid := LookupParser("img"), LookupParser("vid")
+	imgExts, vidExts := img.Extensions, vid.Extensions
+	defer func() {
+		associations = map[string]*ParserSpec{}
+		img.Extensions, vid.Extensions = imgExts, vidExts
+	}()
+	// Two specs sharing a list with room to grow in place
+	shared := make([]string, 1, 4)
+	shared[0] = ".raw"
+	img.Extensions, vid.Extensions = shared, shared
+	if err := AssociateExtension(".avif", "img"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AssociateExtension(".m4v", "vid"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(img.Extensions, []string{".raw", ".avif"}) || !slices.Equal(vid.Extensions, []string{".raw", ".m4v"}) {
+		t.Errorf("img extensions %v, vid extensions %v", img.Extensions, vid.Extensions)
+	}
+	if !slices.Equal(shared, []string{".raw"}) {
+		t.Errorf("shared list changed to %v", shared)
+	}
+}
+
+// TestAssociateExtension_Concurrent associates extensions while files are
+// detected, as when plugins load mid-run; run with -race
+func TestAssociateExtension_Concurrent(t *testing.T) {
+	md := LookupParser("md")
+	mdExts := md.Extensions
+	defer func() {
+		associations = map[string]*ParserSpec{}
+		md.Extensions = mdExts
+	}()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			if err := AssociateExtension(fmt.Sprintf(".m%d", i), "md"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := range 50 {
+		DetectSpec(fmt.Sprintf("page.m%d", i), "")
+		md.HasExtension("page.mdx")
+	}
+	wg.Wait()
+	if spec := DetectSpec("page.m49", ""); spec != md {
+		t.Errorf("DetectSpec(page.m49) = %v, want md", spec)
+	}
+}
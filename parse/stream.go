@@ -0,0 +1,65 @@
+package parse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// StreamParser is implemented by parsers that can read input incrementally.
+// ParseStream calls emit for each block as soon as it is complete, so callers
+// can show the first blocks before the rest of the input has been read.
+type StreamParser interface {
+	Parser
+	ParseStream(ctx context.Context, r io.Reader, emit func(Block)) error
+}
+
+// streamChunkLines caps the lines held in a streamed block; longer sections
+// are emitted as several blocks so memory stays bounded
+const streamChunkLines = 20 * LinesPerPage
+
+// ctxCheckInterval is how many lines scanLines reads between context checks
+const ctxCheckInterval = 1024
+
+// ParseStream parses r with p, streaming when p is a StreamParser and
+// reading all input and calling Parse otherwise
+func ParseStream(ctx context.Context, p Parser, r io.Reader, emit func(Block)) error {
+	if sp, ok := p.(StreamParser); ok {
+		return sp.ParseStream(ctx, r, emit)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, block := range p.Parse(string(data)) {
+		emit(block)
+	}
+	return nil
+}
+
+// scanLines calls fn for each line of r without its line ending, stopping
+// early if ctx is cancelled. Lines are not length limited.
+func scanLines(ctx context.Context, r io.Reader, fn func(line string)) error {
+	br := bufio.NewReaderSize(r, sniffSampleSize)
+	for n := 1; ; n++ {
+		if n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		line, err := br.ReadString('\n')
+		if len(line) > 0 || err == nil {
+			fn(strings.TrimSuffix(line, "\n"))
+		}
+		if err == io.EOF {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collectStream runs ParseStream and returns the emitted blocks
+func collectStream(t *testing.T, p Parser, content string) []Block {
+	t.Helper()
+	var blocks []Block
+	err := ParseStream(context.Background(), p, strings.NewReader(content), func(b Block) {
+		blocks = append(blocks, b)
+	})
+	if err != nil {
+		t.Fatalf("ParseStream: %v", err)
+	}
+	return blocks
+}
+
+func TestStreamParsers(t *testing.T) {
+	for _, name := range []string{"txt", "diff", "csv", "jsonl"} {
+		if _, ok := LookupParser(name).New().(StreamParser); !ok {
+			t.Errorf("%s parser does not implement StreamParser", name)
+		}
+	}
+}
+
+func TestTxtParseStreamMatchesParse(t *testing.T) {
+	content := "intro line\nshell\n[white:#303030] ls -la [-:-:-]\nfile1\nfile2\n$ git status (10:00)\nclean"
+	p := &TxtParser{}
+	want := p.Parse(content)
+	got := collectStream(t, p, content)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stream blocks differ from Parse\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestTxtParseStreamChunksLongBlocks(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("$ make\n")
+	for i := 0; i < streamChunkLines*2; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	blocks := collectStream(t, &TxtParser{}, sb.String())
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks, want 3", len(blocks))
+	}
+	for _, b := range blocks {
+		if b.Name != "make" {
+			t.Errorf("chunk name = %q, want %q", b.Name, "make")
+		}
+	}
+}
+
+func TestDiffParseStreamSplitsFiles(t *testing.T) {
+	content := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+ package a
+-var x = 1
++var x = 2
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1 +1,2 @@
+ package b
++--- not a header
+`
+	blocks := collectStream(t, &DiffParser{}, content)
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	if blocks[0].Name != "a.go" || blocks[1].Name != "b.go" {
+		t.Errorf("names = %q, %q", blocks[0].Name, blocks[1].Name)
+	}
+	if !strings.Contains(blocks[1].Content, "+--- not a header") {
+		t.Error("added line starting with --- split the file section")
+	}
+}
+
+func TestCsvParseStreamChunks(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id,name\n")
+	for i := 1; i <= csvStreamRows+10; i++ {
+		fmt.Fprintf(&sb, "%d,row%d\n", i, i)
+	}
+	blocks := collectStream(t, &CsvParser{}, sb.String())
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	want := fmt.Sprintf("CSV (rows %d-%d)", csvStreamRows+1, csvStreamRows+10)
+	if blocks[1].Name != want {
+		t.Errorf("second block name = %q, want %q", blocks[1].Name, want)
+	}
+	data := blocks[1].Data.(*CsvData)
+	if data.Records[0][1] != "name" || len(data.Records) != 11 {
+		t.Errorf("second chunk records = %v", data.Records)
+	}
+}
+
+func TestJSONLParseStreamMatchesParse(t *testing.T) {
+	content := `{"type":"user","message":{"content":"first question"}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"first answer"}]}}
+{"type":"user","message":{"content":"second question"}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"second answer"}]}}`
+	want := (&JSONLParser{}).Parse(content)
+	got := collectStream(t, &JSONLParser{}, content)
+	if len(want) != 2 {
+		t.Fatalf("Parse returned %d blocks, want 2", len(want))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stream blocks differ from Parse\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestParseStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	content := strings.Repeat("$ echo hi\nhi\n", ctxCheckInterval)
+	err := (&TxtParser{}).ParseStream(ctx, strings.NewReader(content), func(Block) {})
+	if err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
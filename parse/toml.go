@@ -0,0 +1,442 @@
+package parse
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlParser reads TOML frontmatter: key/value pairs with dotted keys,
+// [tables] and [[arrays of tables]], strings, numbers, booleans, arrays and
+// inline tables. Dates and times are kept as strings.
+type tomlParser struct {
+	src string
+	pos int
+}
+
+// ParseTOML parses a TOML document, such as frontmatter or a config file
+func ParseTOML(src string) (*Map, error) {
+	p := &tomlParser{src: strings.ReplaceAll(src, "\r\n", "\n")}
+	root := NewMap()
+	table := root
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return root, nil
+		}
+		switch p.src[p.pos] {
+		case '\n':
+			p.pos++
+			continue
+		case '#':
+			p.skipComment()
+			continue
+		case '[':
+			t, err := p.header(root)
+			if err != nil {
+				return nil, err
+			}
+			table = t
+		default:
+			if err := p.keyValue(table); err != nil {
+				return nil, err
+			}
+		}
+		p.skipSpace()
+		p.skipComment()
+		if p.pos < len(p.src) && p.src[p.pos] != '\n' {
+			return nil, p.errorf("expected a new line, found %q", p.rest())
+		}
+	}
+}
+
+// errorf returns an error at the current line
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return &FrontmatterError{
+		Line:    1 + strings.Count(p.src[:p.pos], "\n"),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// rest returns the remainder of the current line, for errors
+func (p *tomlParser) rest() string {
+	rest := p.src[p.pos:]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
+
+// skipSpace moves past spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment moves past a # comment to the end of the line
+func (p *tomlParser) skipComment() {
+	if p.pos < len(p.src) && p.src[p.pos] == '#' {
+		for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank moves past whitespace, line breaks and comments, as allowed
+// inside arrays
+func (p *tomlParser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n':
+			p.pos++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// header parses a [table] or [[array of tables]] line and returns the table
+// that following keys belong to
+func (p *tomlParser) header(root *Map) (*Map, error) {
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	p.pos++
+	if array {
+		p.pos++
+	}
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q", closing)
+	}
+	p.pos += len(closing)
+
+	if !array {
+		return p.table(root, keys)
+	}
+	parent, err := p.table(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	t := NewMap()
+	switch existing := parent.Values[last].(type) {
+	case nil:
+		if _, ok := parent.Values[last]; ok {
+			return nil, p.errorf("key %q is already defined", last)
+		}
+		parent.Set(last, []any{t})
+	case []any:
+		parent.Values[last] = append(existing, t)
+	default:
+		return nil, p.errorf("key %q is already defined", last)
+	}
+	return t, nil
+}
+
+// table returns the table at keys under m, creating missing tables. A key
+// holding an array of tables refers to its last table.
+func (p *tomlParser) table(m *Map, keys []string) (*Map, error) {
+	for _, key := range keys {
+		v, ok := m.Get(key)
+		if !ok {
+			t := NewMap()
+			m.Set(key, t)
+			m = t
+			continue
+		}
+		switch v := v.(type) {
+		case *Map:
+			m = v
+		case []any:
+			t, ok := any(nil), false
+			if len(v) > 0 {
+				t = v[len(v)-1]
+			}
+			if m, ok = t.(*Map); !ok {
+				return nil, p.errorf("key %q is not a table", key)
+			}
+		default:
+			return nil, p.errorf("key %q is not a table", key)
+		}
+	}
+	return m, nil
+}
+
+// keyValue parses "key = value" into t
+func (p *tomlParser) keyValue(t *Map) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return p.errorf("expected \"=\" after key")
+	}
+	p.pos++
+	p.skipSpace()
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.table(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, dup := parent.Values[last]; dup {
+		return p.errorf("duplicate key %q", last)
+	}
+	parent.Set(last, v)
+	return nil
+}
+
+// key parses a bare, quoted or dotted key
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("expected a key")
+		}
+		switch c := p.src[p.pos]; c {
+		case '"', '\'':
+			if strings.HasPrefix(p.src[p.pos:], strings.Repeat(string(c), 3)) {
+				return nil, p.errorf("keys can't be multi-line strings")
+			}
+			s, err := p.str(c)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, s)
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && isTOMLBareKey(p.src[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a key, found %q", p.rest())
+			}
+			keys = append(keys, p.src[start:p.pos])
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+var (
+	reTOMLDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?)?([Zz]|[-+]\d{2}:\d{2})?$|^\d{2}:\d{2}:\d{2}(\.\d+)?$`)
+	reTOMLNum  = regexp.MustCompile(`^[-+]?(0x[0-9A-Fa-f_]+|0o[0-7_]+|0b[01_]+|[0-9_]+(\.[0-9_]+)?([eE][-+]?[0-9_]+)?|inf|nan)$`)
+)
+
+// value parses a TOML value
+func (p *tomlParser) value() (any, error) {
+	if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+		return nil, p.errorf("expected a value")
+	}
+	switch c := p.src[p.pos]; c {
+	case '"', '\'':
+		return p.str(c)
+	case '[':
+		p.pos++
+		items := []any{}
+		for {
+			p.skipBlank()
+			if p.pos >= len(p.src) {
+				return nil, p.errorf("unterminated array")
+			}
+			if p.src[p.pos] == ']' {
+				p.pos++
+				return items, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+			p.skipBlank()
+			if p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+				return nil, p.errorf("expected \",\" or \"]\" in array")
+			}
+		}
+	case '{':
+		p.pos++
+		t := NewMap()
+		for {
+			p.skipSpace()
+			if p.pos < len(p.src) && p.src[p.pos] == '}' {
+				p.pos++
+				return t, nil
+			}
+			if err := p.keyValue(t); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.src) || p.src[p.pos] != '}' {
+				return nil, p.errorf("expected \",\" or \"}\" in inline table")
+			}
+		}
+	}
+
+	// Booleans, numbers and dates run to the next delimiter; a date and a
+	// time may be separated by a space
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\n,]}#", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if p.pos+1 < len(p.src) && p.src[p.pos] == ' ' && p.pos-start == 10 && isDigit(p.src[p.pos+1]) && reTOMLDate.MatchString(p.src[start:p.pos]) {
+		p.pos++
+		for p.pos < len(p.src) && !strings.ContainsRune(" \t\n,]}#", rune(p.src[p.pos])) {
+			p.pos++
+		}
+	}
+	token := p.src[start:p.pos]
+	switch {
+	case token == "true":
+		return true, nil
+	case token == "false":
+		return false, nil
+	case reTOMLDate.MatchString(token):
+		return token, nil
+	case reTOMLNum.MatchString(token):
+		if v, ok := tomlNumber(token); ok {
+			return v, nil
+		}
+	}
+	p.pos = start
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// tomlNumber converts an integer or float token
+func tomlNumber(token string) (any, bool) {
+	sign := ""
+	digits := strings.ReplaceAll(token, "_", "")
+	if digits[0] == '+' || digits[0] == '-' {
+		sign, digits = digits[:1], digits[1:]
+	}
+	switch {
+	case digits == "inf":
+		if sign == "-" {
+			return math.Inf(-1), true
+		}
+		return math.Inf(1), true
+	case digits == "nan":
+		return math.NaN(), true
+	case strings.HasPrefix(digits, "0x"), strings.HasPrefix(digits, "0o"), strings.HasPrefix(digits, "0b"):
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[digits[1]]
+		n, err := strconv.ParseInt(digits[2:], base, 64)
+		return n, err == nil
+	case strings.ContainsAny(digits, ".eE"):
+		f, err := strconv.ParseFloat(sign+digits, 64)
+		return f, err == nil
+	}
+	n, err := strconv.ParseInt(sign+digits, 10, 64)
+	return n, err == nil
+}
+
+// str parses a basic ("...") or literal ('...') string, single or multi-line
+func (p *tomlParser) str(quote byte) (string, error) {
+	delim := strings.Repeat(string(quote), 3)
+	multi := strings.HasPrefix(p.src[p.pos:], delim)
+	if multi {
+		p.pos += 3
+		// A line break right after the opening delimiter is trimmed
+		if strings.HasPrefix(p.src[p.pos:], "\n") {
+			p.pos++
+		}
+	} else {
+		p.pos++
+	}
+
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case multi && strings.HasPrefix(p.src[p.pos:], delim):
+			// Up to two quotes may end the string just before its delimiter
+			p.pos += 3
+			for i := 0; i < 2 && p.pos < len(p.src) && p.src[p.pos] == quote; i++ {
+				sb.WriteByte(quote)
+				p.pos++
+			}
+			return sb.String(), nil
+		case !multi && c == quote:
+			p.pos++
+			return sb.String(), nil
+		case !multi && c == '\n':
+			return "", p.errorf("unterminated string")
+		case c == '\\' && quote == '"':
+			if err := p.escape(&sb, multi); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// tomlEscapes maps single-character escapes to the text they stand for
+var tomlEscapes = map[byte]string{
+	'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", 'e': "\x1b", '"': "\"", '\\': "\\",
+}
+
+// escape writes the character for the escape sequence at p.pos. In
+// multi-line strings a backslash at the end of a line trims the following
+// whitespace.
+func (p *tomlParser) escape(sb *strings.Builder, multi bool) error {
+	p.pos++
+	if p.pos >= len(p.src) {
+		return p.errorf("unterminated string")
+	}
+	c := p.src[p.pos]
+	if simple, ok := tomlEscapes[c]; ok {
+		sb.WriteString(simple)
+		p.pos++
+		return nil
+	}
+	if multi && (c == ' ' || c == '\t' || c == '\n') {
+		rest := strings.TrimLeft(p.src[p.pos:], " \t")
+		if strings.HasPrefix(rest, "\n") {
+			p.pos = len(p.src) - len(strings.TrimLeft(rest, " \t\n"))
+			return nil
+		}
+	}
+	if width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]; width > 0 && p.pos+width < len(p.src) {
+		code, err := strconv.ParseUint(p.src[p.pos+1:p.pos+1+width], 16, 32)
+		if err == nil && utf8.ValidRune(rune(code)) {
+			sb.WriteRune(rune(code))
+			p.pos += 1 + width
+			return nil
+		}
+	}
+	return p.errorf("invalid escape \\%c", c)
+}
+
+// isTOMLBareKey reports whether c may appear in a bare key
+func isTOMLBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-'
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
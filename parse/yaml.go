@@ -0,0 +1,632 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlParser reads the YAML used in frontmatter: block mappings and
+// sequences, flow [lists] and {maps}, quoted and plain scalars, and | and >
+// block scalars. Anchors, aliases and tags are read as plain text.
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+// errUnterminated reports a quoted string or flow collection that runs past
+// the end of its text
+var errUnterminated = errors.New("unterminated")
+
+// parseYAML parses a YAML mapping
+func parseYAML(src string) (*Map, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")}
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return NewMap(), nil
+	}
+	v, err := p.node(-1)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	m, ok := v.(*Map)
+	if !ok {
+		return nil, &FrontmatterError{Line: 1, Message: "frontmatter is not a list of \"key: value\" fields"}
+	}
+	return m, nil
+}
+
+// errorf returns an error at the current line
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return &FrontmatterError{Line: p.pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+// skipBlank moves past blank and comment lines
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) {
+		trimmed := strings.TrimSpace(p.lines[p.pos])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// node parses the mapping, sequence or scalar starting on the next line, if
+// it is indented more than parent
+func (p *yamlParser) node(parent int) (any, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	line := p.lines[p.pos]
+	ind := yamlIndent(line)
+	if ind <= parent {
+		return nil, nil
+	}
+	text := line[ind:]
+	if strings.HasPrefix(text, "\t") {
+		return nil, p.errorf("tabs can't be used for indentation")
+	}
+	if isYAMLSeqItem(text) {
+		return p.sequence(ind)
+	}
+	if _, _, ok := splitYAMLKey(text); ok {
+		return p.mapping(ind)
+	}
+	p.pos++
+	return p.value(text, parent)
+}
+
+// mapping parses "key: value" lines at indentation ind
+func (p *yamlParser) mapping(ind int) (*Map, error) {
+	m := NewMap()
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return m, nil
+		}
+		line := p.lines[p.pos]
+		switch lineInd := yamlIndent(line); {
+		case lineInd < ind:
+			return m, nil
+		case lineInd > ind:
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, rest, ok := splitYAMLKey(line[ind:])
+		if !ok {
+			return nil, p.errorf("expected \"key: value\"")
+		}
+		if _, dup := m.Values[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+
+		var v any
+		var err error
+		if yamlPlain(rest) == "" {
+			// A nested block, or a sequence at the key's own indentation
+			v, err = p.node(ind)
+			if v == nil && err == nil && p.seqAt(ind) {
+				v, err = p.sequence(ind)
+			}
+		} else {
+			v, err = p.value(rest, ind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Set(key, v)
+	}
+}
+
+// seqAt reports whether the next line is a sequence item at indentation ind
+func (p *yamlParser) seqAt(ind int) bool {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return false
+	}
+	line := p.lines[p.pos]
+	return yamlIndent(line) == ind && isYAMLSeqItem(line[ind:])
+}
+
+// sequence parses "- item" lines at indentation ind
+func (p *yamlParser) sequence(ind int) ([]any, error) {
+	items := []any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return items, nil
+		}
+		line := p.lines[p.pos]
+		lineInd := yamlIndent(line)
+		if lineInd > ind {
+			return nil, p.errorf("unexpected indentation")
+		}
+		if lineInd < ind || !isYAMLSeqItem(line[ind:]) {
+			return items, nil
+		}
+		text := line[ind:]
+		content := strings.TrimLeft(text[1:], " ")
+		offset := len(text) - len(content)
+
+		var v any
+		var err error
+		_, _, isKey := splitYAMLKey(content)
+		switch {
+		case yamlPlain(content) == "":
+			p.pos++
+			v, err = p.node(ind)
+		case isKey || isYAMLSeqItem(content):
+			// "- key: value" starts a mapping (or "- - x" a sequence)
+			// indented to the item's content
+			p.lines[p.pos] = strings.Repeat(" ", ind+offset) + content
+			v, err = p.node(ind)
+		default:
+			p.pos++
+			v, err = p.value(content, ind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+}
+
+// value parses the scalar or flow collection in text, which follows a key or
+// list marker. Quoted strings and flow collections may continue on the
+// following lines; plain scalars continue on lines indented past parent.
+func (p *yamlParser) value(text string, parent int) (any, error) {
+	text = strings.TrimLeft(text, " \t")
+	start := p.pos
+	switch {
+	case text == "":
+		return nil, nil
+	case text[0] == '|' || text[0] == '>':
+		return p.blockScalar(text, parent)
+	case text[0] == '"' || text[0] == '\'' || text[0] == '[' || text[0] == '{':
+		joined := text
+		for {
+			var v any
+			var n int
+			var err error
+			if text[0] == '"' || text[0] == '\'' {
+				v, n, err = scanYAMLQuoted(joined)
+			} else {
+				f := &yamlFlow{s: joined}
+				v, err = f.value()
+				n = f.pos
+			}
+			if errors.Is(err, errUnterminated) && p.pos < len(p.lines) {
+				joined += "\n" + p.lines[p.pos]
+				p.pos++
+				continue
+			}
+			if errors.Is(err, errUnterminated) {
+				err = fmt.Errorf("unterminated %s", yamlCollectionName(text[0]))
+			} else if rest := yamlPlain(joined[n:]); err == nil && rest != "" {
+				err = fmt.Errorf("unexpected %q after %s", rest, yamlCollectionName(text[0]))
+			}
+			if err != nil {
+				// Report the line the value starts on
+				return nil, &FrontmatterError{Line: start, Message: err.Error()}
+			}
+			return v, nil
+		}
+	}
+
+	s := yamlPlain(text)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		next := yamlPlain(line)
+		if next == "" || yamlIndent(line) <= parent {
+			break
+		}
+		if _, _, ok := splitYAMLKey(strings.TrimLeft(line, " ")); ok {
+			break
+		}
+		s += " " + next
+		p.pos++
+	}
+	return resolveYAMLPlain(s), nil
+}
+
+// blockScalar parses a | (literal) or > (folded) block scalar. Its lines are
+// those indented past parent.
+func (p *yamlParser) blockScalar(header string, parent int) (string, error) {
+	header = yamlPlain(header)
+	folded := header[0] == '>'
+	chomp := byte(0)
+	contentInd := -1
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			contentInd = max(parent, 0) + int(c-'0')
+		default:
+			return "", &FrontmatterError{Line: p.pos, Message: fmt.Sprintf("invalid block scalar header %q", header)}
+		}
+	}
+
+	var lines []string
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		ind := yamlIndent(line)
+		if contentInd < 0 {
+			if ind <= parent {
+				break
+			}
+			contentInd = ind
+		}
+		if ind < contentInd {
+			break
+		}
+		lines = append(lines, line[contentInd:])
+		p.pos++
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case !folded || line == "":
+				sb.WriteByte('\n')
+			case prev == "":
+				// The blank lines before it already broke the paragraph
+			case strings.HasPrefix(prev, " ") || strings.HasPrefix(line, " "):
+				// More-indented lines keep their breaks
+				sb.WriteByte('\n')
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+	}
+	text := sb.String()
+	switch chomp {
+	case '-':
+		return text, nil
+	case '+':
+		return text + "\n" + strings.Repeat("\n", trailing), nil
+	}
+	return text + "\n", nil
+}
+
+// yamlFlow parses a flow collection: [a, b] or {key: value}
+type yamlFlow struct {
+	s   string
+	pos int
+}
+
+// skip moves past whitespace, line breaks and comments
+func (f *yamlFlow) skip() {
+	for f.pos < len(f.s) {
+		switch c := f.s[f.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			f.pos++
+		case c == '#' && (f.pos == 0 || strings.ContainsRune(" \t\n", rune(f.s[f.pos-1]))):
+			for f.pos < len(f.s) && f.s[f.pos] != '\n' {
+				f.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (f *yamlFlow) value() (any, error) {
+	f.skip()
+	if f.pos >= len(f.s) {
+		return nil, errUnterminated
+	}
+	switch f.s[f.pos] {
+	case '[':
+		f.pos++
+		items := []any{}
+		for {
+			f.skip()
+			if f.pos >= len(f.s) {
+				return nil, errUnterminated
+			}
+			if f.s[f.pos] == ']' {
+				f.pos++
+				return items, nil
+			}
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		m := NewMap()
+		for {
+			f.skip()
+			if f.pos >= len(f.s) {
+				return nil, errUnterminated
+			}
+			if f.s[f.pos] == '}' {
+				f.pos++
+				return m, nil
+			}
+			key, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			f.skip()
+			var v any
+			if f.pos < len(f.s) && f.s[f.pos] == ':' {
+				f.pos++
+				f.skip()
+				if f.pos < len(f.s) && f.s[f.pos] != ',' && f.s[f.pos] != '}' {
+					if v, err = f.value(); err != nil {
+						return nil, err
+					}
+				}
+			}
+			m.Set(FormatValue(key), v)
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		v, n, err := scanYAMLQuoted(f.s[f.pos:])
+		f.pos += n
+		return v, err
+	case ']', '}', ',':
+		return nil, fmt.Errorf("unexpected %q", f.s[f.pos])
+	}
+
+	// Plain scalars end at a flow indicator or ": "
+	start := f.pos
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		if c == ',' || c == ']' || c == '}' || c == '\n' ||
+			c == ':' && (f.pos+1 == len(f.s) || strings.ContainsRune(" \t\n,]}", rune(f.s[f.pos+1]))) ||
+			c == '#' && f.pos > start && (f.s[f.pos-1] == ' ' || f.s[f.pos-1] == '\t') {
+			break
+		}
+		f.pos++
+	}
+	return resolveYAMLPlain(strings.TrimSpace(f.s[start:f.pos])), nil
+}
+
+// separator consumes the comma after a flow entry, or stops at the closing
+// bracket
+func (f *yamlFlow) separator(closing byte) error {
+	f.skip()
+	if f.pos >= len(f.s) {
+		return errUnterminated
+	}
+	switch f.s[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fmt.Errorf("expected ',' or '%c'", closing)
+}
+
+// scanYAMLQuoted reads the quoted string at the start of s, returning its
+// value and length. Line breaks inside fold to spaces, as in YAML.
+func scanYAMLQuoted(s string) (string, int, error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); {
+		c := s[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			sb.WriteByte('\'')
+			i += 2
+		case c == quote:
+			return sb.String(), i + 1, nil
+		case c == '\n':
+			// Fold the break: trailing spaces go, blank lines become newlines
+			out := strings.TrimRight(sb.String(), " \t")
+			sb.Reset()
+			sb.WriteString(out)
+			breaks := 0
+			for i < len(s) && (s[i] == '\n' || s[i] == ' ' || s[i] == '\t' || s[i] == '\r') {
+				if s[i] == '\n' {
+					breaks++
+				}
+				i++
+			}
+			if breaks == 1 {
+				sb.WriteByte(' ')
+			} else {
+				sb.WriteString(strings.Repeat("\n", breaks-1))
+			}
+		case c == '\\' && quote == '"':
+			if i+1 >= len(s) {
+				return "", len(s), errUnterminated
+			}
+			n, err := yamlEscape(&sb, s[i+1:])
+			if err != nil {
+				return "", i, err
+			}
+			i += 1 + n
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return "", len(s), errUnterminated
+}
+
+// yamlEscapes maps single-character escapes to the text they stand for
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v",
+	'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// yamlEscape writes the character for the escape sequence at the start of s
+// (after the backslash) and returns its length
+func yamlEscape(sb *strings.Builder, s string) (int, error) {
+	if r, ok := yamlEscapes[s[0]]; ok {
+		sb.WriteString(r)
+		return 1, nil
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+	switch {
+	case s[0] == '\n':
+		// An escaped line break joins the lines without a space
+		n := 1
+		for n < len(s) && (s[n] == ' ' || s[n] == '\t') {
+			n++
+		}
+		return n, nil
+	case width > 0 && len(s) > width:
+		code, err := strconv.ParseUint(s[1:1+width], 16, 32)
+		if err == nil && utf8.ValidRune(rune(code)) {
+			sb.WriteRune(rune(code))
+			return 1 + width, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid escape \\%c", s[0])
+}
+
+var (
+	reYAMLInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	reYAMLFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolveYAMLPlain types a plain scalar with the YAML 1.2 core schema: null,
+// booleans, integers and floats; anything else is a string
+func resolveYAMLPlain(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	switch {
+	case reYAMLInt.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case strings.HasPrefix(s, "0x"):
+		if n, err := strconv.ParseInt(s[2:], 16, 64); err == nil {
+			return n
+		}
+	case strings.HasPrefix(s, "0o"):
+		if n, err := strconv.ParseInt(s[2:], 8, 64); err == nil {
+			return n
+		}
+	}
+	if reYAMLFloat.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// splitYAMLKey splits "key: rest". Keys may be quoted; plain keys end at the
+// first ": ".
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" || isYAMLSeqItem(text) {
+		return "", "", false
+	}
+	switch text[0] {
+	case '"', '\'':
+		k, n, err := scanYAMLQuoted(text)
+		if err != nil {
+			return "", "", false
+		}
+		after := strings.TrimLeft(text[n:], " \t")
+		if !strings.HasPrefix(after, ":") || len(after) > 1 && after[1] != ' ' && after[1] != '\t' {
+			return "", "", false
+		}
+		return k, after[1:], true
+	case '[', '{', '#', '|', '>', '%', '@', '`':
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case ':':
+			if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t' {
+				key = strings.TrimSpace(text[:i])
+				return key, text[i+1:], key != ""
+			}
+		case '#':
+			if i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+				return "", "", false
+			}
+		}
+	}
+	return "", "", false
+}
+
+// yamlPlain trims text and drops a trailing " # comment"
+func yamlPlain(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+	for i := 1; i < len(text); i++ {
+		if text[i] == '#' && (text[i-1] == ' ' || text[i-1] == '\t') {
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return text
+}
+
+// yamlIndent counts a line's leading spaces
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isYAMLSeqItem reports whether text starts a "- item" line
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// yamlCollectionName names the construct opened by c, for errors
+func yamlCollectionName(c byte) string {
+	switch c {
+	case '[':
+		return "list"
+	case '{':
+		return "map"
+	}
+	return "string"
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/wildreason/reader/tui"
+)
+
+// maxPlaces is how many files the reading positions are kept for
+const maxPlaces = 200
+
+// streamHashSize is how much of the start of a streamed file its content
+// hash covers
+const streamHashSize = 64 << 10
+
+// place is where a file was left in the reader, and its bookmarks
+type place struct {
+	Hash  string               `json:"hash"` // Content hash, to find the file after it moves
+	Place tui.Place            `json:"place"`
+	Marks map[string]tui.Place `json:"marks,omitempty"`
+	Time  int64                `json:"time"` // When the file was last closed
+}
+
+// getPlacesFile returns path to ~/.aster/places.json
+func getPlacesFile() (string, error) {
+	recentFile, err := getRecentFile()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(recentFile), "places.json"), nil
+}
+
+// loadPlaces reads the places kept in file, by absolute path
+func loadPlaces(file string) map[string]place {
+	places := make(map[string]place)
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, &places)
+	}
+	return places
+}
+
+// savePlaces writes places to file, keeping the most recent maxPlaces
+func savePlaces(file string, places map[string]place) error {
+	if len(places) > maxPlaces {
+		paths := make([]string, 0, len(places))
+		for path := range places {
+			paths = append(paths, path)
+		}
+		sort.Slice(paths, func(i, j int) bool {
+			return places[paths[i]].Time > places[paths[j]].Time
+		})
+		for _, path := range paths[maxPlaces:] {
+			delete(places, path)
+		}
+	}
+	data, err := json.MarshalIndent(places, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// findPlace returns the place kept for a file, and the path it was kept
+// under: the file's own, or the latest of a file with the same content
+// that is gone, when the file was moved or renamed
+func findPlace(places map[string]place, absPath, hash string) (place, string, bool) {
+	if p, ok := places[absPath]; ok {
+		return p, absPath, true
+	}
+	found, foundPath := place{}, ""
+	for path, p := range places {
+		if p.Hash != hash || p.Time < found.Time {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			found, foundPath = p, path
+		}
+	}
+	return found, foundPath, foundPath != ""
+}
+
+// contentHash returns a short hash of a file's content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// resumeDocument opens doc where it was left last time, with its
+// bookmarks, and has the reader keep its place when it closes
+func resumeDocument(doc *tui.Document, content []byte) {
+	file, err := getPlacesFile()
+	if err != nil {
+		return
+	}
+	absPath, err := filepath.Abs(doc.Name)
+	if err != nil {
+		absPath = doc.Name
+	}
+	hash := contentHash(content)
+	p, oldPath, ok := findPlace(loadPlaces(file), absPath, hash)
+	if ok {
+		doc.Place, doc.Marks = p.Place, p.Marks
+	}
+	doc.Save = func(doc *tui.Document) {
+		// Read again, another reader may have saved since
+		places := loadPlaces(file)
+		if ok {
+			delete(places, oldPath)
+		}
+		places[absPath] = place{Hash: hash, Place: doc.Place, Marks: doc.Marks, Time: time.Now().Unix()}
+		savePlaces(file, places)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/wildreason/reader/tui"
+)
+
+func TestPlacesRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "places.json")
+	spec := place{
+		Hash:  contentHash([]byte("# Spec")),
+		Place: tui.Place{Heading: "Errors", Section: 3, Offset: 12},
+		Marks: map[string]tui.Place{"a": {Heading: "Intro", Section: 0}},
+		Time:  100,
+	}
+	if err := savePlaces(file, map[string]place{"/docs/spec.md": spec}); err != nil {
+		t.Fatal(err)
+	}
+	places := loadPlaces(file)
+	got, path, ok := findPlace(places, "/docs/spec.md", "other")
+	if !ok || path != "/docs/spec.md" || got.Place != spec.Place || got.Marks["a"] != spec.Marks["a"] {
+		t.Errorf("findPlace by path = %+v, %q, %v", got, path, ok)
+	}
+	if want := "  › Errors  (marks a)"; leftOff(got) != want {
+		t.Errorf("leftOff = %q, want %q", leftOff(got), want)
+	}
+}
+
+func TestFindPlaceMovedFile(t *testing.T) {
+	dir := t.TempDir()
+	hash := contentHash([]byte("# Spec"))
+	places := map[string]place{
+		filepath.Join(dir, "old.md"):   {Hash: hash, Place: tui.Place{Offset: 7}, Time: 2},
+		filepath.Join(dir, "older.md"): {Hash: hash, Place: tui.Place{Offset: 3}, Time: 1},
+	}
+	got, path, ok := findPlace(places, filepath.Join(dir, "new.md"), hash)
+	if !ok || path != filepath.Join(dir, "old.md") || got.Place.Offset != 7 {
+		t.Errorf("findPlace by content = %+v, %q, %v", got, path, ok)
+	}
+	if _, _, ok := findPlace(places, filepath.Join(dir, "new.md"), "other"); ok {
+		t.Errorf("expected no place for other content")
+	}
+}
+
+func TestSavePlacesKeepsRecent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "places.json")
+	places := make(map[string]place)
+	for i := 0; i < maxPlaces+5; i++ {
+		places[fmt.Sprintf("/f%d.md", i)] = place{Time: int64(i)}
+	}
+	if err := savePlaces(file, places); err != nil {
+		t.Fatal(err)
+	}
+	loaded := loadPlaces(file)
+	if len(loaded) != maxPlaces {
+		t.Fatalf("kept %d places, want %d", len(loaded), maxPlaces)
+	}
+	if _, ok := loaded["/f0.md"]; ok {
+		t.Errorf("expected the oldest place to be dropped")
+	}
+}
@@ -9,7 +9,8 @@ import (
 	"strings"
 )
 
-const maxRecent = 5
+// maxRecent is how many files the recent list keeps (recent in the config)
+var maxRecent = 5
 
 // getRecentFile returns path to ~/.aster/recent
 func getRecentFile() (string, error) {
@@ -24,7 +25,7 @@ func getRecentFile() (string, error) {
 	return filepath.Join(dir, "recent"), nil
 }
 
-// AddRecent adds a file to recent history (deduped, max 5)
+// AddRecent adds a file to recent history (deduped, max maxRecent)
 func AddRecent(filePath string) error {
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
@@ -89,18 +90,8 @@ func GetRecent(exts []string) ([]string, error) {
 		if line == "" {
 			continue
 		}
-		if len(exts) > 0 {
-			ext := strings.ToLower(filepath.Ext(line))
-			match := false
-			for _, e := range exts {
-				if ext == e {
-					match = true
-					break
-				}
-			}
-			if !match {
-				continue
-			}
+		if len(exts) > 0 && !hasExtension(line, exts) {
+			continue
 		}
 		lines = append(lines, line)
 	}
@@ -114,6 +105,11 @@ func ShowRecentPicker(exts []string) (string, error) {
 		return "", fmt.Errorf("no recent files")
 	}
 
+	var places map[string]place
+	if file, err := getPlacesFile(); err == nil {
+		places = loadPlaces(file)
+	}
+
 	fmt.Println("Recent files:")
 	for i, path := range recent {
 		display := path
@@ -124,7 +120,7 @@ func ShowRecentPicker(exts []string) (string, error) {
 				display = filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path))
 			}
 		}
-		fmt.Printf("  %d. %s\n", i+1, display)
+		fmt.Printf("  %d. %s%s\n", i+1, display, leftOff(places[path]))
 	}
 
 	fmt.Print("\n> ")
@@ -137,6 +133,23 @@ func ShowRecentPicker(exts []string) (string, error) {
 	return recent[choice-1], nil
 }
 
+// leftOff describes where a file was left in the reader, for the picker
+func leftOff(p place) string {
+	var s string
+	if p.Place.Heading != "" {
+		s = "  › " + p.Place.Heading
+	}
+	if len(p.Marks) > 0 {
+		letters := make([]string, 0, len(p.Marks))
+		for letter := range p.Marks {
+			letters = append(letters, letter)
+		}
+		sort.Strings(letters)
+		s += "  (marks " + strings.Join(letters, " ") + ")"
+	}
+	return s
+}
+
 // GetNewestFile returns the most recently modified file in cwd, optionally filtered by extensions
 func GetNewestFile(exts []string) (string, error) {
 	entries, err := os.ReadDir(".")
@@ -157,18 +170,8 @@ func GetNewestFile(exts []string) (string, error) {
 		if strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
-		if len(exts) > 0 {
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			match := false
-			for _, e := range exts {
-				if ext == e {
-					match = true
-					break
-				}
-			}
-			if !match {
-				continue
-			}
+		if len(exts) > 0 && !hasExtension(entry.Name(), exts) {
+			continue
 		}
 		info, err := entry.Info()
 		if err != nil {
@@ -187,3 +190,15 @@ func GetNewestFile(exts []string) (string, error) {
 
 	return files[0].path, nil
 }
+
+// hasExtension reports whether path ends in one of exts, which may have
+// several dots like ".jsonl.log"
+func hasExtension(path string, exts []string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, e := range exts {
+		if strings.HasSuffix(name, e) {
+			return true
+		}
+	}
+	return false
+}
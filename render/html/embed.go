@@ -0,0 +1,18 @@
+package html
+
+import _ "embed"
+
+//go:embed embed/highlight.min.js
+var highlightJS string
+
+//go:embed embed/github.min.css
+var highlightCSS string
+
+//go:embed embed/github-dark.min.css
+var highlightDarkCSS string
+
+//go:embed embed/mermaid.js
+var mermaidJS string
+
+//go:embed embed/math.js
+var mathJS string
@@ -0,0 +1,629 @@
+/*
+ * Offline TeX math renderer for aster's HTML output.
+ *
+ * Converts the TeX source of each .math element to MathML, which browsers
+ * typeset natively. It covers the commonly used LaTeX math commands and
+ * environments; an expression it can't read keeps its source, marked with
+ * the reason.
+ */
+(function () {
+'use strict';
+
+var GREEK = {
+  alpha: 'α', beta: 'β', gamma: 'γ', delta: 'δ', epsilon: 'ϵ', varepsilon: 'ε', zeta: 'ζ', eta: 'η',
+  theta: 'θ', vartheta: 'ϑ', iota: 'ι', kappa: 'κ', varkappa: 'ϰ', lambda: 'λ', mu: 'μ', nu: 'ν', xi: 'ξ',
+  omicron: 'ο', pi: 'π', varpi: 'ϖ', rho: 'ρ', varrho: 'ϱ', sigma: 'σ', varsigma: 'ς', tau: 'τ',
+  upsilon: 'υ', phi: 'ϕ', varphi: 'φ', chi: 'χ', psi: 'ψ', omega: 'ω', digamma: 'ϝ'
+};
+
+// Upper-case Greek and other letter-like symbols are set upright
+var UPRIGHT = {
+  Gamma: 'Γ', Delta: 'Δ', Theta: 'Θ', Lambda: 'Λ', Xi: 'Ξ', Pi: 'Π', Sigma: 'Σ', Upsilon: 'Υ',
+  Phi: 'Φ', Psi: 'Ψ', Omega: 'Ω', infty: '∞', emptyset: '∅', varnothing: '∅', nabla: '∇',
+  partial: '∂', ell: 'ℓ', hbar: 'ℏ', hslash: 'ℏ', aleph: 'ℵ', beth: 'ℶ', Re: 'ℜ', Im: 'ℑ', wp: '℘',
+  imath: 'ı', jmath: 'ȷ', top: '⊤', bot: '⊥', angle: '∠', triangle: '△', square: '□', Box: '□',
+  degree: '°', prime: '′', backslash: '\\', clubsuit: '♣', diamondsuit: '♢', heartsuit: '♡',
+  spadesuit: '♠', flat: '♭', natural: '♮', sharp: '♯', checkmark: '✓', dagger: '†', ddagger: '‡',
+  S: '§', P: '¶', copyright: '©', pounds: '£', mho: '℧', complement: '∁', Finv: 'Ⅎ', eth: 'ð'
+};
+
+var OPERATORS = {
+  pm: '±', mp: '∓', times: '×', div: '÷', cdot: '⋅', ast: '∗', star: '⋆', circ: '∘', bullet: '∙',
+  oplus: '⊕', ominus: '⊖', otimes: '⊗', oslash: '⊘', odot: '⊙', cup: '∪', cap: '∩', sqcup: '⊔',
+  sqcap: '⊓', uplus: '⊎', vee: '∨', lor: '∨', wedge: '∧', land: '∧', setminus: '∖', smallsetminus: '∖',
+  wr: '≀', amalg: '⨿', diamond: '⋄', bigtriangleup: '△', bigtriangledown: '▽', triangleleft: '◃',
+  triangleright: '▹', cdotp: '⋅', ltimes: '⋉', rtimes: '⋊', boxplus: '⊞', boxtimes: '⊠',
+  leq: '≤', le: '≤', geq: '≥', ge: '≥', neq: '≠', ne: '≠', equiv: '≡', approx: '≈', approxeq: '≊',
+  sim: '∼', simeq: '≃', cong: '≅', propto: '∝', ll: '≪', gg: '≫', lll: '⋘', ggg: '⋙',
+  subset: '⊂', supset: '⊃', subseteq: '⊆', supseteq: '⊇', subsetneq: '⊊', supsetneq: '⊋',
+  sqsubset: '⊏', sqsupset: '⊐', sqsubseteq: '⊑', sqsupseteq: '⊒', in: '∈', notin: '∉', ni: '∋',
+  owns: '∋', mid: '∣', nmid: '∤', parallel: '∥', nparallel: '∦', perp: '⊥', models: '⊨', vdash: '⊢',
+  dashv: '⊣', vDash: '⊨', prec: '≺', succ: '≻', preceq: '⪯', succeq: '⪰', doteq: '≐', asymp: '≍',
+  coloneqq: '≔', eqqcolon: '≕', leqslant: '⩽', geqslant: '⩾', lesssim: '≲', gtrsim: '≳',
+  triangleq: '≜', bowtie: '⋈', smile: '⌣', frown: '⌢', nless: '≮', ngtr: '≯', nleq: '≰', ngeq: '≱',
+  nsim: '≁', ncong: '≇', nsubseteq: '⊈', nsupseteq: '⊉', therefore: '∴', because: '∵',
+  to: '→', rightarrow: '→', leftarrow: '←', gets: '←', leftrightarrow: '↔', Rightarrow: '⇒',
+  Leftarrow: '⇐', Leftrightarrow: '⇔', implies: '⟹', impliedby: '⟸', iff: '⟺', mapsto: '↦',
+  longrightarrow: '⟶', longleftarrow: '⟵', longleftrightarrow: '⟷', Longrightarrow: '⟹',
+  Longleftarrow: '⟸', Longleftrightarrow: '⟺', longmapsto: '⟼', uparrow: '↑', downarrow: '↓',
+  updownarrow: '↕', Uparrow: '⇑', Downarrow: '⇓', Updownarrow: '⇕', hookrightarrow: '↪',
+  hookleftarrow: '↩', rightharpoonup: '⇀', rightharpoondown: '⇁', leftharpoonup: '↼',
+  leftharpoondown: '↽', rightleftharpoons: '⇌', leftrightharpoons: '⇋', nearrow: '↗', searrow: '↘',
+  swarrow: '↙', nwarrow: '↖', leadsto: '⇝', rightsquigarrow: '⇝', twoheadrightarrow: '↠',
+  circlearrowleft: '↺', circlearrowright: '↻', nrightarrow: '↛', nleftarrow: '↚',
+  ldots: '…', dots: '…', dotsc: '…', dotsb: '⋯', cdots: '⋯', vdots: '⋮', ddots: '⋱',
+  neg: '¬', lnot: '¬', forall: '∀', exists: '∃', nexists: '∄', colon: ':', vert: '|', Vert: '‖',
+  lvert: '|', rvert: '|', lVert: '‖', rVert: '‖', langle: '⟨', rangle: '⟩', lfloor: '⌊', rfloor: '⌋',
+  lceil: '⌈', rceil: '⌉', lbrace: '{', rbrace: '}', lbrack: '[', rbrack: ']', ulcorner: '⌜',
+  urcorner: '⌝', llcorner: '⌞', lrcorner: '⌟', surd: '√'
+};
+
+// Operators with limits above and below in display style
+var BIG_OPERATORS = {
+  sum: '∑', prod: '∏', coprod: '∐', bigcup: '⋃', bigcap: '⋂', bigoplus: '⨁', bigotimes: '⨂',
+  bigodot: '⨀', bigvee: '⋁', bigwedge: '⋀', bigsqcup: '⨆', biguplus: '⨄'
+};
+
+var INTEGRALS = { int: '∫', iint: '∬', iiint: '∭', oint: '∮', oiint: '∯', intop: '∫', smallint: '∫' };
+
+var FUNCTIONS = [
+  'arccos', 'arcsin', 'arctan', 'arg', 'cos', 'cosh', 'cot', 'coth', 'csc', 'deg', 'dim', 'exp',
+  'hom', 'ker', 'lg', 'ln', 'log', 'sec', 'sin', 'sinh', 'tan', 'tanh', 'sgn'
+];
+
+// Functions with limits under them in display style
+var LIMIT_FUNCTIONS = ['det', 'gcd', 'inf', 'lim', 'liminf', 'limsup', 'max', 'min', 'Pr', 'sup', 'argmax', 'argmin'];
+
+var ACCENTS = {
+  hat: ['^', false], widehat: ['^', true], check: ['ˇ', false], widecheck: ['ˇ', true],
+  tilde: ['~', false], widetilde: ['~', true], acute: ['´', false], grave: ['`', false],
+  dot: ['˙', false], ddot: ['¨', false], dddot: ['⃛', false], breve: ['˘', false],
+  bar: ['¯', false], vec: ['→', false], mathring: ['˚', false],
+  overline: ['‾', true], overrightarrow: ['→', true], overleftarrow: ['←', true],
+  overleftrightarrow: ['↔', true]
+};
+
+var FONTS = {
+  mathrm: 'normal', textup: 'normal', mathup: 'normal', rm: 'normal', mathnormal: '',
+  mathit: '', textit: '', mathbf: 'bold', textbf: 'bold', boldsymbol: 'bold', bm: 'bold', bf: 'bold',
+  mathbb: 'double-struck', Bbb: 'double-struck', mathcal: 'script', mathscr: 'script',
+  mathfrak: 'fraktur', mathsf: 'sans-serif', textsf: 'sans-serif', mathtt: 'monospace', texttt: 'monospace'
+};
+
+var SPACES = {
+  ',': '0.1667em', thinspace: '0.1667em', ':': '0.2222em', '>': '0.2222em', medspace: '0.2222em',
+  ';': '0.2778em', thickspace: '0.2778em', ' ': '0.25em', enspace: '0.5em', quad: '1em', qquad: '2em'
+};
+
+var BIG_DELIMITERS = {
+  big: '1.2em', bigl: '1.2em', bigr: '1.2em', bigm: '1.2em',
+  Big: '1.8em', Bigl: '1.8em', Bigr: '1.8em', Bigm: '1.8em',
+  bigg: '2.4em', biggl: '2.4em', biggr: '2.4em', biggm: '2.4em',
+  Bigg: '3em', Biggl: '3em', Biggr: '3em', Biggm: '3em'
+};
+
+var NEGATIONS = {
+  '=': '≠', '<': '≮', '>': '≯', in: '∉', ni: '∌', subset: '⊄', supset: '⊅', subseteq: '⊈',
+  supseteq: '⊉', leq: '≰', geq: '≱', le: '≰', ge: '≱', equiv: '≢', sim: '≁', approx: '≉',
+  cong: '≇', mid: '∤', parallel: '∦', exists: '∄'
+};
+
+var MATRICES = {
+  matrix: ['', ''], smallmatrix: ['', ''], pmatrix: ['(', ')'], bmatrix: ['[', ']'],
+  Bmatrix: ['{', '}'], vmatrix: ['|', '|'], Vmatrix: ['‖', '‖']
+};
+
+// Unicode math alphanumerics: the first capital, small letter and digit of
+// each style, and the letters that live elsewhere in Unicode
+var ALPHABETS = {
+  'bold': [0x1D400, 0x1D41A, 0x1D7CE, {}],
+  'double-struck': [0x1D538, 0x1D552, 0x1D7D8, { C: 'ℂ', H: 'ℍ', N: 'ℕ', P: 'ℙ', Q: 'ℚ', R: 'ℝ', Z: 'ℤ' }],
+  'script': [0x1D49C, 0x1D4B6, 0, { B: 'ℬ', E: 'ℰ', F: 'ℱ', H: 'ℋ', I: 'ℐ', L: 'ℒ', M: 'ℳ', R: 'ℛ', e: 'ℯ', g: 'ℊ', o: 'ℴ' }],
+  'fraktur': [0x1D504, 0x1D51E, 0, { C: 'ℭ', H: 'ℌ', I: 'ℑ', R: 'ℜ', Z: 'ℨ' }],
+  'sans-serif': [0x1D5A0, 0x1D5BA, 0x1D7E2, {}],
+  'monospace': [0x1D670, 0x1D68A, 0x1D7F6, {}]
+};
+
+function esc(s) {
+  return String(s).replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+function row(nodes) {
+  return nodes.length === 1 ? nodes[0] : '<mrow>' + nodes.join('') + '</mrow>';
+}
+
+function mo(s, attrs) {
+  return '<mo' + (attrs || '') + '>' + esc(s) + '</mo>';
+}
+
+function space(width) {
+  return '<mspace width="' + width + '"></mspace>';
+}
+
+// styled maps a letter or digit to its Unicode math alphanumeric in font
+function styled(c, font) {
+  var a = ALPHABETS[font];
+  if (!a) return c;
+  if (a[3][c]) return a[3][c];
+  var code = c.charCodeAt(0);
+  if (c >= 'A' && c <= 'Z') return String.fromCodePoint(a[0] + code - 65);
+  if (c >= 'a' && c <= 'z') return String.fromCodePoint(a[1] + code - 97);
+  if (c >= '0' && c <= '9' && a[2]) return String.fromCodePoint(a[2] + code - 48);
+  return c;
+}
+
+// Parser converts TeX math to MathML by recursive descent
+function Parser(src, display) {
+  this.src = src;
+  this.pos = 0;
+  this.display = display;
+  this.font = '';
+}
+
+Parser.prototype.error = function (msg) {
+  throw new Error(msg);
+};
+
+Parser.prototype.skipSpace = function () {
+  while (this.pos < this.src.length && /\s/.test(this.src[this.pos])) this.pos++;
+};
+
+Parser.prototype.startsWith = function (s) {
+  return this.src.slice(this.pos, this.pos + s.length) === s;
+};
+
+// peekCommand returns the name of the command at the current position
+Parser.prototype.peekCommand = function () {
+  var m = /^\\([a-zA-Z]+|.)/.exec(this.src.slice(this.pos));
+  return m ? m[1] : '';
+};
+
+// parse converts the whole source; & and \\ outside an environment lay the
+// expression out as aligned rows
+Parser.prototype.parse = function () {
+  var rows = this.table();
+  if (this.pos < this.src.length) this.error('unexpected "' + this.src.slice(this.pos, this.pos + 10) + '"');
+  if (rows.length === 1 && rows[0].length === 1) return row(rows[0][0].length ? rows[0][0] : ['<mrow></mrow>']);
+  return this.mtable(rows, 'aligned');
+};
+
+// expression parses atoms up to a closing brace, &, \\, \right or \end
+Parser.prototype.expression = function () {
+  var nodes = [];
+  for (;;) {
+    this.skipSpace();
+    if (this.pos >= this.src.length) break;
+    var c = this.src[this.pos];
+    if (c === '}' || c === '&' || this.startsWith('\\\\')) break;
+    var cmd = c === '\\' ? this.peekCommand() : '';
+    if (cmd === 'right' || cmd === 'end') break;
+    var atom;
+    if (c === '^' || c === '_' || c === "'") atom = { ml: '<mrow></mrow>' };
+    else atom = this.atom(false);
+    if (atom === null) continue;
+    if (atom.rest) {
+      // Style switches such as \color apply to the rest of the group
+      nodes.push(atom.rest(row(this.expression())));
+      break;
+    }
+    atom = this.scripts(atom);
+    nodes.push(atom.ml);
+    if (atom.fn) nodes.push('<mo>⁡</mo>');
+  }
+  return nodes;
+};
+
+// table parses cells separated by & and rows separated by \\
+Parser.prototype.table = function () {
+  var rows = [[]];
+  for (;;) {
+    rows[rows.length - 1].push(this.expression());
+    if (this.src[this.pos] === '&') {
+      this.pos++;
+      continue;
+    }
+    if (this.startsWith('\\\\')) {
+      this.pos += 2;
+      this.skipSpace();
+      if (this.src[this.pos] === '[') this.pos = this.src.indexOf(']', this.pos) + 1 || this.src.length;
+      rows.push([]);
+      continue;
+    }
+    break;
+  }
+  var last = rows[rows.length - 1];
+  if (rows.length > 1 && last.length === 1 && !last[0].length) rows.pop();
+  return rows;
+};
+
+// mtable lays out rows; aligned columns alternate right and left
+Parser.prototype.mtable = function (rows, kind, columns) {
+  var out = '<mtable' + (kind === 'aligned' || kind === 'gather' ? ' displaystyle="true"' : '') + '>';
+  rows.forEach(function (cells) {
+    out += '<mtr>';
+    cells.forEach(function (cell, i) {
+      var align = 'center', pad = '';
+      if (kind === 'aligned') {
+        align = i % 2 ? 'left' : 'right';
+        pad = i % 2 ? 'padding-left:0;' : 'padding-right:0;';
+      } else if (kind === 'cases') {
+        align = 'left';
+      } else if (columns && columns[i]) {
+        align = columns[i];
+      }
+      out += '<mtd columnalign="' + align + '" style="text-align:' + align + ';' + pad + '">' + row(cell.length ? cell : ['<mrow></mrow>']) + '</mtd>';
+    });
+    out += '</mtr>';
+  });
+  return out + '</mtable>';
+};
+
+// argument parses a single-token or braced command argument
+Parser.prototype.argument = function () {
+  this.skipSpace();
+  if (this.pos >= this.src.length) this.error('missing argument');
+  var atom = this.atom(true);
+  return atom === null ? '<mrow></mrow>' : atom.ml;
+};
+
+// rawGroup returns the unparsed text of a braced group
+Parser.prototype.rawGroup = function () {
+  this.skipSpace();
+  if (this.src[this.pos] !== '{') {
+    var ch = this.src[this.pos] || '';
+    this.pos++;
+    return ch;
+  }
+  var depth = 0, start = this.pos + 1;
+  for (; this.pos < this.src.length; this.pos++) {
+    var c = this.src[this.pos];
+    if (c === '\\') { this.pos++; continue; }
+    if (c === '{') depth++;
+    else if (c === '}' && --depth === 0) {
+      this.pos++;
+      return this.src.slice(start, this.pos - 1);
+    }
+  }
+  this.error('missing }');
+};
+
+// optional returns the text of an optional [argument], or null
+Parser.prototype.optional = function () {
+  this.skipSpace();
+  if (this.src[this.pos] !== '[') return null;
+  var end = this.src.indexOf(']', this.pos);
+  if (end < 0) this.error('missing ]');
+  var s = this.src.slice(this.pos + 1, end);
+  this.pos = end + 1;
+  return s;
+};
+
+// sub converts a fragment of TeX in the current mode
+Parser.prototype.sub = function (tex) {
+  var p = new Parser(tex, this.display);
+  p.font = this.font;
+  return p.parse();
+};
+
+Parser.prototype.group = function () {
+  this.pos++;
+  var nodes = this.expression();
+  if (this.src[this.pos] !== '}') this.error('missing }');
+  this.pos++;
+  return { ml: row(nodes.length ? nodes : ['<mrow></mrow>']) };
+};
+
+// atom parses one token: a group, command, number, letter or operator.
+// Arguments of commands and scripts take a single digit.
+Parser.prototype.atom = function (single) {
+  var c = this.src[this.pos];
+  if (c === '{') return this.group();
+  if (c === '\\') return this.command();
+  if (/[0-9]/.test(c) || (c === '.' && /[0-9]/.test(this.src[this.pos + 1] || ''))) {
+    var m = single ? c : /^[0-9]*\.?[0-9]+|^[0-9]+/.exec(this.src.slice(this.pos))[0];
+    this.pos += m.length;
+    var digits = this.font && this.font !== 'normal' ? m.replace(/[0-9]/g, function (d) { return styled(d, this.font); }.bind(this)) : m;
+    return { ml: '<mn>' + esc(digits) + '</mn>' };
+  }
+  var cp = this.src.codePointAt(this.pos);
+  var ch = String.fromCodePoint(cp);
+  this.pos += ch.length;
+  if (/[a-zA-Z]/.test(ch)) {
+    if (this.font === 'normal') return { ml: '<mi mathvariant="normal">' + ch + '</mi>' };
+    if (this.font) return { ml: '<mi mathvariant="normal">' + styled(ch, this.font) + '</mi>' };
+    return { ml: '<mi>' + ch + '</mi>' };
+  }
+  switch (ch) {
+    case '~': return { ml: space('0.3333em') };
+    case '-': return { ml: mo('−') };
+    case '*': return { ml: mo('∗') };
+    case '}': this.error('unexpected }');
+    // falls through
+    case '^': case '_': this.error('unexpected ' + ch);
+  }
+  if (/[+=<>,;:!?()[\]|/@]/.test(ch) || /[^\p{L}\p{N}]/u.test(ch)) {
+    if (ch === '(' || ch === ')' || ch === '[' || ch === ']' || ch === '|') return { ml: mo(ch, ' stretchy="false"') };
+    return { ml: mo(ch) };
+  }
+  return { ml: '<mi>' + esc(ch) + '</mi>' };
+};
+
+// scripts attaches sub- and superscripts and primes to a base
+Parser.prototype.scripts = function (base) {
+  var sub = null, sup = null, primes = '';
+  for (;;) {
+    this.skipSpace();
+    var c = this.src[this.pos];
+    if (c === "'") {
+      this.pos++;
+      primes += '′';
+    } else if (c === '^' && sup === null) {
+      this.pos++;
+      sup = this.argument();
+    } else if (c === '_' && sub === null) {
+      this.pos++;
+      sub = this.argument();
+    } else if (this.startsWith('\\limits')) {
+      this.pos += 7;
+      base.limits = 'always';
+    } else if (this.startsWith('\\nolimits')) {
+      this.pos += 9;
+      base.limits = '';
+    } else {
+      break;
+    }
+  }
+  if (primes) sup = sup === null ? mo(primes) : '<mrow>' + mo(primes) + sup + '</mrow>';
+  if (sub === null && sup === null) return base;
+  var under = base.limits === 'always' || (base.limits === 'display' && this.display);
+  var name = under ? (sub && sup ? 'munderover' : sub ? 'munder' : 'mover') : (sub && sup ? 'msubsup' : sub ? 'msub' : 'msup');
+  return { ml: '<' + name + '>' + base.ml + (sub || '') + (sup || '') + '</' + name + '>', fn: base.fn };
+};
+
+// delimiter reads the delimiter after \left, \right, \middle or \big
+Parser.prototype.delimiter = function () {
+  this.skipSpace();
+  var c = this.src[this.pos];
+  if (c === undefined) this.error('missing delimiter');
+  if (c === '.') {
+    this.pos++;
+    return '';
+  }
+  if (c === '\\') {
+    var name = this.peekCommand();
+    this.pos += name.length + 1;
+    if (name === '{' || name === '}') return name;
+    if (name === '|') return '‖';
+    if (OPERATORS[name]) return OPERATORS[name];
+    this.error('unknown delimiter \\' + name);
+  }
+  this.pos++;
+  return c === '<' ? '⟨' : c === '>' ? '⟩' : c;
+};
+
+Parser.prototype.environment = function (name) {
+  var base = name.replace(/\*$/, '');
+  var columns = null;
+  if (base === 'array' || base === 'alignat') {
+    columns = this.rawGroup().replace(/[^lcr]/g, '').split('').map(function (c) {
+      return { l: 'left', c: 'center', r: 'right' }[c];
+    });
+    if (base === 'alignat') columns = null;
+  }
+  var rows = this.table();
+  if (!this.startsWith('\\end')) this.error('missing \\end{' + name + '}');
+  this.pos += 4;
+  var end = this.rawGroup();
+  if (end !== name) this.error('\\begin{' + name + '} ended by \\end{' + end + '}');
+
+  if (MATRICES[base]) {
+    var d = MATRICES[base];
+    var table = this.mtable(rows, 'matrix');
+    if (!d[0]) return table;
+    return '<mrow>' + mo(d[0], ' fence="true" stretchy="true"') + table + mo(d[1], ' fence="true" stretchy="true"') + '</mrow>';
+  }
+  switch (base) {
+    case 'cases':
+      return '<mrow>' + mo('{', ' fence="true" stretchy="true"') + this.mtable(rows, 'cases') + '</mrow>';
+    case 'rcases':
+      return '<mrow>' + this.mtable(rows, 'cases') + mo('}', ' fence="true" stretchy="true"') + '</mrow>';
+    case 'aligned': case 'align': case 'split': case 'alignat': case 'alignedat': case 'eqnarray': case 'flalign':
+      return this.mtable(rows, 'aligned');
+    case 'gather': case 'gathered': case 'multline': case 'equation': case 'subarray':
+      return rows.length === 1 && rows[0].length === 1 ? row(rows[0][0]) : this.mtable(rows, 'gather');
+    case 'array':
+      return this.mtable(rows, 'array', columns);
+  }
+  this.error('unknown environment ' + name);
+};
+
+// command parses a control sequence and its arguments
+Parser.prototype.command = function () {
+  var name = this.peekCommand();
+  if (!name) this.error('stray \\');
+  this.pos += name.length + 1;
+  if (name === 'operatorname' && this.src[this.pos] === '*') {
+    this.pos++;
+    name = 'operatorname*';
+  }
+
+  if (GREEK[name]) return { ml: '<mi>' + GREEK[name] + '</mi>' };
+  if (UPRIGHT[name]) return { ml: '<mi mathvariant="normal">' + esc(UPRIGHT[name]) + '</mi>' };
+  if (BIG_OPERATORS[name]) return { ml: mo(BIG_OPERATORS[name], ' largeop="true" movablelimits="false"'), limits: 'display' };
+  if (INTEGRALS[name]) return { ml: mo(INTEGRALS[name], ' largeop="true"'), limits: '' };
+  if (OPERATORS[name]) return { ml: mo(OPERATORS[name]) };
+  if (FUNCTIONS.indexOf(name) >= 0) return { ml: '<mi>' + name + '</mi>', fn: true };
+  if (LIMIT_FUNCTIONS.indexOf(name) >= 0) {
+    var label = name === 'argmax' ? 'arg max' : name === 'argmin' ? 'arg min' : name === 'liminf' ? 'lim inf' : name === 'limsup' ? 'lim sup' : name;
+    return { ml: '<mi>' + label + '</mi>', fn: true, limits: 'display' };
+  }
+  if (SPACES[name]) return { ml: space(SPACES[name]) };
+  if (ACCENTS[name]) {
+    var accent = ACCENTS[name];
+    return { ml: '<mover accent="true">' + this.argument() + mo(accent[0], accent[1] ? ' stretchy="true"' : ' stretchy="false"') + '</mover>' };
+  }
+  if (BIG_DELIMITERS[name]) {
+    var size = BIG_DELIMITERS[name];
+    return { ml: mo(this.delimiter(), ' minsize="' + size + '" maxsize="' + size + '" stretchy="true"') };
+  }
+  if (FONTS[name] !== undefined) {
+    var saved = this.font;
+    this.font = FONTS[name];
+    var arg = name.slice(0, 4) === 'text' && FONTS[name] !== 'bold' ? '<mtext>' + esc(this.rawGroup()) + '</mtext>' : this.argument();
+    this.font = saved;
+    return { ml: arg };
+  }
+
+  switch (name) {
+    case '{': case '}': return { ml: mo(name, ' stretchy="false"') };
+    case '|': return { ml: mo('‖', ' stretchy="false"') };
+    case '%': case '&': case '#': case '$': case '_': return { ml: '<mi mathvariant="normal">' + esc(name) + '</mi>' };
+    case '!': case 'negthinspace': return null;
+    case 'frac': case 'dfrac': case 'tfrac': case 'cfrac':
+      var fr = '<mfrac>' + this.argument() + this.argument() + '</mfrac>';
+      if (name === 'dfrac' || name === 'cfrac') fr = '<mstyle displaystyle="true">' + fr + '</mstyle>';
+      if (name === 'tfrac') fr = '<mstyle displaystyle="false">' + fr + '</mstyle>';
+      return { ml: fr };
+    case 'binom': case 'dbinom': case 'tbinom':
+      return { ml: '<mrow>' + mo('(') + '<mfrac linethickness="0">' + this.argument() + this.argument() + '</mfrac>' + mo(')') + '</mrow>' };
+    case 'sqrt':
+      var index = this.optional();
+      var radicand = this.argument();
+      return { ml: index === null ? '<msqrt>' + radicand + '</msqrt>' : '<mroot>' + radicand + this.sub(index) + '</mroot>' };
+    case 'left':
+      var open = this.delimiter();
+      var inner = this.expression();
+      if (this.peekCommand() !== 'right') this.error('missing \\right');
+      this.pos += 6;
+      var close = this.delimiter();
+      return {
+        ml: '<mrow>' + (open ? mo(open, ' fence="true" stretchy="true"') : '') + row(inner.length ? inner : ['<mrow></mrow>']) +
+          (close ? mo(close, ' fence="true" stretchy="true"') : '') + '</mrow>'
+      };
+    case 'middle':
+      return { ml: mo(this.delimiter(), ' stretchy="true"') };
+    case 'begin':
+      return { ml: this.environment(this.rawGroup()) };
+    case 'text': case 'textrm': case 'textnormal': case 'mbox': case 'hbox':
+      var t = this.rawGroup().replace(/\\([$%&#_{} ])/g, '$1').replace(/^ | $/g, ' ');
+      return { ml: '<mtext>' + esc(t) + '</mtext>' };
+    case 'operatorname': case 'operatorname*':
+      var opname = this.rawGroup().replace(/\\,/g, ' ');
+      return { ml: opname.length === 1 ? '<mi mathvariant="normal">' + esc(opname) + '</mi>' : '<mi>' + esc(opname) + '</mi>', fn: true, limits: name === 'operatorname*' ? 'display' : '' };
+    case 'underline':
+      return { ml: '<munder accentunder="true">' + this.argument() + mo('_', ' stretchy="true"') + '</munder>' };
+    case 'overbrace':
+      return { ml: '<mover accent="true">' + this.argument() + mo('⏞', ' stretchy="true"') + '</mover>', limits: 'always' };
+    case 'underbrace':
+      return { ml: '<munder accentunder="true">' + this.argument() + mo('⏟', ' stretchy="true"') + '</munder>', limits: 'always' };
+    case 'overset': case 'stackrel':
+      var over = this.argument();
+      return { ml: '<mover>' + this.argument() + over + '</mover>' };
+    case 'underset':
+      var under = this.argument();
+      return { ml: '<munder>' + this.argument() + under + '</munder>' };
+    case 'xrightarrow': case 'xleftarrow':
+      var below = this.optional();
+      var above = this.argument();
+      var arrow = mo(name === 'xrightarrow' ? '→' : '←', ' stretchy="true" minsize="2em"');
+      return { ml: below === null ? '<mover>' + arrow + above + '</mover>' : '<munderover>' + arrow + this.sub(below) + above + '</munderover>' };
+    case 'not':
+      this.skipSpace();
+      var next = this.src[this.pos] === '\\' ? this.peekCommand() : this.src[this.pos];
+      if (NEGATIONS[next]) {
+        this.pos += next.length + (this.src[this.pos] === '\\' ? 1 : 0);
+        return { ml: mo(NEGATIONS[next]) };
+      }
+      var negated = this.atom(true);
+      return { ml: negated.ml.replace(/(<\/m[io]>)$/, '̸$1') };
+    case 'pmod':
+      return { ml: '<mrow>' + space('1em') + mo('(') + '<mi>mod</mi>' + space('0.3333em') + this.argument() + mo(')') + '</mrow>' };
+    case 'bmod':
+      return { ml: mo('mod', ' lspace="0.2222em" rspace="0.2222em"') };
+    case 'mod':
+      return { ml: '<mrow>' + space('1em') + '<mi>mod</mi>' + space('0.3333em') + '</mrow>' };
+    case 'phantom':
+      return { ml: '<mphantom>' + this.argument() + '</mphantom>' };
+    case 'boxed': case 'fbox':
+      return { ml: '<mrow style="border:1px solid;padding:0.2em">' + (name === 'fbox' ? '<mtext>' + esc(this.rawGroup()) + '</mtext>' : this.argument()) + '</mrow>' };
+    case 'cancel': case 'bcancel': case 'xcancel': case 'sout':
+      return { ml: '<mrow style="text-decoration:line-through">' + this.argument() + '</mrow>' };
+    case 'substack':
+      this.skipSpace();
+      if (this.src[this.pos] !== '{') this.error('missing {');
+      this.pos++;
+      var rows = this.table();
+      if (this.src[this.pos] !== '}') this.error('missing }');
+      this.pos++;
+      return { ml: '<mstyle scriptlevel="1">' + this.mtable(rows, 'matrix') + '</mstyle>' };
+    case 'color':
+      var color = this.colorName();
+      return { rest: function (ml) { return '<mstyle mathcolor="' + color + '">' + ml + '</mstyle>'; } };
+    case 'textcolor':
+      var tc = this.colorName();
+      return { ml: '<mstyle mathcolor="' + tc + '">' + this.argument() + '</mstyle>' };
+    case 'displaystyle': case 'textstyle':
+      var ds = name === 'displaystyle';
+      return { rest: function (ml) { return '<mstyle displaystyle="' + ds + '" scriptlevel="0">' + ml + '</mstyle>'; } };
+    case 'scriptstyle':
+      return { rest: function (ml) { return '<mstyle scriptlevel="1">' + ml + '</mstyle>'; } };
+    case 'hspace': case 'kern': case 'mkern': case 'mskip': case 'hskip':
+      var dim = /^\s*\{?\s*(-?[\d.]+)\s*(em|ex|pt|px|mu)\s*\}?/.exec(this.src.slice(this.pos));
+      if (!dim) this.error('bad space in \\' + name);
+      this.pos += dim[0].length;
+      var value = parseFloat(dim[1]), unit = dim[2];
+      if (unit === 'mu') { value /= 18; unit = 'em'; }
+      return value > 0 ? { ml: space(value + unit) } : null;
+    case 'tag': case 'label': case 'ref': case 'eqref':
+      var tagText = this.rawGroup();
+      return name === 'tag' || name === 'label' ? null : { ml: '<mtext>(' + esc(tagText) + ')</mtext>' };
+    case 'nonumber': case 'notag': case 'limits': case 'nolimits': case 'displaylimits': case 'mathstrut':
+    case 'strut': case 'relax': case 'allowbreak': case 'nobreak': case 'middlebreak':
+      return null;
+    case 'mathop': case 'mathrel': case 'mathbin': case 'mathord': case 'mathopen': case 'mathclose': case 'mathpunct':
+      return { ml: this.argument(), limits: name === 'mathop' ? 'display' : '' };
+    case 'dots': case 'dotsm': case 'dotsi': case 'dotso':
+      return { ml: mo('…') };
+  }
+  this.error('unknown command \\' + name);
+};
+
+Parser.prototype.colorName = function () {
+  var c = this.rawGroup().trim();
+  if (!/^#?[A-Za-z0-9]+$/.test(c)) this.error('bad color "' + c + '"');
+  return c;
+};
+
+// toMathML converts TeX to a MathML element, keeping the source as an
+// annotation
+function toMathML(tex, display) {
+  var body = new Parser(tex, display).parse();
+  return '<math xmlns="http://www.w3.org/1998/Math/MathML"' + (display ? ' display="block"' : '') + '>' +
+    '<semantics>' + (body.indexOf('<mrow>') === 0 ? body : '<mrow>' + body + '</mrow>') +
+    '<annotation encoding="application/x-tex">' + esc(tex) + '</annotation></semantics></math>';
+}
+
+// renderAll typesets each .math element under root
+function renderAll(root) {
+  (root || document).querySelectorAll('.math').forEach(function (el) {
+    if (el.getAttribute('data-rendered')) return;
+    el.setAttribute('data-rendered', 'true');
+    var tex = el.textContent;
+    try {
+      el.innerHTML = toMathML(tex, el.classList.contains('math-display'));
+    } catch (e) {
+      el.classList.add('math-error');
+      el.title = 'Math not rendered: ' + e.message;
+    }
+  });
+}
+
+var api = { toMathML: toMathML, renderAll: renderAll };
+if (typeof window !== 'undefined') window.asterMath = api;
+else if (typeof globalThis !== 'undefined') globalThis.asterMath = api;
+
+if (typeof document !== 'undefined' && document.querySelectorAll) {
+  if (document.readyState === 'loading') document.addEventListener('DOMContentLoaded', function () { renderAll(); });
+  else renderAll();
+}
+})();
@@ -0,0 +1,2007 @@
+/*
+ * Offline renderer for Mermaid diagrams in aster's HTML output.
+ *
+ * Draws flowcharts, sequence, state, gantt and class diagrams as inline SVG
+ * from the source in each <pre class="mermaid"> element. It covers the
+ * commonly used part of the Mermaid syntax; a diagram it can't read keeps
+ * its source on the page with a note saying why.
+ */
+(function () {
+'use strict';
+
+var FONT_FAMILY = "-apple-system, BlinkMacSystemFont, 'Helvetica Neue', Helvetica, Arial, sans-serif";
+var FONT_SIZE = 14;
+var LINE_HEIGHT = 1.35;
+var C = {
+  text: '#1d1d1f',
+  muted: '#6e6e73',
+  line: '#86868b',
+  fill: '#f5f5f7',
+  stroke: '#aeaeb2',
+  accent: '#0071e3',
+  cluster: '#fbfbfd',
+  clusterStroke: '#d2d2d7',
+  note: '#fff8c5',
+  noteStroke: '#d4a72c',
+  background: '#ffffff',
+  task: '#cfe2f7',
+  taskActive: '#9ec5f0',
+  taskDone: '#e5e5ea',
+  crit: '#d1242f',
+  critFill: '#f8d0d0'
+};
+
+// themeColors takes the palette from the page's --mermaid-* CSS variables
+// (--mermaid-note-stroke for noteStroke), keeping defaults for any unset
+function themeColors() {
+  if (typeof getComputedStyle === 'undefined' || !document.documentElement) return;
+  var style = getComputedStyle(document.documentElement);
+  for (var k in C) {
+    var name = '--mermaid-' + k.replace(/[A-Z]/g, function (c) { return '-' + c.toLowerCase(); });
+    var v = style.getPropertyValue(name).trim();
+    if (v) C[k] = v;
+  }
+}
+
+var diagramCount = 0;
+
+/* --- Text --- */
+
+var measureCtx;
+
+// textWidth measures s in the diagram font, estimating when no canvas exists
+function textWidth(s, size, bold) {
+  size = size || FONT_SIZE;
+  if (measureCtx === undefined) {
+    measureCtx = null;
+    if (typeof document !== 'undefined' && document.createElement) {
+      var canvas = document.createElement('canvas');
+      measureCtx = (canvas.getContext && canvas.getContext('2d')) || null;
+    }
+  }
+  if (measureCtx) {
+    measureCtx.font = (bold ? 'bold ' : '') + size + 'px ' + FONT_FAMILY;
+    return measureCtx.measureText(s).width;
+  }
+  return s.length * size * (bold ? 0.6 : 0.55);
+}
+
+// textSize returns the width and height of a block of lines
+function textSize(lines, size, bold) {
+  size = size || FONT_SIZE;
+  var w = 0;
+  lines.forEach(function (l) { w = Math.max(w, textWidth(l, size, bold)); });
+  return { w: w, h: lines.length * size * LINE_HEIGHT };
+}
+
+// decodeEntities turns Mermaid's #name; and #123; escapes into characters
+function decodeEntities(s) {
+  var named = { quot: '"', amp: '&', lt: '<', gt: '>', nbsp: '\u00a0', apos: "'", num: '#', semi: ';' };
+  return s.replace(/#(\w+);/g, function (m, name) {
+    if (/^\d+$/.test(name)) return String.fromCharCode(parseInt(name, 10));
+    return named[name] !== undefined ? named[name] : m;
+  });
+}
+
+// unquote strips one pair of surrounding double quotes and markdown backticks
+function unquote(s) {
+  s = s.trim();
+  if (s.length >= 2 && s[0] === '"' && s[s.length - 1] === '"') s = s.slice(1, -1);
+  if (s.length >= 2 && s[0] === '`' && s[s.length - 1] === '`') s = s.slice(1, -1);
+  return s;
+}
+
+// labelLines splits label text on <br> tags and escaped newlines
+function labelLines(s) {
+  return decodeEntities(unquote(String(s))).split(/<br\s*\/?>|\\n/i).map(function (l) {
+    return l.trim().replace(/\*\*(.+?)\*\*/g, '$1');
+  });
+}
+
+/* --- SVG --- */
+
+function esc(s) {
+  return String(s).replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
+
+function num(n) {
+  return Math.round(n * 100) / 100;
+}
+
+// tag writes an SVG element; attributes that are undefined or null are left out
+function tag(name, attrs, body) {
+  var out = '<' + name;
+  for (var k in attrs) {
+    if (attrs[k] !== undefined && attrs[k] !== null && attrs[k] !== '') {
+      out += ' ' + k + '="' + esc(typeof attrs[k] === 'number' ? num(attrs[k]) : attrs[k]) + '"';
+    }
+  }
+  return body === undefined ? out + '/>' : out + '>' + body + '</' + name + '>';
+}
+
+// textBlock writes lines of text vertically centered on y
+function textBlock(lines, x, y, opts) {
+  opts = opts || {};
+  var size = opts.size || FONT_SIZE;
+  var lh = size * LINE_HEIGHT;
+  var top = y - (lines.length - 1) * lh / 2;
+  var body = lines.map(function (l, i) {
+    return tag('tspan', { x: x, y: top + i * lh }, esc(l));
+  }).join('');
+  return tag('text', {
+    'text-anchor': opts.anchor || 'middle',
+    'dominant-baseline': 'central',
+    'font-size': size,
+    'font-weight': opts.bold ? 'bold' : '',
+    'font-style': opts.italic ? 'italic' : '',
+    'text-decoration': opts.underline ? 'underline' : '',
+    fill: opts.color || C.text
+  }, body);
+}
+
+// labelBox writes text on a background so it stays readable over lines
+function labelBox(lines, x, y, opts) {
+  var size = textSize(lines, (opts && opts.size) || FONT_SIZE);
+  return tag('rect', { x: x - size.w / 2 - 4, y: y - size.h / 2 - 2, width: size.w + 8, height: size.h + 4, rx: 3, fill: C.background, 'fill-opacity': 0.9 }) +
+    textBlock(lines, x, y, opts);
+}
+
+function polygon(points, style) {
+  var attrs = { points: points.map(function (p) { return num(p[0]) + ',' + num(p[1]); }).join(' ') };
+  for (var k in style) attrs[k] = style[k];
+  return tag('polygon', attrs);
+}
+
+function line(x1, y1, x2, y2, style) {
+  var attrs = { x1: x1, y1: y1, x2: x2, y2: y2 };
+  for (var k in style) attrs[k] = style[k];
+  return tag('line', attrs);
+}
+
+// smoothPath draws a path through points, rounding the corners
+function smoothPath(points) {
+  var d = 'M' + num(points[0].x) + ',' + num(points[0].y);
+  for (var i = 1; i < points.length - 1; i++) {
+    var p = points[i], q = points[i + 1];
+    d += ' Q' + num(p.x) + ',' + num(p.y) + ' ' + num((p.x + q.x) / 2) + ',' + num((p.y + q.y) / 2);
+  }
+  var last = points[points.length - 1];
+  return d + ' L' + num(last.x) + ',' + num(last.y);
+}
+
+function marker(id, width, height, refX, refY, body) {
+  return tag('marker', {
+    id: id, viewBox: '0 0 ' + width + ' ' + height, refX: refX, refY: refY,
+    markerWidth: width, markerHeight: height, markerUnits: 'userSpaceOnUse', orient: 'auto-start-reverse'
+  }, body);
+}
+
+// markerDefs defines the arrowheads a diagram's edges refer to by id
+function markerDefs(id) {
+  var stroke = { stroke: C.line, 'stroke-width': 1.5 };
+  function styled(name, attrs) {
+    for (var k in stroke) if (attrs[k] === undefined) attrs[k] = stroke[k];
+    return tag(name, attrs);
+  }
+  return '<defs>' +
+    marker(id + '-arrow', 10, 10, 9, 5, tag('path', { d: 'M0,0 L10,5 L0,10 z', fill: C.line })) +
+    marker(id + '-open', 10, 10, 9, 5, styled('path', { d: 'M1,1 L9,5 L1,9', fill: 'none' })) +
+    marker(id + '-circle', 10, 10, 9, 5, styled('circle', { cx: 5, cy: 5, r: 4, fill: C.background })) +
+    marker(id + '-cross', 10, 10, 8, 5, styled('path', { d: 'M1,1 L9,9 M9,1 L1,9', 'stroke-width': 2 })) +
+    marker(id + '-triangle', 16, 16, 15, 8, styled('path', { d: 'M1,1 L15,8 L1,15 z', fill: C.background })) +
+    marker(id + '-diamond', 20, 12, 19, 6, styled('path', { d: 'M1,6 L10,1 L19,6 L10,11 z', fill: C.line })) +
+    marker(id + '-odiamond', 20, 12, 19, 6, styled('path', { d: 'M1,6 L10,1 L19,6 L10,11 z', fill: C.background })) +
+    '</defs>';
+}
+
+// svgDocument wraps diagram content in a scalable svg element
+function svgDocument(width, height, body, defs) {
+  width = Math.ceil(width);
+  height = Math.ceil(height);
+  return '<svg xmlns="http://www.w3.org/2000/svg" class="mermaid-svg" role="img" viewBox="0 0 ' + width + ' ' + height +
+    '" width="' + width + '" style="max-width:100%;height:auto" font-family="' + esc(FONT_FAMILY) + '" font-size="' + FONT_SIZE + '">' +
+    (defs || '') + body + '</svg>';
+}
+
+/* --- Styles from classDef and style statements --- */
+
+// parseStyle reads a Mermaid style list such as fill:#f9f,stroke:#333
+function parseStyle(s) {
+  var style = {};
+  s.split(/,(?![^(]*\))/).forEach(function (part) {
+    var kv = part.split(':');
+    if (kv.length < 2) return;
+    var key = kv[0].trim(), value = kv.slice(1).join(':').trim().replace(/;$/, '');
+    if (/^(fill|stroke|stroke-width|stroke-dasharray|color|font-weight)$/.test(key)) style[key] = value;
+  });
+  return style;
+}
+
+// shapeStyle merges custom styles over the default node colors
+function shapeStyle(custom) {
+  var style = { fill: C.fill, stroke: C.stroke, 'stroke-width': 1.2 };
+  for (var k in custom) {
+    if (k !== 'color' && k !== 'font-weight') style[k] = custom[k];
+  }
+  return style;
+}
+
+/* --- Layered graph layout --- */
+
+// layoutGraph places the nodes of a directed graph in ranks along the flow
+// direction and orders each rank to reduce edge crossings. Nodes are
+// {id, w, h, clusters}, where clusters lists the enclosing cluster ids from
+// the outermost in; edges are {from, to, minlen, labelW, labelH}. Node
+// positions (x, y centers), edge points and cluster boxes are set in place.
+function layoutGraph(g) {
+  var dir = g.dir || 'TB';
+  var horizontal = dir === 'LR' || dir === 'RL';
+  var nodeSep = g.nodeSep || 40;
+  var rankSep = g.rankSep || 50;
+  var clusterPad = 14;
+  var clusterTitle = g.clusterTitle || 24;
+
+  var nodes = [], byId = {};
+  g.nodes.forEach(function (n) {
+    var v = {
+      id: n.id, src: n, dummy: false, clusters: n.clusters || [],
+      w: horizontal ? n.h : n.w, h: horizontal ? n.w : n.h,
+      out: [], up: [], down: [], rank: 0, extraRight: n.loopSpace || 0
+    };
+    nodes.push(v);
+    byId[n.id] = v;
+  });
+
+  var ledges = [];
+  g.edges.forEach(function (e) {
+    e.points = null;
+    if (e.from === e.to) return;
+    var le = { e: e, from: byId[e.from], to: byId[e.to], minlen: e.minlen === undefined ? 1 : e.minlen, reversed: false };
+    ledges.push(le);
+    le.from.out.push(le);
+  });
+
+  // Break cycles by reversing the edges that point back up a DFS tree
+  var visit = {};
+  function dfs(v) {
+    visit[v.id] = 1;
+    v.out.forEach(function (le) {
+      if (visit[le.to.id] === 1) le.reversed = true;
+      else if (!visit[le.to.id]) dfs(le.to);
+    });
+    visit[v.id] = 2;
+  }
+  nodes.forEach(function (v) { if (!visit[v.id]) dfs(v); });
+  ledges.forEach(function (le) {
+    le.tail = le.reversed ? le.to : le.from;
+    le.head = le.reversed ? le.from : le.to;
+  });
+
+  // Longest-path ranking; ranks are doubled so every edge has a middle
+  // rank for its label
+  var indeg = {}, outs = {}, ins = {};
+  nodes.forEach(function (v) { indeg[v.id] = 0; outs[v.id] = []; ins[v.id] = []; });
+  ledges.forEach(function (le) {
+    indeg[le.head.id]++;
+    outs[le.tail.id].push(le);
+    ins[le.head.id].push(le);
+  });
+  var queue = nodes.filter(function (v) { return indeg[v.id] === 0; });
+  var topo = [];
+  while (queue.length) {
+    var v = queue.shift();
+    topo.push(v);
+    outs[v.id].forEach(function (le) {
+      le.head.rank = Math.max(le.head.rank, le.tail.rank + 2 * le.minlen);
+      if (--indeg[le.head.id] === 0) queue.push(le.head);
+    });
+  }
+  // Pull sources down next to their nearest successor
+  for (var ti = topo.length - 1; ti >= 0; ti--) {
+    var s = topo[ti];
+    if (ins[s.id].length || !outs[s.id].length) continue;
+    var r = Infinity;
+    outs[s.id].forEach(function (le) { r = Math.min(r, le.head.rank - 2 * le.minlen); });
+    s.rank = r;
+  }
+
+  // Dummy nodes carry edges across ranks; the middle one holds the label
+  var dummyCount = 0;
+  ledges.forEach(function (le) {
+    var chain = [le.tail];
+    var span = le.head.rank - le.tail.rank;
+    var common = commonPrefix(le.tail.clusters, le.head.clusters);
+    var mid = le.tail.rank + Math.max(1, Math.floor(span / 2));
+    for (var r = le.tail.rank + 1; r < le.head.rank; r++) {
+      var labelled = r === mid && le.e.labelW;
+      var d = {
+        id: '\u0000d' + (dummyCount++), dummy: true, clusters: common, rank: r,
+        w: labelled ? (horizontal ? le.e.labelH : le.e.labelW) : 0,
+        h: labelled ? (horizontal ? le.e.labelW : le.e.labelH) : 0,
+        up: [], down: [], out: [], extraRight: 0
+      };
+      if (labelled) le.labelNode = d;
+      nodes.push(d);
+      chain.push(d);
+    }
+    chain.push(le.head);
+    for (var i = 1; i < chain.length; i++) {
+      chain[i - 1].down.push(chain[i]);
+      chain[i].up.push(chain[i - 1]);
+    }
+    le.chain = chain;
+  });
+
+  var minRank = Infinity, maxRank = -Infinity;
+  nodes.forEach(function (v) { minRank = Math.min(minRank, v.rank); maxRank = Math.max(maxRank, v.rank); });
+  var layers = [];
+  for (var lr = 0; lr <= maxRank - minRank; lr++) layers.push([]);
+  nodes.forEach(function (v) {
+    v.rank -= minRank;
+    v.pos = layers[v.rank].length;
+    layers[v.rank].push(v);
+  });
+
+  orderLayers(layers);
+  assignCross(layers, nodeSep, clusterPad, horizontal ? clusterTitle : 0);
+
+  // Rank positions, leaving room for cluster borders and titles
+  var clusterRanks = {};
+  nodes.forEach(function (v) {
+    if (v.dummy) return;
+    v.clusters.forEach(function (c) {
+      var cr = clusterRanks[c] || (clusterRanks[c] = { min: v.rank, max: v.rank });
+      cr.min = Math.min(cr.min, v.rank);
+      cr.max = Math.max(cr.max, v.rank);
+    });
+  });
+  var before = layers.map(function () { return 0; }), after = before.slice();
+  Object.keys(clusterRanks).forEach(function (c) {
+    before[clusterRanks[c].min] += clusterPad + (horizontal ? 0 : clusterTitle);
+    after[clusterRanks[c].max] += clusterPad;
+  });
+  var y = 0;
+  layers.forEach(function (layer, r) {
+    var h = 0;
+    layer.forEach(function (v) { h = Math.max(h, v.h); });
+    y += before[r];
+    layer.forEach(function (v) { v.y = y + h / 2; });
+    y += h + after[r] + rankSep / 2;
+  });
+
+  // Back to the requested direction
+  var maxX = 0, maxY = y;
+  nodes.forEach(function (v) { maxX = Math.max(maxX, v.x + v.w / 2); });
+  function place(x, y) {
+    switch (dir) {
+      case 'BT': return { x: x, y: maxY - y };
+      case 'LR': return { x: y, y: x };
+      case 'RL': return { x: maxY - y, y: x };
+      default: return { x: x, y: y };
+    }
+  }
+  nodes.forEach(function (v) {
+    var p = place(v.x, v.y);
+    v.fx = p.x;
+    v.fy = p.y;
+    if (!v.dummy) {
+      v.src.x = p.x;
+      v.src.y = p.y;
+    }
+  });
+  ledges.forEach(function (le) {
+    var pts = le.chain.map(function (v) { return { x: v.fx, y: v.fy }; });
+    if (le.reversed) pts.reverse();
+    le.e.points = pts;
+    if (le.labelNode) le.e.labelPos = { x: le.labelNode.fx, y: le.labelNode.fy };
+    else le.e.labelPos = { x: (pts[0].x + pts[pts.length - 1].x) / 2, y: (pts[0].y + pts[pts.length - 1].y) / 2 };
+  });
+
+  // Cluster boxes, innermost first so parents enclose them
+  var boxes = {};
+  var clusterIds = Object.keys(g.clusters || {});
+  clusterIds.sort(function (a, b) { return depth(g.clusters, b) - depth(g.clusters, a); });
+  clusterIds.forEach(function (c) {
+    var box = null;
+    function add(x1, y1, x2, y2) {
+      if (!box) box = { x1: x1, y1: y1, x2: x2, y2: y2 };
+      else {
+        box.x1 = Math.min(box.x1, x1); box.y1 = Math.min(box.y1, y1);
+        box.x2 = Math.max(box.x2, x2); box.y2 = Math.max(box.y2, y2);
+      }
+    }
+    g.nodes.forEach(function (n) {
+      if ((n.clusters || []).indexOf(c) >= 0) add(n.x - n.w / 2, n.y - n.h / 2, n.x + n.w / 2, n.y + n.h / 2);
+    });
+    clusterIds.forEach(function (o) {
+      if (boxes[o] && g.clusters[o].parent === c) add(boxes[o].x1, boxes[o].y1, boxes[o].x2, boxes[o].y2);
+    });
+    if (!box) return;
+    box.x1 -= clusterPad; box.x2 += clusterPad;
+    box.y1 -= clusterPad + clusterTitle; box.y2 += clusterPad;
+    boxes[c] = box;
+  });
+
+  // Shift everything into view with a margin
+  var margin = g.margin === undefined ? 16 : g.margin;
+  var bx1 = Infinity, by1 = Infinity, bx2 = -Infinity, by2 = -Infinity;
+  function extend(x1, y1, x2, y2) {
+    bx1 = Math.min(bx1, x1); by1 = Math.min(by1, y1);
+    bx2 = Math.max(bx2, x2); by2 = Math.max(by2, y2);
+  }
+  g.nodes.forEach(function (n) { extend(n.x - n.w / 2, n.y - n.h / 2, n.x + n.w / 2 + (n.loopSpace || 0), n.y + n.h / 2); });
+  g.edges.forEach(function (e) {
+    (e.points || []).forEach(function (p) { extend(p.x, p.y, p.x, p.y); });
+    if (e.points && e.labelW) extend(e.labelPos.x - e.labelW / 2, e.labelPos.y - e.labelH / 2, e.labelPos.x + e.labelW / 2, e.labelPos.y + e.labelH / 2);
+  });
+  Object.keys(boxes).forEach(function (c) { var b = boxes[c]; extend(b.x1, b.y1, b.x2, b.y2); });
+  if (bx1 === Infinity) { bx1 = by1 = 0; bx2 = by2 = 0; }
+  var dx = margin - bx1, dy = margin - by1;
+  g.nodes.forEach(function (n) { n.x += dx; n.y += dy; });
+  g.edges.forEach(function (e) {
+    (e.points || []).forEach(function (p) { p.x += dx; p.y += dy; });
+    if (e.labelPos) { e.labelPos.x += dx; e.labelPos.y += dy; }
+  });
+  g.boxes = {};
+  Object.keys(boxes).forEach(function (c) {
+    var b = boxes[c];
+    g.boxes[c] = { x: b.x1 + dx, y: b.y1 + dy, w: b.x2 - b.x1, h: b.y2 - b.y1 };
+  });
+  g.width = bx2 - bx1 + 2 * margin;
+  g.height = by2 - by1 + 2 * margin;
+  return g;
+}
+
+function commonPrefix(a, b) {
+  var out = [];
+  for (var i = 0; i < a.length && i < b.length && a[i] === b[i]; i++) out.push(a[i]);
+  return out;
+}
+
+function depth(clusters, c) {
+  var d = 0;
+  while (clusters[c] && clusters[c].parent) { c = clusters[c].parent; d++; }
+  return d;
+}
+
+// orderLayers sorts each rank by the mean position of its neighbors,
+// sweeping down and up, keeping cluster members together and the ordering
+// with the fewest crossings
+function orderLayers(layers) {
+  function snapshot() { return layers.map(function (l) { return l.slice(); }); }
+  function crossings() {
+    var count = 0;
+    for (var r = 0; r + 1 < layers.length; r++) {
+      var segs = [];
+      layers[r].forEach(function (v) { v.down.forEach(function (u) { segs.push([v.pos, u.pos]); }); });
+      for (var i = 0; i < segs.length; i++) {
+        for (var j = i + 1; j < segs.length; j++) {
+          if ((segs[i][0] - segs[j][0]) * (segs[i][1] - segs[j][1]) < 0) count++;
+        }
+      }
+    }
+    return count;
+  }
+  function sortLayer(layer, neighbors) {
+    layer.forEach(function (v) {
+      var ns = neighbors(v);
+      if (!ns.length) { v.bc = v.pos; return; }
+      var sum = 0;
+      ns.forEach(function (u) { sum += u.pos; });
+      v.bc = sum / ns.length;
+    });
+    var keys = {}, counts = {};
+    layer.forEach(function (v) {
+      v.clusters.forEach(function (c) {
+        keys[c] = (keys[c] || 0) + v.bc;
+        counts[c] = (counts[c] || 0) + 1;
+      });
+    });
+    layer.sort(function (a, b) {
+      var i = 0;
+      while (i < a.clusters.length && i < b.clusters.length && a.clusters[i] === b.clusters[i]) i++;
+      var ka = i < a.clusters.length ? keys[a.clusters[i]] / counts[a.clusters[i]] : a.bc;
+      var kb = i < b.clusters.length ? keys[b.clusters[i]] / counts[b.clusters[i]] : b.bc;
+      if (ka !== kb) return ka - kb;
+      if (i < a.clusters.length && i < b.clusters.length) return a.clusters[i] < b.clusters[i] ? -1 : 1;
+      return a.pos - b.pos;
+    });
+    layer.forEach(function (v, i) { v.pos = i; });
+  }
+
+  var best = snapshot(), bestCount = crossings();
+  for (var iter = 0; iter < 24 && bestCount > 0; iter++) {
+    var r;
+    if (iter % 2 === 0) {
+      for (r = 1; r < layers.length; r++) sortLayer(layers[r], function (v) { return v.up; });
+    } else {
+      for (r = layers.length - 2; r >= 0; r--) sortLayer(layers[r], function (v) { return v.down; });
+    }
+    var count = crossings();
+    if (count < bestCount) { bestCount = count; best = snapshot(); }
+  }
+  best.forEach(function (layer, r) {
+    layers[r] = layer;
+    layer.forEach(function (v, i) { v.pos = i; });
+  });
+}
+
+// assignCross sets positions across the flow: each rank is packed in order
+// and pulled toward its neighbors in the ranks above and below
+function assignCross(layers, nodeSep, clusterPad, clusterTitle) {
+  function gap(a, b) {
+    var common = commonPrefix(a.clusters, b.clusters).length;
+    var leaving = a.clusters.length - common, entering = b.clusters.length - common;
+    var sep = a.dummy && b.dummy ? nodeSep / 4 : (a.dummy || b.dummy ? nodeSep / 2 : nodeSep);
+    return (a.w + b.w) / 2 + sep + a.extraRight + leaving * clusterPad + entering * (clusterPad + clusterTitle);
+  }
+  function place(layer, targets) {
+    var offsets = [0];
+    for (var i = 1; i < layer.length; i++) offsets.push(offsets[i - 1] + gap(layer[i - 1], layer[i]));
+    var xs = pava(layer.map(function (v, i) { return targets[i] - offsets[i]; }),
+      layer.map(function (v) { return v.dummy ? 2 : 1; }));
+    layer.forEach(function (v, i) { v.x = xs[i] + offsets[i]; });
+  }
+  function pull(layer, neighbors) {
+    place(layer, layer.map(function (v) {
+      var ns = neighbors(v);
+      if (!ns.length) return v.x;
+      var sum = 0;
+      ns.forEach(function (u) { sum += u.x; });
+      return sum / ns.length;
+    }));
+  }
+
+  layers.forEach(function (layer) {
+    layer.forEach(function (v) { v.x = 0; });
+    place(layer, layer.map(function () { return 0; }));
+  });
+  for (var iter = 0; iter < 8; iter++) {
+    var r;
+    for (r = 1; r < layers.length; r++) pull(layers[r], function (v) { return v.up; });
+    for (r = layers.length - 2; r >= 0; r--) pull(layers[r], function (v) { return v.down; });
+  }
+  for (var k = 0; k < 2; k++) {
+    layers.forEach(function (layer) {
+      pull(layer, function (v) { return v.up.concat(v.down); });
+    });
+  }
+
+  var minX = Infinity;
+  layers.forEach(function (layer) {
+    layer.forEach(function (v) { minX = Math.min(minX, v.x - v.w / 2); });
+  });
+  layers.forEach(function (layer) {
+    layer.forEach(function (v) { v.x -= minX; });
+  });
+}
+
+// pava returns the weighted least-squares nondecreasing fit to targets
+function pava(targets, weights) {
+  var blocks = [];
+  targets.forEach(function (t, i) {
+    blocks.push({ v: t, w: weights[i], n: 1 });
+    while (blocks.length > 1 && blocks[blocks.length - 2].v > blocks[blocks.length - 1].v) {
+      var b = blocks.pop(), a = blocks[blocks.length - 1];
+      a.v = (a.v * a.w + b.v * b.w) / (a.w + b.w);
+      a.w += b.w;
+      a.n += b.n;
+    }
+  });
+  var out = [];
+  blocks.forEach(function (b) { for (var k = 0; k < b.n; k++) out.push(b.v); });
+  return out;
+}
+
+// clipToShape moves an edge end from a node's center to its outline, along
+// the line toward point p
+function clipToShape(n, p) {
+  var dx = p.x - n.x, dy = p.y - n.y;
+  if (dx === 0 && dy === 0) return { x: n.x, y: n.y };
+  var hw = n.w / 2, hh = n.h / 2, t;
+  switch (n.outline) {
+    case 'ellipse':
+      t = 1 / Math.sqrt((dx * dx) / (hw * hw) + (dy * dy) / (hh * hh));
+      break;
+    case 'diamond':
+      t = 1 / (Math.abs(dx) / hw + Math.abs(dy) / hh);
+      break;
+    default:
+      t = Math.min(dx ? hw / Math.abs(dx) : Infinity, dy ? hh / Math.abs(dy) : Infinity);
+  }
+  t = Math.min(t, 1);
+  return { x: n.x + dx * t, y: n.y + dy * t };
+}
+
+// edgePath clips an edge's points to its end nodes and draws it
+function edgePath(e, from, to, style) {
+  var pts = e.points.slice();
+  pts[0] = clipToShape(from, pts[1]);
+  pts[pts.length - 1] = clipToShape(to, pts[pts.length - 2]);
+  var attrs = { d: smoothPath(pts), fill: 'none' };
+  for (var k in style) attrs[k] = style[k];
+  e.drawn = pts;
+  return tag('path', attrs);
+}
+
+// selfLoop draws an edge from a node back to itself on its right side
+function selfLoop(n, style, dir) {
+  var d;
+  if (dir === 'LR' || dir === 'RL') {
+    var by = n.y + n.h / 2;
+    d = 'M' + num(n.x - n.w / 4) + ',' + num(by) + ' C' + num(n.x - n.w / 4) + ',' + num(by + 36) + ' ' +
+      num(n.x + n.w / 4) + ',' + num(by + 36) + ' ' + num(n.x + n.w / 4) + ',' + num(by);
+  } else {
+    var rx = n.x + n.w / 2;
+    d = 'M' + num(rx) + ',' + num(n.y - n.h / 4) + ' C' + num(rx + 36) + ',' + num(n.y - n.h / 4) + ' ' +
+      num(rx + 36) + ',' + num(n.y + n.h / 4) + ' ' + num(rx) + ',' + num(n.y + n.h / 4);
+  }
+  var attrs = { d: d, fill: 'none' };
+  for (var k in style) attrs[k] = style[k];
+  return tag('path', attrs);
+}
+
+function selfLoopLabel(n, dir) {
+  if (dir === 'LR' || dir === 'RL') return { x: n.x, y: n.y + n.h / 2 + 36 };
+  return { x: n.x + n.w / 2 + 30, y: n.y };
+}
+
+/* --- Source --- */
+
+// splitStatements splits a line on semicolons outside quotes and brackets
+function splitStatements(line) {
+  var out = [], depth = 0, quote = false, start = 0;
+  for (var i = 0; i < line.length; i++) {
+    var c = line[i];
+    if (c === '"') quote = !quote;
+    else if (quote) continue;
+    else if (c === '[' || c === '(' || c === '{') depth++;
+    else if ((c === ']' || c === ')' || c === '}') && depth > 0) depth--;
+    else if (c === ';' && depth === 0) {
+      out.push(line.slice(start, i));
+      start = i + 1;
+    }
+  }
+  out.push(line.slice(start));
+  return out.map(function (s) { return s.trim(); }).filter(function (s) { return s !== ''; });
+}
+
+// preprocess drops front matter, directives and comments, returning the
+// title from front matter and the remaining lines
+function preprocess(src) {
+  src = src.replace(/\r\n?/g, '\n');
+  var title = '';
+  var fm = /^\s*---\n([\s\S]*?)\n---[ \t]*(?:\n|$)/.exec(src);
+  if (fm) {
+    var t = /^title:\s*(.*)$/m.exec(fm[1]);
+    if (t) title = unquote(t[1]);
+    src = src.slice(fm[0].length);
+  }
+  src = src.replace(/%%\{[\s\S]*?\}%%/g, '');
+  var lines = src.split('\n').map(function (l) { return l.trim(); }).filter(function (l) {
+    return l !== '' && l.slice(0, 2) !== '%%';
+  });
+  return { title: title, lines: lines };
+}
+
+/* --- Flowcharts --- */
+
+var SHAPES = [
+  ['(((', ')))', 'dcircle'],
+  ['([', '])', 'stadium'],
+  ['[[', ']]', 'subroutine'],
+  ['[(', ')]', 'cylinder'],
+  ['((', '))', 'circle'],
+  ['{{', '}}', 'hexagon'],
+  ['[/', '/]', 'lean-r'],
+  ['[/', '\\]', 'trapezoid'],
+  ['[\\', '\\]', 'lean-l'],
+  ['[\\', '/]', 'inv-trapezoid'],
+  ['>', ']', 'flag'],
+  ['(', ')', 'round'],
+  ['[', ']', 'rect'],
+  ['{', '}', 'diamond']
+];
+
+var reNodeID = /^[\w\u00c0-\uffff]+/;
+var reLinkText = /^([<ox]?)(--|==|-\.)\s+(?![->=])(.+?)\s+(-{2,}|={2,}|\.+-)([>ox]?)(?=[\s\w"([{]|$)/;
+var reLink = /^([<ox]?)(-{2,}|={2,}|-\.+-|~{3,})([>ox]?)/;
+
+// flowParser reads flowchart statements into nodes, edges and subgraphs
+function flowParser() {
+  var p = { dir: 'TB', nodes: [], byId: {}, edges: [], clusters: {}, clusterOrder: [], stack: [], classDefs: {}, styles: {}, classes: {} };
+
+  p.node = function (id) {
+    var n = p.byId[id];
+    if (!n) {
+      n = { id: id, label: [id], shape: 'rect', clusters: p.stack.slice() };
+      p.byId[id] = n;
+      p.nodes.push(n);
+    }
+    return n;
+  };
+
+  // readNode reads a node reference with its optional shape and class at
+  // the start of s, returning the node and the remaining text
+  p.readNode = function (s) {
+    var m = reNodeID.exec(s);
+    if (!m) return null;
+    var n = p.node(m[0]);
+    var rest = s.slice(m[0].length);
+    for (var i = 0; i < SHAPES.length; i++) {
+      var open = SHAPES[i][0];
+      if (rest.slice(0, open.length) !== open) continue;
+      var body = rest.slice(open.length), end = -1, shape = null;
+      var q = body.match(/^\s*"((?:[^"\\]|\\.)*)"\s*/);
+      SHAPES.forEach(function (sh) {
+        if (sh[0] !== open) return;
+        var idx = q ? (body.slice(q[0].length, q[0].length + sh[1].length) === sh[1] ? q[0].length : -1) : body.indexOf(sh[1]);
+        if (idx >= 0 && (end < 0 || idx < end)) { end = idx; shape = sh; }
+      });
+      if (!shape) continue;
+      n.label = labelLines(q ? q[1] : body.slice(0, end));
+      n.shape = shape[2];
+      rest = body.slice(end + shape[1].length);
+      break;
+    }
+    var cls = /^:::([\w-]+)/.exec(rest);
+    if (cls) {
+      p.classes[n.id] = (p.classes[n.id] || []).concat(cls[1]);
+      rest = rest.slice(cls[0].length);
+    }
+    return { node: n, rest: rest };
+  };
+
+  // readGroup reads node references joined by &
+  p.readGroup = function (s) {
+    var group = [];
+    for (;;) {
+      var r = p.readNode(s.trim());
+      if (!r) return group.length ? { nodes: group, rest: s } : null;
+      group.push(r.node);
+      s = r.rest;
+      var amp = /^\s*&\s*/.exec(s);
+      if (!amp) return { nodes: group, rest: s };
+      s = s.slice(amp[0].length);
+    }
+  };
+
+  // readLink reads an edge operator and its label
+  p.readLink = function (s) {
+    s = s.replace(/^\s+/, '');
+    var m = reLinkText.exec(s), link;
+    if (m) {
+      link = { start: m[1], body: m[2] + m[4], end: m[5], label: m[3], rest: s.slice(m[0].length) };
+    } else if ((m = reLink.exec(s))) {
+      var end = m[3], len = m[0].length;
+      if ((end === 'o' || end === 'x') && /^[\w\u00c0-\uffff]/.test(s.slice(len))) {
+        end = '';
+        len--;
+      }
+      link = { start: m[1], body: m[2], end: end, label: '', rest: s.slice(len) };
+      var pipe = /^\s*\|([^|]*)\|/.exec(link.rest);
+      if (pipe) {
+        link.label = pipe[1];
+        link.rest = link.rest.slice(pipe[0].length);
+      }
+    } else {
+      return null;
+    }
+    var b = link.body;
+    link.style = b.indexOf('~') >= 0 ? 'invisible' : b.indexOf('.') >= 0 ? 'dotted' : b.indexOf('=') >= 0 ? 'thick' : 'normal';
+    var count = (b.match(/[-=.]/g) || []).length;
+    if (link.style === 'dotted') count = (b.match(/\./g) || []).length + 1;
+    link.minlen = Math.max(1, count - (link.end ? 1 : 2));
+    if (link.style === 'dotted') link.minlen = Math.max(1, count - 1);
+    return link;
+  };
+
+  p.statement = function (s) {
+    var m;
+    if ((m = /^(graph|flowchart)\b\s*(\w+)?/.exec(s))) {
+      if (m[2]) p.dir = m[2] === 'TD' ? 'TB' : m[2];
+      return;
+    }
+    if ((m = /^subgraph\s+(.*)$/.exec(s))) {
+      var rest = m[1].trim(), id, title;
+      var t = /^([\w\u00c0-\uffff-]+)\s*\[(.*)\]$/.exec(rest);
+      if (t) { id = t[1]; title = t[2]; }
+      else if (/^".*"$/.test(rest)) { id = 'subgraph' + p.clusterOrder.length; title = rest; }
+      else { id = rest; title = rest; }
+      p.clusters[id] = { id: id, title: labelLines(title), parent: p.stack[p.stack.length - 1] || null };
+      p.clusterOrder.push(id);
+      p.stack.push(id);
+      return;
+    }
+    if (s === 'end') { p.stack.pop(); return; }
+    if (/^direction\s/.test(s)) return;
+    if ((m = /^classDef\s+([\w,-]+)\s+(.*)$/.exec(s))) {
+      var st = parseStyle(m[2]);
+      m[1].split(',').forEach(function (c) { p.classDefs[c] = st; });
+      return;
+    }
+    if ((m = /^class\s+([^\s]+)\s+([\w-]+)$/.exec(s))) {
+      m[1].split(',').forEach(function (id) { p.classes[id] = (p.classes[id] || []).concat(m[2]); });
+      return;
+    }
+    if ((m = /^style\s+(\S+)\s+(.*)$/.exec(s))) {
+      p.styles[m[1]] = parseStyle(m[2]);
+      return;
+    }
+    if (/^(linkStyle|click|accTitle|accDescr)\b/.test(s)) return;
+
+    var left = p.readGroup(s);
+    if (!left) throw new Error('cannot read "' + s + '"');
+    var rest2 = left.rest;
+    for (;;) {
+      if (!rest2.trim()) return;
+      var link = p.readLink(rest2);
+      if (!link) throw new Error('cannot read "' + rest2.trim() + '"');
+      var right = p.readGroup(link.rest);
+      if (!right) throw new Error('missing node after link in "' + s + '"');
+      left.nodes.forEach(function (a) {
+        right.nodes.forEach(function (b) {
+          p.edges.push({ from: a.id, to: b.id, label: link.label ? labelLines(link.label) : null, style: link.style, start: link.start, end: link.end, minlen: link.minlen });
+        });
+      });
+      left = right;
+      rest2 = right.rest;
+    }
+  };
+
+  // finish points edges at subgraphs to the subgraph's first node
+  p.finish = function () {
+    Object.keys(p.clusters).forEach(function (c) {
+      var first = null;
+      p.nodes.forEach(function (n) { if (!first && n.id !== c && n.clusters.indexOf(c) >= 0) first = n; });
+      if (!first) return;
+      p.clusters[c].first = first.id;
+      if (p.byId[c]) {
+        p.nodes.splice(p.nodes.indexOf(p.byId[c]), 1);
+        delete p.byId[c];
+      }
+      p.edges.forEach(function (e) {
+        if (e.from === c) e.from = first.id;
+        if (e.to === c) e.to = first.id;
+      });
+    });
+  };
+  return p;
+}
+
+// nodeStyle combines the default class, the node's classes and style
+function nodeStyle(p, id) {
+  var style = {};
+  function merge(s) { for (var k in s) style[k] = s[k]; }
+  if (p.classDefs['default']) merge(p.classDefs['default']);
+  (p.classes[id] || []).forEach(function (c) { if (p.classDefs[c]) merge(p.classDefs[c]); });
+  if (p.styles[id]) merge(p.styles[id]);
+  return style;
+}
+
+// sizeShape sets a node's size and outline from its label and shape
+function sizeShape(n) {
+  var t = textSize(n.label);
+  var h = t.h + 20;
+  switch (n.shape) {
+    case 'circle':
+      n.w = n.h = Math.max(t.w, t.h) + 24;
+      n.outline = 'ellipse';
+      break;
+    case 'dcircle':
+      n.w = n.h = Math.max(t.w, t.h) + 34;
+      n.outline = 'ellipse';
+      break;
+    case 'diamond':
+      n.w = n.h = Math.max(t.w + t.h + 24, 48);
+      n.outline = 'diamond';
+      break;
+    case 'stadium':
+      n.w = t.w + h + 8; n.h = h;
+      break;
+    case 'hexagon':
+      n.w = t.w + 32 + h / 2; n.h = h;
+      break;
+    case 'lean-r': case 'lean-l': case 'trapezoid': case 'inv-trapezoid':
+      n.w = t.w + 32 + h; n.h = h;
+      break;
+    case 'cylinder':
+      n.w = t.w + 32; n.h = h + 14;
+      break;
+    case 'subroutine':
+      n.w = t.w + 48; n.h = h;
+      break;
+    case 'flag':
+      n.w = t.w + 32 + h / 4; n.h = h;
+      break;
+    default:
+      n.w = t.w + 32; n.h = h;
+  }
+}
+
+// drawShape draws a node's outline and label
+function drawShape(n, custom) {
+  var st = shapeStyle(custom);
+  var x = n.x, y = n.y, hw = n.w / 2, hh = n.h / 2, out = '';
+  function with_(attrs) { for (var k in st) attrs[k] = st[k]; return attrs; }
+  switch (n.shape) {
+    case 'circle':
+      out = tag('circle', with_({ cx: x, cy: y, r: hw }));
+      break;
+    case 'dcircle':
+      out = tag('circle', with_({ cx: x, cy: y, r: hw })) + tag('circle', with_({ cx: x, cy: y, r: hw - 5 }));
+      break;
+    case 'diamond':
+      out = polygon([[x, y - hh], [x + hw, y], [x, y + hh], [x - hw, y]], st);
+      break;
+    case 'round':
+      out = tag('rect', with_({ x: x - hw, y: y - hh, width: n.w, height: n.h, rx: 10 }));
+      break;
+    case 'stadium':
+      out = tag('rect', with_({ x: x - hw, y: y - hh, width: n.w, height: n.h, rx: hh }));
+      break;
+    case 'hexagon':
+      out = polygon([[x - hw, y], [x - hw + hh / 2, y - hh], [x + hw - hh / 2, y - hh], [x + hw, y], [x + hw - hh / 2, y + hh], [x - hw + hh / 2, y + hh]], st);
+      break;
+    case 'lean-r':
+      out = polygon([[x - hw + hh, y - hh], [x + hw, y - hh], [x + hw - hh, y + hh], [x - hw, y + hh]], st);
+      break;
+    case 'lean-l':
+      out = polygon([[x - hw, y - hh], [x + hw - hh, y - hh], [x + hw, y + hh], [x - hw + hh, y + hh]], st);
+      break;
+    case 'trapezoid':
+      out = polygon([[x - hw + hh, y - hh], [x + hw - hh, y - hh], [x + hw, y + hh], [x - hw, y + hh]], st);
+      break;
+    case 'inv-trapezoid':
+      out = polygon([[x - hw, y - hh], [x + hw, y - hh], [x + hw - hh, y + hh], [x - hw + hh, y + hh]], st);
+      break;
+    case 'flag':
+      out = polygon([[x - hw, y - hh], [x + hw, y - hh], [x + hw, y + hh], [x - hw, y + hh], [x - hw + hh / 2, y]], st);
+      break;
+    case 'cylinder':
+      var ry = 7;
+      out = tag('path', with_({
+        d: 'M' + num(x - hw) + ',' + num(y - hh + ry) +
+          ' A' + num(hw) + ',' + ry + ' 0 0 1 ' + num(x + hw) + ',' + num(y - hh + ry) +
+          ' L' + num(x + hw) + ',' + num(y + hh - ry) +
+          ' A' + num(hw) + ',' + ry + ' 0 0 1 ' + num(x - hw) + ',' + num(y + hh - ry) + ' z'
+      })) + tag('path', with_({ d: 'M' + num(x - hw) + ',' + num(y - hh + ry) + ' A' + num(hw) + ',' + ry + ' 0 0 0 ' + num(x + hw) + ',' + num(y - hh + ry), fill: 'none' }));
+      break;
+    case 'subroutine':
+      out = tag('rect', with_({ x: x - hw, y: y - hh, width: n.w, height: n.h })) +
+        line(x - hw + 8, y - hh, x - hw + 8, y + hh, { stroke: st.stroke, 'stroke-width': st['stroke-width'] }) +
+        line(x + hw - 8, y - hh, x + hw - 8, y + hh, { stroke: st.stroke, 'stroke-width': st['stroke-width'] });
+      break;
+    default:
+      out = tag('rect', with_({ x: x - hw, y: y - hh, width: n.w, height: n.h, rx: 3 }));
+  }
+  var ty = n.shape === 'cylinder' ? y + 4 : y;
+  return out + textBlock(n.label, x, ty, { color: custom.color, bold: custom['font-weight'] === 'bold' });
+}
+
+// edgeStyle returns the stroke attributes and markers of an edge
+function edgeStyle(id, e) {
+  var heads = { '>': 'arrow', 'o': 'circle', 'x': 'cross', '<': 'arrow' };
+  var st = { stroke: C.line, 'stroke-width': e.style === 'thick' ? 3 : 1.5 };
+  if (e.style === 'dotted') st['stroke-dasharray'] = '3 3';
+  if (e.end) st['marker-end'] = 'url(#' + id + '-' + heads[e.end] + ')';
+  if (e.start) st['marker-start'] = 'url(#' + id + '-' + heads[e.start] + ')';
+  return st;
+}
+
+function renderFlowchart(lines) {
+  var p = flowParser();
+  lines.forEach(function (l) { splitStatements(l).forEach(p.statement); });
+  p.finish();
+  if (!p.nodes.length) throw new Error('the flowchart has no nodes');
+
+  p.nodes.forEach(sizeShape);
+  var loops = {};
+  p.edges.forEach(function (e) {
+    if (e.label) {
+      var t = textSize(e.label);
+      e.labelW = t.w + 10;
+      e.labelH = t.h + 4;
+    }
+    if (e.from === e.to) loops[e.from] = Math.max(loops[e.from] || 0, 40 + (e.labelW || 0));
+  });
+  p.nodes.forEach(function (n) { n.loopSpace = loops[n.id] || 0; });
+  var g = layoutGraph({ dir: p.dir, nodes: p.nodes, edges: p.edges, clusters: p.clusters });
+
+  var id = 'mmd' + (++diagramCount);
+  var body = '';
+  p.clusterOrder.slice().sort(function (a, b) { return depth(p.clusters, a) - depth(p.clusters, b); }).forEach(function (c) {
+    var b = g.boxes[c];
+    if (!b) return;
+    var st = p.styles[c] || {};
+    body += tag('rect', { x: b.x, y: b.y, width: b.w, height: b.h, rx: 6, fill: st.fill || C.cluster, stroke: st.stroke || C.clusterStroke, 'stroke-width': 1 }) +
+      textBlock(p.clusters[c].title, b.x + b.w / 2, b.y + 14, { color: st.color || C.muted, size: 13 });
+  });
+  var labels = '';
+  p.edges.forEach(function (e) {
+    if (e.style === 'invisible') return;
+    var from = p.byId[e.from], to = p.byId[e.to];
+    var st = edgeStyle(id, e);
+    if (e.from === e.to) {
+      body += selfLoop(from, st, p.dir);
+      if (e.label) {
+        var lp = selfLoopLabel(from, p.dir);
+        labels += labelBox(e.label, lp.x + (p.dir === 'LR' || p.dir === 'RL' ? 0 : e.labelW / 2), lp.y);
+      }
+      return;
+    }
+    body += edgePath(e, from, to, st);
+    if (e.label) labels += labelBox(e.label, e.labelPos.x, e.labelPos.y);
+  });
+  p.nodes.forEach(function (n) { body += drawShape(n, nodeStyle(p, n.id)); });
+  return svgDocument(g.width, g.height, body + labels, markerDefs(id));
+}
+
+/* --- State diagrams --- */
+
+function renderState(lines) {
+  var dir = 'TB';
+  var nodes = [], byId = {}, edges = [], clusters = {}, clusterOrder = [], stack = [];
+  var notes = 0;
+
+  function scopeKey() { return stack.join('/'); }
+  function state(id) {
+    var n = byId[id];
+    if (!n) {
+      n = { id: id, kind: 'state', label: [id], desc: [], clusters: stack.slice() };
+      byId[id] = n;
+      nodes.push(n);
+    }
+    return n;
+  }
+  function endpoint(ref, start) {
+    ref = ref.replace(/:::[\w-]+$/, '');
+    if (ref === '[*]') {
+      var id = (start ? '\u0000start:' : '\u0000end:') + scopeKey();
+      var n = state(id);
+      n.kind = start ? 'start' : 'end';
+      return n;
+    }
+    return state(ref);
+  }
+
+  for (var i = 0; i < lines.length; i++) {
+    var s = lines[i], m;
+    if (/^stateDiagram(-v2)?$/.test(s) || /^(hide empty description|scale\b|classDef\b|class\s|style\s|--$)/.test(s)) continue;
+    if ((m = /^direction\s+(\w+)/.exec(s))) {
+      if (!stack.length) dir = m[1];
+      continue;
+    }
+    if (s === '}') {
+      stack.pop();
+      continue;
+    }
+    if ((m = /^state\s+"([^"]*)"\s+as\s+([\w\u00c0-\uffff]+)\s*(\{)?$/.exec(s)) ||
+        (m = /^state\s+([\w\u00c0-\uffff]+)\s+as\s+"([^"]*)"\s*(\{)?$/.exec(s))) {
+      var named = /^state\s+"/.test(s) ? state(m[2]) : state(m[1]);
+      named.label = labelLines(/^state\s+"/.test(s) ? m[1] : m[2]);
+      if (m[3]) {
+        clusters[named.id] = { id: named.id, parent: stack[stack.length - 1] || null };
+        clusterOrder.push(named.id);
+        stack.push(named.id);
+      }
+      continue;
+    }
+    if ((m = /^state\s+([\w\u00c0-\uffff]+)\s*(?:<<(\w+)>>)?\s*(\{)?$/.exec(s))) {
+      var st = state(m[1]);
+      if (m[2]) st.kind = m[2];
+      if (m[3]) {
+        clusters[st.id] = { id: st.id, parent: stack[stack.length - 1] || null };
+        clusterOrder.push(st.id);
+        stack.push(st.id);
+      }
+      continue;
+    }
+    if ((m = /^note\s+(left|right)\s+of\s+(\S+)\s*(?::\s*(.*))?$/.exec(s))) {
+      var text = m[3];
+      if (text === undefined) {
+        var body = [];
+        while (++i < lines.length && !/^end\s*note$/.test(lines[i])) body.push(lines[i]);
+        text = body.join('<br>');
+      }
+      var note = { id: '\u0000note' + (notes++), kind: 'note', label: labelLines(text), clusters: stack.slice() };
+      nodes.push(note);
+      byId[note.id] = note;
+      var target = state(m[2]).id;
+      edges.push(m[1] === 'left' ? { from: note.id, to: target, note: true, minlen: 0 } : { from: target, to: note.id, note: true, minlen: 0 });
+      continue;
+    }
+    if ((m = /^(\S+)\s*-->\s*(\S+?)\s*(?::\s*(.*))?$/.exec(s))) {
+      var a = endpoint(m[1], true), b = endpoint(m[2], false);
+      edges.push({ from: a.id, to: b.id, label: m[3] ? labelLines(m[3]) : null });
+      continue;
+    }
+    if ((m = /^([\w\u00c0-\uffff]+)\s*:\s*(.*)$/.exec(s))) {
+      state(m[1]).desc.push(decodeEntities(m[2].trim()));
+      continue;
+    }
+    if ((m = /^([\w\u00c0-\uffff]+)(?::::[\w-]+)?$/.exec(s))) {
+      state(m[1]);
+      continue;
+    }
+    throw new Error('cannot read "' + s + '"');
+  }
+
+  // Composite states become clusters; edges to them go to their start state
+  clusterOrder.forEach(function (c) {
+    var cn = byId[c];
+    clusters[c].title = cn ? cn.label : [c];
+    var inner = null, start = byId['\u0000start:' + stackPath(clusters, c)], end = byId['\u0000end:' + stackPath(clusters, c)];
+    nodes.forEach(function (n) { if (!inner && n.id !== c && n.clusters.indexOf(c) >= 0) inner = n; });
+    if (cn) nodes.splice(nodes.indexOf(cn), 1);
+    delete byId[c];
+    edges.forEach(function (e) {
+      if (e.to === c) e.to = (start || inner || {}).id;
+      if (e.from === c) e.from = (end || inner || {}).id;
+    });
+  });
+  edges = edges.filter(function (e) { return e.from && e.to; });
+  if (!nodes.length) throw new Error('the state diagram has no states');
+
+  var horizontal = dir === 'LR' || dir === 'RL';
+  nodes.forEach(function (n) {
+    switch (n.kind) {
+      case 'start': case 'end':
+        n.w = n.h = 16; n.outline = 'ellipse';
+        break;
+      case 'choice':
+        n.w = n.h = 28; n.outline = 'diamond';
+        break;
+      case 'fork': case 'join':
+        n.w = horizontal ? 8 : 70; n.h = horizontal ? 70 : 8;
+        break;
+      case 'note':
+        var tn = textSize(n.label);
+        n.w = tn.w + 20; n.h = tn.h + 14;
+        break;
+      default:
+        var tl = textSize(n.label);
+        if (n.desc.length) {
+          var td = textSize(n.desc);
+          n.w = Math.max(tl.w, td.w) + 32;
+          n.h = tl.h + td.h + 26;
+        } else {
+          n.w = Math.max(tl.w + 32, 60);
+          n.h = tl.h + 18;
+        }
+    }
+  });
+  var loops = {};
+  edges.forEach(function (e) {
+    if (e.label) {
+      var t = textSize(e.label);
+      e.labelW = t.w + 10;
+      e.labelH = t.h + 4;
+    }
+    if (e.from === e.to) loops[e.from] = Math.max(loops[e.from] || 0, 40 + (e.labelW || 0));
+  });
+  nodes.forEach(function (n) { n.loopSpace = loops[n.id] || 0; });
+  var g = layoutGraph({ dir: dir, nodes: nodes, edges: edges, clusters: clusters });
+
+  var id = 'mmd' + (++diagramCount);
+  var out = '';
+  clusterOrder.slice().sort(function (a, b) { return depth(clusters, a) - depth(clusters, b); }).forEach(function (c) {
+    var box = g.boxes[c];
+    if (!box) return;
+    out += tag('rect', { x: box.x, y: box.y, width: box.w, height: box.h, rx: 8, fill: C.cluster, stroke: C.clusterStroke }) +
+      textBlock(clusters[c].title, box.x + box.w / 2, box.y + 14, { size: 13, color: C.muted }) +
+      line(box.x, box.y + 26, box.x + box.w, box.y + 26, { stroke: C.clusterStroke });
+  });
+  var labels = '';
+  edges.forEach(function (e) {
+    var from = byId[e.from], to = byId[e.to];
+    var st = e.note ? { stroke: C.noteStroke, 'stroke-dasharray': '2 3', 'stroke-width': 1 } :
+      { stroke: C.line, 'stroke-width': 1.5, 'marker-end': 'url(#' + id + '-arrow)' };
+    if (e.from === e.to) {
+      out += selfLoop(from, st, dir);
+      if (e.label) {
+        var lp = selfLoopLabel(from, dir);
+        labels += labelBox(e.label, lp.x + (horizontal ? 0 : e.labelW / 2), lp.y);
+      }
+      return;
+    }
+    out += edgePath(e, from, to, st);
+    if (e.label) labels += labelBox(e.label, e.labelPos.x, e.labelPos.y);
+  });
+  nodes.forEach(function (n) {
+    var x = n.x, y = n.y;
+    switch (n.kind) {
+      case 'start':
+        out += tag('circle', { cx: x, cy: y, r: 7, fill: C.text });
+        break;
+      case 'end':
+        out += tag('circle', { cx: x, cy: y, r: 7.5, fill: C.background, stroke: C.text, 'stroke-width': 1.5 }) +
+          tag('circle', { cx: x, cy: y, r: 4, fill: C.text });
+        break;
+      case 'choice':
+        out += polygon([[x, y - 14], [x + 14, y], [x, y + 14], [x - 14, y]], { fill: C.fill, stroke: C.stroke });
+        break;
+      case 'fork': case 'join':
+        out += tag('rect', { x: x - n.w / 2, y: y - n.h / 2, width: n.w, height: n.h, rx: 2, fill: C.text });
+        break;
+      case 'note':
+        out += tag('rect', { x: x - n.w / 2, y: y - n.h / 2, width: n.w, height: n.h, fill: C.note, stroke: C.noteStroke }) +
+          textBlock(n.label, x, y);
+        break;
+      default:
+        out += tag('rect', { x: x - n.w / 2, y: y - n.h / 2, width: n.w, height: n.h, rx: 8, fill: C.fill, stroke: C.stroke, 'stroke-width': 1.2 });
+        if (n.desc.length) {
+          var lh = textSize(n.label).h;
+          var top = y - n.h / 2;
+          out += textBlock(n.label, x, top + 8 + lh / 2) +
+            line(x - n.w / 2, top + lh + 14, x + n.w / 2, top + lh + 14, { stroke: C.stroke }) +
+            textBlock(n.desc, x - n.w / 2 + 16, top + lh + 20 + textSize(n.desc).h / 2, { anchor: 'start', color: C.muted });
+        } else {
+          out += textBlock(n.label, x, y);
+        }
+    }
+  });
+  return svgDocument(g.width, g.height, out + labels, markerDefs(id));
+}
+
+// stackPath returns the scope key of the composite state c
+function stackPath(clusters, c) {
+  var path = [];
+  for (var k = c; k; k = clusters[k].parent) path.unshift(k);
+  return path.join('/');
+}
+
+/* --- Class diagrams --- */
+
+var reClassName = '([\\w.\\u00c0-\\uffff]+(?:~[^~]+~)?)';
+var reClassRelation = new RegExp('^' + reClassName + '\\s*(?:"([^"]*)"\\s*)?(<\\||\\*|o|<|\\(\\))?(--|\\.\\.)(\\|>|\\*|o|>|\\(\\))?\\s*(?:"([^"]*)"\\s*)?' + reClassName + '\\s*(?::\\s*(.*))?$');
+
+// genericName shows Mermaid's ~T~ generics as <T>
+function genericName(s) {
+  return s.replace(/~([^~]+)~/g, '<$1>');
+}
+
+function renderClass(lines) {
+  var dir = 'TB';
+  var classes = [], byId = {}, edges = [], clusters = {}, clusterOrder = [], stack = [];
+  var notes = 0;
+
+  function cls(name) {
+    var id = name.replace(/~[^~]+~$/, '');
+    var c = byId[id];
+    if (!c) {
+      c = { id: id, title: genericName(name), annotations: [], attrs: [], methods: [], clusters: stack.slice() };
+      byId[id] = c;
+      classes.push(c);
+    } else if (name !== id) {
+      c.title = genericName(name);
+    }
+    return c;
+  }
+  function member(c, text) {
+    text = text.trim();
+    if (!text) return;
+    var a = /^<<(.+)>>$/.exec(text);
+    if (a) { c.annotations.push(a[1]); return; }
+    var m = { text: genericName(text), italic: false, underline: false };
+    if (/\$$/.test(m.text)) { m.underline = true; m.text = m.text.slice(0, -1); }
+    else if (/\*$/.test(m.text)) { m.italic = true; m.text = m.text.slice(0, -1); }
+    (/\(/.test(text) ? c.methods : c.attrs).push(m);
+  }
+
+  for (var i = 0; i < lines.length; i++) {
+    var s = lines[i], m;
+    if (/^classDiagram(-v2)?$/.test(s) || /^(link|click|callback|cssClass|style|classDef)\s/.test(s)) continue;
+    if ((m = /^direction\s+(\w+)/.exec(s))) { dir = m[1]; continue; }
+    if ((m = /^namespace\s+([\w.]+)\s*\{$/.exec(s))) {
+      clusters[m[1]] = { id: m[1], title: [m[1]], parent: stack[stack.length - 1] || null };
+      clusterOrder.push(m[1]);
+      stack.push(m[1]);
+      continue;
+    }
+    if (s === '}') { stack.pop(); continue; }
+    if ((m = new RegExp('^class\\s+' + reClassName + '(?:\\["([^"]*)"\\])?(?::::[\\w-]+)?\\s*(\\{)?\\s*(?:(.*?)\\s*\\})?$').exec(s))) {
+      var c = cls(m[1]);
+      if (m[2]) c.title = m[2];
+      if (m[3] && m[4] !== undefined) {
+        m[4].split(/;/).forEach(function (t) { member(c, t); });
+      } else if (m[3]) {
+        while (++i < lines.length && lines[i] !== '}') member(c, lines[i]);
+      }
+      continue;
+    }
+    if ((m = new RegExp('^<<(.+)>>\\s*' + reClassName + '$').exec(s))) {
+      cls(m[2]).annotations.push(m[1]);
+      continue;
+    }
+    if ((m = /^note\s+for\s+(\S+)\s+"(.*)"$/.exec(s)) || (m = /^note\s+"(.*)"$/.exec(s))) {
+      var note = { id: '\u0000note' + (notes++), note: true, label: labelLines(m[2] !== undefined ? m[2] : m[1]), clusters: stack.slice() };
+      classes.push(note);
+      byId[note.id] = note;
+      if (m[2] !== undefined) edges.push({ from: note.id, to: cls(m[1]).id, note: true });
+      continue;
+    }
+    if ((m = reClassRelation.exec(s))) {
+      var from = cls(m[1]), to = cls(m[7]);
+      edges.push({
+        from: from.id, to: to.id, startHead: m[3] || '', endHead: m[5] || '', dashed: m[4] === '..',
+        fromCard: m[2] || '', toCard: m[6] || '', label: m[8] ? labelLines(m[8]) : null
+      });
+      continue;
+    }
+    if ((m = new RegExp('^' + reClassName + '\\s*:\\s*(.*)$').exec(s))) {
+      member(cls(m[1]), m[2]);
+      continue;
+    }
+    throw new Error('cannot read "' + s + '"');
+  }
+  if (!classes.length) throw new Error('the class diagram has no classes');
+
+  var lh = FONT_SIZE * LINE_HEIGHT;
+  classes.forEach(function (c) {
+    if (c.note) {
+      var tn = textSize(c.label);
+      c.w = tn.w + 20; c.h = tn.h + 14;
+      return;
+    }
+    var w = textWidth(c.title, FONT_SIZE, true);
+    c.annotations.forEach(function (a) { w = Math.max(w, textWidth('\u00ab' + a + '\u00bb', 12)); });
+    c.attrs.concat(c.methods).forEach(function (mb) { w = Math.max(w, textWidth(mb.text)); });
+    c.headH = lh * (1 + c.annotations.length) + 12;
+    c.attrH = c.attrs.length ? c.attrs.length * lh + 10 : 10;
+    c.methodH = c.methods.length ? c.methods.length * lh + 10 : 10;
+    c.w = Math.max(w + 24, 80);
+    c.h = c.headH + c.attrH + c.methodH;
+  });
+  edges.forEach(function (e) {
+    if (e.label) {
+      var t = textSize(e.label);
+      e.labelW = t.w + 10;
+      e.labelH = t.h + 4;
+    }
+  });
+  var g = layoutGraph({ dir: dir, nodes: classes, edges: edges, clusters: clusters, nodeSep: 50, rankSep: 60 });
+
+  var id = 'mmd' + (++diagramCount);
+  var heads = { '<|': 'triangle', '|>': 'triangle', '*': 'diamond', 'o': 'odiamond', '<': 'open', '>': 'open', '()': 'circle' };
+  var out = '';
+  clusterOrder.forEach(function (c) {
+    var box = g.boxes[c];
+    if (!box) return;
+    out += tag('rect', { x: box.x, y: box.y, width: box.w, height: box.h, rx: 6, fill: C.cluster, stroke: C.clusterStroke }) +
+      textBlock(clusters[c].title, box.x + box.w / 2, box.y + 14, { size: 13, color: C.muted });
+  });
+  var labels = '';
+  edges.forEach(function (e) {
+    if (e.from === e.to) return;
+    var from = byId[e.from], to = byId[e.to];
+    var st = { stroke: C.line, 'stroke-width': 1.3 };
+    if (e.dashed || e.note) st['stroke-dasharray'] = '4 3';
+    if (e.startHead) st['marker-start'] = 'url(#' + id + '-' + heads[e.startHead] + ')';
+    if (e.endHead) st['marker-end'] = 'url(#' + id + '-' + heads[e.endHead] + ')';
+    out += edgePath(e, from, to, st);
+    if (e.label) labels += labelBox(e.label, e.labelPos.x, e.labelPos.y);
+    [[e.fromCard, 0, 1], [e.toCard, e.drawn.length - 1, e.drawn.length - 2]].forEach(function (card) {
+      if (!card[0]) return;
+      var p = e.drawn[card[1]], q = e.drawn[card[2]];
+      var dx = q.x - p.x, dy = q.y - p.y, len = Math.sqrt(dx * dx + dy * dy) || 1;
+      labels += textBlock([card[0]], p.x + dx / len * 18 - dy / len * 12, p.y + dy / len * 18 + dx / len * 12, { size: 12, color: C.muted });
+    });
+  });
+  classes.forEach(function (c) {
+    var x = c.x - c.w / 2, y = c.y - c.h / 2;
+    if (c.note) {
+      out += tag('rect', { x: x, y: y, width: c.w, height: c.h, fill: C.note, stroke: C.noteStroke }) + textBlock(c.label, c.x, c.y);
+      return;
+    }
+    out += tag('rect', { x: x, y: y, width: c.w, height: c.h, rx: 3, fill: C.fill, stroke: C.stroke, 'stroke-width': 1.2 });
+    var ty = y + 6 + lh / 2;
+    c.annotations.forEach(function (a) {
+      out += textBlock(['\u00ab' + a + '\u00bb'], c.x, ty, { size: 12, color: C.muted });
+      ty += lh;
+    });
+    out += textBlock([c.title], c.x, ty, { bold: true });
+    var sy = y + c.headH;
+    out += line(x, sy, x + c.w, sy, { stroke: C.stroke });
+    c.attrs.forEach(function (a, k) {
+      out += textBlock([a.text], x + 10, sy + 5 + lh * (k + 0.5), { anchor: 'start', italic: a.italic, underline: a.underline });
+    });
+    sy += c.attrH;
+    out += line(x, sy, x + c.w, sy, { stroke: C.stroke });
+    c.methods.forEach(function (a, k) {
+      out += textBlock([a.text], x + 10, sy + 5 + lh * (k + 0.5), { anchor: 'start', italic: a.italic, underline: a.underline });
+    });
+  });
+  return svgDocument(g.width, g.height, out + labels, markerDefs(id));
+}
+
+/* --- Sequence diagrams --- */
+
+var reMessage = /^([^-<>+:]+?)\s*(<<)?(--?)(>>|>|x|\))([+-]?)\s*([^:]+?)\s*(?::(.*))?$/;
+
+function renderSequence(lines) {
+  var actors = [], byId = {}, items = [], title = '', autonumber = false;
+  function actor(id, label, kind) {
+    var a = byId[id];
+    if (!a) {
+      a = { id: id, label: labelLines(label || id), kind: kind || 'participant', idx: actors.length };
+      byId[id] = a;
+      actors.push(a);
+    } else if (label) {
+      a.label = labelLines(label);
+    }
+    if (kind) a.kind = kind;
+    return a;
+  }
+
+  var counter = 0;
+  lines.forEach(function (s) {
+    var m;
+    if (s === 'sequenceDiagram') return;
+    if ((m = /^title\s*:?\s*(.*)$/.exec(s))) { title = m[1]; return; }
+    if (/^autonumber\b/.test(s)) { autonumber = true; return; }
+    if ((m = /^(?:create\s+)?(participant|actor)\s+(.+?)(?:\s+as\s+(.+))?$/.exec(s))) {
+      actor(m[2].trim(), m[3], m[1]);
+      return;
+    }
+    if (/^(destroy|box|links?|properties|details)\b/.test(s)) {
+      if (/^box\b/.test(s)) items.push({ type: 'box' });
+      return;
+    }
+    if ((m = /^(activate|deactivate)\s+(.+)$/.exec(s))) {
+      items.push({ type: m[1], actor: actor(m[2].trim()) });
+      return;
+    }
+    if ((m = /^note\s+(left of|right of|over)\s+([^:]+?)\s*:\s*(.*)$/i.exec(s))) {
+      items.push({ type: 'note', pos: m[1].toLowerCase().split(' ')[0], actors: m[2].split(',').map(function (a) { return actor(a.trim()); }), label: labelLines(m[3]) });
+      return;
+    }
+    if ((m = /^(loop|alt|opt|par|critical|break|rect)\b\s*(.*)$/.exec(s))) {
+      items.push({ type: 'start', kind: m[1], label: m[1] === 'rect' ? '' : decodeEntities(m[2]), color: m[1] === 'rect' ? m[2] : '' });
+      return;
+    }
+    if ((m = /^(else|and|option)\b\s*(.*)$/.exec(s))) {
+      items.push({ type: 'section', label: decodeEntities(m[2]) });
+      return;
+    }
+    if (s === 'end') { items.push({ type: 'end' }); return; }
+    if ((m = reMessage.exec(s))) {
+      var from = actor(m[1].trim()), to = actor(m[6].trim());
+      var heads = { '>>': 'arrow', '>': '', 'x': 'cross', ')': 'open' };
+      items.push({
+        type: 'message', from: from, to: to, dotted: m[3] === '--', head: heads[m[4]], both: !!m[2],
+        activate: m[5] === '+', deactivate: m[5] === '-', label: labelLines(m[7] || ''),
+        num: autonumber ? ++counter : 0
+      });
+      return;
+    }
+    throw new Error('cannot read "' + s + '"');
+  });
+  if (!actors.length) throw new Error('the sequence diagram has no participants');
+
+  // Participant widths and the space between them
+  var lh = FONT_SIZE * LINE_HEIGHT;
+  actors.forEach(function (a) {
+    var t = textSize(a.label);
+    a.w = Math.max(t.w + 30, 90);
+    a.h = a.kind === 'actor' ? 48 + t.h : Math.max(t.h + 22, 40);
+  });
+  var headH = 0;
+  actors.forEach(function (a) { headH = Math.max(headH, a.h); });
+  var gaps = [];
+  for (var k = 0; k + 1 < actors.length; k++) gaps.push((actors[k].w + actors[k + 1].w) / 2 + 30);
+  var needs = [];
+  var rightExtra = 0, leftExtra = 0;
+  items.forEach(function (it) {
+    if (it.type === 'message') {
+      var w = textSize(it.label).w + 30 + (it.num ? 20 : 0);
+      if (it.from === it.to) {
+        if (it.from.idx < gaps.length) needs.push([it.from.idx, it.from.idx + 1, w + 40]);
+        else rightExtra = Math.max(rightExtra, w + 40);
+      } else {
+        needs.push([Math.min(it.from.idx, it.to.idx), Math.max(it.from.idx, it.to.idx), w]);
+      }
+    } else if (it.type === 'note') {
+      var nw = textSize(it.label).w + 24;
+      var a = it.actors[0];
+      if (it.pos === 'right') {
+        if (a.idx < gaps.length) needs.push([a.idx, a.idx + 1, nw + 20]);
+        else rightExtra = Math.max(rightExtra, nw + 10);
+      } else if (it.pos === 'left') {
+        if (a.idx > 0) needs.push([a.idx - 1, a.idx, nw + 20]);
+        else leftExtra = Math.max(leftExtra, nw + 10);
+      } else if (it.actors.length === 1) {
+        if (a.idx > 0) needs.push([a.idx - 1, a.idx, nw / 2 + actors[a.idx - 1].w / 2 + 10]);
+        else leftExtra = Math.max(leftExtra, nw / 2 - a.w / 2);
+        if (a.idx < gaps.length) needs.push([a.idx, a.idx + 1, nw / 2 + actors[a.idx + 1].w / 2 + 10]);
+        else rightExtra = Math.max(rightExtra, nw / 2 - a.w / 2);
+      }
+    }
+  });
+  needs.sort(function (x, y) { return (x[1] - x[0]) - (y[1] - y[0]); });
+  needs.forEach(function (n) {
+    var have = 0;
+    for (var g = n[0]; g < n[1]; g++) have += gaps[g];
+    if (have < n[2]) {
+      for (var g2 = n[0]; g2 < n[1]; g2++) gaps[g2] += (n[2] - have) / (n[1] - n[0]);
+    }
+  });
+  var x = 20 + Math.max(leftExtra, 0) + actors[0].w / 2;
+  actors.forEach(function (a, i) {
+    if (i > 0) x += gaps[i - 1];
+    a.x = x;
+  });
+
+  var id = 'mmd' + (++diagramCount);
+  var back = '', front = '';
+  var titleH = title ? 34 : 0;
+  var top = 10 + titleH;
+  var y = top + headH + 20;
+  var minX = 0, maxX = x + actors[actors.length - 1].w / 2 + Math.max(rightExtra, 0) + 20;
+  var frames = [], active = {};
+
+  function extendFrames(x1, x2) {
+    frames.forEach(function (f) {
+      f.x1 = Math.min(f.x1, x1);
+      f.x2 = Math.max(f.x2, x2);
+    });
+  }
+  function activeOffset(a, toward) {
+    var depthA = (active[a.id] || []).length;
+    return depthA ? 5 + (depthA - 1) * 4 : 0;
+  }
+
+  items.forEach(function (it) {
+    switch (it.type) {
+      case 'message':
+        var a = it.from, b = it.to;
+        var labelH = it.label.join('') ? it.label.length * lh : 0;
+        if (a === b) {
+          y += labelH + 6;
+          var sx = a.x + activeOffset(a);
+          var d = 'M' + num(sx) + ',' + num(y) + ' C' + num(sx + 50) + ',' + num(y - 6) + ' ' + num(sx + 50) + ',' + num(y + 36) + ' ' + num(sx + 2) + ',' + num(y + 30);
+          var st = { d: d, fill: 'none', stroke: C.text, 'stroke-width': 1.3, 'stroke-dasharray': it.dotted ? '4 3' : '' };
+          if (it.head) st['marker-end'] = 'url(#' + id + '-' + it.head + ')';
+          front += tag('path', st);
+          if (labelH) front += textBlock(it.label, sx + 10, y - 4 - labelH / 2, { anchor: 'start' });
+          extendFrames(a.x - a.w / 2, a.x + 60 + textSize(it.label).w);
+          if (it.num) front += numberBadge(sx, y, it.num);
+          y += 44;
+        } else {
+          y += labelH + 6;
+          var dirn = b.x > a.x ? 1 : -1;
+          var x1 = a.x + dirn * activeOffset(a);
+          if (it.deactivate) x1 = a.x;
+          var x2 = b.x - dirn * (activeOffset(b) + (it.activate ? 5 : 0));
+          var ls = { stroke: C.text, 'stroke-width': 1.3, 'stroke-dasharray': it.dotted ? '4 3' : '' };
+          if (it.head) ls['marker-end'] = 'url(#' + id + '-' + it.head + ')';
+          if (it.both && it.head) ls['marker-start'] = 'url(#' + id + '-' + it.head + ')';
+          front += line(x1, y, x2, y, ls);
+          if (labelH) front += textBlock(it.label, (a.x + b.x) / 2, y - 4 - labelH / 2);
+          if (it.num) front += numberBadge(x1, y, it.num);
+          extendFrames(Math.min(a.x - a.w / 2, b.x - b.w / 2), Math.max(a.x + a.w / 2, b.x + b.w / 2));
+          y += 14;
+        }
+        if (it.activate) (active[b.id] = active[b.id] || []).push(y - 14);
+        if (it.deactivate) back += closeActivation(a, y - 14);
+        break;
+      case 'activate':
+        (active[it.actor.id] = active[it.actor.id] || []).push(y - 8);
+        break;
+      case 'deactivate':
+        back += closeActivation(it.actor, y - 8);
+        break;
+      case 'note':
+        var nt = textSize(it.label);
+        var nw = nt.w + 24, nh = nt.h + 14, nx;
+        var first = it.actors[0], last = it.actors[it.actors.length - 1];
+        y += 6;
+        if (it.pos === 'right') nx = first.x + 10;
+        else if (it.pos === 'left') nx = first.x - 10 - nw;
+        else {
+          var lo = Math.min(first.x, last.x), hi = Math.max(first.x, last.x);
+          if (first !== last) nw = Math.max(nw, hi - lo + 50);
+          nx = (lo + hi) / 2 - nw / 2;
+        }
+        front += tag('rect', { x: nx, y: y, width: nw, height: nh, fill: C.note, stroke: C.noteStroke, 'stroke-width': 1 }) +
+          textBlock(it.label, nx + nw / 2, y + nh / 2);
+        minX = Math.min(minX, nx - 10);
+        maxX = Math.max(maxX, nx + nw + 10);
+        extendFrames(nx, nx + nw);
+        y += nh + 12;
+        break;
+      case 'start':
+        y += 10;
+        frames.push({ kind: it.kind, label: it.label, color: it.color, y: y, x1: Infinity, x2: -Infinity, sections: [], inner: 0 });
+        y += it.kind === 'rect' ? 8 : 28;
+        break;
+      case 'section':
+        var f = frames[frames.length - 1];
+        if (f) {
+          y += 6;
+          f.sections.push({ y: y, label: it.label });
+          y += 24;
+        }
+        break;
+      case 'box':
+        frames.push({ kind: 'box', skip: true, y: y, x1: Infinity, x2: -Infinity, sections: [], inner: 0 });
+        break;
+      case 'end':
+        var fr = frames.pop();
+        if (!fr || fr.skip) break;
+        y += 6;
+        if (fr.x1 === Infinity) {
+          fr.x1 = actors[0].x - actors[0].w / 2;
+          fr.x2 = actors[actors.length - 1].x + actors[actors.length - 1].w / 2;
+        }
+        var pad = 8 + 8 * fr.inner;
+        var fx = fr.x1 - pad, fw = fr.x2 - fr.x1 + 2 * pad;
+        if (fr.kind === 'rect') {
+          back = tag('rect', { x: fx, y: fr.y, width: fw, height: y - fr.y, fill: fr.color || 'rgba(0,113,227,0.06)' }) + back;
+        } else {
+          front += frameHTML(fr, fx, fw, y);
+        }
+        var parent = frames[frames.length - 1];
+        if (parent) {
+          parent.x1 = Math.min(parent.x1, fr.x1);
+          parent.x2 = Math.max(parent.x2, fr.x2);
+          parent.inner = Math.max(parent.inner, fr.inner + 1);
+        }
+        minX = Math.min(minX, fx - 6);
+        maxX = Math.max(maxX, fx + fw + 6);
+        y += 8;
+        break;
+    }
+  });
+  Object.keys(active).forEach(function (aid) {
+    while (active[aid].length) back += closeActivation(byId[aid], y);
+  });
+  function closeActivation(a, endY) {
+    var stack = active[a.id];
+    if (!stack || !stack.length) return '';
+    var startY = stack.pop();
+    var ox = stack.length * 4;
+    return tag('rect', { x: a.x - 5 + ox, y: startY, width: 10, height: Math.max(endY - startY, 8), fill: C.fill, stroke: C.stroke });
+  }
+
+  y += 12;
+  var lifelines = '', heads = '';
+  actors.forEach(function (a) {
+    lifelines += line(a.x, top + headH, a.x, y, { stroke: C.stroke, 'stroke-dasharray': '3 3' });
+    heads += actorShape(a, top + headH - a.h, a.h) + actorShape(a, y, a.h);
+  });
+  var height = y + headH + 12;
+  var shift = 20 - Math.min(minX, 20);
+  var titleSVG = title ? textBlock([decodeEntities(title)], (maxX + shift) / 2, 10 + titleH / 2, { bold: true, size: 16 }) : '';
+  var bodySVG = tag('g', { transform: shift ? 'translate(' + num(shift) + ',0)' : '' }, lifelines + back + heads + front);
+  return svgDocument(maxX + shift, height, titleSVG + bodySVG, markerDefs(id));
+}
+
+function numberBadge(x, y, n) {
+  return tag('circle', { cx: x, cy: y, r: 8, fill: C.text }) + textBlock([String(n)], x, y, { size: 10, color: C.background });
+}
+
+// frameHTML draws a loop, alt or other block with its label tab and sections
+function frameHTML(f, x, w, bottom) {
+  var kindW = textWidth(f.kind, 12, true) + 16;
+  var out = tag('rect', { x: x, y: f.y, width: w, height: bottom - f.y, fill: 'none', stroke: C.line, 'stroke-width': 1 });
+  out += polygon([[x, f.y], [x + kindW, f.y], [x + kindW, f.y + 12], [x + kindW - 6, f.y + 20], [x, f.y + 20]], { fill: C.fill, stroke: C.line, 'stroke-width': 1 });
+  out += textBlock([f.kind], x + 8, f.y + 10, { anchor: 'start', size: 12, bold: true });
+  if (f.label) out += textBlock(['[' + f.label + ']'], x + kindW + 10, f.y + 10, { anchor: 'start', size: 12, color: C.muted });
+  f.sections.forEach(function (s) {
+    out += line(x, s.y, x + w, s.y, { stroke: C.line, 'stroke-dasharray': '4 3' });
+    if (s.label) out += textBlock(['[' + s.label + ']'], x + 10, s.y + 11, { anchor: 'start', size: 12, color: C.muted });
+  });
+  return out;
+}
+
+// actorShape draws a participant box or a stick figure with its name
+function actorShape(a, y, h) {
+  if (a.kind === 'actor') {
+    var cx = a.x, t = y + 2;
+    return tag('g', { stroke: C.text, 'stroke-width': 1.5, fill: 'none' },
+      tag('circle', { cx: cx, cy: t + 8, r: 7 }) +
+      line(cx, t + 15, cx, t + 30, {}) +
+      line(cx - 11, t + 20, cx + 11, t + 20, {}) +
+      line(cx, t + 30, cx - 9, t + 42, {}) +
+      line(cx, t + 30, cx + 9, t + 42, {})) +
+      textBlock(a.label, cx, t + 46 + textSize(a.label).h / 2);
+  }
+  return tag('rect', { x: a.x - a.w / 2, y: y, width: a.w, height: h, rx: 4, fill: C.fill, stroke: C.stroke, 'stroke-width': 1.2 }) +
+    textBlock(a.label, a.x, y + h / 2);
+}
+
+/* --- Gantt charts --- */
+
+var DAY = 86400000;
+var MONTHS = ['January', 'February', 'March', 'April', 'May', 'June', 'July', 'August', 'September', 'October', 'November', 'December'];
+var WEEKDAYS = ['Sunday', 'Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday'];
+
+// dateParser returns a function reading dates in a Mermaid (dayjs) format
+function dateParser(format) {
+  var fields = [];
+  var pattern = format.replace(/[.*+?^${}()|[\]\\]/g, '\\$&').replace(/YYYY|YY|MM|M|DD|D|HH|H|mm|m|ss|s|X|x/g, function (tok) {
+    fields.push(tok);
+    return tok === 'X' || tok === 'x' ? '(\\d+)' : tok.length === 4 ? '(\\d{4})' : '(\\d{1,2})';
+  });
+  var re = new RegExp('^' + pattern + '$');
+  return function (s) {
+    var m = re.exec(s.trim());
+    if (!m) {
+      var t = Date.parse(s);
+      return isNaN(t) ? null : t;
+    }
+    var d = { Y: 1970, M: 0, D: 1, H: 0, m: 0, s: 0 };
+    for (var i = 0; i < fields.length; i++) {
+      var v = parseInt(m[i + 1], 10);
+      switch (fields[i]) {
+        case 'X': return v * 1000;
+        case 'x': return v;
+        case 'YYYY': d.Y = v; break;
+        case 'YY': d.Y = 2000 + v; break;
+        case 'MM': case 'M': d.M = v - 1; break;
+        case 'DD': case 'D': d.D = v; break;
+        case 'HH': case 'H': d.H = v; break;
+        case 'mm': case 'm': d.m = v; break;
+        case 'ss': case 's': d.s = v; break;
+      }
+    }
+    return Date.UTC(d.Y, d.M, d.D, d.H, d.m, d.s);
+  };
+}
+
+// parseDuration reads durations such as 3d, 12h or 2w in milliseconds
+function parseDuration(s) {
+  var m = /^(\d+(?:\.\d+)?)\s*(ms|s|m|h|d|w|M|y)$/.exec(s.trim());
+  if (!m) return null;
+  var unit = { ms: 1, s: 1000, m: 60000, h: 3600000, d: DAY, w: 7 * DAY, M: 30 * DAY, y: 365 * DAY }[m[2]];
+  return parseFloat(m[1]) * unit;
+}
+
+// strftime formats a UTC timestamp with the d3 time format directives
+function strftime(t, format) {
+  var d = new Date(t);
+  function pad(n) { return n < 10 ? '0' + n : String(n); }
+  return format.replace(/%([a-zA-Z%])/g, function (m, c) {
+    switch (c) {
+      case 'Y': return String(d.getUTCFullYear());
+      case 'y': return pad(d.getUTCFullYear() % 100);
+      case 'm': return pad(d.getUTCMonth() + 1);
+      case 'd': return pad(d.getUTCDate());
+      case 'e': return String(d.getUTCDate());
+      case 'b': return MONTHS[d.getUTCMonth()].slice(0, 3);
+      case 'B': return MONTHS[d.getUTCMonth()];
+      case 'a': return WEEKDAYS[d.getUTCDay()].slice(0, 3);
+      case 'A': return WEEKDAYS[d.getUTCDay()];
+      case 'H': return pad(d.getUTCHours());
+      case 'I': return pad(d.getUTCHours() % 12 || 12);
+      case 'M': return pad(d.getUTCMinutes());
+      case 'S': return pad(d.getUTCSeconds());
+      case 'p': return d.getUTCHours() < 12 ? 'AM' : 'PM';
+      case '%': return '%';
+    }
+    return m;
+  });
+}
+
+// ganttTicks returns evenly spaced axis positions between min and max
+function ganttTicks(min, max) {
+  var steps = [3600000, 3 * 3600000, 6 * 3600000, 12 * 3600000, DAY, 2 * DAY, 7 * DAY, 14 * DAY];
+  var ticks = [], t;
+  for (var i = 0; i < steps.length; i++) {
+    if ((max - min) / steps[i] <= 12) {
+      var step = steps[i];
+      t = Math.ceil(min / step) * step;
+      if (step === 7 * DAY || step === 14 * DAY) {
+        // Weeks start on Monday
+        t = Math.floor(min / DAY) * DAY;
+        while (new Date(t).getUTCDay() !== 1) t += DAY;
+      }
+      for (; t <= max; t += step) ticks.push(t);
+      return ticks;
+    }
+  }
+  var months = [1, 3, 6, 12];
+  for (var k = 0; k < months.length; k++) {
+    var d = new Date(min);
+    var y = d.getUTCFullYear(), mo = d.getUTCMonth() + 1;
+    mo = Math.ceil(mo / months[k]) * months[k];
+    ticks = [];
+    for (t = Date.UTC(y, mo, 1); t <= max; mo += months[k], t = Date.UTC(y, mo, 1)) ticks.push(t);
+    if (ticks.length <= 12) return ticks;
+  }
+  return ticks;
+}
+
+function renderGantt(lines) {
+  var title = '', parseDate = dateParser('YYYY-MM-DD'), axisFormat = '', sections = [], tasks = [], byId = {};
+  var section = null;
+  lines.forEach(function (s) {
+    var m;
+    if (s === 'gantt') return;
+    if ((m = /^title\s+(.*)$/.exec(s))) { title = m[1]; return; }
+    if ((m = /^dateFormat\s+(.*)$/.exec(s))) { parseDate = dateParser(m[1].trim()); return; }
+    if ((m = /^axisFormat\s+(.*)$/.exec(s))) { axisFormat = m[1].trim(); return; }
+    if (/^(excludes|includes|todayMarker|tickInterval|weekday|inclusiveEndDates|topAxis|displayMode|click|accTitle|accDescr)\b/.test(s)) return;
+    if ((m = /^section\s+(.*)$/.exec(s))) {
+      section = { name: m[1], tasks: [] };
+      sections.push(section);
+      return;
+    }
+    if ((m = /^([^:]+?)\s*:\s*(.*)$/.exec(s))) {
+      if (!section) {
+        section = { name: '', tasks: [] };
+        sections.push(section);
+      }
+      var parts = m[2].split(',').map(function (x) { return x.trim(); }).filter(function (x) { return x !== ''; });
+      var task = { name: m[1], tags: {}, idx: tasks.length, section: section };
+      while (parts.length && /^(done|active|crit|milestone)$/.test(parts[0])) task.tags[parts.shift()] = true;
+      if (parts.length >= 3) { task.id = parts[0]; task.startSpec = parts[1]; task.endSpec = parts[2]; }
+      else if (parts.length === 2) { task.startSpec = parts[0]; task.endSpec = parts[1]; }
+      else if (parts.length === 1) { task.endSpec = parts[0]; }
+      if (task.id) byId[task.id] = task;
+      section.tasks.push(task);
+      tasks.push(task);
+      return;
+    }
+    throw new Error('cannot read "' + s + '"');
+  });
+  if (!tasks.length) throw new Error('the gantt chart has no tasks');
+
+  // Resolve start and end times; tasks may refer to others by id
+  function resolve(task, prev) {
+    var start;
+    if (!task.startSpec) {
+      if (!prev) throw new Error('the first task "' + task.name + '" needs a start date');
+      if (prev.end === undefined) return false;
+      start = prev.end;
+    } else if (/^after\s/.test(task.startSpec)) {
+      var refs = task.startSpec.slice(6).trim().split(/\s+/);
+      start = -Infinity;
+      for (var i = 0; i < refs.length; i++) {
+        var r = byId[refs[i]];
+        if (!r) throw new Error('unknown task "' + refs[i] + '"');
+        if (r.end === undefined) return false;
+        start = Math.max(start, r.end);
+      }
+    } else {
+      start = parseDate(task.startSpec);
+      if (start === null) throw new Error('cannot read date "' + task.startSpec + '"');
+    }
+    var end, dur = parseDuration(task.endSpec || '');
+    if (dur !== null) end = start + dur;
+    else if (/^until\s/.test(task.endSpec)) {
+      end = Infinity;
+      var until = task.endSpec.slice(6).trim().split(/\s+/);
+      for (var j = 0; j < until.length; j++) {
+        var u = byId[until[j]];
+        if (!u) throw new Error('unknown task "' + until[j] + '"');
+        if (u.start === undefined) return false;
+        end = Math.min(end, u.start);
+      }
+    } else {
+      end = parseDate(task.endSpec || '');
+      if (end === null) throw new Error('cannot read end "' + task.endSpec + '"');
+    }
+    task.start = start;
+    task.end = Math.max(end, start);
+    return true;
+  }
+  for (var pass = 0, left = tasks.length; left > 0; pass++) {
+    if (pass > tasks.length) throw new Error('task dependencies form a cycle');
+    left = 0;
+    tasks.forEach(function (t, i) {
+      if (t.start === undefined && !resolve(t, tasks[i - 1])) left++;
+    });
+  }
+
+  var min = Infinity, max = -Infinity;
+  tasks.forEach(function (t) { min = Math.min(min, t.start); max = Math.max(max, t.end); });
+  if (max === min) max = min + DAY;
+  var ticks = ganttTicks(min, max);
+  if (!axisFormat) axisFormat = max - min <= 2 * DAY ? '%H:%M' : '%Y-%m-%d';
+
+  var labelW = 0;
+  sections.forEach(function (s) { labelW = Math.max(labelW, textWidth(s.name, 13, true)); });
+  labelW = labelW ? labelW + 24 : 12;
+  var chartW = 720, rowH = 30, barH = 20;
+  var titleH = title ? 36 : 8;
+  var width = labelW + chartW + 20;
+  function xOf(t) { return labelW + (t - min) / (max - min) * chartW; }
+
+  var out = '', grid = '', y = titleH;
+  var rows = tasks.length * rowH;
+  ticks.forEach(function (t) {
+    var x = xOf(t);
+    grid += line(x, titleH, x, titleH + rows, { stroke: C.clusterStroke, 'stroke-width': 1 }) +
+      textBlock([strftime(t, axisFormat)], x, titleH + rows + 14, { size: 11, color: C.muted });
+  });
+  sections.forEach(function (s, si) {
+    if (!s.tasks.length) return;
+    var h = s.tasks.length * rowH;
+    out += tag('rect', { x: 0, y: y, width: width, height: h, fill: si % 2 ? C.background : C.fill });
+    if (s.name) out += textBlock([s.name], 10, y + h / 2, { anchor: 'start', size: 13, bold: true });
+    y += h;
+  });
+  out += grid;
+  tasks.forEach(function (t, i) {
+    var ty = titleH + i * rowH + rowH / 2;
+    var fill = C.task, stroke = C.accent;
+    if (t.tags.active) fill = C.taskActive;
+    if (t.tags.done) { fill = C.taskDone; stroke = C.stroke; }
+    if (t.tags.crit) { stroke = C.crit; if (!t.tags.done) fill = C.critFill; }
+    var x1 = xOf(t.start), x2 = xOf(t.end);
+    if (t.tags.milestone) {
+      var mx = x1, r = barH / 2;
+      out += polygon([[mx, ty - r], [mx + r, ty], [mx, ty + r], [mx - r, ty]], { fill: stroke, stroke: stroke });
+      if (mx + r + 6 + textWidth(t.name, 12) < width) out += textBlock([t.name], mx + r + 6, ty, { anchor: 'start', size: 12 });
+      else out += textBlock([t.name], mx - r - 6, ty, { anchor: 'end', size: 12 });
+      return;
+    }
+    out += tag('rect', { x: x1, y: ty - barH / 2, width: Math.max(x2 - x1, 2), height: barH, rx: 3, fill: fill, stroke: stroke, 'stroke-width': 1 });
+    var tw = textWidth(t.name, 12);
+    if (tw + 10 < x2 - x1) out += textBlock([t.name], (x1 + x2) / 2, ty, { size: 12 });
+    else if (x2 + 6 + tw < width) out += textBlock([t.name], x2 + 6, ty, { anchor: 'start', size: 12 });
+    else out += textBlock([t.name], x1 - 6, ty, { anchor: 'end', size: 12 });
+  });
+  if (title) out += textBlock([decodeEntities(title)], width / 2, 18, { bold: true, size: 16 });
+  return svgDocument(width, titleH + rows + 30, out);
+}
+
+/* --- Entry points --- */
+
+// render returns the SVG for a Mermaid diagram, throwing on syntax it
+// doesn't understand
+function render(src) {
+  var doc = preprocess(src);
+  if (!doc.lines.length) throw new Error('the diagram is empty');
+  var head = doc.lines[0].split(/\s+/)[0];
+  var svg;
+  if (head === 'graph' || head === 'flowchart' || head === 'flowchart-elk') svg = renderFlowchart(doc.lines);
+  else if (head === 'sequenceDiagram') svg = renderSequence(doc.lines);
+  else if (head === 'stateDiagram' || head === 'stateDiagram-v2') svg = renderState(doc.lines);
+  else if (head === 'classDiagram' || head === 'classDiagram-v2') svg = renderClass(doc.lines);
+  else if (head === 'gantt') svg = renderGantt(doc.lines);
+  else throw new Error('"' + head + '" diagrams are not supported');
+  if (doc.title) svg = '<div class="mermaid-title">' + esc(doc.title) + '</div>' + svg;
+  return svg;
+}
+
+// renderAll replaces each <pre class="mermaid"> under root with its diagram
+function renderAll(root) {
+  themeColors();
+  (root || document).querySelectorAll('pre.mermaid').forEach(function (pre) {
+    if (pre.getAttribute('data-rendered')) return;
+    pre.setAttribute('data-rendered', 'true');
+    var out = document.createElement('div');
+    try {
+      out.className = 'mermaid-diagram';
+      out.innerHTML = render(pre.textContent);
+      pre.hidden = true;
+    } catch (e) {
+      out.className = 'mermaid-error';
+      out.textContent = 'Diagram not rendered: ' + e.message;
+    }
+    pre.parentNode.insertBefore(out, pre);
+  });
+}
+
+var api = { render: render, renderAll: renderAll };
+if (typeof window !== 'undefined') window.asterMermaid = api;
+else if (typeof globalThis !== 'undefined') globalThis.asterMermaid = api;
+
+if (typeof document !== 'undefined' && document.querySelectorAll) {
+  if (document.readyState === 'loading') document.addEventListener('DOMContentLoaded', function () { renderAll(); });
+  else renderAll();
+  // Redraw in the new colors when the page theme changes
+  document.addEventListener('aster:theme', function () {
+    document.querySelectorAll('pre.mermaid[data-rendered]').forEach(function (pre) {
+      var prev = pre.previousSibling;
+      if (prev && (prev.className === 'mermaid-diagram' || prev.className === 'mermaid-error')) prev.remove();
+      pre.removeAttribute('data-rendered');
+      pre.hidden = false;
+    });
+    renderAll();
+  });
+}
+})();
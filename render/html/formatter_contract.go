@@ -0,0 +1,43 @@
+package html
+
+import (
+	"strings"
+
+	"github.com/wildreason/reader/parse/markdown"
+)
+
+// renderClauseBodyHTML converts clause body markdown to HTML
+func renderClauseBodyHTML(body string) string {
+	r := markdownRenderer(0, nil)
+	override := r.Override
+	r.Override = func(w *markdown.HTMLWriter, n *markdown.Node) bool {
+		// A paragraph that is entirely bold is a sub-heading
+		if n.Kind == markdown.Paragraph && n.Parent.Kind == markdown.Document &&
+			n.FirstChild != nil && n.FirstChild == n.LastChild && n.FirstChild.Kind == markdown.Strong {
+			w.CR()
+			w.Raw("<h4 class=\"clause-subheading\">")
+			w.Children(n.FirstChild)
+			w.Raw("</h4>\n")
+			return true
+		}
+		return override(w, n)
+	}
+	return r.Render(markdown.Parse(strings.TrimSpace(body)))
+}
+
+// renderPreambleHTML converts preamble markdown to HTML
+func renderPreambleHTML(preamble string) string {
+	r := markdownRenderer(0, nil)
+	override := r.Override
+	r.Override = func(w *markdown.HTMLWriter, n *markdown.Node) bool {
+		if n.Kind == markdown.Heading && n.Level == 1 {
+			w.CR()
+			w.Raw("<h1 class=\"contract-title\">")
+			w.Children(n)
+			w.Raw("</h1>\n")
+			return true
+		}
+		return override(w, n)
+	}
+	return r.Render(markdown.Parse(preamble))
+}
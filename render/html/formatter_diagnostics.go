@@ -0,0 +1,35 @@
+package html
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+)
+
+// diagnosticsPanelHTML renders parse diagnostics as a collapsible panel.
+// The panel starts open when there are errors.
+func diagnosticsPanelHTML(diags []parse.Diagnostic) string {
+	if len(diags) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	if parse.HasErrors(diags) {
+		sb.WriteString("<details class=\"diagnostics has-errors\" open>\n")
+	} else {
+		sb.WriteString("<details class=\"diagnostics\">\n")
+	}
+	sb.WriteString(fmt.Sprintf("<summary>%s</summary>\n", html.EscapeString(parse.SummarizeDiagnostics(diags))))
+	sb.WriteString("<ul>\n")
+	for _, d := range diags {
+		sb.WriteString(fmt.Sprintf("<li class=\"diag-%s\">", d.Severity))
+		if pos := d.Position(); pos != "" {
+			sb.WriteString(fmt.Sprintf("<span class=\"diag-pos\">%s</span> ", pos))
+		}
+		sb.WriteString(fmt.Sprintf("<span class=\"diag-severity\">%s</span> %s</li>\n", d.Severity, html.EscapeString(d.Message)))
+	}
+	sb.WriteString("</ul>\n</details>\n")
+	return sb.String()
+}
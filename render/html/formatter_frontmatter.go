@@ -0,0 +1,77 @@
+package html
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+)
+
+// frontmatterHTML renders a document's frontmatter as a collapsible
+// metadata header. Nested lists and tables keep their structure.
+func frontmatterHTML(fm *parse.Frontmatter) string {
+	if fm.Fields.Len() == 0 && fm.Err == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<details class=\"frontmatter\">\n")
+	fields := "fields"
+	if fm.Fields.Len() == 1 {
+		fields = "field"
+	}
+	sb.WriteString(fmt.Sprintf("<summary>Metadata <span class=\"frontmatter-count\">%d %s</span></summary>\n", fm.Fields.Len(), fields))
+	if fm.Err != nil {
+		sb.WriteString(fmt.Sprintf("<div class=\"frontmatter-error\">Frontmatter %s</div>\n", html.EscapeString(fm.Err.Error())))
+	}
+	sb.WriteString(frontmatterMapHTML(fm.Fields))
+	sb.WriteString("</details>\n")
+	return sb.String()
+}
+
+// frontmatterMapHTML renders a table as a definition list
+func frontmatterMapHTML(m *parse.Map) string {
+	var sb strings.Builder
+	sb.WriteString("<dl>")
+	for _, key := range m.Keys {
+		sb.WriteString(fmt.Sprintf("<dt>%s</dt><dd>%s</dd>", html.EscapeString(key), frontmatterValueHTML(m.Values[key])))
+	}
+	sb.WriteString("</dl>\n")
+	return sb.String()
+}
+
+// frontmatterValueHTML renders a field value: lists of plain values as
+// chips, other lists as bullets, tables as nested definition lists
+func frontmatterValueHTML(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "<span class=\"fm-literal\">null</span>"
+	case string:
+		return html.EscapeString(v)
+	case *parse.Map:
+		return frontmatterMapHTML(v)
+	case []any:
+		flat := true
+		for _, item := range v {
+			switch item.(type) {
+			case []any, *parse.Map:
+				flat = false
+			}
+		}
+		var sb strings.Builder
+		if flat {
+			for _, item := range v {
+				sb.WriteString(fmt.Sprintf("<span class=\"fm-chip\">%s</span> ", html.EscapeString(parse.FormatValue(item))))
+			}
+			return strings.TrimSuffix(sb.String(), " ")
+		}
+		sb.WriteString("<ul>")
+		for _, item := range v {
+			sb.WriteString("<li>" + frontmatterValueHTML(item) + "</li>")
+		}
+		sb.WriteString("</ul>")
+		return sb.String()
+	}
+	return fmt.Sprintf("<span class=\"fm-literal\">%s</span>", html.EscapeString(parse.FormatValue(v)))
+}
@@ -1,4 +1,4 @@
-package main
+package html
 
 import (
 	"crypto/sha1"
@@ -8,25 +8,37 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/parse/markdown"
 )
 
 // RenderHTMLPage renders blocks as a full HTML document with enhanced web features
-func RenderHTMLPage(title string, blocks []Block, showLineNums bool) string {
+func RenderHTMLPage(title string, blocks []parse.Block, showLineNums bool, diags ...parse.Diagnostic) string {
+	return renderPage(title, blocks, showLineNums, nil, diags)
+}
+
+// renderPage renders a served page; page resolves the links of notes
+func renderPage(title string, blocks []parse.Block, showLineNums bool, page *pageLinks, diags []parse.Diagnostic) string {
 	var sb strings.Builder
 
-	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	sb.WriteString("<!DOCTYPE html>\n" + htmlOpenTag() + "\n<head>\n")
 	sb.WriteString("<meta charset=\"UTF-8\">\n")
 	sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
 	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(title)))
 
-	// highlight.js CDN for syntax highlighting (light theme)
-	sb.WriteString("<link rel=\"stylesheet\" href=\"https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/styles/github.min.css\">\n")
-	sb.WriteString("<script src=\"https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/highlight.min.js\"></script>\n")
+	// highlight.js CDN for syntax highlighting, light and dark themes
+	sb.WriteString(highlightThemesHTML(false))
+	sb.WriteString("<script src=\"" + highlightCDN + "highlight.min.js\"></script>\n")
+	sb.WriteString("<script>\n" + themeScript() + "</script>\n")
 
 	sb.WriteString("<style>\n")
+	sb.WriteString(themeCSS())
 	sb.WriteString(cssStyles())
 	sb.WriteString("</style>\n")
+	sb.WriteString(customStylesHTML())
 	sb.WriteString("</head>\n<body>\n")
+	sb.WriteString(themeToggleHTML())
 
 	transcript := isTranscriptContent(blocks)
 
@@ -37,9 +49,10 @@ func RenderHTMLPage(title string, blocks []Block, showLineNums bool) string {
 		sb.WriteString(fmt.Sprintf("<div class=\"transcript-title\">%s</div>\n", html.EscapeString(title)))
 		sb.WriteString(fmt.Sprintf("<div class=\"transcript-meta\">%d turns</div>\n", len(blocks)))
 		sb.WriteString("</div>\n")
+		sb.WriteString(diagnosticsPanelHTML(diags))
 
 		for i := range blocks {
-			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, false))
+			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, false, nil))
 		}
 
 		sb.WriteString("</main>\n")
@@ -77,11 +90,13 @@ func RenderHTMLPage(title string, blocks []Block, showLineNums bool) string {
 			containerClass = "container has-toc"
 		}
 		sb.WriteString(fmt.Sprintf("<main class=\"%s\">\n", containerClass))
+		sb.WriteString(diagnosticsPanelHTML(diags))
 
 		singleBlock := len(blocks) == 1
 		for i := range blocks {
-			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, singleBlock))
+			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, singleBlock, page))
 		}
+		sb.WriteString(page.backlinksHTML())
 
 		sb.WriteString("</main>\n")
 
@@ -93,6 +108,7 @@ func RenderHTMLPage(title string, blocks []Block, showLineNums bool) string {
 		sb.WriteString("</script>\n")
 	}
 
+	sb.WriteString(rendererScriptsHTML(sb.String()))
 	sb.WriteString("</body>\n</html>\n")
 
 	return sb.String()
@@ -100,25 +116,32 @@ func RenderHTMLPage(title string, blocks []Block, showLineNums bool) string {
 
 // DocMeta holds metadata for a document in directory mode
 type DocMeta struct {
-	Slug    string
-	Title   string
-	Created string
-	Tags    []string
-	ModTime time.Time
-}
-
-// RenderIndexPage renders a directory listing as an HTML page
-func RenderIndexPage(dirName string, docs []DocMeta) string {
+	Slug        string
+	Title       string
+	Created     string
+	Tags        []string
+	ModTime     time.Time
+	Frontmatter parse.Frontmatter
+}
+
+// RenderIndexPage renders a directory listing as an HTML page. Each column
+// names a frontmatter field, as a dotted path like "review.status", shown
+// after the standard columns.
+func RenderIndexPage(dirName string, docs []DocMeta, columns ...string) string {
 	var sb strings.Builder
 
-	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	sb.WriteString("<!DOCTYPE html>\n" + htmlOpenTag() + "\n<head>\n")
 	sb.WriteString("<meta charset=\"UTF-8\">\n")
 	sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
 	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(dirName)))
+	sb.WriteString("<script>\n" + themeScript() + "</script>\n")
 	sb.WriteString("<style>\n")
+	sb.WriteString(themeCSS())
 	sb.WriteString(indexCSS())
 	sb.WriteString("</style>\n")
+	sb.WriteString(customStylesHTML())
 	sb.WriteString("</head>\n<body>\n")
+	sb.WriteString(themeToggleHTML())
 
 	sb.WriteString("<main class=\"index-container\">\n")
 	sb.WriteString(fmt.Sprintf("<h1 class=\"index-title\">%s</h1>\n", html.EscapeString(dirName)))
@@ -128,7 +151,11 @@ func RenderIndexPage(dirName string, docs []DocMeta) string {
 		sb.WriteString("<p class=\"index-empty\">No markdown files found.</p>\n")
 	} else {
 		sb.WriteString("<table class=\"index-table\">\n")
-		sb.WriteString("<thead><tr><th>Title</th><th>Created</th><th>Tags</th></tr></thead>\n")
+		sb.WriteString("<thead><tr><th>Title</th><th>Created</th><th>Tags</th>")
+		for _, col := range columns {
+			sb.WriteString(fmt.Sprintf("<th>%s</th>", html.EscapeString(col)))
+		}
+		sb.WriteString("</tr></thead>\n")
 		sb.WriteString("<tbody>\n")
 		for _, doc := range docs {
 			title := html.EscapeString(doc.Title)
@@ -139,8 +166,12 @@ func RenderIndexPage(dirName string, docs []DocMeta) string {
 				tagParts = append(tagParts, fmt.Sprintf("<span class=\"tag\">%s</span>", html.EscapeString(tag)))
 			}
 			tags := strings.Join(tagParts, " ")
-			sb.WriteString(fmt.Sprintf("<tr><td><a href=\"/%s\">%s</a></td><td class=\"date-col\">%s</td><td>%s</td></tr>\n",
+			sb.WriteString(fmt.Sprintf("<tr><td><a href=\"/%s\">%s</a></td><td class=\"date-col\">%s</td><td>%s</td>",
 				slug, title, created, tags))
+			for _, col := range columns {
+				sb.WriteString(fmt.Sprintf("<td>%s</td>", indexFieldHTML(doc.Frontmatter, col)))
+			}
+			sb.WriteString("</tr>\n")
 		}
 		sb.WriteString("</tbody>\n</table>\n")
 	}
@@ -158,13 +189,28 @@ func RenderIndexPage(dirName string, docs []DocMeta) string {
 	return sb.String()
 }
 
+// indexFieldHTML renders a frontmatter field for an index column; lists
+// show as tags
+func indexFieldHTML(fm parse.Frontmatter, path string) string {
+	v, _ := fm.Lookup(path)
+	items, ok := v.([]any)
+	if !ok {
+		return html.EscapeString(parse.FormatValue(v))
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprintf("<span class=\"tag\">%s</span>", html.EscapeString(parse.FormatValue(item)))
+	}
+	return strings.Join(parts, " ")
+}
+
 // indexCSS returns CSS for the index page
 func indexCSS() string {
 	return `
 * { margin: 0; padding: 0; box-sizing: border-box; }
 body {
-  background: #fff;
-  color: #1d1d1f;
+  background: var(--bg);
+  color: var(--text);
   font-family: -apple-system, BlinkMacSystemFont, 'SF Pro Text', 'SF Pro Display', 'Helvetica Neue', Helvetica, Arial, sans-serif;
   font-weight: 400;
   font-size: 17px;
@@ -177,19 +223,19 @@ body {
   padding: 3rem 1.5rem;
 }
 .index-title {
-  color: #1d1d1f;
+  color: var(--text);
   font-size: 40px;
   font-weight: 700;
   letter-spacing: -0.005em;
   margin-bottom: 0.25rem;
 }
 .index-count {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 14px;
   margin-bottom: 2rem;
 }
 .index-empty {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 14px;
 }
 .index-table {
@@ -200,35 +246,35 @@ body {
 .index-table th {
   text-align: left;
   padding: 0.5rem 0.75rem;
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 12px;
   font-weight: 600;
   text-transform: uppercase;
   letter-spacing: 0.05em;
-  border-bottom: 2px solid #d2d2d7;
+  border-bottom: 2px solid var(--border);
 }
 .index-table td {
   padding: 0.6rem 0.75rem;
-  border-bottom: 1px solid #e8e8ed;
+  border-bottom: 1px solid var(--surface-strong);
   vertical-align: top;
 }
-.index-table tr:hover td { background: #f5f5f7; }
+.index-table tr:hover td { background: var(--surface); }
 .index-table a {
-  color: #1d1d1f;
+  color: var(--text);
   text-decoration: none;
   font-weight: 600;
 }
-.index-table a:hover { color: #06c; }
+.index-table a:hover { color: var(--link); }
 .date-col {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
   font-size: 13px;
   white-space: nowrap;
 }
 .tag {
   display: inline-block;
-  background: #e8e8ed;
-  color: #1d1d1f;
+  background: var(--surface-strong);
+  color: var(--text);
   padding: 0.1rem 0.45rem;
   border-radius: 3px;
   font-size: 12px;
@@ -244,29 +290,38 @@ type tocHeader struct {
 	id    string
 }
 
-// collectHeaders scans all blocks for h1/h2/h3 headers
-func collectHeaders(blocks []Block) []tocHeader {
+// collectHeaders scans all blocks for h1/h2/h3 headings
+func collectHeaders(blocks []parse.Block) []tocHeader {
 	var headers []tocHeader
 	for _, block := range blocks {
-		for _, page := range block.Pages {
-			for _, line := range strings.Split(page, "\n") {
-				trimmed := strings.TrimSpace(line)
-				if strings.HasPrefix(trimmed, "### ") {
-					text := strings.TrimPrefix(trimmed, "### ")
-					headers = append(headers, tocHeader{level: 3, text: text, id: headerID(text)})
-				} else if strings.HasPrefix(trimmed, "## ") && !strings.HasPrefix(trimmed, "### ") {
-					text := strings.TrimPrefix(trimmed, "## ")
-					headers = append(headers, tocHeader{level: 2, text: text, id: headerID(text)})
-				} else if strings.HasPrefix(trimmed, "# ") && !strings.HasPrefix(trimmed, "## ") {
-					text := strings.TrimPrefix(trimmed, "# ")
-					headers = append(headers, tocHeader{level: 1, text: text, id: headerID(text)})
-				}
+		if !hasMarkdownPages(&block) {
+			continue
+		}
+		doc := markdown.Parse(strings.Join(block.Pages, "\n"))
+		for n := doc.FirstChild; n != nil; n = n.NextSibling {
+			if n.Kind == markdown.Heading && n.Level <= 3 {
+				text := markdown.PlainText(n)
+				headers = append(headers, tocHeader{level: n.Level, text: text, id: headerID(text)})
 			}
 		}
 	}
 	return headers
 }
 
+// hasMarkdownPages reports whether any page of a block renders as markdown
+func hasMarkdownPages(block *parse.Block) bool {
+	switch block.ContentType {
+	case parse.BlockContentTranscript, parse.BlockContentImage, parse.BlockContentVideo, parse.BlockContentContract:
+		return false
+	}
+	for i := range block.Pages {
+		if isMarkdownPage(pageType(block, i)) {
+			return true
+		}
+	}
+	return false
+}
+
 // headerID generates a URL-safe anchor ID from header text
 func headerID(text string) string {
 	// Strip markdown formatting
@@ -281,9 +336,9 @@ func headerID(text string) string {
 }
 
 // isTranscriptContent checks if any block is a transcript
-func isTranscriptContent(blocks []Block) bool {
+func isTranscriptContent(blocks []parse.Block) bool {
 	for _, b := range blocks {
-		if b.ContentType == BlockContentTranscript {
+		if b.ContentType == parse.BlockContentTranscript {
 			return true
 		}
 	}
@@ -291,7 +346,7 @@ func isTranscriptContent(blocks []Block) bool {
 }
 
 // formatTranscriptBlockHTML renders a single conversation turn as HTML
-func formatTranscriptBlockHTML(block *Block) string {
+func formatTranscriptBlockHTML(block *parse.Block) string {
 	var sb strings.Builder
 
 	// Extract turn number from block name (e.g., "Turn 3" -> "3")
@@ -303,8 +358,8 @@ func formatTranscriptBlockHTML(block *Block) string {
 	sb.WriteString("<div class=\"turn\">\n")
 	sb.WriteString(fmt.Sprintf("<div class=\"turn-gutter\">%s</div>\n", html.EscapeString(turnLabel)))
 
-	var parts []TurnPart
-	if tData, ok := block.Data.(*TranscriptData); ok {
+	var parts []parse.TurnPart
+	if tData, ok := block.Data.(*parse.TranscriptData); ok {
 		parts = tData.TurnParts
 	}
 
@@ -317,7 +372,7 @@ func formatTranscriptBlockHTML(block *Block) string {
 
 		case "assistant":
 			sb.WriteString("<div class=\"turn-assistant\">")
-			sb.WriteString(formatMarkdownHTML(part.Content, block, 0, false))
+			sb.WriteString(formatMarkdownHTML(part.Content, block, 0, false, nil))
 			sb.WriteString("</div>\n")
 
 		case "diff":
@@ -352,24 +407,24 @@ func formatTranscriptBlockHTML(block *Block) string {
 
 // formatBlockHTML renders a single block with all pages concatenated
 // When singleBlock is true, the block header bar is hidden (headings are in the content)
-func formatBlockHTML(block *Block, showLineNums bool, singleBlock bool) string {
+func formatBlockHTML(block *parse.Block, showLineNums bool, singleBlock bool, page *pageLinks) string {
 	// Transcript blocks use dedicated renderer
-	if block.ContentType == BlockContentTranscript {
+	if block.ContentType == parse.BlockContentTranscript {
 		return formatTranscriptBlockHTML(block)
 	}
 
 	// Image blocks: dedicated renderer
-	if block.ContentType == BlockContentImage {
+	if block.ContentType == parse.BlockContentImage {
 		return formatImageBlockHTML(block)
 	}
 
 	// Video blocks: dedicated renderer
-	if block.ContentType == BlockContentVideo {
+	if block.ContentType == parse.BlockContentVideo {
 		return formatVideoBlockHTML(block)
 	}
 
 	// Contract blocks: dedicated renderer
-	if block.ContentType == BlockContentContract {
+	if block.ContentType == parse.BlockContentContract {
 		return formatContractBlockHTML(block)
 	}
 
@@ -383,26 +438,39 @@ func formatBlockHTML(block *Block, showLineNums bool, singleBlock bool) string {
 		sb.WriteString(fmt.Sprintf("<header class=\"block-header\">%s</header>\n", displayName))
 	}
 
+	// Markdown documents carry their frontmatter
+	if fm, ok := block.Data.(*parse.Frontmatter); ok {
+		sb.WriteString(frontmatterHTML(fm))
+	}
+
 	// Render all pages
-	for pageNum := range block.Pages {
+	for pageNum := 0; pageNum < len(block.Pages); pageNum++ {
 		pageContent := block.Pages[pageNum]
 
-		pageType := block.ContentType
-		if len(block.PageTypes) > pageNum {
-			pageType = block.PageTypes[pageNum]
-		}
-
-		switch pageType {
-		case BlockContentDiff:
+		switch pageType(block, pageNum) {
+		case parse.BlockContentDiff:
 			sb.WriteString(formatDiffHTML(pageContent))
-		case BlockContentJSON:
+		case parse.BlockContentJSON:
 			sb.WriteString(formatCodeBlockHTML(pageContent, "json"))
-		case BlockContentYAML:
+		case parse.BlockContentYAML:
 			sb.WriteString(formatCodeBlockHTML(pageContent, "yaml"))
-		case BlockContentCSV:
+		case parse.BlockContentCSV:
 			sb.WriteString(formatCsvHTML(block))
+		case parse.BlockContentShell:
+			startLine := 0
+			if showLineNums && len(block.PageStartLine) > pageNum {
+				startLine = block.PageStartLine[pageNum]
+			}
+			sb.WriteString(formatPlainHTML(pageContent, startLine))
 		default:
-			sb.WriteString(formatMarkdownHTML(pageContent, block, pageNum, showLineNums))
+			// Consecutive markdown pages render as one document so code
+			// fences, lists and link references can span page breaks
+			end := pageNum + 1
+			for end < len(block.Pages) && isMarkdownPage(pageType(block, end)) {
+				end++
+			}
+			sb.WriteString(formatMarkdownHTML(strings.Join(block.Pages[pageNum:end], "\n"), block, pageNum, showLineNums, page))
+			pageNum = end - 1
 		}
 	}
 
@@ -410,9 +478,27 @@ func formatBlockHTML(block *Block, showLineNums bool, singleBlock bool) string {
 	return sb.String()
 }
 
+// pageType returns the content type of a page, which PageTypes overrides
+// for mixed-content blocks
+func pageType(block *parse.Block, pageNum int) parse.BlockContentType {
+	if len(block.PageTypes) > pageNum {
+		return block.PageTypes[pageNum]
+	}
+	return block.ContentType
+}
+
+// isMarkdownPage reports whether pages of this type render as markdown
+func isMarkdownPage(t parse.BlockContentType) bool {
+	switch t {
+	case parse.BlockContentDiff, parse.BlockContentJSON, parse.BlockContentYAML, parse.BlockContentCSV, parse.BlockContentShell:
+		return false
+	}
+	return true
+}
+
 // formatImageBlockHTML renders an image block as HTML
-func formatImageBlockHTML(block *Block) string {
-	imgData, ok := block.Data.(*ImageData)
+func formatImageBlockHTML(block *parse.Block) string {
+	imgData, ok := block.Data.(*parse.ImageData)
 	if !ok {
 		return "<p>Error: missing image data</p>\n"
 	}
@@ -426,9 +512,9 @@ func formatImageBlockHTML(block *Block) string {
 		src = "/asset/" + html.EscapeString(filepath.Base(imgData.Alt))
 	}
 
-	sb.WriteString(fmt.Sprintf("<div class=\"title\" style=\"text-align:center;font-size:14px;font-weight:600;color:#6e6e73;margin-bottom:1.5rem\">%s</div>\n", html.EscapeString(imgData.Alt)))
+	sb.WriteString(fmt.Sprintf("<div class=\"title\" style=\"text-align:center;font-size:14px;font-weight:600;color:var(--text-secondary);margin-bottom:1.5rem\">%s</div>\n", html.EscapeString(imgData.Alt)))
 	sb.WriteString("<div class=\"img-container\" style=\"text-align:center\">\n")
-	sb.WriteString(fmt.Sprintf("  <img src=\"%s\" alt=\"%s\" style=\"max-width:100%%;max-height:85vh;border-radius:6px;border:1px solid #d2d2d7;cursor:pointer\" onclick=\"this.classList.toggle('expanded')\">\n",
+	sb.WriteString(fmt.Sprintf("  <img src=\"%s\" alt=\"%s\" style=\"max-width:100%%;max-height:85vh;border-radius:6px;border:1px solid var(--border);cursor:pointer\" onclick=\"this.classList.toggle('expanded')\">\n",
 		src, html.EscapeString(imgData.Alt)))
 	sb.WriteString("</div>\n")
 	sb.WriteString("</div>\n")
@@ -436,8 +522,8 @@ func formatImageBlockHTML(block *Block) string {
 }
 
 // formatVideoBlockHTML renders a video block as HTML
-func formatVideoBlockHTML(block *Block) string {
-	vidData, ok := block.Data.(*VideoData)
+func formatVideoBlockHTML(block *parse.Block) string {
+	vidData, ok := block.Data.(*parse.VideoData)
 	if !ok {
 		return "<p>Error: missing video data</p>\n"
 	}
@@ -453,24 +539,24 @@ func formatVideoBlockHTML(block *Block) string {
 	mime := html.EscapeString(vidData.MIME)
 
 	sb.WriteString("<div class=\"content video-content\" style=\"display:flex;flex-direction:column;align-items:center\">\n")
-	sb.WriteString(fmt.Sprintf("<div class=\"title\" style=\"font-size:14px;font-weight:600;color:#6e6e73;margin-bottom:1.5rem\">%s</div>\n", title))
+	sb.WriteString(fmt.Sprintf("<div class=\"title\" style=\"font-size:14px;font-weight:600;color:var(--text-secondary);margin-bottom:1.5rem\">%s</div>\n", title))
 	sb.WriteString("<div class=\"video-container\" style=\"max-width:960px;width:100%%\">\n")
-	sb.WriteString(fmt.Sprintf("  <video id=\"player\" controls style=\"width:100%%;border-radius:6px;border:1px solid #d2d2d7;background:#1d1d1f;outline:none\">\n    <source src=\"%s\" type=\"%s\">\n  </video>\n", src, mime))
+	sb.WriteString(fmt.Sprintf("  <video id=\"player\" controls style=\"width:100%%;border-radius:6px;border:1px solid var(--border);background:#1d1d1f;outline:none\">\n    <source src=\"%s\" type=\"%s\">\n  </video>\n", src, mime))
 
 	// Speed controls
-	sb.WriteString(`  <div class="controls" style="display:flex;align-items:center;gap:0.75rem;margin-top:0.75rem;font-size:13px;color:#6e6e73;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace">
+	sb.WriteString(`  <div class="controls" style="display:flex;align-items:center;gap:0.75rem;margin-top:0.75rem;font-size:13px;color:var(--text-secondary);font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace">
     <span id="time-display">0:00 / 0:00</span>
     <span style="flex:1"></span>
-    <button class="speed-btn" data-speed="0.5" style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:#6e6e73;cursor:pointer">0.5x</button>
-    <button class="speed-btn active" data-speed="1" style="background:#06c;border:1px solid #06c;border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:#fff;cursor:pointer">1x</button>
-    <button class="speed-btn" data-speed="1.5" style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:#6e6e73;cursor:pointer">1.5x</button>
-    <button class="speed-btn" data-speed="2" style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:#6e6e73;cursor:pointer">2x</button>
+    <button class="speed-btn" data-speed="0.5" style="background:var(--surface);border:1px solid var(--border);border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:var(--text-secondary);cursor:pointer">0.5x</button>
+    <button class="speed-btn active" data-speed="1" style="background:var(--link);border:1px solid var(--link);border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:#fff;cursor:pointer">1x</button>
+    <button class="speed-btn" data-speed="1.5" style="background:var(--surface);border:1px solid var(--border);border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:var(--text-secondary);cursor:pointer">1.5x</button>
+    <button class="speed-btn" data-speed="2" style="background:var(--surface);border:1px solid var(--border);border-radius:4px;padding:0.2rem 0.5rem;font-size:12px;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;color:var(--text-secondary);cursor:pointer">2x</button>
   </div>
-  <div style="margin-top:1rem;font-size:12px;color:#86868b">
-    <kbd style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">Space</kbd> play/pause
-    <kbd style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">F</kbd> fullscreen
-    <kbd style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">&#x2190;</kbd> -5s
-    <kbd style="background:#f5f5f7;border:1px solid #d2d2d7;border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">&#x2192;</kbd> +5s
+  <div style="margin-top:1rem;font-size:12px;color:var(--text-tertiary)">
+    <kbd style="background:var(--surface);border:1px solid var(--border);border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">Space</kbd> play/pause
+    <kbd style="background:var(--surface);border:1px solid var(--border);border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">F</kbd> fullscreen
+    <kbd style="background:var(--surface);border:1px solid var(--border);border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">&#x2190;</kbd> -5s
+    <kbd style="background:var(--surface);border:1px solid var(--border);border-radius:3px;padding:0.1rem 0.35rem;font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:11px">&#x2192;</kbd> +5s
   </div>
 `)
 	sb.WriteString("</div>\n")
@@ -479,8 +565,8 @@ func formatVideoBlockHTML(block *Block) string {
 }
 
 // formatContractBlockHTML renders a contract block as HTML
-func formatContractBlockHTML(block *Block) string {
-	cData, ok := block.Data.(*ContractData)
+func formatContractBlockHTML(block *parse.Block) string {
+	cData, ok := block.Data.(*parse.ContractData)
 	if !ok {
 		return "<p>Error: missing contract data</p>\n"
 	}
@@ -488,12 +574,12 @@ func formatContractBlockHTML(block *Block) string {
 	var sb strings.Builder
 
 	// Contract header
-	sb.WriteString("<div class=\"contract-header\" style=\"margin-bottom:2rem;padding-bottom:1rem;border-bottom:1px solid #d2d2d7\">\n")
+	sb.WriteString("<div class=\"contract-header\" style=\"margin-bottom:2rem;padding-bottom:1rem;border-bottom:1px solid var(--border)\">\n")
 	if cData.Parties != "" {
-		sb.WriteString(fmt.Sprintf("<div style=\"font-size:13px;color:#6e6e73;font-weight:600;text-transform:uppercase;letter-spacing:0.03em\">%s</div>\n", html.EscapeString(cData.Parties)))
+		sb.WriteString(fmt.Sprintf("<div style=\"font-size:13px;color:var(--text-secondary);font-weight:600;text-transform:uppercase;letter-spacing:0.03em\">%s</div>\n", html.EscapeString(cData.Parties)))
 	}
 	if cData.Effective != "" {
-		sb.WriteString(fmt.Sprintf("<div style=\"font-size:13px;color:#86868b;margin-top:0.25rem\">Effective %s</div>\n", html.EscapeString(cData.Effective)))
+		sb.WriteString(fmt.Sprintf("<div style=\"font-size:13px;color:var(--text-tertiary);margin-top:0.25rem\">Effective %s</div>\n", html.EscapeString(cData.Effective)))
 	}
 	sb.WriteString("</div>\n")
 
@@ -520,7 +606,7 @@ func formatContractBlockHTML(block *Block) string {
 		if clause.Level == 2 {
 			tag = "h3"
 		}
-		sb.WriteString(fmt.Sprintf("<%s style=\"font-size:20px;font-weight:600;color:#1d1d1f;margin-bottom:0.75rem\"><span style=\"font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:0.85em;color:#6e6e73\">%s.</span> %s</%s>\n",
+		sb.WriteString(fmt.Sprintf("<%s style=\"font-size:20px;font-weight:600;color:var(--text);margin-bottom:0.75rem\"><span style=\"font-family:'SF Mono',SFMono-Regular,ui-monospace,Menlo,monospace;font-size:0.85em;color:var(--text-secondary)\">%s.</span> %s</%s>\n",
 			tag, html.EscapeString(clause.ID), html.EscapeString(clause.Title), tag))
 
 		sb.WriteString("<div class=\"clause-body\">\n")
@@ -547,9 +633,9 @@ func formatCodeBlockHTML(content string, lang string) string {
 }
 
 // formatCsvHTML renders CSV data as an interactive table with filtering and optional chart
-func formatCsvHTML(block *Block) string {
+func formatCsvHTML(block *parse.Block) string {
 	var records [][]string
-	if csvData, ok := block.Data.(*CsvData); ok {
+	if csvData, ok := block.Data.(*parse.CsvData); ok {
 		records = csvData.Records
 	}
 	if len(records) < 1 {
@@ -748,7 +834,7 @@ func csvAutoChart(headers []string, rows [][]string) string {
 	chartW := svgW - padL - padR
 	chartH := svgH - padT - padB
 
-	colors := []string{"#06c", "#1d1d1f"}
+	colors := []string{"var(--link)", "var(--text)"}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("<svg viewBox=\"0 0 %d %d\" class=\"csv-svg\">\n", svgW, svgH))
@@ -758,14 +844,14 @@ func csvAutoChart(headers []string, rows [][]string) string {
 	for i := 0; i <= gridSteps; i++ {
 		y := padT + chartH - (i*chartH)/gridSteps
 		val := minVal + (float64(i)/float64(gridSteps))*valRange
-		sb.WriteString(fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#d2d2d7\" stroke-width=\"1\"/>\n",
+		sb.WriteString(fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" style=\"stroke:var(--border)\" stroke-width=\"1\"/>\n",
 			padL, y, padL+chartW, y))
-		sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"end\" fill=\"#6e6e73\" font-size=\"11\" font-family=\"-apple-system,sans-serif\">%.0f</text>\n",
+		sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"end\" style=\"fill:var(--text-secondary)\" font-size=\"11\" font-family=\"-apple-system,sans-serif\">%.0f</text>\n",
 			padL-8, y+4, val))
 	}
 
 	// Axes
-	sb.WriteString(fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#d2d2d7\" stroke-width=\"1\"/>\n",
+	sb.WriteString(fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" style=\"stroke:var(--border)\" stroke-width=\"1\"/>\n",
 		padL, padT+chartH, padL+chartW, padT+chartH))
 
 	// Plot each series
@@ -781,14 +867,14 @@ func csvAutoChart(headers []string, rows [][]string) string {
 		}
 
 		// Line
-		sb.WriteString(fmt.Sprintf("<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"2\"/>\n",
+		sb.WriteString(fmt.Sprintf("<polyline points=\"%s\" style=\"fill:none;stroke:%s\" stroke-width=\"2\"/>\n",
 			strings.Join(polyPoints, " "), color))
 
 		// Dots
 		for i, p := range points {
 			x := padL + (i*chartW)/(len(points)-1)
 			y := padT + chartH - int(((p.vals[si]-minVal)/valRange)*float64(chartH))
-			sb.WriteString(fmt.Sprintf("<circle cx=\"%d\" cy=\"%d\" r=\"3\" fill=\"%s\"/>\n", x, y, color))
+			sb.WriteString(fmt.Sprintf("<circle cx=\"%d\" cy=\"%d\" r=\"3\" style=\"fill:%s\"/>\n", x, y, color))
 			_ = p // used above
 		}
 	}
@@ -804,7 +890,7 @@ func csvAutoChart(headers []string, rows [][]string) string {
 		if len(label) > 12 {
 			label = label[:12]
 		}
-		sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"middle\" fill=\"#6e6e73\" font-size=\"11\" font-family=\"-apple-system,sans-serif\" transform=\"rotate(-45 %d %d)\">%s</text>\n",
+		sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"middle\" style=\"fill:var(--text-secondary)\" font-size=\"11\" font-family=\"-apple-system,sans-serif\" transform=\"rotate(-45 %d %d)\">%s</text>\n",
 			x, padT+chartH+16, x, padT+chartH+16, html.EscapeString(label)))
 	}
 
@@ -812,9 +898,9 @@ func csvAutoChart(headers []string, rows [][]string) string {
 	for si, col := range numericCols {
 		color := colors[si%len(colors)]
 		lx := padL + si*120
-		sb.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"12\" height=\"12\" fill=\"%s\" rx=\"2\"/>\n",
+		sb.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"12\" height=\"12\" style=\"fill:%s\" rx=\"2\"/>\n",
 			lx, svgH-16, color))
-		sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" fill=\"#1d1d1f\" font-size=\"12\" font-family=\"-apple-system,sans-serif\">%s</text>\n",
+		sb.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" style=\"fill:var(--text)\" font-size=\"12\" font-family=\"-apple-system,sans-serif\">%s</text>\n",
 			lx+16, svgH-5, html.EscapeString(headers[col])))
 	}
 
@@ -831,175 +917,6 @@ func parseCSVFloat(s string) float64 {
 	return val
 }
 
-// formatMarkdownHTML renders markdown content as HTML
-func formatMarkdownHTML(text string, block *Block, pageNum int, showLineNums bool) string {
-	lines := strings.Split(text, "\n")
-	var sb strings.Builder
-	inCodeBlock := false
-	var codeLines []string
-	var codeLang string
-	inTable := false
-	var tableLines []string
-
-	// Determine starting line number for this page
-	startLine := 0
-	if showLineNums && len(block.PageStartLine) > pageNum {
-		startLine = block.PageStartLine[pageNum]
-	}
-
-	sb.WriteString("<div class=\"content\">\n")
-
-	flushTable := func() {
-		if len(tableLines) > 0 {
-			sb.WriteString(renderTableHTML(tableLines))
-			tableLines = nil
-		}
-		inTable = false
-	}
-
-	for lineIdx, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Table detection (before code blocks)
-		if !inCodeBlock && isTableLine(trimmed) {
-			if !inTable {
-				inTable = true
-				tableLines = []string{line}
-			} else {
-				tableLines = append(tableLines, line)
-			}
-			continue
-		} else if inTable {
-			flushTable()
-		}
-
-		// Code fence
-		if strings.HasPrefix(trimmed, "```") {
-			if !inCodeBlock {
-				codeLang = strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
-				codeLines = []string{}
-				inCodeBlock = true
-			} else {
-				// End code block - with highlight.js class and copy button
-				langClass := ""
-				if codeLang != "" {
-					langClass = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(codeLang))
-				}
-				langLabel := ""
-				if codeLang != "" {
-					langLabel = fmt.Sprintf("<span class=\"code-lang\">%s</span>", html.EscapeString(codeLang))
-				}
-				sb.WriteString(fmt.Sprintf("<div class=\"code-block\">%s<button class=\"copy-btn\" onclick=\"copyCode(this)\" title=\"Copy\">Copy</button><pre><code%s>", langLabel, langClass))
-				for _, cl := range codeLines {
-					sb.WriteString(html.EscapeString(cl))
-					sb.WriteString("\n")
-				}
-				sb.WriteString("</code></pre></div>\n")
-				inCodeBlock = false
-				codeLines = nil
-			}
-			continue
-		}
-
-		if inCodeBlock {
-			codeLines = append(codeLines, line)
-			continue
-		}
-
-		// Line number gutter
-		lineNumHTML := ""
-		if showLineNums && startLine > 0 {
-			fileLineNum := startLine + lineIdx
-			lineNumHTML = fmt.Sprintf("<span class=\"line-num\">%d</span>", fileLineNum)
-		}
-
-		// Headers with anchor IDs
-		if strings.HasPrefix(trimmed, "### ") {
-			raw := strings.TrimPrefix(trimmed, "### ")
-			content := processInlineHTML(raw)
-			id := headerID(raw)
-			sb.WriteString(fmt.Sprintf("<h3 id=\"%s\">%s<a class=\"anchor\" href=\"#%s\">#</a>%s</h3>\n", id, lineNumHTML, id, content))
-			continue
-		}
-		if strings.HasPrefix(trimmed, "## ") {
-			raw := strings.TrimPrefix(trimmed, "## ")
-			content := processInlineHTML(raw)
-			id := headerID(raw)
-			sb.WriteString(fmt.Sprintf("<h2 id=\"%s\">%s<a class=\"anchor\" href=\"#%s\">#</a>%s</h2>\n", id, lineNumHTML, id, content))
-			continue
-		}
-		if strings.HasPrefix(trimmed, "# ") {
-			raw := strings.TrimPrefix(trimmed, "# ")
-			content := processInlineHTML(raw)
-			id := headerID(raw)
-			sb.WriteString(fmt.Sprintf("<h1 id=\"%s\">%s<a class=\"anchor\" href=\"#%s\">#</a>%s</h1>\n", id, lineNumHTML, id, content))
-			continue
-		}
-
-		// Horizontal rule
-		if trimmed == "---" || trimmed == "***" || trimmed == "___" {
-			sb.WriteString("<hr>\n")
-			continue
-		}
-
-		// Empty line
-		if trimmed == "" {
-			sb.WriteString("<br>\n")
-			continue
-		}
-
-		// Image: ![alt](url)
-		if imgMatch := regexp.MustCompile(`^!\[([^\]]*)\]\(([^)]+)\)$`).FindStringSubmatch(trimmed); imgMatch != nil {
-			alt := html.EscapeString(imgMatch[1])
-			src := html.EscapeString(imgMatch[2])
-			sb.WriteString(fmt.Sprintf("<div class=\"img-wrapper\">%s<img src=\"%s\" alt=\"%s\" loading=\"lazy\" onclick=\"this.classList.toggle('expanded')\"><div class=\"img-caption\">%s</div></div>\n", lineNumHTML, src, alt, alt))
-			continue
-		}
-
-		// List items
-		if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
-			leadingSpaces := len(line) - len(strings.TrimLeft(line, " \t"))
-			content := strings.TrimPrefix(strings.TrimPrefix(trimmed, "- "), "* ")
-			content = processInlineHTML(content)
-			class := "list-item"
-			if leadingSpaces >= 2 {
-				class = "list-item nested"
-			}
-			sb.WriteString(fmt.Sprintf("<div class=\"%s\">%s<span class=\"bullet\">-</span> %s</div>\n", class, lineNumHTML, content))
-			continue
-		}
-
-		// Numbered list
-		if numMatch := regexp.MustCompile(`^(\d+)\.\s+(.+)$`).FindStringSubmatch(trimmed); numMatch != nil {
-			content := processInlineHTML(numMatch[2])
-			sb.WriteString(fmt.Sprintf("<div class=\"list-item\">%s<span class=\"list-num\">%s.</span> %s</div>\n", lineNumHTML, html.EscapeString(numMatch[1]), content))
-			continue
-		}
-
-		// Regular paragraph
-		content := processInlineHTML(trimmed)
-		sb.WriteString(fmt.Sprintf("<p>%s%s</p>\n", lineNumHTML, content))
-	}
-
-	// Flush unclosed table
-	if inTable {
-		flushTable()
-	}
-
-	// Flush unclosed code block
-	if inCodeBlock && len(codeLines) > 0 {
-		sb.WriteString("<div class=\"code-block\"><pre><code>")
-		for _, cl := range codeLines {
-			sb.WriteString(html.EscapeString(cl))
-			sb.WriteString("\n")
-		}
-		sb.WriteString("</code></pre></div>\n")
-	}
-
-	sb.WriteString("</div>\n")
-	return sb.String()
-}
-
 // sanitizeURL strips dangerous URL schemes (javascript:, data:, vbscript:)
 func sanitizeURL(u string) string {
 	lower := strings.ToLower(strings.TrimSpace(u))
@@ -1009,53 +926,9 @@ func sanitizeURL(u string) string {
 	return u
 }
 
-// processInlineHTML handles inline markdown: bold, italic, code, links, images
-func processInlineHTML(text string) string {
-	escaped := html.EscapeString(text)
-
-	// Inline code: `code`
-	codeRe := regexp.MustCompile("`([^`]+)`")
-	escaped = codeRe.ReplaceAllString(escaped, "<code class=\"inline\">$1</code>")
-
-	// Inline images: ![alt](url)
-	imgRe := regexp.MustCompile(`!\[([^\]]*)\]\(([^)]+)\)`)
-	escaped = imgRe.ReplaceAllStringFunc(escaped, func(m string) string {
-		parts := imgRe.FindStringSubmatch(m)
-		if len(parts) < 3 {
-			return m
-		}
-		return fmt.Sprintf(`<img class="inline-img" src="%s" alt="%s" loading="lazy">`, sanitizeURL(parts[2]), parts[1])
-	})
-
-	// Bold: **text**
-	boldRe := regexp.MustCompile(`\*\*([^*]+)\*\*`)
-	escaped = boldRe.ReplaceAllString(escaped, "<strong>$1</strong>")
-
-	// Bold: __text__
-	boldUnderRe := regexp.MustCompile(`__([^_]+)__`)
-	escaped = boldUnderRe.ReplaceAllString(escaped, "<strong>$1</strong>")
-
-	// Italic: *text* (not **)
-	italicRe := regexp.MustCompile(`\*([^*]+)\*`)
-	escaped = italicRe.ReplaceAllString(escaped, "<em>$1</em>")
-
-	// Links: [text](url) -- open in new tab with external icon
-	linkRe := regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
-	escaped = linkRe.ReplaceAllStringFunc(escaped, func(m string) string {
-		parts := linkRe.FindStringSubmatch(m)
-		if len(parts) < 3 {
-			return m
-		}
-		href := sanitizeURL(parts[2])
-		return fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener" title="%s">%s<span class="ext-icon">&#x2197;</span></a>`, href, href, parts[1])
-	})
-
-	return escaped
-}
-
 // formatDiffHTML renders diff content with side-by-side view, collapsible hunks, and word-level highlighting
 func formatDiffHTML(content string) string {
-	hunks := ParseHunks(content)
+	hunks := parse.ParseHunks(content)
 	if len(hunks) == 0 {
 		return "<pre>" + html.EscapeString(content) + "</pre>\n"
 	}
@@ -1085,7 +958,7 @@ func formatDiffHTML(content string) string {
 		for i < len(hunk.Lines) {
 			line := hunk.Lines[i]
 
-			if line.Type == DiffContext {
+			if line.Type == parse.DiffContext {
 				sb.WriteString(fmt.Sprintf("<tr class=\"diff-row-context\"><td class=\"diff-num\">%d</td><td class=\"diff-code\"> %s</td><td class=\"diff-num\">%d</td><td class=\"diff-code\"> %s</td></tr>\n",
 					oldLineNum, html.EscapeString(line.Content), newLineNum, html.EscapeString(line.Content)))
 				oldLineNum++
@@ -1095,14 +968,14 @@ func formatDiffHTML(content string) string {
 			}
 
 			// Collect consecutive removed lines
-			var removed []DiffLine
-			for i < len(hunk.Lines) && hunk.Lines[i].Type == DiffRemoved {
+			var removed []parse.DiffLine
+			for i < len(hunk.Lines) && hunk.Lines[i].Type == parse.DiffRemoved {
 				removed = append(removed, hunk.Lines[i])
 				i++
 			}
 			// Collect consecutive added lines
-			var added []DiffLine
-			for i < len(hunk.Lines) && hunk.Lines[i].Type == DiffAdded {
+			var added []parse.DiffLine
+			for i < len(hunk.Lines) && hunk.Lines[i].Type == parse.DiffAdded {
 				added = append(added, hunk.Lines[i])
 				i++
 			}
@@ -1232,40 +1105,6 @@ func wordDiffHTML(oldLine, newLine string) (string, string) {
 	return oldHTML.String(), newHTML.String()
 }
 
-// renderTableHTML renders markdown table lines as a sortable HTML table with scroll wrapper
-func renderTableHTML(lines []string) string {
-	if len(lines) < 2 {
-		return ""
-	}
-
-	var sb strings.Builder
-	sb.WriteString("<div class=\"table-scroll\">\n")
-	sb.WriteString("<table class=\"sortable\">\n")
-
-	headerCells := parseTableCells(lines[0])
-	sb.WriteString("<thead><tr>")
-	for colIdx, cell := range headerCells {
-		sb.WriteString(fmt.Sprintf("<th onclick=\"sortTable(this, %d)\" class=\"sortable-th\">%s <span class=\"sort-icon\">&#x25B4;&#x25BE;</span></th>", colIdx, html.EscapeString(cell)))
-	}
-	sb.WriteString("</tr></thead>\n")
-
-	sb.WriteString("<tbody>\n")
-	for i := 1; i < len(lines); i++ {
-		if isTableSeparator(lines[i]) {
-			continue
-		}
-		cells := parseTableCells(lines[i])
-		sb.WriteString("<tr>")
-		for _, cell := range cells {
-			sb.WriteString(fmt.Sprintf("<td>%s</td>", processInlineHTML(cell)))
-		}
-		sb.WriteString("</tr>\n")
-	}
-	sb.WriteString("</tbody>\n</table>\n</div>\n")
-
-	return sb.String()
-}
-
 // searchOverlayHTML returns the search overlay markup
 func searchOverlayHTML() string {
 	return `<div id="search-overlay" class="search-overlay hidden">
@@ -1279,9 +1118,7 @@ func searchOverlayHTML() string {
 }
 
 // cssStyles returns the minimal Apple-style CSS
-// Palette: Text #1d1d1f, Secondary #6e6e73, Tertiary #86868b,
-//          Surface #f5f5f7, White #fff, Border #d2d2d7, Light border #e8e8ed,
-//          Link #06c, Highlight #ffd60a
+// Palette: the variables set by themeCSS
 // Type: System font stack (body), SF Mono (code)
 // Scale: H1 40px, H2 32px, H3 24px, Body 17px, Small 14px, Caption 12px
 func cssStyles() string {
@@ -1289,8 +1126,8 @@ func cssStyles() string {
 * { margin: 0; padding: 0; box-sizing: border-box; }
 
 body {
-  background: #fff;
-  color: #1d1d1f;
+  background: var(--bg);
+  color: var(--text);
   font-family: -apple-system, BlinkMacSystemFont, 'SF Pro Text', 'SF Pro Display', 'Helvetica Neue', Helvetica, Arial, sans-serif;
   font-weight: 400;
   font-size: 17px;
@@ -1319,7 +1156,7 @@ body {
   left: 0;
   width: 280px;
   height: 100vh;
-  background: #fff;
+  background: var(--bg);
   border-right: none;
   overflow-y: auto;
   padding: 2.5rem 0;
@@ -1333,14 +1170,14 @@ body {
 .toc-toggle {
   padding: 0.5rem 1.5rem 1.25rem;
   cursor: pointer;
-  color: #1d1d1f;
+  color: var(--text);
   font-size: 15px;
   font-weight: 600;
   display: flex;
   align-items: center;
   gap: 0.5rem;
 }
-.toc-toggle:hover { color: #1d1d1f; }
+.toc-toggle:hover { color: var(--text); }
 .toc-icon { flex-shrink: 0; }
 .toc.collapsed .toc-label { display: none; }
 .toc.collapsed .toc-toggle { justify-content: center; padding-left: 0; padding-right: 0; }
@@ -1352,22 +1189,22 @@ body {
 .toc-link {
   display: block;
   padding: 0.4rem 1.5rem;
-  color: #86868b;
+  color: var(--text-tertiary);
   text-decoration: none;
   font-size: 15px;
   border-left: 3px solid transparent;
   transition: all 0.15s;
   line-height: 1.4;
 }
-.toc-link:hover { color: #1d1d1f; }
-.toc-link.active { color: #1d1d1f; font-weight: 600; border-left-color: #1d1d1f; }
+.toc-link:hover { color: var(--text); }
+.toc-link.active { color: var(--text); font-weight: 600; border-left-color: var(--text); }
 .toc-h2 { padding-left: 2rem; font-size: 15px; }
 .toc-h3 { padding-left: 2.5rem; font-size: 14px; }
 
 @media (max-width: 1100px) {
   .toc { transform: translateX(-100%); }
   .toc.open { transform: translateX(0); }
-  .toc-toggle { position: fixed; top: 0.5rem; left: 0.5rem; z-index: 101; background: #fff; border: 1px solid #d2d2d7; border-radius: 8px; padding: 0.4rem 0.75rem; font-size: 13px; box-shadow: 0 1px 3px rgba(0,0,0,0.08); }
+  .toc-toggle { position: fixed; top: 0.5rem; left: 0.5rem; z-index: 101; background: var(--bg); border: 1px solid var(--border); border-radius: 8px; padding: 0.4rem 0.75rem; font-size: 13px; box-shadow: 0 1px 3px var(--shadow); }
   .container.has-toc { margin-left: auto; }
 }
 
@@ -1375,43 +1212,47 @@ body {
 .block { margin-bottom: 3rem; }
 
 .block-header {
-  background: #f5f5f7;
-  color: #1d1d1f;
+  background: var(--surface);
+  color: var(--text);
   padding: 0.4rem 0.8rem;
   font-size: 12px;
   font-weight: 600;
   letter-spacing: 0.02em;
-  border-bottom: 1px solid #d2d2d7;
+  border-bottom: 1px solid var(--border);
 }
 
 .content { padding: 0.5rem 0; }
 
 /* --- Headers --- */
-h1, h2, h3 { position: relative; font-weight: 700; color: #1d1d1f; letter-spacing: -0.005em; }
+h1, h2, h3, h4, h5, h6 { position: relative; font-weight: 700; color: var(--text); letter-spacing: -0.005em; }
 h1 { font-size: 40px; line-height: 1.1; margin: 2.5rem 0 0.25rem; padding-left: 0; }
 h2 { font-size: 32px; line-height: 1.125; margin: 2.5rem 0 0.15rem; padding-left: 0; }
 h3 { font-size: 24px; line-height: 1.16667; margin: 2rem 0 0.1rem; padding-left: 0; }
+h4 { font-size: 20px; line-height: 1.2; margin: 1.5rem 0 0.1rem; }
+h5, h6 { font-size: 17px; line-height: 1.3; margin: 1.25rem 0 0.1rem; }
+h6 { color: var(--text-secondary); }
 
 .anchor {
   position: absolute;
   left: -1.2em;
-  color: #d2d2d7;
+  color: var(--border);
   text-decoration: none;
   font-size: 0.6em;
   top: 0.35em;
   opacity: 0;
   transition: opacity 0.15s;
 }
-h1:hover .anchor, h2:hover .anchor, h3:hover .anchor { opacity: 1; }
-.anchor:hover { color: #06c; }
+h1:hover .anchor, h2:hover .anchor, h3:hover .anchor,
+h4:hover .anchor, h5:hover .anchor, h6:hover .anchor { opacity: 1; }
+.anchor:hover { color: var(--link); }
 
 p { margin: 1em 0; }
 
-strong { color: #1d1d1f; font-weight: 600; }
+strong { color: var(--text); font-weight: 600; }
 em { font-weight: 600; font-style: normal; }
 
 /* --- Links --- */
-a { color: #06c; text-decoration: none; position: relative; }
+a { color: var(--link); text-decoration: none; position: relative; }
 a:hover { text-decoration: underline; }
 a[target="_blank"] .ext-icon {
   font-size: 0.65em;
@@ -1420,13 +1261,39 @@ a[target="_blank"] .ext-icon {
   vertical-align: super;
 }
 a[target="_blank"]:hover .ext-icon { opacity: 0.8; }
+.wikilink.broken {
+  color: var(--error);
+  text-decoration: underline dotted;
+  cursor: help;
+}
+
+/* --- Backlinks --- */
+.backlinks {
+  margin-top: 3rem;
+  padding-top: 1rem;
+  border-top: 1px solid var(--border);
+  font-size: 14px;
+}
+.backlinks-title {
+  color: var(--text-secondary);
+  font-weight: 600;
+  margin-bottom: 0.5rem;
+}
+.backlinks ul {
+  list-style: none;
+  padding: 0;
+  margin: 0;
+}
+.backlinks li {
+  margin: 0.25rem 0;
+}
 a[target="_blank"]:hover::after {
   content: attr(title);
   position: absolute;
   bottom: 100%;
   left: 0;
-  background: #1d1d1f;
-  color: #fff;
+  background: var(--text);
+  color: var(--bg);
   padding: 0.25rem 0.5rem;
   border-radius: 4px;
   font-size: 12px;
@@ -1441,8 +1308,8 @@ a[target="_blank"]:hover::after {
 
 code.inline {
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
-  color: #1d1d1f;
-  background: #f5f5f7;
+  color: var(--text);
+  background: var(--surface);
   border: none;
   padding: 0.15rem 0.4rem;
   border-radius: 4px;
@@ -1456,7 +1323,7 @@ code.inline {
   border-radius: 12px;
   overflow-x: auto;
   position: relative;
-  background: #f5f5f7;
+  background: var(--surface);
 }
 
 .code-block .code-lang {
@@ -1467,8 +1334,8 @@ code.inline {
   position: absolute;
   top: 0.75rem;
   right: 0.75rem;
-  background: #fff;
-  color: #1d1d1f;
+  background: var(--bg);
+  color: var(--text);
   border: none;
   border-radius: 6px;
   padding: 0.35rem 0.75rem;
@@ -1479,11 +1346,11 @@ code.inline {
   opacity: 0;
   transition: opacity 0.15s;
   z-index: 2;
-  box-shadow: 0 1px 3px rgba(0,0,0,0.08);
+  box-shadow: 0 1px 3px var(--shadow);
 }
 .code-block:hover .copy-btn { opacity: 1; }
-.copy-btn:hover { background: #e8e8ed; }
-.copy-btn.copied { color: #06c; }
+.copy-btn:hover { background: var(--surface-strong); }
+.copy-btn.copied { color: var(--link); }
 
 .code-block pre {
   margin: 0;
@@ -1501,23 +1368,23 @@ code.inline {
 /* --- Diff --- */
 .diff { margin: 0.75rem 0; font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace; font-size: 14px; }
 
-.diff-hunk { margin-bottom: 0.75rem; border: 1px solid #d2d2d7; border-radius: 6px; overflow: hidden; }
+.diff-hunk { margin-bottom: 0.75rem; border: 1px solid var(--border); border-radius: 6px; overflow: hidden; }
 
 .diff-hunk-header {
-  background: #f5f5f7;
+  background: var(--surface);
   padding: 0.35rem 0.75rem;
   cursor: pointer;
   user-select: none;
   font-size: 12px;
   font-family: -apple-system, BlinkMacSystemFont, 'SF Pro Text', 'Helvetica Neue', sans-serif;
-  color: #6e6e73;
-  border-bottom: 1px solid #d2d2d7;
+  color: var(--text-secondary);
+  border-bottom: 1px solid var(--border);
 }
-.diff-hunk-header:hover { color: #1d1d1f; }
+.diff-hunk-header:hover { color: var(--text); }
 .diff-hunk-toggle { display: inline-block; transition: transform 0.15s; font-size: 10px; margin-right: 0.3rem; }
 .diff-hunk.collapsed .diff-hunk-toggle { transform: rotate(-90deg); }
 .diff-hunk.collapsed .diff-hunk-body { display: none; }
-.diff-hunk-range { color: #6e6e73; font-size: 11px; }
+.diff-hunk-range { color: var(--text-secondary); font-size: 11px; }
 
 .diff-table {
   width: 100%;
@@ -1529,13 +1396,13 @@ code.inline {
 
 .diff-table tr { border-bottom: none; }
 .diff-num {
-  color: #6e6e73;
+  color: var(--text-secondary);
   text-align: right;
   padding: 0 0.4rem;
   font-size: 12px;
   user-select: none;
   vertical-align: top;
-  background: #f5f5f7;
+  background: var(--surface);
 }
 .diff-code {
   padding: 0 0.5rem;
@@ -1544,23 +1411,23 @@ code.inline {
   vertical-align: top;
 }
 
-.diff-cell-removed { background: #FEF2F2; }
-.diff-cell-added { background: #F0FDF4; }
-.diff-cell-empty { background: #fff; }
+.diff-cell-removed { background: var(--diff-removed-bg); }
+.diff-cell-added { background: var(--diff-added-bg); }
+.diff-cell-empty { background: var(--bg); }
 .diff-row-context td { background: transparent; }
-.diff-row-context .diff-code { color: #6e6e73; }
+.diff-row-context .diff-code { color: var(--text-secondary); }
 
-.diff-cell-removed .diff-num { background: #FEE2E2; color: #EF4444; }
-.diff-cell-added .diff-num { background: #DCFCE7; color: #10B981; }
+.diff-cell-removed .diff-num { background: var(--diff-removed-num-bg); color: var(--diff-removed-num); }
+.diff-cell-added .diff-num { background: var(--diff-added-num-bg); color: var(--diff-added-num); }
 
-.diff-word-del { background: #FECACA; color: #991B1B; border-radius: 2px; padding: 0 1px; }
-.diff-word-add { background: #BBF7D0; color: #166534; border-radius: 2px; padding: 0 1px; }
+.diff-word-del { background: var(--diff-word-del-bg); color: var(--diff-word-del); border-radius: 2px; padding: 0 1px; }
+.diff-word-add { background: var(--diff-word-add-bg); color: var(--diff-word-add); border-radius: 2px; padding: 0 1px; }
 
 /* --- Tables --- */
 .table-scroll {
   overflow-x: auto;
   margin: 1.5rem 0;
-  border: 1px solid #d2d2d7;
+  border: 1px solid var(--border);
   border-radius: 6px;
 }
 
@@ -1570,13 +1437,13 @@ table {
   min-width: 100%;
 }
 th, td {
-  border: 1px solid #d2d2d7;
+  border: 1px solid var(--border);
   padding: 0.4rem 0.75rem;
   text-align: left;
 }
 th {
-  background: #f5f5f7;
-  color: #1d1d1f;
+  background: var(--surface);
+  color: var(--text);
   font-weight: 600;
   font-size: 13px;
 }
@@ -1585,10 +1452,10 @@ th {
   user-select: none;
   white-space: nowrap;
 }
-.sortable-th:hover { background: #e8e8ed; }
-.sort-icon { font-size: 0.7em; color: #d2d2d7; margin-left: 0.3em; }
-.sortable-th.asc .sort-icon { color: #06c; }
-.sortable-th.desc .sort-icon { color: #06c; }
+.sortable-th:hover { background: var(--surface-strong); }
+.sort-icon { font-size: 0.7em; color: var(--border); margin-left: 0.3em; }
+.sortable-th.asc .sort-icon { color: var(--link); }
+.sortable-th.desc .sort-icon { color: var(--link); }
 
 /* --- Images --- */
 .img-wrapper {
@@ -1597,13 +1464,13 @@ th {
 .img-wrapper img {
   max-width: 100%;
   border-radius: 6px;
-  border: 1px solid #d2d2d7;
+  border: 1px solid var(--border);
   cursor: pointer;
   transition: max-width 0.2s;
 }
 .img-wrapper img.expanded { max-width: none; }
 .img-caption {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 12px;
   margin-top: 0.3rem;
 }
@@ -1614,17 +1481,131 @@ th {
 }
 
 /* --- Lists --- */
-.list-item {
-  padding-left: 1.5rem;
-  margin: 0.5em 0;
+ul, ol { padding-left: 1.5rem; margin: 1em 0; }
+li { margin: 0.5em 0; }
+li > ul, li > ol { margin: 0.25em 0; }
+li > p { margin: 0.5em 0; }
+li > .code-block, li > blockquote, li > .table-scroll { margin: 0.75rem 0; }
+ul ul { list-style-type: circle; }
+ul ul ul { list-style-type: square; }
+ol > li::marker { font-weight: 600; }
+blockquote blockquote { margin: 0.5em 0; }
+
+/* --- Block quotes --- */
+blockquote {
+  margin: 1em 0;
+  padding: 0 0 0 1rem;
+  border-left: 3px solid var(--border);
+  color: var(--text-secondary);
+}
+
+/* --- Callouts: GitHub alerts and MkDocs admonitions --- */
+.callout {
+  --callout: #0969da;
+  margin: 1.5em 0;
+  padding: 0.5rem 1rem;
+  border: 1px solid color-mix(in srgb, var(--callout) 30%, transparent);
+  border-left: 4px solid var(--callout);
+  border-radius: 6px;
+  background: color-mix(in srgb, var(--callout) 6%, transparent);
+}
+.callout > :first-child { margin-top: 0.25rem; }
+.callout > :last-child { margin-bottom: 0.25rem; }
+.callout-title {
+  color: color-mix(in srgb, var(--callout) var(--callout-strength), var(--text));
+  font-weight: 600;
+}
+.callout-title::before { display: inline-block; width: 1.4em; }
+summary.callout-title { cursor: pointer; }
+details.callout:not([open]) > summary { margin-bottom: 0.25rem; }
+.callout-note { --callout: #0969da; }
+.callout-note > .callout-title::before { content: "\2139\FE0E"; }
+.callout-abstract { --callout: #0097a7; }
+.callout-abstract > .callout-title::before { content: "\2630"; }
+.callout-info { --callout: #00838f; }
+.callout-info > .callout-title::before { content: "\2139\FE0E"; }
+.callout-tip { --callout: #1a7f37; }
+.callout-tip > .callout-title::before { content: "\2605"; }
+.callout-important { --callout: #8250df; }
+.callout-important > .callout-title::before { content: "\275D"; }
+.callout-success { --callout: #2e7d32; }
+.callout-success > .callout-title::before { content: "\2714\FE0E"; }
+.callout-question { --callout: #558b2f; }
+.callout-question > .callout-title::before { content: "?"; }
+.callout-warning { --callout: #9a6700; }
+.callout-warning > .callout-title::before { content: "\26A0\FE0E"; }
+.callout-caution { --callout: #d1242f; }
+.callout-caution > .callout-title::before { content: "\2298"; }
+.callout-failure { --callout: #c62828; }
+.callout-failure > .callout-title::before { content: "\2716\FE0E"; }
+.callout-danger { --callout: #b71c1c; }
+.callout-danger > .callout-title::before { content: "\26A1\FE0E"; }
+.callout-bug { --callout: #ad1457; }
+.callout-bug > .callout-title::before { content: "\2731"; }
+.callout-example { --callout: #6a1b9a; }
+.callout-example > .callout-title::before { content: "\2630"; }
+.callout-quote { --callout: #6e6e73; }
+.callout-quote > .callout-title::before { content: "\201C"; }
+
+/* --- Mermaid diagrams --- */
+.mermaid-block {
+  margin: 1.5rem 0;
+  overflow-x: auto;
+  text-align: center;
+}
+.mermaid-block svg {
+  max-width: 100%;
+  height: auto;
+}
+.mermaid-block pre.mermaid {
+  text-align: left;
+  background: var(--surface);
+  border-radius: 12px;
+  padding: 1rem 1.25rem;
+  font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
+  font-size: 13px;
+  overflow-x: auto;
+}
+.mermaid-title {
+  font-weight: 600;
+  margin-bottom: 0.5rem;
+}
+.mermaid-error {
+  color: var(--error);
+  font-size: 13px;
+  text-align: left;
+  margin-bottom: 0.5rem;
+}
+
+/* --- Math --- */
+math {
+  font-family: 'STIX Two Math', 'Cambria Math', 'Latin Modern Math', math;
+  font-size: 1.05em;
+}
+.math-display {
+  display: block;
+  margin: 1rem 0;
+  overflow-x: auto;
+  overflow-y: hidden;
+  text-align: center;
+}
+.math:not([data-rendered]), .math-error {
+  font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
+  font-size: 0.9em;
+}
+.math-error {
+  color: var(--error);
+}
+
+pre.plain {
+  font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
+  font-size: 13px;
+  white-space: pre-wrap;
 }
-.list-item.nested { padding-left: 3rem; }
-.bullet { color: #1d1d1f; }
-.list-num { color: #1d1d1f; font-weight: 600; }
 
 /* --- Line numbers --- */
 .line-num {
-  color: #6e6e73;
+  color: var(--text-secondary);
   display: inline-block;
   min-width: 3em;
   text-align: right;
@@ -1636,10 +1617,24 @@ th {
 
 hr {
   border: none;
-  border-top: 1px solid #d2d2d7;
+  border-top: 1px solid var(--border);
   margin: 2rem 0;
 }
 
+/* --- Task lists, strikethrough, footnotes --- */
+li:has(> input[type="checkbox"]), li:has(> p > input[type="checkbox"]) { list-style: none; }
+li > input[type="checkbox"], li > p > input[type="checkbox"] { margin: 0 0.4em 0 -1.3em; vertical-align: middle; }
+del { color: var(--text-tertiary); }
+.footnote-ref a { font-size: 0.75em; padding: 0 0.1em; }
+.footnotes {
+  margin-top: 3rem;
+  padding-top: 1rem;
+  border-top: 1px solid var(--border);
+  font-size: 14px;
+  color: var(--text-secondary);
+}
+.footnote-backref { text-decoration: none; }
+
 br { display: block; content: ""; margin: 0.3rem 0; }
 
 /* --- Search overlay --- */
@@ -1649,7 +1644,7 @@ br { display: block; content: ""; margin: 0.3rem 0; }
   left: 0;
   width: 100%;
   height: 100%;
-  background: rgba(0,0,0,0.1);
+  background: var(--overlay);
   z-index: 200;
   display: flex;
   flex-direction: column;
@@ -1659,76 +1654,76 @@ br { display: block; content: ""; margin: 0.3rem 0; }
 .search-overlay.hidden { display: none; }
 
 .search-box {
-  background: #fff;
-  border: 1px solid #d2d2d7;
+  background: var(--bg);
+  border: 1px solid var(--border);
   border-radius: 8px;
   width: 560px;
   max-width: 90vw;
   padding: 0.75rem 1rem;
-  box-shadow: 0 4px 24px rgba(0,0,0,0.08);
+  box-shadow: 0 4px 24px var(--shadow);
 }
 .search-box input {
   width: 100%;
   background: transparent;
   border: none;
-  color: #1d1d1f;
+  color: var(--text);
   font-family: -apple-system, BlinkMacSystemFont, 'SF Pro Text', 'Helvetica Neue', sans-serif;
   font-size: 17px;
   outline: none;
 }
-.search-box input::placeholder { color: #86868b; }
+.search-box input::placeholder { color: var(--text-tertiary); }
 .search-meta {
   display: flex;
   justify-content: space-between;
   font-size: 12px;
-  color: #6e6e73;
+  color: var(--text-secondary);
   margin-top: 0.3rem;
 }
 
 .search-results {
-  background: #fff;
-  border: 1px solid #d2d2d7;
+  background: var(--bg);
+  border: 1px solid var(--border);
   border-radius: 8px;
   width: 560px;
   max-width: 90vw;
   max-height: 50vh;
   overflow-y: auto;
   margin-top: 0.3rem;
-  box-shadow: 0 4px 24px rgba(0,0,0,0.08);
+  box-shadow: 0 4px 24px var(--shadow);
 }
 .search-results:empty { display: none; }
 
 .search-result {
   padding: 0.5rem 1rem;
   cursor: pointer;
-  border-bottom: 1px solid #e8e8ed;
+  border-bottom: 1px solid var(--surface-strong);
   font-size: 14px;
 }
-.search-result:hover, .search-result.active { background: #f5f5f7; }
-.search-result .sr-context { color: #6e6e73; font-size: 12px; }
-.search-result mark { background: #ffd60a; color: #1d1d1f; border-radius: 2px; padding: 0 2px; }
+.search-result:hover, .search-result.active { background: var(--surface); }
+.search-result .sr-context { color: var(--text-secondary); font-size: 12px; }
+.search-result mark { background: var(--highlight); color: #1d1d1f; border-radius: 2px; padding: 0 2px; }
 
 /* Highlight in page */
-.search-highlight { background: #ffd60a; border-radius: 2px; }
+.search-highlight { background: var(--search-highlight); border-radius: 2px; }
 
 /* --- CSV --- */
 .csv-meta {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 13px;
   margin-bottom: 0.5rem;
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
 }
 .csv-row-count {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 12px;
   margin-bottom: 0.5rem;
 }
 .csv-chart {
   margin: 1rem 0;
-  border: 1px solid #d2d2d7;
+  border: 1px solid var(--border);
   border-radius: 6px;
   padding: 1rem;
-  background: #fff;
+  background: var(--bg);
 }
 .csv-svg {
   width: 100%;
@@ -1737,22 +1732,22 @@ br { display: block; content: ""; margin: 0.3rem 0; }
 }
 .csv-table .filter-row th {
   padding: 0.3rem 0.4rem;
-  background: #fff;
-  border-bottom: 1px solid #d2d2d7;
+  background: var(--bg);
+  border-bottom: 1px solid var(--border);
 }
 .col-filter {
   width: 100%;
   padding: 0.25rem 0.4rem;
   font-size: 12px;
   font-family: -apple-system, BlinkMacSystemFont, 'SF Pro Text', 'Helvetica Neue', sans-serif;
-  border: 1px solid #d2d2d7;
+  border: 1px solid var(--border);
   border-radius: 3px;
-  background: #f5f5f7;
-  color: #1d1d1f;
+  background: var(--surface);
+  color: var(--text);
   outline: none;
   box-sizing: border-box;
 }
-.col-filter:focus { border-color: #06c; }
+.col-filter:focus { border-color: var(--link); }
 
 /* --- Transcript --- */
 .transcript {
@@ -1763,8 +1758,8 @@ br { display: block; content: ""; margin: 0.3rem 0; }
 .transcript-header {
   position: sticky;
   top: 0;
-  background: #fff;
-  border-bottom: 1px solid #d2d2d7;
+  background: var(--bg);
+  border-bottom: 1px solid var(--border);
   padding: 0.75rem 0;
   margin-bottom: 1.5rem;
   z-index: 50;
@@ -1775,28 +1770,28 @@ br { display: block; content: ""; margin: 0.3rem 0; }
 .transcript-title {
   font-size: 14px;
   font-weight: 600;
-  color: #1d1d1f;
+  color: var(--text);
 }
 .transcript-meta {
   font-size: 12px;
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
 }
 .turn {
   margin-bottom: 1.5rem;
   padding-bottom: 1.5rem;
-  border-bottom: 1px solid #e8e8ed;
+  border-bottom: 1px solid var(--surface-strong);
 }
 .turn:last-child { border-bottom: none; }
 .turn-gutter {
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-size: 12px;
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
   margin-bottom: 0.5rem;
 }
 .turn-user {
-  background: #f5f5f7;
-  color: #1d1d1f;
+  background: var(--surface);
+  color: var(--text);
   padding: 0.75rem 1rem;
   border-radius: 6px;
   margin-bottom: 0.75rem;
@@ -1819,30 +1814,30 @@ br { display: block; content: ""; margin: 0.3rem 0; }
 }
 .turn-diff-header {
   font-size: 12px;
-  color: #6e6e73;
+  color: var(--text-secondary);
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
   margin-bottom: 0.25rem;
 }
 .turn-tool {
   margin: 0.5rem 0;
-  border-left: 3px solid #d2d2d7;
+  border-left: 3px solid var(--border);
   padding-left: 0.75rem;
 }
 .turn-tool summary {
   font-size: 13px;
-  color: #6e6e73;
+  color: var(--text-secondary);
   cursor: pointer;
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
   padding: 0.25rem 0;
 }
-.turn-tool summary:hover { color: #1d1d1f; }
+.turn-tool summary:hover { color: var(--text); }
 .turn-tool pre {
   font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
   font-size: 13px;
   white-space: pre-wrap;
   word-break: break-word;
-  color: #1d1d1f;
-  background: #f5f5f7;
+  color: var(--text);
+  background: var(--surface);
   padding: 0.5rem 0.75rem;
   border-radius: 4px;
   margin-top: 0.25rem;
@@ -1850,8 +1845,8 @@ br { display: block; content: ""; margin: 0.3rem 0; }
   overflow-y: auto;
 }
 .turn-question {
-  background: #f5f5f7;
-  border: 1px solid #d2d2d7;
+  background: var(--surface);
+  border: 1px solid var(--border);
   border-radius: 6px;
   padding: 0.75rem 1rem;
   margin: 0.5rem 0;
@@ -1862,35 +1857,124 @@ br { display: block; content: ""; margin: 0.3rem 0; }
   white-space: pre-wrap;
   word-break: break-word;
   margin: 0;
-  color: #1d1d1f;
+  color: var(--text);
   background: transparent;
 }
+
+/* --- Parse diagnostics --- */
+.diagnostics {
+  margin: 0 0 2rem;
+  border: 1px solid var(--border);
+  border-left: 3px solid var(--warning);
+  border-radius: 6px;
+  padding: 0.5rem 0.75rem;
+  font-size: 14px;
+}
+.diagnostics.has-errors { border-left-color: var(--danger); }
+.diagnostics[open] { padding-bottom: 0.75rem; }
+.diagnostics summary {
+  cursor: pointer;
+  color: var(--text-secondary);
+  font-weight: 500;
+}
+.diagnostics ul { list-style: none; margin-top: 0.5rem; }
+.diagnostics li { padding: 0.15rem 0; }
+.diag-pos, .diag-severity {
+  font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
+  font-size: 13px;
+}
+.diag-pos { color: var(--text-secondary); }
+.diag-error .diag-severity { color: var(--danger); }
+.diag-warning .diag-severity { color: var(--warning); }
+.diag-info .diag-severity { color: var(--info); }
+
+/* --- Frontmatter metadata --- */
+.frontmatter {
+  margin: 0 0 2rem;
+  border: 1px solid var(--border);
+  border-radius: 6px;
+  padding: 0.5rem 0.75rem;
+  font-size: 14px;
+}
+.frontmatter[open] { padding-bottom: 0.75rem; }
+.frontmatter summary {
+  cursor: pointer;
+  color: var(--text-secondary);
+  font-weight: 500;
+}
+.frontmatter-count { font-weight: 400; margin-left: 0.25rem; }
+.frontmatter-error { color: var(--error); margin-top: 0.5rem; }
+.frontmatter dl {
+  display: grid;
+  grid-template-columns: max-content 1fr;
+  gap: 0.25rem 1rem;
+  margin-top: 0.5rem;
+}
+.frontmatter dd dl { margin-top: 0; }
+.frontmatter dt {
+  color: var(--text-secondary);
+  font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
+  font-size: 13px;
+}
+.frontmatter dd { margin: 0; min-width: 0; overflow-wrap: anywhere; }
+.frontmatter ul { margin: 0; padding-left: 1.1rem; }
+.fm-literal {
+  font-family: 'SF Mono', SFMono-Regular, ui-monospace, Menlo, monospace;
+  font-size: 13px;
+  color: var(--info);
+}
+.fm-chip {
+  display: inline-block;
+  background: var(--surface);
+  border: 1px solid var(--border);
+  padding: 0 0.45rem;
+  border-radius: 3px;
+  font-size: 12px;
+}
 `
 }
 
 // RenderStaticHTMLPage renders blocks as a self-contained HTML document (no CDN, no SSE)
-func RenderStaticHTMLPage(title string, blocks []Block, showLineNums bool) string {
+func RenderStaticHTMLPage(title string, blocks []parse.Block, showLineNums bool, diags ...parse.Diagnostic) string {
+	return renderStaticPage(title, blocks, showLineNums, nil, diags)
+}
+
+// RenderStaticHTMLBundle renders the document at docPath like
+// RenderStaticHTMLPage, bundling the local files its markdown references:
+// images in the document's directory are inlined as data URIs and linked
+// markdown files are appended as sections that the links jump to.
+func RenderStaticHTMLBundle(docPath string, title string, blocks []parse.Block, showLineNums bool, diags ...parse.Diagnostic) string {
+	files := openLocalFiles(docPath)
+	defer files.close()
+	return renderStaticPage(title, blocks, showLineNums, files, diags)
+}
+
+// renderStaticPage renders a self-contained page, bundling local files when
+// files is set
+func renderStaticPage(title string, blocks []parse.Block, showLineNums bool, files *localFiles, diags []parse.Diagnostic) string {
 	var sb strings.Builder
 
-	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	sb.WriteString("<!DOCTYPE html>\n" + htmlOpenTag() + "\n<head>\n")
 	sb.WriteString("<meta charset=\"UTF-8\">\n")
 	sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
 	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(title)))
 
-	// Inline highlight.js CSS (no CDN)
-	sb.WriteString("<style>\n")
-	sb.WriteString(highlightCSS)
-	sb.WriteString("\n</style>\n")
+	// Inline highlight.js CSS, light and dark themes (no CDN)
+	sb.WriteString(highlightThemesHTML(true))
 
 	// Inline highlight.js JS (no CDN)
 	sb.WriteString("<script>\n")
 	sb.WriteString(highlightJS)
 	sb.WriteString("\n</script>\n")
+	sb.WriteString("<script>\n" + themeScript() + "</script>\n")
 
 	sb.WriteString("<style>\n")
+	sb.WriteString(themeCSS())
 	sb.WriteString(cssStyles())
 	sb.WriteString("</style>\n")
+	sb.WriteString(customStylesHTML())
 	sb.WriteString("</head>\n<body>\n")
+	sb.WriteString(themeToggleHTML())
 
 	transcript := isTranscriptContent(blocks)
 
@@ -1901,9 +1985,10 @@ func RenderStaticHTMLPage(title string, blocks []Block, showLineNums bool) strin
 		sb.WriteString(fmt.Sprintf("<div class=\"transcript-title\">%s</div>\n", html.EscapeString(title)))
 		sb.WriteString(fmt.Sprintf("<div class=\"transcript-meta\">%d turns</div>\n", len(blocks)))
 		sb.WriteString("</div>\n")
+		sb.WriteString(diagnosticsPanelHTML(diags))
 
 		for i := range blocks {
-			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, false))
+			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, false, nil))
 		}
 
 		sb.WriteString("</main>\n")
@@ -1941,11 +2026,13 @@ func RenderStaticHTMLPage(title string, blocks []Block, showLineNums bool) strin
 			containerClass = "container has-toc"
 		}
 		sb.WriteString(fmt.Sprintf("<main class=\"%s\">\n", containerClass))
+		sb.WriteString(diagnosticsPanelHTML(diags))
 
 		singleBlock := len(blocks) == 1
 		for i := range blocks {
-			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, singleBlock))
+			sb.WriteString(formatBlockHTML(&blocks[i], showLineNums, singleBlock, files.mainPage()))
 		}
+		sb.WriteString(files.pagesHTML())
 
 		sb.WriteString("</main>\n")
 
@@ -1957,11 +2044,25 @@ func RenderStaticHTMLPage(title string, blocks []Block, showLineNums bool) strin
 		sb.WriteString("</script>\n")
 	}
 
+	sb.WriteString(rendererScriptsHTML(sb.String()))
 	sb.WriteString("</body>\n</html>\n")
 
 	return sb.String()
 }
 
+// rendererScriptsHTML returns the embedded diagram and math renderers needed
+// by a rendered page body, so pages without them stay small
+func rendererScriptsHTML(body string) string {
+	var sb strings.Builder
+	if strings.Contains(body, "<pre class=\"mermaid\">") {
+		sb.WriteString("<script>\n" + mermaidJS + "\n</script>\n")
+	}
+	if strings.Contains(body, "class=\"math math-") {
+		sb.WriteString("<script>\n" + mathJS + "\n</script>\n")
+	}
+	return sb.String()
+}
+
 // staticScript returns JavaScript for static export (no SSE live reload)
 func staticScript() string {
 	return `
@@ -2066,7 +2167,7 @@ function toggleHunk(id) {
   document.querySelectorAll('.block').forEach(function(block) {
     var header = block.querySelector('.block-header');
     var blockName = header ? header.textContent : '';
-    block.querySelectorAll('p, h1, h2, h3, .list-item, .code-block code, td, th').forEach(function(el) {
+    block.querySelectorAll('p, h1, h2, h3, h4, h5, h6, li:not(:has(> p)), .code-block code, td, th').forEach(function(el) {
       var text = el.textContent || '';
       if (text.trim()) {
         searchItems.push({ text: text.trim(), el: el, blockName: blockName });
@@ -2350,7 +2451,7 @@ function toggleHunk(id) {
   document.querySelectorAll('.block').forEach(function(block) {
     var header = block.querySelector('.block-header');
     var blockName = header ? header.textContent : '';
-    block.querySelectorAll('p, h1, h2, h3, .list-item, .code-block code, td, th').forEach(function(el) {
+    block.querySelectorAll('p, h1, h2, h3, h4, h5, h6, li:not(:has(> p)), .code-block code, td, th').forEach(function(el) {
       var text = el.textContent || '';
       if (text.trim()) {
         searchItems.push({ text: text.trim(), el: el, blockName: blockName });
@@ -0,0 +1,218 @@
+package html
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/parse/markdown"
+)
+
+// formatMarkdownHTML renders markdown content as HTML
+func formatMarkdownHTML(text string, block *parse.Block, pageNum int, showLineNums bool, page *pageLinks) string {
+	// Determine starting line number for this page
+	startLine := 0
+	if showLineNums && len(block.PageStartLine) > pageNum {
+		startLine = block.PageStartLine[pageNum]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<div class=\"content\">\n")
+	sb.WriteString(markdownRenderer(startLine, page).Render(markdown.Parse(text)))
+	sb.WriteString("</div>\n")
+	return sb.String()
+}
+
+// formatPlainHTML renders preformatted text (shell output) line by line
+func formatPlainHTML(text string, startLine int) string {
+	var sb strings.Builder
+	sb.WriteString("<div class=\"content\">\n<pre class=\"plain\">")
+	for i, line := range strings.Split(text, "\n") {
+		if startLine > 0 {
+			sb.WriteString(fmt.Sprintf("<span class=\"line-num\">%d</span>", startLine+i))
+		}
+		sb.WriteString(html.EscapeString(line))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</pre>\n</div>\n")
+	return sb.String()
+}
+
+// processInlineHTML renders a single line of inline markdown: emphasis, code,
+// links and images
+func processInlineHTML(text string) string {
+	r := markdownRenderer(0, nil)
+	var sb strings.Builder
+	for n := markdown.ParseInline(text).FirstChild; n != nil; n = n.NextSibling {
+		sb.WriteString(r.Render(n))
+	}
+	return sb.String()
+}
+
+// markdownRenderer returns the page renderer: raw HTML is escaped, URLs are
+// sanitized, headings get anchors, code blocks get copy buttons, external links
+// open in a new tab and tables are sortable and aligned. Line numbers are shown
+// when startLine is positive. A page from a bundle has its local images and
+// markdown links resolved.
+func markdownRenderer(startLine int, page *pageLinks) *markdown.HTMLRenderer {
+	lineNum := func(n *markdown.Node) string {
+		if startLine <= 0 {
+			return ""
+		}
+		return fmt.Sprintf("<span class=\"line-num\">%d</span>", startLine+n.Line-1)
+	}
+
+	r := &markdown.HTMLRenderer{SanitizeURL: sanitizeURL}
+	r.Override = func(w *markdown.HTMLWriter, n *markdown.Node) bool {
+		switch n.Kind {
+		case markdown.Heading:
+			id := page.headingID(headerID(markdown.PlainText(n)))
+			w.CR()
+			w.Raw(fmt.Sprintf("<h%d id=\"%s\">%s<a class=\"anchor\" href=\"#%s\">#</a>", n.Level, id, lineNum(n), id))
+			w.Children(n)
+			w.Raw(fmt.Sprintf("</h%d>\n", n.Level))
+
+		case markdown.Paragraph:
+			// A paragraph holding only an image becomes a captioned figure
+			if img := n.FirstChild; img != nil && img == n.LastChild && img.Kind == markdown.Image && !markdown.InTightList(n) {
+				w.ImageAlt(img, func(alt string) {
+					w.CR()
+					w.Raw(fmt.Sprintf("<div class=\"img-wrapper\">%s<img src=\"%s\" alt=\"%s\" loading=\"lazy\" onclick=\"this.classList.toggle('expanded')\"><div class=\"img-caption\">%s</div></div>\n",
+						lineNum(n), imageSrc(w, page, img), alt, alt))
+				})
+				return true
+			}
+			if markdown.InTightList(n) {
+				w.Raw(lineNum(n))
+				w.Children(n)
+				return true
+			}
+			w.CR()
+			w.Raw("<p>" + lineNum(n))
+			w.Children(n)
+			w.Raw(markdown.FootnoteBackrefs(n))
+			w.Raw("</p>\n")
+
+		case markdown.CodeBlock:
+			// highlight.js class and copy button
+			lang := markdown.InfoLanguage(n.Info)
+			if lang == "mermaid" {
+				// Drawn in the browser by the embedded mermaid.js
+				w.CR()
+				w.Raw(fmt.Sprintf("<div class=\"mermaid-block\">%s<pre class=\"mermaid\">", lineNum(n)))
+				w.Text(n.Literal)
+				w.Raw("</pre></div>\n")
+				return true
+			}
+			langClass := ""
+			langLabel := ""
+			if lang != "" {
+				langClass = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(lang))
+				langLabel = fmt.Sprintf("<span class=\"code-lang\">%s</span>", html.EscapeString(lang))
+			}
+			w.CR()
+			w.Raw(fmt.Sprintf("<div class=\"code-block\">%s<button class=\"copy-btn\" onclick=\"copyCode(this)\" title=\"Copy\">Copy</button><pre><code%s>", langLabel, langClass))
+			w.Text(n.Literal)
+			w.Raw("</code></pre></div>\n")
+
+		case markdown.Table:
+			w.CR()
+			w.Raw("<div class=\"table-scroll\">\n<table class=\"sortable\">\n")
+			for row := n.FirstChild; row != nil; row = row.NextSibling {
+				if row.Header {
+					w.Raw("<thead>")
+					w.Node(row)
+					w.Raw("</thead>\n<tbody>\n")
+				} else {
+					w.Node(row)
+				}
+			}
+			w.Raw("</tbody>\n</table>\n</div>\n")
+
+		case markdown.TableRow:
+			w.Raw("<tr>")
+			col := 0
+			for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+				align := ""
+				if a := cell.Align.String(); a != "" {
+					align = fmt.Sprintf(" style=\"text-align:%s\"", a)
+				}
+				if cell.Header {
+					w.Raw(fmt.Sprintf("<th onclick=\"sortTable(this, %d)\" class=\"sortable-th\"%s>", col, align))
+					w.Children(cell)
+					w.Raw(" <span class=\"sort-icon\">&#x25B4;&#x25BE;</span></th>")
+				} else {
+					w.Raw(fmt.Sprintf("<td%s>", align))
+					w.Children(cell)
+					w.Raw("</td>")
+				}
+				col++
+			}
+			w.Raw("</tr>\n")
+
+		case markdown.CodeSpan:
+			w.Tag("<code class=\"inline\">")
+			w.Text(n.Literal)
+			w.Tag("</code>")
+
+		case markdown.Link:
+			// Local links stay in this tab: headings and bundled pages of an
+			// exported document, or relative paths the server resolves
+			href, local := page.link(n.Destination)
+			if !local && isLocalURL(n.Destination) {
+				href, local = w.URL(n.Destination), true
+			}
+			if local {
+				w.Tag(fmt.Sprintf("<a href=\"%s\">", markdown.EscapeHTML(href)))
+				w.Children(n)
+				w.Tag("</a>")
+				break
+			}
+			// Open in new tab with external icon
+			href = markdown.EscapeHTML(w.URL(n.Destination))
+			title := href
+			if n.Title != "" {
+				title = markdown.EscapeHTML(n.Title)
+			}
+			w.Tag(fmt.Sprintf("<a href=\"%s\" target=\"_blank\" rel=\"noopener\" title=\"%s\">", href, title))
+			w.Children(n)
+			w.Tag("<span class=\"ext-icon\">&#x2197;</span></a>")
+
+		case markdown.WikiLink:
+			href, ok := page.wikiLink(n.Destination)
+			if !ok {
+				// Flag links to notes that don't exist
+				w.Tag(fmt.Sprintf("<span class=\"wikilink broken\" title=\"No note named %s\">", markdown.EscapeHTML(n.Destination)))
+				w.Children(n)
+				w.Tag("</span>")
+				break
+			}
+			if bundled, ok := page.link(href); ok {
+				href = bundled
+			}
+			w.Tag(fmt.Sprintf("<a class=\"wikilink\" href=\"%s\">", markdown.EscapeHTML(href)))
+			w.Children(n)
+			w.Tag("</a>")
+
+		case markdown.Image:
+			w.ImageAlt(n, func(alt string) {
+				w.Raw(fmt.Sprintf("<img class=\"inline-img\" src=\"%s\" alt=\"%s\" loading=\"lazy\">", imageSrc(w, page, n), alt))
+			})
+
+		default:
+			return false
+		}
+		return true
+	}
+	return r
+}
+
+// imageSrc returns the escaped src of an image, inlined when it is a local
+// file of a bundled page
+func imageSrc(w *markdown.HTMLWriter, page *pageLinks, img *markdown.Node) string {
+	if data := page.image(img.Destination); data != "" {
+		return data
+	}
+	return markdown.EscapeHTML(w.URL(img.Destination))
+}
@@ -0,0 +1,199 @@
+package html
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/parse/markdown"
+)
+
+// localFiles bundles the files an exported document references by relative
+// path: images become data URIs and linked markdown files are appended to
+// the page as extra sections. Files are read through an os.Root, so nothing
+// outside the document's directory is included.
+type localFiles struct {
+	root  *os.Root
+	self  string            // the document's path within root
+	ids   map[string]string // bundled markdown path -> section id
+	queue []string          // bundled markdown paths, in link order
+}
+
+// pageLinks resolves the links and images of a markdown page: local files
+// when the page is part of an exported bundle, and wiki links when it is a
+// note in a notes directory
+type pageLinks struct {
+	files *localFiles
+	path  string // within the bundle root
+	id    string // section id of a bundled page; empty for the main document
+	wiki  *Wiki
+}
+
+// openLocalFiles opens the directory of the document at docPath for
+// bundling, returning nil when it can't be read. The caller closes it once
+// the page is rendered.
+func openLocalFiles(docPath string) *localFiles {
+	root, err := os.OpenRoot(filepath.Dir(docPath))
+	if err != nil {
+		return nil
+	}
+	return &localFiles{root: root, self: filepath.Base(docPath), ids: make(map[string]string)}
+}
+
+// close releases the document's directory
+func (f *localFiles) close() {
+	if f != nil {
+		f.root.Close()
+	}
+}
+
+// mainPage returns the document itself as a page, or nil without a bundle
+func (f *localFiles) mainPage() *pageLinks {
+	if f == nil {
+		return nil
+	}
+	return &pageLinks{files: f, path: f.self}
+}
+
+// pagesHTML renders the bundled markdown files as sections. Pages linked
+// from bundled pages are bundled too.
+func (f *localFiles) pagesHTML() string {
+	if f == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for i := 0; i < len(f.queue); i++ {
+		p := f.queue[i]
+		data, err := fs.ReadFile(f.root.FS(), p)
+		if err != nil {
+			continue
+		}
+		_, body := parse.ParseFrontmatter(string(data))
+		page := &pageLinks{files: f, path: p, id: f.ids[p]}
+		sb.WriteString(fmt.Sprintf("<article class=\"block bundled-page\" id=\"%s\">\n", page.id))
+		sb.WriteString(fmt.Sprintf("<header class=\"block-header\">%s</header>\n", html.EscapeString(p)))
+		sb.WriteString("<div class=\"content\">\n")
+		sb.WriteString(markdownRenderer(0, page).Render(markdown.Parse(body)))
+		sb.WriteString("</div>\n</article>\n")
+	}
+	return sb.String()
+}
+
+// resolve splits a relative URL into its cleaned path within the bundle
+// root, taken from the page's directory, and its fragment. ok is false for
+// URLs with a scheme or host, absolute paths and paths leaving the root.
+func (p *pageLinks) resolve(dest string) (target string, fragment string, ok bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" || strings.HasPrefix(u.Path, "/") {
+		return "", "", false
+	}
+	if u.Path == "" {
+		return p.path, u.Fragment, true
+	}
+	target = path.Clean(path.Join(path.Dir(p.path), u.Path))
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return "", "", false
+	}
+	return target, u.Fragment, true
+}
+
+// image returns a data URI for a local image, or "" when dest is not one
+func (p *pageLinks) image(dest string) string {
+	if p == nil || p.files == nil {
+		return ""
+	}
+	target, _, ok := p.resolve(dest)
+	if !ok {
+		return ""
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(target)))
+	if !strings.HasPrefix(mimeType, "image/") {
+		return ""
+	}
+	data, err := fs.ReadFile(p.files.root.FS(), target)
+	if err != nil {
+		return ""
+	}
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// link returns the in-page href for a link to a heading or to a local
+// markdown file, bundling the file. ok is false for other links.
+func (p *pageLinks) link(dest string) (href string, ok bool) {
+	if p == nil || p.files == nil {
+		return "", false
+	}
+	target, fragment, ok := p.resolve(dest)
+	if !ok {
+		return "", false
+	}
+	if target == p.path || target == p.files.self {
+		id := p.id
+		if target == p.files.self {
+			id = ""
+		}
+		return "#" + prefixID(id, fragment), true
+	}
+	if !isMarkdownPath(target) {
+		return "", false
+	}
+	id, seen := p.files.ids[target]
+	if !seen {
+		if info, err := p.files.root.Stat(target); err != nil || info.IsDir() {
+			return "", false
+		}
+		id = "page-" + headerID(target)
+		p.files.ids[target] = id
+		p.files.queue = append(p.files.queue, target)
+	}
+	if fragment == "" {
+		return "#" + id, true
+	}
+	return "#" + prefixID(id, fragment), true
+}
+
+// headingID returns the anchor of a heading, unique across bundled pages
+func (p *pageLinks) headingID(id string) string {
+	if p == nil {
+		return id
+	}
+	return prefixID(p.id, id)
+}
+
+// prefixID joins a page's section id and an anchor within it
+func prefixID(pageID, id string) string {
+	switch {
+	case pageID == "":
+		return id
+	case id == "":
+		return pageID
+	}
+	return pageID + "-" + id
+}
+
+// isMarkdownPath reports whether p names a markdown file
+func isMarkdownPath(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// isLocalURL reports whether a link stays within the site: a relative path
+// or a fragment, rather than a URL with a scheme or host
+func isLocalURL(dest string) bool {
+	u, err := url.Parse(dest)
+	return err == nil && u.Scheme == "" && u.Host == "" && !strings.HasPrefix(dest, "//")
+}
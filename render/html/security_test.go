@@ -0,0 +1,333 @@
+package html
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wildreason/reader/parse"
+)
+
+// --- URL sanitization ---
+
+func TestSanitizeURL_BlocksJavascript(t *testing.T) {
+	dangerous := []string{
+		"javascript:alert(1)",
+		"JavaScript:alert(1)",
+		"JAVASCRIPT:ALERT(1)",
+		"javascript:void(0)",
+		"  javascript:alert(1)",
+	}
+	for _, u := range dangerous {
+		if result := sanitizeURL(u); result != "#" {
+			t.Errorf("sanitizeURL(%q) = %q, want %q", u, result, "#")
+		}
+	}
+}
+
+func TestSanitizeURL_BlocksDataAndVbscript(t *testing.T) {
+	dangerous := []string{
+		"data:text/html,<script>alert(1)</script>",
+		"DATA:text/html,payload",
+		"vbscript:MsgBox(1)",
+		"VBSCRIPT:payload",
+	}
+	for _, u := range dangerous {
+		if result := sanitizeURL(u); result != "#" {
+			t.Errorf("sanitizeURL(%q) = %q, want %q", u, result, "#")
+		}
+	}
+}
+
+func TestSanitizeURL_AllowsSafeSchemes(t *testing.T) {
+	safe := []string{
+		"https://example.com",
+		"http://example.com",
+		"mailto:user@example.com",
+		"#section-1",
+		"/relative/path",
+		"../file.md",
+	}
+	for _, u := range safe {
+		if result := sanitizeURL(u); result != u {
+			t.Errorf("sanitizeURL(%q) = %q, want unchanged", u, result)
+		}
+	}
+}
+
+// --- Inline HTML rendering ---
+
+func TestProcessInlineHTML_SanitizesLinkHref(t *testing.T) {
+	input := `[click me](javascript:alert(1))`
+	result := processInlineHTML(input)
+	if strings.Contains(result, "javascript:") {
+		t.Errorf("processInlineHTML should sanitize javascript: URLs, got: %s", result)
+	}
+	if !strings.Contains(result, `href="#"`) {
+		t.Errorf("processInlineHTML should replace dangerous href with #, got: %s", result)
+	}
+}
+
+func TestProcessInlineHTML_SanitizesImageSrc(t *testing.T) {
+	input := `![xss](javascript:alert(1))`
+	result := processInlineHTML(input)
+	if strings.Contains(result, "javascript:") {
+		t.Errorf("processInlineHTML should sanitize javascript: in image src, got: %s", result)
+	}
+}
+
+func TestProcessInlineHTML_EscapesHTMLInText(t *testing.T) {
+	input := `<script>alert(1)</script>`
+	result := processInlineHTML(input)
+	if strings.Contains(result, "<script>") {
+		t.Errorf("processInlineHTML should escape HTML tags, got: %s", result)
+	}
+	if !strings.Contains(result, "&lt;script&gt;") {
+		t.Errorf("processInlineHTML should produce escaped tags, got: %s", result)
+	}
+}
+
+func TestProcessInlineHTML_PreservesNormalLinks(t *testing.T) {
+	input := `[docs](https://example.com/docs)`
+	result := processInlineHTML(input)
+	if !strings.Contains(result, `href="https://example.com/docs"`) {
+		t.Errorf("processInlineHTML should preserve https links, got: %s", result)
+	}
+	if !strings.Contains(result, ">docs<") {
+		t.Errorf("processInlineHTML should render link text, got: %s", result)
+	}
+}
+
+// --- Video block HTML ---
+
+func TestVideoBlockHTML_EscapesTitle(t *testing.T) {
+	block := parse.Block{
+		Name:        `<img src=x onerror=alert(1)>.mp4`,
+		ContentType: parse.BlockContentVideo,
+		Data: &parse.VideoData{
+			Src:  "/video",
+			MIME: "video/mp4",
+		},
+	}
+	result := formatVideoBlockHTML(&block)
+	if strings.Contains(result, `<img src=x`) {
+		t.Errorf("formatVideoBlockHTML should escape title, got unescaped HTML in output")
+	}
+}
+
+func TestVideoBlockHTML_EscapesMime(t *testing.T) {
+	block := parse.Block{
+		Name:        "test.mp4",
+		ContentType: parse.BlockContentVideo,
+		Data: &parse.VideoData{
+			Src:  "/video",
+			MIME: `"><script>alert(1)</script>`,
+		},
+	}
+	result := formatVideoBlockHTML(&block)
+	if strings.Contains(result, "<script>alert") {
+		t.Errorf("formatVideoBlockHTML should escape mime type")
+	}
+}
+
+// --- Contract renderer ---
+
+func TestContractPipeline_EscapesFrontmatter(t *testing.T) {
+	content := "---\ntitle: <script>alert(\"xss\")</script>\ntype: contract\nparties: <img src=x onerror=alert(1)>\neffective: 2026-01-01\" onclick=\"alert(1)\n---\n## 1. Test\n\nBody text."
+	parser := &parse.ContractParser{}
+	blocks := parser.Parse(content)
+	if len(blocks) == 0 {
+		t.Fatal("parse.ContractParser should produce blocks")
+	}
+	result := RenderStaticHTMLPage("Test", blocks, false)
+
+	if strings.Contains(result, "<script>alert") {
+		t.Errorf("Contract pipeline should escape title")
+	}
+	if strings.Contains(result, `<img src=x`) {
+		t.Errorf("Contract pipeline should escape parties")
+	}
+	if strings.Contains(result, `"alert(1)`) {
+		t.Errorf("Contract pipeline should escape quotes in effective date")
+	}
+}
+
+// --- Diagnostics panel ---
+
+func TestDiagnosticsPanel_EscapesMessage(t *testing.T) {
+	diags := []parse.Diagnostic{{Line: 2, Column: 5, Severity: parse.SeverityError, Message: "bad <script>alert(1)</script>"}}
+	result := RenderStaticHTMLPage("Test", []parse.Block{{Name: "x", Pages: []string{"x"}, TotalPages: 1}}, false, diags...)
+
+	if strings.Contains(result, "<script>alert") {
+		t.Errorf("Diagnostics panel should escape messages")
+	}
+	if !strings.Contains(result, `<details class="diagnostics has-errors" open>`) {
+		t.Errorf("Expected an open diagnostics panel for errors")
+	}
+}
+
+// --- Mermaid diagrams ---
+
+func TestMermaidBlock_EscapesSourceAndEmbedsRenderer(t *testing.T) {
+	content := "```mermaid\ngraph TD\n  A[\"</pre><script>alert(1)</script>\"] --> B\n```"
+	block := parse.Block{Name: "x", Content: content, Pages: []string{content}, TotalPages: 1}
+	result := RenderStaticHTMLPage("Test", []parse.Block{block}, false)
+
+	if !strings.Contains(result, `<pre class="mermaid">graph TD`) {
+		t.Errorf("Expected mermaid source in a pre.mermaid element")
+	}
+	if strings.Contains(result, "<script>alert") {
+		t.Errorf("Mermaid source should be escaped")
+	}
+	if !strings.Contains(result, "window.asterMermaid") || strings.Contains(result, "cdn") {
+		t.Errorf("Expected the diagram renderer inlined without a CDN")
+	}
+
+	plain := RenderStaticHTMLPage("Test", []parse.Block{{Name: "x", Content: "text", Pages: []string{"text"}, TotalPages: 1}}, false)
+	if strings.Contains(plain, "window.asterMermaid") {
+		t.Errorf("Renderer should only be included for documents with diagrams")
+	}
+}
+
+// --- Math ---
+
+func TestMath_EscapesSourceAndEmbedsRenderer(t *testing.T) {
+	content := "Inline $a < b$ and\n\n$$\n\\frac{1}{2} </div><script>alert(1)</script>\n$$"
+	block := parse.Block{Name: "x", Content: content, Pages: []string{content}, TotalPages: 1}
+	result := RenderStaticHTMLPage("Test", []parse.Block{block}, false)
+
+	if !strings.Contains(result, `<span class="math math-inline">a &lt; b</span>`) {
+		t.Errorf("Expected escaped inline math span")
+	}
+	if !strings.Contains(result, `<div class="math math-display">\frac{1}{2} &lt;/div&gt;`) {
+		t.Errorf("Expected escaped display math block")
+	}
+	if strings.Contains(result, "<script>alert") {
+		t.Errorf("Math source should be escaped")
+	}
+	if !strings.Contains(result, "window.asterMath") {
+		t.Errorf("Expected the math renderer inlined")
+	}
+
+	plain := RenderStaticHTMLPage("Test", []parse.Block{{Name: "x", Content: "costs $5 or $10", Pages: []string{"costs $5 or $10"}, TotalPages: 1}}, false)
+	if strings.Contains(plain, "window.asterMath") {
+		t.Errorf("Renderer should only be included for documents with math")
+	}
+}
+
+func TestProcessInlineHTML_LeavesMathAlone(t *testing.T) {
+	got := processInlineHTML("see $a*b*c_1 + d_2$ and *this*")
+	if !strings.Contains(got, `<span class="math math-inline">a*b*c_1 + d_2</span>`) {
+		t.Errorf("Expected math untouched by emphasis, got %q", got)
+	}
+	if !strings.Contains(got, "<em>this</em>") {
+		t.Errorf("Expected emphasis outside math, got %q", got)
+	}
+}
+
+// --- Local files ---
+
+func TestStaticBundle_InlinesImagesAndBundlesLinks(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "img"), 0o755)
+	os.WriteFile(filepath.Join(dir, "img", "a.png"), []byte("PNG"), 0o644)
+	os.WriteFile(filepath.Join(dir, "guide.md"), []byte("# Setup\n\nBack to [readme](README.md#top).\n"), 0o644)
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.png"), []byte("SECRET"), 0o644)
+
+	content := "# Top\n\n![arch](./img/a.png) ![out](../secret.png)\n\nSee [setup](guide.md#setup) and [web](https://example.com).\n"
+	block := parse.Block{Name: "README.md", Content: content, Pages: []string{content}, TotalPages: 1}
+	result := RenderStaticHTMLBundle(filepath.Join(dir, "README.md"), "Test", []parse.Block{block}, false)
+
+	if !strings.Contains(result, `src="data:image/png;base64,UE5H"`) {
+		t.Errorf("Expected local image inlined as a data URI")
+	}
+	if !strings.Contains(result, `src="../secret.png"`) || strings.Contains(result, base64.StdEncoding.EncodeToString([]byte("SECRET"))) {
+		t.Errorf("Files outside the document directory should not be inlined")
+	}
+	if !strings.Contains(result, `<a href="#page-guide-md-setup">setup</a>`) {
+		t.Errorf("Expected link to the bundled page's heading")
+	}
+	if !strings.Contains(result, `id="page-guide-md"`) || !strings.Contains(result, `<h1 id="page-guide-md-setup">`) {
+		t.Errorf("Expected guide.md bundled with prefixed heading anchors")
+	}
+	if !strings.Contains(result, `<a href="#top">readme</a>`) {
+		t.Errorf("Expected link back to the main document to become an anchor")
+	}
+	if !strings.Contains(result, `href="https://example.com" target="_blank"`) {
+		t.Errorf("Expected external links to open in a new tab")
+	}
+}
+
+func TestStaticBundle_ClosesDirectory(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	dir := t.TempDir()
+	content := "# Top\n\n![arch](a.png)\n"
+	block := parse.Block{Name: "README.md", Content: content, Pages: []string{content}, TotalPages: 1}
+	for i := 0; i < 20; i++ {
+		RenderStaticHTMLBundle(filepath.Join(dir, "README.md"), "Test", []parse.Block{block}, false)
+	}
+	after, _ := os.ReadDir("/proc/self/fd")
+	if len(after) > len(fds)+2 {
+		t.Errorf("open files grew from %d to %d rendering bundles", len(fds), len(after))
+	}
+}
+
+func TestNotePage_EscapesWikiLinksAndBacklinks(t *testing.T) {
+	wiki := &Wiki{
+		Resolve:   func(note string) (string, bool) { return "/" + note, note == "known" },
+		Backlinks: []DocMeta{{Slug: "x\"y", Title: "<b>Other</b>"}},
+	}
+	blocks := []parse.Block{{
+		Pages:       []string{"[[known#Next Steps]] [[<img src=x onerror=alert(1)>]]"},
+		TotalPages:  1,
+		ContentType: parse.BlockContentPlain,
+	}}
+	out := RenderNotePage("Note", blocks, wiki)
+
+	if !strings.Contains(out, `<a class="wikilink" href="/known#next-steps">known#Next Steps</a>`) {
+		t.Error("resolved wiki link missing")
+	}
+	if strings.Contains(out, "<img src=x") {
+		t.Error("broken wiki link name not escaped")
+	}
+	if !strings.Contains(out, `class="wikilink broken"`) {
+		t.Error("broken wiki link not flagged")
+	}
+	if !strings.Contains(out, `<a href="/x%22y">&lt;b&gt;Other&lt;/b&gt;</a>`) {
+		t.Error("backlink not escaped")
+	}
+}
+
+func TestFrontmatterHeaderAndIndexColumns(t *testing.T) {
+	fm, body := parse.ParseFrontmatter("---\ntitle: Spec\nauthors:\n  - <b>Ann</b>\nreview:\n  status: approved\n---\nBody")
+	blocks := []parse.Block{{
+		Content:     body,
+		Pages:       []string{body},
+		TotalPages:  1,
+		ContentType: parse.BlockContentPlain,
+		Data:        &fm,
+	}}
+	out := RenderHTMLPage("Spec", blocks, false)
+	if !strings.Contains(out, `<details class="frontmatter">`) || !strings.Contains(out, "3 fields") {
+		t.Error("metadata header missing")
+	}
+	if !strings.Contains(out, `<dt>review</dt><dd><dl><dt>status</dt><dd>approved</dd></dl>`) {
+		t.Error("nested field not rendered as a nested list")
+	}
+	if strings.Contains(out, "<b>Ann</b>") {
+		t.Error("field value not escaped")
+	}
+
+	index := RenderIndexPage("docs", []DocMeta{{Slug: "spec", Title: "Spec", Frontmatter: fm}}, "review.status", "authors")
+	if !strings.Contains(index, "<th>review.status</th><th>authors</th>") {
+		t.Error("column headers missing")
+	}
+	if !strings.Contains(index, `<td>approved</td><td><span class="tag">&lt;b&gt;Ann&lt;/b&gt;</span></td>`) {
+		t.Error("column values missing or unescaped")
+	}
+}
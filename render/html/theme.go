@@ -0,0 +1,302 @@
+package html
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+)
+
+// PageTheme is the color scheme pages open in
+type PageTheme string
+
+const (
+	ThemeAuto  PageTheme = "auto" // Follow the reader's system (prefers-color-scheme)
+	ThemeLight PageTheme = "light"
+	ThemeDark  PageTheme = "dark"
+)
+
+// highlightCDN is where served pages load highlight.js and its themes from
+const highlightCDN = "https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/"
+
+// stylesheet is custom CSS added to every page: inlined text, or a link
+type stylesheet struct {
+	css  string
+	href string
+}
+
+// pageTheme and stylesheets style every page; styleMu guards them, as
+// pages may render on several goroutines
+var (
+	styleMu     sync.RWMutex
+	pageTheme   = ThemeAuto
+	stylesheets []stylesheet
+)
+
+// ParsePageTheme returns the page theme with the given name
+func ParsePageTheme(name string) (PageTheme, bool) {
+	switch t := PageTheme(strings.ToLower(name)); t {
+	case ThemeAuto, ThemeLight, ThemeDark:
+		return t, true
+	}
+	return "", false
+}
+
+// SetPageTheme sets the color scheme pages open in. Readers can switch it
+// with the page's toggle, which remembers their choice.
+func SetPageTheme(t PageTheme) {
+	styleMu.Lock()
+	defer styleMu.Unlock()
+	pageTheme = t
+}
+
+// AddStylesheet inlines CSS in every page, after the built-in styles, so it
+// can override them. Setting the variables in themeCSS restyles a page.
+func AddStylesheet(css string) {
+	styleMu.Lock()
+	defer styleMu.Unlock()
+	stylesheets = append(stylesheets, stylesheet{css: css})
+}
+
+// LinkStylesheet links a stylesheet URL from every page, after the
+// built-in styles
+func LinkStylesheet(href string) {
+	styleMu.Lock()
+	defer styleMu.Unlock()
+	stylesheets = append(stylesheets, stylesheet{href: href})
+}
+
+// htmlOpenTag returns the page's <html> tag, naming the theme unless it
+// follows the system
+func htmlOpenTag() string {
+	styleMu.RLock()
+	defer styleMu.RUnlock()
+	if pageTheme == ThemeLight || pageTheme == ThemeDark {
+		return fmt.Sprintf("<html lang=\"en\" data-theme=\"%s\">", pageTheme)
+	}
+	return "<html lang=\"en\">"
+}
+
+// highlightMedia returns the media query that enables the highlight.js
+// theme for variant, "light" or "dark"
+func highlightMedia(variant PageTheme) string {
+	styleMu.RLock()
+	defer styleMu.RUnlock()
+	switch pageTheme {
+	case ThemeLight, ThemeDark:
+		if variant == pageTheme {
+			return "all"
+		}
+		return "not all"
+	}
+	return "(prefers-color-scheme: " + string(variant) + ")"
+}
+
+// highlightThemesHTML returns the light and dark highlight.js themes, only
+// one of them enabled. Static pages inline them instead of using the CDN.
+func highlightThemesHTML(static bool) string {
+	var sb strings.Builder
+	themes := []struct {
+		variant PageTheme
+		file    string
+		css     string
+	}{
+		{ThemeLight, "github.min.css", highlightCSS},
+		{ThemeDark, "github-dark.min.css", highlightDarkCSS},
+	}
+	for _, t := range themes {
+		if static {
+			sb.WriteString(fmt.Sprintf("<style media=\"%s\" data-hljs-theme=\"%s\">\n%s\n</style>\n", highlightMedia(t.variant), t.variant, t.css))
+		} else {
+			sb.WriteString(fmt.Sprintf("<link rel=\"stylesheet\" href=\"%sstyles/%s\" media=\"%s\" data-hljs-theme=\"%s\">\n", highlightCDN, t.file, highlightMedia(t.variant), t.variant))
+		}
+	}
+	return sb.String()
+}
+
+// customStylesHTML returns the stylesheets added with AddStylesheet and
+// LinkStylesheet, in order
+func customStylesHTML() string {
+	styleMu.RLock()
+	defer styleMu.RUnlock()
+	var sb strings.Builder
+	for _, s := range stylesheets {
+		if s.href != "" {
+			sb.WriteString(fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s\">\n", html.EscapeString(s.href)))
+			continue
+		}
+		// A stylesheet can't end the <style> element early
+		css := strings.ReplaceAll(s.css, "</", "<\\/")
+		sb.WriteString("<style>\n" + css + "\n</style>\n")
+	}
+	return sb.String()
+}
+
+// themeToggleHTML returns the button that switches between light and dark
+func themeToggleHTML() string {
+	return "<button class=\"theme-toggle\" onclick=\"toggleTheme()\" title=\"Toggle dark mode\" aria-label=\"Toggle dark mode\"></button>\n"
+}
+
+// themeScript returns JavaScript, run in the head before the page is
+// drawn, that applies the reader's saved theme. setTheme switches the page
+// and highlight.js themes and tells renderers like Mermaid to redraw.
+func themeScript() string {
+	return `
+/* --- Theme --- */
+function setTheme(theme) {
+  if (theme) document.documentElement.setAttribute('data-theme', theme);
+  document.querySelectorAll('[data-hljs-theme]').forEach(function(el) {
+    el.media = el.getAttribute('data-hljs-theme') === currentTheme() ? 'all' : 'not all';
+  });
+  document.dispatchEvent(new Event('aster:theme'));
+}
+function currentTheme() {
+  var theme = document.documentElement.getAttribute('data-theme');
+  if (theme) return theme;
+  return window.matchMedia && matchMedia('(prefers-color-scheme: dark)').matches ? 'dark' : 'light';
+}
+function toggleTheme() {
+  var theme = currentTheme() === 'dark' ? 'light' : 'dark';
+  setTheme(theme);
+  try { localStorage.setItem('aster-theme', theme); } catch (e) {}
+}
+(function() {
+  var saved = null;
+  try { saved = localStorage.getItem('aster-theme'); } catch (e) {}
+  if (saved === 'light' || saved === 'dark') setTheme(saved);
+  if (window.matchMedia) {
+    matchMedia('(prefers-color-scheme: dark)').addEventListener('change', function() {
+      if (!document.documentElement.getAttribute('data-theme')) setTheme();
+    });
+  }
+})();
+`
+}
+
+// themeCSS returns the page colors as CSS variables: the light palette,
+// and the dark one when the system asks for it or the page is set to dark
+func themeCSS() string {
+	return `
+:root {
+  color-scheme: light;
+  --bg: #fff;
+  --text: #1d1d1f;
+  --text-secondary: #6e6e73;
+  --text-tertiary: #86868b;
+  --surface: #f5f5f7;
+  --surface-strong: #e8e8ed;
+  --border: #d2d2d7;
+  --link: #06c;
+  --highlight: #ffd60a;
+  --search-highlight: #ffd60a;
+  --error: #b3261e;
+  --danger: #cf222e;
+  --warning: #bf8700;
+  --info: #0969da;
+  --callout-strength: 100%;
+  --diff-removed-bg: #FEF2F2;
+  --diff-added-bg: #F0FDF4;
+  --diff-removed-num-bg: #FEE2E2;
+  --diff-removed-num: #EF4444;
+  --diff-added-num-bg: #DCFCE7;
+  --diff-added-num: #10B981;
+  --diff-word-del-bg: #FECACA;
+  --diff-word-del: #991B1B;
+  --diff-word-add-bg: #BBF7D0;
+  --diff-word-add: #166534;
+  --shadow: rgba(0,0,0,0.08);
+  --overlay: rgba(0,0,0,0.1);
+  --mermaid-text: #1d1d1f;
+  --mermaid-muted: #6e6e73;
+  --mermaid-line: #86868b;
+  --mermaid-fill: #f5f5f7;
+  --mermaid-stroke: #aeaeb2;
+  --mermaid-accent: #0071e3;
+  --mermaid-cluster: #fbfbfd;
+  --mermaid-cluster-stroke: #d2d2d7;
+  --mermaid-note: #fff8c5;
+  --mermaid-note-stroke: #d4a72c;
+  --mermaid-background: #ffffff;
+  --mermaid-task: #cfe2f7;
+  --mermaid-task-active: #9ec5f0;
+  --mermaid-task-done: #e5e5ea;
+  --mermaid-crit: #d1242f;
+  --mermaid-crit-fill: #f8d0d0;
+  --toggle-icon: "\263E";
+}
+@media (prefers-color-scheme: dark) {
+  :root:not([data-theme="light"]) {` + darkThemeVars + `  }
+}
+:root[data-theme="dark"] {` + darkThemeVars + `}
+
+.theme-toggle {
+  position: fixed;
+  top: 0.75rem;
+  right: 0.75rem;
+  z-index: 150;
+  width: 2rem;
+  height: 2rem;
+  background: var(--surface);
+  color: var(--text-secondary);
+  border: 1px solid var(--border);
+  border-radius: 8px;
+  font-size: 15px;
+  line-height: 1;
+  cursor: pointer;
+  opacity: 0.6;
+  transition: opacity 0.15s;
+}
+.theme-toggle::before { content: var(--toggle-icon); }
+.theme-toggle:hover { opacity: 1; color: var(--text); }
+@media print { .theme-toggle { display: none; } }
+`
+}
+
+// darkThemeVars is the dark palette, set in two places by themeCSS
+const darkThemeVars = `
+  color-scheme: dark;
+  --bg: #161618;
+  --text: #f5f5f7;
+  --text-secondary: #a1a1a6;
+  --text-tertiary: #8e8e93;
+  --surface: #232326;
+  --surface-strong: #2f2f33;
+  --border: #3a3a3e;
+  --link: #2997ff;
+  --highlight: #ffd60a;
+  --search-highlight: rgba(255,214,10,0.35);
+  --error: #ff6961;
+  --danger: #f85149;
+  --warning: #d29922;
+  --info: #4493f8;
+  --callout-strength: 65%;
+  --diff-removed-bg: rgba(248,81,73,0.1);
+  --diff-added-bg: rgba(46,160,67,0.12);
+  --diff-removed-num-bg: rgba(248,81,73,0.2);
+  --diff-removed-num: #f85149;
+  --diff-added-num-bg: rgba(46,160,67,0.25);
+  --diff-added-num: #3fb950;
+  --diff-word-del-bg: rgba(248,81,73,0.4);
+  --diff-word-del: #ffdcd7;
+  --diff-word-add-bg: rgba(46,160,67,0.4);
+  --diff-word-add: #aff5b4;
+  --shadow: rgba(0,0,0,0.4);
+  --overlay: rgba(0,0,0,0.5);
+  --mermaid-text: #f5f5f7;
+  --mermaid-muted: #a1a1a6;
+  --mermaid-line: #8e8e93;
+  --mermaid-fill: #232326;
+  --mermaid-stroke: #636366;
+  --mermaid-accent: #2997ff;
+  --mermaid-cluster: #1c1c1e;
+  --mermaid-cluster-stroke: #3a3a3e;
+  --mermaid-note: #3d3519;
+  --mermaid-note-stroke: #9e7c1a;
+  --mermaid-background: #161618;
+  --mermaid-task: #1d3b5c;
+  --mermaid-task-active: #245a94;
+  --mermaid-task-done: #3a3a3e;
+  --mermaid-crit: #f85149;
+  --mermaid-crit-fill: #5c1d1d;
+  --toggle-icon: "\2600\FE0E";
+`
@@ -0,0 +1,88 @@
+package html
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wildreason/reader/parse"
+)
+
+// resetPageStyle restores the default theme and removes added stylesheets
+func resetPageStyle() {
+	pageTheme = ThemeAuto
+	stylesheets = nil
+}
+
+func testBlocks() []parse.Block {
+	return []parse.Block{{Name: "a.md", Content: "# A\n\n```go\nx := 1\n```\n", Pages: []string{"# A\n\n```go\nx := 1\n```\n"}, TotalPages: 1, ContentType: parse.BlockContentPlain}}
+}
+
+func TestPageThemeAuto(t *testing.T) {
+	defer resetPageStyle()
+	page := RenderHTMLPage("a.md", testBlocks(), false)
+	for _, want := range []string{
+		`<html lang="en">`,
+		`styles/github.min.css" media="(prefers-color-scheme: light)" data-hljs-theme="light"`,
+		`styles/github-dark.min.css" media="(prefers-color-scheme: dark)" data-hljs-theme="dark"`,
+		`@media (prefers-color-scheme: dark)`,
+		`class="theme-toggle"`,
+		`localStorage.getItem('aster-theme')`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+}
+
+func TestPageThemeFixed(t *testing.T) {
+	defer resetPageStyle()
+	SetPageTheme(ThemeDark)
+	page := RenderStaticHTMLPage("a.md", testBlocks(), false)
+	for _, want := range []string{
+		`<html lang="en" data-theme="dark">`,
+		`<style media="not all" data-hljs-theme="light">`,
+		`<style media="all" data-hljs-theme="dark">`,
+		highlightDarkCSS,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("static page missing %q", want)
+		}
+	}
+	if strings.Contains(page, "cdnjs") {
+		t.Errorf("static page loads from a CDN")
+	}
+}
+
+func TestAddStylesheet(t *testing.T) {
+	defer resetPageStyle()
+	AddStylesheet(":root { --link: #c00; } /* </style><script>x</script> */")
+	LinkStylesheet("https://example.com/brand.css?a=1&b=2")
+	for name, page := range map[string]string{
+		"page":   RenderHTMLPage("a.md", testBlocks(), false),
+		"static": RenderStaticHTMLPage("a.md", testBlocks(), false),
+		"index":  RenderIndexPage("notes", nil),
+	} {
+		custom := strings.Index(page, ":root { --link: #c00; }")
+		link := strings.Index(page, `<link rel="stylesheet" href="https://example.com/brand.css?a=1&amp;b=2">`)
+		builtin := strings.LastIndex(page, "--toggle-icon")
+		if custom < 0 || link < 0 {
+			t.Errorf("%s: stylesheets missing", name)
+			continue
+		}
+		if custom < builtin || link < custom {
+			t.Errorf("%s: stylesheets not added after the built-in styles, in order", name)
+		}
+		if strings.Contains(page, "</style><script>x") {
+			t.Errorf("%s: stylesheet closed the style element", name)
+		}
+	}
+}
+
+func TestParsePageTheme(t *testing.T) {
+	if th, ok := ParsePageTheme("Dark"); !ok || th != ThemeDark {
+		t.Errorf("ParsePageTheme(Dark) = %q, %v", th, ok)
+	}
+	if _, ok := ParsePageTheme("sepia"); ok {
+		t.Errorf("ParsePageTheme accepted an unknown theme")
+	}
+}
@@ -0,0 +1,65 @@
+package html
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+)
+
+// Wiki links a note to the other notes in its directory
+type Wiki struct {
+	// Resolve returns the URL of the named note, or false if there is none
+	Resolve func(note string) (string, bool)
+	// Backlinks are the notes that link to this one
+	Backlinks []DocMeta
+}
+
+// RenderNotePage renders a note like RenderHTMLPage, resolving its
+// [[wiki links]] against the other notes and listing the notes that link
+// to it
+func RenderNotePage(title string, blocks []parse.Block, wiki *Wiki) string {
+	return renderPage(title, blocks, false, &pageLinks{wiki: wiki}, nil)
+}
+
+// wikiLink returns the URL of a [[note#heading]] link; ok is false when the
+// wiki has no such note. Without a wiki, notes are the markdown files next
+// to the page.
+func (p *pageLinks) wikiLink(target string) (href string, ok bool) {
+	note, heading, _ := strings.Cut(target, "#")
+	note = strings.TrimSpace(note)
+	switch {
+	case note == "":
+		// A heading on this page
+	case p == nil || p.wiki == nil || p.wiki.Resolve == nil:
+		if !isMarkdownPath(note) {
+			note += ".md"
+		}
+		href = (&url.URL{Path: note}).String()
+	default:
+		if href, ok = p.wiki.Resolve(note); !ok {
+			return "", false
+		}
+	}
+	if heading = strings.TrimSpace(heading); heading != "" {
+		href += "#" + headerID(heading)
+	}
+	return href, true
+}
+
+// backlinksHTML lists the notes that link to a note
+func (p *pageLinks) backlinksHTML() string {
+	if p == nil || p.wiki == nil || len(p.wiki.Backlinks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<section class=\"backlinks\">\n<div class=\"backlinks-title\">Linked from</div>\n<ul>\n")
+	for _, doc := range p.wiki.Backlinks {
+		sb.WriteString(fmt.Sprintf("<li><a href=\"/%s\">%s</a></li>\n",
+			html.EscapeString(url.PathEscape(doc.Slug)), html.EscapeString(doc.Title)))
+	}
+	sb.WriteString("</ul>\n</section>\n")
+	return sb.String()
+}
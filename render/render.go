@@ -0,0 +1,189 @@
+// Package render parses content with the aster parser registry and renders it
+// as HTML or terminal output.
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render/html"
+	"github.com/wildreason/reader/render/term"
+)
+
+// Format selects the output produced by Render
+type Format int
+
+const (
+	// FormatHTML is a standalone page that loads highlight.js from a CDN
+	FormatHTML Format = iota
+	// FormatStaticHTML is a self-contained page with all assets inlined
+	FormatStaticHTML
+	// FormatTerminal is tview-tagged text, as shown in the aster TUI
+	FormatTerminal
+	// FormatText is terminal output with formatting tags stripped
+	FormatText
+)
+
+// Options controls parsing and rendering
+type Options struct {
+	Type        string // Parser type or alias (e.g. "md", "diff"); empty detects from Name and content
+	Name        string // Source file name, used for detection and as the default title
+	Format      Format
+	Width       int  // Terminal width for FormatTerminal and FormatText (default 80, at least minWidth)
+	Height      int  // Terminal height used to size markdown pages (default 24)
+	LineNumbers bool // Show source line numbers
+	Bundle      bool // FormatStaticHTML: inline local images and linked markdown files, found relative to Name
+}
+
+// minWidth is the narrowest terminal output Render produces
+const minWidth = 20
+
+// Document is the result of Render
+type Document struct {
+	Title       string
+	Type        string // Name of the parser that produced Blocks
+	Frontmatter parse.Frontmatter
+	Blocks      []parse.Block
+	Diagnostics []parse.Diagnostic // Problems reported by the parser
+	Output      string             // Rendered output in the requested format
+}
+
+// Render reads all of r, parses it and renders it in the requested format.
+// Binary types (images, video) need a file path; use parse.FileParser for those.
+// Render is safe for concurrent use: each call renders with its own Options
+// and leaves the package settings of render/term untouched. Terminal output
+// uses the current theme.
+func Render(ctx context.Context, r io.Reader, opts Options) (Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Document{}, err
+	}
+	content := string(data)
+
+	spec, err := resolveSpec(opts, content)
+	if err != nil {
+		return Document{}, err
+	}
+
+	fm, body := parse.ParseFrontmatter(content)
+	doc := Document{
+		Title:       documentTitle(opts.Name, fm, spec),
+		Type:        spec.Name,
+		Frontmatter: fm,
+	}
+
+	switch opts.Format {
+	case FormatHTML, FormatStaticHTML:
+		blockName := doc.Title
+		if opts.Name != "" {
+			blockName = filepath.Base(opts.Name)
+		}
+		doc.Blocks, doc.Diagnostics = htmlBlocks(spec, content, body, blockName, &doc.Frontmatter)
+	case FormatTerminal, FormatText:
+		doc.Blocks, doc.Diagnostics = terminalBlocks(spec, content, opts.Height)
+	default:
+		return Document{}, fmt.Errorf("render: unknown format %d", opts.Format)
+	}
+	if err := ctx.Err(); err != nil {
+		return Document{}, err
+	}
+
+	switch opts.Format {
+	case FormatHTML:
+		doc.Output = html.RenderHTMLPage(doc.Title, doc.Blocks, opts.LineNumbers, doc.Diagnostics...)
+	case FormatStaticHTML:
+		if opts.Bundle && opts.Name != "" {
+			doc.Output = html.RenderStaticHTMLBundle(opts.Name, doc.Title, doc.Blocks, opts.LineNumbers, doc.Diagnostics...)
+		} else {
+			doc.Output = html.RenderStaticHTMLPage(doc.Title, doc.Blocks, opts.LineNumbers, doc.Diagnostics...)
+		}
+	default:
+		width := opts.Width
+		if width <= 0 {
+			width = 80
+		}
+		width = max(width, minWidth)
+		settings := term.Settings{LineNumbers: opts.LineNumbers}
+		if opts.LineNumbers {
+			settings.GutterWidth = term.ComputeGutterWidth(doc.Blocks)
+		}
+		doc.Output = term.FormatBlocksWith(doc.Blocks, width, term.BorderNone, settings)
+		if opts.Format == FormatText {
+			doc.Output = term.StripTviewTags(doc.Output)
+		}
+	}
+	return doc, nil
+}
+
+// resolveSpec picks the parser spec for the options and content
+func resolveSpec(opts Options, content string) (*parse.ParserSpec, error) {
+	var spec *parse.ParserSpec
+	switch {
+	case opts.Type != "":
+		spec = parse.LookupParser(opts.Type)
+		if spec == nil {
+			return nil, fmt.Errorf("render: unknown type %q", opts.Type)
+		}
+	case opts.Name != "":
+		spec = parse.DetectSpec(opts.Name, content)
+		if spec == nil {
+			spec = parse.LookupParser("md")
+		}
+	default:
+		spec = parse.DetectContentSpec(content)
+	}
+	if spec.Binary {
+		return nil, fmt.Errorf("render: %s content needs a file path", spec.Name)
+	}
+	return spec, nil
+}
+
+// documentTitle prefers the frontmatter title, then the file name
+func documentTitle(name string, fm parse.Frontmatter, spec *parse.ParserSpec) string {
+	switch {
+	case fm.Title != "":
+		return fm.Title
+	case name != "":
+		return filepath.Base(name)
+	default:
+		return spec.Label
+	}
+}
+
+// htmlBlocks parses structured types; other types become a single block so
+// the HTML renderer handles headings natively. Markdown blocks carry their
+// frontmatter for the metadata header.
+func htmlBlocks(spec *parse.ParserSpec, content string, body string, name string, fm *parse.Frontmatter) ([]parse.Block, []parse.Diagnostic) {
+	if spec.Structured {
+		return parse.ParseWithDiagnostics(spec.New(), content)
+	}
+	block := parse.Block{
+		Name:        name,
+		Content:     body,
+		Pages:       []string{body},
+		TotalPages:  1,
+		ContentType: spec.RawType,
+	}
+	if fm.Format != "" && spec.RawType == parse.BlockContentPlain {
+		block.Data = fm
+	}
+	return []parse.Block{block}, parse.Diagnose(spec.New(), content)
+}
+
+// terminalBlocks parses content for the terminal; markdown is paged continuously
+func terminalBlocks(spec *parse.ParserSpec, content string, height int) ([]parse.Block, []parse.Diagnostic) {
+	parser := spec.New()
+	if md, ok := parser.(*parse.MarkdownParser); ok {
+		if height <= 0 {
+			height = 24
+		}
+		return md.ParseContinuous(content, height), nil
+	}
+	return parse.ParseWithDiagnostics(parser, content)
+}
@@ -0,0 +1,137 @@
+package render
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wildreason/reader/render/html"
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+)
+
+const concurrentDoc = `# Title
+
+Some text with a [link](other.md).
+
+| Name | Description |
+|------|-------------|
+| one  | a description long enough to wrap at a narrow terminal width |
+
+    indented code
+`
+
+// TestRender_Concurrent renders with different options on many goroutines
+// while the term settings and themes are set underneath; run with -race
+func TestRender_Concurrent(t *testing.T) {
+	opts := []Options{
+		{Name: "a.md", Format: FormatText, Width: 40},
+		{Name: "a.md", Format: FormatText, Width: 40, LineNumbers: true},
+		{Name: "a.md", Format: FormatText, Width: 100},
+		{Name: "a.md", Format: FormatTerminal, Width: 60, LineNumbers: true},
+		{Name: "a.md", Format: FormatStaticHTML},
+	}
+	want := make([]string, len(opts))
+	for i, o := range opts {
+		doc, err := Render(context.Background(), strings.NewReader(concurrentDoc), o)
+		if err != nil {
+			t.Fatalf("Render(%+v): %v", o, err)
+		}
+		want[i] = doc.Output
+	}
+	if want[0] == want[1] {
+		t.Fatal("Expected line numbers to change the output")
+	}
+
+	defer term.SetLineNumbers(false, 0)
+	defer term.SetNoWrap(false)
+	defer term.SetHyperlinks(false, "")
+	defer theme.Set(theme.Current())
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dark := theme.Current()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			term.SetLineNumbers(i%2 == 0, 4)
+			term.SetNoWrap(i%2 == 0)
+			term.SetHyperlinks(i%2 == 0, "/tmp")
+			theme.Set(dark)
+			html.SetPageTheme(html.ThemeAuto)
+		}
+	}()
+
+	var renders sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		renders.Add(1)
+		go func(g int) {
+			defer renders.Done()
+			for n := 0; n < 20; n++ {
+				i := (g + n) % len(opts)
+				doc, err := Render(context.Background(), strings.NewReader(concurrentDoc), opts[i])
+				if err != nil {
+					t.Errorf("Render(%+v): %v", opts[i], err)
+					return
+				}
+				if doc.Output != want[i] {
+					t.Errorf("Render(%+v) output changed under concurrency:\n%s\nwant:\n%s", opts[i], doc.Output, want[i])
+					return
+				}
+			}
+		}(g)
+	}
+	renders.Wait()
+	close(done)
+	wg.Wait()
+}
+
+func TestRender_NarrowWidth(t *testing.T) {
+	src := "# Title\n\n```go\nfunc main() {}\n```\n"
+	for _, width := range []int{1, 2, 4, 5} {
+		doc, err := Render(context.Background(), strings.NewReader(src), Options{Name: "a.md", Format: FormatText, Width: width})
+		if err != nil {
+			t.Fatalf("Render(width %d): %v", width, err)
+		}
+		if !strings.Contains(doc.Output, "func main() {}") {
+			t.Errorf("Render(width %d) should render at least %d columns:\n%s", width, minWidth, doc.Output)
+		}
+	}
+}
+
+func TestRender_TerminalFrontmatter(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"yaml", "---\ntitle: Notes\ntags: [a, b]\n---\n\n# Heading\n\nBody text.\n"},
+		{"toml", "+++\ntitle = \"Notes\"\ntags = [\"a\", \"b\"]\n+++\n\n# Heading\n\nBody text.\n"},
+	}
+	for _, tt := range tests {
+		doc, err := Render(context.Background(), strings.NewReader(tt.src), Options{Name: "a.md", Format: FormatText, Width: 60, LineNumbers: true})
+		if err != nil {
+			t.Fatalf("%s: Render: %v", tt.name, err)
+		}
+		out := doc.Output
+		for _, want := range []string{"Metadata · 2 fields", "title: Notes", "tags: a, b", "Heading", "Body text."} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output missing %q:\n%s", tt.name, want, out)
+			}
+		}
+		for _, bad := range []string{"---", "+++", "title =", "───"} {
+			if strings.Contains(out, bad) {
+				t.Errorf("%s: frontmatter leaked into the body as %q:\n%s", tt.name, bad, out)
+			}
+		}
+		if !strings.Contains(out, "6  Heading") {
+			t.Errorf("%s: heading should keep its line number 6:\n%s", tt.name, out)
+		}
+	}
+}
@@ -1,11 +1,17 @@
-package main
+package term
 
 import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/parse/markdown"
+	"github.com/wildreason/reader/theme"
 )
 
 // BorderStyle defines visual separation style for blocks
@@ -76,24 +82,29 @@ func (br *BorderRenderer) RenderBlockStart(blockName string, pageInfo string, wi
 	if maxHeaderLen < 10 {
 		maxHeaderLen = 10
 	}
-	if len(header) > maxHeaderLen {
+	// Pad by display width so tagged and wide headers line up
+	headerWidth := tview.TaggedStringWidth(header)
+	if headerWidth > maxHeaderLen {
 		header = header[:maxHeaderLen-3] + "..."
+		headerWidth = tview.TaggedStringWidth(header)
 	}
 
+	// A screen too narrow for the header is widened to fit it
+	width = max(width, headerWidth+4)
 	switch br.style {
 	case BorderBox:
 		topLine := "┌" + strings.Repeat("─", width-2) + "┐"
-		headerLine := "│ " + header + strings.Repeat(" ", width-4-len(header)) + " │"
+		headerLine := "│ " + header + strings.Repeat(" ", width-4-headerWidth) + " │"
 		return topLine + "\n" + headerLine
 
 	case BorderDouble:
 		topLine := "╔" + strings.Repeat("═", width-2) + "╗"
-		headerLine := "║ " + header + strings.Repeat(" ", width-4-len(header)) + " ║"
+		headerLine := "║ " + header + strings.Repeat(" ", width-4-headerWidth) + " ║"
 		return topLine + "\n" + headerLine
 
 	case BorderRounded:
 		topLine := "╭" + strings.Repeat("─", width-2) + "╮"
-		headerLine := "│ " + header + strings.Repeat(" ", width-4-len(header)) + " │"
+		headerLine := "│ " + header + strings.Repeat(" ", width-4-headerWidth) + " │"
 		return topLine + "\n" + headerLine
 
 	default:
@@ -103,6 +114,7 @@ func (br *BorderRenderer) RenderBlockStart(blockName string, pageInfo string, wi
 
 // RenderBlockEnd returns closing border for box-style borders
 func (br *BorderRenderer) RenderBlockEnd(width int) string {
+	width = max(width, 2)
 	switch br.style {
 	case BorderBox:
 		return "└" + strings.Repeat("─", width-2) + "┘"
@@ -140,19 +152,54 @@ type annotatedLine struct {
 	sourceLine int // 0-based index within page content, -1 for synthetic
 }
 
-var lineNumConfig struct {
-	enabled     bool
-	gutterWidth int
+// Settings are the rendering options that apply to every block formatted
+type Settings struct {
+	LineNumbers  bool   // Show the source line number gutter
+	GutterWidth  int    // Width of the gutter, from ComputeGutterWidth
+	NoWrap       bool   // Keep tables, code and preformatted lines at full width
+	Hyperlinks   bool   // Write links as OSC 8 hyperlinks instead of numbered references
+	LinkBase     string // Directory relative links and inline images resolve against
+	InlineImages bool   // Draw local images that stand alone in a paragraph
 }
 
+// settings are used by the Format functions. settingsMu is held while they
+// change and for the whole of each Format call, so concurrent calls never
+// see each other's settings half applied.
+var (
+	settingsMu sync.Mutex
+	settings   Settings
+)
+
 // SetLineNumbers enables or disables line number gutter
 func SetLineNumbers(enabled bool, gutterWidth int) {
-	lineNumConfig.enabled = enabled
-	lineNumConfig.gutterWidth = gutterWidth
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.LineNumbers = enabled
+	settings.GutterWidth = gutterWidth
 }
 
-// computeGutterWidth calculates gutter width from max line number across all blocks
-func computeGutterWidth(blocks []Block) int {
+// SetNoWrap sets whether wide tables, code and preformatted lines run past
+// the terminal width, for a reader that scrolls sideways. Paragraphs still
+// wrap.
+func SetNoWrap(enabled bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.NoWrap = enabled
+}
+
+// FormatBlocksWith renders all blocks like FormatBlocks, with s in place of
+// the package settings for this call only. It is safe to call concurrently.
+func FormatBlocksWith(blocks []parse.Block, termWidth int, borderStyle BorderStyle, s Settings) string {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	saved := settings
+	settings = s
+	defer func() { settings = saved }()
+	return formatBlocks(blocks, termWidth, borderStyle)
+}
+
+// ComputeGutterWidth calculates gutter width from max line number across all blocks
+func ComputeGutterWidth(blocks []parse.Block) int {
 	maxLine := 0
 	for _, b := range blocks {
 		if len(b.PageStartLine) == 0 {
@@ -189,7 +236,14 @@ func annotatedLinesToString(lines []annotatedLine) string {
 }
 
 // FormatBlockPage renders a specific page of a block with page indicator
-func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle BorderStyle) string {
+func FormatBlockPage(block *parse.Block, pageNum int, termWidth int, borderStyle BorderStyle) string {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return formatBlockPage(block, pageNum, termWidth, borderStyle)
+}
+
+// formatBlockPage renders a page of a block; settingsMu is held
+func formatBlockPage(block *parse.Block, pageNum int, termWidth int, borderStyle BorderStyle) string {
 	if block == nil {
 		return ""
 	}
@@ -208,13 +262,13 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 	}
 
 	// Render diff pages with diff formatter
-	if pageType == BlockContentDiff {
+	if pageType == parse.BlockContentDiff {
 		// Get filename from PageMeta if available
 		filename := ""
 		if len(block.PageMeta) > pageNum {
 			filename = block.PageMeta[pageNum]
 		}
-		return FormatDiffPage(block, pageNum, termWidth, filename)
+		return formatDiffPage(block, pageNum, termWidth, filename)
 	}
 
 	// Translate ANSI escape codes to tview color tags
@@ -225,8 +279,8 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 
 	// Compute gutter width for line numbers
 	gutterW := 0
-	if lineNumConfig.enabled && len(block.PageStartLine) > pageNum {
-		gutterW = lineNumConfig.gutterWidth
+	if settings.LineNumbers && len(block.PageStartLine) > pageNum {
+		gutterW = settings.GutterWidth
 	}
 
 	// Adjust content width based on border indent and gutter
@@ -234,9 +288,21 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 	if !renderer.IsBoxStyle() {
 		contentWidth = contentWidth - 1 // Account for " " prefix added to non-box content lines
 	}
+	contentWidth = max(contentWidth, 1)
 
-	// Render markdown
-	annotatedLines := formatMarkdown(pageContent, contentWidth)
+	// Render content: markdown from its syntax tree, preformatted text line by line
+	var annotatedLines []annotatedLine
+	switch pageType {
+	case parse.BlockContentShell:
+		annotatedLines = formatPlainLines(pageContent, contentWidth)
+	case parse.BlockContentTranscript:
+		annotatedLines = formatTranscript(block, block.Pages[pageNum], contentWidth)
+	default:
+		annotatedLines = formatMarkdownPage(block, pageContent, contentWidth)
+		if fm, ok := block.Data.(*parse.Frontmatter); ok && pageNum == 0 {
+			annotatedLines = append(formatFrontmatter(fm, contentWidth), annotatedLines...)
+		}
+	}
 
 	// Determine display name: use page-specific breadcrumb if available
 	displayName := block.Name
@@ -245,14 +311,14 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 	}
 
 	// Add content type prefix with color
-	if block.ContentType == BlockContentTranscript {
+	if block.ContentType == parse.BlockContentTranscript {
 		// Extract just the block number from "block-N" format
 		blockNum := strings.TrimPrefix(displayName, "block-")
 		if blockNum != displayName { // It was a block-N format
-			displayName = "[#b294bb]chat[-] [#808080]" + blockNum + "[-]"
+			displayName = theme.Tag(theme.Chat) + "chat[-] " + theme.Tag(theme.Muted) + blockNum + "[-]"
 		}
-	} else if block.ContentType == BlockContentShell {
-		displayName = "[#99b494]shell[-]"
+	} else if block.ContentType == parse.BlockContentShell {
+		displayName = theme.Tag(theme.Shell) + "shell[-]"
 	}
 
 	// Build output
@@ -272,7 +338,7 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 		}
 	} else {
 		// Non-box styles: render header (with background highlight only for markdown)
-		if block.ContentType != BlockContentTranscript && block.ContentType != BlockContentShell {
+		if block.ContentType != parse.BlockContentTranscript && block.ContentType != parse.BlockContentShell {
 			// Markdown blocks: gray background highlight
 			if block.TotalPages > 1 {
 				// Right-align page indicator
@@ -290,14 +356,14 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 				if len(header) < termWidth {
 					header = header + strings.Repeat(" ", termWidth-len(header))
 				}
-				output.WriteString("[white:#333333]" + header + "[-:-:-]")
+				output.WriteString(theme.TagBg(theme.Header, theme.HeaderBg) + header + "[-:-:-]")
 			} else {
 				// Left-align single block name with margin and background
 				header := " " + displayName + " "
 				if len(header) < termWidth {
 					header = header + strings.Repeat(" ", termWidth-len(header))
 				}
-				output.WriteString("[white:#333333]" + header + "[-:-:-]")
+				output.WriteString(theme.TagBg(theme.Header, theme.HeaderBg) + header + "[-:-:-]")
 			}
 		} else {
 			// Non-markdown blocks: no background, simple header
@@ -330,7 +396,7 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 			if al.sourceLine >= 0 {
 				fileLineNum := block.PageStartLine[pageNum] + al.sourceLine
 				numStr := fmt.Sprintf("%d", fileLineNum)
-				gutter = "[#555555]" + strings.Repeat(" ", gutterW-1-len(numStr)) + numStr + " " + "[-]"
+				gutter = theme.Tag(theme.Gutter) + strings.Repeat(" ", gutterW-1-len(numStr)) + numStr + " " + "[-]"
 			} else {
 				gutter = strings.Repeat(" ", gutterW)
 			}
@@ -339,7 +405,7 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 		if renderer.IsBoxStyle() {
 			// For box styles, wrap each line
 			if isEmpty {
-				output.WriteString("│" + gutter + strings.Repeat(" ", termWidth-2-gutterW) + "│")
+				output.WriteString("│" + gutter + strings.Repeat(" ", max(termWidth-2-gutterW, 0)) + "│")
 			} else {
 				// Pad line to fit in box
 				if len(line) < contentWidth {
@@ -373,14 +439,41 @@ func FormatBlockPage(block *Block, pageNum int, termWidth int, borderStyle Borde
 	return output.String()
 }
 
+// FormatBlocks renders all blocks and all their pages into a single string
+func FormatBlocks(blocks []parse.Block, termWidth int, borderStyle BorderStyle) string {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return formatBlocks(blocks, termWidth, borderStyle)
+}
+
+// formatBlocks renders all pages of all blocks; settingsMu is held
+func formatBlocks(blocks []parse.Block, termWidth int, borderStyle BorderStyle) string {
+	var out strings.Builder
+	for i := range blocks {
+		block := &blocks[i]
+		for page := 0; page < block.TotalPages; page++ {
+			rendered := formatBlockPage(block, page, termWidth, borderStyle)
+			out.WriteString(rendered)
+		}
+	}
+	return out.String()
+}
+
 // FormatBlockPlain renders a block (first page only, for backwards compatibility)
-func FormatBlockPlain(block *Block, termWidth int, style string, borderStyle BorderStyle) string {
+func FormatBlockPlain(block *parse.Block, termWidth int, style string, borderStyle BorderStyle) string {
 	return FormatBlockPage(block, 0, termWidth, borderStyle)
 }
 
 // FormatDiffPage renders a diff page from a mixed-content block
 // Uses same header style as plain pages for consistency
-func FormatDiffPage(block *Block, pageNum int, termWidth int, filename string) string {
+func FormatDiffPage(block *parse.Block, pageNum int, termWidth int, filename string) string {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return formatDiffPage(block, pageNum, termWidth, filename)
+}
+
+// formatDiffPage renders a diff page; settingsMu is held
+func formatDiffPage(block *parse.Block, pageNum int, termWidth int, filename string) string {
 	// Get the diff content from the page
 	if pageNum < 0 || pageNum >= len(block.Pages) {
 		return ""
@@ -388,7 +481,7 @@ func FormatDiffPage(block *Block, pageNum int, termWidth int, filename string) s
 	diffContent := block.Pages[pageNum]
 
 	// Parse hunks from this diff content
-	hunks := ParseHunks(diffContent)
+	hunks := parse.ParseHunks(diffContent)
 	if len(hunks) == 0 {
 		return diffContent
 	}
@@ -397,7 +490,7 @@ func FormatDiffPage(block *Block, pageNum int, termWidth int, filename string) s
 	// Count diff pages before this one to determine hunk index
 	hunkIndex := 0
 	for i := 0; i < pageNum; i++ {
-		if len(block.PageTypes) > i && block.PageTypes[i] == BlockContentDiff {
+		if len(block.PageTypes) > i && block.PageTypes[i] == parse.BlockContentDiff {
 			// Check if same diff content (same file)
 			if len(block.PageMeta) > i && block.PageMeta[i] == filename {
 				hunkIndex++
@@ -415,7 +508,7 @@ func FormatDiffPage(block *Block, pageNum int, termWidth int, filename string) s
 
 	// Use provided filename, fallback to extracting from diff
 	if filename == "" {
-		filename = GetFileFromDiff(diffContent)
+		filename = parse.GetFileFromDiff(diffContent)
 	}
 	if filename == "" {
 		filename = "diff"
@@ -438,7 +531,7 @@ func FormatDiffPage(block *Block, pageNum int, termWidth int, filename string) s
 	}
 
 	// Format: block-N  filename  [page/total]
-	header := fmt.Sprintf("%s  [green]%s[-]", block.Name, displayName)
+	header := fmt.Sprintf("%s  %s%s[-]", block.Name, theme.Tag(theme.File), displayName)
 	spacing := termWidth - len(block.Name) - len(displayName) - len(pageIndicator) - 8
 	if spacing < 1 {
 		spacing = 1
@@ -453,109 +546,6 @@ func FormatDiffPage(block *Block, pageNum int, termWidth int, filename string) s
 	return output.String()
 }
 
-// formatMarkdown performs lightweight markdown rendering
-// Handles: code blocks, tables, inline code, bold, italic, lists, links
-// Returns annotated lines with source line mapping for line number gutter
-func formatMarkdown(text string, maxWidth int) []annotatedLine {
-	if maxWidth <= 0 {
-		maxWidth = 76 // Default
-	}
-
-	lines := strings.Split(text, "\n")
-	var result []annotatedLine
-	inCodeBlock := false
-	var codeBlockLines []string
-	var codeBlockLanguage string
-	var fenceStartLine int
-	inTable := false
-	var tableLines []string
-
-	for lineIdx, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Handle tables - detect lines with pipes (must check before code blocks)
-		if !inCodeBlock && isTableLine(trimmed) {
-			if !inTable {
-				inTable = true
-				tableLines = []string{line}
-			} else {
-				tableLines = append(tableLines, line)
-			}
-			continue
-		} else if inTable {
-			// End of table - render as table if it fits, otherwise list
-			tableResult := renderTable(tableLines, maxWidth)
-			if tableResult == nil {
-				tableResult = tableToList(tableLines)
-			}
-			for _, tr := range tableResult {
-				result = append(result, annotatedLine{text: tr, sourceLine: -1})
-			}
-			inTable = false
-			tableLines = nil
-			// Fall through to process current line
-		}
-
-		// Handle code blocks (```language ... ```)
-		if strings.HasPrefix(trimmed, "```") {
-			if !inCodeBlock {
-				// Starting code block - extract language if present
-				codeBlockLanguage = strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
-				codeBlockLines = []string{}
-				fenceStartLine = lineIdx
-				inCodeBlock = true
-			} else {
-				// Ending code block - render the code block with wrapper
-				codeBlock := renderCodeBlock(codeBlockLines, codeBlockLanguage, maxWidth)
-				isBoxed := !containsBoxDrawing(codeBlockLines)
-				annotated := annotateCodeBlockResult(codeBlock, fenceStartLine, len(codeBlockLines), isBoxed, codeBlockLanguage != "")
-				result = append(result, annotated...)
-				result = append(result, annotatedLine{text: "", sourceLine: -1}) // Empty line after code block
-				inCodeBlock = false
-				codeBlockLines = nil
-			}
-			continue
-		}
-
-		if inCodeBlock {
-			// Collect code block lines
-			codeBlockLines = append(codeBlockLines, line)
-			continue
-		}
-
-		// Process regular markdown line
-		processed := processMarkdownLine(line, maxWidth)
-		for i, p := range processed {
-			sl := -1
-			if i == 0 {
-				sl = lineIdx
-			}
-			result = append(result, annotatedLine{text: p, sourceLine: sl})
-		}
-	}
-
-	// Handle unclosed code block (edge case)
-	if inCodeBlock && len(codeBlockLines) > 0 {
-		codeBlock := renderCodeBlock(codeBlockLines, codeBlockLanguage, maxWidth)
-		isBoxed := !containsBoxDrawing(codeBlockLines)
-		annotated := annotateCodeBlockResult(codeBlock, fenceStartLine, len(codeBlockLines), isBoxed, codeBlockLanguage != "")
-		result = append(result, annotated...)
-	}
-
-	// Handle unclosed table (edge case)
-	if inTable && len(tableLines) > 0 {
-		tableResult := renderTable(tableLines, maxWidth)
-		if tableResult == nil {
-			tableResult = tableToList(tableLines)
-		}
-		for _, tr := range tableResult {
-			result = append(result, annotatedLine{text: tr, sourceLine: -1})
-		}
-	}
-
-	return result
-}
-
 // annotateCodeBlockResult maps rendered code block lines back to source line indices
 func annotateCodeBlockResult(rendered []string, fenceStartLine int, numContent int, isBoxed bool, hasLanguage bool) []annotatedLine {
 	var result []annotatedLine
@@ -587,12 +577,14 @@ func annotateCodeBlockResult(rendered []string, fenceStartLine int, numContent i
 	return result
 }
 
-// renderCodeBlock renders a code block with visual wrapper
+// renderCodeBlock renders a code block with visual wrapper, highlighting
+// code in the fence's language and emphasizing lines named by {1,3-5}.
 // Detects ASCII art and renders without border to avoid conflicts
-func renderCodeBlock(lines []string, language string, maxWidth int) []string {
+func renderCodeBlock(lines []string, info string, maxWidth int) []string {
 	if len(lines) == 0 {
 		return []string{}
 	}
+	language, emphasized := parseFenceInfo(info)
 
 	// ASCII art detection: if content has box-drawing chars, render simply
 	if containsBoxDrawing(lines) {
@@ -600,7 +592,7 @@ func renderCodeBlock(lines []string, language string, maxWidth int) []string {
 	}
 
 	// Normal code: use box border
-	return renderCodeBlockBoxed(lines, language, maxWidth)
+	return renderCodeBlockBoxed(lines, language, emphasized, maxWidth)
 }
 
 // containsBoxDrawing checks if any line has box-drawing characters
@@ -621,7 +613,7 @@ func containsBoxDrawing(lines []string) bool {
 func renderCodeBlockSimple(lines []string, language string) []string {
 	var result []string
 
-	gray := "[#707070]"
+	gray := theme.Tag(theme.Muted)
 	reset := "[-]"
 
 	// Language label if present
@@ -637,52 +629,51 @@ func renderCodeBlockSimple(lines []string, language string) []string {
 	return result
 }
 
-// renderCodeBlockBoxed renders with box-drawing border (for normal code)
-// Uses gray color to keep code blocks visually muted
-func renderCodeBlockBoxed(lines []string, language string, maxWidth int) []string {
+// renderCodeBlockBoxed renders with box-drawing border (for normal code).
+// Emphasized lines get a marked border and a background.
+func renderCodeBlockBoxed(lines []string, language string, emphasized map[int]bool, maxWidth int) []string {
 	// Calculate the width of the code block (longest line + padding)
 	maxLineLen := 0
 	for _, line := range lines {
-		if len(line) > maxLineLen {
-			maxLineLen = len(line)
-		}
+		maxLineLen = max(maxLineLen, utf8.RuneCountInString(line))
 	}
 
 	// Limit to maxWidth - 4 (for border characters)
 	codeWidth := maxLineLen
-	if codeWidth > maxWidth-4 {
-		codeWidth = maxWidth - 4
+	if codeWidth > maxWidth-4 && !settings.NoWrap {
+		codeWidth = max(maxWidth-4, 1)
 	}
 
 	var result []string
 
-	// Gray color for entire code block
-	gray := "[#707070]"
+	codeColors := theme.Current().Code
+	border := "[" + codeColors.Border + "]"
 	reset := "[-]"
 
 	// Top border with optional language label
 	topBorder := "┌" + strings.Repeat("─", codeWidth+2) + "┐"
 	if language != "" {
 		label := " " + language + " "
-		if len(label) <= codeWidth {
-			topBorder = "┌" + label + strings.Repeat("─", codeWidth+2-len(label)) + "┐"
+		if n := utf8.RuneCountInString(label); n <= codeWidth {
+			topBorder = "┌" + label + strings.Repeat("─", codeWidth+2-n) + "┐"
 		}
 	}
-	result = append(result, gray+topBorder+reset)
+	result = append(result, border+topBorder+reset)
 
 	// Code lines with side borders
-	for _, line := range lines {
-		displayLine := line
-		if len(displayLine) > codeWidth {
-			displayLine = displayLine[:codeWidth]
+	for i, toks := range highlightCode(lines, language) {
+		code, used := renderTokens(toks, codeWidth)
+		padding := strings.Repeat(" ", codeWidth-used)
+		if emphasized[i+1] {
+			result = append(result, "["+codeColors.Emphasis+"]┃"+reset+"[:"+codeColors.EmphasisBg+"] "+code+padding+" [:-]"+border+"│"+reset)
+			continue
 		}
-		padded := displayLine + strings.Repeat(" ", codeWidth-len(displayLine))
-		result = append(result, gray+"│ "+padded+" │"+reset)
+		result = append(result, border+"│"+reset+" "+code+padding+" "+border+"│"+reset)
 	}
 
 	// Bottom border
 	bottomBorder := "└" + strings.Repeat("─", codeWidth+2) + "┘"
-	result = append(result, gray+bottomBorder+reset)
+	result = append(result, border+bottomBorder+reset)
 
 	return result
 }
@@ -734,7 +725,7 @@ func wrapLine(text string, maxWidth int, continuationIndent string) []string {
 				for j < len(runes) && runes[j] != ']' {
 					j++
 				}
-				if j < len(runes) {
+				if j < len(runes) && isTviewTag(string(runes[i+1:j])) {
 					i = j + 1
 					continue
 				}
@@ -787,263 +778,17 @@ func wrapLine(text string, maxWidth int, continuationIndent string) []string {
 	return lines
 }
 
-// processMarkdownLine processes a single markdown line
-func processMarkdownLine(line string, maxWidth int) []string {
-	processed := line
-	trimmed := strings.TrimSpace(line)
-	leadingSpaces := len(line) - len(strings.TrimLeft(line, " \t"))
-
-	// Check for headers first (# ## ###) - process before other formatting
-	if strings.HasPrefix(trimmed, "# ") {
-		content := strings.TrimPrefix(trimmed, "# ")
-		content = processInlineCode(content)
-		content = removeMarkdownBold(content)
-		return wrapLine("[yellow:-:b]"+content+"[-:-:-]", maxWidth, "")
-	}
-	if strings.HasPrefix(trimmed, "## ") {
-		content := strings.TrimPrefix(trimmed, "## ")
-		content = processInlineCode(content)
-		content = removeMarkdownBold(content)
-		return wrapLine("[#87ceeb:-:b]"+content+"[-:-:-]", maxWidth, "")
-	}
-	if strings.HasPrefix(trimmed, "### ") {
-		content := strings.TrimPrefix(trimmed, "### ")
-		content = processInlineCode(content)
-		content = removeMarkdownBold(content)
-		return wrapLine("[#808080:-:b]"+content+"[-:-:-]", maxWidth, "")
-	}
-
-	// Process inline formatting
-	processed = processInlineCode(processed)
-	processed = processLinks(processed)
-	processed = removeMarkdownBold(processed)
-	processed = removeMarkdownItalic(processed)
-	processed = processListItems(processed)
-
-	// Determine continuation indent from original line type
-	continuationIndent := ""
-	if leadingSpaces >= 2 && (strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ")) {
-		// Nested bullet: "    - content" -> continuation at 6
-		continuationIndent = "      "
-	} else if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
-		// Top-level bullet: "  - content" -> continuation at 4
-		continuationIndent = "    "
-	} else {
-		// Check numbered list
-		for i := 0; i < len(trimmed) && i < 4; i++ {
-			if trimmed[i] >= '0' && trimmed[i] <= '9' {
-				continue
-			}
-			if trimmed[i] == '.' && i > 0 && i+1 < len(trimmed) && trimmed[i+1] == ' ' {
-				// baseIndent (2) + number (i) + ". " (2)
-				continuationIndent = strings.Repeat(" ", i+4)
-				break
-			}
-			break
-		}
-	}
-
-	return wrapLine(processed, maxWidth, continuationIndent)
-}
-
-// removeMarkdownBold removes **text** and __text__ markers and applies bold styling
-func removeMarkdownBold(text string) string {
-	boldStart := "[#ffd700:-:b]"  // Gold for bold text
-	boldEnd := "[-:-:-]"        // Reset all three: foreground, background, flags
-
-	// Use regex for more reliable matching
-	// Match **text** (not part of longer sequence) and wrap with bold tags
-	boldRegex := regexp.MustCompile(`\*\*([^*]+)\*\*`)
-	text = boldRegex.ReplaceAllString(text, boldStart+"$1"+boldEnd)
-
-	// Match __text__ (not part of longer sequence) and wrap with bold tags
-	boldUnderscoreRegex := regexp.MustCompile(`__([^_]+)__`)
-	text = boldUnderscoreRegex.ReplaceAllString(text, boldStart+"$1"+boldEnd)
-
-	return text
-}
-
-// removeMarkdownItalic removes *text* and _text_ markers and applies italic styling
-func removeMarkdownItalic(text string) string {
-	// Use tview regions for italic: [::i]text[::-]
-	// This is more reliable than ANSI codes in tview
-	italicStart := "[::i]"
-	italicEnd := "[::-]"
-
-	// Process single *text* (not **text**)
-	// Go regex doesn't support lookbehind, so we use a different approach
-	// Match *text* where * is not preceded or followed by another *
-	// We'll use a simple state machine approach
-	text = removeItalicMarkers(text, '*', italicStart, italicEnd)
-	text = removeItalicMarkers(text, '_', italicStart, italicEnd)
-
-	return text
-}
-
-// removeItalicMarkers removes single markers (not double) for italic and applies ANSI italic
-func removeItalicMarkers(text string, marker byte, italicStart, italicEnd string) string {
-	var result strings.Builder
-	runes := []rune(text)
-	i := 0
-
-	for i < len(runes) {
-		if runes[i] == rune(marker) {
-			// Check if it's a double marker (bold)
-			if i+1 < len(runes) && runes[i+1] == rune(marker) {
-				// It's bold, skip both markers (already handled by removeMarkdownBold)
-				result.WriteRune(runes[i])
-				result.WriteRune(runes[i+1])
-				i += 2
-				continue
-			}
-
-			// Check if it's a single marker (italic) - find the closing marker
-			// Look for the next single marker that's not part of a double
-			found := false
-			for j := i + 1; j < len(runes); j++ {
-				if runes[j] == rune(marker) {
-					// Check if it's part of a double marker
-					if j+1 < len(runes) && runes[j+1] == rune(marker) {
-						// This is the start of a double marker, not a closing single
-						break
-					}
-					// Found closing single marker - wrap with ANSI italic codes
-					result.WriteString(italicStart)
-					result.WriteString(string(runes[i+1 : j]))
-					result.WriteString(italicEnd)
-					i = j + 1
-					found = true
-					break
-				}
-			}
-
-			if !found {
-				// No closing marker found, keep the marker
-				result.WriteRune(runes[i])
-				i++
-			}
-		} else {
-			result.WriteRune(runes[i])
-			i++
-		}
-	}
-
-	return result.String()
-}
-
-// processInlineCode formats `code` with tview color tags
-func processInlineCode(text string) string {
-	// Gray for inline code
-	codeRegex := regexp.MustCompile("`([^`]+)`")
-	return codeRegex.ReplaceAllString(text, "[#a0a0a0]$1[-]")
-}
-
-// processLinks converts [text](url) to blue colored format: [blue]text[white]
-// Only shows the link text in blue, hides the URL (still extractable for 'o' key)
-// Note: OSC 8 hyperlinks don't work through tview, so we use keyboard shortcut instead
-func processLinks(text string) string {
-	// Match [text](url) pattern
-	linkRegex := regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
-	// Replace with blue colored format: [blue]text[white]
-	// This makes links visually distinct and intuitive, like web browsers
-	return linkRegex.ReplaceAllString(text, "[blue]$1[white]")
-}
-
-// processListItems handles list formatting with colored bullets and consistent indentation
-func processListItems(line string) string {
-	trimmed := strings.TrimSpace(line)
-	leadingSpaces := len(line) - len(strings.TrimLeft(line, " \t"))
-
-	// TODO: Experiment with indent levels - may need adjustment
-	// Base indent for top-level lists, extra for nested
-	baseIndent := "  "  // FIX: 2 spaces base indent for all lists
-	nestedIndent := "    "  // FIX: 4 spaces for nested lists
-
-	// Check if it's a nested bullet list item (starts with spaces + - or *)
-	if leadingSpaces >= 2 && (strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ")) {
-		content := strings.TrimPrefix(strings.TrimPrefix(trimmed, "- "), "* ")
-		return nestedIndent + "[#808080]-[-] " + content  // FIX: Gray for nested bullets
-	}
-
-	// Check if it's a top-level bullet list item (- or *)
-	if strings.HasPrefix(trimmed, "- ") {
-		content := strings.TrimPrefix(trimmed, "- ")
-		return baseIndent + "[cyan]-[-] " + content
-	}
-	if strings.HasPrefix(trimmed, "* ") {
-		content := strings.TrimPrefix(trimmed, "* ")
-		return baseIndent + "[cyan]*[-] " + content
-	}
-
-	// Check if it's a numbered list (1. 2. 3.)
-	if len(trimmed) >= 3 {
-		for i := 0; i < len(trimmed) && i < 4; i++ {
-			if trimmed[i] >= '0' && trimmed[i] <= '9' {
-				continue
-			}
-			if trimmed[i] == '.' && i > 0 && i+1 < len(trimmed) && trimmed[i+1] == ' ' {
-				num := trimmed[:i+1]
-				content := trimmed[i+2:]
-				return baseIndent + "[yellow]" + num + "[-] " + content
-			}
-			break
-		}
-	}
-
-	return line
-}
-
 // tviewTagRegex matches tview color/style tags like [#hex], [-], [-:-:-], [color:bg:flags]
 var tviewTagRegex = regexp.MustCompile(`\[([^\[\]]*)\]`)
 
-// stripTviewTags removes tview formatting tags from rendered content.
+// regionTagRegex matches the inside of tview region tags like ["link-1"] and [""]
+var regionTagRegex = regexp.MustCompile(`^"[a-zA-Z0-9_,;: .-]*"$`)
+
+// StripTviewTags removes tview formatting tags from rendered content.
 // Keeps structural content (indentation, borders, line breaks) intact.
-func stripTviewTags(s string) string {
+func StripTviewTags(s string) string {
 	return tviewTagRegex.ReplaceAllStringFunc(s, func(match string) string {
-		inner := match[1 : len(match)-1]
-		// Preserve markdown-style link text that happens to be in brackets
-		// tview tags are: color names, #hex, -, ::flags, color:bg:flags
-		if inner == "" || inner == "-" || inner == "-:-:-" || inner == ":-:-" || inner == "::-" {
-			return ""
-		}
-		if strings.HasPrefix(inner, "#") {
-			return ""
-		}
-		if strings.HasPrefix(inner, "::") {
-			return ""
-		}
-		if strings.Contains(inner, ":-:") || strings.Contains(inner, ":") {
-			// Looks like a tview color:bg:flags tag
-			parts := strings.Split(inner, ":")
-			for _, p := range parts {
-				p = strings.TrimSpace(p)
-				if p == "" || p == "-" || p == "b" || p == "i" || p == "u" || p == "d" {
-					continue
-				}
-				if strings.HasPrefix(p, "#") {
-					continue
-				}
-				// Known tview color names
-				colors := map[string]bool{
-					"white": true, "black": true, "red": true, "green": true,
-					"blue": true, "yellow": true, "cyan": true, "gray": true,
-					"grey": true, "orange": true, "purple": true, "pink": true,
-				}
-				if colors[strings.ToLower(p)] {
-					continue
-				}
-				// Not a recognized tview tag -- keep it
-				return match
-			}
-			return ""
-		}
-		// Single word: could be a color name
-		colors := map[string]bool{
-			"white": true, "black": true, "red": true, "green": true,
-			"blue": true, "yellow": true, "cyan": true, "gray": true,
-			"grey": true, "orange": true, "purple": true, "pink": true,
-		}
-		if colors[strings.ToLower(inner)] {
+		if isTviewTag(match[1 : len(match)-1]) {
 			return ""
 		}
 		// Not a tview tag, keep the brackets
@@ -1051,73 +796,102 @@ func stripTviewTags(s string) string {
 	})
 }
 
-// isTableLine checks if a line is part of a markdown table
-func isTableLine(line string) bool {
-	trimmed := strings.TrimSpace(line)
-	if trimmed == "" {
-		return false
+// escapedTagRegex matches the start of a tag escaped with tview.Escape,
+// whose closing "[]" is displayed as "]"
+var escapedTagRegex = regexp.MustCompile(`\[[a-zA-Z0-9_,;: \-."#]+$`)
+
+// StripTviewTagsMapped removes tview tags like StripTviewTags, returning the
+// text as displayed and, for each of its bytes plus its end, the offset of
+// that byte in s
+func StripTviewTagsMapped(s string) (string, []int) {
+	var sb strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	keep := func(from, to int) {
+		sb.WriteString(s[from:to])
+		for i := from; i < to; i++ {
+			offsets = append(offsets, i)
+		}
 	}
-	// Must have pipes and at least 2 cells
-	return strings.Contains(trimmed, "|") && strings.Count(trimmed, "|") >= 2
-}
-
-// isTableSeparator checks if a line is a table separator (|---|---|)
-func isTableSeparator(line string) bool {
-	for _, ch := range line {
-		if ch != '-' && ch != ':' && ch != '|' && ch != ' ' {
-			return false
+	last := 0
+	for _, m := range tviewTagRegex.FindAllStringIndex(s, -1) {
+		inner := s[m[0]+1 : m[1]-1]
+		switch {
+		case inner == "" && escapedTagRegex.MatchString(s[last:m[0]]):
+			keep(last, m[0])
+			keep(m[1]-1, m[1])
+		case isTviewTag(inner):
+			keep(last, m[0])
+		default:
+			keep(last, m[1])
 		}
+		last = m[1]
 	}
-	return strings.Contains(line, "-")
+	keep(last, len(s))
+	return sb.String(), append(offsets, len(s))
 }
 
-// parseTableCells extracts cells from a table row
-func parseTableCells(line string) []string {
-	trimmed := strings.TrimSpace(line)
-	trimmed = strings.Trim(trimmed, "|")
-
-	parts := strings.Split(trimmed, "|")
-	var cells []string
-	for _, p := range parts {
-		cells = append(cells, strings.TrimSpace(p))
-	}
-	return cells
+// tviewColorNames are the color names recognized in tags
+var tviewColorNames = map[string]bool{
+	"white": true, "black": true, "red": true, "green": true,
+	"blue": true, "yellow": true, "cyan": true, "gray": true,
+	"grey": true, "orange": true, "purple": true, "pink": true,
 }
 
-// renderTable renders a markdown table with box-drawing characters
-// Returns nil if the table doesn't fit in maxWidth (caller should fall back to list)
-func renderTable(lines []string, maxWidth int) []string {
-	if len(lines) < 2 {
-		return nil
+// isTviewTag reports whether the inside of a [...] is a tview tag rather
+// than bracketed text like markdown link text or a [3] link reference
+func isTviewTag(inner string) bool {
+	// tview tags are: color names, #hex, -, ::flags, color:bg:flags, regions
+	if inner == "" || inner == "-" || inner == "-:-:-" || inner == ":-:-" || inner == "::-" {
+		return true
 	}
-
-	// Parse all rows
-	var allRows [][]string
-	var separatorIdx int = -1
-	for i, line := range lines {
-		if isTableSeparator(line) {
-			separatorIdx = i
-			continue
+	if strings.HasPrefix(inner, "#") {
+		return true
+	}
+	if strings.HasPrefix(inner, "::") {
+		return true
+	}
+	if regionTagRegex.MatchString(inner) {
+		return true
+	}
+	if strings.Contains(inner, ":") {
+		// Looks like a tview color:bg:flags tag
+		for _, p := range strings.Split(inner, ":") {
+			p = strings.TrimSpace(p)
+			if p == "" || p == "-" || strings.Trim(p, "bidlsu") == "" {
+				continue
+			}
+			if strings.HasPrefix(p, "#") || tviewColorNames[strings.ToLower(p)] {
+				continue
+			}
+			// Not a recognized tview tag
+			return false
 		}
-		allRows = append(allRows, parseTableCells(line))
+		return true
 	}
+	// Single word: could be a color name
+	return tviewColorNames[strings.ToLower(inner)]
+}
 
-	if len(allRows) < 1 {
+// renderTable renders table rows with box-drawing characters; the first row
+// is the header. Cells are padded according to their column's alignment.
+// Returns nil if the table doesn't fit in maxWidth (caller should fall back to list)
+func renderTable(rows [][]string, aligns []markdown.Align, maxWidth int) []string {
+	if len(rows) < 1 {
 		return nil
 	}
 
 	// Find number of columns from header
-	numCols := len(allRows[0])
+	numCols := len(rows[0])
 	if numCols == 0 {
 		return nil
 	}
 
 	// Calculate max width per column
 	colWidths := make([]int, numCols)
-	for _, row := range allRows {
+	for _, row := range rows {
 		for c := 0; c < numCols && c < len(row); c++ {
-			if len(row[c]) > colWidths[c] {
-				colWidths[c] = len(row[c])
+			if w := tview.TaggedStringWidth(row[c]); w > colWidths[c] {
+				colWidths[c] = w
 			}
 		}
 	}
@@ -1151,7 +925,7 @@ func renderTable(lines []string, maxWidth int) []string {
 	midLine := buildHLine("├", "┼", "┤", "─")
 	botLine := buildHLine("└", "┴", "┘", "─")
 
-	gray := "[#707070]"
+	gray := theme.Tag(theme.Border)
 
 	buildRow := func(cells []string, cellColor string) string {
 		var b strings.Builder
@@ -1161,12 +935,20 @@ func renderTable(lines []string, maxWidth int) []string {
 			if c < len(cells) {
 				cell = cells[c]
 			}
-			pad := colWidths[c] - len(cell)
 			if cellColor != "" {
-				b.WriteString(" " + cellColor + cell + "[-:-:-]" + strings.Repeat(" ", pad) + " " + gray + "│[-]")
-			} else {
-				b.WriteString(" " + cell + strings.Repeat(" ", pad) + " " + gray + "│[-]")
+				cell = cellColor + cell + "[-:-:-]"
+			}
+			pad := colWidths[c] - tview.TaggedStringWidth(cell)
+			left := 0
+			if c < len(aligns) {
+				switch aligns[c] {
+				case markdown.AlignRight:
+					left = pad
+				case markdown.AlignCenter:
+					left = pad / 2
+				}
 			}
+			b.WriteString(" " + strings.Repeat(" ", left) + cell + strings.Repeat(" ", pad-left) + " " + gray + "│[-]")
 		}
 		return b.String()
 	}
@@ -1175,33 +957,58 @@ func renderTable(lines []string, maxWidth int) []string {
 	result = append(result, gray+topLine+"[-]")
 
 	// Header row (first row, bold/colored)
-	result = append(result, buildRow(allRows[0], "[#87ceeb:-:b]"))
+	result = append(result, buildRow(rows[0], theme.Tag(theme.Heading2)+"[::b]"))
 
 	// Separator after header
-	if separatorIdx >= 0 || len(allRows) > 1 {
-		result = append(result, gray+midLine+"[-]")
-	}
+	result = append(result, gray+midLine+"[-]")
 
 	// Data rows
-	for i := 1; i < len(allRows); i++ {
-		result = append(result, buildRow(allRows[i], ""))
+	for i := 1; i < len(rows); i++ {
+		result = append(result, buildRow(rows[i], ""))
 	}
 
 	result = append(result, gray+botLine+"[-]")
 	return result
 }
 
-// tableToList converts markdown table to list format
-// First column header becomes the label, remaining columns become key-value pairs
-func tableToList(lines []string) []string {
-	if len(lines) < 2 {
-		return lines // Not enough for header + data
+// FrozenColumns returns, for each rendered line, the display width of the
+// table's first column (borders included) on lines that belong to a table
+// of several columns, and 0 elsewhere. A reader scrolling sideways keeps
+// that part of the line in place.
+func FrozenColumns(lines []string) []int {
+	frozen := make([]int, len(lines))
+	width := 0
+	for i, line := range lines {
+		if width == 0 {
+			top := strings.Index(line, "┌─")
+			if top < 0 {
+				continue
+			}
+			mid := strings.Index(line[top:], "┬")
+			if mid < 0 || strings.Contains(line[top:top+mid], "┐") {
+				continue
+			}
+			width = tview.TaggedStringWidth(line[:top+mid]) + 1
+		}
+		frozen[i] = width
+		if strings.Contains(line, "└") {
+			width = 0
+		}
+	}
+	return frozen
+}
+
+// tableToList converts table rows to list format
+// First column header becomes the label, remaining columns become key-value pairs.
+// rowLines holds the source line of each row for the line number gutter.
+func tableToList(rows [][]string, rowLines []int) []annotatedLine {
+	if len(rows) < 2 {
+		return nil // Not enough for header + data
 	}
 
-	// Parse header row to get column names
-	headers := parseTableCells(lines[0])
+	headers := rows[0]
 	if len(headers) == 0 {
-		return lines
+		return nil
 	}
 
 	// First column header becomes the item label
@@ -1210,38 +1017,32 @@ func tableToList(lines []string) []string {
 		itemLabel = "Item"
 	}
 
-	var result []string
-
-	// Process data rows (skip header and separator)
-	for i, line := range lines {
-		if i == 0 {
-			continue // Skip header
-		}
-		if isTableSeparator(line) {
-			continue // Skip separator
-		}
+	var result []annotatedLine
 
-		cells := parseTableCells(line)
+	for i := 1; i < len(rows); i++ {
+		cells := rows[i]
 		if len(cells) == 0 {
 			continue
 		}
 
+		// Blank line between items
+		if len(result) > 0 {
+			result = append(result, annotatedLine{text: "", sourceLine: -1})
+		}
+
 		// First cell with header label (e.g., "Item: listCmd()")
 		itemName := cells[0]
 		if itemName == "" {
 			itemName = "(empty)"
 		}
-		result = append(result, fmt.Sprintf("[cyan]%s:[-] %s", itemLabel, itemName))
+		result = append(result, annotatedLine{text: fmt.Sprintf("%s%s:[-] %s", theme.Tag(theme.Accent), itemLabel, itemName), sourceLine: rowLines[i]})
 
 		// Remaining cells become indented key-value pairs
 		for j := 1; j < len(cells) && j < len(headers); j++ {
 			if cells[j] != "" {
-				result = append(result, fmt.Sprintf("    %s: %s", headers[j], cells[j]))
+				result = append(result, annotatedLine{text: fmt.Sprintf("    %s: %s", headers[j], cells[j]), sourceLine: -1})
 			}
 		}
-
-		// Add blank line between items
-		result = append(result, "")
 	}
 
 	return result
@@ -1,67 +1,45 @@
-package main
+package term
 
 import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/theme"
 )
 
-// DiffColors defines the color scheme for diff rendering
-// Terminal style: bright highlights on dark backgrounds
+// DiffColors defines the color scheme for diff rendering, as ANSI escape
+// sequences
 type DiffColors struct {
 	// Text colors
-	AddedText   string // White text on added lines
-	RemovedText string // White text on removed lines
-	ContextText string // Gray for context lines
-	HeaderText  string // Gray for headers
+	AddedText   string
+	RemovedText string
+	ContextText string
+	HeaderText  string
 
-	// Background colors (dark terminal style)
-	AddedBg   string // Dark green #2d5a2d
-	RemovedBg string // Dark magenta #5a2d5a
+	// Background colors
+	AddedBg   string
+	RemovedBg string
 
 	Reset string
 }
 
-// DefaultDiffColors returns the magenta/green terminal color scheme
+// DefaultDiffColors returns the diff colors of the current theme
 func DefaultDiffColors() DiffColors {
 	return DiffColors{
-		// White text on dark backgrounds
-		AddedText:   "\033[38;2;255;255;255m", // White
-		RemovedText: "\033[38;2;255;255;255m", // White
-		ContextText: "\033[38;2;128;128;128m", // Gray
-		HeaderText:  "\033[38;2;128;128;128m", // Gray
+		AddedText:   theme.ANSI(theme.Added, false),
+		RemovedText: theme.ANSI(theme.Removed, false),
+		ContextText: theme.ANSI(theme.Muted, false),
+		HeaderText:  theme.ANSI(theme.Muted, false),
 
-		// Dark background colors
-		AddedBg:   "\033[48;2;45;90;45m",  // #2d5a2d - Dark green
-		RemovedBg: "\033[48;2;90;45;90m",  // #5a2d5a - Dark magenta
+		AddedBg:   theme.ANSI(theme.AddedBg, true),
+		RemovedBg: theme.ANSI(theme.RemovedBg, true),
 
 		Reset: "\033[0m",
 	}
 }
 
-// DiffHunk represents a single hunk from a unified diff
-type DiffHunk struct {
-	Header   string   // The @@ line (we hide this in display)
-	Lines    []DiffLine
-	StartOld int      // Starting line in old file
-	StartNew int      // Starting line in new file
-}
-
-// DiffLine represents a single line in a hunk
-type DiffLine struct {
-	Type    DiffLineType
-	Content string
-}
-
-// DiffLineType indicates whether a line was added, removed, or context
-type DiffLineType int
-
-const (
-	DiffContext DiffLineType = iota
-	DiffAdded
-	DiffRemoved
-)
-
 // DiffFormatter renders diff content with the designed visual style
 type DiffFormatter struct {
 	Colors          DiffColors
@@ -80,80 +58,9 @@ func NewDiffFormatter(width int) *DiffFormatter {
 	}
 }
 
-// ParseHunks extracts hunks from unified diff content
-func ParseHunks(content string) []DiffHunk {
-	lines := strings.Split(content, "\n")
-	var hunks []DiffHunk
-	var currentHunk *DiffHunk
-
-	for _, line := range lines {
-		// Skip file headers
-		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
-			continue
-		}
-
-		// New hunk starts with @@
-		if strings.HasPrefix(line, "@@") {
-			if currentHunk != nil {
-				hunks = append(hunks, *currentHunk)
-			}
-			currentHunk = &DiffHunk{
-				Header: line,
-				Lines:  []DiffLine{},
-			}
-			// Parse line numbers from @@ -start,count +start,count @@
-			parseHunkHeader(line, currentHunk)
-			continue
-		}
-
-		// Add lines to current hunk
-		if currentHunk != nil {
-			var lineType DiffLineType
-			var lineContent string
-
-			if strings.HasPrefix(line, "+") {
-				lineType = DiffAdded
-				lineContent = line[1:] // Strip the + prefix
-			} else if strings.HasPrefix(line, "-") {
-				lineType = DiffRemoved
-				lineContent = line[1:] // Strip the - prefix
-			} else if strings.HasPrefix(line, " ") {
-				lineType = DiffContext
-				lineContent = line[1:] // Strip the space prefix
-			} else {
-				// Empty line or other content
-				lineType = DiffContext
-				lineContent = line
-			}
-
-			currentHunk.Lines = append(currentHunk.Lines, DiffLine{
-				Type:    lineType,
-				Content: lineContent,
-			})
-		}
-	}
-
-	// Don't forget the last hunk
-	if currentHunk != nil {
-		hunks = append(hunks, *currentHunk)
-	}
-
-	return hunks
-}
-
-// parseHunkHeader extracts line numbers from @@ -old,count +new,count @@
-func parseHunkHeader(header string, hunk *DiffHunk) {
-	re := regexp.MustCompile(`@@ -(\d+),?\d* \+(\d+),?\d* @@`)
-	matches := re.FindStringSubmatch(header)
-	if len(matches) >= 3 {
-		fmt.Sscanf(matches[1], "%d", &hunk.StartOld)
-		fmt.Sscanf(matches[2], "%d", &hunk.StartNew)
-	}
-}
-
 // FormatHunk renders a single hunk with the designed visual style
 // Returns only the diff content - header/footer handled by FormatDiffPage
-func (f *DiffFormatter) FormatHunk(hunk DiffHunk, hunkIndex int, totalHunks int, filename string) string {
+func (f *DiffFormatter) FormatHunk(hunk parse.DiffHunk, hunkIndex int, totalHunks int, filename string) string {
 	var sb strings.Builder
 	c := f.Colors
 
@@ -179,7 +86,7 @@ func (f *DiffFormatter) FormatHunk(hunk DiffHunk, hunkIndex int, totalHunks int,
 }
 
 // formatLine renders a single diff line with colors and padding
-func (f *DiffFormatter) formatLine(line DiffLine, width int) string {
+func (f *DiffFormatter) formatLine(line parse.DiffLine, width int) string {
 	c := f.Colors
 	content := line.Content
 
@@ -191,15 +98,15 @@ func (f *DiffFormatter) formatLine(line DiffLine, width int) string {
 	paddedContent := content + strings.Repeat(" ", padding)
 
 	switch line.Type {
-	case DiffAdded:
+	case parse.DiffAdded:
 		// High contrast: dark green text on light green background
 		return fmt.Sprintf("    %s%s%s%s", c.AddedBg, c.AddedText, paddedContent, c.Reset)
 
-	case DiffRemoved:
+	case parse.DiffRemoved:
 		// High contrast: dark red text on light red background
 		return fmt.Sprintf("    %s%s%s%s", c.RemovedBg, c.RemovedText, paddedContent, c.Reset)
 
-	case DiffContext:
+	case parse.DiffContext:
 		// Gray text, no background
 		return fmt.Sprintf("    %s%s%s", c.ContextText, content, c.Reset)
 
@@ -209,7 +116,7 @@ func (f *DiffFormatter) formatLine(line DiffLine, width int) string {
 }
 
 // detectFunctions finds function/class definitions in hunk (iteration 5)
-func (f *DiffFormatter) detectFunctions(hunk DiffHunk) string {
+func (f *DiffFormatter) detectFunctions(hunk parse.DiffHunk) string {
 	var functions []string
 	seen := make(map[string]bool)
 
@@ -262,7 +169,7 @@ func (f *DiffFormatter) detectFunctions(hunk DiffHunk) string {
 
 // Format renders the entire diff content
 func (f *DiffFormatter) Format(content string, filename string) string {
-	hunks := ParseHunks(content)
+	hunks := parse.ParseHunks(content)
 	if len(hunks) == 0 {
 		return content // Not a valid diff, return as-is
 	}
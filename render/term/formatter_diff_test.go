@@ -0,0 +1,67 @@
+package term
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wildreason/reader/parse"
+)
+
+func TestDetectFunctions(t *testing.T) {
+	formatter := NewDiffFormatter(80)
+
+	// Test Go function detection
+	hunk := parse.DiffHunk{
+		Lines: []parse.DiffLine{
+			{Type: parse.DiffAdded, Content: "func calculateWithTax(subtotal int) float64 {"},
+			{Type: parse.DiffAdded, Content: "    return float64(subtotal) * (1 + TaxRate)"},
+			{Type: parse.DiffAdded, Content: "}"},
+		},
+	}
+
+	result := formatter.detectFunctions(hunk)
+	if !strings.Contains(result, "calculateWithTax()") {
+		t.Errorf("Expected to detect calculateWithTax(), got: %s", result)
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	formatter := NewDiffFormatter(80)
+
+	// Test added line has background color (dark green: #2d5a2d)
+	addedLine := parse.DiffLine{Type: parse.DiffAdded, Content: "added line"}
+	result := formatter.formatLine(addedLine, 40)
+
+	// Should contain ANSI background code for dark green
+	if !strings.Contains(result, "\033[48;2;45;90;45m") {
+		t.Error("Added line should have dark green background")
+	}
+
+	// Test removed line has background color (dark magenta: #5a2d5a)
+	removedLine := parse.DiffLine{Type: parse.DiffRemoved, Content: "removed line"}
+	result = formatter.formatLine(removedLine, 40)
+
+	// Should contain ANSI background code for dark magenta
+	if !strings.Contains(result, "\033[48;2;90;45;90m") {
+		t.Error("Removed line should have dark magenta background")
+	}
+}
+
+// Run this to see visual output
+func ExampleDiffFormatter() {
+	diffContent := `--- a/file.go
++++ b/file.go
+@@ -3,6 +3,8 @@
+
+ import "fmt"
+
++const TaxRate = 0.08
++
+ func calculateTotal(items []int) int {
+`
+
+	formatter := NewDiffFormatter(80)
+	output := formatter.Format(diffContent, "file.go")
+	fmt.Println(output)
+}
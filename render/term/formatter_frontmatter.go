@@ -0,0 +1,37 @@
+package term
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/theme"
+)
+
+// formatFrontmatter renders a document's frontmatter as a metadata header:
+// one "key: value" line per field, nested values flattened, then a blank line.
+func formatFrontmatter(fm *parse.Frontmatter, width int) []annotatedLine {
+	if fm.Fields.Len() == 0 && fm.Err == nil {
+		return nil
+	}
+
+	fields := "fields"
+	if fm.Fields.Len() == 1 {
+		fields = "field"
+	}
+	header := fmt.Sprintf("%sMetadata · %d %s[-]", theme.Tag(theme.Muted), fm.Fields.Len(), fields)
+	result := []annotatedLine{{text: header, sourceLine: -1}}
+	if fm.Err != nil {
+		msg := theme.Tag(theme.Error) + "Frontmatter " + tview.Escape(fm.Err.Error()) + "[-]"
+		for _, w := range wrapLine(msg, width, "") {
+			result = append(result, annotatedLine{text: w, sourceLine: -1})
+		}
+	}
+	for _, key := range fm.Fields.Keys {
+		line := theme.Tag(theme.Accent) + tview.Escape(key) + "[-]: " + tview.Escape(parse.FormatValue(fm.Fields.Values[key]))
+		for _, w := range wrapLine(line, width, "  ") {
+			result = append(result, annotatedLine{text: w, sourceLine: -1})
+		}
+	}
+	return append(result, annotatedLine{text: "", sourceLine: -1})
+}
@@ -0,0 +1,517 @@
+package term
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/parse/markdown"
+	"github.com/wildreason/reader/theme"
+)
+
+// formatMarkdown renders markdown from its syntax tree, followed by the
+// numbered list of its links unless they are hyperlinks
+// Returns annotated lines with source line mapping for line number gutter
+func formatMarkdown(text string, maxWidth int) []annotatedLine {
+	if maxWidth <= 0 {
+		maxWidth = 76 // Default
+	}
+	doc := markdown.Parse(text)
+	targets := numberLinks(doc)
+	result := renderBlocks(doc, maxWidth, false)
+	if !settings.Hyperlinks && len(targets) > 0 {
+		result = append(result, annotatedLine{text: "", sourceLine: -1})
+		result = append(result, renderLinkList(targets, maxWidth)...)
+	}
+	return result
+}
+
+// formatMarkdownPage renders one page of a markdown block. Pages are parsed
+// on their own, so when the page references footnotes the block's footnote
+// definitions are appended; their lines map to no source line.
+func formatMarkdownPage(block *parse.Block, page string, maxWidth int) []annotatedLine {
+	if block.TotalPages <= 1 || !strings.Contains(page, "[^") {
+		return formatMarkdown(page, maxWidth)
+	}
+	defs := footnoteDefinitions(block.Content)
+	if defs == "" {
+		return formatMarkdown(page, maxWidth)
+	}
+	pageLines := strings.Count(page, "\n") + 1
+	result := formatMarkdown(page+"\n\n"+defs, maxWidth)
+	for i := range result {
+		if result[i].sourceLine >= pageLines {
+			result[i].sourceLine = -1
+		}
+	}
+	return result
+}
+
+// footnoteDefinitions returns the source of the referenced footnote
+// definitions in a markdown document
+func footnoteDefinitions(content string) string {
+	footnotes := markdown.Parse(content).LastChild
+	if footnotes == nil || footnotes.Kind != markdown.FootnoteList {
+		return ""
+	}
+	lines := strings.Split(content, "\n")
+	var defs []string
+	for def := footnotes.FirstChild; def != nil; def = def.NextSibling {
+		defs = append(defs, strings.Join(lines[def.Line-1:def.EndLine], "\n"))
+	}
+	return strings.Join(defs, "\n\n")
+}
+
+// formatPlainLines renders preformatted text (shell output, transcripts)
+// line by line, wrapping long lines (unless noWrap is set) but never
+// joining them
+func formatPlainLines(text string, maxWidth int) []annotatedLine {
+	if settings.NoWrap {
+		maxWidth = 0
+	}
+	var result []annotatedLine
+	for i, line := range strings.Split(text, "\n") {
+		for j, w := range wrapLine(line, maxWidth, "") {
+			sl := -1
+			if j == 0 {
+				sl = i
+			}
+			result = append(result, annotatedLine{text: w, sourceLine: sl})
+		}
+	}
+	return result
+}
+
+// formatTranscript renders a transcript turn part by part: assistant text as
+// markdown, everything else (user messages, diffs, tool output) as is
+func formatTranscript(block *parse.Block, text string, maxWidth int) []annotatedLine {
+	data, ok := block.Data.(*parse.TranscriptData)
+	if !ok || len(data.TurnParts) == 0 {
+		return formatPlainLines(text, maxWidth)
+	}
+
+	var result []annotatedLine
+	for _, part := range data.TurnParts {
+		partText, ok := parse.TurnPartText(part)
+		if !ok {
+			continue
+		}
+		partText = tview.TranslateANSI(partText)
+
+		var lines []annotatedLine
+		if part.Type == "assistant" {
+			lines = formatMarkdown(partText, maxWidth)
+		} else {
+			lines = formatPlainLines(partText, maxWidth)
+		}
+		if len(result) > 0 {
+			result = append(result, annotatedLine{text: "", sourceLine: -1})
+		}
+		// Turn parts don't map to file lines
+		for _, l := range lines {
+			result = append(result, annotatedLine{text: l.text, sourceLine: -1})
+		}
+	}
+	return result
+}
+
+// renderBlocks renders the child blocks of a container, separated by blank
+// lines unless tight (the items of a tight list)
+func renderBlocks(parent *markdown.Node, width int, tight bool) []annotatedLine {
+	var result []annotatedLine
+	for n := parent.FirstChild; n != nil; n = n.NextSibling {
+		lines := renderBlock(n, width)
+		if len(lines) == 0 {
+			continue
+		}
+		if len(result) > 0 && !tight {
+			result = append(result, annotatedLine{text: "", sourceLine: -1})
+		}
+		result = append(result, lines...)
+	}
+	return result
+}
+
+// renderBlock renders a single block node at the given width
+func renderBlock(n *markdown.Node, width int) []annotatedLine {
+	switch n.Kind {
+	case markdown.Paragraph:
+		if settings.InlineImages {
+			if lines := renderInlineImage(n, width); lines != nil {
+				return lines
+			}
+		}
+		return wrapInlines(renderInlines(n, inlineStyle{}), n.Line-1, width)
+
+	case markdown.Heading:
+		style := inlineStyle{fg: theme.Color(theme.Heading3), bold: true}
+		switch n.Level {
+		case 1:
+			style.fg = theme.Color(theme.Heading)
+		case 2:
+			style.fg = theme.Color(theme.Heading2)
+		}
+		text := headingAnchor(n.Level, strings.TrimSpace(markdown.PlainText(n))) + style.tag() + renderInlines(n, style) + "[-:-:-]"
+		return wrapInlines(text, n.Line-1, width)
+
+	case markdown.ThematicBreak:
+		return []annotatedLine{{text: theme.Tag(theme.Muted) + strings.Repeat("─", width) + "[-]", sourceLine: n.Line - 1}}
+
+	case markdown.CodeBlock:
+		if n.Literal == "" {
+			return nil
+		}
+		lines := strings.Split(strings.TrimSuffix(n.Literal, "\n"), "\n")
+		language, _ := parseFenceInfo(n.Info)
+		// fenceStartLine is the line before the first content line
+		fenceStartLine := n.Line - 1
+		if !n.Fenced {
+			fenceStartLine = n.Line - 2
+		}
+		rendered := renderCodeBlock(lines, n.Info, width)
+		return annotateCodeBlockResult(rendered, fenceStartLine, len(lines), !containsBoxDrawing(lines), language != "")
+
+	case markdown.MathBlock:
+		// Display math is indented on its own lines in the math color
+		var result []annotatedLine
+		for i, line := range strings.Split(texToUnicode(n.Literal), "\n") {
+			sl := -1
+			if i == 0 {
+				sl = n.Line - 1
+			}
+			for _, w := range wrapLine(line, width-4, "") {
+				result = append(result, annotatedLine{text: "    " + theme.Tag(theme.Math) + w + "[-]", sourceLine: sl})
+				sl = -1
+			}
+		}
+		return result
+
+	case markdown.HTMLBlock:
+		var result []annotatedLine
+		for i, line := range strings.Split(strings.TrimSuffix(n.Literal, "\n"), "\n") {
+			result = append(result, wrapInlines(line, n.Line-1+i, width)...)
+		}
+		return result
+
+	case markdown.BlockQuote:
+		bar := theme.Tag(theme.Muted) + "│[-]"
+		var result []annotatedLine
+		for _, l := range renderBlocks(n, width-2, false) {
+			if l.text == "" {
+				l.text = bar
+			} else {
+				l.text = bar + " " + l.text
+			}
+			result = append(result, l)
+		}
+		return result
+
+	case markdown.Callout:
+		return renderCallout(n, width)
+
+	case markdown.List:
+		return renderList(n, width)
+
+	case markdown.Table:
+		return renderTableNode(n, width)
+
+	case markdown.FootnoteList:
+		return renderFootnotes(n, width)
+	}
+	return nil
+}
+
+// calloutStyles gives the color role and title icon of each callout type
+var calloutStyles = map[string]struct {
+	role theme.Role
+	icon string
+}{
+	"note":      {theme.CalloutNote, "ℹ"},
+	"abstract":  {theme.CalloutNote, "≡"},
+	"info":      {theme.CalloutNote, "ℹ"},
+	"tip":       {theme.CalloutTip, "★"},
+	"important": {theme.CalloutImportant, "❝"},
+	"success":   {theme.CalloutTip, "✔"},
+	"question":  {theme.CalloutTip, "?"},
+	"warning":   {theme.CalloutWarning, "⚠"},
+	"caution":   {theme.CalloutCaution, "⊘"},
+	"failure":   {theme.CalloutCaution, "✖"},
+	"danger":    {theme.CalloutCaution, "‼"},
+	"bug":       {theme.CalloutCaution, "✱"},
+	"example":   {theme.CalloutImportant, "≡"},
+	"quote":     {theme.Muted, "❝"},
+}
+
+// renderCallout renders an alert or admonition as a colored panel in the
+// rounded box border style, with its icon and title in the header
+func renderCallout(n *markdown.Node, width int) []annotatedLine {
+	style, ok := calloutStyles[n.CalloutType]
+	if !ok {
+		style = calloutStyles["note"]
+	}
+	color := theme.Tag(style.role)
+	br := NewBorderRenderer(BorderRounded)
+	inner := width - br.GetContentIndent()
+	if inner < 1 {
+		inner = 1
+	}
+
+	var result []annotatedLine
+	if n.Title != "" {
+		title := "[::b]" + style.icon + " " + tview.Escape(n.Title) + "[::-]"
+		top, header, _ := strings.Cut(br.RenderBlockStart(title, "", width), "\n")
+		result = append(result,
+			annotatedLine{text: color + top + "[-]", sourceLine: n.Line - 1},
+			annotatedLine{text: color + header + "[-]", sourceLine: -1})
+	} else {
+		top, _, _ := strings.Cut(br.RenderBlockStart("", "", width), "\n")
+		result = append(result, annotatedLine{text: color + top + "[-]", sourceLine: n.Line - 1})
+	}
+
+	side := color + "│[-]"
+	for _, l := range renderBlocks(n, inner, false) {
+		pad := inner - tview.TaggedStringWidth(l.text)
+		if pad < 0 {
+			pad = 0
+		}
+		l.text = side + " " + l.text + "[-:-:-]" + strings.Repeat(" ", pad) + " " + side
+		result = append(result, l)
+	}
+	result = append(result, annotatedLine{text: color + br.RenderBlockEnd(width) + "[-]", sourceLine: -1})
+	return result
+}
+
+// renderFootnotes renders the footnotes collected at the end of a document
+// under a short rule, each marked with its superscript number
+func renderFootnotes(list *markdown.Node, width int) []annotatedLine {
+	ruleWidth := 20
+	if ruleWidth > width {
+		ruleWidth = width
+	}
+	result := []annotatedLine{{text: theme.Tag(theme.Muted) + strings.Repeat("─", ruleWidth) + "[-]", sourceLine: -1}}
+	for def := list.FirstChild; def != nil; def = def.NextSibling {
+		marker := superscript(def.Index) + " "
+		indent := strings.Repeat(" ", tview.TaggedStringWidth(marker))
+		lines := renderBlocks(def, width-len(indent), false)
+		if len(lines) == 0 {
+			lines = []annotatedLine{{text: "", sourceLine: def.Line - 1}}
+		}
+		for i, l := range lines {
+			switch {
+			case i == 0:
+				l.text = theme.Tag(theme.Muted) + marker + "[-]" + l.text
+			case l.text != "":
+				l.text = indent + l.text
+			}
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// superscript writes n with superscript digits
+func superscript(n int) string {
+	const digits = "⁰¹²³⁴⁵⁶⁷⁸⁹"
+	var sb strings.Builder
+	for _, d := range strconv.Itoa(n) {
+		sb.WriteRune([]rune(digits)[d-'0'])
+	}
+	return sb.String()
+}
+
+// renderList renders list items with colored markers, indenting
+// continuation lines and nested blocks under the item text
+func renderList(list *markdown.Node, width int) []annotatedLine {
+	nested := false
+	for p := list.Parent; p != nil; p = p.Parent {
+		if p.Kind == markdown.List {
+			nested = true
+			break
+		}
+	}
+
+	// Ordered markers are right-aligned so item text lines up past 9.
+	numWidth := 0
+	if list.Ordered {
+		last := list.Start
+		for item := list.FirstChild.NextSibling; item != nil; item = item.NextSibling {
+			last++
+		}
+		numWidth = len(strconv.Itoa(last))
+	}
+
+	var result []annotatedLine
+	num := list.Start
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		var marker string
+		switch {
+		case list.Ordered:
+			n := strconv.Itoa(num)
+			marker = strings.Repeat(" ", numWidth-len(n)) + theme.Tag(theme.Highlight) + n + string(list.Delimiter) + "[-] "
+			num++
+		case nested:
+			marker = theme.Tag(theme.Muted) + string(list.BulletChar) + "[-] "
+		default:
+			marker = theme.Tag(theme.Accent) + string(list.BulletChar) + "[-] "
+		}
+		if !nested {
+			marker = "  " + marker
+		}
+		indent := strings.Repeat(" ", tview.TaggedStringWidth(marker))
+
+		lines := renderBlocks(item, width-len(indent), list.Tight)
+		if len(lines) == 0 {
+			lines = []annotatedLine{{text: "", sourceLine: item.Line - 1}}
+		}
+		if len(result) > 0 && !list.Tight {
+			result = append(result, annotatedLine{text: "", sourceLine: -1})
+		}
+		for i, l := range lines {
+			switch {
+			case i == 0:
+				l.text = marker + l.text
+			case l.text != "":
+				l.text = indent + l.text
+			}
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// renderTableNode renders a table with box-drawing characters, falling back
+// to a list when it doesn't fit (or never, when noWrap is set)
+func renderTableNode(table *markdown.Node, width int) []annotatedLine {
+	var rows [][]string
+	var rowLines []int
+	var aligns []markdown.Align
+	for cell := table.FirstChild.FirstChild; cell != nil; cell = cell.NextSibling {
+		aligns = append(aligns, cell.Align)
+	}
+	for row := table.FirstChild; row != nil; row = row.NextSibling {
+		var cells []string
+		for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+			cells = append(cells, renderInlines(cell, inlineStyle{}))
+		}
+		rows = append(rows, cells)
+		rowLines = append(rowLines, row.Line-1)
+	}
+
+	if settings.NoWrap {
+		width = math.MaxInt
+	}
+	rendered := renderTable(rows, aligns, width)
+	if rendered == nil {
+		return tableToList(rows, rowLines)
+	}
+
+	// Top border, header, separator, body rows, bottom border
+	result := make([]annotatedLine, len(rendered))
+	for i, text := range rendered {
+		result[i] = annotatedLine{text: text, sourceLine: -1}
+	}
+	result[1].sourceLine = rowLines[0]
+	for i := 1; i < len(rows); i++ {
+		result[i+2].sourceLine = rowLines[i]
+	}
+	return result
+}
+
+// wrapInlines wraps rendered inline text, which may contain hard breaks,
+// mapping the first line to sourceLine
+func wrapInlines(text string, sourceLine int, width int) []annotatedLine {
+	var result []annotatedLine
+	for _, line := range strings.Split(text, "\n") {
+		for _, w := range wrapLine(line, width, "") {
+			sl := -1
+			if len(result) == 0 {
+				sl = sourceLine
+			}
+			result = append(result, annotatedLine{text: w, sourceLine: sl})
+		}
+	}
+	return result
+}
+
+// inlineStyle is the tview style in effect while rendering inlines
+type inlineStyle struct {
+	fg     string
+	bold   bool
+	italic bool
+	strike bool
+}
+
+// tag returns the tview tag that switches to this style
+func (s inlineStyle) tag() string {
+	fg := s.fg
+	if fg == "" {
+		fg = "-"
+	}
+	flags := ""
+	if s.bold {
+		flags += "b"
+	}
+	if s.italic {
+		flags += "i"
+	}
+	if s.strike {
+		flags += "s"
+	}
+	if flags == "" {
+		flags = "-"
+	}
+	return "[" + fg + ":-:" + flags + "]"
+}
+
+// renderInlines renders the inline children of n as tview-tagged text.
+// Soft breaks become spaces so paragraphs reflow; hard breaks become newlines.
+func renderInlines(n *markdown.Node, base inlineStyle) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Kind {
+		case markdown.Text, markdown.HTMLInline:
+			sb.WriteString(c.Literal)
+		case markdown.SoftBreak:
+			sb.WriteString(" ")
+		case markdown.HardBreak:
+			sb.WriteString("\n")
+		case markdown.CodeSpan:
+			style := base
+			style.fg = theme.Color(theme.Code)
+			sb.WriteString(style.tag() + c.Literal + base.tag())
+		case markdown.Math:
+			style := base
+			style.fg = theme.Color(theme.Math)
+			text := strings.ReplaceAll(texToUnicode(c.Literal), "\n", "; ")
+			sb.WriteString(style.tag() + text + base.tag())
+		case markdown.Emph:
+			style := base
+			style.italic = true
+			sb.WriteString(style.tag() + renderInlines(c, style) + base.tag())
+		case markdown.Strong:
+			style := base
+			style.fg = theme.Color(theme.Strong)
+			style.bold = true
+			sb.WriteString(style.tag() + renderInlines(c, style) + base.tag())
+		case markdown.Strikethrough:
+			style := base
+			style.strike = true
+			sb.WriteString(style.tag() + renderInlines(c, style) + base.tag())
+		case markdown.TaskCheckbox:
+			if c.Checked {
+				sb.WriteString(theme.Tag(theme.Success) + "☑" + base.tag())
+			} else {
+				sb.WriteString(theme.Tag(theme.Muted) + "☐" + base.tag())
+			}
+		case markdown.FootnoteReference:
+			sb.WriteString(theme.Tag(theme.Muted) + superscript(c.Index) + base.tag())
+		case markdown.Link, markdown.WikiLink:
+			sb.WriteString(renderLink(c, base))
+		case markdown.Image:
+			sb.WriteString(theme.Tag(theme.Muted) + "image: " + markdown.PlainText(c) + base.tag())
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,230 @@
+package term
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/theme"
+)
+
+func TestFormatMarkdownReflowsParagraphs(t *testing.T) {
+	lines := formatMarkdown("one **two**\nthree\n\nfour", 80)
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %v", len(lines), lines)
+	}
+	if got := StripTviewTags(lines[0].text); got != "one two three" {
+		t.Errorf("Expected soft line break to reflow, got %q", got)
+	}
+	if lines[0].sourceLine != 0 || lines[1].sourceLine != -1 || lines[2].sourceLine != 3 {
+		t.Errorf("Unexpected source lines: %d %d %d", lines[0].sourceLine, lines[1].sourceLine, lines[2].sourceLine)
+	}
+}
+
+func TestFormatMarkdownNarrowWidths(t *testing.T) {
+	src := "# A heading\n\n```go\nfunc main() {}\n```\n\n> [!NOTE] Title\n> text\n\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+	blocks := []parse.Block{{Name: "doc.md", Content: src, Pages: []string{src}, TotalPages: 1, PageStartLine: []int{1}}}
+	for width := 1; width <= 8; width++ {
+		for _, border := range []BorderStyle{BorderNone, BorderBox} {
+			out := FormatBlocksWith(blocks, width, border, Settings{})
+			if !strings.Contains(StripTviewTags(out), "│ f") {
+				t.Errorf("width %d, %s border: code block missing:\n%s", width, border, out)
+			}
+		}
+	}
+}
+
+func TestFormatMarkdownNestedList(t *testing.T) {
+	lines := formatMarkdown("- a\n  - b\n- c", 80)
+	var got []string
+	for _, l := range lines {
+		got = append(got, StripTviewTags(l.text))
+	}
+	want := []string{"  - a", "    - b", "  - c"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestFormatMarkdownBlockQuote(t *testing.T) {
+	lines := formatMarkdown("> quoted\n> *text*", 80)
+	if len(lines) != 1 || StripTviewTags(lines[0].text) != "│ quoted text" {
+		t.Errorf("Expected one quoted line, got %v", lines)
+	}
+}
+
+func TestFormatMarkdownTableAlignment(t *testing.T) {
+	lines := formatMarkdown("| l | c | r |\n|:--|:-:|--:|\n| a | b | c |\n| aaa | bbb | ccc |", 80)
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 table lines, got %d", len(lines))
+	}
+	if got := StripTviewTags(lines[3].text); got != "│ a   │  b  │   c │" {
+		t.Errorf("Unexpected aligned row %q", got)
+	}
+	if lines[3].sourceLine != 2 {
+		t.Errorf("Expected row to map to source line 2, got %d", lines[3].sourceLine)
+	}
+}
+
+func TestFormatMarkdownTableNoWrap(t *testing.T) {
+	table := "| name | description |\n|---|---|\n| a | " + strings.Repeat("long ", 20) + "|"
+	if lines := formatMarkdown(table, 40); len(lines) == 5 {
+		t.Fatalf("Expected a wide table to fall back to a list")
+	}
+
+	SetNoWrap(true)
+	defer SetNoWrap(false)
+	lines := formatMarkdown(table, 40)
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 table lines without wrapping, got %d", len(lines))
+	}
+	if w := tview.TaggedStringWidth(lines[3].text); w <= 40 {
+		t.Errorf("Expected the table to be wider than 40 columns, got %d", w)
+	}
+}
+
+func TestFrozenColumns(t *testing.T) {
+	lines := []string{
+		"intro",
+		" ┌──────┬─────┐",
+		" │ [::b]name[-:-:-] │ age │",
+		" ├──────┼─────┤",
+		" │ ada  │ 36  │",
+		" └──────┴─────┘",
+		"┌────┐",
+		"│ go │",
+		"└────┘",
+	}
+	want := []int{0, 9, 9, 9, 9, 9, 0, 0, 0}
+	if got := FrozenColumns(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("FrozenColumns = %v, want %v", got, want)
+	}
+}
+
+func TestFormatMarkdownPageResolvesFootnotes(t *testing.T) {
+	content := "See[^1].\n\nmore\n\n[^1]: Note."
+	block := &parse.Block{Content: content, Pages: []string{"See[^1].", "\nmore\n\n[^1]: Note."}, TotalPages: 2}
+	lines := formatMarkdownPage(block, block.Pages[0], 80)
+	last := lines[len(lines)-1]
+	if StripTviewTags(last.text) != "¹ Note." || last.sourceLine != -1 {
+		t.Errorf("Expected footnote from a later page, got %q (line %d)", last.text, last.sourceLine)
+	}
+}
+
+func TestFormatMarkdownOrderedListWithCode(t *testing.T) {
+	var src strings.Builder
+	for i := 1; i <= 10; i++ {
+		src.WriteString(fmt.Sprintf("%d. item\n", i))
+	}
+	src.WriteString("    ```\n    code\n    ```\n")
+	lines := formatMarkdown(src.String(), 80)
+
+	var got []string
+	for _, l := range lines {
+		got = append(got, StripTviewTags(l.text))
+	}
+	if got[0] != "   1. item" || got[9] != "  10. item" {
+		t.Errorf("Expected right-aligned numbers, got %q and %q", got[0], got[9])
+	}
+	if got[11] != "      │ code │" || lines[11].sourceLine != 11 {
+		t.Errorf("Expected code indented under the item at source line 11, got %q (line %d)", got[11], lines[11].sourceLine)
+	}
+}
+
+func TestFormatMarkdownCallout(t *testing.T) {
+	lines := formatMarkdown("> [!NOTE]\n> Read this.", 20)
+	var got []string
+	for _, l := range lines {
+		got = append(got, StripTviewTags(l.text))
+	}
+	want := []string{
+		"╭──────────────────╮",
+		"│ ℹ Note           │",
+		"│ Read this.       │",
+		"╰──────────────────╯",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected panel\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if lines[0].sourceLine != 0 || lines[2].sourceLine != 1 {
+		t.Errorf("Expected marker at line 0 and body at line 1, got %d and %d", lines[0].sourceLine, lines[2].sourceLine)
+	}
+}
+
+func TestFormatMarkdownCalloutThemeColors(t *testing.T) {
+	defer theme.Set(theme.Current())
+	for _, th := range []theme.Theme{theme.Dark(), theme.Light(), theme.HighContrastLight()} {
+		theme.Set(th)
+		lines := formatMarkdown("> [!WARNING]\n> Careful.", 20)
+		if want := "[" + th.Colors[theme.CalloutWarning] + "]"; !strings.HasPrefix(lines[0].text, want) {
+			t.Errorf("Expected warning border in %s, got %q", want, lines[0].text)
+		}
+	}
+}
+
+func TestFormatMarkdownMath(t *testing.T) {
+	lines := formatMarkdown("Energy $E = mc^2$ and $\\alpha_i \\leq \\frac{1}{2}$.\n\n$$\n\\sum_{i=1}^{n} x_i = \\frac{a+b}{c}\n$$", 80)
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %v", len(lines), lines)
+	}
+	if got := StripTviewTags(lines[0].text); got != "Energy E = mc² and αᵢ ≤ ½." {
+		t.Errorf("Unexpected inline math %q", got)
+	}
+	if got := StripTviewTags(lines[2].text); got != "    ∑ᵢ₌₁ⁿ xᵢ = (a+b)/c" || lines[2].sourceLine != 2 {
+		t.Errorf("Unexpected display math %q (line %d)", got, lines[2].sourceLine)
+	}
+}
+
+func TestTexToUnicode(t *testing.T) {
+	tests := map[string]string{
+		`e^{i\pi}`:                                     "e^(iπ)",
+		`\sqrt{x^2+1}`:                                 "√(x²+1)",
+		`\mathbb{R}^n \to \mathbb{R}`:                  "ℝⁿ → ℝ",
+		`\left( \frac{n(n+1)}{2} \right)`:              "(n(n+1)/2)",
+		`\lim_{x \to 0} \sin x`:                        "lim_(x → 0) sin x",
+		`\begin{pmatrix} a & b \\ c & d \end{pmatrix}`: "(a b; c d)",
+		`|x| = \begin{cases} x & x \geq 0 \\ -x & \text{otherwise} \end{cases}`: "|x| = { x  x ≥ 0\n        -x  otherwise",
+	}
+	for tex, want := range tests {
+		if got := texToUnicode(tex); got != want {
+			t.Errorf("texToUnicode(%q) = %q, want %q", tex, got, want)
+		}
+	}
+}
+
+func TestStripTviewTagsMapped(t *testing.T) {
+	s := `[yellow]a[-] ["link-61"][blue]b[""] [2] [x[]`
+	plain, offsets := StripTviewTagsMapped(s)
+	if plain != "a b [2] [x]" {
+		t.Fatalf("Expected tags removed and escapes undone, got %q", plain)
+	}
+	if len(offsets) != len(plain)+1 || offsets[len(plain)] != len(s) {
+		t.Fatalf("Expected an offset per byte plus the end, got %v", offsets)
+	}
+	for i := 0; i < len(plain); i++ {
+		if s[offsets[i]] != plain[i] {
+			t.Errorf("Offset %d points at %q, want %q", i, s[offsets[i]], plain[i])
+		}
+	}
+}
+
+func TestFindHeadings(t *testing.T) {
+	text := annotatedLinesToString(formatMarkdown("# Design\n\nIntro.\n\n## Data *model*\n\n### Storage", 80))
+	headings := FindHeadings(text)
+	want := []Heading{{1, "Design", 0}, {2, "Data model", 4}, {3, "Storage", 6}}
+	if len(headings) != len(want) {
+		t.Fatalf("Expected %d headings, got %+v", len(want), headings)
+	}
+	for i, h := range headings {
+		if h != want[i] {
+			t.Errorf("Heading %d = %+v, want %+v", i, h, want[i])
+		}
+	}
+	if got := StripTviewTags(strings.Split(text, "\n")[4]); got != "Data model" {
+		t.Errorf("Expected heading anchors to take no space, got %q", got)
+	}
+}
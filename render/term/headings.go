@@ -0,0 +1,40 @@
+package term
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Heading is a markdown heading in rendered text
+type Heading struct {
+	Level int    // 1 to 6
+	Title string // Plain text of the heading
+	Line  int    // 0-based line the heading starts on
+}
+
+// headingRegionRegex matches the empty regions that mark headings
+var headingRegionRegex = regexp.MustCompile(`\["heading-([1-6])-([0-9a-f]*)"\]`)
+
+// headingAnchor returns an empty region marking a heading, so its line can
+// be found in rendered text. The title is hex-encoded like link targets.
+func headingAnchor(level int, title string) string {
+	return `["heading-` + strconv.Itoa(level) + "-" + hex.EncodeToString([]byte(title)) + `"][""]`
+}
+
+// FindHeadings returns the headings in rendered text in reading order
+func FindHeadings(text string) []Heading {
+	var headings []Heading
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range headingRegionRegex.FindAllStringSubmatch(line, -1) {
+			title, err := hex.DecodeString(m[2])
+			if err != nil {
+				continue
+			}
+			level, _ := strconv.Atoi(m[1])
+			headings = append(headings, Heading{Level: level, Title: string(title), Line: i})
+		}
+	}
+	return headings
+}
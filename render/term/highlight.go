@@ -0,0 +1,779 @@
+package term
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wildreason/reader/theme"
+)
+
+// CodeColors defines the color scheme for highlighted code blocks. Colors
+// are tview color names or #rrggbb values; an empty color leaves the text in
+// the terminal's default color.
+type CodeColors = theme.CodeColors
+
+// DefaultCodeColors returns the color scheme for dark terminals
+func DefaultCodeColors() CodeColors {
+	return theme.DarkCode()
+}
+
+// LightCodeColors returns the color scheme for light terminals
+func LightCodeColors() CodeColors {
+	return theme.LightCode()
+}
+
+// PlainCodeColors returns a scheme without highlighting: code in one muted
+// gray, with emphasized lines still marked
+func PlainCodeColors() CodeColors {
+	return theme.PlainCode()
+}
+
+// CodeColorsByName returns a built-in color scheme: "dark" (the default),
+// "light" or "plain"
+func CodeColorsByName(name string) (CodeColors, bool) {
+	switch strings.ToLower(name) {
+	case "dark", "default":
+		return DefaultCodeColors(), true
+	case "light":
+		return LightCodeColors(), true
+	case "plain", "none", "mono":
+		return PlainCodeColors(), true
+	}
+	return CodeColors{}, false
+}
+
+// SetCodeColors sets the color scheme for code blocks in the current theme
+func SetCodeColors(c CodeColors) {
+	t := theme.Current()
+	t.Code = c
+	theme.Set(t)
+}
+
+// tokenKind classifies a run of highlighted code
+type tokenKind int
+
+const (
+	tokText tokenKind = iota
+	tokKeyword
+	tokType
+	tokFunction
+	tokString
+	tokNumber
+	tokConstant
+	tokComment
+	tokOperator
+	tokKey
+	tokVariable
+	tokInserted
+	tokDeleted
+	tokMeta
+)
+
+// codeColor returns the scheme's color for a token kind
+func codeColor(c CodeColors, kind tokenKind) string {
+	switch kind {
+	case tokKeyword:
+		return c.Keyword
+	case tokType:
+		return c.Type
+	case tokFunction:
+		return c.Function
+	case tokString:
+		return c.String
+	case tokNumber:
+		return c.Number
+	case tokConstant:
+		return c.Constant
+	case tokComment:
+		return c.Comment
+	case tokOperator:
+		return c.Operator
+	case tokKey:
+		return c.Key
+	case tokVariable:
+		return c.Variable
+	case tokInserted:
+		return c.Inserted
+	case tokDeleted:
+		return c.Deleted
+	case tokMeta:
+		return c.Meta
+	}
+	return c.Text
+}
+
+// codeToken is a run of code of one kind
+type codeToken struct {
+	kind tokenKind
+	text string
+}
+
+// tokenWriter collects tokens, merging neighbors of the same kind
+type tokenWriter []codeToken
+
+func (w *tokenWriter) emit(kind tokenKind, text string) {
+	if text == "" {
+		return
+	}
+	if n := len(*w); n > 0 && (*w)[n-1].kind == kind {
+		(*w)[n-1].text += text
+		return
+	}
+	*w = append(*w, codeToken{kind, text})
+}
+
+// codeLanguage describes a language for the generic tokenizer
+type codeLanguage struct {
+	keywords     map[string]bool
+	types        map[string]bool
+	constants    map[string]bool
+	builtins     map[string]bool
+	lineComments []string
+	blockComment [2]string
+	quotes       string // characters that open strings
+	multiline    string // quotes whose strings may span lines
+	prefixes     map[string]bool
+	tripleQuotes bool // Python """ and ''' strings
+	foldCase     bool // SQL: keywords in any case
+	capitalTypes bool // Capitalized identifiers are types
+	decorators   bool // @name
+	shell        bool // $variables; # comments start words
+	rustChars    bool // ' opens char literals but not lifetimes
+	jsonKeys     bool // strings followed by ':' are keys
+	identExtra   string
+}
+
+// words builds a set from a space-separated list
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	goLanguage = &codeLanguage{
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+		types:        words("any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr"),
+		constants:    words("true false nil iota"),
+		builtins:     words("append cap clear close complex copy delete imag len make max min new panic print println real recover"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+	}
+	pythonLanguage = &codeLanguage{
+		keywords:     words("and as assert async await break case class continue def del elif else except finally for from global if import in is lambda match nonlocal not or pass raise return try while with yield"),
+		types:        words("bool bytes dict float frozenset int list object set str tuple type"),
+		constants:    words("True False None"),
+		builtins:     words("abs all any enumerate filter getattr hasattr isinstance len map max min open print range repr reversed round setattr sorted sum super zip self cls"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		prefixes:     words("r u b f br rb fr rf R U B F BR RB FR RF Br bR Rb rB Fr fR Rf rF"),
+		tripleQuotes: true,
+		decorators:   true,
+	}
+	jsKeywords = "async await break case catch class const continue debugger default delete do else export extends finally for from function get if import in instanceof let new of return set static super switch this throw try typeof var void while with yield"
+	jsLanguage = &codeLanguage{
+		keywords:     words(jsKeywords),
+		constants:    words("true false null undefined NaN Infinity"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		capitalTypes: true,
+		decorators:   true,
+		identExtra:   "$",
+	}
+	tsLanguage = &codeLanguage{
+		keywords:     words(jsKeywords + " abstract as declare enum implements infer interface is keyof namespace private protected public readonly satisfies type"),
+		types:        words("any bigint boolean never number object string symbol unknown void"),
+		constants:    jsLanguage.constants,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		capitalTypes: true,
+		decorators:   true,
+		identExtra:   "$",
+	}
+	rustLanguage = &codeLanguage{
+		keywords:     words("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self static struct super trait type unsafe use where while"),
+		types:        words("bool char f32 f64 i8 i16 i32 i64 i128 isize str u8 u16 u32 u64 u128 usize"),
+		constants:    words("true false"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		multiline:    "\"",
+		prefixes:     words("b r br"),
+		capitalTypes: true,
+		rustChars:    true,
+	}
+	shellLanguage = &codeLanguage{
+		keywords:     words("if then else elif fi case esac for select while until do done in function time return exit break continue"),
+		builtins:     words("alias cd echo eval exec export local printf read readonly set shift source test trap unset wait"),
+		constants:    words("true false"),
+		lineComments: []string{"#"},
+		quotes:       "\"'`",
+		multiline:    "\"'`",
+		shell:        true,
+	}
+	sqlLanguage = &codeLanguage{
+		keywords:     words("add all alter and any as asc begin between by case check column commit constraint create cross database default delete desc distinct drop else end exists explain foreign from full group having if in index inner insert intersect into is join key left like limit merge not offset on or order outer over partition primary references returning right rollback select set table then transaction trigger truncate union unique update using values view when where window with"),
+		types:        words("bigint binary bit blob boolean char date datetime decimal double float int integer interval json jsonb numeric real serial smallint text time timestamp timestamptz uuid varchar"),
+		constants:    words("null true false"),
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+		multiline:    "'",
+		foldCase:     true,
+	}
+	jsonLanguage = &codeLanguage{
+		constants:    words("true false null"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"",
+		jsonKeys:     true,
+	}
+)
+
+// codeLanguages maps fence languages to tokenizers
+var codeLanguages = map[string]*codeLanguage{
+	"go": goLanguage, "golang": goLanguage,
+	"python": pythonLanguage, "py": pythonLanguage, "python3": pythonLanguage,
+	"javascript": jsLanguage, "js": jsLanguage, "jsx": jsLanguage, "mjs": jsLanguage, "cjs": jsLanguage,
+	"typescript": tsLanguage, "ts": tsLanguage, "tsx": tsLanguage,
+	"rust": rustLanguage, "rs": rustLanguage,
+	"sh": shellLanguage, "bash": shellLanguage, "zsh": shellLanguage, "shell": shellLanguage, "console": shellLanguage,
+	"sql":  sqlLanguage,
+	"json": jsonLanguage, "jsonc": jsonLanguage, "json5": jsonLanguage,
+}
+
+// highlightCode tokenizes code in language, returning the tokens of each
+// line. Unknown languages come back as plain text.
+func highlightCode(lines []string, language string) [][]codeToken {
+	src := strings.Join(lines, "\n")
+	var toks []codeToken
+	switch lang := strings.ToLower(language); lang {
+	case "diff", "patch", "udiff":
+		toks = lexDiff(src)
+	case "yaml", "yml":
+		toks = lexYAML(src)
+	default:
+		if l, ok := codeLanguages[lang]; ok {
+			toks = l.lex(src)
+		} else {
+			toks = []codeToken{{tokText, src}}
+		}
+	}
+
+	// Split tokens at line breaks
+	result := make([][]codeToken, 1, len(lines))
+	for _, tok := range toks {
+		parts := strings.Split(tok.text, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				result = append(result, nil)
+			}
+			if part != "" {
+				result[len(result)-1] = append(result[len(result)-1], codeToken{tok.kind, part})
+			}
+		}
+	}
+	return result
+}
+
+// lex tokenizes src with the language's rules
+func (l *codeLanguage) lex(src string) []codeToken {
+	var w tokenWriter
+	for i := 0; i < len(src); {
+		c := src[i]
+		rest := src[i:]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			w.emit(tokText, src[i:i+1])
+			i++
+
+		case l.lineCommentAt(src, i):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			w.emit(tokComment, rest[:end])
+			i += end
+
+		case l.blockComment[0] != "" && strings.HasPrefix(rest, l.blockComment[0]):
+			end := strings.Index(rest[len(l.blockComment[0]):], l.blockComment[1])
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += len(l.blockComment[0]) + len(l.blockComment[1])
+			}
+			w.emit(tokComment, rest[:end])
+			i += end
+
+		case l.stringAt(src, i):
+			end := l.scanString(src, i)
+			kind := tokString
+			if l.jsonKeys && strings.HasPrefix(strings.TrimLeft(src[end:], " \t"), ":") {
+				kind = tokKey
+			}
+			w.emit(kind, src[i:end])
+			i = end
+
+		case l.shell && c == '$':
+			end := scanShellVariable(src, i)
+			w.emit(tokVariable, src[i:end])
+			i = end
+
+		case unicode.IsDigit(rune(c)) || c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1])):
+			end := scanNumber(src, i)
+			w.emit(tokNumber, src[i:end])
+			i = end
+
+		case l.decorators && c == '@' && i+1 < len(src) && isIdentStart(src[i+1]):
+			end := l.scanIdent(src, i+1)
+			for end < len(src) && src[end] == '.' && end+1 < len(src) && isIdentStart(src[end+1]) {
+				end = l.scanIdent(src, end+1)
+			}
+			w.emit(tokFunction, src[i:end])
+			i = end
+
+		case isIdentStart(c) || strings.IndexByte(l.identExtra, c) >= 0:
+			end := l.scanIdent(src, i)
+			word := src[i:end]
+			if l.prefixes[word] && end < len(src) && strings.IndexByte(l.quotes, src[end]) >= 0 {
+				// A string prefix like r"..." or f"..."
+				strEnd := l.scanString(src, end)
+				w.emit(tokString, src[i:strEnd])
+				i = strEnd
+				continue
+			}
+			w.emit(l.classify(word, src, end), word)
+			i = end
+
+		case strings.IndexByte("+-*/%=<>!&|^~?:", c) >= 0:
+			w.emit(tokOperator, src[i:i+1])
+			i++
+
+		default:
+			_, size := utf8.DecodeRuneInString(rest)
+			w.emit(tokText, rest[:size])
+			i += size
+		}
+	}
+	return w
+}
+
+// lineCommentAt reports whether a line comment starts at src[i]. In shells,
+// # only starts a comment at the start of a word.
+func (l *codeLanguage) lineCommentAt(src string, i int) bool {
+	for _, prefix := range l.lineComments {
+		if !strings.HasPrefix(src[i:], prefix) {
+			continue
+		}
+		if l.shell && i > 0 && !strings.ContainsRune(" \t\n;|&(", rune(src[i-1])) {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// stringAt reports whether a string literal starts at src[i]
+func (l *codeLanguage) stringAt(src string, i int) bool {
+	c := src[i]
+	if strings.IndexByte(l.quotes, c) < 0 {
+		return false
+	}
+	if l.rustChars && c == '\'' {
+		// 'a' and '\n' are chars; 'a alone is a lifetime
+		r, size := utf8.DecodeRuneInString(src[i+min(1, len(src)-i):])
+		if r == '\\' {
+			return true
+		}
+		return size > 0 && i+1+size < len(src) && src[i+1+size] == '\''
+	}
+	return true
+}
+
+// scanString returns the end of the string literal starting at src[i]
+func (l *codeLanguage) scanString(src string, i int) int {
+	quote := src[i]
+	if l.tripleQuotes {
+		triple := strings.Repeat(string(quote), 3)
+		if strings.HasPrefix(src[i:], triple) {
+			if end := strings.Index(src[i+3:], triple); end >= 0 {
+				return i + 3 + end + 3
+			}
+			return len(src)
+		}
+	}
+	multiline := strings.IndexByte(l.multiline, quote) >= 0
+	// Backslashes escape in most strings, but not Go raw strings or shell
+	// single quotes
+	escapes := quote != '`' && !(l.shell && quote == '\'')
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			if escapes {
+				j++
+			}
+		case '\n':
+			if !multiline {
+				return j
+			}
+		case quote:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// scanIdent returns the end of the identifier starting at src[i]
+func (l *codeLanguage) scanIdent(src string, i int) int {
+	j := i
+	for j < len(src) && (isIdentStart(src[j]) || unicode.IsDigit(rune(src[j])) || strings.IndexByte(l.identExtra, src[j]) >= 0 ||
+		l.shell && src[j] == '-' && j > i) {
+		j++
+	}
+	return j
+}
+
+// classify returns the kind of an identifier followed by src[end:]
+func (l *codeLanguage) classify(word string, src string, end int) tokenKind {
+	key := word
+	if l.foldCase {
+		key = strings.ToLower(word)
+	}
+	switch {
+	case l.constants[key]:
+		return tokConstant
+	case l.keywords[key]:
+		return tokKeyword
+	case l.types[key]:
+		return tokType
+	case l.builtins[key]:
+		return tokFunction
+	}
+	next := strings.TrimLeft(src[end:], " \t")
+	switch {
+	case strings.HasPrefix(next, "("):
+		return tokFunction
+	case l.rustChars && strings.HasPrefix(src[end:], "!") && !strings.HasPrefix(src[end:], "!="):
+		return tokFunction // macro
+	case l.capitalTypes && word[0] >= 'A' && word[0] <= 'Z':
+		return tokType
+	}
+	return tokText
+}
+
+// scanNumber returns the end of the number starting at src[i]: digits,
+// prefixes and suffixes like 0x1F or 10u32, one decimal point and an exponent
+func scanNumber(src string, i int) int {
+	j := i
+	dot := false
+	for j < len(src) {
+		c := src[j]
+		switch {
+		case unicode.IsDigit(rune(c)) || isIdentStart(c):
+			if (c == 'e' || c == 'E') && j+1 < len(src) && (src[j+1] == '+' || src[j+1] == '-') && !strings.HasPrefix(src[i:], "0x") {
+				j++
+			}
+		case c == '.' && !dot && j+1 < len(src) && unicode.IsDigit(rune(src[j+1])):
+			dot = true
+		default:
+			return j
+		}
+		j++
+	}
+	return j
+}
+
+// scanShellVariable returns the end of the $variable at src[i]: $name,
+// ${...}, or a special parameter like $1 or $?
+func scanShellVariable(src string, i int) int {
+	j := i + 1
+	if j >= len(src) {
+		return j
+	}
+	switch c := src[j]; {
+	case c == '{':
+		if end := strings.IndexByte(src[j:], '}'); end >= 0 {
+			return j + end + 1
+		}
+		return len(src)
+	case isIdentStart(c):
+		for j < len(src) && (isIdentStart(src[j]) || unicode.IsDigit(rune(src[j]))) {
+			j++
+		}
+		return j
+	case unicode.IsDigit(rune(c)) || strings.IndexByte("?@*#$!-", c) >= 0:
+		return j + 1
+	}
+	return j
+}
+
+// lexDiff colors unified diff lines
+func lexDiff(src string) []codeToken {
+	var w tokenWriter
+	for i, line := range strings.Split(src, "\n") {
+		if i > 0 {
+			w.emit(tokText, "\n")
+		}
+		kind := tokText
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "diff "),
+			strings.HasPrefix(line, "index "), strings.HasPrefix(line, "@@"):
+			kind = tokMeta
+		case strings.HasPrefix(line, "+"):
+			kind = tokInserted
+		case strings.HasPrefix(line, "-"):
+			kind = tokDeleted
+		case strings.HasPrefix(line, `\`):
+			kind = tokComment
+		}
+		w.emit(kind, line)
+	}
+	return w
+}
+
+// lexYAML colors YAML line by line: keys, scalars, comments, list markers
+// and block scalars
+func lexYAML(src string) []codeToken {
+	var w tokenWriter
+	blockIndent := -1 // indentation of the key owning a | or > block scalar
+	for i, line := range strings.Split(src, "\n") {
+		if i > 0 {
+			w.emit(tokText, "\n")
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		text := line[indent:]
+		if blockIndent >= 0 {
+			if text == "" || indent > blockIndent {
+				w.emit(tokString, line)
+				continue
+			}
+			blockIndent = -1
+		}
+		w.emit(tokText, line[:indent])
+
+		switch {
+		case strings.HasPrefix(text, "#"):
+			w.emit(tokComment, text)
+			continue
+		case text == "---" || text == "...":
+			w.emit(tokMeta, text)
+			continue
+		}
+		for strings.HasPrefix(text, "- ") || text == "-" {
+			w.emit(tokOperator, "-")
+			rest := strings.TrimLeft(text[1:], " ")
+			w.emit(tokText, text[1:len(text)-len(rest)])
+			indent += len(text) - len(rest)
+			text = rest
+		}
+		if key, rest, ok := splitYAMLKeyToken(text); ok {
+			w.emit(tokKey, key)
+			w.emit(tokOperator, ":")
+			text = rest
+			if header := strings.TrimSpace(text); strings.HasPrefix(header, "|") || strings.HasPrefix(header, ">") {
+				blockIndent = indent
+			}
+		}
+		lexYAMLValue(&w, text)
+	}
+	return w
+}
+
+// splitYAMLKeyToken splits "key: rest" at the colon
+func splitYAMLKeyToken(text string) (key, rest string, ok bool) {
+	if text == "" || strings.ContainsRune("[{#|>'\"", rune(text[0])) {
+		if text != "" && (text[0] == '"' || text[0] == '\'') {
+			if end := strings.IndexByte(text[1:], text[0]); end >= 0 && strings.HasPrefix(text[end+2:], ":") {
+				return text[:end+2], text[end+3:], true
+			}
+		}
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return text[:i], text[i+1:], true
+		}
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+	}
+	return "", "", false
+}
+
+// lexYAMLValue colors the scalar or flow collection after a key
+func lexYAMLValue(w *tokenWriter, text string) {
+	body := strings.TrimLeft(text, " ")
+	w.emit(tokText, text[:len(text)-len(body)])
+	comment := ""
+	if i := strings.Index(" "+body, " #"); i >= 0 && !strings.ContainsAny(body[:i], "\"'") {
+		body, comment = body[:i], body[i:]
+	}
+	trimmed := strings.TrimRight(body, " ")
+	space := body[len(trimmed):]
+	switch {
+	case trimmed == "":
+	case trimmed[0] == '"' || trimmed[0] == '\'':
+		w.emit(tokString, trimmed)
+	case trimmed[0] == '&' || trimmed[0] == '*':
+		w.emit(tokVariable, trimmed)
+	case trimmed[0] == '|' || trimmed[0] == '>':
+		w.emit(tokOperator, trimmed)
+	case trimmed[0] == '[' || trimmed[0] == '{':
+		// Flow collections: reuse the JSON tokenizer for quoted strings,
+		// then color plain entries
+		for _, tok := range jsonLanguage.lex(trimmed) {
+			if tok.kind == tokText {
+				for _, part := range splitKeep(tok.text, "[]{},: ") {
+					if strings.ContainsAny(part, "[]{},: ") {
+						w.emit(tokText, part)
+					} else {
+						w.emit(yamlScalarKind(part), part)
+					}
+				}
+				continue
+			}
+			w.emit(tok.kind, tok.text)
+		}
+	default:
+		w.emit(yamlScalarKind(trimmed), trimmed)
+	}
+	w.emit(tokText, space)
+	w.emit(tokComment, comment)
+}
+
+// yamlScalarKind classifies a plain YAML scalar
+func yamlScalarKind(s string) tokenKind {
+	switch s {
+	case "true", "false", "True", "False", "TRUE", "FALSE", "null", "Null", "NULL", "~":
+		return tokConstant
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+		return tokNumber
+	}
+	return tokString
+}
+
+// splitKeep splits s into runs of separator and non-separator characters
+func splitKeep(s string, seps string) []string {
+	var parts []string
+	start := 0
+	for i := 1; i <= len(s); i++ {
+		if i == len(s) || strings.ContainsRune(seps, rune(s[i])) != strings.ContainsRune(seps, rune(s[i-1])) {
+			parts = append(parts, s[start:i])
+			start = i
+		}
+	}
+	return parts
+}
+
+// isIdentStart reports whether c can start an identifier
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// parseFenceInfo returns a code fence's language and the 1-based lines it
+// emphasizes, from attributes like "go {1,3-5}", "go{2}", "{.go 4}" or
+// hl_lines="1 3-5"
+func parseFenceInfo(info string) (language string, emphasized map[int]bool) {
+	info = strings.TrimSpace(info)
+	var attrs []string
+	if open := strings.IndexByte(info, '{'); open >= 0 {
+		if end := strings.IndexByte(info[open:], '}'); end >= 0 {
+			attrs = strings.FieldsFunc(info[open+1:open+end], func(r rune) bool { return r == ',' || r == ' ' })
+			info = info[:open] + " " + info[open+end+1:]
+		}
+	}
+	if i := strings.Index(info, "hl_lines="); i >= 0 {
+		value := strings.TrimLeft(info[i+len("hl_lines="):], "\"'")
+		if end := strings.IndexAny(value, "\"'"); end >= 0 {
+			value = value[:end]
+		}
+		attrs = append(attrs, strings.Fields(value)...)
+		info = info[:i]
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		language = fields[0]
+	}
+
+	for _, attr := range attrs {
+		if strings.HasPrefix(attr, ".") {
+			if language == "" {
+				language = attr[1:]
+			}
+			continue
+		}
+		from, to, isRange := strings.Cut(attr, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil || first < 1 {
+			continue
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil || last < first {
+				continue
+			}
+		}
+		if emphasized == nil {
+			emphasized = make(map[int]bool)
+		}
+		for n := first; n <= last && n-first < 10000; n++ {
+			emphasized[n] = true
+		}
+	}
+	return language, emphasized
+}
+
+// renderTokens writes tokens with color tags, truncated to width runes. A
+// line's trailing text keeps its color to the end.
+func renderTokens(toks []codeToken, width int) (string, int) {
+	var sb strings.Builder
+	cur := ""
+	used := 0
+	colors := theme.Current().Code
+	for _, tok := range toks {
+		if used >= width {
+			break
+		}
+		text := tok.text
+		if n := utf8.RuneCountInString(text); used+n > width {
+			text = truncateRunes(text, width-used)
+		}
+		tag := "[-]"
+		if c := codeColor(colors, tok.kind); c != "" {
+			tag = "[" + c + "]"
+		}
+		if tag != cur {
+			sb.WriteString(tag)
+			cur = tag
+		}
+		// Repeat the color after each [ so code like x[i] or []int can't
+		// read as a tag
+		sb.WriteString(strings.ReplaceAll(text, "[", "["+tag))
+		used += utf8.RuneCountInString(text)
+	}
+	if cur != "" && cur != "[-]" {
+		sb.WriteString("[-]")
+	}
+	return sb.String(), used
+}
+
+// truncateRunes cuts s to n runes
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package term
+
+import (
+	"strings"
+	"testing"
+)
+
+// kindOf returns the kind of the first token in line whose text is text
+func kindOf(line []codeToken, text string) (tokenKind, bool) {
+	for _, tok := range line {
+		if tok.text == text {
+			return tok.kind, true
+		}
+	}
+	return 0, false
+}
+
+func TestHighlightCodeTokens(t *testing.T) {
+	tests := []struct {
+		language string
+		code     string
+		line     int
+		text     string
+		want     tokenKind
+	}{
+		{"go", "func main() {}", 0, "func", tokKeyword},
+		{"go", "func main() {}", 0, "main", tokFunction},
+		{"go", "var s string = `a\nb`", 1, "b`", tokString},
+		{"go", "x := 0x1F // hex", 0, "// hex", tokComment},
+		{"golang", "return nil", 0, "nil", tokConstant},
+		{"py", "def f():\n    \"\"\"doc\n    more\"\"\"", 2, "    more\"\"\"", tokString},
+		{"python", "@cache\ndef f(): pass", 0, "@cache", tokFunction},
+		{"python", "x = f'{y}'", 0, "f'{y}'", tokString},
+		{"ts", "let n: number = 1.5", 0, "number", tokType},
+		{"js", "new Map()", 0, "Map", tokFunction},
+		{"rust", "fn f<'a>(s: &'a str) -> Option<char>", 0, "Option", tokType},
+		{"rust", "println!(\"{}\", 'x')", 0, "'x'", tokString},
+		{"rust", "println!(\"{}\", 'x')", 0, "println", tokFunction},
+		{"bash", "echo \"$HOME\" $PATH # done", 0, "$PATH", tokVariable},
+		{"bash", "echo \"$HOME\" $PATH # done", 0, "# done", tokComment},
+		{"sh", "ls foo#bar", 0, "ls foo#bar", tokText},
+		{"json", `{"a": "b", "c": true}`, 0, `"a"`, tokKey},
+		{"json", `{"a": "b", "c": true}`, 0, `"b"`, tokString},
+		{"json", `{"a": "b", "c": true}`, 0, "true", tokConstant},
+		{"yaml", "name: demo # c", 0, "name", tokKey},
+		{"yaml", "name: demo # c", 0, "# c", tokComment},
+		{"yml", "- n: 1.5", 0, "1.5", tokNumber},
+		{"yaml", "desc: |\n  text: not a key\nnext: ~", 1, "  text: not a key", tokString},
+		{"yaml", "desc: |\n  text: not a key\nnext: ~", 2, "~", tokConstant},
+		{"sql", "select * from t where x is null", 0, "from", tokKeyword},
+		{"sql", "SELECT count(*) FROM t -- c", 0, "count", tokFunction},
+		{"sql", "SELECT 'it''s'", 0, "'it''s'", tokString},
+		{"diff", "--- a/x\n-old\n+new", 1, "-old", tokDeleted},
+		{"diff", "--- a/x\n-old\n+new", 2, "+new", tokInserted},
+		{"patch", "@@ -1 +1 @@", 0, "@@ -1 +1 @@", tokMeta},
+	}
+	for _, tt := range tests {
+		lines := highlightCode(strings.Split(tt.code, "\n"), tt.language)
+		if len(lines) != strings.Count(tt.code, "\n")+1 {
+			t.Errorf("%s %q: expected one token line per code line, got %d", tt.language, tt.code, len(lines))
+			continue
+		}
+		got, ok := kindOf(lines[tt.line], tt.text)
+		if !ok {
+			t.Errorf("%s %q: no token %q in line %d: %v", tt.language, tt.code, tt.text, tt.line, lines[tt.line])
+		} else if got != tt.want {
+			t.Errorf("%s %q: token %q has kind %d, want %d", tt.language, tt.code, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightCodeKeepsText(t *testing.T) {
+	code := []string{"x := []int{1}", "m[red] = `a", "b` // [::b]", "", "ünïcode"}
+	for _, language := range []string{"go", "python", "ts", "rust", "bash", "json", "yaml", "sql", "diff", "unknown"} {
+		var got []string
+		for _, line := range highlightCode(code, language) {
+			text, _ := renderTokens(line, 80)
+			got = append(got, StripTviewTags(text))
+		}
+		if strings.Join(got, "\n") != strings.Join(code, "\n") {
+			t.Errorf("%s: expected highlighted text to strip back to the code, got %q", language, got)
+		}
+	}
+}
+
+func TestParseFenceInfo(t *testing.T) {
+	tests := []struct {
+		info     string
+		language string
+		lines    []int
+	}{
+		{"go", "go", nil},
+		{"go {1,3-5}", "go", []int{1, 3, 4, 5}},
+		{"python{2}", "python", []int{2}},
+		{"{.rust 4}", "rust", []int{4}},
+		{`py hl_lines="1 3-4"`, "py", []int{1, 3, 4}},
+		{"sh {x,5-2}", "sh", nil},
+	}
+	for _, tt := range tests {
+		language, emphasized := parseFenceInfo(tt.info)
+		if language != tt.language || len(emphasized) != len(tt.lines) {
+			t.Errorf("%q: expected %q %v, got %q %v", tt.info, tt.language, tt.lines, language, emphasized)
+			continue
+		}
+		for _, n := range tt.lines {
+			if !emphasized[n] {
+				t.Errorf("%q: expected line %d emphasized, got %v", tt.info, n, emphasized)
+			}
+		}
+	}
+}
+
+func TestFormatMarkdownCodeBlockHighlighting(t *testing.T) {
+	defer SetCodeColors(DefaultCodeColors())
+	colors := DefaultCodeColors()
+	colors.Keyword = "#010203"
+	SetCodeColors(colors)
+
+	lines := formatMarkdown("```go {2}\nfunc f() {\n\treturn\n}\n```", 40)
+	if len(lines) != 5 {
+		t.Fatalf("Expected boxed code block, got %v", lines)
+	}
+	if got := StripTviewTags(lines[0].text); !strings.HasPrefix(got, "┌ go ─") {
+		t.Errorf("Expected language label without attributes, got %q", got)
+	}
+	if !strings.Contains(lines[1].text, "[#010203]func") {
+		t.Errorf("Expected keyword in the theme's color, got %q", lines[1].text)
+	}
+	if got := StripTviewTags(lines[2].text); !strings.HasPrefix(got, "┃ \treturn") || !strings.Contains(lines[2].text, "[:"+colors.EmphasisBg+"]") {
+		t.Errorf("Expected line 2 emphasized, got %q", lines[2].text)
+	}
+	if got := StripTviewTags(lines[1].text); !strings.HasPrefix(got, "│ func f() {") {
+		t.Errorf("Expected line 1 not emphasized, got %q", got)
+	}
+	if lines[1].sourceLine != 1 || lines[2].sourceLine != 2 {
+		t.Errorf("Expected code lines mapped to source, got %d %d", lines[1].sourceLine, lines[2].sourceLine)
+	}
+}
@@ -0,0 +1,463 @@
+package term
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+
+	"github.com/wildreason/reader/parse/markdown"
+	"github.com/wildreason/reader/theme"
+)
+
+// ImageProtocol is a way of drawing images in a terminal
+type ImageProtocol string
+
+const (
+	ImageKitty   ImageProtocol = "kitty"   // kitty graphics protocol
+	ImageITerm   ImageProtocol = "iterm"   // iTerm2 inline images
+	ImageSixel   ImageProtocol = "sixel"   // DEC sixel graphics
+	ImageBlocks  ImageProtocol = "blocks"  // Truecolor Unicode half blocks
+	ImageBraille ImageProtocol = "braille" // Truecolor Unicode braille dots
+)
+
+// ImageProtocols lists the protocols by name
+var ImageProtocols = []ImageProtocol{ImageKitty, ImageITerm, ImageSixel, ImageBlocks, ImageBraille}
+
+// DecodeImage decodes a PNG, JPEG, GIF, BMP or WebP image, returning the
+// format name. Animated images decode to their first frame.
+func DecodeImage(r io.Reader) (image.Image, string, error) {
+	return image.Decode(r)
+}
+
+// defaultCellSize is the assumed pixel size of a terminal cell when the
+// terminal doesn't report it
+var defaultCellSize = image.Pt(10, 20)
+
+// WriteImage draws img in the terminal with the given protocol, scaled
+// down to fit cols x rows cells. cell is the pixel size of a cell, or zero
+// if unknown. The cursor ends on the line after the image.
+func WriteImage(w io.Writer, img image.Image, protocol ImageProtocol, cols, rows int, cell image.Point) error {
+	if cell.X <= 0 || cell.Y <= 0 {
+		cell = defaultCellSize
+	}
+	bw := bufio.NewWriter(w)
+	switch protocol {
+	case ImageKitty, ImageITerm:
+		size := fitSize(img.Bounds().Size(), image.Pt(cols*cell.X, rows*cell.Y))
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, scaleImage(img, size)); err != nil {
+			return err
+		}
+		width := (size.X + cell.X - 1) / cell.X
+		if protocol == ImageKitty {
+			writeKitty(bw, buf.Bytes(), width)
+		} else {
+			fmt.Fprintf(bw, "\x1b]1337;File=inline=1;size=%d;width=%d;preserveAspectRatio=1:%s\a\n",
+				buf.Len(), width, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		}
+	case ImageSixel:
+		size := fitSize(img.Bounds().Size(), image.Pt(cols*cell.X, rows*cell.Y))
+		writeSixel(bw, scaleImage(img, size))
+		bw.WriteString("\n")
+	default:
+		for _, row := range imageCells(img, protocol, cols, rows) {
+			writeCellsANSI(bw, row)
+		}
+	}
+	return bw.Flush()
+}
+
+// writeKitty sends a PNG with the kitty graphics protocol, in chunks of at
+// most 4096 base64 bytes, displayed cols cells wide
+func writeKitty(w *bufio.Writer, data []byte, cols int) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for first := true; first || encoded != ""; first = false {
+		chunk := encoded
+		if len(chunk) > 4096 {
+			chunk = chunk[:4096]
+		}
+		encoded = encoded[len(chunk):]
+		more := 0
+		if encoded != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(w, "\x1b_Ga=T,f=100,q=2,c=%d,m=%d;%s\x1b\\", cols, more, chunk)
+		} else {
+			fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	w.WriteString("\n")
+}
+
+// writeSixel encodes img as sixels, dithered to the web-safe palette.
+// Transparent pixels are left unpainted.
+func writeSixel(w *bufio.Writer, img *image.NRGBA) {
+	b := img.Bounds()
+	pal := color.Palette(palette.WebSafe)
+	paletted := image.NewPaletted(b, pal)
+	draw.FloydSteinberg.Draw(paletted, b, img, b.Min)
+
+	fmt.Fprintf(w, "\x1bP0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i, c := range pal {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+
+	sixels := make([]byte, b.Dx())
+	for y0 := 0; y0 < b.Dy(); y0 += 6 {
+		// Colors used in this band of six rows
+		used := make(map[uint8]bool)
+		for y := y0; y < y0+6 && y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				if img.NRGBAAt(x, y).A >= 128 {
+					used[paletted.ColorIndexAt(x, y)] = true
+				}
+			}
+		}
+		for i := 0; i < len(pal); i++ {
+			if !used[uint8(i)] {
+				continue
+			}
+			for x := range sixels {
+				var bits byte
+				for k := 0; k < 6 && y0+k < b.Dy(); k++ {
+					if paletted.ColorIndexAt(x, y0+k) == uint8(i) && img.NRGBAAt(x, y0+k).A >= 128 {
+						bits |= 1 << k
+					}
+				}
+				sixels[x] = '?' + bits
+			}
+			fmt.Fprintf(w, "#%d", i)
+			writeSixelRuns(w, sixels)
+			w.WriteByte('$')
+		}
+		w.WriteByte('-')
+	}
+	w.WriteString("\x1b\\")
+}
+
+// writeSixelRuns writes sixel data with runs of four or more compressed
+func writeSixelRuns(w *bufio.Writer, sixels []byte) {
+	for i := 0; i < len(sixels); {
+		j := i
+		for j < len(sixels) && sixels[j] == sixels[i] {
+			j++
+		}
+		if n := j - i; n >= 4 {
+			fmt.Fprintf(w, "!%d%c", n, sixels[i])
+		} else {
+			w.Write(sixels[i:j])
+		}
+		i = j
+	}
+}
+
+// imageCell is one terminal cell of an image drawn with Unicode characters
+type imageCell struct {
+	ch           rune
+	fg, bg       color.NRGBA
+	hasFg, hasBg bool
+}
+
+// imageCells draws img in at most cols x rows cells with half blocks (two
+// pixels per cell) or braille (eight dots per cell)
+func imageCells(img image.Image, protocol ImageProtocol, cols, rows int) [][]imageCell {
+	if protocol == ImageBraille {
+		return brailleCells(img, cols, rows)
+	}
+	size := fitSize(img.Bounds().Size(), image.Pt(cols, rows*2))
+	scaled := scaleImage(img, size)
+	var result [][]imageCell
+	for y := 0; y < size.Y; y += 2 {
+		row := make([]imageCell, size.X)
+		for x := range row {
+			top := scaled.NRGBAAt(x, y)
+			bottom := scaled.NRGBAAt(x, y+1) // Transparent past the last row
+			topOn, bottomOn := top.A >= 128, bottom.A >= 128
+			switch {
+			case topOn && bottomOn:
+				row[x] = imageCell{ch: '▀', fg: top, bg: bottom, hasFg: true, hasBg: true}
+			case topOn:
+				row[x] = imageCell{ch: '▀', fg: top, hasFg: true}
+			case bottomOn:
+				row[x] = imageCell{ch: '▄', fg: bottom, hasFg: true}
+			default:
+				row[x] = imageCell{ch: ' '}
+			}
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+// brailleDots maps a dot's position in a 2x4 braille cell to its bit
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// brailleCells draws img with braille dots: in each cell, pixels brighter
+// than the cell's average are dots in their average color, drawn over the
+// average color of the rest
+func brailleCells(img image.Image, cols, rows int) [][]imageCell {
+	size := fitSize(img.Bounds().Size(), image.Pt(cols*2, rows*4))
+	scaled := scaleImage(img, size)
+	var result [][]imageCell
+	for y0 := 0; y0 < size.Y; y0 += 4 {
+		row := make([]imageCell, (size.X+1)/2)
+		for cx := range row {
+			var pixels []color.NRGBA
+			var bits []rune
+			lum := 0
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					c := scaled.NRGBAAt(cx*2+dx, y0+dy)
+					if c.A < 128 {
+						continue
+					}
+					pixels = append(pixels, c)
+					bits = append(bits, brailleDots[dy][dx])
+					lum += luminance(c)
+				}
+			}
+			if len(pixels) == 0 {
+				row[cx] = imageCell{ch: ' '}
+				continue
+			}
+			mean := lum / len(pixels)
+			var on, off []color.NRGBA
+			ch := rune(0x2800)
+			for i, c := range pixels {
+				if luminance(c) >= mean {
+					ch |= bits[i]
+					on = append(on, c)
+				} else {
+					off = append(off, c)
+				}
+			}
+			cell := imageCell{ch: ch, fg: averageColor(on), hasFg: true}
+			if len(off) > 0 {
+				cell.bg, cell.hasBg = averageColor(off), true
+			}
+			row[cx] = cell
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+// luminance returns a color's approximate brightness, 0-255
+func luminance(c color.NRGBA) int {
+	return (int(c.R)*299 + int(c.G)*587 + int(c.B)*114) / 1000
+}
+
+// averageColor returns the mean of opaque colors
+func averageColor(colors []color.NRGBA) color.NRGBA {
+	var r, g, b int
+	for _, c := range colors {
+		r += int(c.R)
+		g += int(c.G)
+		b += int(c.B)
+	}
+	n := len(colors)
+	return color.NRGBA{uint8(r / n), uint8(g / n), uint8(b / n), 0xff}
+}
+
+// writeCellsANSI writes a row of cells with 24-bit ANSI colors
+func writeCellsANSI(w *bufio.Writer, row []imageCell) {
+	var fg, bg string
+	for _, cell := range row {
+		if f := ansiColor(38, cell.fg, cell.hasFg); f != fg {
+			w.WriteString(f)
+			fg = f
+		}
+		if b := ansiColor(48, cell.bg, cell.hasBg); b != bg {
+			w.WriteString(b)
+			bg = b
+		}
+		w.WriteRune(cell.ch)
+	}
+	w.WriteString("\x1b[0m\n")
+}
+
+// ansiColor returns the escape sequence setting a foreground (38) or
+// background (48) color, or resetting it
+func ansiColor(kind int, c color.NRGBA, set bool) string {
+	if !set {
+		return fmt.Sprintf("\x1b[%dm", kind+1)
+	}
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", kind, c.R, c.G, c.B)
+}
+
+// formatImageCells writes a row of cells with tview color tags
+func formatImageCells(row []imageCell) string {
+	var sb strings.Builder
+	tag := ""
+	for _, cell := range row {
+		fg, bg := "-", "-"
+		if cell.hasFg {
+			fg = fmt.Sprintf("#%02x%02x%02x", cell.fg.R, cell.fg.G, cell.fg.B)
+		}
+		if cell.hasBg {
+			bg = fmt.Sprintf("#%02x%02x%02x", cell.bg.R, cell.bg.G, cell.bg.B)
+		}
+		if t := "[" + fg + ":" + bg + "]"; t != tag {
+			sb.WriteString(t)
+			tag = t
+		}
+		sb.WriteRune(cell.ch)
+	}
+	sb.WriteString("[-:-]")
+	return sb.String()
+}
+
+// fitSize scales size down to fit in box, keeping its aspect ratio. Images
+// that fit are not scaled up.
+func fitSize(size, box image.Point) image.Point {
+	if size.X <= box.X && size.Y <= box.Y {
+		return size
+	}
+	w, h := box.X, size.Y*box.X/size.X
+	if h > box.Y {
+		w, h = size.X*box.Y/size.Y, box.Y
+	}
+	return image.Pt(max(w, 1), max(h, 1))
+}
+
+// scaleImage resizes img to size, averaging a grid of up to 4x4 source
+// pixels for each pixel when shrinking
+func scaleImage(img image.Image, size image.Point) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return dst
+	}
+	for y := 0; y < size.Y; y++ {
+		sy0 := b.Min.Y + y*b.Dy()/size.Y
+		sy1 := max(b.Min.Y+(y+1)*b.Dy()/size.Y, sy0+1)
+		stepY := max((sy1-sy0)/4, 1)
+		for x := 0; x < size.X; x++ {
+			sx0 := b.Min.X + x*b.Dx()/size.X
+			sx1 := max(b.Min.X+(x+1)*b.Dx()/size.X, sx0+1)
+			stepX := max((sx1-sx0)/4, 1)
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy += stepY {
+				for sx := sx0; sx < sx1; sx += stepX {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			// Average premultiplied values, then convert to non-premultiplied
+			c := color.NRGBA64{}
+			if a > 0 {
+				c = color.NRGBA64{uint16(r * 0xffff / a), uint16(g * 0xffff / a), uint16(bl * 0xffff / a), uint16(a / n)}
+			}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
+
+// imageCache keeps the images drawn in markdown, by path
+var (
+	imageCacheMu sync.Mutex
+	imageCache   = make(map[string]image.Image)
+)
+
+// inlineImageRows bounds the height of images drawn in markdown
+const inlineImageRows = 20
+
+// SetInlineImages draws local images that stand alone in a markdown
+// paragraph with half blocks. Relative paths resolve against the base
+// directory given to SetHyperlinks; remote images are never fetched.
+func SetInlineImages(enabled bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.InlineImages = enabled
+}
+
+// renderInlineImage draws a paragraph holding only an image, or returns
+// nil if it isn't one or the image can't be loaded
+func renderInlineImage(n *markdown.Node, width int) []annotatedLine {
+	img := n.FirstChild
+	if img == nil || img != n.LastChild || img.Kind != markdown.Image {
+		return nil
+	}
+	decoded := loadImage(localImagePath(img.Destination))
+	if decoded == nil {
+		return nil
+	}
+	var result []annotatedLine
+	for i, row := range imageCells(decoded, ImageBlocks, width, inlineImageRows) {
+		sl := -1
+		if i == 0 {
+			sl = n.Line - 1
+		}
+		result = append(result, annotatedLine{text: formatImageCells(row), sourceLine: sl})
+	}
+	if alt := markdown.PlainText(img); alt != "" {
+		for _, w := range wrapLine(tview.Escape(alt), width, "") {
+			result = append(result, annotatedLine{text: theme.Tag(theme.Muted) + w + "[-]", sourceLine: -1})
+		}
+	}
+	return result
+}
+
+// localImagePath returns the file an image destination refers to, or ""
+// for remote images
+func localImagePath(dest string) string {
+	if dest == "" || schemeRegex.MatchString(dest) {
+		return ""
+	}
+	path, _, _ := strings.Cut(dest, "?")
+	path, _, _ = strings.Cut(path, "#")
+	if p, err := url.PathUnescape(path); err == nil {
+		path = p
+	}
+	if !filepath.IsAbs(path) {
+		if settings.LinkBase == "" {
+			return ""
+		}
+		path = filepath.Join(settings.LinkBase, path)
+	}
+	return path
+}
+
+// loadImage decodes the image at path, caching the result. It returns nil
+// if the file can't be read or decoded.
+func loadImage(path string) image.Image {
+	if path == "" {
+		return nil
+	}
+	imageCacheMu.Lock()
+	defer imageCacheMu.Unlock()
+	if img, ok := imageCache[path]; ok {
+		return img
+	}
+	var img image.Image
+	if f, err := os.Open(path); err == nil {
+		img, _, _ = DecodeImage(f)
+		f.Close()
+	}
+	imageCache[path] = img
+	return img
+}
@@ -0,0 +1,193 @@
+package term
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+)
+
+// testImage returns a w x h image, red on top and blue below
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{255, 0, 0, 255}
+			if y >= h/2 {
+				c = color.NRGBA{0, 0, 255, 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestDecodeImageFormats(t *testing.T) {
+	for name, encode := range map[string]func(*bytes.Buffer) error{
+		"png": func(b *bytes.Buffer) error { return png.Encode(b, testImage(4, 4)) },
+		"bmp": func(b *bytes.Buffer) error { return bmp.Encode(b, testImage(4, 4)) },
+	} {
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		img, format, err := DecodeImage(&buf)
+		if err != nil || format != name || img.Bounds().Dx() != 4 {
+			t.Errorf("Expected a 4px %s image, got %q, %v", name, format, err)
+		}
+	}
+}
+
+func TestImageCellsHalfBlocks(t *testing.T) {
+	rows := imageCells(testImage(8, 8), ImageBlocks, 4, 10)
+	if len(rows) != 2 || len(rows[0]) != 4 {
+		t.Fatalf("Expected 4x2 cells for an 8px image in 4 columns, got %dx%d", len(rows[0]), len(rows))
+	}
+	top, bottom := rows[0][0], rows[1][0]
+	if top.ch != '▀' || top.fg.R != 255 || top.bg.R != 255 || bottom.fg.B != 255 {
+		t.Errorf("Expected red over blue half blocks, got %+v and %+v", top, bottom)
+	}
+	if got := formatImageCells(rows[0][:2]); got != "[#ff0000:#ff0000]▀▀[-:-]" {
+		t.Errorf("Expected one color tag per run, got %q", got)
+	}
+}
+
+func TestImageCellsTransparent(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 2))
+	img.SetNRGBA(0, 1, color.NRGBA{0, 255, 0, 255})
+	cell := imageCells(img, ImageBlocks, 1, 1)[0][0]
+	if cell.ch != '▄' || cell.hasBg {
+		t.Errorf("Expected a lower half block with no background, got %+v", cell)
+	}
+}
+
+func TestImageCellsBraille(t *testing.T) {
+	rows := imageCells(testImage(4, 8), ImageBraille, 2, 2)
+	if len(rows) != 2 || len(rows[0]) != 2 || rows[0][0].ch != '⣿' {
+		t.Errorf("Expected full braille cells for flat color, got %+v", rows)
+	}
+}
+
+func TestFitSize(t *testing.T) {
+	tests := []struct{ size, box, want image.Point }{
+		{image.Pt(10, 10), image.Pt(80, 40), image.Pt(10, 10)},
+		{image.Pt(200, 100), image.Pt(80, 80), image.Pt(80, 40)},
+		{image.Pt(100, 400), image.Pt(80, 40), image.Pt(10, 40)},
+		{image.Pt(1000, 1), image.Pt(10, 10), image.Pt(10, 1)},
+	}
+	for _, tt := range tests {
+		if got := fitSize(tt.size, tt.box); got != tt.want {
+			t.Errorf("fitSize(%v, %v) = %v, want %v", tt.size, tt.box, got, tt.want)
+		}
+	}
+}
+
+func TestWriteImageProtocols(t *testing.T) {
+	// Noise doesn't compress, so the PNG needs several chunks
+	noise := testImage(200, 200)
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(uint32(i) * 2654435761 >> 24)
+	}
+	var buf bytes.Buffer
+	WriteImage(&buf, noise, ImageKitty, 20, 10, image.Pt(10, 20))
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1b_Ga=T,f=100,q=2,c=20,m=1;") || !strings.Contains(out, "\x1b\\\x1b_Gm=1;") || !strings.Contains(out, "\x1b_Gm=0;") {
+		t.Errorf("Expected a chunked kitty image 20 cells wide, got %.60q", out)
+	}
+
+	buf.Reset()
+	WriteImage(&buf, testImage(12, 12), ImageSixel, 80, 24, image.Point{})
+	out = buf.String()
+	if !strings.HasPrefix(out, "\x1bP0;1;0q\"1;1;12;12") || !strings.Contains(out, "!12~") || !strings.HasSuffix(out, "\x1b\\\n") {
+		t.Errorf("Expected sixel data with run-length runs, got %.80q", out)
+	}
+
+	buf.Reset()
+	WriteImage(&buf, testImage(2, 2), ImageBlocks, 80, 24, image.Point{})
+	if want := "\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀▀\x1b[0m\n"; buf.String() != want {
+		t.Errorf("Expected truecolor half blocks %q, got %q", want, buf.String())
+	}
+}
+
+func TestParseGraphicsReply(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("KITTY_WINDOW_ID", "")
+	tests := []struct {
+		reply string
+		want  TerminalGraphics
+	}{
+		{"\x1b_Gi=31;OK\x1b\\\x1b[6;20;10t\x1b[?62;22c", TerminalGraphics{ImageKitty, image.Pt(10, 20)}},
+		{"\x1bP>|iTerm2 3.5.0\x1b\\\x1b[?62;4c", TerminalGraphics{Protocol: ImageITerm}},
+		{"\x1b[?62;4;22c", TerminalGraphics{Protocol: ImageSixel}},
+		{"\x1b[?62;22;44c", TerminalGraphics{Protocol: ImageBlocks}},
+		{"", TerminalGraphics{Protocol: ImageBlocks}},
+	}
+	for _, tt := range tests {
+		if got := parseGraphicsReply(tt.reply); got != tt.want {
+			t.Errorf("parseGraphicsReply(%q) = %+v, want %+v", tt.reply, got, tt.want)
+		}
+	}
+}
+
+func TestParseBackgroundReply(t *testing.T) {
+	tests := []struct {
+		reply    string
+		dark, ok bool
+	}{
+		{"\x1b]11;rgb:0000/0000/0000\x1b\\\x1b[?62;22c", true, true},
+		{"\x1b]11;rgb:ffff/ffff/ffff\x07\x1b[?62c", false, true},
+		{"\x1b]11;rgb:fd/f6/e3\x1b\\", false, true},
+		{"\x1b]11;rgb:2828/2c2c/3434\x1b\\", true, true},
+		{"\x1b[?62;22c", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		dark, ok := parseBackgroundReply(tt.reply)
+		if dark != tt.dark || ok != tt.ok {
+			t.Errorf("parseBackgroundReply(%q) = %v, %v, want %v, %v", tt.reply, dark, ok, tt.dark, tt.ok)
+		}
+	}
+
+	for value, want := range map[string]bool{"15;0": true, "0;15": false, "15;default;8": true, "0;7": false} {
+		if dark, ok := parseColorFGBG(value); !ok || dark != want {
+			t.Errorf("parseColorFGBG(%q) = %v, %v, want %v", value, dark, ok, want)
+		}
+	}
+	if _, ok := parseColorFGBG("default"); ok {
+		t.Errorf("parseColorFGBG(\"default\") reported a background")
+	}
+}
+
+func TestFormatMarkdownInlineImage(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "pic.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, testImage(4, 4))
+	f.Close()
+
+	SetHyperlinks(false, dir)
+	SetInlineImages(true)
+	defer SetInlineImages(false)
+	defer SetHyperlinks(false, "")
+
+	lines := formatMarkdown("# Title\n\n![A red square](pic.png)\n\n![missing](nope.png)", 80)
+	var got []string
+	for _, l := range lines {
+		got = append(got, StripTviewTags(l.text))
+	}
+	want := []string{"Title", "", "▀▀▀▀", "▀▀▀▀", "A red square", "", "image: missing"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected the image drawn in place, got %q", got)
+	}
+	if lines[2].sourceLine != 2 || lines[3].sourceLine != -1 {
+		t.Errorf("Expected the image to map to its source line, got %d and %d", lines[2].sourceLine, lines[3].sourceLine)
+	}
+}
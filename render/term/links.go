@@ -0,0 +1,179 @@
+package term
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wildreason/reader/parse/markdown"
+	"github.com/wildreason/reader/theme"
+)
+
+// SetHyperlinks makes links clickable OSC 8 hyperlinks, for terminals that
+// support them. Relative links and inline images resolve against baseDir.
+// When disabled, links are numbered and listed at the end of each block.
+func SetHyperlinks(enabled bool, baseDir string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.Hyperlinks = enabled
+	settings.LinkBase = baseDir
+}
+
+// Link is a link in rendered text
+type Link struct {
+	Region string // tview region ID wrapping the link text
+	Target string // URL or path, as written in the document
+	Line   int    // 0-based line the link starts on
+}
+
+// linkRegionRegex matches the region tags that open links
+var linkRegionRegex = regexp.MustCompile(`\["(link-([0-9a-f]*))"\]`)
+
+// FindLinks returns the links in rendered text in reading order. Links to
+// the same target share a region, so each target is listed once.
+func FindLinks(text string) []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range linkRegionRegex.FindAllStringSubmatch(line, -1) {
+			target, err := hex.DecodeString(m[2])
+			if err != nil || seen[m[1]] {
+				continue
+			}
+			seen[m[1]] = true
+			links = append(links, Link{Region: m[1], Target: string(target), Line: i})
+		}
+	}
+	return links
+}
+
+// linkRegion returns the region ID for links to target. The target is
+// hex-encoded since region IDs allow only a few characters.
+func linkRegion(target string) string {
+	return "link-" + hex.EncodeToString([]byte(target))
+}
+
+// linkTarget returns where a link points. Wiki links point to the note's
+// markdown file.
+func linkTarget(n *markdown.Node) string {
+	if n.Kind != markdown.WikiLink {
+		return n.Destination
+	}
+	note, heading, hasHeading := strings.Cut(n.Destination, "#")
+	if note != "" && filepath.Ext(note) == "" {
+		note += ".md"
+	}
+	if hasHeading {
+		return note + "#" + heading
+	}
+	return note
+}
+
+// isAutolink reports whether a link shows its own target, like
+// <https://example.com>, so a reference number would repeat it
+func isAutolink(n *markdown.Node) bool {
+	text := markdown.PlainText(n)
+	return n.Kind == markdown.Link && (text == n.Destination || "mailto:"+text == n.Destination)
+}
+
+// numberLinks numbers the links of a document for the link list, setting
+// each link's Index. Links to the same target share a number; autolinks
+// get none. It returns the numbered targets in order.
+func numberLinks(doc *markdown.Node) []string {
+	var targets []string
+	numbers := make(map[string]int)
+	markdown.Walk(doc, func(n *markdown.Node) bool {
+		if n.Kind != markdown.Link && n.Kind != markdown.WikiLink {
+			return true
+		}
+		if isAutolink(n) {
+			return false
+		}
+		target := linkTarget(n)
+		if numbers[target] == 0 {
+			targets = append(targets, target)
+			numbers[target] = len(targets)
+		}
+		n.Index = numbers[target]
+		return false
+	})
+	return targets
+}
+
+// renderLink renders a link's text inside its region: as an OSC 8
+// hyperlink when enabled, otherwise followed by its reference number
+func renderLink(n *markdown.Node, base inlineStyle) string {
+	style := base
+	style.fg = theme.Color(theme.Link)
+	target := linkTarget(n)
+	text := style.tag() + renderInlines(n, style) + base.tag()
+	if settings.Hyperlinks {
+		if u := hyperlinkURL(target); u != "" {
+			text = "[:::" + u + "]" + text + "[:::-]"
+		}
+	} else if n.Index > 0 {
+		text += theme.Tag(theme.Muted) + "[" + strconv.Itoa(n.Index) + "]" + base.tag()
+	}
+	return `["` + linkRegion(target) + `"]` + text + `[""]`
+}
+
+// schemeRegex matches a URL scheme like https: or mailto:
+var schemeRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// hyperlinkURL returns target as an absolute URL for a hyperlink, resolving
+// relative paths to file: URLs. In-page anchors, and relative paths with no
+// base directory, have none.
+func hyperlinkURL(target string) string {
+	if target == "" || strings.HasPrefix(target, "#") {
+		return ""
+	}
+	if !schemeRegex.MatchString(target) {
+		if settings.LinkBase == "" {
+			return ""
+		}
+		path, fragment, _ := strings.Cut(target, "#")
+		path, _, _ = strings.Cut(path, "?")
+		if p, err := url.PathUnescape(path); err == nil {
+			path = p
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(settings.LinkBase, path)
+		}
+		target = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path), Fragment: fragment}).String()
+	}
+
+	// tview URLs must be ASCII without brackets
+	var sb strings.Builder
+	for i := 0; i < len(target); i++ {
+		c := target[i]
+		if c <= ' ' || c >= 0x7f || c == '[' || c == ']' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// renderLinkList renders the numbered targets listed after a block's text
+func renderLinkList(targets []string, width int) []annotatedLine {
+	numWidth := len(strconv.Itoa(len(targets)))
+	var result []annotatedLine
+	for i, target := range targets {
+		marker := fmt.Sprintf("[%d]", i+1) + strings.Repeat(" ", numWidth-len(strconv.Itoa(i+1))) + " "
+		indent := strings.Repeat(" ", len(marker))
+		for j, w := range wrapLine(target, width-len(indent), "") {
+			if j == 0 {
+				w = marker + w
+			} else {
+				w = indent + w
+			}
+			result = append(result, annotatedLine{text: theme.Tag(theme.Muted) + w + "[-]", sourceLine: -1})
+		}
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package term
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatMarkdownNumbersLinks(t *testing.T) {
+	lines := formatMarkdown("See [docs](https://example.com/docs), [setup](guide.md#setup),\n<https://auto.example.com> and [again](https://example.com/docs).", 80)
+	var got []string
+	for _, l := range lines {
+		got = append(got, StripTviewTags(l.text))
+	}
+	want := []string{
+		"See docs[1], setup[2], https://auto.example.com and again[1].",
+		"",
+		"[1] https://example.com/docs",
+		"[2] guide.md#setup",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected numbered links and a link list, got %q", got)
+	}
+	if lines[2].sourceLine != -1 {
+		t.Errorf("Expected the link list to map to no source line, got %d", lines[2].sourceLine)
+	}
+
+	links := FindLinks(annotatedLinesToString(lines))
+	if len(links) != 3 || links[0].Target != "https://example.com/docs" || links[1].Target != "guide.md#setup" || links[2].Target != "https://auto.example.com" {
+		t.Errorf("Expected one link per target in order, got %+v", links)
+	}
+}
+
+func TestFormatMarkdownWikiLinkTarget(t *testing.T) {
+	lines := formatMarkdown("A [[Project Plan#Goals|plan]].", 80)
+	got := StripTviewTags(annotatedLinesToString(lines))
+	if !strings.Contains(got, "A plan[1].") || !strings.Contains(got, "[1] Project Plan.md#Goals") {
+		t.Errorf("Expected wiki link to point at the note's file, got %q", got)
+	}
+}
+
+func TestFormatMarkdownHyperlinks(t *testing.T) {
+	SetHyperlinks(true, "/docs")
+	defer SetHyperlinks(false, "")
+
+	lines := formatMarkdown("[web](https://example.com/a[1]) [guide](<my guide.md#setup>) [top](#top)", 80)
+	if len(lines) != 1 {
+		t.Fatalf("Expected no link list with hyperlinks, got %v", lines)
+	}
+	text := lines[0].text
+	for _, want := range []string{"[:::https://example.com/a%5B1%5D]", "[:::file:///docs/my%20guide.md#setup]", "[:::-]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in %q", want, text)
+		}
+	}
+	if strings.Count(text, "[:::") != 4 {
+		t.Errorf("Expected no hyperlink for an in-page anchor, got %q", text)
+	}
+	if got := StripTviewTags(text); got != "web guide top" {
+		t.Errorf("Expected plain link text, got %q", got)
+	}
+}
+
+func TestWrapLineCountsLinkNumbers(t *testing.T) {
+	lines := wrapLine("aaaa[1] bbbb[2] cccc[3]", 16, "")
+	if len(lines) != 2 || lines[0] != "aaaa[1] bbbb[2]" {
+		t.Errorf("Expected [N] references to take up width, got %q", lines)
+	}
+}
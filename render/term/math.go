@@ -0,0 +1,500 @@
+package term
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/tview"
+)
+
+// texSymbols maps TeX commands to the Unicode characters they typeset
+var texSymbols = map[string]string{
+	// Greek
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "omicron": "ο", "pi": "π", "varpi": "ϖ",
+	"rho": "ρ", "varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ",
+	"phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+
+	// Letter-like symbols
+	"infty": "∞", "emptyset": "∅", "varnothing": "∅", "nabla": "∇", "partial": "∂", "ell": "ℓ",
+	"hbar": "ℏ", "aleph": "ℵ", "Re": "ℜ", "Im": "ℑ", "wp": "℘", "top": "⊤", "bot": "⊥",
+	"angle": "∠", "triangle": "△", "prime": "′", "degree": "°", "dagger": "†", "ddagger": "‡",
+
+	// Operators
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "·", "ast": "∗", "star": "⋆",
+	"circ": "∘", "bullet": "•", "oplus": "⊕", "ominus": "⊖", "otimes": "⊗", "odot": "⊙",
+	"cup": "∪", "cap": "∩", "vee": "∨", "lor": "∨", "wedge": "∧", "land": "∧", "setminus": "∖",
+	"neg": "¬", "lnot": "¬", "forall": "∀", "exists": "∃", "nexists": "∄",
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	"bigcup": "⋃", "bigcap": "⋂", "bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+
+	// Relations
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "equiv": "≡",
+	"approx": "≈", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫",
+	"subset": "⊂", "supset": "⊃", "subseteq": "⊆", "supseteq": "⊇", "in": "∈", "notin": "∉",
+	"ni": "∋", "mid": "∣", "parallel": "∥", "perp": "⊥", "models": "⊨", "vdash": "⊢",
+	"prec": "≺", "succ": "≻", "doteq": "≐", "coloneqq": "≔", "leqslant": "⩽", "geqslant": "⩾",
+	"therefore": "∴", "because": "∵",
+
+	// Arrows
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "impliedby": "⟸",
+	"iff": "⟺", "mapsto": "↦", "longrightarrow": "⟶", "longleftarrow": "⟵", "longmapsto": "⟼",
+	"uparrow": "↑", "downarrow": "↓", "Uparrow": "⇑", "Downarrow": "⇓", "hookrightarrow": "↪",
+	"rightleftharpoons": "⇌",
+
+	// Dots and delimiters
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "lvert": "|", "rvert": "|", "Vert": "‖", "lVert": "‖", "rVert": "‖", "|": "‖",
+	"lbrace": "{", "rbrace": "}", "{": "{", "}": "}", "backslash": "\\",
+
+	// Escapes and spacing
+	"%": "%", "$": "$", "&": "&", "#": "#", "_": "_",
+	",": " ", ":": " ", ";": " ", ">": " ", " ": " ", "!": "", "quad": "  ", "qquad": "    ",
+}
+
+// texFunctions are set as upright names, like \sin
+var texFunctions = map[string]bool{
+	"arccos": true, "arcsin": true, "arctan": true, "arg": true, "cos": true, "cosh": true,
+	"cot": true, "coth": true, "csc": true, "deg": true, "det": true, "dim": true, "exp": true,
+	"gcd": true, "hom": true, "inf": true, "ker": true, "lg": true, "lim": true, "liminf": true,
+	"limsup": true, "ln": true, "log": true, "max": true, "min": true, "Pr": true, "sec": true,
+	"sin": true, "sinh": true, "sup": true, "tan": true, "tanh": true, "bmod": true, "mod": true,
+}
+
+// texCombining maps accent commands to the combining mark they add
+var texCombining = map[string]rune{
+	"hat": '̂', "widehat": '̂', "tilde": '̃', "widetilde": '̃',
+	"bar": '̄', "overline": '̅', "vec": '⃗', "dot": '̇', "ddot": '̈',
+	"check": '̌', "breve": '̆', "acute": '́', "grave": '̀', "underline": '̲',
+}
+
+// texIgnored are commands that only affect spacing, sizing or numbering
+var texIgnored = map[string]bool{
+	"left": true, "right": true, "middle": true, "big": true, "Big": true, "bigg": true, "Bigg": true,
+	"bigl": true, "bigr": true, "Bigl": true, "Bigr": true, "biggl": true, "biggr": true,
+	"Biggl": true, "Biggr": true, "displaystyle": true, "textstyle": true, "limits": true,
+	"nolimits": true, "nonumber": true, "notag": true, "mathstrut": true,
+}
+
+// texFonts are commands that set their argument in another font; letters
+// keep their plain form except for blackboard bold
+var texFonts = map[string]bool{
+	"mathrm": true, "mathit": true, "mathbf": true, "mathsf": true, "mathtt": true,
+	"mathcal": true, "mathscr": true, "mathfrak": true, "boldsymbol": true, "bm": true,
+	"operatorname": true, "mathop": true, "mathbb": true,
+}
+
+// texDoubleStruck maps letters to their blackboard bold forms
+var texDoubleStruck = map[rune]string{
+	'C': "ℂ", 'H': "ℍ", 'N': "ℕ", 'P': "ℙ", 'Q': "ℚ", 'R': "ℝ", 'Z': "ℤ",
+	'1': "𝟙", 'E': "𝔼", 'F': "𝔽", 'K': "𝕂",
+}
+
+// texFractions are the fractions with a single Unicode character
+var texFractions = map[string]string{
+	"1/2": "½", "1/3": "⅓", "2/3": "⅔", "1/4": "¼", "3/4": "¾", "1/5": "⅕", "2/5": "⅖",
+	"3/5": "⅗", "4/5": "⅘", "1/6": "⅙", "5/6": "⅚", "1/8": "⅛", "3/8": "⅜", "5/8": "⅝", "7/8": "⅞",
+}
+
+var (
+	superscripts = map[rune]rune{
+		'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+		'+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', 'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ',
+		'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ', 'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ',
+		'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ', 't': 'ᵗ', 'u': 'ᵘ', 'v': 'ᵛ', 'w': 'ʷ',
+		'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ', 'A': 'ᴬ', 'B': 'ᴮ', 'D': 'ᴰ', 'E': 'ᴱ', 'G': 'ᴳ', 'H': 'ᴴ',
+		'I': 'ᴵ', 'J': 'ᴶ', 'K': 'ᴷ', 'L': 'ᴸ', 'M': 'ᴹ', 'N': 'ᴺ', 'O': 'ᴼ', 'P': 'ᴾ', 'R': 'ᴿ',
+		'T': 'ᵀ', 'U': 'ᵁ', 'V': 'ⱽ', 'W': 'ᵂ', 'α': 'ᵅ', 'β': 'ᵝ', 'γ': 'ᵞ', 'δ': 'ᵟ', 'θ': 'ᶿ',
+		'φ': 'ᵠ', 'χ': 'ᵡ', '∗': '*', '*': '*', '′': '′', '∘': '°', ' ': ' ',
+	}
+	subscripts = map[rune]rune{
+		'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+		'+': '₊', '-': '₋', '=': '₌', '(': '₍', ')': '₎', 'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ',
+		'j': 'ⱼ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ', 'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ',
+		't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ', 'β': 'ᵦ', 'γ': 'ᵧ', 'ρ': 'ᵨ', 'φ': 'ᵩ', 'χ': 'ᵪ',
+		' ': ' ',
+	}
+)
+
+// texToUnicode renders TeX math as readable plain text: symbols become their
+// Unicode characters, scripts use super- and subscript characters where they
+// exist and fractions are written linearly. Unknown commands are kept as
+// written. Rows of display math (\\) become separate lines.
+func texToUnicode(tex string) string {
+	p := &texParser{src: tex, column: " "}
+	var lines []string
+	hang := 0
+	for _, line := range strings.Split(p.sequence(false), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		line = strings.NewReplacer(" "+texGap+" ", "  ", texGap, "  ", " ; ", "; ", "( ", "(", " )", ")").Replace(line)
+		if strings.HasPrefix(line, texHang) {
+			line = strings.Repeat(" ", hang) + line[len(texHang):]
+		} else if i := strings.Index(line, texHang); i >= 0 {
+			hang = tview.TaggedStringWidth(line[:i])
+			line = line[:i] + line[i+len(texHang):]
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// texGap marks the column gap of cases and texHang the column their rows
+// line up at; both survive collapsing spaces
+const (
+	texGap  = "\x00"
+	texHang = "\x01"
+)
+
+// texParser converts TeX to text by recursive descent
+type texParser struct {
+	src    string
+	pos    int
+	env    int    // depth of enclosing environments
+	matrix int    // depth of enclosing matrix environments
+	column string // written for & in the current environment
+}
+
+// sequence renders atoms up to the end of input, or the closing brace of a
+// group, or the \end of an environment
+func (p *texParser) sequence(group bool) string {
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '}':
+			p.pos++
+			if group {
+				return sb.String()
+			}
+		case c == '^' || c == '_':
+			p.pos++
+			sb.WriteString(texScript(p.argument(), c == '^'))
+		case c == '&':
+			p.pos++
+			sb.WriteString(p.column)
+		case strings.HasPrefix(p.src[p.pos:], `\\`):
+			p.pos += 2
+			if p.matrix > 0 {
+				sb.WriteString("; ")
+			} else {
+				sb.WriteString("\n")
+			}
+		case p.env > 0 && strings.HasPrefix(p.src[p.pos:], `\end`):
+			return sb.String()
+		default:
+			sb.WriteString(p.atom())
+		}
+	}
+	return sb.String()
+}
+
+// argument renders the next braced group or single token
+func (p *texParser) argument() string {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	return p.atom()
+}
+
+// rawGroup returns the unparsed text of the next braced group
+func (p *texParser) rawGroup() string {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return ""
+	}
+	depth := 0
+	for i := p.pos; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				s := p.src[p.pos+1 : i]
+				p.pos = i + 1
+				return s
+			}
+		}
+	}
+	s := p.src[p.pos+1:]
+	p.pos = len(p.src)
+	return s
+}
+
+// atom renders one token: a group, a command or a character
+func (p *texParser) atom() string {
+	c := p.src[p.pos]
+	switch c {
+	case '{':
+		p.pos++
+		return p.sequence(true)
+	case '\\':
+		return p.command()
+	case '~':
+		p.pos++
+		return " "
+	case '\'':
+		p.pos++
+		return "′"
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.pos += size
+	return string(r)
+}
+
+// command renders a control sequence and its arguments
+func (p *texParser) command() string {
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.src) && isASCIILetter(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start && p.pos < len(p.src) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "operatorname" && p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+	}
+
+	if s, ok := texSymbols[name]; ok {
+		return s
+	}
+	if texFunctions[name] {
+		// Keep the name apart from a following letter or command
+		if p.pos < len(p.src) && (isASCIILetter(p.src[p.pos]) || p.src[p.pos] == '\\') {
+			return name + " "
+		}
+		return name
+	}
+	if texIgnored[name] {
+		if (name == "left" || name == "right") && strings.HasPrefix(p.src[p.pos:], ".") {
+			p.pos++ // \left. and \right. are invisible
+		}
+		return ""
+	}
+	if mark, ok := texCombining[name]; ok {
+		return combine(p.argument(), mark)
+	}
+	if texFonts[name] {
+		arg := p.argument()
+		if name != "mathbb" {
+			return arg
+		}
+		var sb strings.Builder
+		for _, r := range arg {
+			if s, ok := texDoubleStruck[r]; ok {
+				sb.WriteString(s)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+		return sb.String()
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := p.argument()
+		return texFraction(num, p.argument())
+	case "binom":
+		n := p.argument()
+		return "C(" + n + ", " + p.argument() + ")"
+	case "sqrt":
+		root := "√"
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			end := strings.IndexByte(p.src[p.pos:], ']')
+			if end > 0 {
+				switch index := p.src[p.pos+1 : p.pos+end]; index {
+				case "3":
+					root = "∛"
+				case "4":
+					root = "∜"
+				default:
+					root = texScript(texToUnicode(index), true) + "√"
+				}
+				p.pos += end + 1
+			}
+		}
+		return root + texGroup(p.argument())
+	case "text", "textrm", "textit", "textbf", "textsf", "texttt", "textnormal", "mbox", "hbox":
+		return p.rawGroup()
+	case "not":
+		return combine(p.argument(), '̸')
+	case "pmod":
+		return " (mod " + p.argument() + ")"
+	case "begin":
+		return p.environment(p.rawGroup())
+	case "overset", "stackrel", "underset":
+		p.argument()
+		return p.argument()
+	case "tag", "label":
+		p.rawGroup()
+		return ""
+	case "color":
+		p.rawGroup()
+		return ""
+	case "textcolor":
+		p.rawGroup()
+		return p.argument()
+	}
+	return `\` + name
+}
+
+// environment renders the body of \begin{name} up to its \end: matrices
+// keep their brackets with rows separated by semicolons, other environments
+// put each row on its own line
+func (p *texParser) environment(name string) string {
+	open, close := "", ""
+	switch strings.TrimSuffix(name, "*") {
+	case "pmatrix":
+		open, close = "(", ")"
+	case "bmatrix":
+		open, close = "[", "]"
+	case "Bmatrix":
+		open, close = "{", "}"
+	case "vmatrix":
+		open, close = "|", "|"
+	case "Vmatrix":
+		open, close = "‖", "‖"
+	case "matrix", "smallmatrix":
+	case "array":
+		p.rawGroup()
+	case "cases":
+		open = "{ "
+	}
+	matrix := strings.HasSuffix(strings.TrimSuffix(name, "*"), "matrix")
+	column := p.column
+	switch {
+	case matrix:
+		p.matrix++
+		p.column = " "
+	case open == "{ ":
+		p.column = texGap
+	default:
+		p.column = ""
+	}
+	p.env++
+	body := strings.TrimSpace(p.sequence(false))
+	p.env--
+	p.column = column
+	if matrix {
+		p.matrix--
+	}
+	if strings.HasPrefix(p.src[p.pos:], `\end`) {
+		p.pos += len(`\end`)
+		p.rawGroup()
+	}
+	body = strings.TrimSuffix(strings.TrimSpace(body), ";")
+	if open == "{ " {
+		// Each case on its own line, hanging under the first
+		rows := strings.Split(body, "\n")
+		for i := range rows {
+			rows[i] = strings.TrimSpace(rows[i])
+		}
+		return open + texHang + strings.Join(rows, "\n"+texHang)
+	}
+	return open + body + close
+}
+
+// texScript writes s as a superscript or subscript, with Unicode script
+// characters when all of s has them and with ^ or _ otherwise
+func texScript(s string, super bool) string {
+	s = strings.TrimSpace(s)
+	table, mark := subscripts, "_"
+	if super {
+		table, mark = superscripts, "^"
+	}
+	if s == "" {
+		return ""
+	}
+	if super && strings.Trim(s, "′") == "" {
+		return s
+	}
+	var sb strings.Builder
+	for _, r := range s {
+		sr, ok := table[r]
+		if !ok {
+			if utf8.RuneCountInString(s) == 1 || (strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")) {
+				return mark + s
+			}
+			return mark + "(" + s + ")"
+		}
+		sb.WriteRune(sr)
+	}
+	return sb.String()
+}
+
+// texFraction writes a fraction linearly, as a single character when one
+// exists
+func texFraction(num, den string) string {
+	num, den = strings.TrimSpace(num), strings.TrimSpace(den)
+	if s, ok := texFractions[num+"/"+den]; ok {
+		return s
+	}
+	return texGroup(num) + "/" + texGroup(den)
+}
+
+// texGroup parenthesizes s unless it is a single term, such as a product
+// of letters, roots and parenthesized factors
+func texGroup(s string) string {
+	s = strings.TrimSpace(s)
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth > 0, unicode.IsLetter(r), unicode.IsDigit(r), unicode.Is(unicode.Mn, r),
+			r == '.', r == '√', r == '′', isScriptRune(r):
+		default:
+			return "(" + s + ")"
+		}
+	}
+	return s
+}
+
+// isScriptRune reports whether r is a super- or subscript character
+func isScriptRune(r rune) bool {
+	for _, table := range []map[rune]rune{superscripts, subscripts} {
+		for k, v := range table {
+			if v == r && k != v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// combine adds a combining mark after each character of s
+func combine(s string, mark rune) string {
+	s = strings.TrimSpace(s)
+	var sb strings.Builder
+	for _, r := range s {
+		sb.WriteRune(r)
+		if mark == '̸' || mark == '̅' || mark == '̲' || utf8.RuneCountInString(s) == 1 {
+			sb.WriteRune(mark)
+		}
+	}
+	if utf8.RuneCountInString(s) > 1 && mark != '̸' && mark != '̅' && mark != '̲' {
+		sb.WriteRune(mark)
+	}
+	return sb.String()
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
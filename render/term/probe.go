@@ -0,0 +1,143 @@
+package term
+
+import (
+	"image"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// graphicsQuery asks the terminal whether it supports kitty graphics, for
+// its name and version (XTVERSION) and cell size in pixels, then for its
+// primary device attributes. Every terminal answers the last query, so its
+// reply ends the probe.
+const graphicsQuery = "\x1b_Gi=31,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\" + "\x1b[>0q" + "\x1b[16t" + "\x1b[c"
+
+// probeTimeout bounds how long to wait for the terminal to answer
+const probeTimeout = 500 * time.Millisecond
+
+// backgroundQuery asks the terminal for its background color (OSC 11),
+// then for its primary device attributes to end the probe
+const backgroundQuery = "\x1b]11;?\x1b\\" + "\x1b[c"
+
+var (
+	backgroundRegex  = regexp.MustCompile(`\x1b\]11;rgb:([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})`)
+	deviceAttrsRegex = regexp.MustCompile(`\x1b\[\?([0-9;]*)c`)
+	cellSizeRegex    = regexp.MustCompile(`\x1b\[6;([0-9]+);([0-9]+)t`)
+	xtversionRegex   = regexp.MustCompile(`\x1bP>\|([^\x1b]*)\x1b\\`)
+)
+
+// TerminalGraphics is how the terminal draws images
+type TerminalGraphics struct {
+	Protocol ImageProtocol
+	Cell     image.Point // Pixel size of a cell, zero if unknown
+}
+
+// DetectGraphics probes the terminal for the best image protocol it
+// supports, falling back to the environment when it doesn't answer
+func DetectGraphics() TerminalGraphics {
+	return parseGraphicsReply(queryTerminal(graphicsQuery, deviceAttrsRegex.MatchString))
+}
+
+// ParseImageProtocol returns the image protocol with the given name
+func ParseImageProtocol(name string) (ImageProtocol, bool) {
+	for _, p := range ImageProtocols {
+		if string(p) == name {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// parseGraphicsReply picks an image protocol from the terminal's answers to
+// graphicsQuery
+func parseGraphicsReply(reply string) TerminalGraphics {
+	var g TerminalGraphics
+	if m := cellSizeRegex.FindStringSubmatch(reply); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		w, _ := strconv.Atoi(m[2])
+		g.Cell = image.Pt(w, h)
+	}
+	var name string
+	if m := xtversionRegex.FindStringSubmatch(reply); m != nil {
+		name = m[1]
+	}
+	sixel := false
+	if m := deviceAttrsRegex.FindStringSubmatch(reply); m != nil {
+		for _, attr := range strings.Split(m[1], ";") {
+			sixel = sixel || attr == "4"
+		}
+	}
+
+	switch {
+	case strings.Contains(reply, "\x1b_Gi=31;OK"):
+		g.Protocol = ImageKitty
+	case strings.HasPrefix(name, "iTerm2"), strings.HasPrefix(name, "WezTerm"):
+		g.Protocol = ImageITerm
+	case sixel:
+		g.Protocol = ImageSixel
+	default:
+		g.Protocol = envImageProtocol()
+	}
+	return g
+}
+
+// envImageProtocol guesses the image protocol from environment variables,
+// for terminals that don't answer queries (or multiplexers that swallow
+// them)
+func envImageProtocol() ImageProtocol {
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm":
+		return ImageITerm
+	case "ghostty":
+		return ImageKitty
+	}
+	if os.Getenv("KITTY_WINDOW_ID") != "" {
+		return ImageKitty
+	}
+	return ImageBlocks
+}
+
+// DetectDarkBackground reports whether the terminal's background is dark,
+// asking the terminal or falling back to $COLORFGBG. ok is false when
+// neither tells.
+func DetectDarkBackground() (dark, ok bool) {
+	reply := queryTerminal(backgroundQuery, deviceAttrsRegex.MatchString)
+	if dark, ok := parseBackgroundReply(reply); ok {
+		return dark, true
+	}
+	return parseColorFGBG(os.Getenv("COLORFGBG"))
+}
+
+// parseBackgroundReply reads the background color from the terminal's
+// answer to backgroundQuery, reporting whether it is dark
+func parseBackgroundReply(reply string) (dark, ok bool) {
+	m := backgroundRegex.FindStringSubmatch(reply)
+	if m == nil {
+		return false, false
+	}
+	var rgb [3]float64
+	for i, hex := range m[1:] {
+		v, _ := strconv.ParseUint(hex, 16, 16)
+		rgb[i] = float64(v) / float64(uint64(1)<<(4*len(hex))-1)
+	}
+	luminance := 0.2126*rgb[0] + 0.7152*rgb[1] + 0.0722*rgb[2]
+	return luminance < 0.5, true
+}
+
+// parseColorFGBG reads the background from $COLORFGBG ("fg;bg", sometimes
+// "fg;default;bg"), set by some terminals to ANSI color numbers
+func parseColorFGBG(value string) (dark, ok bool) {
+	parts := strings.Split(value, ";")
+	if len(parts) < 2 {
+		return false, false
+	}
+	bg, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || bg < 0 || bg > 15 {
+		return false, false
+	}
+	// 7 (white) and the bright colors but 8 (dark gray) are light
+	return bg < 7 || bg == 8, true
+}
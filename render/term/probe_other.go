@@ -0,0 +1,8 @@
+//go:build !unix
+
+package term
+
+// queryTerminal can't query the terminal on this platform
+func queryTerminal(query string, done func(reply string) bool) string {
+	return ""
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package term
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	xterm "golang.org/x/term"
+)
+
+// queryTerminal writes query to the controlling terminal in raw mode and
+// returns its reply, once done reports it complete or probeTimeout passes.
+// It returns "" if there is no terminal.
+func queryTerminal(query string, done func(reply string) bool) string {
+	// Opened non-blocking so reads can time out
+	fd, err := syscall.Open("/dev/tty", syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return ""
+	}
+	state, err := xterm.MakeRaw(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return ""
+	}
+	tty := os.NewFile(uintptr(fd), "/dev/tty")
+	defer tty.Close()
+	defer xterm.Restore(fd, state)
+
+	if tty.SetDeadline(time.Now().Add(probeTimeout)) != nil {
+		return ""
+	}
+	if _, err := tty.WriteString(query); err != nil {
+		return ""
+	}
+	var reply []byte
+	buf := make([]byte, 256)
+	for !done(string(reply)) {
+		n, err := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	return string(reply)
+}
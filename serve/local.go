@@ -0,0 +1,74 @@
+package serve
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render/html"
+)
+
+// serveLocalFile serves a file from the document directory at the request
+// path, so relative images and links in markdown resolve: markdown files are
+// rendered as pages and other files sent as they are. The root keeps
+// requests inside the directory; hidden files and directories are not served.
+func serveLocalFile(w http.ResponseWriter, r *http.Request, root *os.Root) {
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if root == nil || name == "" {
+		http.NotFound(w, r)
+		return
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	info, err := root.Stat(name)
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		content, err := fs.ReadFile(root.FS(), name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		page := renderMarkdownFile(strings.TrimSuffix(path.Base(name), path.Ext(name)), string(content))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	default:
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFileFS(w, r, root.FS(), name)
+	}
+}
+
+// renderMarkdownFile renders a markdown file as a page titled by its
+// frontmatter, or by slug
+func renderMarkdownFile(slug string, content string) string {
+	fm, body := parse.ParseFrontmatter(content)
+
+	title := slug
+	if fm.Title != "" {
+		title = fm.Title
+	}
+
+	blocks := []parse.Block{{
+		Name:        title,
+		Content:     body,
+		Pages:       []string{body},
+		TotalPages:  1,
+		ContentType: parse.BlockContentPlain,
+	}}
+	if fm.Format != "" {
+		blocks[0].Data = &fm
+	}
+	return html.RenderHTMLPage(title, blocks, false)
+}
@@ -1,4 +1,4 @@
-package main
+package serve
 
 import (
 	"fmt"
@@ -9,6 +9,10 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/wildreason/reader/parse"
+
+	"github.com/wildreason/reader/render/html"
 )
 
 // formatServerAddr returns the listen address for localhost binding
@@ -58,47 +62,9 @@ func (b *sseBroadcaster) notify() {
 	}
 }
 
-// imageMIME returns the MIME type for an image extension
-func imageMIME(ext string) string {
-	switch strings.ToLower(ext) {
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".gif":
-		return "image/gif"
-	case ".webp":
-		return "image/webp"
-	case ".svg":
-		return "image/svg+xml"
-	case ".bmp":
-		return "image/bmp"
-	case ".ico":
-		return "image/x-icon"
-	default:
-		return "application/octet-stream"
-	}
-}
-
-// videoMIME returns the MIME type for a video extension
-func videoMIME(ext string) string {
-	switch strings.ToLower(ext) {
-	case ".mp4":
-		return "video/mp4"
-	case ".webm":
-		return "video/webm"
-	case ".mov":
-		return "video/quicktime"
-	case ".mkv":
-		return "video/x-matroska"
-	default:
-		return "application/octet-stream"
-	}
-}
-
-// serveHTMLAsync starts an HTTP server on the given port and returns an error
+// FileAsync starts an HTTP server on the given port and returns an error
 // instead of calling os.Exit. The stopCh can be closed to signal shutdown.
-func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan struct{}) error {
+func FileAsync(filePath string, blocks []parse.Block, port int, showLineNums bool, stopCh <-chan struct{}, diags ...parse.Diagnostic) error {
 	var (
 		mu          sync.RWMutex
 		currentHTML string
@@ -108,16 +74,19 @@ func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan str
 
 	// Extract frontmatter title if available (for single-block markdown)
 	if len(blocks) == 1 {
-		fm, body := ParseFrontmatter(blocks[0].Content)
+		fm, body := parse.ParseFrontmatter(blocks[0].Content)
 		if fm.Title != "" {
 			title = fm.Title
 		}
 		blocks[0].Content = body
 		blocks[0].Pages = []string{body}
+		if fm.Format != "" && blocks[0].Data == nil {
+			blocks[0].Data = &fm
+		}
 	}
 
 	// Initial render
-	currentHTML = RenderHTMLPage(title, blocks, showLineNumbers)
+	currentHTML = html.RenderHTMLPage(title, blocks, showLineNums, diags...)
 
 	// File watcher: re-parse + re-render on change, notify SSE clients
 	if filePath != "" && filePath != "stdin" {
@@ -131,11 +100,11 @@ func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan str
 		}()
 
 		singleBlock := len(blocks) == 1
-		ct := BlockContentPlain
+		ct := parse.BlockContentPlain
 		if singleBlock {
 			ct = blocks[0].ContentType
 		}
-		go watchAndRerender(filePath, title, singleBlock, ct, &mu, &currentHTML, broadcaster, watchStop)
+		go watchAndRerender(filePath, title, singleBlock, ct, showLineNums, &mu, &currentHTML, broadcaster, watchStop)
 	}
 
 	mux := http.NewServeMux()
@@ -143,8 +112,8 @@ func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan str
 	// Register asset routes for binary content (images, video)
 	for _, block := range blocks {
 		switch block.ContentType {
-		case BlockContentImage:
-			if imgData, ok := block.Data.(*ImageData); ok && !imgData.Inline {
+		case parse.BlockContentImage:
+			if imgData, ok := block.Data.(*parse.ImageData); ok && !imgData.Inline {
 				assetPath := imgData.Src
 				assetMIME := imgData.MIME
 				mux.HandleFunc("/asset/"+filepath.Base(imgData.Alt), func(w http.ResponseWriter, r *http.Request) {
@@ -153,8 +122,8 @@ func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan str
 					http.ServeFile(w, r, assetPath)
 				})
 			}
-		case BlockContentVideo:
-			if vidData, ok := block.Data.(*VideoData); ok && !vidData.Inline {
+		case parse.BlockContentVideo:
+			if vidData, ok := block.Data.(*parse.VideoData); ok && !vidData.Inline {
 				assetPath := vidData.Src
 				assetMIME := vidData.MIME
 				mux.HandleFunc("/asset/"+filepath.Base(block.Name), func(w http.ResponseWriter, r *http.Request) {
@@ -166,10 +135,24 @@ func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan str
 		}
 	}
 
+	// Files next to the document, for relative images and links
+	var root *os.Root
+	if filePath != "" && filePath != "stdin" {
+		if dir, err := os.OpenRoot(filepath.Dir(filePath)); err == nil {
+			root = dir
+			defer root.Close()
+		}
+	}
+
 	// GET / -- serve rendered HTML
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if root != nil && r.URL.Path == "/"+filepath.Base(filePath) {
+			// A link back to the document itself
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
 		if r.URL.Path != "/" {
-			http.NotFound(w, r)
+			serveLocalFile(w, r, root)
 			return
 		}
 		mu.RLock()
@@ -222,10 +205,10 @@ func serveHTMLAsync(filePath string, blocks []Block, port int, stopCh <-chan str
 	return server.ListenAndServe()
 }
 
-// serveHTML starts an HTTP server serving the rendered file (blocking, exits on error)
-func serveHTML(filePath string, blocks []Block, port int) {
+// File starts an HTTP server serving the rendered file (blocking, exits on error)
+func File(filePath string, blocks []parse.Block, port int, showLineNums bool, diags ...parse.Diagnostic) {
 	stopCh := make(chan struct{})
-	if err := serveHTMLAsync(filePath, blocks, port, stopCh); err != nil {
+	if err := FileAsync(filePath, blocks, port, showLineNums, stopCh, diags...); err != nil {
 		if err == http.ErrServerClosed {
 			return
 		}
@@ -236,14 +219,17 @@ func serveHTML(filePath string, blocks []Block, port int) {
 
 // docEntry holds a cached document for directory mode
 type docEntry struct {
-	slug     string
-	fm       Frontmatter
-	html     string
-	modTime  time.Time
+	slug    string
+	fm      parse.Frontmatter
+	body    string
+	links   []string // notes named by the document's wiki links
+	html    string
+	modTime time.Time
 }
 
-// serveDirectory starts an HTTP server listing all markdown files in dirPath
-func serveDirectory(dirPath string, port int) {
+// Directory starts an HTTP server listing all markdown files in dirPath.
+// columns name extra frontmatter fields to show in the index.
+func Directory(dirPath string, port int, columns ...string) {
 	var (
 		mu          sync.RWMutex
 		cache       = make(map[string]*docEntry) // slug -> entry
@@ -254,7 +240,15 @@ func serveDirectory(dirPath string, port int) {
 
 	// Initial scan
 	scanDirectory(dirPath, cache)
-	indexHTML = renderIndex(dirName, cache)
+	indexHTML = renderIndex(dirName, cache, columns)
+
+	// Files in the directory, for relative images and links
+	root, err := os.OpenRoot(dirPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer root.Close()
 
 	mux := http.NewServeMux()
 
@@ -284,29 +278,30 @@ func serveDirectory(dirPath string, port int) {
 		}
 	})
 
-	// GET / and GET /{slug}
+	// GET / and GET /{slug}; relative links to {slug}.md land on the slug
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		mu.RLock()
 		defer mu.RUnlock()
 
 		path := strings.TrimPrefix(r.URL.Path, "/")
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 		if path == "" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			fmt.Fprint(w, indexHTML)
 			return
 		}
 
-		entry, ok := cache[path]
+		entry, ok := cache[strings.TrimSuffix(path, ".md")]
 		if !ok {
-			http.NotFound(w, r)
+			serveLocalFile(w, r, root)
 			return
 		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		fmt.Fprint(w, entry.html)
 	})
 
 	// Directory watcher
-	go watchDirectory(dirPath, dirName, &mu, cache, &indexHTML, broadcaster)
+	go watchDirectory(dirPath, dirName, columns, &mu, cache, &indexHTML, broadcaster)
 
 	addr := formatServerAddr(port)
 	fmt.Fprintf(os.Stderr, "Serving at http://localhost:%d\n", port)
@@ -329,9 +324,13 @@ func scanDirectory(dirPath string, cache map[string]*docEntry) {
 	for _, f := range files {
 		loadDocEntry(f, cache)
 	}
+	for _, entry := range cache {
+		entry.html = renderNote(entry, cache)
+	}
 }
 
-// loadDocEntry reads a single file and adds/updates it in the cache
+// loadDocEntry reads a single file and adds/updates it in the cache. The
+// caller renders it once the cache holds the notes it links to.
 func loadDocEntry(filePath string, cache map[string]*docEntry) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
@@ -343,44 +342,32 @@ func loadDocEntry(filePath string, cache map[string]*docEntry) {
 	}
 
 	slug := slugFromPath(filePath)
-	fm, body := ParseFrontmatter(string(content))
-
-	title := slug
-	if fm.Title != "" {
-		title = fm.Title
-	}
-
-	blocks := []Block{{
-		Name:        title,
-		Content:     body,
-		Pages:       []string{body},
-		TotalPages:  1,
-		ContentType: BlockContentPlain,
-	}}
-	rendered := RenderHTMLPage(title, blocks, false)
+	fm, body := parse.ParseFrontmatter(string(content))
 
 	cache[slug] = &docEntry{
 		slug:    slug,
 		fm:      fm,
-		html:    rendered,
+		body:    body,
+		links:   wikiLinkNotes(body),
 		modTime: stat.ModTime(),
 	}
 }
 
 // renderIndex builds the index HTML from current cache
-func renderIndex(dirName string, cache map[string]*docEntry) string {
-	docs := make([]DocMeta, 0, len(cache))
+func renderIndex(dirName string, cache map[string]*docEntry, columns []string) string {
+	docs := make([]html.DocMeta, 0, len(cache))
 	for _, entry := range cache {
 		title := entry.slug
 		if entry.fm.Title != "" {
 			title = entry.fm.Title
 		}
-		docs = append(docs, DocMeta{
-			Slug:    entry.slug,
-			Title:   title,
-			Created: entry.fm.Created,
-			Tags:    entry.fm.Tags,
-			ModTime: entry.modTime,
+		docs = append(docs, html.DocMeta{
+			Slug:        entry.slug,
+			Title:       title,
+			Created:     entry.fm.Created,
+			Tags:        entry.fm.Tags,
+			ModTime:     entry.modTime,
+			Frontmatter: entry.fm,
 		})
 	}
 	// Sort by created date desc, then by title
@@ -390,11 +377,11 @@ func renderIndex(dirName string, cache map[string]*docEntry) string {
 		}
 		return docs[i].Title < docs[j].Title
 	})
-	return RenderIndexPage(dirName, docs)
+	return html.RenderIndexPage(dirName, docs, columns...)
 }
 
 // watchDirectory polls for file changes in dirPath and updates cache
-func watchDirectory(dirPath string, dirName string, mu *sync.RWMutex, cache map[string]*docEntry, indexHTML *string, broadcaster *sseBroadcaster) {
+func watchDirectory(dirPath string, dirName string, columns []string, mu *sync.RWMutex, cache map[string]*docEntry, indexHTML *string, broadcaster *sseBroadcaster) {
 	for {
 		time.Sleep(500 * time.Millisecond)
 
@@ -433,33 +420,74 @@ func watchDirectory(dirPath string, dirName string, mu *sync.RWMutex, cache map[
 		}
 
 		mu.Lock()
-		// Remove deleted entries
-		for slug := range cache {
-			if _, exists := currentSlugs[slug]; !exists {
-				delete(cache, slug)
+		updateNotes(currentSlugs, cache)
+		*indexHTML = renderIndex(dirName, cache, columns)
+		mu.Unlock()
+
+		broadcaster.notify()
+	}
+}
+
+// updateNotes brings the cache in line with the .md files in currentSlugs
+// (slug -> filepath). It re-renders only the notes whose page changes:
+// changed notes, the notes they linked to before and after (backlinks), and
+// the notes linking to added or deleted ones (broken links).
+func updateNotes(currentSlugs map[string]string, cache map[string]*docEntry) {
+	affected := make(map[string]bool)
+	var added, removed, updated []string
+	for slug, entry := range cache {
+		if _, exists := currentSlugs[slug]; !exists {
+			for _, target := range linkedSlugs(entry, cache) {
+				affected[target] = true
 			}
+			removed = append(removed, slug)
 		}
-		// Add/update entries
-		for slug, fpath := range currentSlugs {
-			stat, err := os.Stat(fpath)
-			if err != nil {
-				continue
+	}
+	for _, slug := range removed {
+		delete(cache, slug)
+	}
+	for slug, fpath := range currentSlugs {
+		stat, err := os.Stat(fpath)
+		if err != nil {
+			continue
+		}
+		entry, exists := cache[slug]
+		if exists && !stat.ModTime().After(entry.modTime) {
+			continue
+		}
+		if exists {
+			for _, target := range linkedSlugs(entry, cache) {
+				affected[target] = true
 			}
-			entry, exists := cache[slug]
-			if !exists || stat.ModTime().After(entry.modTime) {
-				loadDocEntry(fpath, cache)
+		} else {
+			added = append(added, slug)
+		}
+		affected[slug] = true
+		updated = append(updated, slug)
+		loadDocEntry(fpath, cache)
+	}
+	for _, slug := range updated {
+		if entry, ok := cache[slug]; ok {
+			for _, target := range linkedSlugs(entry, cache) {
+				affected[target] = true
 			}
 		}
-		*indexHTML = renderIndex(dirName, cache)
-		mu.Unlock()
-
-		broadcaster.notify()
+	}
+	for _, entry := range cache {
+		if linksToAny(entry, append(added, removed...)) {
+			affected[entry.slug] = true
+		}
+	}
+	for slug := range affected {
+		if entry, ok := cache[slug]; ok {
+			entry.html = renderNote(entry, cache)
+		}
 	}
 }
 
 // watchAndRerender polls the file for changes, re-parses, re-renders HTML, and notifies SSE clients
-func watchAndRerender(filePath string, title string, singleBlock bool, contentType BlockContentType, mu *sync.RWMutex, currentHTML *string, broadcaster *sseBroadcaster, stopCh <-chan struct{}) {
-	parser := detectParser(filePath)
+func watchAndRerender(filePath string, title string, singleBlock bool, contentType parse.BlockContentType, showLineNums bool, mu *sync.RWMutex, currentHTML *string, broadcaster *sseBroadcaster, stopCh <-chan struct{}) {
+	parser := parse.DetectParser(filePath)
 	var lastModTime time.Time
 
 	for {
@@ -484,31 +512,35 @@ func watchAndRerender(filePath string, title string, singleBlock bool, contentTy
 				continue
 			}
 
-			var blocks []Block
+			var blocks []parse.Block
 			renderTitle := title
 			var rendered string
 			if singleBlock {
 				bodyStr := string(content)
-				fm, body := ParseFrontmatter(bodyStr)
+				fm, body := parse.ParseFrontmatter(bodyStr)
 				if fm.Title != "" {
 					renderTitle = fm.Title
 				}
 				bodyStr = body
 
-				blocks = []Block{{
+				blocks = []parse.Block{{
 					Name:        renderTitle,
 					Content:     bodyStr,
 					Pages:       []string{bodyStr},
 					TotalPages:  1,
 					ContentType: contentType,
 				}}
-				rendered = RenderHTMLPage(renderTitle, blocks, showLineNumbers)
+				if fm.Format != "" {
+					blocks[0].Data = &fm
+				}
+				rendered = html.RenderHTMLPage(renderTitle, blocks, showLineNums, parse.Diagnose(parser, string(content))...)
 			} else {
-				blocks = parser.Parse(string(content))
+				var diags []parse.Diagnostic
+				blocks, diags = parse.ParseWithDiagnostics(parser, string(content))
 				if len(blocks) == 0 {
 					continue
 				}
-				rendered = RenderHTMLPage(renderTitle, blocks, showLineNumbers)
+				rendered = html.RenderHTMLPage(renderTitle, blocks, showLineNums, diags...)
 			}
 
 			mu.Lock()
@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServerBindsLocalhost(t *testing.T) {
+	// Verify the address format produces localhost binding
+	port := 3000
+	addr := formatServerAddr(port)
+	if !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Errorf("server should bind to 127.0.0.1, got: %s", addr)
+	}
+}
+
+func TestServeLocalFileStaysInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "img"), 0o755)
+	os.WriteFile(filepath.Join(dir, "img", "a.png"), []byte("PNG"), 0o644)
+	os.WriteFile(filepath.Join(dir, "guide.md"), []byte("---\ntitle: Guide\n---\n# Setup\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("TOKEN"), 0o644)
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("SECRET"), 0o644)
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = path
+		serveLocalFile(rec, req, root)
+		return rec
+	}
+
+	if rec := get("/img/a.png"); rec.Code != 200 || rec.Body.String() != "PNG" {
+		t.Errorf("Expected image served, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get("/guide.md"); rec.Code != 200 || !strings.Contains(rec.Body.String(), "<title>Guide</title>") {
+		t.Errorf("Expected markdown rendered as a page, got %d", rec.Code)
+	}
+	for _, path := range []string{"/../secret.txt", "/img/../../secret.txt", "/.env", "/img"} {
+		if rec := get(path); rec.Code != 404 {
+			t.Errorf("Expected %s to be refused, got %d %q", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestNotesWikiLinksAndBacklinks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.md", "---\ntitle: Alpha\n---\nSee [[b]] and [[Missing Note|gone]].\n")
+	write("b.md", "# Beta\n")
+	write("c.md", "Unrelated.\n")
+
+	cache := make(map[string]*docEntry)
+	scanDirectory(dir, cache)
+
+	if a := cache["a"].html; !strings.Contains(a, `<a class="wikilink" href="/b">b</a>`) ||
+		!strings.Contains(a, `<span class="wikilink broken" title="No note named Missing Note">gone</span>`) {
+		t.Errorf("a: wiki links not resolved:\n%s", a)
+	}
+	if b := cache["b"].html; !strings.Contains(b, "Linked from") || !strings.Contains(b, `<a href="/a">Alpha</a>`) {
+		t.Errorf("b: missing backlink from a:\n%s", b)
+	}
+
+	// Adding the missing note fixes a's link, and a new link from it adds
+	// a backlink to b; c is untouched
+	write("missing note.md", "See [[B]].\n")
+	cache["c"].html = "unchanged"
+	current := map[string]string{}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.md"))
+	for _, f := range files {
+		current[slugFromPath(f)] = f
+	}
+	updateNotes(current, cache)
+
+	if a := cache["a"].html; !strings.Contains(a, `<a class="wikilink" href="/missing%20note">gone</a>`) {
+		t.Errorf("a: link to new note not resolved:\n%s", a)
+	}
+	if b := cache["b"].html; !strings.Contains(b, `<a href="/missing%20note">missing note</a>`) {
+		t.Errorf("b: missing backlink from new note:\n%s", b)
+	}
+	if cache["c"].html != "unchanged" {
+		t.Error("c: re-rendered without a change to its links")
+	}
+
+	// Deleting a removes its backlink from b
+	os.Remove(filepath.Join(dir, "a.md"))
+	delete(current, "a")
+	updateNotes(current, cache)
+	if b := cache["b"].html; strings.Contains(b, "Alpha") {
+		t.Errorf("b: backlink from deleted note remains:\n%s", b)
+	}
+}
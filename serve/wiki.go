@@ -0,0 +1,126 @@
+package serve
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/parse/markdown"
+	"github.com/wildreason/reader/render/html"
+)
+
+// renderNote renders a directory entry, resolving its wiki links against
+// the other notes in the cache and listing the notes that link to it
+func renderNote(entry *docEntry, cache map[string]*docEntry) string {
+	blocks := []parse.Block{{
+		Name:        entry.title(),
+		Content:     entry.body,
+		Pages:       []string{entry.body},
+		TotalPages:  1,
+		ContentType: parse.BlockContentPlain,
+	}}
+	if entry.fm.Format != "" {
+		blocks[0].Data = &entry.fm
+	}
+	wiki := &html.Wiki{
+		Resolve: func(note string) (string, bool) {
+			slug, ok := resolveNote(note, cache)
+			return "/" + url.PathEscape(slug), ok
+		},
+		Backlinks: backlinks(entry.slug, cache),
+	}
+	return html.RenderNotePage(entry.title(), blocks, wiki)
+}
+
+// title returns the entry's frontmatter title, or its slug
+func (e *docEntry) title() string {
+	if e.fm.Title != "" {
+		return e.fm.Title
+	}
+	return e.slug
+}
+
+// wikiLinkNotes returns the notes named by the [[wiki links]] in body, once
+// each, without any #heading
+func wikiLinkNotes(body string) []string {
+	var notes []string
+	seen := make(map[string]bool)
+	markdown.Walk(markdown.Parse(body), func(n *markdown.Node) bool {
+		if n.Kind != markdown.WikiLink {
+			return true
+		}
+		note, _, _ := strings.Cut(n.Destination, "#")
+		note = strings.TrimSpace(note)
+		if note != "" && !seen[note] {
+			seen[note] = true
+			notes = append(notes, note)
+		}
+		return false
+	})
+	return notes
+}
+
+// resolveNote returns the slug of the note a wiki link names: the exact
+// slug if there is one, otherwise a case-insensitive match
+func resolveNote(note string, cache map[string]*docEntry) (string, bool) {
+	note = strings.TrimSuffix(note, ".md")
+	if _, ok := cache[note]; ok {
+		return note, true
+	}
+	match := ""
+	for slug := range cache {
+		if strings.EqualFold(slug, note) && (match == "" || slug < match) {
+			match = slug
+		}
+	}
+	return match, match != ""
+}
+
+// linkedSlugs returns the slugs of the notes an entry links to
+func linkedSlugs(entry *docEntry, cache map[string]*docEntry) []string {
+	var slugs []string
+	for _, note := range entry.links {
+		if slug, ok := resolveNote(note, cache); ok {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// linksToAny reports whether an entry has a wiki link naming one of slugs,
+// resolved or not
+func linksToAny(entry *docEntry, slugs []string) bool {
+	for _, note := range entry.links {
+		note = strings.TrimSuffix(note, ".md")
+		for _, slug := range slugs {
+			if strings.EqualFold(note, slug) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backlinks returns the notes that link to slug, sorted by title
+func backlinks(slug string, cache map[string]*docEntry) []html.DocMeta {
+	var docs []html.DocMeta
+	for _, entry := range cache {
+		if entry.slug == slug {
+			continue
+		}
+		for _, target := range linkedSlugs(entry, cache) {
+			if target == slug {
+				docs = append(docs, html.DocMeta{Slug: entry.slug, Title: entry.title()})
+				break
+			}
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Title != docs[j].Title {
+			return docs[i].Title < docs[j].Title
+		}
+		return docs[i].Slug < docs[j].Slug
+	})
+	return docs
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// stdinSampleWait is how long input may pause before its type is detected
+// from what has arrived, so a followed log still opens
+const stdinSampleWait = 500 * time.Millisecond
+
+// inputChunk is data read from the input, with the error that ended it
+type inputChunk struct {
+	data []byte
+	err  error
+}
+
+// sampleInput reads the start of r to detect its type: stdinSampleSize
+// bytes, or all of it when it is shorter. Only input that pauses for
+// stdinSampleWait is detected from less. It returns the sample and a
+// reader of all the input, the sample first.
+func sampleInput(r io.Reader) ([]byte, io.Reader) {
+	// A goroutine reads ahead, a few chunks at most
+	chunks := make(chan inputChunk, 4)
+	go func() {
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := r.Read(buf)
+			if n > 0 || err != nil {
+				chunks <- inputChunk{data: buf[:n], err: err}
+			}
+			if err != nil {
+				close(chunks)
+				return
+			}
+		}
+	}()
+
+	in := &chunkReader{chunks: chunks}
+	var pause <-chan time.Time
+	for len(in.buf) < stdinSampleSize && in.err == nil {
+		select {
+		case c := <-chunks:
+			in.buf = append(in.buf, c.data...)
+			in.err = c.err
+			pause = time.After(stdinSampleWait)
+		case <-pause:
+			return in.buf, in
+		}
+	}
+	return in.buf, in
+}
+
+// chunkReader reads the chunks sampleInput's goroutine reads
+type chunkReader struct {
+	chunks <-chan inputChunk
+	buf    []byte
+	err    error
+}
+
+// Read returns buffered data, then the next chunk
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		chunk, ok := <-c.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		c.buf, c.err = chunk.data, chunk.err
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSampleInput_FillsSample(t *testing.T) {
+	// Input arriving a byte at a time still fills the sample
+	content := strings.Repeat("diff --git a/x b/x\n", 5000)
+	sample, in := sampleInput(iotest.OneByteReader(strings.NewReader(content)))
+	if len(sample) != stdinSampleSize || !strings.HasPrefix(content, string(sample)) {
+		t.Errorf("sample is %d bytes, want %d from the start of the input", len(sample), stdinSampleSize)
+	}
+	all, err := io.ReadAll(in)
+	if err != nil || string(all) != content {
+		t.Errorf("read %d bytes (%v), want all %d", len(all), err, len(content))
+	}
+}
+
+func TestSampleInput_ShortInput(t *testing.T) {
+	sample, in := sampleInput(iotest.HalfReader(bytes.NewReader([]byte("a,b\n1,2\n"))))
+	if string(sample) != "a,b\n1,2\n" {
+		t.Errorf("sample = %q, want the whole input", sample)
+	}
+	if all, _ := io.ReadAll(in); string(all) != "a,b\n1,2\n" {
+		t.Errorf("read %q", all)
+	}
+}
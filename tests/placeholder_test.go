@@ -1,9 +0,0 @@
-package tests
-
-import "testing"
-
-// Placeholder test to ensure the tests package exists
-func TestPlaceholder(t *testing.T) {
-	// This test always passes
-	// Add actual tests as needed
-}
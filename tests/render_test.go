@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render"
+)
+
+func TestRender_MarkdownStaticHTML(t *testing.T) {
+	input := "---\ntitle: Release Notes\n---\n\n# Changes\n\nSome **bold** text."
+	doc, err := render.Render(context.Background(), strings.NewReader(input), render.Options{
+		Name:   "notes.md",
+		Format: render.FormatStaticHTML,
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if doc.Type != "md" {
+		t.Errorf("expected type md, got %q", doc.Type)
+	}
+	if doc.Title != "Release Notes" {
+		t.Errorf("expected frontmatter title, got %q", doc.Title)
+	}
+	if !strings.Contains(doc.Output, "<strong>bold</strong>") {
+		t.Errorf("expected rendered markdown in output")
+	}
+	if strings.Contains(doc.Output, "title: Release Notes") {
+		t.Errorf("frontmatter should not be rendered as body")
+	}
+}
+
+func TestRender_DetectsDiffFromContent(t *testing.T) {
+	input := "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n-old line\n+new line\n"
+	doc, err := render.Render(context.Background(), strings.NewReader(input), render.Options{
+		Format: render.FormatText,
+		Width:  80,
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if doc.Type != "diff" {
+		t.Errorf("expected diff type, got %q", doc.Type)
+	}
+	if len(doc.Blocks) == 0 || doc.Blocks[0].ContentType != parse.BlockContentDiff {
+		t.Errorf("expected a diff block")
+	}
+	if !strings.Contains(doc.Output, "new line") {
+		t.Errorf("expected diff lines in text output")
+	}
+}
+
+func TestRender_TextStripsTags(t *testing.T) {
+	doc, err := render.Render(context.Background(), strings.NewReader("# Title\n\nbody"), render.Options{
+		Type:   "md",
+		Format: render.FormatText,
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(doc.Output, "[-]") || strings.Contains(doc.Output, "[::b]") {
+		t.Errorf("text output should not contain tview tags: %q", doc.Output)
+	}
+}
+
+func TestRender_UnknownTypeAndCancel(t *testing.T) {
+	if _, err := render.Render(context.Background(), strings.NewReader("x"), render.Options{Type: "nope"}); err == nil {
+		t.Errorf("expected error for unknown type")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := render.Render(ctx, strings.NewReader("x"), render.Options{}); err == nil {
+		t.Errorf("expected error for cancelled context")
+	}
+}
+
+func TestParse_RegistryIsPublic(t *testing.T) {
+	spec := parse.LookupParser("csv")
+	if spec == nil {
+		t.Fatal("csv parser should be registered")
+	}
+	blocks := spec.New().Parse("a,b\n1,2\n3,4\n")
+	if len(blocks) == 0 {
+		t.Fatal("expected csv blocks")
+	}
+	data, ok := blocks[0].Data.(*parse.CsvData)
+	if !ok || len(data.Records) != 3 {
+		t.Errorf("expected 3 csv records, got %#v", blocks[0].Data)
+	}
+}
+
+func TestRender_ReportsDiagnostics(t *testing.T) {
+	doc, err := render.Render(context.Background(), strings.NewReader("[{\"content\": }]"), render.Options{
+		Type:   "json",
+		Format: render.FormatStaticHTML,
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !parse.HasErrors(doc.Diagnostics) {
+		t.Fatalf("expected a JSON error, got %v", doc.Diagnostics)
+	}
+	if !strings.Contains(doc.Output, `class="diagnostics has-errors"`) {
+		t.Errorf("expected diagnostics panel in HTML output")
+	}
+}
@@ -0,0 +1,145 @@
+package theme
+
+// CodeColors defines the color scheme for highlighted code blocks
+type CodeColors struct {
+	Text     string // Identifiers, punctuation and code in unknown languages
+	Keyword  string
+	Type     string
+	Function string // Function and macro calls, builtins, decorators
+	String   string
+	Number   string
+	Constant string // true, false, nil, null
+	Comment  string
+	Operator string
+	Key      string // JSON and YAML keys
+	Variable string // Shell $variables, YAML anchors and aliases
+	Inserted string // Diff + lines
+	Deleted  string // Diff - lines
+	Meta     string // Diff file headers and hunk markers, YAML document markers
+
+	Border     string // Box border and language label
+	Emphasis   string // Border beside lines emphasized with {1,3-5}
+	EmphasisBg string // Background of emphasized lines
+}
+
+// set changes the color named by a config key like "keyword" or
+// "emphasis_bg", reporting whether there is one
+func (c *CodeColors) set(field, color string) bool {
+	fields := map[string]*string{
+		"text": &c.Text, "keyword": &c.Keyword, "type": &c.Type,
+		"function": &c.Function, "string": &c.String, "number": &c.Number,
+		"constant": &c.Constant, "comment": &c.Comment, "operator": &c.Operator,
+		"key": &c.Key, "variable": &c.Variable, "inserted": &c.Inserted,
+		"deleted": &c.Deleted, "meta": &c.Meta, "border": &c.Border,
+		"emphasis": &c.Emphasis, "emphasis_bg": &c.EmphasisBg,
+	}
+	p, ok := fields[field]
+	if ok {
+		*p = color
+	}
+	return ok
+}
+
+// DarkCode returns the code colors for dark terminals
+func DarkCode() CodeColors {
+	return CodeColors{
+		Text:       "#c5c8c6",
+		Keyword:    "#b294bb",
+		Type:       "#f0c674",
+		Function:   "#81a2be",
+		String:     "#b5bd68",
+		Number:     "#de935f",
+		Constant:   "#de935f",
+		Comment:    "#707070",
+		Operator:   "#8abeb7",
+		Key:        "#cc6666",
+		Variable:   "#cc6666",
+		Inserted:   "#b5bd68",
+		Deleted:    "#cc6666",
+		Meta:       "#81a2be",
+		Border:     "#707070",
+		Emphasis:   "#f0c674",
+		EmphasisBg: "#373b41",
+	}
+}
+
+// LightCode returns the code colors for light terminals
+func LightCode() CodeColors {
+	return CodeColors{
+		Text:       "#383a42",
+		Keyword:    "#a626a4",
+		Type:       "#c18401",
+		Function:   "#4078f2",
+		String:     "#50a14f",
+		Number:     "#986801",
+		Constant:   "#986801",
+		Comment:    "#a0a1a7",
+		Operator:   "#0184bc",
+		Key:        "#e45649",
+		Variable:   "#e45649",
+		Inserted:   "#50a14f",
+		Deleted:    "#e45649",
+		Meta:       "#4078f2",
+		Border:     "#a0a1a7",
+		Emphasis:   "#c18401",
+		EmphasisBg: "#e5e5e6",
+	}
+}
+
+// PlainCode returns code colors without highlighting: code in one muted
+// gray, with emphasized lines still marked
+func PlainCode() CodeColors {
+	gray := "#707070"
+	return CodeColors{
+		Text: gray, Keyword: gray, Type: gray, Function: gray, String: gray,
+		Number: gray, Constant: gray, Comment: gray, Operator: gray, Key: gray,
+		Variable: gray, Inserted: gray, Deleted: gray, Meta: gray,
+		Border: gray, Emphasis: "#a0a0a0", EmphasisBg: "#303030",
+	}
+}
+
+// HighContrastDarkCode returns bright code colors for black backgrounds
+func HighContrastDarkCode() CodeColors {
+	return CodeColors{
+		Text:       "white",
+		Keyword:    "#ff87ff",
+		Type:       "#ffff00",
+		Function:   "#5fd7ff",
+		String:     "#87ff5f",
+		Number:     "#ffaf5f",
+		Constant:   "#ffaf5f",
+		Comment:    "#bcbcbc",
+		Operator:   "#00ffff",
+		Key:        "#ff8787",
+		Variable:   "#ff8787",
+		Inserted:   "#87ff5f",
+		Deleted:    "#ff5f5f",
+		Meta:       "#5fd7ff",
+		Border:     "#c0c0c0",
+		Emphasis:   "#ffff00",
+		EmphasisBg: "#3a3a3a",
+	}
+}
+
+// HighContrastLightCode returns dark code colors for white backgrounds
+func HighContrastLightCode() CodeColors {
+	return CodeColors{
+		Text:       "black",
+		Keyword:    "#870087",
+		Type:       "#875f00",
+		Function:   "#0000af",
+		String:     "#005f00",
+		Number:     "#af5f00",
+		Constant:   "#af5f00",
+		Comment:    "#4e4e4e",
+		Operator:   "#005f87",
+		Key:        "#af0000",
+		Variable:   "#af0000",
+		Inserted:   "#005f00",
+		Deleted:    "#af0000",
+		Meta:       "#0000af",
+		Border:     "#3a3a3a",
+		Emphasis:   "#875f00",
+		EmphasisBg: "#e4e4e4",
+	}
+}
@@ -0,0 +1,393 @@
+// Package theme holds the terminal color theme: the colors of the semantic
+// roles used by the parsers, the terminal renderer and the reader. Colors
+// are tview color names or #rrggbb values; an empty color leaves text in
+// the terminal's default color.
+package theme
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Role is a use of color in the terminal
+type Role string
+
+const (
+	Background   Role = "background" // Reader background
+	Text         Role = "text"       // Reader text, borders and titles
+	Heading      Role = "heading"    // Level 1 headings
+	Heading2     Role = "heading2"   // Level 2 headings and table headers
+	Heading3     Role = "heading3"   // Deeper headings
+	Strong       Role = "strong"     // Bold text
+	Code         Role = "code"       // Code spans
+	Link         Role = "link"       // Links and file paths
+	Math         Role = "math"
+	Muted        Role = "muted" // Rules, markers, hints and other secondary text
+	Border       Role = "border"
+	Gutter       Role = "gutter" // Line numbers
+	Accent       Role = "accent" // Bullets, labels and options
+	Highlight    Role = "highlight"
+	Success      Role = "success"
+	Warning      Role = "warning"
+	Error        Role = "error"
+	Added        Role = "added" // Diff lines
+	AddedBg      Role = "added-bg"
+	Removed      Role = "removed"
+	RemovedBg    Role = "removed-bg"
+	UserBubble   Role = "user-bubble" // User messages in transcripts
+	UserBubbleBg Role = "user-bubble-bg"
+	Header       Role = "header" // Block header bar
+	HeaderBg     Role = "header-bg"
+	Chat         Role = "chat"     // Transcript block label
+	Shell        Role = "shell"    // Shell block label
+	Tool         Role = "tool"     // Tool name in shell output headers
+	File         Role = "file"     // File names in diff headers
+	Selected     Role = "selected" // Current tab, outline selection
+	SelectedBg   Role = "selected-bg"
+	Prompt       Role = "prompt" // Search and prompt labels
+
+	// Callout borders and titles; other admonitions share these
+	CalloutNote      Role = "callout-note"
+	CalloutTip       Role = "callout-tip"
+	CalloutImportant Role = "callout-important"
+	CalloutWarning   Role = "callout-warning"
+	CalloutCaution   Role = "callout-caution"
+)
+
+// Roles lists every role
+var Roles = []Role{
+	Background, Text, Heading, Heading2, Heading3, Strong, Code, Link, Math,
+	Muted, Border, Gutter, Accent, Highlight, Success, Warning, Error,
+	Added, AddedBg, Removed, RemovedBg, UserBubble, UserBubbleBg,
+	Header, HeaderBg, Chat, Shell, Tool, File, Selected, SelectedBg, Prompt,
+	CalloutNote, CalloutTip, CalloutImportant, CalloutWarning, CalloutCaution,
+}
+
+// Theme gives the color of each role and of highlighted code
+type Theme struct {
+	Colors map[Role]string
+	Code   CodeColors
+}
+
+// current is the theme in use; mu guards it, as rendering may run on
+// several goroutines
+var (
+	mu      sync.RWMutex
+	current = Dark()
+)
+
+// Set makes t the current theme
+func Set(t Theme) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = t
+}
+
+// Current returns the current theme
+func Current() Theme {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Color returns the current theme's color for a role
+func Color(r Role) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.Colors[r]
+}
+
+// Tag returns the tview tag that switches to a role's color, or back to the
+// default color when it has none
+func Tag(r Role) string {
+	return "[" + orDefault(Color(r)) + "]"
+}
+
+// TagBg returns the tview tag that switches to one role's color on
+// another's background
+func TagBg(fg, bg Role) string {
+	return "[" + orDefault(Color(fg)) + ":" + orDefault(Color(bg)) + "]"
+}
+
+// orDefault returns "-", tview's default color, for an empty color
+func orDefault(color string) string {
+	if color == "" {
+		return "-"
+	}
+	return color
+}
+
+// ANSI returns the escape sequence that sets a role's color as the
+// foreground (or background), or "" when it has none
+func ANSI(r Role, bg bool) string {
+	color := Color(r)
+	if color == "" {
+		return ""
+	}
+	red, green, blue := tcell.GetColor(color).RGB()
+	layer := 38
+	if bg {
+		layer = 48
+	}
+	return fmt.Sprintf("\033[%d;2;%d;%d;%dm", layer, red, green, blue)
+}
+
+// ValidColor reports whether color is empty, a color name or #rrggbb
+func ValidColor(color string) bool {
+	return color == "" || strings.EqualFold(color, "default") || tcell.GetColor(color) != tcell.ColorDefault
+}
+
+// ParseRole returns the role with the given name
+func ParseRole(name string) (Role, bool) {
+	for _, r := range Roles {
+		if string(r) == name {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// With returns a copy of t with some colors changed. Keys are role names,
+// or code.FIELD (e.g. code.keyword) for highlighted code.
+func (t Theme) With(colors map[string]string) (Theme, error) {
+	out := Theme{Colors: make(map[Role]string, len(t.Colors)), Code: t.Code}
+	for r, c := range t.Colors {
+		out.Colors[r] = c
+	}
+	keys := make([]string, 0, len(colors))
+	for key := range colors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		color := colors[key]
+		if !ValidColor(color) {
+			return Theme{}, fmt.Errorf("%s: unknown color %q", key, color)
+		}
+		if field, ok := strings.CutPrefix(key, "code."); ok {
+			if !out.Code.set(field, color) {
+				return Theme{}, fmt.Errorf("unknown code color %q", field)
+			}
+			continue
+		}
+		r, ok := ParseRole(key)
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown color role %q", key)
+		}
+		out.Colors[r] = color
+	}
+	return out, nil
+}
+
+// Names lists the built-in themes
+var Names = []string{"dark", "light", "high-contrast", "high-contrast-dark", "high-contrast-light"}
+
+// ByName returns a built-in theme. "high-contrast" is the dark or light
+// variant to suit the background.
+func ByName(name string, darkBackground bool) (Theme, bool) {
+	switch strings.ToLower(name) {
+	case "dark":
+		return Dark(), true
+	case "light":
+		return Light(), true
+	case "high-contrast":
+		if darkBackground {
+			return HighContrastDark(), true
+		}
+		return HighContrastLight(), true
+	case "high-contrast-dark":
+		return HighContrastDark(), true
+	case "high-contrast-light":
+		return HighContrastLight(), true
+	}
+	return Theme{}, false
+}
+
+// Dark returns the theme for dark backgrounds, the default
+func Dark() Theme {
+	return Theme{
+		Colors: map[Role]string{
+			Background:       "black",
+			Text:             "white",
+			Heading:          "yellow",
+			Heading2:         "#87ceeb",
+			Heading3:         "#808080",
+			Strong:           "#ffd700",
+			Code:             "#a0a0a0",
+			Link:             "blue",
+			Math:             "#d7afff",
+			Muted:            "#808080",
+			Border:           "#707070",
+			Gutter:           "#555555",
+			Accent:           "cyan",
+			Highlight:        "yellow",
+			Success:          "green",
+			Warning:          "yellow",
+			Error:            "red",
+			Added:            "white",
+			AddedBg:          "#2d5a2d",
+			Removed:          "white",
+			RemovedBg:        "#5a2d5a",
+			UserBubble:       "white",
+			UserBubbleBg:     "#303030",
+			Header:           "white",
+			HeaderBg:         "#333333",
+			Chat:             "#b294bb",
+			Shell:            "#99b494",
+			Tool:             "#179299",
+			File:             "green",
+			Selected:         "black",
+			SelectedBg:       "yellow",
+			Prompt:           "yellow",
+			CalloutNote:      "#58a6ff",
+			CalloutTip:       "#3fb950",
+			CalloutImportant: "#a371f7",
+			CalloutWarning:   "#d29922",
+			CalloutCaution:   "#f85149",
+		},
+		Code: DarkCode(),
+	}
+}
+
+// Light returns the theme for light backgrounds, drawn on the terminal's
+// own background
+func Light() Theme {
+	return Theme{
+		Colors: map[Role]string{
+			Background:       "",
+			Text:             "",
+			Heading:          "#986801",
+			Heading2:         "#0969da",
+			Heading3:         "#57606a",
+			Strong:           "#953800",
+			Code:             "#57606a",
+			Link:             "#0550ae",
+			Math:             "#8250df",
+			Muted:            "#6e7781",
+			Border:           "#a0a1a7",
+			Gutter:           "#a0a1a7",
+			Accent:           "#0184bc",
+			Highlight:        "#986801",
+			Success:          "#1a7f37",
+			Warning:          "#9a6700",
+			Error:            "#cf222e",
+			Added:            "#24292f",
+			AddedBg:          "#dafbe1",
+			Removed:          "#24292f",
+			RemovedBg:        "#ffebe9",
+			UserBubble:       "#24292f",
+			UserBubbleBg:     "#eaeef2",
+			Header:           "#24292f",
+			HeaderBg:         "#e1e4e8",
+			Chat:             "#8250df",
+			Shell:            "#1a7f37",
+			Tool:             "#0e7490",
+			File:             "#1a7f37",
+			Selected:         "white",
+			SelectedBg:       "#0969da",
+			Prompt:           "#9a6700",
+			CalloutNote:      "#0969da",
+			CalloutTip:       "#1a7f37",
+			CalloutImportant: "#8250df",
+			CalloutWarning:   "#9a6700",
+			CalloutCaution:   "#cf222e",
+		},
+		Code: LightCode(),
+	}
+}
+
+// HighContrastDark returns a theme of bright colors on black
+func HighContrastDark() Theme {
+	return Theme{
+		Colors: map[Role]string{
+			Background:       "black",
+			Text:             "white",
+			Heading:          "#ffff00",
+			Heading2:         "#00ffff",
+			Heading3:         "white",
+			Strong:           "#ffd75f",
+			Code:             "#d0d0d0",
+			Link:             "#5fafff",
+			Math:             "#ff87ff",
+			Muted:            "#c0c0c0",
+			Border:           "#c0c0c0",
+			Gutter:           "#a8a8a8",
+			Accent:           "#00ffff",
+			Highlight:        "#ffff00",
+			Success:          "#00ff00",
+			Warning:          "#ffaf00",
+			Error:            "#ff5f5f",
+			Added:            "black",
+			AddedBg:          "#00d700",
+			Removed:          "white",
+			RemovedBg:        "#d70000",
+			UserBubble:       "black",
+			UserBubbleBg:     "#e0e0e0",
+			Header:           "black",
+			HeaderBg:         "white",
+			Chat:             "#ff87ff",
+			Shell:            "#87ff87",
+			Tool:             "#00ffff",
+			File:             "#00ff00",
+			Selected:         "black",
+			SelectedBg:       "#ffff00",
+			Prompt:           "#ffff00",
+			CalloutNote:      "#5fafff",
+			CalloutTip:       "#00ff00",
+			CalloutImportant: "#ff87ff",
+			CalloutWarning:   "#ffaf00",
+			CalloutCaution:   "#ff5f5f",
+		},
+		Code: HighContrastDarkCode(),
+	}
+}
+
+// HighContrastLight returns a theme of dark colors on white
+func HighContrastLight() Theme {
+	return Theme{
+		Colors: map[Role]string{
+			Background:       "white",
+			Text:             "black",
+			Heading:          "#00005f",
+			Heading2:         "#005f87",
+			Heading3:         "black",
+			Strong:           "#5f0000",
+			Code:             "#1c1c1c",
+			Link:             "#0000d7",
+			Math:             "#5f00af",
+			Muted:            "#3a3a3a",
+			Border:           "#3a3a3a",
+			Gutter:           "#4e4e4e",
+			Accent:           "#005f87",
+			Highlight:        "#875f00",
+			Success:          "#005f00",
+			Warning:          "#875f00",
+			Error:            "#af0000",
+			Added:            "white",
+			AddedBg:          "#005f00",
+			Removed:          "white",
+			RemovedBg:        "#af0000",
+			UserBubble:       "white",
+			UserBubbleBg:     "#303030",
+			Header:           "white",
+			HeaderBg:         "black",
+			Chat:             "#5f00af",
+			Shell:            "#005f00",
+			Tool:             "#005f87",
+			File:             "#005f00",
+			Selected:         "white",
+			SelectedBg:       "black",
+			Prompt:           "#875f00",
+			CalloutNote:      "#0000d7",
+			CalloutTip:       "#005f00",
+			CalloutImportant: "#5f00af",
+			CalloutWarning:   "#875f00",
+			CalloutCaution:   "#af0000",
+		},
+		Code: HighContrastLightCode(),
+	}
+}
@@ -0,0 +1,65 @@
+package theme
+
+import "testing"
+
+func TestWith(t *testing.T) {
+	base := Dark()
+	got, err := base.With(map[string]string{"heading": "#ff0000", "code.keyword": "red", "muted": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Colors[Heading] != "#ff0000" || got.Code.Keyword != "red" || got.Colors[Muted] != "" {
+		t.Errorf("With did not change colors: %v %q", got.Colors, got.Code.Keyword)
+	}
+	if base.Colors[Heading] != "yellow" {
+		t.Errorf("With changed the base theme")
+	}
+
+	for _, colors := range []map[string]string{
+		{"headline": "red"},
+		{"heading": "reddish"},
+		{"code.keywords": "red"},
+	} {
+		if _, err := base.With(colors); err == nil {
+			t.Errorf("With(%v) succeeded, want an error", colors)
+		}
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range Names {
+		th, ok := ByName(name, true)
+		if !ok {
+			t.Errorf("ByName(%q) not found", name)
+			continue
+		}
+		for _, r := range Roles {
+			if _, ok := th.Colors[r]; !ok {
+				t.Errorf("%s theme has no %s color", name, r)
+			}
+		}
+	}
+	if th, _ := ByName("high-contrast", false); th.Colors[Background] != "white" {
+		t.Errorf("high-contrast on a light background = %q background", th.Colors[Background])
+	}
+	if _, ok := ByName("solarized", true); ok {
+		t.Errorf("ByName found an unknown theme")
+	}
+}
+
+func TestTagAndANSI(t *testing.T) {
+	defer Set(Current())
+	Set(Light())
+	if got := Tag(Text); got != "[-]" {
+		t.Errorf("Tag(Text) = %q, want [-]", got)
+	}
+	if got := TagBg(Added, AddedBg); got != "[#24292f:#dafbe1]" {
+		t.Errorf("TagBg = %q", got)
+	}
+	if got := ANSI(AddedBg, true); got != "\033[48;2;218;251;225m" {
+		t.Errorf("ANSI = %q", got)
+	}
+	if got := ANSI(Text, false); got != "" {
+		t.Errorf("ANSI for an empty color = %q", got)
+	}
+}
@@ -0,0 +1,414 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+)
+
+// Document is a parsed file shown in a reader buffer
+type Document struct {
+	Name   string // File path, or "stdin"
+	Blocks []parse.Block
+	Diags  []parse.Diagnostic
+
+	// Place is where the reader opens the document and, once it closes,
+	// where the document was left. Marks are its bookmarks, by letter.
+	Place Place
+	Marks map[string]Place
+	// Save, if set, is called when the reader closes to keep Place and
+	// Marks for the next time
+	Save func(doc *Document)
+	// Unbounded marks streamed input too large to keep all of, such as a
+	// pipe: the reader drops its oldest lines as more stream in
+	Unbounded bool
+}
+
+// OpenFunc reads and parses a file to show in a new buffer
+type OpenFunc func(path string) (*Document, error)
+
+// buffer is one document's view in the reader: its text, with search, the
+// outline and link selection. Each buffer keeps its own scroll position.
+type buffer struct {
+	name    string
+	text    *tview.TextView
+	body    *tview.Flex // Outline panel and text side by side
+	layout  *tview.Flex // Body above the search bar, prompt and status area
+	links   *linkNavigator
+	search  *searcher
+	outline *outline
+	marks   map[string]Place // Bookmarks set with m, by letter
+	lines   []string         // Rendered lines without regions, for frozen columns
+	frozen  []int            // Width of each line kept in place when scrolling sideways
+	stale   bool             // lines and frozen need finding again in the text
+	message *tview.TextView  // Line below the text for messages, when there is no tab bar
+
+	// toggleWrap switches between fitting wide lines to the screen and
+	// scrolling sideways; set by the buffer's owner
+	toggleWrap func()
+}
+
+// newBuffer returns an empty buffer for the document called sourceName
+func newBuffer(app *tview.Application, sourceName string, showLineNums bool) *buffer {
+	b := &buffer{name: sourceName, marks: make(map[string]Place)}
+	b.text = tview.NewTextView().
+		SetWrap(false).
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetScrollable(true)
+	b.body = tview.NewFlex().AddItem(b.text, 0, 1, true)
+	b.layout = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(b.body, 0, 1, true)
+	b.links = newLinkNavigator(app, b.text, sourceName, showLineNums)
+	b.search = newSearcher(app, b.text, b.layout)
+	b.outline = newOutline(app, b.text, b.body, b.layout)
+	return b
+}
+
+// setFrozen records the lines of content and how much of each stays in
+// place when scrolling sideways: the first column of tables
+func (b *buffer) setFrozen(content string) {
+	b.lines = strings.Split(regionRegex.ReplaceAllString(content, ""), "\n")
+	b.frozen = term.FrozenColumns(b.lines)
+}
+
+// drawFrozen redraws the frozen start of the lines in view over the text
+// scrolled sideways, so tables keep their first column
+func (b *buffer) drawFrozen(screen tcell.Screen) {
+	row, col := b.text.GetScrollOffset()
+	if col == 0 {
+		return
+	}
+	if b.stale {
+		b.setFrozen(b.search.content)
+		b.stale = false
+	}
+	x, y, width, height := b.text.GetInnerRect()
+	bg := tcell.StyleDefault.Background(b.text.GetBackgroundColor())
+	for i := 0; i < height && row+i < len(b.lines); i++ {
+		w := min(b.frozen[row+i], width)
+		if w == 0 {
+			continue
+		}
+		for j := 0; j < w; j++ {
+			screen.SetContent(x+j, y+i, ' ', nil, bg)
+		}
+		tview.Print(screen, b.lines[row+i], x, y+i, w, tview.AlignLeft, themeColor(theme.Text))
+	}
+}
+
+// showMessage shows a message on a line below the text until the next key,
+// for a buffer without a buffer list's tab bar
+func (b *buffer) showMessage(message string) {
+	if b.message == nil {
+		b.message = tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	}
+	b.message.SetText(" " + theme.Tag(theme.Error) + tview.Escape(message) + "[-]")
+	b.layout.RemoveItem(b.message)
+	b.layout.AddItem(b.message, 1, 0, false)
+}
+
+// clearMessage hides the message line
+func (b *buffer) clearMessage() {
+	if b.message != nil {
+		b.layout.RemoveItem(b.message)
+	}
+}
+
+// documentBuffer is a buffer showing a parsed document, rendered at the
+// width of the screen
+type documentBuffer struct {
+	*buffer
+	doc     *Document
+	width   int  // Width the document was last rendered at
+	wrapped bool // Whether it was rendered to fit the width
+}
+
+// render renders the document at width, keeping the section in view, or
+// going to the document's place the first time. Unless wrap is set, tables,
+// code and preformatted lines keep their full width.
+func (b *documentBuffer) render(width int, wrap bool, borderStyle term.BorderStyle, showLineNums bool) {
+	first := b.width == 0
+	b.width = width
+	b.wrapped = wrap
+	section, offset := b.outline.position()
+	settings := b.links.renderSettings()
+	settings.NoWrap = !wrap
+	if showLineNums {
+		settings.LineNumbers = true
+		settings.GutterWidth = term.ComputeGutterWidth(b.doc.Blocks)
+	}
+	content := tview.TranslateANSI(term.FormatBlocksWith(b.doc.Blocks, width, borderStyle, settings))
+	b.setFrozen(content)
+	b.search.setContent(content)
+	b.outline.changed()
+	if first {
+		b.outline.restorePlace(b.doc.Place)
+		return
+	}
+	b.outline.restore(section, offset)
+}
+
+// bufferList holds the reader's open buffers, showing one at a time with a
+// tab bar above when there are several
+type bufferList struct {
+	app          *tview.Application
+	pages        *tview.Pages
+	tabs         *tview.TextView
+	root         *tview.Flex
+	buffers      []*documentBuffer
+	current      int
+	open         OpenFunc
+	borderStyle  term.BorderStyle
+	showLineNums bool
+	wrap         bool   // Fit tables, code and preformatted lines to the screen
+	message      string // Shown in the tab bar until the next switch
+}
+
+// newBufferList returns a buffer list opening files with open, which may
+// be nil
+func newBufferList(app *tview.Application, open OpenFunc, borderStyle term.BorderStyle, showLineNums bool) *bufferList {
+	bl := &bufferList{app: app, open: open, borderStyle: borderStyle, showLineNums: showLineNums, wrap: true}
+	bl.pages = tview.NewPages()
+	bl.tabs = tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	bl.root = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(bl.tabs, 0, 0, false).
+		AddItem(bl.pages, 0, 1, true)
+	return bl
+}
+
+// add opens a buffer for a parsed document and switches to it
+func (bl *bufferList) add(doc *Document) {
+	b := &documentBuffer{buffer: newBuffer(bl.app, doc.Name, bl.showLineNums), doc: doc}
+	b.links.openBuffer = bl.openLink
+	b.outline.command = bl.command
+	b.toggleWrap = bl.toggleWrap
+	for letter, p := range doc.Marks {
+		b.marks[letter] = p
+	}
+	b.text.SetInputCapture(readerKeys(bl.app, b.buffer, bl))
+	if len(doc.Diags) > 0 {
+		status, height := newStatusArea(doc.Diags)
+		b.layout.AddItem(status, height, 0, false)
+	}
+	bl.buffers = append(bl.buffers, b)
+	bl.pages.AddPage(strconv.Itoa(len(bl.buffers)), b.layout, true, false)
+	bl.show(len(bl.buffers) - 1)
+}
+
+// show switches to the buffer at position i
+func (bl *bufferList) show(i int) {
+	if i < 0 || i >= len(bl.buffers) {
+		return
+	}
+	bl.current = i
+	bl.pages.SwitchToPage(strconv.Itoa(i + 1))
+	bl.app.SetFocus(bl.buffers[i].text)
+	bl.message = ""
+	bl.updateTabs()
+}
+
+// cycle switches to the next (or previous) buffer, wrapping around
+func (bl *bufferList) cycle(forward bool) {
+	step := 1
+	if !forward {
+		step = -1
+	}
+	bl.show((bl.current + step + len(bl.buffers)) % len(bl.buffers))
+}
+
+// toggleWrap switches every buffer between fitting its content to the
+// screen and keeping wide lines whole to scroll sideways
+func (bl *bufferList) toggleWrap() {
+	bl.wrap = !bl.wrap
+}
+
+// sync renders the current buffer if the screen width or the wrapping
+// changed since it was last rendered, and has its outline follow the
+// section in view
+func (bl *bufferList) sync(screenWidth int) {
+	b := bl.buffers[bl.current]
+	width := screenWidth
+	if b.outline.visible {
+		width -= outlineWidth
+	}
+	if width != b.width || b.wrapped != bl.wrap {
+		b.render(width, bl.wrap, bl.borderStyle, bl.showLineNums)
+	}
+	b.outline.follow()
+}
+
+// updateTabs lists the buffers in the tab bar, which is shown when there
+// are several buffers or a message
+func (bl *bufferList) updateTabs() {
+	var sb strings.Builder
+	if len(bl.buffers) > 1 {
+		for i, b := range bl.buffers {
+			label := fmt.Sprintf(" %d %s ", i+1, tview.Escape(filepath.Base(b.name)))
+			if i == bl.current {
+				sb.WriteString(theme.TagBg(theme.Selected, theme.SelectedBg) + label + "[-:-]")
+			} else {
+				sb.WriteString(theme.Tag(theme.Muted) + label + "[-]")
+			}
+		}
+	}
+	if bl.message != "" {
+		sb.WriteString("  " + theme.Tag(theme.Error) + tview.Escape(bl.message) + "[-]")
+	}
+	bl.tabs.SetText(sb.String())
+	height := 0
+	if sb.Len() > 0 {
+		height = 1
+	}
+	bl.root.ResizeItem(bl.tabs, height, 0)
+}
+
+// showMessage shows a message in the tab bar
+func (bl *bufferList) showMessage(message string) {
+	bl.message = message
+	bl.updateTabs()
+}
+
+// openFile switches to the buffer showing path, or opens a new one,
+// reporting whether it could be shown
+func (bl *bufferList) openFile(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for i, b := range bl.buffers {
+		if other, err := filepath.Abs(b.name); err == nil && other == abs && b.name != "stdin" {
+			bl.show(i)
+			return true
+		}
+	}
+	if bl.open == nil {
+		return false
+	}
+	doc, err := bl.open(path)
+	if err != nil {
+		bl.showMessage(err.Error())
+		return false
+	}
+	bl.add(doc)
+	return true
+}
+
+// openLink opens a link to a local file in a buffer, scrolled to the
+// heading named by its fragment, if any
+func (bl *bufferList) openLink(path, fragment string) bool {
+	if !bl.openFile(path) {
+		return false
+	}
+	if fragment != "" {
+		b := bl.buffers[bl.current]
+		b.ensureRendered(bl)
+		b.outline.jumpTo(strings.ReplaceAll(fragment, "-", " "))
+	}
+	return true
+}
+
+// ensureRendered renders a buffer that hasn't been drawn yet, so its
+// headings can be found before the next draw
+func (b *documentBuffer) ensureRendered(bl *bufferList) {
+	if b.width == 0 {
+		_, _, width, _ := bl.pages.GetInnerRect()
+		b.render(width, bl.wrap, bl.borderStyle, bl.showLineNums)
+	}
+}
+
+// commandRegex matches prompt commands: b N (or buffer N) switches to a
+// buffer, bn and bp to the next and previous, e FILE (or edit FILE) opens
+// a file
+var commandRegex = regexp.MustCompile(`^\s*(b|buffer|bn|bnext|bp|bprev|e|edit)(?:\s*(\d+)|\s+(.+?))?\s*$`)
+
+// command runs a prompt command when run is set, reporting whether input
+// is one. Other input goes to a heading.
+func (bl *bufferList) command(input string, run bool) bool {
+	m := commandRegex.FindStringSubmatch(input)
+	if m == nil {
+		return false
+	}
+	name, number, arg := m[1], m[2], m[3]
+	switch name {
+	case "b", "buffer":
+		if number == "" {
+			return false
+		}
+	case "bn", "bnext", "bp", "bprev":
+		if number != "" || arg != "" {
+			return false
+		}
+	case "e", "edit":
+		if arg == "" && number == "" {
+			return false
+		}
+	}
+	if !run {
+		return true
+	}
+
+	switch name {
+	case "b", "buffer":
+		n, _ := strconv.Atoi(number)
+		if n < 1 || n > len(bl.buffers) {
+			bl.showMessage(fmt.Sprintf("No buffer %d", n))
+			return true
+		}
+		bl.show(n - 1)
+	case "bn", "bnext":
+		bl.cycle(true)
+	case "bp", "bprev":
+		bl.cycle(false)
+	case "e", "edit":
+		path := arg + number
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		bl.openFile(path)
+	}
+	return true
+}
+
+// save records where each document shown was left, and its marks
+func (bl *bufferList) save() {
+	for _, b := range bl.buffers {
+		if b.width == 0 || b.doc.Save == nil {
+			continue
+		}
+		b.doc.Place = b.outline.place()
+		b.doc.Marks = b.marks
+		b.doc.Save(b.doc)
+	}
+}
+
+// run shows the buffers until the reader is closed, then saves their places
+func (bl *bufferList) run() error {
+	bl.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		w, _ := screen.Size()
+		bl.sync(w)
+		return false
+	})
+	bl.app.SetAfterDrawFunc(func(screen tcell.Screen) {
+		bl.buffers[bl.current].drawFrozen(screen)
+	})
+	if err := bl.app.SetRoot(bl.root, true).Run(); err != nil {
+		return err
+	}
+	bl.save()
+	return nil
+}
@@ -0,0 +1,239 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/wildreason/reader/parse"
+)
+
+// Command represents a parsed user command
+type Command struct {
+	Action string // next, prev, quit
+	Arg    string
+}
+
+// Navigator manages navigation state
+type Navigator struct {
+	index       *BlockIndex
+	currentPos  int // Position in block list
+	currentPage int // Current page within block (0-indexed)
+	history     []int
+	maxHistory  int
+}
+
+// NewNavigator creates a new navigator
+func NewNavigator(index *BlockIndex) *Navigator {
+	return &Navigator{
+		index:       index,
+		currentPos:  0,
+		currentPage: 0,
+		history:     []int{},
+		maxHistory:  10,
+	}
+}
+
+// ExecuteCommand processes a command and returns the result
+func (nav *Navigator) ExecuteCommand(cmd *Command) (string, *parse.Block, bool) {
+	if cmd == nil {
+		return "", nil, false
+	}
+
+	switch cmd.Action {
+	case "next":
+		return nav.handleNext()
+	case "prev":
+		return nav.handlePrev()
+	case "quit", "exit":
+		return "", nil, true
+	default:
+		return "", nil, false
+	}
+}
+
+// handleNext jumps to the next block
+func (nav *Navigator) handleNext() (string, *parse.Block, bool) {
+	if nav.currentPos+1 >= len(nav.index.blocks) {
+		return "Already at the last block.", nil, false
+	}
+
+	nav.saveHistory(nav.currentPos)
+	nav.currentPos++
+	nav.currentPage = 0
+	block := nav.index.GetBlockByPosition(nav.currentPos)
+
+	return "", block, false
+}
+
+// handlePrev jumps to the previous block
+func (nav *Navigator) handlePrev() (string, *parse.Block, bool) {
+	if nav.currentPos <= 0 {
+		return "Already at the first block.", nil, false
+	}
+
+	nav.saveHistory(nav.currentPos)
+	nav.currentPos--
+	nav.currentPage = 0
+	block := nav.index.GetBlockByPosition(nav.currentPos)
+
+	return "", block, false
+}
+
+// saveHistory saves current position to history
+func (nav *Navigator) saveHistory(pos int) {
+	nav.history = append(nav.history, pos)
+	if len(nav.history) > nav.maxHistory {
+		nav.history = nav.history[1:]
+	}
+}
+
+// GetCurrentBlock returns the current block
+func (nav *Navigator) GetCurrentBlock() *parse.Block {
+	return nav.index.GetBlockByPosition(nav.currentPos)
+}
+
+// GetCurrentPosition returns current position in document
+func (nav *Navigator) GetCurrentPosition() int {
+	return nav.currentPos
+}
+
+// GetTotalBlocks returns total number of blocks
+func (nav *Navigator) GetTotalBlocks() int {
+	return len(nav.index.blocks)
+}
+
+// GetCurrentPage returns the current page (0-indexed)
+func (nav *Navigator) GetCurrentPage() int {
+	return nav.currentPage
+}
+
+// NextPage moves to the next page within current block
+func (nav *Navigator) NextPage() bool {
+	block := nav.GetCurrentBlock()
+	if block == nil {
+		return false
+	}
+
+	if nav.currentPage+1 >= block.TotalPages {
+		return false
+	}
+
+	nav.currentPage++
+	return true
+}
+
+// PrevPage moves to the previous page within current block
+func (nav *Navigator) PrevPage() bool {
+	if nav.currentPage <= 0 {
+		return false
+	}
+
+	nav.currentPage--
+	return true
+}
+
+// BlockIndex maps block names to blocks for quick lookup
+type BlockIndex struct {
+	blocks    []parse.Block
+	nameIndex map[string]int
+}
+
+// NewBlockIndex creates an index from blocks
+func NewBlockIndex(blocks []parse.Block) *BlockIndex {
+	index := &BlockIndex{
+		blocks:    blocks,
+		nameIndex: make(map[string]int),
+	}
+
+	// Build name index (case-insensitive for easier lookup)
+	for i, block := range blocks {
+		lowerName := strings.ToLower(block.Name)
+		index.nameIndex[lowerName] = i
+	}
+
+	return index
+}
+
+// FindBlock looks up a block by name: an exact match, then the first name
+// containing the query, then the first with its characters in order
+func (bi *BlockIndex) FindBlock(query string) *parse.Block {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	// Exact match first
+	if idx, ok := bi.nameIndex[query]; ok {
+		return &bi.blocks[idx]
+	}
+
+	// Fuzzy match: find blocks that contain the query
+	var matches []int
+	for i, block := range bi.blocks {
+		if strings.Contains(strings.ToLower(block.Name), query) {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) > 0 {
+		// Return the first (best) match
+		return &bi.blocks[matches[0]]
+	}
+
+	// Loosest match: the query's characters appear in order, so "dsgn"
+	// finds "Design"
+	for i, block := range bi.blocks {
+		if isSubsequence(query, strings.ToLower(block.Name)) {
+			return &bi.blocks[i]
+		}
+	}
+
+	return nil
+}
+
+// isSubsequence reports whether the characters of query appear in s in order
+func isSubsequence(query, s string) bool {
+	for _, r := range query {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// GetBlockByPosition returns block at given position in document
+func (bi *BlockIndex) GetBlockByPosition(pos int) *parse.Block {
+	if pos >= 0 && pos < len(bi.blocks) {
+		return &bi.blocks[pos]
+	}
+	return nil
+}
+
+// NextBlock returns the next block after the given block name
+func (bi *BlockIndex) NextBlock(currentName string) *parse.Block {
+	currentName = strings.ToLower(currentName)
+	if idx, ok := bi.nameIndex[currentName]; ok {
+		if idx+1 < len(bi.blocks) {
+			return &bi.blocks[idx+1]
+		}
+	}
+	return nil
+}
+
+// PrevBlock returns the previous block before the given block name
+func (bi *BlockIndex) PrevBlock(currentName string) *parse.Block {
+	currentName = strings.ToLower(currentName)
+	if idx, ok := bi.nameIndex[currentName]; ok {
+		if idx > 0 {
+			return &bi.blocks[idx-1]
+		}
+	}
+	return nil
+}
+
+// GetAllBlockNames returns a list of all block names
+func (bi *BlockIndex) GetAllBlockNames() []string {
+	names := make([]string, len(bi.blocks))
+	for i, block := range bi.blocks {
+		names[i] = block.Name
+	}
+	return names
+}
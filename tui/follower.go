@@ -1,4 +1,4 @@
-package main
+package tui
 
 import (
 	"fmt"
@@ -9,10 +9,14 @@ import (
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+
+	"github.com/wildreason/reader/render/term"
 )
 
 // watchFile monitors a JSONL file for new content and parses new blocks
-func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, navigator *Navigator, onNewBlock func(), stopCh <-chan struct{}) {
+func watchFile(filePath string, jsonlParser *parse.JSONLParser, index *BlockIndex, navigator *Navigator, onNewBlock func(), stopCh <-chan struct{}) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return
@@ -28,7 +32,7 @@ func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, nav
 	buf := make([]byte, 4096)
 	var partial string
 	turnNumber := len(index.blocks)
-	var currentTurn *ConversationTurn
+	var currentTurn *parse.ConversationTurn
 	var currentBlockIdx int = -1
 
 	showUser := jsonlParser.Filters["user"]
@@ -95,12 +99,12 @@ func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, nav
 				if currentTurn != nil {
 					needsRebuild := false
 
-					if showDiff && hasStructuredPatch(msg) {
-						diffContent := extractStructuredPatch(msg)
+					if showDiff && parse.HasStructuredPatch(msg) {
+						diffContent := parse.ExtractStructuredPatch(msg)
 						if diffContent != "" {
 							toolUseResult, _ := msg["toolUseResult"].(map[string]interface{})
 							fp, _ := toolUseResult["filePath"].(string)
-							currentTurn.Parts = append(currentTurn.Parts, TurnPart{
+							currentTurn.Parts = append(currentTurn.Parts, parse.TurnPart{
 								Type:    "diff",
 								Content: diffContent,
 								Meta:    fp,
@@ -112,7 +116,7 @@ func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, nav
 					if showToolResult {
 						toolContent := jsonlParser.ExtractToolResultContent(msg)
 						if toolContent != "" {
-							currentTurn.Parts = append(currentTurn.Parts, TurnPart{
+							currentTurn.Parts = append(currentTurn.Parts, parse.TurnPart{
 								Type:    "tool_result",
 								Content: toolContent,
 							})
@@ -132,8 +136,8 @@ func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, nav
 				userContent := jsonlParser.ExtractUserContent(msg)
 				if userContent != "" {
 					turnNumber++
-					currentTurn = &ConversationTurn{
-						Parts:   []TurnPart{{Type: "user", Content: userContent}},
+					currentTurn = &parse.ConversationTurn{
+						Parts:   []parse.TurnPart{{Type: "user", Content: userContent}},
 						LineNum: 0,
 					}
 					newBlock := jsonlParser.CreateTurnBlock(currentTurn, turnNumber)
@@ -149,7 +153,7 @@ func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, nav
 			if msgType == "assistant" && showAssistant && currentTurn != nil {
 				assistantContent := jsonlParser.ExtractAssistantContent(msg)
 				if assistantContent != "" {
-					currentTurn.Parts = append(currentTurn.Parts, TurnPart{
+					currentTurn.Parts = append(currentTurn.Parts, parse.TurnPart{
 						Type:    "assistant",
 						Content: assistantContent,
 					})
@@ -161,8 +165,8 @@ func watchFile(filePath string, jsonlParser *JSONLParser, index *BlockIndex, nav
 }
 
 // watchGenericFile monitors any file for changes and reloads it
-func watchGenericFile(filePath string, onReload func([]Block), stopCh <-chan struct{}) {
-	parser := detectParser(filePath)
+func watchGenericFile(filePath string, onReload func([]parse.Block), stopCh <-chan struct{}) {
+	parser := parse.DetectParser(filePath)
 	var lastModTime time.Time
 
 	for {
@@ -195,20 +199,20 @@ func watchGenericFile(filePath string, onReload func([]Block), stopCh <-chan str
 	}
 }
 
-// runFollowMode runs the follow mode TUI
-func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth int, style string, borderStyle BorderStyle) {
-	var blocks []Block
+// Follow runs the follow mode TUI, re-rendering as the file changes.
+// jsonlFilters is non-nil for JSONL transcripts, which are tailed turn by turn.
+func Follow(filePath string, fileContent string, jsonlFilters map[string]bool, termWidth int, style string, borderStyle term.BorderStyle) {
+	var blocks []parse.Block
 	var index *BlockIndex
-	var jsonlParser *JSONLParser
+	var jsonlParser *parse.JSONLParser
+	isJSONL := jsonlFilters != nil
 
 	// Parse initial blocks
 	if isJSONL {
-		jsonlParser = &JSONLParser{}
-		filters := showContentSelector(fileContent)
-		jsonlParser.Filters = filters
+		jsonlParser = &parse.JSONLParser{Filters: jsonlFilters}
 		blocks = jsonlParser.Parse(fileContent)
 	} else {
-		parser := detectParser(filePath)
+		parser := parse.DetectParser(filePath)
 		blocks = parser.Parse(fileContent)
 	}
 
@@ -221,6 +225,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 	navigator := NewNavigator(index)
 
 	// Start TUI
+	setStyles()
 	app := tview.NewApplication()
 	textView := tview.NewTextView().
 		SetDynamicColors(true).
@@ -233,7 +238,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 	currentBlock := navigator.GetCurrentBlock()
 	if currentBlock != nil {
 		navigator.currentPage = currentBlock.TotalPages - 1
-		rendered := FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
+		rendered := term.FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
 		textView.SetText(tview.TranslateANSI(rendered))
 	}
 
@@ -246,7 +251,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 			currentBlock := navigator.GetCurrentBlock()
 			if currentBlock != nil {
 				navigator.currentPage = currentBlock.TotalPages - 1
-				rendered := FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
+				rendered := term.FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
 				textView.SetText(tview.TranslateANSI(rendered))
 			}
 		})
@@ -256,7 +261,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 		if isJSONL {
 			go watchFile(filePath, jsonlParser, index, navigator, onNewBlock, fileWatcherStop)
 		} else {
-			go watchGenericFile(filePath, func(newBlocks []Block) {
+			go watchGenericFile(filePath, func(newBlocks []parse.Block) {
 				app.QueueUpdateDraw(func() {
 					index.blocks = newBlocks
 					index.nameIndex = make(map[string]int)
@@ -267,7 +272,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 					navigator.currentPage = 0
 					currentBlock := navigator.GetCurrentBlock()
 					if currentBlock != nil {
-						rendered := FormatBlockPlain(currentBlock, termWidth, style, borderStyle)
+						rendered := term.FormatBlockPlain(currentBlock, termWidth, style, borderStyle)
 						textView.SetText(tview.TranslateANSI(rendered))
 					}
 				})
@@ -286,7 +291,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 			navigator.currentPage = 0
 			currentBlock := navigator.GetCurrentBlock()
 			if currentBlock != nil {
-				rendered := FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
+				rendered := term.FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
 				textView.SetText(tview.TranslateANSI(rendered))
 				textView.ScrollToBeginning()
 			}
@@ -296,7 +301,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 			navigator.currentPage = 0
 			currentBlock := navigator.GetCurrentBlock()
 			if currentBlock != nil {
-				rendered := FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
+				rendered := term.FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
 				textView.SetText(tview.TranslateANSI(rendered))
 				textView.ScrollToBeginning()
 			}
@@ -311,7 +316,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 			navigator.currentPage = 0
 			currentBlock := navigator.GetCurrentBlock()
 			if currentBlock != nil {
-				rendered := FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
+				rendered := term.FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
 				textView.SetText(tview.TranslateANSI(rendered))
 				textView.ScrollToBeginning()
 			}
@@ -321,7 +326,7 @@ func runFollowMode(filePath string, fileContent string, isJSONL bool, termWidth
 			navigator.currentPage = 0
 			currentBlock := navigator.GetCurrentBlock()
 			if currentBlock != nil {
-				rendered := FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
+				rendered := term.FormatBlockPage(currentBlock, navigator.GetCurrentPage(), termWidth, borderStyle)
 				textView.SetText(tview.TranslateANSI(rendered))
 				textView.ScrollToBeginning()
 			}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// defaultKeys lists the reader's actions with the keys bound to them. Keys
+// are single characters, tcell key names in lower case (pgdn, tab, ctrl-d,
+// ...), or two characters typed one after the other, like gt.
+var defaultKeys = map[string][]string{
+	"scroll_down":     {"j", "J"},
+	"scroll_up":       {"k", "K"},
+	"half_page_down":  {"d"},
+	"half_page_up":    {"u"},
+	"page_down":       {"pgdn"},
+	"page_up":         {"pgup"},
+	"scroll_left":     {"h", "left"},
+	"scroll_right":    {"l", "right"},
+	"line_start":      {"0"},
+	"line_end":        {"$"},
+	"wrap":            {"w"},
+	"top":             {"g"},
+	"bottom":          {"G"},
+	"search":          {"/"},
+	"search_backward": {"?"},
+	"next_match":      {"n"},
+	"prev_match":      {"N"},
+	"mark":            {"m"},
+	"jump_mark":       {"'"},
+	"outline":         {"o"},
+	"prompt":          {":"},
+	"next_buffer":     {"gt"},
+	"prev_buffer":     {"gT"},
+	"next_link":       {"tab"},
+	"prev_link":       {"backtab"},
+	"open_link":       {"enter"},
+	"quit":            {"q", "Q"},
+}
+
+// keymap maps keys to reader actions
+var keymap = bindKeys(defaultKeys)
+
+// bindKeys returns the keymap for a set of bindings
+func bindKeys(bindings map[string][]string) map[string]string {
+	m := make(map[string]string)
+	for action, keys := range bindings {
+		for _, key := range keys {
+			m[key] = action
+		}
+	}
+	return m
+}
+
+// SetKeys remaps reader actions: each action given is bound to its keys
+// instead of the defaults, and an empty list unbinds it. A key taken from
+// another action moves to the new one.
+func SetKeys(bindings map[string][]string) error {
+	merged := make(map[string][]string, len(defaultKeys))
+	for action, keys := range defaultKeys {
+		merged[action] = keys
+	}
+	for action, keys := range bindings {
+		if _, ok := defaultKeys[action]; !ok {
+			return fmt.Errorf("unknown key action %q", action)
+		}
+		for _, key := range keys {
+			if !validKey(key) {
+				return fmt.Errorf("unknown key %q for %s", key, action)
+			}
+		}
+		merged[action] = nil
+	}
+	// Remapped actions are bound last so they take their keys from others
+	m := bindKeys(merged)
+	for action, keys := range bindings {
+		for _, key := range keys {
+			m[key] = action
+		}
+	}
+	keymap = m
+	return nil
+}
+
+// validKey reports whether key is a character, a key name or two
+// characters
+func validKey(key string) bool {
+	n := utf8.RuneCountInString(key)
+	return n == 1 || n == 2 || isKeyName(key)
+}
+
+// isKeyName reports whether key is the name of a special key
+func isKeyName(key string) bool {
+	for _, name := range tcell.KeyNames {
+		if key == strings.ToLower(name) {
+			return true
+		}
+	}
+	return key == "space"
+}
+
+// keyName returns the name of a key press as used in the keymap
+func keyName(ev *tcell.EventKey) string {
+	if ev.Key() != tcell.KeyRune {
+		return strings.ToLower(tcell.KeyNames[ev.Key()])
+	}
+	if ev.Rune() == ' ' {
+		return "space"
+	}
+	return string(ev.Rune())
+}
+
+// isKeyPrefix reports whether key starts a two key binding
+func isKeyPrefix(key string) bool {
+	for k := range keymap {
+		if utf8.RuneCountInString(k) == 2 && !isKeyName(k) && strings.HasPrefix(k, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/render/term"
+)
+
+// linkNavigator selects the links on screen with Tab and opens them with
+// Enter: local files in a nested reader, everything else with the system
+// opener
+type linkNavigator struct {
+	app     *tview.Application
+	text    *tview.TextView
+	baseDir string   // directory relative links resolve against
+	flags   []string // flags passed on to nested readers
+
+	// openBuffer opens a local file in a new buffer, scrolled to the
+	// heading named by fragment, reporting whether it could. Files are
+	// opened in a nested reader when it is nil or fails.
+	openBuffer func(path, fragment string) bool
+}
+
+// newLinkNavigator returns a link navigator for a text view showing
+// sourceName
+func newLinkNavigator(app *tview.Application, text *tview.TextView, sourceName string, showLineNums bool) *linkNavigator {
+	ln := &linkNavigator{app: app, text: text, flags: []string{"-t"}}
+	if sourceName != "stdin" {
+		if dir, err := filepath.Abs(filepath.Dir(sourceName)); err == nil {
+			ln.baseDir = dir
+		}
+	} else if dir, err := os.Getwd(); err == nil {
+		ln.baseDir = dir
+	}
+	if showLineNums {
+		ln.flags = append(ln.flags, "-n")
+	}
+	return ln
+}
+
+// renderSettings returns the settings buffers render the document with:
+// hyperlinks if the terminal supports them, and images drawn in place,
+// both resolving relative paths against the document's directory
+func (ln *linkNavigator) renderSettings() term.Settings {
+	return term.Settings{
+		Hyperlinks:   hyperlinksSupported(),
+		LinkBase:     ln.baseDir,
+		InlineImages: true,
+	}
+}
+
+// cycle highlights the next (or previous) link on screen, wrapping around
+func (ln *linkNavigator) cycle(forward bool) {
+	top, _ := ln.text.GetScrollOffset()
+	_, _, _, height := ln.text.GetInnerRect()
+	var visible []term.Link
+	for _, l := range term.FindLinks(ln.text.GetText(false)) {
+		if l.Line >= top && l.Line < top+height {
+			visible = append(visible, l)
+		}
+	}
+	if len(visible) == 0 {
+		return
+	}
+
+	current := -1
+	if highlights := ln.text.GetHighlights(); len(highlights) > 0 {
+		for i, l := range visible {
+			if l.Region == highlights[0] {
+				current = i
+			}
+		}
+	}
+	switch {
+	case forward:
+		current = (current + 1) % len(visible)
+	case current <= 0:
+		current = len(visible) - 1
+	default:
+		current--
+	}
+	ln.text.Highlight(visible[current].Region)
+}
+
+// clear removes the link highlight, reporting whether there was one
+func (ln *linkNavigator) clear() bool {
+	if len(ln.text.GetHighlights()) == 0 {
+		return false
+	}
+	ln.text.Highlight()
+	return true
+}
+
+// open opens the highlighted link, reporting whether one was highlighted
+func (ln *linkNavigator) open() bool {
+	highlights := ln.text.GetHighlights()
+	if len(highlights) == 0 {
+		return false
+	}
+	for _, l := range term.FindLinks(ln.text.GetText(false)) {
+		if l.Region != highlights[0] {
+			continue
+		}
+		if path, ok := ln.localFile(l.Target); ok {
+			_, fragment, _ := strings.Cut(l.Target, "#")
+			if ln.openBuffer != nil && ln.openBuffer(path, fragment) {
+				break
+			}
+			ln.app.Suspend(func() {
+				exe, err := os.Executable()
+				if err != nil {
+					return
+				}
+				cmd := exec.Command(exe, append(ln.flags, path)...)
+				cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+				cmd.Run()
+			})
+		} else if !strings.HasPrefix(l.Target, "#") {
+			openExternal(ln.resolve(l.Target))
+		}
+		break
+	}
+	return true
+}
+
+// resolve returns a link target as a URL or absolute path
+func (ln *linkNavigator) resolve(target string) string {
+	if u, err := url.Parse(target); err == nil && len(u.Scheme) > 1 {
+		return target
+	}
+	path, _, _ := strings.Cut(target, "#")
+	path, _, _ = strings.Cut(path, "?")
+	if p, err := url.PathUnescape(path); err == nil {
+		path = p
+	}
+	if !filepath.IsAbs(path) && ln.baseDir != "" {
+		path = filepath.Join(ln.baseDir, path)
+	}
+	return path
+}
+
+// localFile returns the path of a link to a local file, which is followed
+// in a nested reader rather than opened externally
+func (ln *linkNavigator) localFile(target string) (string, bool) {
+	path := target
+	if u, err := url.Parse(target); err == nil && len(u.Scheme) > 1 {
+		if u.Scheme != "file" {
+			return "", false
+		}
+		path = u.Path
+	} else {
+		if strings.HasPrefix(target, "#") {
+			return "", false
+		}
+		path = ln.resolve(target)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		return path, true
+	}
+	return "", false
+}
+
+// openExternal opens a URL or path with the system's default application
+func openExternal(target string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	cmd.Start()
+}
+
+// hyperlinksSupported reports whether the terminal shows OSC 8 hyperlinks.
+// FORCE_HYPERLINK=1 or 0 overrides detection.
+func hyperlinksSupported() bool {
+	if v, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		return v != "" && v != "0"
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby", "rio":
+		return true
+	}
+	for _, env := range []string{"KITTY_WINDOW_ID", "WT_SESSION", "KONSOLE_VERSION", "WEZTERM_EXECUTABLE"} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	for _, name := range []string{"kitty", "alacritty", "foot", "wezterm", "ghostty"} {
+		if strings.Contains(os.Getenv("TERM"), name) {
+			return true
+		}
+	}
+	return false
+}
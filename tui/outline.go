@@ -0,0 +1,277 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+)
+
+// outlineWidth is the width of the outline panel
+const outlineWidth = 32
+
+// outline is the side panel listing the document's headings. It follows
+// the section in view, and selecting a heading scrolls to it. The go to
+// heading prompt jumps to the best fuzzy match as its query is typed.
+type outline struct {
+	app    *tview.Application
+	text   *tview.TextView
+	body   *tview.Flex // holds the panel and text side by side
+	layout *tview.Flex // holds the go to heading prompt below the body
+	list   *tview.List
+	prompt *tview.InputField
+
+	headings []term.Heading
+	index    *BlockIndex // headings by title; LineNum is the heading's position
+	stale    bool        // text changed since headings were found
+	visible  bool
+	origin   int // first line on screen when the prompt opened
+
+	// command runs prompt input that isn't a heading when run is set,
+	// reporting whether it is a command
+	command func(input string, run bool) bool
+}
+
+// newOutline returns the outline of a text view, shown in body beside it
+func newOutline(app *tview.Application, text *tview.TextView, body, layout *tview.Flex) *outline {
+	o := &outline{app: app, text: text, body: body, layout: layout, stale: true}
+	o.list = tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedStyle(selectedStyle()).
+		SetSelectedFunc(func(i int, _, _ string, _ rune) {
+			o.jump(i)
+			o.app.SetFocus(o.text)
+		})
+	o.list.SetBorder(true).SetTitle(" Outline ")
+	o.list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEscape {
+			o.toggle()
+			return nil
+		}
+		switch keymap[keyName(ev)] {
+		case "outline":
+			o.toggle()
+			return nil
+		case "scroll_down":
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		case "scroll_up":
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		case "quit":
+			o.app.Stop()
+			return nil
+		}
+		return ev
+	})
+	o.prompt = tview.NewInputField().
+		SetLabel(":").
+		SetLabelColor(themeColor(theme.Prompt)).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetChangedFunc(o.preview).
+		SetDoneFunc(o.promptDone)
+	return o
+}
+
+// changed marks the headings for finding again after the text changes
+func (o *outline) changed() {
+	o.stale = true
+}
+
+// refresh finds the headings in the text view and lists them, indented by
+// level
+func (o *outline) refresh() {
+	if !o.stale {
+		return
+	}
+	o.stale = false
+	o.headings = term.FindHeadings(o.text.GetText(false))
+	blocks := make([]parse.Block, len(o.headings))
+	selected := o.list.GetCurrentItem()
+	o.list.Clear()
+	for i, h := range o.headings {
+		blocks[i] = parse.Block{Name: h.Title, LineNum: i}
+		o.list.AddItem(strings.Repeat("  ", h.Level-1)+tview.Escape(h.Title), "", 0, nil)
+	}
+	o.index = NewBlockIndex(blocks)
+	if len(o.headings) == 0 {
+		o.list.AddItem(theme.Tag(theme.Muted)+"No headings[-]", "", 0, nil)
+	}
+	o.list.SetCurrentItem(selected)
+}
+
+// toggle shows the outline panel and focuses it, or hides it
+func (o *outline) toggle() {
+	o.visible = !o.visible
+	if !o.visible {
+		o.body.RemoveItem(o.list)
+		o.app.SetFocus(o.text)
+		return
+	}
+	o.refresh()
+	o.body.RemoveItem(o.text)
+	o.body.AddItem(o.list, outlineWidth, 0, true).AddItem(o.text, 0, 1, false)
+	o.follow()
+	o.app.SetFocus(o.list)
+}
+
+// current returns the position of the heading of the section in view, or
+// -1 above the first heading
+func (o *outline) current() int {
+	row, _ := o.text.GetScrollOffset()
+	current := -1
+	for i, h := range o.headings {
+		if h.Line > row {
+			break
+		}
+		current = i
+	}
+	return current
+}
+
+// position returns the section in view and how many lines into it the view
+// is scrolled, for restore
+func (o *outline) position() (section, offset int) {
+	o.refresh()
+	row, _ := o.text.GetScrollOffset()
+	section = o.current()
+	if section < 0 {
+		return -1, row
+	}
+	return section, row - o.headings[section].Line
+}
+
+// restore scrolls back to a position from position after the text is
+// rendered again, perhaps at another width
+func (o *outline) restore(section, offset int) {
+	o.refresh()
+	row := offset
+	if section >= 0 && section < len(o.headings) {
+		row += o.headings[section].Line
+	}
+	o.text.ScrollTo(row, 0)
+}
+
+// Place is a position in a document that outlasts rendering at another
+// width, or editing the document: the section in view and how many lines
+// into it the view is scrolled
+type Place struct {
+	Heading string `json:"heading,omitempty"` // Title of the section's heading, "" above the first
+	Section int    `json:"section"`           // Which heading it is, to tell repeated titles apart
+	Offset  int    `json:"offset"`            // Lines scrolled past the heading, or the top
+}
+
+// place returns the place in view
+func (o *outline) place() Place {
+	section, offset := o.position()
+	p := Place{Section: section, Offset: offset}
+	if section >= 0 {
+		p.Heading = o.headings[section].Title
+	}
+	return p
+}
+
+// restorePlace scrolls to a place. Its heading is looked for by title when
+// the document changed and it moved; when it is gone the view stays put.
+func (o *outline) restorePlace(p Place) {
+	if row, ok := o.placeRow(p); ok {
+		o.text.ScrollTo(row, 0)
+	}
+}
+
+// placeRow returns the line a place is at, or false when its heading is
+// gone
+func (o *outline) placeRow(p Place) (int, bool) {
+	o.refresh()
+	section := -1
+	if p.Heading != "" {
+		section = p.Section
+		if section < 0 || section >= len(o.headings) || o.headings[section].Title != p.Heading {
+			section = -1
+			for i, h := range o.headings {
+				if h.Title == p.Heading {
+					section = i
+					break
+				}
+			}
+			if section < 0 {
+				return 0, false
+			}
+		}
+	}
+	row := p.Offset
+	if section >= 0 {
+		row += o.headings[section].Line
+	}
+	return row, true
+}
+
+// follow selects the section in view, unless the panel is being used
+func (o *outline) follow() {
+	if !o.visible || o.list.HasFocus() {
+		return
+	}
+	o.refresh()
+	if i := o.current(); i >= 0 {
+		o.list.SetCurrentItem(i)
+	}
+}
+
+// jump scrolls the heading at position i to the top of the text view
+func (o *outline) jump(i int) {
+	if i < 0 || i >= len(o.headings) {
+		return
+	}
+	_, col := o.text.GetScrollOffset()
+	o.text.ScrollTo(o.headings[i].Line, col)
+}
+
+// goTo opens the go to heading prompt, which also takes commands
+func (o *outline) goTo() {
+	o.refresh()
+	o.origin, _ = o.text.GetScrollOffset()
+	o.layout.AddItem(o.prompt, 1, 0, false)
+	o.prompt.SetText("")
+	o.app.SetFocus(o.prompt)
+}
+
+// jumpTo scrolls to the heading best matching query, reporting whether
+// there is one
+func (o *outline) jumpTo(query string) bool {
+	o.refresh()
+	block := o.index.FindBlock(query)
+	if block == nil {
+		return false
+	}
+	o.jump(block.LineNum)
+	return true
+}
+
+// preview jumps to the heading best matching the query as it is typed.
+// Commands wait for Enter.
+func (o *outline) preview(query string) {
+	if o.command != nil && o.command(query, false) {
+		return
+	}
+	if strings.TrimSpace(query) == "" || !o.jumpTo(query) {
+		_, col := o.text.GetScrollOffset()
+		o.text.ScrollTo(o.origin, col)
+	}
+}
+
+// promptDone closes the prompt: Enter stays at the heading found or runs
+// the command, Escape goes back to where the prompt was opened
+func (o *outline) promptDone(key tcell.Key) {
+	if key == tcell.KeyEscape {
+		_, col := o.text.GetScrollOffset()
+		o.text.ScrollTo(o.origin, col)
+	}
+	o.layout.RemoveItem(o.prompt)
+	o.app.SetFocus(o.text)
+	if key == tcell.KeyEnter && o.command != nil {
+		o.command(o.prompt.GetText(), true)
+	}
+}
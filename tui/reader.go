@@ -0,0 +1,191 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	xterm "golang.org/x/term"
+
+	"github.com/wildreason/reader/render/term"
+)
+
+// Run runs the static reader TUI (non-follow mode) with a buffer for each
+// document. Files opened from links or the : prompt are read with open,
+// which may be nil. Parse diagnostics are listed in a status area below
+// each document's content. Documents open at their Place, and are saved
+// with Save when the reader closes.
+func Run(docs []*Document, open OpenFunc, termWidth int, style string, borderStyle term.BorderStyle, showLineNums bool) {
+	var shown []*Document
+	for _, doc := range docs {
+		if len(doc.Blocks) == 0 {
+			printDiagnostics(os.Stderr, doc.Name, doc.Diags)
+			fmt.Println("Error: No blocks found in file.")
+			continue
+		}
+		shown = append(shown, doc)
+	}
+	if len(shown) == 0 {
+		return
+	}
+
+	// Pipe passthrough: if stdout is not a terminal, print plain text and exit
+	if !xterm.IsTerminal(int(os.Stdout.Fd())) {
+		for _, doc := range shown {
+			content := term.FormatBlocks(doc.Blocks, termWidth, borderStyle)
+			stripped := term.StripTviewTags(content)
+			fmt.Print(stripped)
+			printDiagnostics(os.Stderr, doc.Name, doc.Diags)
+		}
+		return
+	}
+
+	// Each buffer renders itself at the screen width when first drawn and
+	// when the width changes
+	setStyles()
+	app := tview.NewApplication()
+	buffers := newBufferList(app, open, borderStyle, showLineNums)
+	for _, doc := range shown {
+		buffers.add(doc)
+	}
+	buffers.show(0)
+
+	if err := buffers.run(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// sideStep is how many columns the text scrolls sideways at a time
+const sideStep = 8
+
+// readerKeys returns the reader key handling for a buffer, running the
+// action bound to each key in the keymap: scrolling, search, the outline and
+// : prompt, bookmarks, switching buffers and wrapping, selecting and
+// opening links, and quitting. A bookmark is set, or jumped to, with a
+// letter after the mark (or jump_mark) key.
+// Escape ends a search or link selection, or quits. buffers is nil when
+// there is only ever one buffer.
+func readerKeys(app *tview.Application, b *buffer, buffers *bufferList) func(ev *tcell.EventKey) *tcell.EventKey {
+	text, links, search, outline := b.text, b.links, b.search, b.outline
+	scroll := func(lines int) {
+		row, col := text.GetScrollOffset()
+		text.ScrollTo(max(row+lines, 0), col)
+	}
+	// The text view stops at the end of the longest line when drawn
+	scrollSideways := func(columns int) {
+		row, col := text.GetScrollOffset()
+		text.ScrollTo(row, min(max(col+columns, 0), 1<<30))
+	}
+	// A key starting a two key binding still runs its own action; the
+	// second key undoes its scrolling before running the binding's
+	pending, rowBeforePending := "", 0
+	marking := "" // mark or jump_mark, waiting for the bookmark's letter
+	return func(ev *tcell.EventKey) *tcell.EventKey {
+		b.clearMessage()
+		if marking != "" {
+			action := marking
+			marking = ""
+			if ev.Key() != tcell.KeyRune || ev.Rune() < 'a' || ev.Rune() > 'z' {
+				return nil // Any other key cancels
+			}
+			letter := string(ev.Rune())
+			if action == "mark" {
+				b.marks[letter] = outline.place()
+			} else if p, ok := b.marks[letter]; ok {
+				outline.restorePlace(p)
+			} else if buffers != nil {
+				buffers.showMessage("No mark " + letter)
+			} else {
+				b.showMessage("No mark " + letter)
+			}
+			return nil
+		}
+
+		key := keyName(ev)
+		action, ok := keymap[key]
+		if seqAction, isSeq := keymap[pending+key]; pending != "" && isSeq {
+			_, col := text.GetScrollOffset()
+			text.ScrollTo(rowBeforePending, col)
+			action, ok = seqAction, true
+			pending = ""
+		} else if isKeyPrefix(key) {
+			pending = key
+			rowBeforePending, _ = text.GetScrollOffset()
+		} else {
+			pending = ""
+		}
+
+		if !ok {
+			switch ev.Key() {
+			case tcell.KeyEscape: // End the search, clear the link selection, or quit
+				if !search.clear() && !links.clear() {
+					app.Stop()
+				}
+				return nil
+			case tcell.KeyCtrlC:
+				app.Stop()
+				return nil
+			}
+			return ev
+		}
+
+		_, _, _, height := text.GetInnerRect()
+		switch action {
+		case "scroll_down":
+			scroll(3)
+		case "scroll_up":
+			scroll(-3)
+		case "half_page_down":
+			scroll(height / 2)
+		case "half_page_up":
+			scroll(-height / 2)
+		case "page_down":
+			scroll(height)
+		case "page_up":
+			scroll(-height)
+		case "scroll_left":
+			scrollSideways(-sideStep)
+		case "scroll_right":
+			scrollSideways(sideStep)
+		case "line_start":
+			scrollSideways(-1 << 30)
+		case "line_end":
+			scrollSideways(1 << 30)
+		case "wrap":
+			if b.toggleWrap != nil {
+				b.toggleWrap()
+			}
+		case "top":
+			text.ScrollToBeginning()
+		case "bottom":
+			text.ScrollToEnd()
+		case "search", "search_backward":
+			search.start(action == "search_backward")
+		case "next_match":
+			search.next(false)
+		case "prev_match":
+			search.next(true)
+		case "mark", "jump_mark":
+			marking = action
+		case "outline":
+			outline.toggle()
+		case "prompt": // Go to heading or run a command
+			outline.goTo()
+		case "next_buffer", "prev_buffer":
+			if buffers != nil {
+				buffers.cycle(action == "next_buffer")
+			}
+		case "next_link":
+			links.cycle(true)
+		case "prev_link":
+			links.cycle(false)
+		case "open_link":
+			links.open()
+		case "quit":
+			app.Stop()
+		}
+		return nil
+	}
+}
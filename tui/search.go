@@ -0,0 +1,331 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+)
+
+// searchMatch is a search hit in the displayed text
+type searchMatch struct {
+	line       int // 0-based line
+	start, end int // byte offsets in the displayed text
+}
+
+// regionRegex matches tview region tags, capturing the region ID
+var regionRegex = regexp.MustCompile(`\["([a-zA-Z0-9_,;: .-]*)"\]`)
+
+// searcher searches the displayed text as the query is typed: / searches
+// forward and ? backward from the top of the screen, n and N move between
+// matches. Every match is shown in reverse video; the current one is also
+// underlined. The search bar below the text shows the match count.
+type searcher struct {
+	app    *tview.Application
+	text   *tview.TextView
+	layout *tview.Flex
+	bar    *tview.Flex
+	input  *tview.InputField
+	count  *tview.TextView
+
+	content string          // text view content, without search regions
+	buf     strings.Builder // content, appended to as it streams in
+	lines   int             // lines in content
+	plain   string          // content as displayed, computed when searching
+	offsets []int           // offset in content of each byte of plain
+	stale   bool            // plain needs computing
+
+	active   bool // search bar is shown
+	backward bool // searching with ?
+	query    string
+	matches  []searchMatch
+	current  int
+	origin   int // first line on screen when the search started
+}
+
+// newSearcher returns a searcher for a text view, which adds its search bar
+// to layout while searching
+func newSearcher(app *tview.Application, text *tview.TextView, layout *tview.Flex) *searcher {
+	s := &searcher{app: app, text: text, layout: layout}
+	s.input = tview.NewInputField().
+		SetLabelColor(themeColor(theme.Prompt)).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetChangedFunc(s.update).
+		SetDoneFunc(s.done)
+	s.count = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignRight)
+	s.bar = tview.NewFlex().
+		AddItem(s.input, 0, 1, true).
+		AddItem(s.count, 20, 0, false)
+	return s
+}
+
+// setContent replaces the text view's content, keeping the current search
+func (s *searcher) setContent(content string) {
+	s.buf.Reset()
+	s.buf.WriteString(content)
+	s.content = s.buf.String()
+	s.lines = strings.Count(content, "\n")
+	s.stale = true
+	if s.active {
+		s.find()
+		s.current = min(s.current, max(len(s.matches)-1, 0))
+		s.show()
+		return
+	}
+	s.text.SetText(content)
+}
+
+// addContent appends content to the text view; it is searched from the
+// next search on
+func (s *searcher) addContent(content string) {
+	fmt.Fprint(s.text, content)
+	s.buf.WriteString(content)
+	s.content = s.buf.String()
+	s.lines += strings.Count(content, "\n")
+	s.stale = true
+}
+
+// trim replaces the first n lines of content with head, in the text view
+// too, keeping the lines in view and the current match
+func (s *searcher) trim(n int, head string) {
+	end := 0
+	for range n {
+		end += strings.IndexByte(s.content[end:], '\n') + 1
+	}
+	rest := s.content[end:]
+	s.buf.Reset()
+	s.buf.WriteString(head)
+	s.buf.WriteString(rest)
+	s.content = s.buf.String()
+	headLines := strings.Count(head, "\n")
+	s.lines += headLines - n
+	s.stale = true
+
+	shift := n - headLines
+	row, col := s.text.GetScrollOffset()
+	s.origin = max(s.origin-shift, 0)
+	if !s.active {
+		s.text.SetText(s.content)
+		s.text.ScrollTo(max(row-shift, 0), col)
+		return
+	}
+	gone := 0
+	for _, m := range s.matches {
+		if m.line < n {
+			gone++
+		}
+	}
+	s.find()
+	for _, m := range s.matches {
+		if m.line < headLines {
+			gone--
+		}
+	}
+	s.current = min(max(s.current-gone, 0), max(len(s.matches)-1, 0))
+	s.text.ScrollTo(max(row-shift, 0), col)
+	s.show()
+}
+
+// start opens the search bar for a new forward (/) or backward (?) search
+func (s *searcher) start(backward bool) {
+	s.backward = backward
+	s.origin, _ = s.text.GetScrollOffset()
+	if !s.active {
+		s.layout.AddItem(s.bar, 1, 0, false)
+		s.active = true
+	}
+	label := "/"
+	if backward {
+		label = "?"
+	}
+	s.input.SetLabel(label).SetText("")
+	s.app.SetFocus(s.input)
+}
+
+// update searches for the query as it is typed, jumping to the first match
+// from where the search started
+func (s *searcher) update(query string) {
+	s.query = query
+	s.find()
+	s.current = 0
+	for i, m := range s.matches {
+		if s.backward && m.line < s.origin {
+			s.current = i
+		}
+		if !s.backward && m.line >= s.origin {
+			s.current = i
+			break
+		}
+	}
+	if s.backward && len(s.matches) > 0 && s.matches[0].line >= s.origin {
+		s.current = len(s.matches) - 1 // Wrap around
+	}
+	s.show()
+}
+
+// done handles the end of typing: Enter keeps the search, Escape cancels it
+func (s *searcher) done(key tcell.Key) {
+	switch key {
+	case tcell.KeyEnter:
+		if s.query == "" {
+			s.clear()
+			return
+		}
+		s.app.SetFocus(s.text)
+	case tcell.KeyEscape:
+		s.clear()
+		_, col := s.text.GetScrollOffset()
+		s.text.ScrollTo(s.origin, col)
+	}
+}
+
+// next moves to the next match in the search's direction, or the previous
+// one if reverse is set, wrapping around
+func (s *searcher) next(reverse bool) {
+	if len(s.matches) == 0 {
+		return
+	}
+	step := 1
+	if s.backward != reverse {
+		step = -1
+	}
+	s.current = (s.current + step + len(s.matches)) % len(s.matches)
+	s.show()
+}
+
+// clear ends the search, reporting whether there was one
+func (s *searcher) clear() bool {
+	if !s.active {
+		return false
+	}
+	s.active = false
+	s.query, s.matches = "", nil
+	row, col := s.text.GetScrollOffset()
+	s.text.SetText(s.content)
+	s.text.ScrollTo(row, col)
+	s.layout.RemoveItem(s.bar)
+	s.app.SetFocus(s.text)
+	return true
+}
+
+// find lists the matches of the query in the displayed text
+func (s *searcher) find() {
+	s.matches = nil
+	if s.query == "" {
+		return
+	}
+	if s.stale {
+		s.plain, s.offsets = term.StripTviewTagsMapped(s.content)
+		s.stale = false
+	}
+	s.matches = findMatches(s.plain, searchRegexp(s.query))
+}
+
+// searchRegexp compiles a query: a regular expression, or literal text if
+// it doesn't compile. It matches case-insensitively unless the query has
+// upper case letters (smart case).
+func searchRegexp(query string) *regexp.Regexp {
+	expr, literal := query, false
+	if _, err := regexp.Compile(expr); err != nil {
+		expr, literal = regexp.QuoteMeta(query), true
+	}
+	if !hasUpper(query, !literal) {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+// hasUpper reports whether query has an upper case letter. With escapes
+// set, letters in escapes such as \W, \p{Lu} and \xFF don't count.
+func hasUpper(query string, escapes bool) bool {
+	for i := 0; i < len(query); i++ {
+		if escapes && query[i] == '\\' && i+1 < len(query) {
+			i++
+			switch {
+			case strings.IndexByte("pPx", query[i]) >= 0 && strings.HasPrefix(query[i+1:], "{"):
+				if end := strings.IndexByte(query[i:], '}'); end >= 0 {
+					i += end
+				}
+			case query[i] == 'x':
+				i = min(i+2, len(query)-1) // Two hex digits
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(query[i:])
+		if unicode.IsUpper(r) {
+			return true
+		}
+		i += size - 1
+	}
+	return false
+}
+
+// findMatches returns the non-empty matches of re in text, line by line
+func findMatches(text string, re *regexp.Regexp) []searchMatch {
+	var matches []searchMatch
+	start := 0
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range re.FindAllStringIndex(line, -1) {
+			if m[0] < m[1] {
+				matches = append(matches, searchMatch{line: i, start: start + m[0], end: start + m[1]})
+			}
+		}
+		start += len(line) + 1
+	}
+	return matches
+}
+
+// show marks the matches in the text view, scrolls the current one into
+// view and updates the match count
+func (s *searcher) show() {
+	row, col := s.text.GetScrollOffset()
+	s.text.SetText(s.markMatches())
+
+	switch {
+	case len(s.matches) > 0:
+		_, _, _, height := s.text.GetInnerRect()
+		if line := s.matches[s.current].line; line < row || line >= row+height {
+			row = max(line-height/3, 0)
+		}
+		s.count.SetText(fmt.Sprintf("match %d/%d ", s.current+1, len(s.matches)))
+	case s.query != "":
+		s.count.SetText(theme.Tag(theme.Error) + "no matches[-] ")
+	default:
+		s.count.SetText("")
+	}
+	s.text.ScrollTo(row, col)
+}
+
+// markMatches returns the content with each match in reverse video, the
+// current one also underlined. Matches aren't regions, as highlighting
+// regions costs time for each region on every line.
+func (s *searcher) markMatches() string {
+	if len(s.matches) == 0 {
+		return s.content
+	}
+	var sb strings.Builder
+	pos := 0
+	for i, m := range s.matches {
+		start, end := s.offsets[m.start], s.offsets[m.end-1]+1
+		on, off := "[::r]", "[::R]"
+		if i == s.current {
+			on, off = "[::ru]", "[::RU]"
+		}
+		sb.WriteString(s.content[pos:start])
+		sb.WriteString(on)
+		sb.WriteString(s.content[start:end])
+		sb.WriteString(off)
+		pos = end
+	}
+	sb.WriteString(s.content[pos:])
+	return sb.String()
+}
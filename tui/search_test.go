@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestSearchRegexp_SmartCase(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  bool
+	}{
+		{"foo", "FOO", true},
+		{"Foo", "foo", false},
+		{"Foo", "Foo", true},
+		{`\W+foo`, " FOO", true},
+		{`\Sfoo`, "xFOO", true},
+		{`\bfoo\B`, "FOOD", true},
+		{`\p{Lu}oo`, "FOO", true},
+		{`\xFFoo`, "ÿOO", true},
+		{`\x{41}b`, "AB", true},
+		{`\WFoo`, " foo", false},
+		{`foo(`, "FOO(", true},    // Literal, no upper case
+		{`\Foo(`, `\foo(`, false}, // Literal, so the F counts
+		{"été", "ÉTÉ", true},
+		{"Été", "été", false},
+	}
+	for _, tt := range tests {
+		if got := searchRegexp(tt.query).MatchString(tt.text); got != tt.want {
+			t.Errorf("searchRegexp(%q).MatchString(%q) = %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearcherFind(t *testing.T) {
+	tests := []struct {
+		content string
+		query   string
+		want    []searchMatch
+	}{
+		{"one two\nthree two", "two", []searchMatch{{0, 4, 7}, {1, 14, 17}}},
+		{"[red]one[-] two", "two", []searchMatch{{0, 4, 7}}},
+		{"a.b axb", "a.b", []searchMatch{{0, 0, 3}, {0, 4, 7}}},
+		{"a(b a(b", "a(b", []searchMatch{{0, 0, 3}, {0, 4, 7}}},
+		{"abc\n\nabc", "^", nil},
+		{"abc", "", nil},
+		{"abc", "x", nil},
+	}
+	for _, tt := range tests {
+		s := &searcher{content: tt.content, query: tt.query, stale: true}
+		s.find()
+		if fmt.Sprint(s.matches) != fmt.Sprint(tt.want) {
+			t.Errorf("find(%q) in %q = %v, want %v", tt.query, tt.content, s.matches, tt.want)
+		}
+	}
+}
+
+func TestSearcherMarkMatches(t *testing.T) {
+	tests := []struct {
+		content string
+		query   string
+		current int
+		want    string
+	}{
+		{"no match", "x", 0, "no match"},
+		{
+			"[red]foo[-] bar foo", "foo", 0,
+			"[red][::ru]foo[::RU][-] bar [::r]foo[::R]",
+		},
+		{
+			"foo foo", "foo", 1,
+			"[::r]foo[::R] [::ru]foo[::RU]",
+		},
+		{
+			// Tags inside a match are kept
+			"a [red]f[-]oo b", "foo", 0,
+			"a [red][::ru]f[-]oo[::RU] b",
+		},
+		{
+			`see ["link-ab"]the foo page[""] now`, "foo", 0,
+			`see ["link-ab"]the [::ru]foo[::RU] page[""] now`,
+		},
+	}
+	for _, tt := range tests {
+		s := &searcher{content: tt.content, query: tt.query, stale: true}
+		s.find()
+		s.current = tt.current
+		if got := s.markMatches(); got != tt.want {
+			t.Errorf("markMatches(%q in %q) =\n%s\nwant\n%s", tt.query, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestSearcherTrim(t *testing.T) {
+	s := &searcher{text: tview.NewTextView()}
+	for i := range 25 {
+		s.addContent(fmt.Sprintf("line %d\n", i))
+	}
+	s.trim(15, "dropped\n")
+	if s.lines != 11 || s.lines != strings.Count(s.content, "\n") {
+		t.Fatalf("Expected 11 lines kept, got %d:\n%s", s.lines, s.content)
+	}
+	if !strings.HasPrefix(s.content, "dropped\nline 15\n") || !strings.HasSuffix(s.content, "line 24\n") {
+		t.Errorf("Expected the head, then the latest lines, got:\n%s", s.content)
+	}
+	if got := s.text.GetText(false); got != s.content {
+		t.Errorf("Text view holds\n%s\nwant the searched content\n%s", got, s.content)
+	}
+
+	s.query = "line 2"
+	s.find()
+	lines := strings.Split(s.content, "\n")
+	for _, m := range s.matches {
+		if !strings.HasPrefix(lines[m.line], "line 2") {
+			t.Errorf("Match on line %d is %q", m.line, lines[m.line])
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/theme"
+)
+
+// maxStatusLines is the most diagnostics listed in the status area
+const maxStatusLines = 3
+
+// severityRoles are the theme colors for each diagnostic severity
+var severityRoles = map[parse.Severity]theme.Role{
+	parse.SeverityError:   theme.Error,
+	parse.SeverityWarning: theme.Warning,
+	parse.SeverityInfo:    theme.Muted,
+}
+
+// newStatusArea returns a view listing parse diagnostics and its height
+func newStatusArea(diags []parse.Diagnostic) (*tview.TextView, int) {
+	status := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false)
+
+	var lines []string
+	shown := diags
+	if len(diags) > maxStatusLines {
+		shown = diags[:maxStatusLines-1]
+	}
+	for _, d := range shown {
+		line := fmt.Sprintf(" %s%s[-]", theme.Tag(severityRoles[d.Severity]), d.Severity)
+		if pos := d.Position(); pos != "" {
+			line += " " + theme.Tag(theme.Muted) + pos + "[-]"
+		}
+		lines = append(lines, line+" "+tview.Escape(d.Message))
+	}
+	if len(shown) < len(diags) {
+		lines = append(lines, fmt.Sprintf(" %s... %d more (%s)[-]", theme.Tag(theme.Muted), len(diags)-len(shown), parse.SummarizeDiagnostics(diags)))
+	}
+	status.SetText(strings.Join(lines, "\n"))
+	return status, len(lines)
+}
+
+// printDiagnostics writes diagnostics one per line, prefixed with the source
+func printDiagnostics(w io.Writer, sourceName string, diags []parse.Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintln(w, d.Format(sourceName))
+	}
+}
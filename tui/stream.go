@@ -0,0 +1,309 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	xterm "golang.org/x/term"
+
+	"github.com/wildreason/reader/parse"
+
+	"github.com/wildreason/reader/render/term"
+	"github.com/wildreason/reader/theme"
+)
+
+// streamMaxLines bounds the lines kept of unbounded input; the oldest
+// lines are dropped once it is reached
+const streamMaxLines = 100000
+
+// StreamFunc parses input, calling emit for each block as it is ready, and
+// returns the parser's diagnostics once input is exhausted
+type StreamFunc func(ctx context.Context, emit func(parse.Block)) ([]parse.Diagnostic, error)
+
+// RunStream runs the reader TUI while input is still being parsed. Blocks
+// are appended as stream emits them, so the first screen shows before all
+// input has been read. The blocks still shown are rendered again when the
+// width changes or wrapping is toggled; diagnostics are shown in a status
+// area when parsing finishes. doc names the input; its Blocks are not used.
+// All lines are kept unless doc is Unbounded, when only the last
+// streamMaxLines are, below a note of how many were dropped.
+// The reader goes to doc's Place once it has streamed in, and saves the
+// place with Save when it closes. It returns the diagnostics, or nil if the
+// reader was closed before parsing finished.
+func RunStream(doc *Document, termWidth int, style string, borderStyle term.BorderStyle, stream StreamFunc) []parse.Diagnostic {
+	sourceName := doc.Name
+
+	// Pipe passthrough: if stdout is not a terminal, print plain text as it arrives
+	if !xterm.IsTerminal(int(os.Stdout.Fd())) {
+		empty := true
+		diags, err := stream(context.Background(), func(b parse.Block) {
+			empty = false
+			content := term.FormatBlocks([]parse.Block{b}, termWidth, borderStyle)
+			fmt.Print(term.StripTviewTags(content))
+		})
+		printDiagnostics(os.Stderr, sourceName, diags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", sourceName, err)
+			os.Exit(1)
+		}
+		if empty {
+			fmt.Println("Error: No blocks found in file.")
+		}
+		return diags
+	}
+
+	setStyles()
+	app := tview.NewApplication()
+
+	b := newStreamBuffer(app, doc, termWidth, borderStyle)
+	layout := b.layout
+	b.text.SetInputCapture(readerKeys(app, b.buffer, nil))
+	app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		w, _ := screen.Size()
+		b.sync(w)
+		return false
+	})
+	app.SetAfterDrawFunc(b.drawFrozen)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan []parse.Diagnostic, 1)
+	go func() {
+		empty := true
+		diags, err := stream(ctx, func(block parse.Block) {
+			empty = false
+			app.QueueUpdateDraw(func() {
+				b.add(block)
+			})
+		})
+		if ctx.Err() == nil {
+			done <- diags
+			app.QueueUpdateDraw(func() {
+				b.resume("", true)
+			})
+		}
+		switch {
+		case err != nil && ctx.Err() == nil:
+			app.QueueUpdateDraw(func() {
+				b.addNote(fmt.Sprintf("\n%sError reading %s: %s[-]\n", theme.Tag(theme.Error), tview.Escape(sourceName), tview.Escape(err.Error())))
+			})
+		case empty && err == nil:
+			app.QueueUpdateDraw(func() {
+				b.addNote("No blocks found in file.\n")
+			})
+		}
+		if len(diags) > 0 {
+			app.QueueUpdateDraw(func() {
+				status, height := newStatusArea(diags)
+				layout.AddItem(status, height, 0, false)
+			})
+		}
+	}()
+
+	if err := app.SetRoot(layout, true).Run(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	b.save()
+
+	select {
+	case diags := <-done:
+		return diags
+	default:
+		return nil
+	}
+}
+
+// streamBuffer is a buffer showing blocks as they are parsed. It keeps the
+// blocks whose lines are still shown, to render them again at a new width.
+type streamBuffer struct {
+	*buffer
+	doc         *Document
+	borderStyle term.BorderStyle
+	blocks      []parse.Block
+	blockLines  []int  // Rendered lines of each block
+	total       int    // Rendered lines of all blocks
+	maxLines    int    // Lines kept, 0 for all
+	dropped     int    // Lines dropped from the start, as they were shown
+	notes       string // Messages shown after the blocks
+	width       int    // Width the blocks were rendered at
+	wrap        bool   // Fit tables, code and preformatted lines to the screen
+	wrapped     bool   // Whether the blocks were rendered to fit
+	resumeAt    *Place // Place to go to once it has streamed in
+	resumeRow   int    // Line resumeAt is at, once its heading is in; -1 before
+}
+
+// newStreamBuffer returns an empty stream buffer for doc, rendering at
+// width
+func newStreamBuffer(app *tview.Application, doc *Document, width int, borderStyle term.BorderStyle) *streamBuffer {
+	b := &streamBuffer{
+		buffer:      newBuffer(app, doc.Name, false),
+		doc:         doc,
+		borderStyle: borderStyle,
+		width:       width,
+		wrap:        true,
+		wrapped:     true,
+		resumeRow:   -1,
+	}
+	if doc.Place != (Place{}) {
+		b.resumeAt = &doc.Place
+	}
+	for letter, p := range doc.Marks {
+		b.marks[letter] = p
+	}
+	if doc.Unbounded {
+		b.maxLines = streamMaxLines
+	}
+	b.toggleWrap = func() { b.wrap = !b.wrap }
+	return b
+}
+
+// format renders blocks at the buffer's width
+func (b *streamBuffer) format(blocks []parse.Block) string {
+	settings := b.links.renderSettings()
+	settings.NoWrap = !b.wrapped
+	return tview.TranslateANSI(term.FormatBlocksWith(blocks, b.width, b.borderStyle, settings))
+}
+
+// add appends a parsed block. Past maxLines lines, the oldest blocks are
+// dropped in batches, as the text view's whole text is set again.
+func (b *streamBuffer) add(block parse.Block) {
+	content := b.format([]parse.Block{block})
+	lines := strings.Count(content, "\n")
+	b.blocks = append(b.blocks, block)
+	b.blockLines = append(b.blockLines, lines)
+	b.total += lines
+	b.write(content)
+	if b.maxLines > 0 && b.total > b.maxLines+b.maxLines/10 {
+		b.evict()
+	}
+	b.resume(content, false)
+}
+
+// evict forgets the oldest blocks past maxLines lines, noting how many
+// lines were dropped in their place
+func (b *streamBuffer) evict() {
+	n := 0
+	for len(b.blocks) > 1 && b.total-b.blockLines[0] >= b.maxLines {
+		n += b.blockLines[0]
+		b.total -= b.blockLines[0]
+		b.blocks[0] = parse.Block{}
+		b.blocks, b.blockLines = b.blocks[1:], b.blockLines[1:]
+	}
+	if n == 0 {
+		return
+	}
+	note := strings.Count(b.droppedNote(), "\n")
+	b.dropped += n
+	b.search.trim(note+n, b.droppedNote())
+	if b.resumeRow >= 0 {
+		b.resumeRow = max(b.resumeRow-note-n+1, 0)
+	}
+	b.outline.changed()
+	b.stale = true
+}
+
+// droppedNote is shown above the blocks once earlier lines were dropped
+func (b *streamBuffer) droppedNote() string {
+	if b.dropped == 0 {
+		return ""
+	}
+	lines := "lines"
+	if b.dropped == 1 {
+		lines = "line"
+	}
+	return fmt.Sprintf(" %s%d earlier %s dropped[-]\n", theme.Tag(theme.Muted), b.dropped, lines)
+}
+
+// addNote appends a message after the blocks
+func (b *streamBuffer) addNote(note string) {
+	b.notes += note
+	b.write(note)
+}
+
+// write appends to the text view, keeping the outline and frozen columns
+// in step
+func (b *streamBuffer) write(content string) {
+	b.search.addContent(content)
+	b.outline.changed()
+	b.stale = true
+}
+
+// sync renders the blocks again if the screen width or the wrapping
+// changed since they were rendered, and has the outline follow the section
+// in view
+func (b *streamBuffer) sync(screenWidth int) {
+	width := screenWidth
+	if b.outline.visible {
+		width -= outlineWidth
+	}
+	if width != b.width || b.wrapped != b.wrap {
+		b.render(width, b.wrap)
+	}
+	b.outline.follow()
+}
+
+// render renders the blocks kept at width, keeping the section in view
+func (b *streamBuffer) render(width int, wrap bool) {
+	b.width, b.wrapped = width, wrap
+	section, offset := b.outline.position()
+	var content strings.Builder
+	content.WriteString(b.droppedNote())
+	b.total = 0
+	for i := range b.blocks {
+		block := b.format(b.blocks[i : i+1])
+		b.blockLines[i] = strings.Count(block, "\n")
+		b.total += b.blockLines[i]
+		content.WriteString(block)
+	}
+	b.search.setContent(content.String() + b.notes)
+	b.outline.changed()
+	b.stale = true
+	b.outline.restore(section, offset)
+}
+
+// resume goes to the document's place once the lines to show it have
+// streamed in, or as near as it can at the end of input. Scrolling first
+// cancels it. content is what was just added, where the place's heading is
+// looked for before finding headings again.
+func (b *streamBuffer) resume(content string, end bool) {
+	p := b.resumeAt
+	if p == nil {
+		return
+	}
+	if row, _ := b.text.GetScrollOffset(); row != 0 {
+		b.resumeAt = nil
+		return
+	}
+	if b.resumeRow < 0 && (end || p.Heading == "" || strings.Contains(content, p.Heading)) {
+		row, ok := b.outline.placeRow(*p)
+		if ok && (end || p.Heading == "" || p.Section < len(b.outline.headings)) {
+			b.resumeRow = row
+		}
+	}
+	_, _, _, height := b.text.GetInnerRect()
+	if b.resumeRow >= 0 && (end || b.search.lines >= b.resumeRow+height) {
+		b.text.ScrollTo(b.resumeRow, 0)
+		b.resumeAt = nil
+	} else if end {
+		b.resumeAt = nil
+	}
+}
+
+// save records the input's marks and where it was left, keeping the
+// place it had if the reader closed before getting to it
+func (b *streamBuffer) save() {
+	if b.doc.Save == nil {
+		return
+	}
+	if b.resumeAt == nil {
+		b.doc.Place = b.outline.place()
+	}
+	b.doc.Marks = b.marks
+	b.doc.Save(b.doc)
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/parse"
+	"github.com/wildreason/reader/render/term"
+)
+
+// streamLines adds n one-line blocks to b
+func streamLines(b *streamBuffer, n int) {
+	for i := range n {
+		line := fmt.Sprintf("log line %d", i)
+		b.add(parse.Block{Name: "Output", Content: line, Pages: []string{line}, TotalPages: 1, ContentType: parse.BlockContentShell})
+	}
+}
+
+func TestStreamBuffer_KeepsFileLines(t *testing.T) {
+	b := newStreamBuffer(tview.NewApplication(), &Document{Name: "app.log"}, 80, term.BorderNone)
+	streamLines(b, 300)
+	if b.maxLines != 0 || len(b.blocks) != 300 || b.dropped != 0 {
+		t.Fatalf("Expected all 300 blocks kept, got %d with %d lines dropped", len(b.blocks), b.dropped)
+	}
+	if !strings.Contains(b.search.content, "log line 0\n") {
+		t.Errorf("First line lost:\n%.200s", b.search.content)
+	}
+}
+
+func TestStreamBuffer_UnboundedDropsWithNote(t *testing.T) {
+	b := newStreamBuffer(tview.NewApplication(), &Document{Name: "stdin", Unbounded: true}, 80, term.BorderNone)
+	b.maxLines = 100
+	streamLines(b, 300)
+	if b.dropped == 0 || b.total > b.maxLines+b.maxLines/10 {
+		t.Fatalf("Expected old lines dropped, kept %d with %d dropped", b.total, b.dropped)
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(term.StripTviewTags(b.search.content)), "\n")
+	if want := fmt.Sprintf("%d earlier lines dropped", b.dropped); first != want {
+		t.Errorf("First line is %q, want %q", first, want)
+	}
+	if b.search.lines != b.total+1 || b.search.lines != strings.Count(b.search.content, "\n") {
+		t.Errorf("Text holds %d lines, want the note and the %d lines kept", b.search.lines, b.total)
+	}
+	if !strings.Contains(b.search.content, "log line 299\n") {
+		t.Errorf("Latest line missing:\n%s", b.search.content)
+	}
+
+	// Rendering again keeps the note and the kept blocks' lines in step
+	b.render(60, true)
+	first, _, _ = strings.Cut(term.StripTviewTags(b.search.content), "\n")
+	if !strings.HasSuffix(first, "earlier lines dropped") || b.search.lines != b.total+1 {
+		t.Errorf("After rendering again: first line %q, %d lines for %d kept", first, b.search.lines, b.total)
+	}
+}
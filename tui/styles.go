@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/wildreason/reader/theme"
+)
+
+// themeColor returns the tcell color of a theme role, the terminal's
+// default color when it has none
+func themeColor(r theme.Role) tcell.Color {
+	return tcell.GetColor(theme.Color(r))
+}
+
+// setStyles sets tview's colors from the theme. Primitives take their
+// colors when created, so it runs before the reader builds its views.
+func setStyles() {
+	bg, text := themeColor(theme.Background), themeColor(theme.Text)
+	tview.Styles.PrimitiveBackgroundColor = bg
+	tview.Styles.PrimaryTextColor = text
+	tview.Styles.BorderColor = text
+	tview.Styles.TitleColor = text
+	tview.Styles.GraphicsColor = text
+	tview.Styles.ContrastBackgroundColor = tcell.ColorDefault
+}
+
+// selectedStyle is the style of the selected item in lists
+func selectedStyle() tcell.Style {
+	return tcell.StyleDefault.
+		Foreground(themeColor(theme.Selected)).
+		Background(themeColor(theme.SelectedBg))
+}
@@ -3,33 +3,35 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 
-	"golang.org/x/term"
-)
+	xterm "golang.org/x/term"
 
-var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".webp", ".ico", ".svg"}
+	"github.com/wildreason/reader/render/term"
+)
 
-func imgTermWidth() string {
-	if term.IsTerminal(int(os.Stdout.Fd())) {
-		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
-			return strconv.Itoa(w)
+// imgTermSize returns the terminal size in cells, or 80x24 when stdout is
+// not a terminal
+func imgTermSize() (int, int) {
+	if xterm.IsTerminal(int(os.Stdout.Fd())) {
+		if w, h, err := xterm.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 0 {
+			return w, h
 		}
 	}
-	return "80"
+	return 80, 24
 }
 
-func imgDetectFormat() string {
-	tp := os.Getenv("TERM_PROGRAM")
-	switch tp {
-	case "iTerm.app", "WezTerm", "Hyper":
-		return "iterm"
-	case "kitty":
-		return "kitty"
+// imgDetectFormat probes the terminal for an image protocol. Output that
+// isn't a terminal gets Unicode blocks. ASTER_IMAGE_PROTOCOL overrides both.
+func imgDetectFormat() term.TerminalGraphics {
+	graphics := term.TerminalGraphics{Protocol: term.ImageBlocks}
+	if xterm.IsTerminal(int(os.Stdout.Fd())) {
+		graphics = term.DetectGraphics()
 	}
-	return "symbols"
+	if p, ok := term.ParseImageProtocol(os.Getenv("ASTER_IMAGE_PROTOCOL")); ok {
+		graphics.Protocol = p
+	}
+	return graphics
 }
 
 func viewImage(path string) {
@@ -38,44 +40,29 @@ func viewImage(path string) {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Try chafa
-	if chafaPath, err := exec.LookPath("chafa"); err == nil {
-		format := imgDetectFormat()
-		w := imgTermWidth()
-		cmd := exec.Command(chafaPath, "--size="+w, "--format="+format, path)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err == nil {
-			fmt.Printf("\n%s (%d bytes)\n", filepath.Base(path), info.Size())
-			return
-		}
-		// Fallback to symbols
-		if format != "symbols" {
-			cmd = exec.Command(chafaPath, "--size="+w, "--format=symbols", path)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			if err := cmd.Run(); err == nil {
-				fmt.Printf("\n%s (%d bytes)\n", filepath.Base(path), info.Size())
-				return
-			}
-		}
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-
-	// Try imgcat (iTerm2 native)
-	if imgcatPath, err := exec.LookPath("imgcat"); err == nil {
-		cmd := exec.Command(imgcatPath, path)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err == nil {
-			return
-		}
+	img, format, err := term.DecodeImage(f)
+	f.Close()
+	if err != nil {
+		// SVG and ICO have no decoder
+		fmt.Printf("Image: %s\n", path)
+		fmt.Printf("Size: %d bytes\n", info.Size())
+		fmt.Printf("Extension: %s\n", filepath.Ext(path))
+		fmt.Println("\nThis format can't be shown in the terminal.")
+		return
 	}
 
-	// Fallback
-	fmt.Printf("Image: %s\n", path)
-	fmt.Printf("Size: %d bytes\n", info.Size())
-	fmt.Printf("Extension: %s\n", filepath.Ext(path))
-	fmt.Println("\nInstall chafa for terminal image preview:")
-	fmt.Println("  brew install chafa")
+	// Leave room for the caption below the image
+	cols, rows := imgTermSize()
+	graphics := imgDetectFormat()
+	if err := term.WriteImage(os.Stdout, img, graphics.Protocol, cols, max(rows-3, 1), graphics.Cell); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	b := img.Bounds()
+	fmt.Printf("\n%s (%dx%d %s, %d bytes)\n", filepath.Base(path), b.Dx(), b.Dy(), format, info.Size())
 }
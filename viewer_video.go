@@ -8,8 +8,6 @@ import (
 	"strings"
 )
 
-var videoExtensions = []string{".mp4", ".webm", ".mov", ".mkv"}
-
 func viewVideo(path string) {
 	info, err := os.Stat(path)
 	if err != nil {